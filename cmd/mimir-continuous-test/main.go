@@ -25,6 +25,11 @@ type Config struct {
 	Client              continuoustest.ClientConfig
 	Manager             continuoustest.ManagerConfig
 	WriteReadSeriesTest continuoustest.WriteReadSeriesTestConfig
+	ExemplarsTest       continuoustest.WriteReadExemplarsTestConfig
+	MetadataTest        continuoustest.WriteReadMetadataTestConfig
+	OutOfOrderTest      continuoustest.WriteReadOutOfOrderTestConfig
+	LabelsTest          continuoustest.WriteReadLabelsTestConfig
+	ShardedQueriesTest  continuoustest.ShardedQueriesTestConfig
 }
 
 func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
@@ -33,6 +38,11 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	cfg.Client.RegisterFlags(f)
 	cfg.Manager.RegisterFlags(f)
 	cfg.WriteReadSeriesTest.RegisterFlags(f)
+	cfg.ExemplarsTest.RegisterFlags(f)
+	cfg.MetadataTest.RegisterFlags(f)
+	cfg.OutOfOrderTest.RegisterFlags(f)
+	cfg.LabelsTest.RegisterFlags(f)
+	cfg.ShardedQueriesTest.RegisterFlags(f)
 }
 
 func main() {
@@ -74,6 +84,21 @@ func main() {
 	// Run continuous testing.
 	m := continuoustest.NewManager(cfg.Manager, logger)
 	m.AddTest(continuoustest.NewWriteReadSeriesTest(cfg.WriteReadSeriesTest, client, logger, registry))
+	if cfg.ExemplarsTest.Enabled {
+		m.AddTest(continuoustest.NewWriteReadExemplarsTest(cfg.ExemplarsTest, client, logger, registry))
+	}
+	if cfg.MetadataTest.Enabled {
+		m.AddTest(continuoustest.NewWriteReadMetadataTest(cfg.MetadataTest, client, logger, registry))
+	}
+	if cfg.OutOfOrderTest.Enabled {
+		m.AddTest(continuoustest.NewWriteReadOutOfOrderTest(cfg.OutOfOrderTest, client, logger, registry))
+	}
+	if cfg.LabelsTest.Enabled {
+		m.AddTest(continuoustest.NewWriteReadLabelsTest(cfg.LabelsTest, client, logger, registry))
+	}
+	if cfg.ShardedQueriesTest.Enabled {
+		m.AddTest(continuoustest.NewShardedQueriesTest(cfg.ShardedQueriesTest, client, logger, registry))
+	}
 	if err := m.Run(context.Background()); err != nil {
 		level.Error(logger).Log("msg", "Failed to run continuous test", "err", err.Error())
 		os.Exit(1)
@@ -22,13 +22,16 @@ var (
 	alertmanagerCommand   commands.AlertmanagerCommand
 	analyzeCommand        commands.AnalyzeCommand
 	bucketValidateCommand commands.BucketValidationCommand
+	cardinalityCommand    commands.CardinalityCommand
 	configCommand         commands.ConfigCommand
 	loadgenCommand        commands.LoadgenCommand
 	logConfig             commands.LoggerConfig
+	overridesCommand      commands.OverridesCommand
 	pushGateway           commands.PushGatewayConfig
 	remoteReadCommand     commands.RemoteReadCommand
 	ruleCommand           commands.RuleCommand
 	backfillCommand       commands.BackfillCommand
+	tenantCommand         commands.TenantCommand
 )
 
 func main() {
@@ -40,13 +43,16 @@ func main() {
 	alertmanagerCommand.Register(app, envVars)
 	analyzeCommand.Register(app, envVars)
 	bucketValidateCommand.Register(app, envVars)
+	cardinalityCommand.Register(app, envVars)
 	configCommand.Register(app, envVars)
 	loadgenCommand.Register(app, envVars)
 	logConfig.Register(app, envVars)
+	overridesCommand.Register(app, envVars)
 	pushGateway.Register(app, envVars)
 	remoteReadCommand.Register(app, envVars)
 	ruleCommand.Register(app, envVars)
 	backfillCommand.Register(app, envVars)
+	tenantCommand.Register(app, envVars)
 
 	app.Command("version", "Get the version of the mimirtool CLI").Action(func(k *kingpin.ParseContext) error {
 		fmt.Fprintln(os.Stdout, mimirversion.Print("Mimirtool"))
@@ -6,6 +6,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 
@@ -21,10 +22,12 @@ import (
 )
 
 type Config struct {
-	ServerMetricsPort int
-	LogLevel          logging.Level
-	ProxyConfig       querytee.ProxyConfig
-	PathPrefix        string
+	ServerMetricsPort  int
+	LogLevel           logging.Level
+	ProxyConfig        querytee.ProxyConfig
+	ReplayConfig       querytee.ReplayConfig
+	PathPrefix         string
+	AlertmanagerPrefix string
 }
 
 func main() {
@@ -32,14 +35,25 @@ func main() {
 	cfg := Config{}
 	flag.IntVar(&cfg.ServerMetricsPort, "server.metrics-port", 9900, "The port where metrics are exposed.")
 	flag.StringVar(&cfg.PathPrefix, "server.path-prefix", "", "Prefix for API paths (query-tee will accept Prometheus API calls at <prefix>/api/v1/...)")
+	flag.StringVar(&cfg.AlertmanagerPrefix, "server.alertmanager-path-prefix", "/alertmanager", "Prefix for Alertmanager API paths (query-tee will accept Alertmanager API calls at <prefix>/api/v2/...)")
 	cfg.LogLevel.RegisterFlags(flag.CommandLine)
 	cfg.ProxyConfig.RegisterFlags(flag.CommandLine)
+	cfg.ReplayConfig.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
 	util_log.InitLogger(&server.Config{
 		LogLevel: cfg.LogLevel,
 	})
 
+	// Replay the recorded requests instead of running the proxy.
+	if cfg.ReplayConfig.File != "" {
+		if err := replay(cfg); err != nil {
+			level.Error(util_log.Logger).Log("msg", "Unable to replay the requests", "err", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Run the instrumentation server.
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(collectors.NewGoCollector())
@@ -65,28 +79,87 @@ func main() {
 	proxy.Await()
 }
 
-func mimirReadRoutes(cfg Config) []querytee.Route {
-	prefix := cfg.PathPrefix
+func replay(cfg Config) error {
+	f, err := os.Open(cfg.ReplayConfig.File)
+	if err != nil {
+		return err
+	}
+	requests, err := querytee.ReadRecordedRequests(f)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
 
-	// Strip trailing slashes.
-	for len(prefix) > 0 && prefix[len(prefix)-1] == '/' {
-		prefix = prefix[:len(prefix)-1]
+	replayer, err := querytee.NewReplayer(cfg.ReplayConfig, cfg.ProxyConfig, mimirReadRoutes(cfg), util_log.Logger)
+	if err != nil {
+		return err
 	}
 
+	level.Info(util_log.Logger).Log("msg", "Replaying the recorded requests", "requests", len(requests))
+	report, err := replayer.Replay(context.Background(), requests)
+	if err != nil {
+		return err
+	}
+	return report.Write(os.Stdout)
+}
+
+func mimirReadRoutes(cfg Config) []querytee.Route {
+	prefix := trimTrailingSlashes(cfg.PathPrefix)
+	alertmanagerPrefix := trimTrailingSlashes(cfg.AlertmanagerPrefix)
+	unordered := cfg.ProxyConfig.CompareIgnoreOrder
+
 	samplesComparator := querytee.NewSamplesComparator(querytee.SampleComparisonOptions{
 		Tolerance:         cfg.ProxyConfig.ValueComparisonTolerance,
 		UseRelativeError:  cfg.ProxyConfig.UseRelativeError,
 		SkipRecentSamples: cfg.ProxyConfig.SkipRecentSamples,
+		MetricTolerances:  cfg.ProxyConfig.MetricComparisonTolerances,
+	})
+
+	// The label names and values are sorted, while the order of the other results depends on the
+	// order the series are read from the storage, or the order of the rules and alerts evaluations.
+	labelsComparator := querytee.NewStructuredComparator(querytee.StructuredComparisonOptions{})
+	unorderedComparator := querytee.NewStructuredComparator(querytee.StructuredComparisonOptions{UnorderedArrays: unordered})
+	rulesConfigComparator := querytee.NewStructuredComparator(querytee.StructuredComparisonOptions{YAML: true, UnorderedArrays: unordered})
+
+	// The evaluation timestamps and the values of the alerts are different for each ruler.
+	rulesComparator := querytee.NewStructuredComparator(querytee.StructuredComparisonOptions{
+		UnorderedArrays: unordered,
+		IgnoredFields:   []string{"evaluationTime", "lastEvaluation", "activeAt", "value"},
+	})
+	alertsComparator := querytee.NewStructuredComparator(querytee.StructuredComparisonOptions{
+		UnorderedArrays: unordered,
+		IgnoredFields:   []string{"activeAt", "value"},
+	})
+	alertmanagerAlertsComparator := querytee.NewStructuredComparator(querytee.StructuredComparisonOptions{
+		UnorderedArrays: unordered,
+		IgnoredFields:   []string{"updatedAt", "endsAt"},
 	})
+	silencesComparator := querytee.NewStructuredComparator(querytee.StructuredComparisonOptions{
+		UnorderedArrays: unordered,
+		IgnoredFields:   []string{"updatedAt"},
+	})
+
 	return []querytee.Route{
 		{Path: prefix + "/api/v1/query", RouteName: "api_v1_query", Methods: []string{"GET", "POST"}, ResponseComparator: samplesComparator},
 		{Path: prefix + "/api/v1/query_range", RouteName: "api_v1_query_range", Methods: []string{"GET", "POST"}, ResponseComparator: samplesComparator},
-		{Path: prefix + "/api/v1/query_exemplars", RouteName: "api_v1_query_exemplars", Methods: []string{"GET", "POST"}, ResponseComparator: nil},
-		{Path: prefix + "/api/v1/labels", RouteName: "api_v1_labels", Methods: []string{"GET", "POST"}, ResponseComparator: nil},
-		{Path: prefix + "/api/v1/label/{name}/values", RouteName: "api_v1_label_name_values", Methods: []string{"GET", "POST"}, ResponseComparator: nil},
-		{Path: prefix + "/api/v1/series", RouteName: "api_v1_series", Methods: []string{"GET", "POST"}, ResponseComparator: nil},
-		{Path: prefix + "/api/v1/metadata", RouteName: "api_v1_metadata", Methods: []string{"GET", "POST"}, ResponseComparator: nil},
-		{Path: prefix + "/prometheus/config/v1/rules", RouteName: "prometheus_config_v1_rules", Methods: []string{"GET", "POST"}, ResponseComparator: nil},
-		{Path: prefix + "/api/v1/alerts", RouteName: "api_v1_alerts", Methods: []string{"GET", "POST"}, ResponseComparator: nil},
+		{Path: prefix + "/api/v1/query_exemplars", RouteName: "api_v1_query_exemplars", Methods: []string{"GET", "POST"}, ResponseComparator: unorderedComparator},
+		{Path: prefix + "/api/v1/labels", RouteName: "api_v1_labels", Methods: []string{"GET", "POST"}, ResponseComparator: labelsComparator},
+		{Path: prefix + "/api/v1/label/{name}/values", RouteName: "api_v1_label_name_values", Methods: []string{"GET", "POST"}, ResponseComparator: labelsComparator},
+		{Path: prefix + "/api/v1/series", RouteName: "api_v1_series", Methods: []string{"GET", "POST"}, ResponseComparator: unorderedComparator},
+		{Path: prefix + "/api/v1/metadata", RouteName: "api_v1_metadata", Methods: []string{"GET", "POST"}, ResponseComparator: unorderedComparator},
+		{Path: prefix + "/prometheus/config/v1/rules", RouteName: "prometheus_config_v1_rules", Methods: []string{"GET", "POST"}, ResponseComparator: rulesConfigComparator},
+		{Path: prefix + "/api/v1/rules", RouteName: "api_v1_rules", Methods: []string{"GET", "POST"}, ResponseComparator: rulesComparator},
+		{Path: prefix + "/api/v1/alerts", RouteName: "api_v1_alerts", Methods: []string{"GET", "POST"}, ResponseComparator: alertsComparator},
+		{Path: alertmanagerPrefix + "/api/v2/alerts", RouteName: "alertmanager_api_v2_alerts", Methods: []string{"GET"}, ResponseComparator: alertmanagerAlertsComparator},
+		{Path: alertmanagerPrefix + "/api/v2/alerts/groups", RouteName: "alertmanager_api_v2_alerts_groups", Methods: []string{"GET"}, ResponseComparator: alertmanagerAlertsComparator},
+		{Path: alertmanagerPrefix + "/api/v2/silences", RouteName: "alertmanager_api_v2_silences", Methods: []string{"GET"}, ResponseComparator: silencesComparator},
+	}
+}
+
+// trimTrailingSlashes strips the trailing slashes of a path prefix.
+func trimTrailingSlashes(prefix string) string {
+	for len(prefix) > 0 && prefix[len(prefix)-1] == '/' {
+		prefix = prefix[:len(prefix)-1]
 	}
+	return prefix
 }
@@ -13,13 +13,21 @@ import (
 )
 
 func TestMimirReadRoutes(t *testing.T) {
-	routes := mimirReadRoutes(Config{PathPrefix: ""})
+	routes := mimirReadRoutes(Config{PathPrefix: "", AlertmanagerPrefix: "/alertmanager"})
 	for _, r := range routes {
+		if strings.HasPrefix(r.RouteName, "alertmanager_") {
+			assert.True(t, strings.HasPrefix(r.Path, "/alertmanager/api/v2/"))
+			continue
+		}
 		assert.True(t, strings.HasPrefix(r.Path, "/api/v1/") || strings.HasPrefix(r.Path, "/prometheus/"))
 	}
 
-	routes = mimirReadRoutes(Config{PathPrefix: "/some/random/prefix///"})
+	routes = mimirReadRoutes(Config{PathPrefix: "/some/random/prefix///", AlertmanagerPrefix: "/some/alertmanager/prefix//"})
 	for _, r := range routes {
+		if strings.HasPrefix(r.RouteName, "alertmanager_") {
+			assert.Regexp(t, "^/some/alertmanager/prefix/api/v2/[a-z].*", r.Path)
+			continue
+		}
 		assert.Regexp(t, "/some/random/prefix/[a-z].*", r.Path)
 	}
 }
@@ -85,6 +85,12 @@ type Config struct {
 	Replicator        Replicator
 	Store             alertstore.AlertStore
 	PersisterConfig   PersisterConfig
+
+	// Number of last notification attempts to keep in the notification history. 0 disables the history.
+	NotificationHistorySize int
+
+	// Maximum size of a state imported through the state transfer API. 0 means no limit.
+	MaxStateSize int64
 }
 
 // An Alertmanager manages the alerts for one user.
@@ -94,6 +100,7 @@ type Alertmanager struct {
 	logger          log.Logger
 	state           *state
 	persister       *statePersister
+	notifications   *notificationHistory
 	nflog           *nflog.Log
 	silences        *silence.Silences
 	marker          types.Marker
@@ -184,7 +191,8 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 
 	am.registry = reg
 	am.state = newReplicatedStates(cfg.UserID, cfg.ReplicationFactor, cfg.Replicator, cfg.Store, am.logger, am.registry)
-	am.persister = newStatePersister(cfg.PersisterConfig, cfg.UserID, am.state, cfg.Store, am.logger, am.registry)
+	am.notifications = newNotificationHistory(cfg.NotificationHistorySize)
+	am.persister = newStatePersister(cfg.PersisterConfig, cfg.UserID, am.state, am.notifications, cfg.Store, am.logger, am.registry)
 
 	am.wg.Add(1)
 	var err error
@@ -278,6 +286,19 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 		am.mux.Handle(a, http.NotFoundHandler())
 	}
 
+	// The notification history isn't part of the upstream API.
+	am.mux.Handle(path.Join(am.cfg.ExternalURL.Path, "/api/v1/notifications"), am.notifications)
+
+	// The export and import of the state aren't part of the upstream API either. They're registered outside
+	// of the external URL path, so that they're only served through the admin route of the state transfer.
+	am.mux.Handle(stateTransferPath, &stateTransfer{
+		logger:       log.With(am.logger, "component", "state-transfer"),
+		state:        am.state,
+		silences:     am.silences,
+		nflog:        am.nflog,
+		maxStateSize: am.cfg.MaxStateSize,
+	})
+
 	am.dispatcherMetrics = dispatch.NewDispatcherMetrics(true, am.registry)
 
 	//TODO: From this point onward, the alertmanager _might_ receive requests - we need to make sure we've settled and are ready.
@@ -351,9 +372,10 @@ func (am *Alertmanager) ApplyConfig(userID string, conf *config.Config, rawCfg s
 				integration: integrationName,
 			}
 
-			return newRateLimitedNotifier(notifier, rl, 10*time.Second, am.rateLimitedNotifications.WithLabelValues(integrationName))
+			notifier = newRateLimitedNotifier(notifier, rl, 10*time.Second, am.rateLimitedNotifications.WithLabelValues(integrationName))
 		}
-		return notifier
+		// The notification history records the rate-limited notifications as well.
+		return am.notifications.WrapNotifier(integrationName, notifier)
 	})
 	if err != nil {
 		return nil
@@ -7,18 +7,23 @@ import (
 	fmt "fmt"
 	_ "github.com/gogo/protobuf/gogoproto"
 	proto "github.com/gogo/protobuf/proto"
+	github_com_gogo_protobuf_sortkeys "github.com/gogo/protobuf/sortkeys"
+	github_com_gogo_protobuf_types "github.com/gogo/protobuf/types"
+	_ "github.com/golang/protobuf/ptypes/timestamp"
 	clusterpb "github.com/prometheus/alertmanager/cluster/clusterpb"
 	io "io"
 	math "math"
 	math_bits "math/bits"
 	reflect "reflect"
 	strings "strings"
+	time "time"
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
+var _ = time.Kitchen
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the proto package it is being compiled against.
@@ -138,6 +143,8 @@ func (m *TemplateDesc) GetBody() string {
 
 type FullStateDesc struct {
 	State *clusterpb.FullState `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	// The last notification attempts of the tenant, the most recent last.
+	Notifications []*NotificationDesc `protobuf:"bytes,2,rep,name=notifications,proto3" json:"notifications,omitempty"`
 }
 
 func (m *FullStateDesc) Reset()      { *m = FullStateDesc{} }
@@ -179,36 +186,159 @@ func (m *FullStateDesc) GetState() *clusterpb.FullState {
 	return nil
 }
 
+func (m *FullStateDesc) GetNotifications() []*NotificationDesc {
+	if m != nil {
+		return m.Notifications
+	}
+	return nil
+}
+
+type NotificationDesc struct {
+	Timestamp      time.Time         `protobuf:"bytes,1,opt,name=timestamp,proto3,stdtime" json:"timestamp"`
+	Receiver       string            `protobuf:"bytes,2,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	Integration    string            `protobuf:"bytes,3,opt,name=integration,proto3" json:"integration,omitempty"`
+	GroupLabels    map[string]string `protobuf:"bytes,4,rep,name=group_labels,json=groupLabels,proto3" json:"group_labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	FiringAlerts   int64             `protobuf:"varint,5,opt,name=firing_alerts,json=firingAlerts,proto3" json:"firing_alerts,omitempty"`
+	ResolvedAlerts int64             `protobuf:"varint,6,opt,name=resolved_alerts,json=resolvedAlerts,proto3" json:"resolved_alerts,omitempty"`
+	Outcome        string            `protobuf:"bytes,7,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	Error          string            `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (m *NotificationDesc) Reset()      { *m = NotificationDesc{} }
+func (*NotificationDesc) ProtoMessage() {}
+func (*NotificationDesc) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{3}
+}
+func (m *NotificationDesc) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *NotificationDesc) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_NotificationDesc.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *NotificationDesc) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NotificationDesc.Merge(m, src)
+}
+func (m *NotificationDesc) XXX_Size() int {
+	return m.Size()
+}
+func (m *NotificationDesc) XXX_DiscardUnknown() {
+	xxx_messageInfo_NotificationDesc.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NotificationDesc proto.InternalMessageInfo
+
+func (m *NotificationDesc) GetTimestamp() time.Time {
+	if m != nil {
+		return m.Timestamp
+	}
+	return time.Time{}
+}
+
+func (m *NotificationDesc) GetReceiver() string {
+	if m != nil {
+		return m.Receiver
+	}
+	return ""
+}
+
+func (m *NotificationDesc) GetIntegration() string {
+	if m != nil {
+		return m.Integration
+	}
+	return ""
+}
+
+func (m *NotificationDesc) GetGroupLabels() map[string]string {
+	if m != nil {
+		return m.GroupLabels
+	}
+	return nil
+}
+
+func (m *NotificationDesc) GetFiringAlerts() int64 {
+	if m != nil {
+		return m.FiringAlerts
+	}
+	return 0
+}
+
+func (m *NotificationDesc) GetResolvedAlerts() int64 {
+	if m != nil {
+		return m.ResolvedAlerts
+	}
+	return 0
+}
+
+func (m *NotificationDesc) GetOutcome() string {
+	if m != nil {
+		return m.Outcome
+	}
+	return ""
+}
+
+func (m *NotificationDesc) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*AlertConfigDesc)(nil), "alerts.AlertConfigDesc")
 	proto.RegisterType((*TemplateDesc)(nil), "alerts.TemplateDesc")
 	proto.RegisterType((*FullStateDesc)(nil), "alerts.FullStateDesc")
+	proto.RegisterType((*NotificationDesc)(nil), "alerts.NotificationDesc")
+	proto.RegisterMapType((map[string]string)(nil), "alerts.NotificationDesc.GroupLabelsEntry")
 }
 
 func init() { proto.RegisterFile("alerts.proto", fileDescriptor_20493709c38b81dc) }
 
 var fileDescriptor_20493709c38b81dc = []byte{
-	// 320 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x50, 0xbf, 0x4e, 0xc2, 0x40,
-	0x18, 0xbf, 0x13, 0x24, 0x70, 0x62, 0x4c, 0x1a, 0x06, 0x42, 0xe2, 0x27, 0x61, 0x22, 0x0e, 0x6d,
-	0x82, 0x9b, 0x03, 0x09, 0x68, 0x7c, 0x00, 0x74, 0x72, 0x31, 0xd7, 0x7a, 0x14, 0x92, 0x96, 0x6b,
-	0xee, 0xae, 0x41, 0x37, 0x1f, 0xc1, 0x47, 0x70, 0xf4, 0x51, 0x1c, 0x19, 0x19, 0xe5, 0x58, 0x18,
-	0x79, 0x04, 0x73, 0x77, 0xa5, 0x38, 0xf5, 0xf7, 0xf5, 0xf7, 0xe7, 0xfb, 0x7e, 0x47, 0x9a, 0x34,
-	0x61, 0x42, 0x49, 0x3f, 0x13, 0x5c, 0x71, 0xaf, 0xe6, 0xa6, 0x4e, 0x2b, 0xe6, 0x31, 0xb7, 0xbf,
-	0x02, 0x83, 0x1c, 0xdb, 0x19, 0xc7, 0x73, 0x35, 0xcb, 0x43, 0x3f, 0xe2, 0x69, 0x90, 0x09, 0x9e,
-	0x32, 0x35, 0x63, 0xb9, 0x0c, 0xac, 0x27, 0xa5, 0x0b, 0x1a, 0x33, 0x11, 0x44, 0x49, 0x2e, 0xd5,
-	0xf1, 0x9b, 0x85, 0x07, 0xe4, 0x32, 0x7a, 0x6f, 0xe4, 0x62, 0x64, 0xf4, 0x77, 0x7c, 0x31, 0x9d,
-	0xc7, 0xf7, 0x4c, 0x46, 0x9e, 0x47, 0xaa, 0xb9, 0x64, 0xa2, 0x8d, 0xbb, 0xb8, 0xdf, 0x98, 0x58,
-	0xec, 0x5d, 0x12, 0x22, 0xe8, 0xf2, 0x25, 0xb2, 0xaa, 0xf6, 0x89, 0x65, 0x1a, 0x82, 0x2e, 0x9d,
-	0xcd, 0x1b, 0x90, 0x86, 0x62, 0x69, 0x96, 0x50, 0xc5, 0x64, 0xbb, 0xd2, 0xad, 0xf4, 0xcf, 0x06,
-	0x2d, 0xbf, 0x68, 0xf2, 0x54, 0x10, 0x26, 0x7b, 0x72, 0x94, 0xf5, 0x86, 0xa4, 0xf9, 0x9f, 0xf2,
-	0x3a, 0xa4, 0x3e, 0x9d, 0x27, 0x6c, 0x41, 0x53, 0x56, 0xac, 0x2e, 0x67, 0x73, 0x52, 0xc8, 0x5f,
-	0xdf, 0x8b, 0xc5, 0x16, 0xf7, 0x46, 0xe4, 0xfc, 0x21, 0x4f, 0x92, 0x47, 0x75, 0x08, 0xb8, 0x26,
-	0xa7, 0xd2, 0x0c, 0xd6, 0x6d, 0x0e, 0x28, 0x3b, 0xfb, 0xa5, 0x70, 0xe2, 0x24, 0xb7, 0xd5, 0xdd,
-	0xd7, 0x15, 0x1a, 0x0f, 0x57, 0x1b, 0x40, 0xeb, 0x0d, 0xa0, 0xfd, 0x06, 0xf0, 0x87, 0x06, 0xfc,
-	0xad, 0x01, 0xff, 0x68, 0xc0, 0x2b, 0x0d, 0xf8, 0x57, 0x03, 0xde, 0x69, 0x40, 0x7b, 0x0d, 0xf8,
-	0x73, 0x0b, 0x68, 0xb5, 0x05, 0xb4, 0xde, 0x02, 0x7a, 0xae, 0xbb, 0x62, 0x59, 0x18, 0xd6, 0xec,
-	0x1b, 0xde, 0xfc, 0x05, 0x00, 0x00, 0xff, 0xff, 0x3d, 0xbd, 0xc1, 0x50, 0xb5, 0x01, 0x00, 0x00,
+	// 560 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x92, 0xbf, 0x6e, 0xdb, 0x30,
+	0x10, 0xc6, 0xc5, 0x28, 0x76, 0x6c, 0xda, 0x6e, 0x0c, 0xc2, 0x83, 0x60, 0xa0, 0xb4, 0xe1, 0x0e,
+	0x75, 0x3b, 0x48, 0x40, 0xba, 0x14, 0x19, 0x0c, 0xd4, 0xfd, 0xb7, 0x04, 0x1d, 0xd4, 0x4c, 0x5d,
+	0x0c, 0x49, 0x39, 0x2b, 0x42, 0x25, 0x51, 0x20, 0x29, 0xa7, 0xde, 0xda, 0x37, 0xc8, 0x23, 0x64,
+	0xec, 0xa3, 0x64, 0xf4, 0x98, 0xa9, 0xad, 0xe5, 0x25, 0x63, 0x1e, 0xa1, 0x10, 0x25, 0xd9, 0x6e,
+	0x80, 0x4e, 0xba, 0xef, 0xee, 0x77, 0xe4, 0x77, 0x47, 0xe1, 0xb6, 0x13, 0x02, 0x97, 0xc2, 0x4c,
+	0x38, 0x93, 0x8c, 0xd4, 0x0b, 0xd5, 0xef, 0xf9, 0xcc, 0x67, 0x2a, 0x65, 0xe5, 0x51, 0x51, 0xed,
+	0x4f, 0xfd, 0x40, 0x5e, 0xa6, 0xae, 0xe9, 0xb1, 0xc8, 0x4a, 0x38, 0x8b, 0x40, 0x5e, 0x42, 0x2a,
+	0x2c, 0xd5, 0x13, 0x39, 0xb1, 0xe3, 0x03, 0xb7, 0xbc, 0x30, 0x15, 0x72, 0xf7, 0x4d, 0xdc, 0x2a,
+	0x2a, 0xcf, 0x18, 0xf8, 0x8c, 0xf9, 0x21, 0x58, 0x4a, 0xb9, 0xe9, 0xdc, 0x92, 0x41, 0x04, 0x42,
+	0x3a, 0x51, 0x52, 0x00, 0xa3, 0x6f, 0xf8, 0xf8, 0x4d, 0x7e, 0xe0, 0x5b, 0x16, 0xcf, 0x03, 0xff,
+	0x1d, 0x08, 0x8f, 0x10, 0x7c, 0x98, 0x0a, 0xe0, 0x06, 0x1a, 0xa2, 0x71, 0xd3, 0x56, 0x31, 0x79,
+	0x8a, 0x31, 0x77, 0xae, 0x66, 0x9e, 0xa2, 0x8c, 0x03, 0x55, 0x69, 0x72, 0xe7, 0xaa, 0x68, 0x23,
+	0x27, 0xb8, 0x29, 0x21, 0x4a, 0x42, 0x47, 0x82, 0x30, 0xf4, 0xa1, 0x3e, 0x6e, 0x9d, 0xf4, 0xcc,
+	0x72, 0xd4, 0xf3, 0xb2, 0x90, 0x9f, 0x6d, 0xef, 0xb0, 0xd1, 0x04, 0xb7, 0xf7, 0x4b, 0xa4, 0x8f,
+	0x1b, 0xf3, 0x20, 0x84, 0xd8, 0x89, 0xa0, 0xbc, 0x7a, 0xab, 0x73, 0x4b, 0x2e, 0xbb, 0x58, 0x96,
+	0x17, 0xab, 0x78, 0xf4, 0x03, 0xe1, 0xce, 0x87, 0x34, 0x0c, 0x3f, 0xcb, 0xea, 0x84, 0x97, 0xb8,
+	0x26, 0x72, 0xa1, 0xda, 0x73, 0x07, 0xdb, 0xad, 0x98, 0x5b, 0xd0, 0x2e, 0x10, 0x32, 0xc1, 0x9d,
+	0x98, 0xc9, 0x60, 0x1e, 0x78, 0x8e, 0x0c, 0x58, 0x2c, 0x8c, 0x03, 0xe5, 0xda, 0xa8, 0x5c, 0x7f,
+	0xda, 0x2b, 0x2a, 0xe7, 0xff, 0xe2, 0xa7, 0x87, 0xf7, 0x37, 0x03, 0x6d, 0x74, 0xa3, 0xe3, 0xee,
+	0x63, 0x92, 0x4c, 0x71, 0x73, 0xbb, 0xe5, 0xd2, 0x4a, 0xdf, 0x2c, 0xde, 0xc1, 0xac, 0xde, 0xc1,
+	0x3c, 0xaf, 0x88, 0x69, 0xe3, 0xf6, 0xd7, 0x40, 0xbb, 0xfe, 0x3d, 0x40, 0xf6, 0xae, 0x2d, 0x5f,
+	0x06, 0x07, 0x0f, 0x82, 0x05, 0xf0, 0x72, 0xe8, 0xad, 0x26, 0x43, 0xdc, 0x0a, 0x62, 0x09, 0x3e,
+	0x57, 0x57, 0x1a, 0xba, 0x2a, 0xef, 0xa7, 0xc8, 0x19, 0x6e, 0xfb, 0x9c, 0xa5, 0xc9, 0x2c, 0x74,
+	0x5c, 0x08, 0x85, 0x71, 0xa8, 0x66, 0x7b, 0xf1, 0xbf, 0xd9, 0xcc, 0x8f, 0x39, 0x7c, 0xa6, 0xd8,
+	0xf7, 0xb1, 0xe4, 0x4b, 0xbb, 0xe5, 0xef, 0x32, 0xe4, 0x19, 0xee, 0xcc, 0x03, 0x1e, 0xc4, 0xfe,
+	0xac, 0xe8, 0x37, 0x6a, 0x43, 0x34, 0xd6, 0xed, 0x76, 0x91, 0x54, 0x7f, 0x8f, 0x20, 0xcf, 0xf1,
+	0x31, 0x07, 0xc1, 0xc2, 0x05, 0x5c, 0x54, 0x58, 0x5d, 0x61, 0x4f, 0xaa, 0x74, 0x09, 0x1a, 0xf8,
+	0x88, 0xa5, 0xd2, 0x63, 0x11, 0x18, 0x47, 0xca, 0x79, 0x25, 0x49, 0x0f, 0xd7, 0x80, 0x73, 0xc6,
+	0x8d, 0x86, 0xca, 0x17, 0xa2, 0x3f, 0xc1, 0xdd, 0xc7, 0xf6, 0x48, 0x17, 0xeb, 0x5f, 0x61, 0x59,
+	0xfe, 0x25, 0x79, 0x98, 0xf7, 0x2e, 0x9c, 0x30, 0x85, 0x72, 0x59, 0x85, 0x38, 0x3d, 0x78, 0x8d,
+	0xa6, 0x93, 0xd5, 0x9a, 0x6a, 0x77, 0x6b, 0xaa, 0x3d, 0xac, 0x29, 0xfa, 0x9e, 0x51, 0xf4, 0x33,
+	0xa3, 0xe8, 0x36, 0xa3, 0x68, 0x95, 0x51, 0xf4, 0x27, 0xa3, 0xe8, 0x3e, 0xa3, 0xda, 0x43, 0x46,
+	0xd1, 0xf5, 0x86, 0x6a, 0xab, 0x0d, 0xd5, 0xee, 0x36, 0x54, 0xfb, 0xd2, 0x28, 0x66, 0x48, 0x5c,
+	0xb7, 0xae, 0x9e, 0xec, 0xd5, 0xdf, 0x01, 0x00, 0xdf, 0x03, 0xf4, 0x60, 0xba, 0x03, 0x00, 0x00,
 }
 
 func (this *AlertConfigDesc) Equal(that interface{}) bool {
@@ -273,6 +403,56 @@ func (this *TemplateDesc) Equal(that interface{}) bool {
 	}
 	return true
 }
+func (this *NotificationDesc) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*NotificationDesc)
+	if !ok {
+		that2, ok := that.(NotificationDesc)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !this.Timestamp.Equal(that1.Timestamp) {
+		return false
+	}
+	if this.Receiver != that1.Receiver {
+		return false
+	}
+	if this.Integration != that1.Integration {
+		return false
+	}
+	if len(this.GroupLabels) != len(that1.GroupLabels) {
+		return false
+	}
+	for i := range this.GroupLabels {
+		if this.GroupLabels[i] != that1.GroupLabels[i] {
+			return false
+		}
+	}
+	if this.FiringAlerts != that1.FiringAlerts {
+		return false
+	}
+	if this.ResolvedAlerts != that1.ResolvedAlerts {
+		return false
+	}
+	if this.Outcome != that1.Outcome {
+		return false
+	}
+	if this.Error != that1.Error {
+		return false
+	}
+	return true
+}
 func (this *AlertConfigDesc) GoString() string {
 	if this == nil {
 		return "nil"
@@ -302,11 +482,43 @@ func (this *FullStateDesc) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 5)
+	s := make([]string, 0, 6)
 	s = append(s, "&alertspb.FullStateDesc{")
 	if this.State != nil {
 		s = append(s, "State: "+fmt.Sprintf("%#v", this.State)+",\n")
 	}
+	if this.Notifications != nil {
+		s = append(s, "Notifications: "+fmt.Sprintf("%#v", this.Notifications)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *NotificationDesc) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 12)
+	s = append(s, "&alertspb.NotificationDesc{")
+	s = append(s, "Timestamp: "+fmt.Sprintf("%#v", this.Timestamp)+",\n")
+	s = append(s, "Receiver: "+fmt.Sprintf("%#v", this.Receiver)+",\n")
+	s = append(s, "Integration: "+fmt.Sprintf("%#v", this.Integration)+",\n")
+	keysForGroupLabels := make([]string, 0, len(this.GroupLabels))
+	for k, _ := range this.GroupLabels {
+		keysForGroupLabels = append(keysForGroupLabels, k)
+	}
+	github_com_gogo_protobuf_sortkeys.Strings(keysForGroupLabels)
+	mapStringForGroupLabels := "map[string]string{"
+	for _, k := range keysForGroupLabels {
+		mapStringForGroupLabels += fmt.Sprintf("%#v: %#v,", k, this.GroupLabels[k])
+	}
+	mapStringForGroupLabels += "}"
+	if this.GroupLabels != nil {
+		s = append(s, "GroupLabels: "+mapStringForGroupLabels+",\n")
+	}
+	s = append(s, "FiringAlerts: "+fmt.Sprintf("%#v", this.FiringAlerts)+",\n")
+	s = append(s, "ResolvedAlerts: "+fmt.Sprintf("%#v", this.ResolvedAlerts)+",\n")
+	s = append(s, "Outcome: "+fmt.Sprintf("%#v", this.Outcome)+",\n")
+	s = append(s, "Error: "+fmt.Sprintf("%#v", this.Error)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -426,6 +638,20 @@ func (m *FullStateDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Notifications) > 0 {
+		for iNdEx := len(m.Notifications) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Notifications[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintAlerts(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
 	if m.State != nil {
 		{
 			size, err := m.State.MarshalToSizedBuffer(dAtA[:i])
@@ -441,6 +667,94 @@ func (m *FullStateDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *NotificationDesc) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *NotificationDesc) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *NotificationDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Error) > 0 {
+		i -= len(m.Error)
+		copy(dAtA[i:], m.Error)
+		i = encodeVarintAlerts(dAtA, i, uint64(len(m.Error)))
+		i--
+		dAtA[i] = 0x42
+	}
+	if len(m.Outcome) > 0 {
+		i -= len(m.Outcome)
+		copy(dAtA[i:], m.Outcome)
+		i = encodeVarintAlerts(dAtA, i, uint64(len(m.Outcome)))
+		i--
+		dAtA[i] = 0x3a
+	}
+	if m.ResolvedAlerts != 0 {
+		i = encodeVarintAlerts(dAtA, i, uint64(m.ResolvedAlerts))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.FiringAlerts != 0 {
+		i = encodeVarintAlerts(dAtA, i, uint64(m.FiringAlerts))
+		i--
+		dAtA[i] = 0x28
+	}
+	if len(m.GroupLabels) > 0 {
+		for k := range m.GroupLabels {
+			v := m.GroupLabels[k]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintAlerts(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(k)
+			copy(dAtA[i:], k)
+			i = encodeVarintAlerts(dAtA, i, uint64(len(k)))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintAlerts(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if len(m.Integration) > 0 {
+		i -= len(m.Integration)
+		copy(dAtA[i:], m.Integration)
+		i = encodeVarintAlerts(dAtA, i, uint64(len(m.Integration)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.Receiver) > 0 {
+		i -= len(m.Receiver)
+		copy(dAtA[i:], m.Receiver)
+		i = encodeVarintAlerts(dAtA, i, uint64(len(m.Receiver)))
+		i--
+		dAtA[i] = 0x12
+	}
+	n2, err2 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.Timestamp, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.Timestamp):])
+	if err2 != nil {
+		return 0, err2
+	}
+	i -= n2
+	i = encodeVarintAlerts(dAtA, i, uint64(n2))
+	i--
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintAlerts(dAtA []byte, offset int, v uint64) int {
 	offset -= sovAlerts(v)
 	base := offset
@@ -502,6 +816,53 @@ func (m *FullStateDesc) Size() (n int) {
 		l = m.State.Size()
 		n += 1 + l + sovAlerts(uint64(l))
 	}
+	if len(m.Notifications) > 0 {
+		for _, e := range m.Notifications {
+			l = e.Size()
+			n += 1 + l + sovAlerts(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *NotificationDesc) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = github_com_gogo_protobuf_types.SizeOfStdTime(m.Timestamp)
+	n += 1 + l + sovAlerts(uint64(l))
+	l = len(m.Receiver)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	l = len(m.Integration)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	if len(m.GroupLabels) > 0 {
+		for k, v := range m.GroupLabels {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovAlerts(uint64(len(k))) + 1 + len(v) + sovAlerts(uint64(len(v)))
+			n += mapEntrySize + 1 + sovAlerts(uint64(mapEntrySize))
+		}
+	}
+	if m.FiringAlerts != 0 {
+		n += 1 + sovAlerts(uint64(m.FiringAlerts))
+	}
+	if m.ResolvedAlerts != 0 {
+		n += 1 + sovAlerts(uint64(m.ResolvedAlerts))
+	}
+	l = len(m.Outcome)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	l = len(m.Error)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
 	return n
 }
 
@@ -543,8 +904,41 @@ func (this *FullStateDesc) String() string {
 	if this == nil {
 		return "nil"
 	}
+	repeatedStringForNotifications := "[]*NotificationDesc{"
+	for _, f := range this.Notifications {
+		repeatedStringForNotifications += strings.Replace(f.String(), "NotificationDesc", "NotificationDesc", 1) + ","
+	}
+	repeatedStringForNotifications += "}"
 	s := strings.Join([]string{`&FullStateDesc{`,
 		`State:` + strings.Replace(fmt.Sprintf("%v", this.State), "FullState", "clusterpb.FullState", 1) + `,`,
+		`Notifications:` + repeatedStringForNotifications + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *NotificationDesc) String() string {
+	if this == nil {
+		return "nil"
+	}
+	keysForGroupLabels := make([]string, 0, len(this.GroupLabels))
+	for k, _ := range this.GroupLabels {
+		keysForGroupLabels = append(keysForGroupLabels, k)
+	}
+	github_com_gogo_protobuf_sortkeys.Strings(keysForGroupLabels)
+	mapStringForGroupLabels := "map[string]string{"
+	for _, k := range keysForGroupLabels {
+		mapStringForGroupLabels += fmt.Sprintf("%v: %v,", k, this.GroupLabels[k])
+	}
+	mapStringForGroupLabels += "}"
+	s := strings.Join([]string{`&NotificationDesc{`,
+		`Timestamp:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.Timestamp), "Timestamp", "timestamp.Timestamp", 1), `&`, ``, 1) + `,`,
+		`Receiver:` + fmt.Sprintf("%v", this.Receiver) + `,`,
+		`Integration:` + fmt.Sprintf("%v", this.Integration) + `,`,
+		`GroupLabels:` + mapStringForGroupLabels + `,`,
+		`FiringAlerts:` + fmt.Sprintf("%v", this.FiringAlerts) + `,`,
+		`ResolvedAlerts:` + fmt.Sprintf("%v", this.ResolvedAlerts) + `,`,
+		`Outcome:` + fmt.Sprintf("%v", this.Outcome) + `,`,
+		`Error:` + fmt.Sprintf("%v", this.Error) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -690,10 +1084,7 @@ func (m *AlertConfigDesc) Unmarshal(dAtA []byte) error {
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthAlerts
-			}
-			if (iNdEx + skippy) < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthAlerts
 			}
 			if (iNdEx + skippy) > l {
@@ -807,10 +1198,7 @@ func (m *TemplateDesc) Unmarshal(dAtA []byte) error {
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthAlerts
-			}
-			if (iNdEx + skippy) < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthAlerts
 			}
 			if (iNdEx + skippy) > l {
@@ -890,16 +1278,47 @@ func (m *FullStateDesc) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Notifications", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Notifications = append(m.Notifications, &NotificationDesc{})
+			if err := m.Notifications[len(m.Notifications)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipAlerts(dAtA[iNdEx:])
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthAlerts
-			}
-			if (iNdEx + skippy) < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthAlerts
 			}
 			if (iNdEx + skippy) > l {
@@ -914,113 +1333,463 @@ func (m *FullStateDesc) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func skipAlerts(dAtA []byte) (n int, err error) {
+func (m *NotificationDesc) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
+		preIndex := iNdEx
 		var wire uint64
 		for shift := uint(0); ; shift += 7 {
 			if shift >= 64 {
-				return 0, ErrIntOverflowAlerts
+				return ErrIntOverflowAlerts
 			}
 			if iNdEx >= l {
-				return 0, io.ErrUnexpectedEOF
+				return io.ErrUnexpectedEOF
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
 		}
+		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
-		switch wireType {
-		case 0:
+		if wireType == 4 {
+			return fmt.Errorf("proto: NotificationDesc: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: NotificationDesc: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
-					return 0, ErrIntOverflowAlerts
+					return ErrIntOverflowAlerts
 				}
 				if iNdEx >= l {
-					return 0, io.ErrUnexpectedEOF
+					return io.ErrUnexpectedEOF
 				}
+				b := dAtA[iNdEx]
 				iNdEx++
-				if dAtA[iNdEx-1] < 0x80 {
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
 					break
 				}
 			}
-			return iNdEx, nil
-		case 1:
-			iNdEx += 8
-			return iNdEx, nil
+			if msglen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdTimeUnmarshal(&m.Timestamp, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		case 2:
-			var length int
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Receiver", wireType)
+			}
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
-					return 0, ErrIntOverflowAlerts
+					return ErrIntOverflowAlerts
 				}
 				if iNdEx >= l {
-					return 0, io.ErrUnexpectedEOF
+					return io.ErrUnexpectedEOF
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				length |= (int(b) & 0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if length < 0 {
-				return 0, ErrInvalidLengthAlerts
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAlerts
 			}
-			iNdEx += length
-			if iNdEx < 0 {
-				return 0, ErrInvalidLengthAlerts
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
 			}
-			return iNdEx, nil
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Receiver = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 3:
-			for {
-				var innerWire uint64
-				var start int = iNdEx
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Integration", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Integration = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupLabels", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.GroupLabels == nil {
+				m.GroupLabels = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
 				for shift := uint(0); ; shift += 7 {
 					if shift >= 64 {
-						return 0, ErrIntOverflowAlerts
+						return ErrIntOverflowAlerts
 					}
 					if iNdEx >= l {
-						return 0, io.ErrUnexpectedEOF
+						return io.ErrUnexpectedEOF
 					}
 					b := dAtA[iNdEx]
 					iNdEx++
-					innerWire |= (uint64(b) & 0x7F) << shift
+					wire |= uint64(b&0x7F) << shift
 					if b < 0x80 {
 						break
 					}
 				}
-				innerWireType := int(innerWire & 0x7)
-				if innerWireType == 4 {
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowAlerts
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthAlerts
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthAlerts
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowAlerts
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthAlerts
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthAlerts
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipAlerts(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if (skippy < 0) || (iNdEx+skippy) < 0 {
+						return ErrInvalidLengthAlerts
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.GroupLabels[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FiringAlerts", wireType)
+			}
+			m.FiringAlerts = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FiringAlerts |= int64(b&0x7F) << shift
+				if b < 0x80 {
 					break
 				}
-				next, err := skipAlerts(dAtA[start:])
-				if err != nil {
-					return 0, err
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResolvedAlerts", wireType)
+			}
+			m.ResolvedAlerts = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
 				}
-				iNdEx = start + next
-				if iNdEx < 0 {
-					return 0, ErrInvalidLengthAlerts
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ResolvedAlerts |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
 				}
 			}
-			return iNdEx, nil
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Outcome", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Outcome = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Error", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Error = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAlerts(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipAlerts(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowAlerts
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthAlerts
+			}
+			iNdEx += length
+		case 3:
+			depth++
 		case 4:
-			return iNdEx, nil
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupAlerts
+			}
+			depth--
 		case 5:
 			iNdEx += 4
-			return iNdEx, nil
 		default:
 			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
 		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthAlerts
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
 	}
-	panic("unreachable")
+	return 0, io.ErrUnexpectedEOF
 }
 
 var (
-	ErrInvalidLengthAlerts = fmt.Errorf("proto: negative length found during unmarshaling")
-	ErrIntOverflowAlerts   = fmt.Errorf("proto: integer overflow")
+	ErrInvalidLengthAlerts        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowAlerts          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupAlerts = fmt.Errorf("proto: unexpected end of group")
 )
@@ -0,0 +1,586 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	"github.com/grafana/mimir/pkg/util"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+const (
+	globalKey            = "global"
+	receiversKey         = "receivers"
+	muteTimeIntervalsKey = "mute_time_intervals"
+	routeKey             = "route"
+	templatesKey         = "templates"
+	nameKey              = "name"
+)
+
+var errConfigObjectNotFound = errors.New("not found")
+
+const (
+	errConfigVersionRequired = "the If-Match header must be set to the version of the Alertmanager configuration, given in the ETag header of the responses"
+	errConfigVersionMismatch = "the Alertmanager configuration has been changed since the version in the If-Match header"
+)
+
+// configObjects is the configuration of a tenant, whose objects (receivers, route, mute time intervals
+// and templates) are managed separately through the configuration objects API.
+//
+// The Alertmanager configuration is edited as a YAML document, rather than being unmarshalled to config.Config,
+// so that the secrets and the parts of the configuration which aren't edited are kept as they are.
+//
+// The version of the configuration is returned in the ETag header of the responses, and must be passed in
+// the If-Match header of the requests changing the configuration, so that a change based on an outdated
+// configuration is rejected. The alert store has no conditional writes, so the version is compared with
+// the stored configuration right before storing the change, and only changes stored at the same time
+// may overwrite each other.
+type configObjects struct {
+	doc       *yaml.Node
+	templates map[string]string
+	version   string
+}
+
+func parseConfigObjects(cfg alertspb.AlertConfigDesc) (*configObjects, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(cfg.RawConfig), doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("the Alertmanager config is not a YAML mapping")
+	}
+
+	return &configObjects{doc: doc, templates: alertspb.ParseTemplates(cfg), version: configVersion(cfg)}, nil
+}
+
+// configVersion returns the version of the configuration, as an entity tag.
+func configVersion(cfg alertspb.AlertConfigDesc) string {
+	templates := make([]*alertspb.TemplateDesc, len(cfg.Templates))
+	copy(templates, cfg.Templates)
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Filename < templates[j].Filename
+	})
+
+	h := sha256.New()
+	_, _ = io.WriteString(h, cfg.RawConfig)
+	for _, t := range templates {
+		// The lengths prevent different configurations from having the same content once concatenated.
+		_, _ = fmt.Fprintf(h, "\x00%d:%s%d:%s", len(t.Filename), t.Filename, len(t.Body), t.Body)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// toProto returns the configuration of the user, assembled from its objects.
+func (c *configObjects) toProto(userID string) (alertspb.AlertConfigDesc, error) {
+	rawConfig, err := marshalYAMLNode(c.doc)
+	if err != nil {
+		return alertspb.AlertConfigDesc{}, err
+	}
+	return alertspb.ToProto(string(rawConfig), c.templates, userID), nil
+}
+
+// value returns the value of the key at the top level of the configuration, or nil if it's not set.
+func (c *configObjects) value(key string) *yaml.Node {
+	return util.YAMLMappingValue(c.doc.Content[0], key)
+}
+
+// setValue sets the value of the key at the top level of the configuration.
+func (c *configObjects) setValue(key string, value *yaml.Node) {
+	util.SetYAMLMappingValue(c.doc.Content[0], key, value)
+}
+
+// removeValue removes the key from the top level of the configuration.
+func (c *configObjects) removeValue(key string) {
+	util.RemoveYAMLMappingKey(c.doc.Content[0], key)
+}
+
+// list returns the objects of the list at the key at the top level of the configuration.
+func (c *configObjects) list(key string) []*yaml.Node {
+	if v := c.value(key); v != nil && v.Kind == yaml.SequenceNode {
+		return v.Content
+	}
+	return nil
+}
+
+// namedObject returns the object with the name in the list at the key, or nil if there's none.
+func (c *configObjects) namedObject(key, name string) *yaml.Node {
+	for _, obj := range c.list(key) {
+		if objectName(obj) == name {
+			return obj
+		}
+	}
+	return nil
+}
+
+// setNamedObject replaces the object with the name of obj in the list at the key, or adds it to the list.
+func (c *configObjects) setNamedObject(key string, obj *yaml.Node) {
+	objects := c.list(key)
+	for i, o := range objects {
+		if objectName(o) == objectName(obj) {
+			objects[i] = obj
+			return
+		}
+	}
+	c.setValue(key, &yaml.Node{Kind: yaml.SequenceNode, Content: append(objects, obj)})
+}
+
+// deleteNamedObject deletes the object with the name from the list at the key.
+func (c *configObjects) deleteNamedObject(key, name string) error {
+	objects := c.list(key)
+	for i, o := range objects {
+		if objectName(o) != name {
+			continue
+		}
+
+		objects = append(objects[:i], objects[i+1:]...)
+		if len(objects) == 0 {
+			c.removeValue(key)
+		} else {
+			c.setValue(key, &yaml.Node{Kind: yaml.SequenceNode, Content: objects})
+		}
+		return nil
+	}
+	return errConfigObjectNotFound
+}
+
+// setTemplate sets the template, and references it in the configuration unless it's already matched
+// by one of the templates referenced by the configuration.
+func (c *configObjects) setTemplate(name, body string) {
+	if c.templates == nil {
+		c.templates = map[string]string{}
+	}
+	c.templates[name] = body
+
+	for _, t := range c.list(templatesKey) {
+		if matched, err := filepath.Match(t.Value, name); err == nil && matched {
+			return
+		}
+	}
+	c.setValue(templatesKey, &yaml.Node{Kind: yaml.SequenceNode, Content: append(c.list(templatesKey), &yaml.Node{Kind: yaml.ScalarNode, Value: name})})
+}
+
+// deleteTemplate deletes the template, and its reference from the configuration.
+func (c *configObjects) deleteTemplate(name string) error {
+	if _, ok := c.templates[name]; !ok {
+		return errConfigObjectNotFound
+	}
+	delete(c.templates, name)
+
+	var references []*yaml.Node
+	for _, t := range c.list(templatesKey) {
+		if t.Value != name {
+			references = append(references, t)
+		}
+	}
+	if len(references) == 0 {
+		c.removeValue(templatesKey)
+	} else {
+		c.setValue(templatesKey, &yaml.Node{Kind: yaml.SequenceNode, Content: references})
+	}
+	return nil
+}
+
+func objectName(obj *yaml.Node) string {
+	if v := util.YAMLMappingValue(obj, nameKey); v != nil {
+		return v.Value
+	}
+	return ""
+}
+
+func marshalYAMLNode(n *yaml.Node) ([]byte, error) {
+	buf := bytes.Buffer{}
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseConfigObject parses a configuration object, which must be a YAML mapping.
+func parseConfigObject(payload []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(payload, doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("the object is not a YAML mapping")
+	}
+	return doc.Content[0], nil
+}
+
+// parseNamedConfigObject parses a configuration object whose name is given by the URL of the request.
+// The name of the object is set to it if the object has none.
+func parseNamedConfigObject(payload []byte, name string) (*yaml.Node, error) {
+	obj, err := parseConfigObject(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if v := util.YAMLMappingValue(obj, nameKey); v == nil {
+		obj.Content = append([]*yaml.Node{{Kind: yaml.ScalarNode, Value: nameKey}, {Kind: yaml.ScalarNode, Value: name}}, obj.Content...)
+	} else if v.Value != name {
+		return nil, fmt.Errorf("the name of the object %q doesn't match the name in the URL %q", v.Value, name)
+	}
+	return obj, nil
+}
+
+// readConfigPayload reads the body of the request, up to the maximum size of the configuration of the tenant.
+func readConfigPayload(r *http.Request, maxConfigSize int) ([]byte, error) {
+	var input io.Reader
+	if maxConfigSize > 0 {
+		// LimitReader will return EOF after reading specified number of bytes. To check if
+		// we have read too many bytes, allow one extra byte.
+		input = io.LimitReader(r.Body, int64(maxConfigSize)+1)
+	} else {
+		input = r.Body
+	}
+	return ioutil.ReadAll(input)
+}
+
+// loadConfigObjects loads the configuration of the tenant, or the fallback configuration if the tenant has none.
+func (am *MultitenantAlertmanager) loadConfigObjects(ctx context.Context, userID string) (*configObjects, error) {
+	cfg, err := am.store.GetAlertConfig(ctx, userID)
+	if errors.Is(err, alertspb.ErrNotFound) {
+		cfg = alertspb.ToProto(am.fallbackConfig, nil, userID)
+	} else if err != nil {
+		return nil, err
+	}
+	return parseConfigObjects(cfg)
+}
+
+// getConfigObject writes the configuration object returned by get for the configuration of the tenant.
+func (am *MultitenantAlertmanager) getConfigObject(w http.ResponseWriter, r *http.Request, get func(*configObjects) (*yaml.Node, error)) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	cfg, err := am.loadConfigObjects(r.Context(), userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	obj, err := get(cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	d, err := marshalYAMLNode(obj)
+	if err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err, "user", userID)
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("ETag", cfg.version)
+	if _, err := w.Write(d); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// updateConfigObjects applies update to the configuration of the tenant, given the body of the request,
+// and stores the resulting configuration if it's valid. The request must have an If-Match header, and the
+// configuration is only changed if its version matches.
+func (am *MultitenantAlertmanager) updateConfigObjects(w http.ResponseWriter, r *http.Request, successStatus int, update func(cfg *configObjects, payload []byte) error) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	match := r.Header.Get("If-Match")
+	if match == "" || match == "*" {
+		http.Error(w, errConfigVersionRequired, http.StatusPreconditionRequired)
+		return
+	}
+
+	maxConfigSize := am.limits.AlertmanagerMaxConfigSize(userID)
+	payload, err := readConfigPayload(r, maxConfigSize)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := am.loadConfigObjects(r.Context(), userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	if match != cfg.version {
+		http.Error(w, errConfigVersionMismatch, http.StatusPreconditionFailed)
+		return
+	}
+
+	if err := update(cfg, payload); err != nil {
+		if errors.Is(err, errConfigObjectNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		level.Warn(logger).Log("msg", errValidatingConfig, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errValidatingConfig, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	cfgDesc, err := cfg.toProto(userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err, "user", userID)
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// The size of the configuration is checked as a whole, like when it's set at once.
+	if size := configSize(cfgDesc); maxConfigSize > 0 && size > maxConfigSize {
+		msg := fmt.Sprintf(errConfigurationTooBig, maxConfigSize)
+		level.Warn(logger).Log("msg", msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := validateUserConfig(logger, cfgDesc, am.limits, userID); err != nil {
+		level.Warn(logger).Log("msg", errValidatingConfig, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errValidatingConfig, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	// Check the version again, in case the configuration has been changed while the change was validated.
+	stored, err := am.loadConfigObjects(r.Context(), userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if match != stored.version {
+		http.Error(w, errConfigVersionMismatch, http.StatusPreconditionFailed)
+		return
+	}
+
+	if err := am.store.SetAlertConfig(r.Context(), cfgDesc); err != nil {
+		level.Error(logger).Log("msg", errStoringConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errStoringConfiguration, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", configVersion(cfgDesc))
+	w.WriteHeader(successStatus)
+}
+
+// configSize returns the size of the configuration, once marshalled like in the requests setting it at once.
+func configSize(cfg alertspb.AlertConfigDesc) int {
+	size := len(cfg.RawConfig)
+	for _, t := range cfg.Templates {
+		size += len(t.Filename) + len(t.Body)
+	}
+	return size
+}
+
+func (am *MultitenantAlertmanager) listNamedConfigObjects(w http.ResponseWriter, r *http.Request, key string) {
+	am.getConfigObject(w, r, func(cfg *configObjects) (*yaml.Node, error) {
+		return &yaml.Node{Kind: yaml.SequenceNode, Content: cfg.list(key)}, nil
+	})
+}
+
+func (am *MultitenantAlertmanager) getNamedConfigObject(w http.ResponseWriter, r *http.Request, key, kind string) {
+	name := mux.Vars(r)["name"]
+	am.getConfigObject(w, r, func(cfg *configObjects) (*yaml.Node, error) {
+		if obj := cfg.namedObject(key, name); obj != nil {
+			return obj, nil
+		}
+		return nil, fmt.Errorf("%s %q not found", kind, name)
+	})
+}
+
+func (am *MultitenantAlertmanager) setNamedConfigObject(w http.ResponseWriter, r *http.Request, key string) {
+	name := mux.Vars(r)["name"]
+	am.updateConfigObjects(w, r, http.StatusCreated, func(cfg *configObjects, payload []byte) error {
+		obj, err := parseNamedConfigObject(payload, name)
+		if err != nil {
+			return err
+		}
+		cfg.setNamedObject(key, obj)
+		return nil
+	})
+}
+
+func (am *MultitenantAlertmanager) deleteNamedConfigObject(w http.ResponseWriter, r *http.Request, key, kind string) {
+	name := mux.Vars(r)["name"]
+	am.updateConfigObjects(w, r, http.StatusOK, func(cfg *configObjects, _ []byte) error {
+		if err := cfg.deleteNamedObject(key, name); err != nil {
+			return errors.Wrapf(err, "%s %q", kind, name)
+		}
+		return nil
+	})
+}
+
+// ListReceivers lists the receivers of the configuration of the tenant.
+func (am *MultitenantAlertmanager) ListReceivers(w http.ResponseWriter, r *http.Request) {
+	am.listNamedConfigObjects(w, r, receiversKey)
+}
+
+// GetReceiver returns a receiver of the configuration of the tenant.
+func (am *MultitenantAlertmanager) GetReceiver(w http.ResponseWriter, r *http.Request) {
+	am.getNamedConfigObject(w, r, receiversKey, "receiver")
+}
+
+// SetReceiver creates or replaces a receiver of the configuration of the tenant.
+func (am *MultitenantAlertmanager) SetReceiver(w http.ResponseWriter, r *http.Request) {
+	am.setNamedConfigObject(w, r, receiversKey)
+}
+
+// DeleteReceiver deletes a receiver of the configuration of the tenant.
+func (am *MultitenantAlertmanager) DeleteReceiver(w http.ResponseWriter, r *http.Request) {
+	am.deleteNamedConfigObject(w, r, receiversKey, "receiver")
+}
+
+// ListMuteTimeIntervals lists the mute time intervals of the configuration of the tenant.
+func (am *MultitenantAlertmanager) ListMuteTimeIntervals(w http.ResponseWriter, r *http.Request) {
+	am.listNamedConfigObjects(w, r, muteTimeIntervalsKey)
+}
+
+// GetMuteTimeInterval returns a mute time interval of the configuration of the tenant.
+func (am *MultitenantAlertmanager) GetMuteTimeInterval(w http.ResponseWriter, r *http.Request) {
+	am.getNamedConfigObject(w, r, muteTimeIntervalsKey, "mute time interval")
+}
+
+// SetMuteTimeInterval creates or replaces a mute time interval of the configuration of the tenant.
+func (am *MultitenantAlertmanager) SetMuteTimeInterval(w http.ResponseWriter, r *http.Request) {
+	am.setNamedConfigObject(w, r, muteTimeIntervalsKey)
+}
+
+// DeleteMuteTimeInterval deletes a mute time interval of the configuration of the tenant.
+func (am *MultitenantAlertmanager) DeleteMuteTimeInterval(w http.ResponseWriter, r *http.Request) {
+	am.deleteNamedConfigObject(w, r, muteTimeIntervalsKey, "mute time interval")
+}
+
+// GetRoute returns the routing tree of the configuration of the tenant.
+func (am *MultitenantAlertmanager) GetRoute(w http.ResponseWriter, r *http.Request) {
+	am.getConfigObject(w, r, func(cfg *configObjects) (*yaml.Node, error) {
+		if route := cfg.value(routeKey); route != nil {
+			return route, nil
+		}
+		return nil, errors.New("route not found")
+	})
+}
+
+// SetRoute replaces the routing tree of the configuration of the tenant.
+func (am *MultitenantAlertmanager) SetRoute(w http.ResponseWriter, r *http.Request) {
+	am.updateConfigObjects(w, r, http.StatusCreated, func(cfg *configObjects, payload []byte) error {
+		route, err := parseConfigObject(payload)
+		if err != nil {
+			return err
+		}
+		cfg.setValue(routeKey, route)
+		return nil
+	})
+}
+
+// ListTemplates returns the templates of the configuration of the tenant, by file name.
+func (am *MultitenantAlertmanager) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	am.getConfigObject(w, r, func(cfg *configObjects) (*yaml.Node, error) {
+		names := make([]string, 0, len(cfg.templates))
+		for name := range cfg.templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		templates := &yaml.Node{Kind: yaml.MappingNode}
+		for _, name := range names {
+			templates.Content = append(templates.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: name},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: cfg.templates[name]},
+			)
+		}
+		return templates, nil
+	})
+}
+
+// GetTemplate returns the body of a template of the configuration of the tenant.
+func (am *MultitenantAlertmanager) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	cfg, err := am.loadConfigObjects(r.Context(), userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	body, ok := cfg.templates[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("template %q not found", name), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := w.Write([]byte(body)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// SetTemplate creates or replaces a template of the configuration of the tenant, given its body.
+// The template is referenced by the configuration, unless it's already matched by one of its templates.
+func (am *MultitenantAlertmanager) SetTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	am.updateConfigObjects(w, r, http.StatusCreated, func(cfg *configObjects, payload []byte) error {
+		if err := validateTemplateFilename(name); err != nil {
+			return err
+		}
+		cfg.setTemplate(name, string(payload))
+		return nil
+	})
+}
+
+// DeleteTemplate deletes a template of the configuration of the tenant, and its reference from the configuration.
+func (am *MultitenantAlertmanager) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	am.updateConfigObjects(w, r, http.StatusOK, func(cfg *configObjects, _ []byte) error {
+		if err := cfg.deleteTemplate(name); err != nil {
+			return errors.Wrapf(err, "template %q", name)
+		}
+		return nil
+	})
+}
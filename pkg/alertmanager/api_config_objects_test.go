@@ -0,0 +1,333 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+const configObjectsTestFallbackConfig = `# The fallback config.
+route:
+  receiver: default
+receivers:
+  - name: default
+`
+
+func newConfigObjectsTestRouter(am *MultitenantAlertmanager) *mux.Router {
+	router := mux.NewRouter()
+	router.Path("/api/v1/alerts/receivers").Methods(http.MethodGet).HandlerFunc(am.ListReceivers)
+	router.Path("/api/v1/alerts/receivers/{name}").Methods(http.MethodGet).HandlerFunc(am.GetReceiver)
+	router.Path("/api/v1/alerts/receivers/{name}").Methods(http.MethodPut).HandlerFunc(am.SetReceiver)
+	router.Path("/api/v1/alerts/receivers/{name}").Methods(http.MethodDelete).HandlerFunc(am.DeleteReceiver)
+	router.Path("/api/v1/alerts/route").Methods(http.MethodGet).HandlerFunc(am.GetRoute)
+	router.Path("/api/v1/alerts/route").Methods(http.MethodPut).HandlerFunc(am.SetRoute)
+	router.Path("/api/v1/alerts/mute_time_intervals").Methods(http.MethodGet).HandlerFunc(am.ListMuteTimeIntervals)
+	router.Path("/api/v1/alerts/mute_time_intervals/{name}").Methods(http.MethodGet).HandlerFunc(am.GetMuteTimeInterval)
+	router.Path("/api/v1/alerts/mute_time_intervals/{name}").Methods(http.MethodPut).HandlerFunc(am.SetMuteTimeInterval)
+	router.Path("/api/v1/alerts/mute_time_intervals/{name}").Methods(http.MethodDelete).HandlerFunc(am.DeleteMuteTimeInterval)
+	router.Path("/api/v1/alerts/templates").Methods(http.MethodGet).HandlerFunc(am.ListTemplates)
+	router.Path("/api/v1/alerts/templates/{name}").Methods(http.MethodGet).HandlerFunc(am.GetTemplate)
+	router.Path("/api/v1/alerts/templates/{name}").Methods(http.MethodPut).HandlerFunc(am.SetTemplate)
+	router.Path("/api/v1/alerts/templates/{name}").Methods(http.MethodDelete).HandlerFunc(am.DeleteTemplate)
+	return router
+}
+
+func TestMultitenantAlertmanager_ConfigObjectsAPI(t *testing.T) {
+	store := prepareInMemoryAlertStore()
+	limits := &mockAlertManagerLimits{}
+	am := &MultitenantAlertmanager{
+		store:          store,
+		fallbackConfig: configObjectsTestFallbackConfig,
+		logger:         util_log.Logger,
+		limits:         limits,
+	}
+	router := newConfigObjectsTestRouter(am)
+
+	currentVersion := func() string {
+		req := httptest.NewRequest(http.MethodGet, "http://alertmanager/api/v1/alerts/receivers", nil)
+		req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Header().Get("ETag")
+	}
+
+	// do sends the request, passing the current version of the config in the If-Match header of the changes.
+	do := func(method, path, body string) (int, string) {
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, "http://alertmanager"+path, reader)
+		req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+		if method != http.MethodGet {
+			req.Header.Set("If-Match", currentVersion())
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		resp := w.Result()
+		respBody, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(respBody)
+	}
+
+	storedConfig := func() *config.Config {
+		cfg, err := store.GetAlertConfig(context.Background(), "user-1")
+		require.NoError(t, err)
+		amCfg, err := config.Load(cfg.RawConfig)
+		require.NoError(t, err)
+		return amCfg
+	}
+
+	t.Run("tenant without config reads the fallback config", func(t *testing.T) {
+		status, body := do(http.MethodGet, "/api/v1/alerts/receivers", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "- name: default\n", body)
+
+		status, _ = do(http.MethodGet, "/api/v1/alerts/receivers/team-a", "")
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, body = do(http.MethodGet, "/api/v1/alerts/mute_time_intervals", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "[]\n", body)
+	})
+
+	t.Run("set receivers on top of the fallback config", func(t *testing.T) {
+		status, body := do(http.MethodPut, "/api/v1/alerts/receivers/team-a", `
+webhook_configs:
+  - url: http://team-a.example.com/
+    http_config:
+      basic_auth:
+        username: team-a
+        password: secret
+`)
+		require.Equal(t, http.StatusCreated, status, body)
+
+		status, body = do(http.MethodPut, "/api/v1/alerts/receivers/team-b", `
+name: team-b
+webhook_configs:
+  - url: http://team-b.example.com/
+`)
+		require.Equal(t, http.StatusCreated, status, body)
+
+		cfg, err := store.GetAlertConfig(context.Background(), "user-1")
+		require.NoError(t, err)
+		// The parts of the config which aren't edited are kept, along with the secrets.
+		assert.True(t, strings.HasPrefix(cfg.RawConfig, "# The fallback config.\n"))
+		assert.Contains(t, cfg.RawConfig, "password: secret")
+
+		amCfg := storedConfig()
+		require.Len(t, amCfg.Receivers, 3)
+		assert.Equal(t, "default", amCfg.Receivers[0].Name)
+		assert.Equal(t, "team-a", amCfg.Receivers[1].Name)
+		assert.Equal(t, "team-b", amCfg.Receivers[2].Name)
+
+		status, body = do(http.MethodGet, "/api/v1/alerts/receivers/team-b", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "name: team-b\nwebhook_configs:\n  - url: http://team-b.example.com/\n", body)
+	})
+
+	t.Run("replace a receiver", func(t *testing.T) {
+		status, body := do(http.MethodPut, "/api/v1/alerts/receivers/team-b", `
+webhook_configs:
+  - url: http://team-b.example.com/new
+`)
+		require.Equal(t, http.StatusCreated, status, body)
+
+		amCfg := storedConfig()
+		require.Len(t, amCfg.Receivers, 3)
+		assert.Equal(t, "http://team-b.example.com/new", amCfg.Receivers[2].WebhookConfigs[0].URL.String())
+		assert.Equal(t, "http://team-a.example.com/", amCfg.Receivers[1].WebhookConfigs[0].URL.String())
+	})
+
+	t.Run("invalid objects are rejected", func(t *testing.T) {
+		status, body := do(http.MethodPut, "/api/v1/alerts/receivers/team-c", `
+name: team-d
+`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, `the name of the object "team-d" doesn't match the name in the URL "team-c"`)
+
+		status, body = do(http.MethodPut, "/api/v1/alerts/receivers/team-c", `
+webhook_configs:
+  - url: http://team-c.example.com/
+    http_config:
+      basic_auth:
+        username: team-c
+        password_file: /etc/passwd
+`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, errPasswordFileNotAllowed.Error())
+
+		status, body = do(http.MethodPut, "/api/v1/alerts/route", `
+receiver: unknown
+`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, `undefined receiver "unknown"`)
+
+		status, _ = do(http.MethodPut, "/api/v1/alerts/route", "- receiver: default")
+		assert.Equal(t, http.StatusBadRequest, status)
+
+		assert.Len(t, storedConfig().Receivers, 3)
+	})
+
+	t.Run("set the route and the mute time intervals", func(t *testing.T) {
+		status, body := do(http.MethodPut, "/api/v1/alerts/mute_time_intervals/weekends", `
+time_intervals:
+  - weekdays: ['saturday', 'sunday']
+`)
+		require.Equal(t, http.StatusCreated, status, body)
+
+		status, body = do(http.MethodPut, "/api/v1/alerts/route", `
+receiver: default
+routes:
+  - receiver: team-a
+    matchers: ['team="a"']
+    mute_time_intervals: [weekends]
+  - receiver: team-b
+    matchers: ['team="b"']
+`)
+		require.Equal(t, http.StatusCreated, status, body)
+
+		amCfg := storedConfig()
+		require.Len(t, amCfg.MuteTimeIntervals, 1)
+		assert.Equal(t, "weekends", amCfg.MuteTimeIntervals[0].Name)
+		require.Len(t, amCfg.Route.Routes, 2)
+		assert.Equal(t, "team-a", amCfg.Route.Routes[0].Receiver)
+		assert.Equal(t, []string{"weekends"}, amCfg.Route.Routes[0].MuteTimeIntervals)
+
+		status, body = do(http.MethodGet, "/api/v1/alerts/route", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "receiver: team-b")
+
+		// Receivers and mute time intervals which are in use can't be deleted.
+		status, _ = do(http.MethodDelete, "/api/v1/alerts/receivers/team-a", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		status, _ = do(http.MethodDelete, "/api/v1/alerts/mute_time_intervals/weekends", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("set and delete templates", func(t *testing.T) {
+		status, body := do(http.MethodPut, "/api/v1/alerts/templates/team-a.tmpl", `{{ define "team-a.title" }}Team A{{ end }}`)
+		require.Equal(t, http.StatusCreated, status, body)
+
+		status, body = do(http.MethodPut, "/api/v1/alerts/templates/invalid.tmpl", `{{ define "invalid" }}`)
+		assert.Equal(t, http.StatusBadRequest, status, body)
+
+		limits.maxTemplatesCount = 1
+		status, body = do(http.MethodPut, "/api/v1/alerts/templates/team-b.tmpl", `{{ define "team-b.title" }}Team B{{ end }}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, "too many templates in the configuration: 2 (limit: 1)")
+		limits.maxTemplatesCount = 0
+
+		cfg, err := store.GetAlertConfig(context.Background(), "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"team-a.tmpl": `{{ define "team-a.title" }}Team A{{ end }}`}, alertspb.ParseTemplates(cfg))
+		assert.Equal(t, []string{"team-a.tmpl"}, storedConfig().Templates)
+
+		status, body = do(http.MethodGet, "/api/v1/alerts/templates/team-a.tmpl", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `{{ define "team-a.title" }}Team A{{ end }}`, body)
+
+		status, body = do(http.MethodGet, "/api/v1/alerts/templates", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "team-a.tmpl: '{{ define \"team-a.title\" }}Team A{{ end }}'\n", body)
+
+		status, _ = do(http.MethodDelete, "/api/v1/alerts/templates/team-a.tmpl", "")
+		require.Equal(t, http.StatusOK, status)
+		status, _ = do(http.MethodDelete, "/api/v1/alerts/templates/team-a.tmpl", "")
+		assert.Equal(t, http.StatusNotFound, status)
+
+		cfg, err = store.GetAlertConfig(context.Background(), "user-1")
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Templates)
+		assert.Empty(t, storedConfig().Templates)
+	})
+
+	t.Run("delete objects", func(t *testing.T) {
+		status, body := do(http.MethodPut, "/api/v1/alerts/route", "receiver: default\n")
+		require.Equal(t, http.StatusCreated, status, body)
+
+		status, _ = do(http.MethodDelete, "/api/v1/alerts/receivers/team-a", "")
+		require.Equal(t, http.StatusOK, status)
+		status, _ = do(http.MethodDelete, "/api/v1/alerts/receivers/team-a", "")
+		assert.Equal(t, http.StatusNotFound, status)
+		status, _ = do(http.MethodDelete, "/api/v1/alerts/mute_time_intervals/weekends", "")
+		require.Equal(t, http.StatusOK, status)
+
+		amCfg := storedConfig()
+		require.Len(t, amCfg.Receivers, 2)
+		assert.Equal(t, "team-b", amCfg.Receivers[1].Name)
+		assert.Empty(t, amCfg.MuteTimeIntervals)
+	})
+
+	t.Run("the size of the assembled config is limited", func(t *testing.T) {
+		cfg, err := store.GetAlertConfig(context.Background(), "user-1")
+		require.NoError(t, err)
+		limits.maxConfigSize = len(cfg.RawConfig) + 10
+		defer func() { limits.maxConfigSize = 0 }()
+
+		status, body := do(http.MethodPut, "/api/v1/alerts/receivers/team-c", "name: team-c\n")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, "Alertmanager configuration is too big")
+	})
+	t.Run("changes based on an outdated config are rejected", func(t *testing.T) {
+		doWithVersion := func(method, path, body, ifMatch string) (int, string) {
+			var reader io.Reader
+			if body != "" {
+				reader = strings.NewReader(body)
+			}
+			req := httptest.NewRequest(method, "http://alertmanager"+path, reader)
+			req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+			if ifMatch != "" {
+				req.Header.Set("If-Match", ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code, w.Header().Get("ETag")
+		}
+
+		status, version := doWithVersion(http.MethodGet, "/api/v1/alerts/receivers", "", "")
+		require.Equal(t, http.StatusOK, status)
+		require.NotEmpty(t, version)
+
+		// Reading the config doesn't change its version.
+		_, sameVersion := doWithVersion(http.MethodGet, "/api/v1/alerts/route", "", "")
+		assert.Equal(t, version, sameVersion)
+
+		// The changes without a version are rejected.
+		status, _ = doWithVersion(http.MethodPut, "/api/v1/alerts/receivers/team-c", "name: team-c\n", "")
+		assert.Equal(t, http.StatusPreconditionRequired, status)
+		status, _ = doWithVersion(http.MethodPut, "/api/v1/alerts/receivers/team-c", "name: team-c\n", "*")
+		assert.Equal(t, http.StatusPreconditionRequired, status)
+		require.Len(t, storedConfig().Receivers, 2)
+
+		status, newVersion := doWithVersion(http.MethodPut, "/api/v1/alerts/receivers/team-c", "name: team-c\n", version)
+		require.Equal(t, http.StatusCreated, status)
+		assert.NotEqual(t, version, newVersion)
+
+		// The previous version is outdated.
+		status, _ = doWithVersion(http.MethodDelete, "/api/v1/alerts/receivers/team-c", "", version)
+		assert.Equal(t, http.StatusPreconditionFailed, status)
+		require.Len(t, storedConfig().Receivers, 3)
+
+		status, _ = doWithVersion(http.MethodDelete, "/api/v1/alerts/receivers/team-c", "", newVersion)
+		assert.Equal(t, http.StatusOK, status)
+		require.Len(t, storedConfig().Receivers, 2)
+	})
+}
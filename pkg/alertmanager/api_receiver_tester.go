@@ -0,0 +1,348 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+	util_net "github.com/grafana/mimir/pkg/util/net"
+)
+
+const (
+	// Timeout of the test notification sent by each integration of the tested receiver.
+	receiverTestTimeout = 30 * time.Second
+
+	// Name of the inline receiver tested, when it has none.
+	defaultTestReceiverName = "test"
+
+	integrationTestStatusSuccess = "success"
+	integrationTestStatusFailure = "failure"
+)
+
+var (
+	errTestReceiverRequired = errors.New("either the name of a receiver or an inline receiver config is required")
+	errTestReceiverConflict = errors.New("the name of a receiver and an inline receiver config can't be both set")
+)
+
+// ReceiverTestRequest is the request to test a receiver, either a receiver of the Alertmanager configuration
+// of the tenant or an inline receiver config.
+type ReceiverTestRequest struct {
+	Receiver       string            `yaml:"receiver"`
+	ReceiverConfig yaml.Node         `yaml:"receiver_config"`
+	Alert          ReceiverTestAlert `yaml:"alert"`
+}
+
+// ReceiverTestAlert is the alert sent by the test of a receiver.
+type ReceiverTestAlert struct {
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// ReceiverTestResult is the result of the test of a receiver.
+type ReceiverTestResult struct {
+	Receiver     string                  `yaml:"receiver"`
+	Integrations []IntegrationTestResult `yaml:"integrations"`
+}
+
+// IntegrationTestResult is the result of the test notification sent by an integration of a receiver.
+type IntegrationTestResult struct {
+	Name   string `yaml:"name"`
+	Index  int    `yaml:"index"`
+	Status string `yaml:"status"`
+	Error  string `yaml:"error,omitempty"`
+}
+
+// TestReceiver sends a test notification through each integration of a receiver, rendering the templates
+// of the tenant, and returns the result of each integration. The notifications are sent through the
+// receivers firewall of the tenant, like the notifications of the Alertmanager of the tenant.
+func (am *MultitenantAlertmanager) TestReceiver(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	maxConfigSize := am.limits.AlertmanagerMaxConfigSize(userID)
+	payload, err := readConfigPayload(r, maxConfigSize)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if maxConfigSize > 0 && len(payload) > maxConfigSize {
+		msg := fmt.Sprintf(errConfigurationTooBig, maxConfigSize)
+		level.Warn(logger).Log("msg", msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	req := ReceiverTestRequest{}
+	if err := yaml.Unmarshal(payload, &req); err != nil {
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := am.loadConfigObjects(r.Context(), userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	name, err := testReceiverConfig(cfg, req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errConfigObjectNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	cfgDesc, err := cfg.toProto(userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err, "user", userID)
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	integrations, err := am.buildTestReceiverIntegrations(logger, userID, cfgDesc, name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s: %s", errValidatingConfig, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	alert := newTestAlert(req.Alert, time.Now())
+	result := ReceiverTestResult{Receiver: name, Integrations: make([]IntegrationTestResult, 0, len(integrations))}
+	for _, i := range integrations {
+		result.Integrations = append(result.Integrations, testIntegration(r.Context(), i, name, alert))
+	}
+
+	d, err := yaml.Marshal(result)
+	if err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err, "user", userID)
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	if _, err := w.Write(d); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// testReceiverConfig reduces the configuration to the receiver to test, either the inline receiver of the
+// request or a receiver of the configuration, and returns the name of the receiver. The global settings and
+// the templates used by the receiver are kept, while the other receivers, the routes and the other objects
+// of the configuration are removed, so that only the receiver to test is validated.
+func testReceiverConfig(cfg *configObjects, req ReceiverTestRequest) (string, error) {
+	var (
+		name string
+		obj  *yaml.Node
+	)
+	switch {
+	case req.ReceiverConfig.IsZero():
+		if req.Receiver == "" {
+			return "", errTestReceiverRequired
+		}
+		name = req.Receiver
+		if obj = cfg.namedObject(receiversKey, name); obj == nil {
+			return "", errors.Wrapf(errConfigObjectNotFound, "receiver %q", name)
+		}
+	case req.Receiver != "":
+		return "", errTestReceiverConflict
+	default:
+		obj = &req.ReceiverConfig
+		if obj.Kind != yaml.MappingNode {
+			return "", errors.New("the inline receiver config is not a YAML mapping")
+		}
+		if name = objectName(obj); name == "" {
+			name = defaultTestReceiverName
+			obj.Content = append([]*yaml.Node{{Kind: yaml.ScalarNode, Value: nameKey}, {Kind: yaml.ScalarNode, Value: name}}, obj.Content...)
+		}
+	}
+
+	global, templates := cfg.value(globalKey), cfg.value(templatesKey)
+	cfg.doc.Content[0] = &yaml.Node{Kind: yaml.MappingNode}
+	if global != nil {
+		cfg.setValue(globalKey, global)
+	}
+	if templates != nil {
+		cfg.setValue(templatesKey, templates)
+	}
+	cfg.setValue(routeKey, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "receiver"}, {Kind: yaml.ScalarNode, Value: name},
+	}})
+	cfg.setValue(receiversKey, &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{obj}})
+	return name, nil
+}
+
+// buildTestReceiverIntegrations validates the configuration of the receiver like a stored configuration and
+// builds the integrations of the receiver, with the templates of the configuration, the receivers firewall
+// and the notification rate limits of the tenant.
+func (am *MultitenantAlertmanager) buildTestReceiverIntegrations(logger log.Logger, userID string, cfgDesc alertspb.AlertConfigDesc, name string) ([]notify.Integration, error) {
+	if err := validateUserConfig(logger, cfgDesc, am.limits, userID); err != nil {
+		return nil, err
+	}
+
+	amCfg, err := config.Load(cfgDesc.RawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var receiver *config.Receiver
+	for _, rcv := range amCfg.Receivers {
+		if rcv.Name == name {
+			receiver = rcv
+		}
+	}
+	if receiver == nil {
+		return nil, fmt.Errorf("receiver %q not found", name)
+	}
+
+	// The templates are rendered from a temporary directory, like when the configuration is validated.
+	tmpDir, err := ioutil.TempDir("", "test-receiver-"+userID)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, tmpl := range cfgDesc.Templates {
+		templateFilepath, err := safeTemplateFilepath(tmpDir, tmpl.Filename)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := storeTemplateFile(templateFilepath, tmpl.Body); err != nil {
+			return nil, fmt.Errorf("unable to store template file '%s'", tmpl.Filename)
+		}
+	}
+
+	templateFiles := make([]string, len(amCfg.Templates))
+	for i, t := range amCfg.Templates {
+		templateFiles[i] = filepath.Join(tmpDir, t)
+	}
+	tmpl, err := template.FromGlobs(templateFiles...)
+	if err != nil {
+		return nil, err
+	}
+	tmpl.ExternalURL = am.cfg.ExternalURL.URL
+
+	firewallDialer := util_net.NewFirewallDialer(newFirewallDialerConfigProvider(userID, am.limits))
+	return buildReceiverIntegrations(receiver, tmpl, firewallDialer, logger, func(integrationName string, n notify.Notifier) notify.Notifier {
+		return &rateLimitedNotifier{
+			upstream:                n,
+			notificationRateLimiter: am.receiverTestLimiter(userID, integrationName),
+		}
+	})
+}
+
+type receiverTestLimiterKey struct {
+	tenant      string
+	integration string
+}
+
+// receiverTestLimiter returns the rate limiter of the test notifications of the tenant sent through the
+// integration. The limiter is shared by the tests of all the receivers of the tenant, so that the test
+// notifications are rate limited across requests with the notification rate limits of the tenant.
+// The limiters are local to each Alertmanager replica.
+func (am *MultitenantAlertmanager) receiverTestLimiter(userID, integrationName string) *notificationRateLimiter {
+	am.receiverTestLimitersMtx.Lock()
+	defer am.receiverTestLimitersMtx.Unlock()
+
+	key := receiverTestLimiterKey{tenant: userID, integration: integrationName}
+	l, ok := am.receiverTestLimiters[key]
+	if !ok {
+		rl := &tenantRateLimits{
+			tenant:      userID,
+			limits:      am.limits,
+			integration: integrationName,
+		}
+		l = newNotificationRateLimiter(rl, 10*time.Second, am.receiverTestRateLimited.WithLabelValues(integrationName))
+		am.receiverTestLimiters[key] = l
+	}
+	return l
+}
+
+// removeReceiverTestLimiters removes the rate limiters of the test notifications of the tenants whose
+// Alertmanager isn't run by this replica, given the configurations of the tenants it runs.
+func (am *MultitenantAlertmanager) removeReceiverTestLimiters(cfgs map[string]alertspb.AlertConfigDesc) {
+	am.receiverTestLimitersMtx.Lock()
+	defer am.receiverTestLimitersMtx.Unlock()
+
+	for key := range am.receiverTestLimiters {
+		if _, exists := cfgs[key.tenant]; !exists {
+			delete(am.receiverTestLimiters, key)
+		}
+	}
+}
+
+// newTestAlert returns the alert sent by the test of a receiver, with default labels and annotations.
+func newTestAlert(a ReceiverTestAlert, now time.Time) *types.Alert {
+	labels := model.LabelSet{
+		model.AlertNameLabel: "TestAlert",
+		model.InstanceLabel:  "Grafana Mimir",
+	}
+	if len(a.Labels) > 0 {
+		labels = model.LabelSet{model.AlertNameLabel: "TestAlert"}
+		for name, value := range a.Labels {
+			labels[model.LabelName(name)] = model.LabelValue(value)
+		}
+	}
+
+	annotations := model.LabelSet{"summary": "Notification test"}
+	if len(a.Annotations) > 0 {
+		annotations = model.LabelSet{}
+		for name, value := range a.Annotations {
+			annotations[model.LabelName(name)] = model.LabelValue(value)
+		}
+	}
+
+	return &types.Alert{
+		Alert: model.Alert{
+			Labels:      labels,
+			Annotations: annotations,
+			StartsAt:    now,
+		},
+		UpdatedAt: now,
+	}
+}
+
+// testIntegration sends the test alert through the integration, as a notification of the group of the alert.
+func testIntegration(ctx context.Context, i notify.Integration, receiver string, alert *types.Alert) IntegrationTestResult {
+	ctx, cancel := context.WithTimeout(ctx, receiverTestTimeout)
+	defer cancel()
+
+	ctx = notify.WithReceiverName(ctx, receiver)
+	ctx = notify.WithGroupKey(ctx, fmt.Sprintf("{}:%s", alert.Labels))
+	ctx = notify.WithGroupLabels(ctx, alert.Labels)
+
+	result := IntegrationTestResult{Name: i.Name(), Index: i.Index(), Status: integrationTestStatusSuccess}
+	if _, err := i.Notify(ctx, alert); err != nil {
+		result.Status = integrationTestStatusFailure
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,287 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+	"go.uber.org/atomic"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestMultitenantAlertmanager_TestReceiver(t *testing.T) {
+	var (
+		slackText       = atomic.NewString("")
+		webhookInvoked  = atomic.NewBool(false)
+		webhookStatus   = atomic.NewInt64(http.StatusOK)
+		receivedWebhook = atomic.NewString("")
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		switch r.URL.Path {
+		case "/slack":
+			msg := struct {
+				Attachments []struct {
+					Text string `json:"text"`
+				} `json:"attachments"`
+			}{}
+			require.NoError(t, json.Unmarshal(body, &msg))
+			require.Len(t, msg.Attachments, 1)
+			slackText.Store(msg.Attachments[0].Text)
+			w.WriteHeader(http.StatusOK)
+		case "/webhook":
+			webhookInvoked.Store(true)
+			receivedWebhook.Store(string(body))
+			w.WriteHeader(int(webhookStatus.Load()))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	store := prepareInMemoryAlertStore()
+	require.NoError(t, store.SetAlertConfig(ctx, alertspb.AlertConfigDesc{
+		User: "user-1",
+		RawConfig: fmt.Sprintf(`
+route:
+  receiver: team-a
+receivers:
+  - name: team-a
+    slack_configs:
+      - api_url: %[1]s/slack
+        channel: '#alerts'
+        text: '{{ template "team-a.text" . }}'
+templates:
+  - team-a.tmpl
+`, server.URL),
+		Templates: []*alertspb.TemplateDesc{{
+			Filename: "team-a.tmpl",
+			Body:     `{{ define "team-a.text" }}{{ .CommonLabels.alertname }} on {{ .CommonLabels.instance }}{{ end }}`,
+		}},
+	}))
+
+	// The receiver team-b can't be stored anymore, since it reads a file.
+	require.NoError(t, store.SetAlertConfig(ctx, alertspb.AlertConfigDesc{
+		User: "user-3",
+		RawConfig: fmt.Sprintf(`
+route:
+  receiver: team-a
+receivers:
+  - name: team-a
+    webhook_configs:
+      - url: %[1]s/webhook
+  - name: team-b
+    webhook_configs:
+      - url: %[1]s/webhook
+        http_config:
+          bearer_token_file: /etc/token
+`, server.URL),
+	}))
+
+	cfg := mockAlertmanagerConfig(t)
+	require.NoError(t, cfg.ExternalURL.Set("http://alertmanager.example.com/alertmanager"))
+
+	newAlertmanager := func(t *testing.T, setLimits func(*validation.Limits)) *MultitenantAlertmanager {
+		var limits validation.Limits
+		flagext.DefaultValues(&limits)
+		setLimits(&limits)
+
+		overrides, err := validation.NewOverrides(limits, nil)
+		require.NoError(t, err)
+
+		return &MultitenantAlertmanager{
+			cfg:                  cfg,
+			store:                store,
+			fallbackConfig:       configObjectsTestFallbackConfig,
+			logger:               util_log.Logger,
+			limits:               overrides,
+			receiverTestLimiters: map[receiverTestLimiterKey]*notificationRateLimiter{},
+			receiverTestRateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: "cortex_alertmanager_receiver_test_notifications_rate_limited_total",
+			}, []string{"integration"}),
+		}
+	}
+
+	send := func(t *testing.T, am *MultitenantAlertmanager, userID, body string) (int, string) {
+		req := httptest.NewRequest(http.MethodPost, "http://alertmanager/api/v1/alerts/test_receiver", strings.NewReader(body))
+		req = req.WithContext(user.InjectOrgID(req.Context(), userID))
+		w := httptest.NewRecorder()
+		am.TestReceiver(w, req)
+
+		resp := w.Result()
+		respBody, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(respBody)
+	}
+
+	do := func(t *testing.T, firewallEnabled bool, userID, body string) (int, string) {
+		am := newAlertmanager(t, func(limits *validation.Limits) {
+			limits.AlertmanagerReceiversBlockPrivateAddresses = firewallEnabled
+		})
+		return send(t, am, userID, body)
+	}
+
+	parseResult := func(t *testing.T, body string) ReceiverTestResult {
+		result := ReceiverTestResult{}
+		require.NoError(t, yaml.Unmarshal([]byte(body), &result))
+		return result
+	}
+
+	t.Run("receiver of the tenant config, rendering the templates of the tenant", func(t *testing.T) {
+		status, body := do(t, false, "user-1", `
+receiver: team-a
+alert:
+  labels:
+    instance: host-1
+`)
+		require.Equal(t, http.StatusOK, status, body)
+		assert.Equal(t, ReceiverTestResult{
+			Receiver:     "team-a",
+			Integrations: []IntegrationTestResult{{Name: "slack", Index: 0, Status: integrationTestStatusSuccess}},
+		}, parseResult(t, body))
+		assert.Equal(t, "TestAlert on host-1", slackText.Load())
+	})
+
+	t.Run("inline receiver on top of the fallback config", func(t *testing.T) {
+		webhookInvoked.Store(false)
+
+		status, body := do(t, false, "user-2", fmt.Sprintf(`
+receiver_config:
+  webhook_configs:
+    - url: %s/webhook
+`, server.URL))
+		require.Equal(t, http.StatusOK, status, body)
+		assert.Equal(t, ReceiverTestResult{
+			Receiver:     defaultTestReceiverName,
+			Integrations: []IntegrationTestResult{{Name: "webhook", Index: 0, Status: integrationTestStatusSuccess}},
+		}, parseResult(t, body))
+
+		require.True(t, webhookInvoked.Load())
+		msg := struct {
+			Receiver          string            `json:"receiver"`
+			CommonLabels      map[string]string `json:"commonLabels"`
+			CommonAnnotations map[string]string `json:"commonAnnotations"`
+			ExternalURL       string            `json:"externalURL"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(receivedWebhook.Load()), &msg))
+		assert.Equal(t, defaultTestReceiverName, msg.Receiver)
+		assert.Equal(t, map[string]string{"alertname": "TestAlert", "instance": "Grafana Mimir"}, msg.CommonLabels)
+		assert.Equal(t, map[string]string{"summary": "Notification test"}, msg.CommonAnnotations)
+		assert.Equal(t, "http://alertmanager.example.com/alertmanager", msg.ExternalURL)
+	})
+
+	t.Run("failed notification", func(t *testing.T) {
+		webhookStatus.Store(http.StatusInternalServerError)
+		defer webhookStatus.Store(http.StatusOK)
+
+		status, body := do(t, false, "user-1", fmt.Sprintf(`
+receiver_config:
+  name: team-b
+  webhook_configs:
+    - url: %s/webhook
+`, server.URL))
+		require.Equal(t, http.StatusOK, status, body)
+
+		result := parseResult(t, body)
+		assert.Equal(t, "team-b", result.Receiver)
+		require.Len(t, result.Integrations, 1)
+		assert.Equal(t, integrationTestStatusFailure, result.Integrations[0].Status)
+		assert.Contains(t, result.Integrations[0].Error, "500")
+	})
+
+	t.Run("notification blocked by the receivers firewall", func(t *testing.T) {
+		webhookInvoked.Store(false)
+
+		status, body := do(t, true, "user-1", fmt.Sprintf(`
+receiver_config:
+  webhook_configs:
+    - url: %s/webhook
+`, server.URL))
+		require.Equal(t, http.StatusOK, status, body)
+
+		result := parseResult(t, body)
+		require.Len(t, result.Integrations, 1)
+		assert.Equal(t, integrationTestStatusFailure, result.Integrations[0].Status)
+		assert.Contains(t, result.Integrations[0].Error, "blocked")
+		assert.False(t, webhookInvoked.Load())
+	})
+
+	t.Run("notifications rate limited with the limits of the tenant across requests", func(t *testing.T) {
+		am := newAlertmanager(t, func(limits *validation.Limits) {
+			limits.NotificationRateLimitPerIntegration = validation.NotificationRateLimitMap{"slack": 0.01}
+		})
+
+		status, body := send(t, am, "user-1", "receiver: team-a")
+		require.Equal(t, http.StatusOK, status, body)
+		assert.Equal(t, integrationTestStatusSuccess, parseResult(t, body).Integrations[0].Status)
+
+		status, body = send(t, am, "user-1", "receiver: team-a")
+		require.Equal(t, http.StatusOK, status, body)
+		result := parseResult(t, body)
+		assert.Equal(t, integrationTestStatusFailure, result.Integrations[0].Status)
+		assert.Equal(t, errRateLimited.Error(), result.Integrations[0].Error)
+		assert.Equal(t, float64(1), testutil.ToFloat64(am.receiverTestRateLimited.WithLabelValues("slack")))
+
+		// The limiters are removed once the Alertmanager of the tenant isn't run by the replica anymore.
+		am.removeReceiverTestLimiters(map[string]alertspb.AlertConfigDesc{"user-1": {}})
+		assert.Len(t, am.receiverTestLimiters, 1)
+		am.removeReceiverTestLimiters(map[string]alertspb.AlertConfigDesc{"user-2": {}})
+		assert.Empty(t, am.receiverTestLimiters)
+	})
+
+	t.Run("only the tested receiver is validated", func(t *testing.T) {
+		status, body := do(t, false, "user-3", "receiver: team-a")
+		require.Equal(t, http.StatusOK, status, body)
+		assert.Equal(t, integrationTestStatusSuccess, parseResult(t, body).Integrations[0].Status)
+
+		status, body = do(t, false, "user-3", "receiver: team-b")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, errValidatingConfig)
+	})
+
+	t.Run("config exceeding the limits of the tenant", func(t *testing.T) {
+		am := newAlertmanager(t, func(limits *validation.Limits) {
+			limits.AlertmanagerMaxTemplateSizeBytes = 10
+		})
+
+		status, body := send(t, am, "user-1", "receiver: team-a")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, "template team-a.tmpl is too big")
+	})
+
+	t.Run("unknown receiver", func(t *testing.T) {
+		status, _ := do(t, false, "user-1", "receiver: unknown")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, body := range []string{
+			"",
+			"receiver: team-a\nreceiver_config:\n  name: team-b",
+			"receiver_config: team-b",
+			"receiver_config:\n  webhook_configs:\n    - url: not-a-url",
+		} {
+			status, respBody := do(t, false, "user-1", body)
+			assert.Equal(t, http.StatusBadRequest, status, respBody)
+		}
+	})
+}
@@ -76,7 +76,8 @@ func (d *Distributor) isQuorumWritePath(p string) bool {
 }
 
 func (d *Distributor) isUnaryWritePath(p string) bool {
-	return strings.HasSuffix(p, "/silences")
+	// The imported state is replicated by the Alertmanager receiving it, like the silences.
+	return strings.HasSuffix(p, "/silences") || p == stateTransferPath
 }
 
 func (d *Distributor) isUnaryDeletePath(p string) bool {
@@ -105,6 +106,9 @@ func (d *Distributor) isQuorumReadPath(p string) (bool, merger.Merger) {
 	if strings.HasSuffix(path.Dir(p), "/v2/silence") {
 		return true, merger.V2SilenceID{}
 	}
+	if strings.HasSuffix(p, "/v1/notifications") {
+		return true, merger.V1Notifications{}
+	}
 	return false, nil
 }
 
@@ -48,6 +48,8 @@ func TestDistributor_DistributeRequest(t *testing.T) {
 		expectedTotalCalls  int
 		headersNotPreserved bool
 		route               string
+		// Whether the route is an admin route, rather than an Alertmanager API route.
+		adminRoute bool
 		// Paths where responses are merged, we need to supply a valid response body.
 		// Note that the actual merging logic is tested elsewhere (merger_test.go).
 		responseBody []byte
@@ -235,12 +237,43 @@ func TestDistributor_DistributeRequest(t *testing.T) {
 			expectedTotalCalls:  0,
 			headersNotPreserved: true,
 			route:               "/receivers",
+		}, {
+			name:               "Read /multitenant_alertmanager/state is sent to only 1 AM",
+			numAM:              5,
+			numHappyAM:         5,
+			replicationFactor:  3,
+			isRead:             true,
+			expStatusCode:      http.StatusOK,
+			expectedTotalCalls: 1,
+			route:              stateTransferPath,
+			adminRoute:         true,
+		}, {
+			name:               "Write /multitenant_alertmanager/state is sent to only 1 AM",
+			numAM:              5,
+			numHappyAM:         5,
+			replicationFactor:  3,
+			expStatusCode:      http.StatusOK,
+			expectedTotalCalls: 1,
+			route:              stateTransferPath,
+			adminRoute:         true,
+		}, {
+			name:                "Write /state of the Alertmanager API is not supported",
+			numAM:               5,
+			numHappyAM:          5,
+			replicationFactor:   3,
+			expStatusCode:       http.StatusNotFound,
+			expectedTotalCalls:  0,
+			headersNotPreserved: true,
+			route:               "/state",
 		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			route := "/alertmanager/api/v1" + c.route
+			if c.adminRoute {
+				route = c.route
+			}
 			d, ams, cleanup := prepare(t, c.numAM, c.numHappyAM, c.replicationFactor, c.responseBody)
 			t.Cleanup(cleanup)
 
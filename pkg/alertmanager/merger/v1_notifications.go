@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package merger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+)
+
+// V1Notifications implements the Merger interface for GET /v1/notifications. Each replica only records
+// the notifications it attempted, except the ones restored from the persisted state which are the same
+// in all the replicas, so the merged response is the union of the notifications of the replicas, the
+// most recent first.
+type V1Notifications struct{}
+
+func (V1Notifications) MergeResponses(in [][]byte) ([]byte, error) {
+	type bodyType struct {
+		Status string                       `json:"status"`
+		Data   []*alertspb.NotificationDesc `json:"data"`
+	}
+
+	notifications := make([]*alertspb.NotificationDesc, 0)
+	seen := map[string]struct{}{}
+	for _, body := range in {
+		parsed := bodyType{}
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			return nil, err
+		}
+		if parsed.Status != statusSuccess {
+			return nil, fmt.Errorf("unable to merge response of status: %s", parsed.Status)
+		}
+		for _, n := range parsed.Data {
+			// The JSON marshalling of the notifications is deterministic, as the group labels are sorted.
+			key, err := json.Marshal(n)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := seen[string(key)]; ok {
+				continue
+			}
+			seen[string(key)] = struct{}{}
+			notifications = append(notifications, n)
+		}
+	}
+
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].Timestamp.After(notifications[j].Timestamp)
+	})
+
+	return json.Marshal(bodyType{
+		Status: statusSuccess,
+		Data:   notifications,
+	})
+}
@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package merger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestV1Notifications(t *testing.T) {
+	in := [][]byte{
+		[]byte(`{"status":"success","data":[` +
+			`{"timestamp":"2022-08-10T10:02:00Z","receiver":"team-a","integration":"webhook","group_labels":{"alertname":"A","job":"a"},"firing_alerts":1,"outcome":"success"},` +
+			`{"timestamp":"2022-08-10T10:00:00Z","receiver":"team-a","integration":"email","group_labels":{"job":"a","alertname":"A"},"firing_alerts":1,"outcome":"failure","error":"connection refused"}` +
+			`]}`),
+		[]byte(`{"status":"success","data":[` +
+			`{"timestamp":"2022-08-10T10:01:00Z","receiver":"team-b","integration":"webhook","resolved_alerts":2,"outcome":"rate_limited","error":"failed to notify due to rate limits"},` +
+			`{"timestamp":"2022-08-10T10:00:00Z","receiver":"team-a","integration":"email","group_labels":{"alertname":"A","job":"a"},"firing_alerts":1,"outcome":"failure","error":"connection refused"}` +
+			`]}`),
+		[]byte(`{"status":"success","data":[]}`),
+	}
+
+	expected := []byte(`{"status":"success","data":[` +
+		`{"timestamp":"2022-08-10T10:02:00Z","receiver":"team-a","integration":"webhook","group_labels":{"alertname":"A","job":"a"},"firing_alerts":1,"outcome":"success"},` +
+		`{"timestamp":"2022-08-10T10:01:00Z","receiver":"team-b","integration":"webhook","resolved_alerts":2,"outcome":"rate_limited","error":"failed to notify due to rate limits"},` +
+		`{"timestamp":"2022-08-10T10:00:00Z","receiver":"team-a","integration":"email","group_labels":{"alertname":"A","job":"a"},"firing_alerts":1,"outcome":"failure","error":"connection refused"}` +
+		`]}`)
+
+	out, err := V1Notifications{}.MergeResponses(in)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), string(out))
+}
+
+func TestV1Notifications_ErrorStatus(t *testing.T) {
+	in := [][]byte{
+		[]byte(`{"status":"error","data":[]}`),
+	}
+
+	_, err := V1Notifications{}.MergeResponses(in)
+	require.EqualError(t, err, "unable to merge response of status: error")
+}
@@ -82,6 +82,8 @@ type MultitenantAlertmanagerConfig struct {
 
 	MaxConcurrentGetRequestsPerTenant int `yaml:"max_concurrent_get_requests_per_tenant" category:"advanced"`
 
+	NotificationHistorySize int `yaml:"notification_history_size" category:"experimental"`
+
 	// For distributor.
 	AlertmanagerClient ClientConfig `yaml:"alertmanager_client"`
 
@@ -112,6 +114,7 @@ func (cfg *MultitenantAlertmanagerConfig) RegisterFlags(f *flag.FlagSet, logger
 	cfg.Persister.RegisterFlagsWithPrefix("alertmanager", f)
 	cfg.ShardingRing.RegisterFlags(f, logger)
 
+	f.IntVar(&cfg.NotificationHistorySize, "alertmanager.notification-history-size", 0, "Number of last notification attempts to keep per tenant, along with their receiver, integration, alert group labels, outcome and error. The notification history is persisted along with the Alertmanager state, and is listed by the <alertmanager-http-prefix>/api/v1/notifications endpoint. 0 to disable.")
 	f.DurationVar(&cfg.PeerTimeout, "alertmanager.peer-timeout", defaultPeerTimeout, "Time to wait between peers to send notifications.")
 }
 
@@ -252,6 +255,10 @@ type MultitenantAlertmanager struct {
 	// effect here.
 	fallbackConfig string
 
+	// Rate limiters of the test notifications sent through the receivers test API, per tenant and integration.
+	receiverTestLimitersMtx sync.Mutex
+	receiverTestLimiters    map[receiverTestLimiterKey]*notificationRateLimiter
+
 	alertmanagersMtx sync.Mutex
 	alertmanagers    map[string]*Alertmanager
 	// Stores the current set of configurations we're running in each tenant's Alertmanager.
@@ -272,6 +279,8 @@ type MultitenantAlertmanager struct {
 	tenantsDiscovered prometheus.Gauge
 	syncTotal         *prometheus.CounterVec
 	syncFailures      *prometheus.CounterVec
+
+	receiverTestRateLimited *prometheus.CounterVec
 }
 
 // NewMultitenantAlertmanager creates a new MultitenantAlertmanager.
@@ -308,16 +317,17 @@ func NewMultitenantAlertmanager(cfg *MultitenantAlertmanagerConfig, store alerts
 
 func createMultitenantAlertmanager(cfg *MultitenantAlertmanagerConfig, fallbackConfig []byte, store alertstore.AlertStore, ringStore kv.Client, limits Limits, logger log.Logger, registerer prometheus.Registerer) (*MultitenantAlertmanager, error) {
 	am := &MultitenantAlertmanager{
-		cfg:                 cfg,
-		fallbackConfig:      string(fallbackConfig),
-		cfgs:                map[string]alertspb.AlertConfigDesc{},
-		alertmanagers:       map[string]*Alertmanager{},
-		alertmanagerMetrics: newAlertmanagerMetrics(),
-		multitenantMetrics:  newMultitenantAlertmanagerMetrics(registerer),
-		store:               store,
-		logger:              log.With(logger, "component", "MultiTenantAlertmanager"),
-		registry:            registerer,
-		limits:              limits,
+		cfg:                  cfg,
+		fallbackConfig:       string(fallbackConfig),
+		cfgs:                 map[string]alertspb.AlertConfigDesc{},
+		alertmanagers:        map[string]*Alertmanager{},
+		receiverTestLimiters: map[receiverTestLimiterKey]*notificationRateLimiter{},
+		alertmanagerMetrics:  newAlertmanagerMetrics(),
+		multitenantMetrics:   newMultitenantAlertmanagerMetrics(registerer),
+		store:                store,
+		logger:               log.With(logger, "component", "MultiTenantAlertmanager"),
+		registry:             registerer,
+		limits:               limits,
 		ringCheckErrors: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_alertmanager_ring_check_errors_total",
 			Help: "Number of errors that have occurred when checking the ring for ownership.",
@@ -330,6 +340,10 @@ func createMultitenantAlertmanager(cfg *MultitenantAlertmanagerConfig, fallbackC
 			Name: "cortex_alertmanager_sync_configs_failed_total",
 			Help: "Total number of times the alertmanager sync operation failed.",
 		}, []string{"reason"}),
+		receiverTestRateLimited: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_alertmanager_receiver_test_notifications_rate_limited_total",
+			Help: "Number of rate-limited test notifications sent through the receivers test API per integration.",
+		}, []string{"integration"}),
 		tenantsDiscovered: promauto.With(registerer).NewGauge(prometheus.GaugeOpts{
 			Name: "cortex_alertmanager_tenants_discovered",
 			Help: "Number of tenants with an Alertmanager configuration discovered.",
@@ -613,6 +627,8 @@ func (am *MultitenantAlertmanager) syncConfigs(cfgs map[string]alertspb.AlertCon
 	}
 	am.alertmanagersMtx.Unlock()
 
+	am.removeReceiverTestLimiters(cfgs)
+
 	// Now stop alertmanagers and wait until they are really stopped, without holding lock.
 	for userID, userAM := range userAlertmanagersToStop {
 		level.Info(am.logger).Log("msg", "deactivating per-tenant alertmanager", "user", userID)
@@ -744,6 +760,8 @@ func (am *MultitenantAlertmanager) newAlertmanager(userID string, amConfig *amco
 		ReplicationFactor:                 am.cfg.ShardingRing.ReplicationFactor,
 		Store:                             am.store,
 		PersisterConfig:                   am.cfg.Persister,
+		NotificationHistorySize:           am.cfg.NotificationHistorySize,
+		MaxStateSize:                      am.cfg.MaxRecvMsgSize,
 		Limits:                            am.limits,
 	}, reg)
 	if err != nil {
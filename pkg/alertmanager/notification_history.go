@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+)
+
+const (
+	notificationOutcomeSuccess     = "success"
+	notificationOutcomeFailure     = "failure"
+	notificationOutcomeRateLimited = "rate_limited"
+)
+
+// notificationHistory keeps the last notification attempts of a tenant, so that the delivery of
+// notifications can be checked afterwards. A nil history keeps nothing.
+type notificationHistory struct {
+	size int
+
+	mtx sync.Mutex
+	// The notification attempts, the most recent last.
+	entries []*alertspb.NotificationDesc
+}
+
+// newNotificationHistory returns a history keeping the last size notification attempts,
+// or nil if size isn't positive.
+func newNotificationHistory(size int) *notificationHistory {
+	if size <= 0 {
+		return nil
+	}
+	return &notificationHistory{size: size}
+}
+
+// WrapNotifier returns a notify.Notifier recording the notification attempts of the integration.
+func (h *notificationHistory) WrapNotifier(integration string, notifier notify.Notifier) notify.Notifier {
+	if h == nil {
+		return notifier
+	}
+	return &historyNotifier{upstream: notifier, integration: integration, history: h}
+}
+
+// Entries returns the notification attempts, the most recent last.
+func (h *notificationHistory) Entries() []*alertspb.NotificationDesc {
+	if h == nil {
+		return nil
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	return append([]*alertspb.NotificationDesc(nil), h.entries...)
+}
+
+// Restore adds the notification attempts, the most recent last, before the ones already in the history.
+func (h *notificationHistory) Restore(entries []*alertspb.NotificationDesc) {
+	if h == nil {
+		return
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.entries = append(append([]*alertspb.NotificationDesc(nil), entries...), h.entries...)
+	h.trim()
+}
+
+func (h *notificationHistory) add(entry *alertspb.NotificationDesc) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.entries = append(h.entries, entry)
+	h.trim()
+}
+
+// trim drops the oldest entries beyond the size of the history. It must be called with the lock held.
+func (h *notificationHistory) trim() {
+	if len(h.entries) > h.size {
+		h.entries = append(h.entries[:0:0], h.entries[len(h.entries)-h.size:]...)
+	}
+}
+
+// ServeHTTP lists the notification attempts, the most recent first. They can be filtered
+// by receiver and integration with the query parameters of the same name.
+func (h *notificationHistory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	receiver, integration := r.FormValue("receiver"), r.FormValue("integration")
+
+	entries := h.Entries()
+	result := make([]*alertspb.NotificationDesc, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if (receiver == "" || e.Receiver == receiver) && (integration == "" || e.Integration == integration) {
+			result = append(result, e)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(notificationsResponse{Status: "success", Data: result}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type notificationsResponse struct {
+	Status string                       `json:"status"`
+	Data   []*alertspb.NotificationDesc `json:"data"`
+}
+
+type historyNotifier struct {
+	upstream    notify.Notifier
+	integration string
+	history     *notificationHistory
+}
+
+func (n *historyNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+	retry, err := n.upstream.Notify(ctx, alerts...)
+
+	entry := &alertspb.NotificationDesc{
+		Timestamp:   time.Now().UTC(),
+		Integration: n.integration,
+		Outcome:     notificationOutcomeSuccess,
+	}
+	entry.Receiver, _ = notify.ReceiverName(ctx)
+	if groupLabels, ok := notify.GroupLabels(ctx); ok && len(groupLabels) > 0 {
+		entry.GroupLabels = make(map[string]string, len(groupLabels))
+		for name, value := range groupLabels {
+			entry.GroupLabels[string(name)] = string(value)
+		}
+	}
+	for _, a := range alerts {
+		if a.Resolved() {
+			entry.ResolvedAlerts++
+		} else {
+			entry.FiringAlerts++
+		}
+	}
+	if err != nil {
+		entry.Outcome = notificationOutcomeFailure
+		if errors.Is(err, errRateLimited) {
+			entry.Outcome = notificationOutcomeRateLimited
+		}
+		entry.Error = err.Error()
+	}
+
+	n.history.add(entry)
+	return retry, err
+}
@@ -0,0 +1,226 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+)
+
+type fakeNotifier struct {
+	err error
+}
+
+func (n *fakeNotifier) Notify(context.Context, ...*types.Alert) (bool, error) {
+	return n.err != nil, n.err
+}
+
+func TestNotificationHistory(t *testing.T) {
+	const historySize = 3
+
+	h := newNotificationHistory(historySize)
+
+	ctx := notify.WithReceiverName(context.Background(), "team-a")
+	ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": "HighLatency"})
+
+	firing := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "HighLatency"}, EndsAt: time.Now().Add(time.Hour)}}
+	resolved := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "HighLatency"}, EndsAt: time.Now().Add(-time.Hour)}}
+
+	_, err := h.WrapNotifier("webhook", &fakeNotifier{}).Notify(ctx, firing, resolved)
+	require.NoError(t, err)
+	_, err = h.WrapNotifier("email", &fakeNotifier{err: errors.New("connection refused")}).Notify(ctx, firing)
+	require.Error(t, err)
+	_, err = h.WrapNotifier("email", &fakeNotifier{err: errRateLimited}).Notify(ctx, firing)
+	require.Error(t, err)
+
+	entries := h.Entries()
+	require.Len(t, entries, 3)
+	for _, e := range entries {
+		assert.Equal(t, "team-a", e.Receiver)
+		assert.Equal(t, map[string]string{"alertname": "HighLatency"}, e.GroupLabels)
+	}
+
+	assert.Equal(t, "webhook", entries[0].Integration)
+	assert.Equal(t, notificationOutcomeSuccess, entries[0].Outcome)
+	assert.Equal(t, "", entries[0].Error)
+	assert.Equal(t, int64(1), entries[0].FiringAlerts)
+	assert.Equal(t, int64(1), entries[0].ResolvedAlerts)
+
+	assert.Equal(t, "email", entries[1].Integration)
+	assert.Equal(t, notificationOutcomeFailure, entries[1].Outcome)
+	assert.Equal(t, "connection refused", entries[1].Error)
+
+	assert.Equal(t, notificationOutcomeRateLimited, entries[2].Outcome)
+	assert.Equal(t, errRateLimited.Error(), entries[2].Error)
+
+	// The oldest entries are dropped beyond the size of the history.
+	_, err = h.WrapNotifier("slack", &fakeNotifier{}).Notify(ctx, firing)
+	require.NoError(t, err)
+
+	entries = h.Entries()
+	require.Len(t, entries, historySize)
+	assert.Equal(t, "email", entries[0].Integration)
+	assert.Equal(t, "slack", entries[2].Integration)
+
+	// The restored entries are older than the recorded ones, so they're dropped first.
+	h = newNotificationHistory(historySize)
+	_, err = h.WrapNotifier("slack", &fakeNotifier{}).Notify(ctx, firing)
+	require.NoError(t, err)
+	h.Restore([]*alertspb.NotificationDesc{{Integration: "pagerduty"}, {Integration: "opsgenie"}, {Integration: "wechat"}})
+
+	entries = h.Entries()
+	require.Len(t, entries, historySize)
+	assert.Equal(t, "opsgenie", entries[0].Integration)
+	assert.Equal(t, "wechat", entries[1].Integration)
+	assert.Equal(t, "slack", entries[2].Integration)
+}
+
+func TestNotificationHistory_Disabled(t *testing.T) {
+	h := newNotificationHistory(0)
+	require.Nil(t, h)
+
+	notifier := &fakeNotifier{}
+	assert.Same(t, notifier, h.WrapNotifier("webhook", notifier))
+	assert.Nil(t, h.Entries())
+	h.Restore([]*alertspb.NotificationDesc{{}})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/notifications", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"success","data":[]}`, rec.Body.String())
+}
+
+func TestNotificationHistory_ServeHTTP(t *testing.T) {
+	h := newNotificationHistory(10)
+	h.Restore([]*alertspb.NotificationDesc{
+		{Receiver: "team-a", Integration: "webhook", Outcome: notificationOutcomeSuccess},
+		{Receiver: "team-b", Integration: "webhook", Outcome: notificationOutcomeSuccess},
+		{Receiver: "team-a", Integration: "email", Outcome: notificationOutcomeFailure, Error: "connection refused"},
+	})
+
+	tests := map[string]struct {
+		url                  string
+		expectedIntegrations []string
+	}{
+		"all notifications, the most recent first": {
+			url:                  "/api/v1/notifications",
+			expectedIntegrations: []string{"email", "webhook", "webhook"},
+		},
+		"filtered by receiver": {
+			url:                  "/api/v1/notifications?receiver=team-a",
+			expectedIntegrations: []string{"email", "webhook"},
+		},
+		"filtered by receiver and integration": {
+			url:                  "/api/v1/notifications?receiver=team-a&integration=webhook",
+			expectedIntegrations: []string{"webhook"},
+		},
+		"no match": {
+			url:                  "/api/v1/notifications?receiver=team-c",
+			expectedIntegrations: []string{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			resp := notificationsResponse{}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "success", resp.Status)
+
+			integrations := []string{}
+			for _, n := range resp.Data {
+				integrations = append(integrations, n.Integration)
+			}
+			assert.Equal(t, tc.expectedIntegrations, integrations)
+		})
+	}
+}
+
+func TestMultitenantAlertmanager_NotificationHistory(t *testing.T) {
+	ctx := context.Background()
+
+	config := `route:
+  receiver: 'email'
+
+receivers:
+- name: 'email'
+  email_configs:
+  - to: test@example.com
+    from: test@example.com
+    smarthost: smtp:2525
+`
+
+	store := prepareInMemoryAlertStore()
+	require.NoError(t, store.SetAlertConfig(ctx, alertspb.AlertConfigDesc{
+		User:      "user",
+		RawConfig: config,
+		Templates: []*alertspb.TemplateDesc{},
+	}))
+
+	// The email notifications are rate-limited, so that they're not sent.
+	limits := mockAlertManagerLimits{}
+
+	cfg := mockAlertmanagerConfig(t)
+	cfg.NotificationHistorySize = 10
+
+	am := setupSingleMultitenantAlertmanager(t, cfg, store, &limits, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, am.loadAndSyncConfigs(ctx, reasonPeriodic))
+
+	am.alertmanagersMtx.Lock()
+	uam := am.alertmanagers["user"]
+	am.alertmanagersMtx.Unlock()
+	require.NotNil(t, uam)
+
+	notifyCtx := notify.WithReceiverName(ctx, "email")
+	notifyCtx = notify.WithGroupKey(notifyCtx, "key")
+	notifyCtx = notify.WithGroupLabels(notifyCtx, model.LabelSet{"alertname": "HighLatency"})
+	notifyCtx = notify.WithRepeatInterval(notifyCtx, time.Minute)
+
+	_, _, err := uam.lastPipeline.Exec(notifyCtx, log.NewNopLogger(), &types.Alert{})
+	require.Error(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/alertmanager/api/v1/notifications", nil)
+	rec := httptest.NewRecorder()
+	am.ServeHTTP(rec, req.WithContext(user.InjectOrgID(req.Context(), "user")))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := ioutil.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	resp := notificationsResponse{}
+	require.NoError(t, json.Unmarshal(body, &resp))
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, "email", resp.Data[0].Receiver)
+	assert.Equal(t, "email", resp.Data[0].Integration)
+	assert.Equal(t, map[string]string{"alertname": "HighLatency"}, resp.Data[0].GroupLabels)
+	assert.Equal(t, notificationOutcomeRateLimited, resp.Data[0].Outcome)
+	assert.Equal(t, errRateLimited.Error(), resp.Data[0].Error)
+
+	// The notification history is persisted along with the state.
+	require.NoError(t, uam.persister.persist(ctx))
+	fs, err := store.GetFullState(ctx, "user")
+	require.NoError(t, err)
+	require.Len(t, fs.Notifications, 1)
+	assert.Equal(t, notificationOutcomeRateLimited, fs.Notifications[0].Outcome)
+}
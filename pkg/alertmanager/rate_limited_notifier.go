@@ -24,7 +24,30 @@ type rateLimits interface {
 
 type rateLimitedNotifier struct {
 	upstream notify.Notifier
-	counter  prometheus.Counter
+	*notificationRateLimiter
+}
+
+func newRateLimitedNotifier(upstream notify.Notifier, limits rateLimits, recheckInterval time.Duration, counter prometheus.Counter) *rateLimitedNotifier {
+	return &rateLimitedNotifier{
+		upstream:                upstream,
+		notificationRateLimiter: newNotificationRateLimiter(limits, recheckInterval, counter),
+	}
+}
+
+var errRateLimited = errors.New("failed to notify due to rate limits")
+
+func (r *rateLimitedNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+	if !r.allow() {
+		// Don't retry this notification later.
+		return false, errRateLimited
+	}
+
+	return r.upstream.Notify(ctx, alerts...)
+}
+
+// notificationRateLimiter rate limits notifications. It can be shared by several notifiers.
+type notificationRateLimiter struct {
+	counter prometheus.Counter
 
 	limiter *rate.Limiter
 	limits  rateLimits
@@ -33,9 +56,8 @@ type rateLimitedNotifier struct {
 	recheckAt       atomic.Int64 // unix nanoseconds timestamp
 }
 
-func newRateLimitedNotifier(upstream notify.Notifier, limits rateLimits, recheckInterval time.Duration, counter prometheus.Counter) *rateLimitedNotifier {
-	return &rateLimitedNotifier{
-		upstream:        upstream,
+func newNotificationRateLimiter(limits rateLimits, recheckInterval time.Duration, counter prometheus.Counter) *notificationRateLimiter {
+	return &notificationRateLimiter{
 		counter:         counter,
 		limits:          limits,
 		limiter:         rate.NewLimiter(limits.RateLimit(), limits.Burst()),
@@ -43,9 +65,8 @@ func newRateLimitedNotifier(upstream notify.Notifier, limits rateLimits, recheck
 	}
 }
 
-var errRateLimited = errors.New("failed to notify due to rate limits")
-
-func (r *rateLimitedNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+// allow returns whether a notification is allowed now, and counts it as rate-limited otherwise.
+func (r *notificationRateLimiter) allow() bool {
 	now := time.Now()
 	if now.UnixNano() >= r.recheckAt.Load() {
 		if limit := r.limits.RateLimit(); r.limiter.Limit() != limit {
@@ -62,9 +83,7 @@ func (r *rateLimitedNotifier) Notify(ctx context.Context, alerts ...*types.Alert
 	// This counts as single notification, no matter how many alerts there are in it.
 	if !r.limiter.AllowN(now, 1) {
 		r.counter.Inc()
-		// Don't retry this notification later.
-		return false, errRateLimited
+		return false
 	}
-
-	return r.upstream.Notify(ctx, alerts...)
+	return true
 }
@@ -54,10 +54,11 @@ type PersistableState interface {
 type statePersister struct {
 	services.Service
 
-	state  PersistableState
-	store  alertstore.AlertStore
-	userID string
-	logger log.Logger
+	state         PersistableState
+	notifications *notificationHistory
+	store         alertstore.AlertStore
+	userID        string
+	logger        log.Logger
 
 	timeout time.Duration
 
@@ -65,15 +66,17 @@ type statePersister struct {
 	persistFailed prometheus.Counter
 }
 
-// newStatePersister creates a new state persister.
-func newStatePersister(cfg PersisterConfig, userID string, state PersistableState, store alertstore.AlertStore, l log.Logger, r prometheus.Registerer) *statePersister {
+// newStatePersister creates a new state persister. The notification history, if any, is persisted
+// along with the state, and restored when the persister starts.
+func newStatePersister(cfg PersisterConfig, userID string, state PersistableState, notifications *notificationHistory, store alertstore.AlertStore, l log.Logger, r prometheus.Registerer) *statePersister {
 
 	s := &statePersister{
-		state:   state,
-		store:   store,
-		userID:  userID,
-		logger:  l,
-		timeout: defaultPersistTimeout,
+		state:         state,
+		notifications: notifications,
+		store:         store,
+		userID:        userID,
+		logger:        l,
+		timeout:       defaultPersistTimeout,
 		persistTotal: promauto.With(r).NewCounter(prometheus.CounterOpts{
 			Name: "alertmanager_state_persist_total",
 			Help: "Number of times we have tried to persist the running state to remote storage.",
@@ -90,11 +93,33 @@ func newStatePersister(cfg PersisterConfig, userID string, state PersistableStat
 }
 
 func (s *statePersister) starting(ctx context.Context) error {
+	s.restoreNotifications(ctx)
+
 	// Waits until the state replicator is settled, so that state is not
 	// persisted before obtaining some initial state.
 	return s.state.WaitReady(ctx)
 }
 
+// restoreNotifications restores the persisted notification history, as the notification history
+// isn't replicated between the replicas.
+func (s *statePersister) restoreNotifications(ctx context.Context) {
+	if s.notifications == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	desc, err := s.store.GetFullState(ctx, s.userID)
+	if err != nil {
+		if !errors.Is(err, alertspb.ErrNotFound) {
+			level.Warn(s.logger).Log("msg", "failed to restore notification history", "user", s.userID, "err", err)
+		}
+		return
+	}
+	s.notifications.Restore(desc.Notifications)
+}
+
 func (s *statePersister) iteration(ctx context.Context) error {
 	if err := s.persist(ctx); err != nil {
 		level.Error(s.logger).Log("msg", "failed to persist state", "user", s.userID, "err", err)
@@ -126,7 +151,7 @@ func (s *statePersister) persist(ctx context.Context) (err error) {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	desc := alertspb.FullStateDesc{State: fs}
+	desc := alertspb.FullStateDesc{State: fs, Notifications: s.notifications.Entries()}
 	if err = s.store.SetFullState(ctx, s.userID, desc); err != nil {
 		return err
 	}
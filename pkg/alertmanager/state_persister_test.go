@@ -92,7 +92,7 @@ func makeTestStatePersister(t *testing.T, position int, userID string) (*fakePer
 	store := &fakeStore{}
 	cfg := PersisterConfig{Interval: 1 * time.Second}
 
-	s := newStatePersister(cfg, userID, state, store, log.NewNopLogger(), nil)
+	s := newStatePersister(cfg, userID, state, nil, store, log.NewNopLogger(), nil)
 
 	require.NoError(t, s.StartAsync(context.Background()))
 	t.Cleanup(func() {
@@ -160,3 +160,38 @@ func TestStatePersister_Position1ShouldNotWrite(t *testing.T) {
 		assert.Equal(t, 0, len(store.getWrites()))
 	}
 }
+
+func TestStatePersister_ShouldPersistAndRestoreNotificationHistory(t *testing.T) {
+	ctx := context.Background()
+	store := prepareInMemoryAlertStore()
+	require.NoError(t, store.SetFullState(ctx, "user-1", alertspb.FullStateDesc{
+		State:         makeTestFullState(),
+		Notifications: []*alertspb.NotificationDesc{{Receiver: "team-a", Integration: "webhook", Outcome: notificationOutcomeSuccess}},
+	}))
+
+	state := newFakePersistableState()
+	state.getResult = makeTestFullState()
+	close(state.readyc)
+
+	notifications := newNotificationHistory(10)
+	s := newStatePersister(PersisterConfig{Interval: time.Hour}, "user-1", state, notifications, store, log.NewNopLogger(), nil)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, s))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(ctx, s))
+	})
+
+	// The persisted notification history is restored when the persister starts.
+	require.Len(t, notifications.Entries(), 1)
+	assert.Equal(t, "team-a", notifications.Entries()[0].Receiver)
+
+	// The notification history is persisted along with the state.
+	notifications.add(&alertspb.NotificationDesc{Receiver: "team-b", Integration: "email", Outcome: notificationOutcomeFailure})
+	require.NoError(t, s.persist(ctx))
+
+	desc, err := store.GetFullState(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, makeTestFullState(), desc.State)
+	require.Len(t, desc.Notifications, 2)
+	assert.Equal(t, "team-a", desc.Notifications[0].Receiver)
+	assert.Equal(t, "team-b", desc.Notifications[1].Receiver)
+}
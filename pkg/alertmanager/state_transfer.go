@@ -0,0 +1,270 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/golang/protobuf/proto"
+	"github.com/matttproud/golang_protobuf_extensions/pbutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/alertmanager/cluster/clusterpb"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const (
+	// Conflict handling of the imported silences having the same ID as an existing silence.
+	// The most recently updated silence is kept.
+	stateImportConflictMerge = "merge"
+	// The imported silence replaces the existing one.
+	stateImportConflictOverwrite = "overwrite"
+	// The existing silence is kept.
+	stateImportConflictSkip = "skip"
+
+	// Path of the admin route exporting and importing the state of the Alertmanager of a tenant.
+	stateTransferPath = "/multitenant_alertmanager/state"
+
+	// Prefixes of the keys of the silences and notification log parts of the state. The keys
+	// end with the tenant ID, which may differ when the state is imported into another tenant.
+	silencesStateKeyPrefix        = "sil:"
+	notificationLogStateKeyPrefix = "nfl:"
+)
+
+// StateImportResult is the result of the import of the state of an Alertmanager.
+type StateImportResult struct {
+	Silences        StateImportCounts `json:"silences"`
+	NotificationLog StateImportCounts `json:"notification_log"`
+}
+
+// StateImportCounts is the number of imported and skipped entries of a part of the state.
+type StateImportCounts struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+// stateTransfer exports and imports the silences and the notification log of the Alertmanager of a tenant,
+// so that they can be moved to another tenant or another cluster.
+type stateTransfer struct {
+	logger   log.Logger
+	state    PersistableState
+	silences *silence.Silences
+	nflog    *nflog.Log
+
+	// Maximum size of the imported state. 0 means no limit.
+	maxStateSize int64
+}
+
+// ServeHTTP exports the state on GET requests, and imports the state on POST requests. The state is
+// exported and imported as an encoded alertspb.FullStateDesc, the format the state is persisted with.
+func (t *stateTransfer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		t.serveExport(w)
+	case http.MethodPost:
+		t.serveImport(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *stateTransfer) serveExport(w http.ResponseWriter) {
+	fs, err := t.state.GetFullState()
+	if err != nil {
+		level.Error(t.logger).Log("msg", "failed to get the state to export", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	d, err := (&alertspb.FullStateDesc{State: fs}).Marshal()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := w.Write(d); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (t *stateTransfer) serveImport(w http.ResponseWriter, r *http.Request) {
+	conflict := r.FormValue("conflict")
+	if conflict == "" {
+		conflict = stateImportConflictMerge
+	}
+	if conflict != stateImportConflictMerge && conflict != stateImportConflictOverwrite && conflict != stateImportConflictSkip {
+		http.Error(w, fmt.Sprintf("invalid conflict handling %q, supported values: %s, %s, %s", conflict, stateImportConflictMerge, stateImportConflictOverwrite, stateImportConflictSkip), http.StatusBadRequest)
+		return
+	}
+
+	reader := r.Body
+	if t.maxStateSize > 0 {
+		reader = http.MaxBytesReader(w, r.Body, t.maxStateSize)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		if util.IsRequestBodyTooLarge(err) {
+			http.Error(w, fmt.Sprintf("the state exceeds the maximum size of %d bytes", t.maxStateSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	desc := alertspb.FullStateDesc{}
+	if err := desc.Unmarshal(body); err != nil {
+		http.Error(w, fmt.Sprintf("unable to decode the state: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	result, err := t.importState(desc.State, conflict, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	level.Info(t.logger).Log("msg", "state imported", "conflict", conflict,
+		"silences_imported", result.Silences.Imported, "silences_skipped", result.Silences.Skipped,
+		"nflog_imported", result.NotificationLog.Imported, "nflog_skipped", result.NotificationLog.Skipped)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// importState merges the silences and the notification log entries of the state into the state of the
+// Alertmanager, which replicates them to the other replicas of the tenant. The conflicts between silences
+// with the same ID are handled according to conflict, while the most recent notification log entries are kept.
+func (t *stateTransfer) importState(fs *clusterpb.FullState, conflict string, now time.Time) (StateImportResult, error) {
+	result := StateImportResult{}
+	if fs == nil {
+		return result, nil
+	}
+
+	for _, p := range fs.Parts {
+		var err error
+		switch {
+		case strings.HasPrefix(p.Key, silencesStateKeyPrefix):
+			err = t.importSilences(p.Data, conflict, now, &result.Silences)
+		case strings.HasPrefix(p.Key, notificationLogStateKeyPrefix):
+			err = t.importNotificationLog(p.Data, now, &result.NotificationLog)
+		default:
+			err = fmt.Errorf("unknown state key %q", p.Key)
+		}
+		if err != nil {
+			return result, errors.Wrapf(err, "failed to import part of state for key: %v", p.Key)
+		}
+	}
+
+	return result, nil
+}
+
+func (t *stateTransfer) importSilences(data []byte, conflict string, now time.Time, counts *StateImportCounts) error {
+	r := bytes.NewReader(data)
+	for {
+		e := &silencepb.MeshSilence{}
+		if _, err := pbutil.ReadDelimited(r, e); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if e.Silence == nil {
+			return silence.ErrInvalidState
+		}
+		if e.ExpiresAt.Before(now) {
+			counts.Skipped++
+			continue
+		}
+
+		existing, err := t.silences.QueryOne(silence.QIDs(e.Silence.Id))
+		if err != nil && !errors.Is(err, silence.ErrNotFound) {
+			return err
+		}
+		if existing != nil {
+			switch conflict {
+			case stateImportConflictSkip:
+				counts.Skipped++
+				continue
+			case stateImportConflictMerge:
+				if !existing.UpdatedAt.Before(e.Silence.UpdatedAt) {
+					counts.Skipped++
+					continue
+				}
+			case stateImportConflictOverwrite:
+				// The silences are merged by keeping the most recently updated one.
+				updatedAt := now
+				if !existing.UpdatedAt.Before(updatedAt) {
+					updatedAt = existing.UpdatedAt.Add(time.Millisecond)
+				}
+				e.Silence.UpdatedAt = updatedAt
+			}
+		}
+
+		// The silences are merged one at a time, so that each one is replicated on its own.
+		if err := mergeStateEntry(t.silences, e); err != nil {
+			return err
+		}
+		counts.Imported++
+	}
+}
+
+func (t *stateTransfer) importNotificationLog(data []byte, now time.Time, counts *StateImportCounts) error {
+	r := bytes.NewReader(data)
+	for {
+		e := &nflogpb.MeshEntry{}
+		if _, err := pbutil.ReadDelimited(r, e); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if e.Entry == nil || e.Entry.Receiver == nil {
+			return nflog.ErrInvalidState
+		}
+		if e.ExpiresAt.Before(now) {
+			counts.Skipped++
+			continue
+		}
+
+		existing, err := t.nflog.Query(nflog.QGroupKey(string(e.Entry.GroupKey)), nflog.QReceiver(e.Entry.Receiver))
+		if err != nil && !errors.Is(err, nflog.ErrNotFound) {
+			return err
+		}
+		if len(existing) > 0 && !existing[0].Timestamp.Before(e.Entry.Timestamp) {
+			counts.Skipped++
+			continue
+		}
+
+		if err := mergeStateEntry(t.nflog, e); err != nil {
+			return err
+		}
+		counts.Imported++
+	}
+}
+
+// mergeStateEntry encodes the entry like the state of the silences and the notification log, and merges it.
+func mergeStateEntry(st cluster.State, e proto.Message) error {
+	var buf bytes.Buffer
+	if _, err := pbutil.WriteDelimited(&buf, e); err != nil {
+		return err
+	}
+	return st.Merge(buf.Bytes())
+}
@@ -0,0 +1,273 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/matttproud/golang_protobuf_extensions/pbutil"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/alertmanager/cluster/clusterpb"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+)
+
+// fakeClusterStates returns the full state of the states it's made of.
+type fakeClusterStates struct {
+	PersistableState
+	states map[string]cluster.State
+}
+
+func (s *fakeClusterStates) GetFullState() (*clusterpb.FullState, error) {
+	fs := &clusterpb.FullState{}
+	for key, st := range s.states {
+		b, err := st.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		fs.Parts = append(fs.Parts, clusterpb.Part{Key: key, Data: b})
+	}
+	return fs, nil
+}
+
+func newTestStateTransfer(t *testing.T, userID string) *stateTransfer {
+	silences, err := silence.New(silence.Options{Retention: time.Hour})
+	require.NoError(t, err)
+	nfl, err := nflog.New(nflog.WithRetention(time.Hour))
+	require.NoError(t, err)
+
+	return &stateTransfer{
+		logger: log.NewNopLogger(),
+		state: &fakeClusterStates{states: map[string]cluster.State{
+			silencesStateKeyPrefix + userID:        silences,
+			notificationLogStateKeyPrefix + userID: nfl,
+		}},
+		silences: silences,
+		nflog:    nfl,
+	}
+}
+
+func newTestMeshSilence(id, comment string, updatedAt time.Time) *silencepb.MeshSilence {
+	return &silencepb.MeshSilence{
+		Silence: &silencepb.Silence{
+			Id:        id,
+			Matchers:  []*silencepb.Matcher{{Name: "alertname", Pattern: "HighLatency"}},
+			StartsAt:  updatedAt,
+			EndsAt:    updatedAt.Add(time.Hour),
+			UpdatedAt: updatedAt,
+			Comment:   comment,
+		},
+		ExpiresAt: updatedAt.Add(2 * time.Hour),
+	}
+}
+
+func encodeTestStatePart(t *testing.T, key string, entries ...proto.Message) clusterpb.Part {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		_, err := pbutil.WriteDelimited(&buf, e)
+		require.NoError(t, err)
+	}
+	return clusterpb.Part{Key: key, Data: buf.Bytes()}
+}
+
+func TestStateTransfer_ExportAndImportIntoAnotherTenant(t *testing.T) {
+	src := newTestStateTransfer(t, "user-1")
+
+	silenceID, err := src.silences.Set(&silencepb.Silence{
+		Matchers:  []*silencepb.Matcher{{Name: "alertname", Pattern: "HighLatency"}},
+		StartsAt:  time.Now(),
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "team-a",
+		Comment:   "maintenance",
+	})
+	require.NoError(t, err)
+
+	receiver := &nflogpb.Receiver{GroupName: "team-a", Integration: "webhook", Idx: 0}
+	require.NoError(t, src.nflog.Log(receiver, "{}:{alertname=\"HighLatency\"}", []uint64{1}, nil))
+
+	// Export the state.
+	rec := httptest.NewRecorder()
+	src.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, stateTransferPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+	exported := rec.Body.Bytes()
+
+	// Import it into another tenant.
+	dst := newTestStateTransfer(t, "user-2")
+	rec = httptest.NewRecorder()
+	dst.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, stateTransferPath, bytes.NewReader(exported)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	result := StateImportResult{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, StateImportResult{
+		Silences:        StateImportCounts{Imported: 1},
+		NotificationLog: StateImportCounts{Imported: 1},
+	}, result)
+
+	sil, err := dst.silences.QueryOne(silence.QIDs(silenceID))
+	require.NoError(t, err)
+	assert.Equal(t, "maintenance", sil.Comment)
+
+	entries, err := dst.nflog.Query(nflog.QGroupKey("{}:{alertname=\"HighLatency\"}"), nflog.QReceiver(receiver))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, []uint64{1}, entries[0].FiringAlerts)
+
+	// Importing the same state again is a no-op.
+	rec = httptest.NewRecorder()
+	dst.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, stateTransferPath, bytes.NewReader(exported)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, StateImportResult{
+		Silences:        StateImportCounts{Skipped: 1},
+		NotificationLog: StateImportCounts{Skipped: 1},
+	}, result)
+}
+
+func TestStateTransfer_ImportSilencesConflicts(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		conflict          string
+		importedUpdatedAt time.Time
+		expectedComment   string
+		expectedCounts    StateImportCounts
+	}{
+		"merge keeps the existing silence if it's the most recently updated": {
+			conflict:          stateImportConflictMerge,
+			importedUpdatedAt: now.Add(-time.Minute),
+			expectedComment:   "existing",
+			expectedCounts:    StateImportCounts{Imported: 1, Skipped: 1},
+		},
+		"merge keeps the imported silence if it's the most recently updated": {
+			conflict:          stateImportConflictMerge,
+			importedUpdatedAt: now.Add(time.Minute),
+			expectedComment:   "imported",
+			expectedCounts:    StateImportCounts{Imported: 2},
+		},
+		"overwrite keeps the imported silence": {
+			conflict:          stateImportConflictOverwrite,
+			importedUpdatedAt: now.Add(-time.Minute),
+			expectedComment:   "imported",
+			expectedCounts:    StateImportCounts{Imported: 2},
+		},
+		"skip keeps the existing silence": {
+			conflict:          stateImportConflictSkip,
+			importedUpdatedAt: now.Add(time.Minute),
+			expectedComment:   "existing",
+			expectedCounts:    StateImportCounts{Imported: 1, Skipped: 1},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tr := newTestStateTransfer(t, "user-1")
+			require.NoError(t, tr.silences.Merge(encodeTestStatePart(t, "", newTestMeshSilence("a", "existing", now)).Data))
+
+			result, err := tr.importState(&clusterpb.FullState{Parts: []clusterpb.Part{
+				encodeTestStatePart(t, "sil:user-2",
+					newTestMeshSilence("a", "imported", tc.importedUpdatedAt),
+					newTestMeshSilence("b", "imported", now),
+				),
+			}}, tc.conflict, now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedCounts, result.Silences)
+
+			sil, err := tr.silences.QueryOne(silence.QIDs("a"))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedComment, sil.Comment)
+
+			sil, err = tr.silences.QueryOne(silence.QIDs("b"))
+			require.NoError(t, err)
+			assert.Equal(t, "imported", sil.Comment)
+		})
+	}
+}
+
+func TestStateTransfer_ImportInvalidState(t *testing.T) {
+	tr := newTestStateTransfer(t, "user-1")
+
+	validState, err := (&alertspb.FullStateDesc{State: &clusterpb.FullState{Parts: []clusterpb.Part{
+		encodeTestStatePart(t, "sil:user-1", newTestMeshSilence("a", "imported", time.Now())),
+	}}}).Marshal()
+	require.NoError(t, err)
+
+	unknownKeyState, err := (&alertspb.FullStateDesc{State: &clusterpb.FullState{Parts: []clusterpb.Part{
+		{Key: "unknown:user-1", Data: []byte("data")},
+	}}}).Marshal()
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		url  string
+		body []byte
+	}{
+		"invalid conflict handling": {
+			url:  stateTransferPath + "?conflict=unknown",
+			body: validState,
+		},
+		"invalid state": {
+			url:  stateTransferPath,
+			body: []byte("invalid"),
+		},
+		"unknown state key": {
+			url:  stateTransferPath,
+			body: unknownKeyState,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tr.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tc.url, bytes.NewReader(tc.body)))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			body, err := ioutil.ReadAll(rec.Body)
+			require.NoError(t, err)
+			assert.NotEmpty(t, body)
+		})
+	}
+
+	// The valid state wasn't imported by the invalid requests.
+	_, err = tr.silences.QueryOne(silence.QIDs("a"))
+	assert.ErrorIs(t, err, silence.ErrNotFound)
+}
+
+func TestStateTransfer_ImportStateTooLarge(t *testing.T) {
+	tr := newTestStateTransfer(t, "user-1")
+
+	state, err := (&alertspb.FullStateDesc{State: &clusterpb.FullState{Parts: []clusterpb.Part{
+		encodeTestStatePart(t, "sil:user-1", newTestMeshSilence("a", "imported", time.Now())),
+	}}}).Marshal()
+	require.NoError(t, err)
+	tr.maxStateSize = int64(len(state)) - 1
+
+	rec := httptest.NewRecorder()
+	tr.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, stateTransferPath, bytes.NewReader(state)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	_, err = tr.silences.QueryOne(silence.QIDs("a"))
+	assert.ErrorIs(t, err, silence.ErrNotFound)
+
+	// The state is imported within the maximum size.
+	tr.maxStateSize = int64(len(state))
+
+	rec = httptest.NewRecorder()
+	tr.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, stateTransferPath, bytes.NewReader(state)))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
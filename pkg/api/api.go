@@ -197,6 +197,7 @@ func (a *API) RegisterAlertmanager(am *alertmanager.MultitenantAlertmanager, api
 	a.RegisterRoute("/multitenant_alertmanager/configs", http.HandlerFunc(am.ListAllConfigs), false, true, "GET")
 	a.RegisterRoute("/multitenant_alertmanager/ring", http.HandlerFunc(am.RingHandler), false, true, "GET", "POST")
 	a.RegisterRoute("/multitenant_alertmanager/delete_tenant_config", http.HandlerFunc(am.DeleteUserConfig), true, true, "POST")
+	a.RegisterRoute("/multitenant_alertmanager/state", am, true, true, "GET", "POST")
 	a.RegisterRoute(path.Join(a.cfg.AlertmanagerHTTPPrefix, "/api/v1/status/buildinfo"), buildInfoHandler, false, true, "GET")
 
 	// UI components lead to a large number of routes to support, utilize a path prefix instead
@@ -208,6 +209,22 @@ func (a *API) RegisterAlertmanager(am *alertmanager.MultitenantAlertmanager, api
 		a.RegisterRoute("/api/v1/alerts", http.HandlerFunc(am.GetUserConfig), true, true, "GET")
 		a.RegisterRoute("/api/v1/alerts", http.HandlerFunc(am.SetUserConfig), true, true, "POST")
 		a.RegisterRoute("/api/v1/alerts", http.HandlerFunc(am.DeleteUserConfig), true, true, "DELETE")
+
+		a.RegisterRoute("/api/v1/alerts/receivers", http.HandlerFunc(am.ListReceivers), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/receivers/{name}", http.HandlerFunc(am.GetReceiver), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/receivers/{name}", http.HandlerFunc(am.SetReceiver), true, true, "PUT")
+		a.RegisterRoute("/api/v1/alerts/receivers/{name}", http.HandlerFunc(am.DeleteReceiver), true, true, "DELETE")
+		a.RegisterRoute("/api/v1/alerts/route", http.HandlerFunc(am.GetRoute), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/route", http.HandlerFunc(am.SetRoute), true, true, "PUT")
+		a.RegisterRoute("/api/v1/alerts/mute_time_intervals", http.HandlerFunc(am.ListMuteTimeIntervals), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/mute_time_intervals/{name}", http.HandlerFunc(am.GetMuteTimeInterval), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/mute_time_intervals/{name}", http.HandlerFunc(am.SetMuteTimeInterval), true, true, "PUT")
+		a.RegisterRoute("/api/v1/alerts/mute_time_intervals/{name}", http.HandlerFunc(am.DeleteMuteTimeInterval), true, true, "DELETE")
+		a.RegisterRoute("/api/v1/alerts/templates", http.HandlerFunc(am.ListTemplates), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/templates/{name}", http.HandlerFunc(am.GetTemplate), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/templates/{name}", http.HandlerFunc(am.SetTemplate), true, true, "PUT")
+		a.RegisterRoute("/api/v1/alerts/templates/{name}", http.HandlerFunc(am.DeleteTemplate), true, true, "DELETE")
+		a.RegisterRoute("/api/v1/alerts/test_receiver", http.HandlerFunc(am.TestReceiver), true, true, "POST")
 	}
 }
 
@@ -283,6 +300,13 @@ func (a *API) RegisterTenantDeletion(api *purger.TenantDeletionAPI) {
 	a.RegisterRoute("/purger/delete_tenant_status", http.HandlerFunc(api.DeleteTenantStatus), true, true, "GET")
 }
 
+// RegisterSeriesDeletion registers the Prometheus compatible series deletion API.
+func (a *API) RegisterSeriesDeletion(api *purger.SeriesDeletionAPI) {
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/admin/tsdb/delete_series"), http.HandlerFunc(api.AddDeleteRequest), true, true, "PUT", "POST")
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/admin/tsdb/delete_series"), http.HandlerFunc(api.GetAllDeleteRequests), true, true, "GET")
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/admin/tsdb/cancel_delete_request"), http.HandlerFunc(api.CancelDeleteRequest), true, true, "PUT", "POST")
+}
+
 // RegisterRuler registers routes associated with the Ruler service.
 func (a *API) RegisterRuler(r *ruler.Ruler) {
 	a.indexPage.AddLinks(defaultWeight, "Ruler", []IndexPageLink{
@@ -306,6 +330,7 @@ func (a *API) RegisterRulerAPI(r *ruler.API, configAPIEnabled bool) {
 	// you would like the API to be disabled and still be able to understand in what state rule evaluations are.
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/rules"), http.HandlerFunc(r.PrometheusRules), true, true, "GET")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/alerts"), http.HandlerFunc(r.PrometheusAlerts), true, true, "GET")
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/rules/history"), http.HandlerFunc(r.PrometheusRulesHistory), true, true, "GET")
 
 	if configAPIEnabled {
 		// Long-term maintained configuration API routes
@@ -313,6 +338,7 @@ func (a *API) RegisterRulerAPI(r *ruler.API, configAPIEnabled bool) {
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}"), http.HandlerFunc(r.ListRules), true, true, "GET")
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}/{groupName}"), http.HandlerFunc(r.GetRuleGroup), true, true, "GET")
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}"), http.HandlerFunc(r.CreateRuleGroup), true, true, "POST")
+		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}/test"), http.HandlerFunc(r.TestRuleGroup), true, true, "POST")
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}/{groupName}"), http.HandlerFunc(r.DeleteRuleGroup), true, true, "DELETE")
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}"), http.HandlerFunc(r.DeleteNamespace), true, true, "DELETE")
 	}
@@ -343,11 +369,17 @@ func (a *API) RegisterStoreGateway(s *storegateway.StoreGateway) {
 func (a *API) RegisterCompactor(c *compactor.MultitenantCompactor) {
 	a.indexPage.AddLinks(defaultWeight, "Compactor", []IndexPageLink{
 		{Desc: "Ring status", Path: "/compactor/ring"},
+		{Desc: "Tenants & Compaction jobs", Path: "/compactor/tenants"},
 	})
 	a.RegisterRoute("/compactor/ring", http.HandlerFunc(c.RingHandler), false, true, "GET", "POST")
+	a.RegisterRoute("/compactor/tenants", http.HandlerFunc(c.TenantsHandler), false, true, "GET")
+	a.RegisterRoute("/compactor/tenant/{tenant}/jobs", http.HandlerFunc(c.JobsHandler), false, true, "GET")
+	a.RegisterRoute("/compactor/tenant/{tenant}/jobs/skip", http.HandlerFunc(c.SkipJobHandler), false, true, "POST")
+	a.RegisterRoute("/compactor/tenant/{tenant}/jobs/retry", http.HandlerFunc(c.RetryJobHandler), false, true, "POST")
 	a.RegisterRoute("/api/v1/upload/block/{block}/start", http.HandlerFunc(c.StartBlockUpload), true, false, http.MethodPost)
 	a.RegisterRoute("/api/v1/upload/block/{block}/files", http.HandlerFunc(c.UploadBlockFile), true, false, http.MethodPost)
 	a.RegisterRoute("/api/v1/upload/block/{block}/finish", http.HandlerFunc(c.FinishBlockUpload), true, false, http.MethodPost)
+	a.RegisterRoute("/api/v1/upload/block/{block}/status", http.HandlerFunc(c.GetBlockUploadState), true, false, http.MethodGet)
 }
 
 type Distributor interface {
@@ -10,6 +10,8 @@ import (
 	"io"
 	"net/http"
 	"path"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/go-kit/log"
@@ -34,6 +36,27 @@ import (
 // Name of file where we store a block's meta file while it's being uploaded.
 const uploadingMetaFilename = "uploading-" + block.MetaFilename
 
+// Name of directory where we store the parts of a block's files while they're being uploaded in parts.
+const uploadingPartsDirname = "uploading-parts"
+
+// Name of file where we store the state of the validation of a block being uploaded.
+const validationFilename = "validation.json"
+
+const (
+	blockUploadStateUploading  = "uploading"
+	blockUploadStateValidating = "validating"
+	blockUploadStateFailed     = "failed"
+	blockUploadStateComplete   = "complete"
+)
+
+const (
+	// validationHeartbeatInterval is how often the validation file of a block being validated is updated.
+	validationHeartbeatInterval = 1 * time.Minute
+	// validationHeartbeatTimeout is how long after its last update the validation of a block is considered
+	// interrupted, for example because the compactor running it has been restarted.
+	validationHeartbeatTimeout = 5 * time.Minute
+)
+
 var rePath = regexp.MustCompile(`^(index|chunks/\d{6})$`)
 
 // StartBlockUpload handles request for starting block upload.
@@ -69,8 +92,9 @@ func (c *MultitenantCompactor) StartBlockUpload(w http.ResponseWriter, r *http.R
 
 // FinishBlockUpload handles request for finishing block upload.
 //
-// Finishing block upload performs block valiation, and if all checks pass, marks block as finished
-// by uploading meta.json file.
+// Finishing block upload marks block as finished by uploading meta.json file. If block validation is
+// enabled, the block is validated in the background, and marked as finished only if all checks pass;
+// the progress of the validation is reported by GetBlockUploadState.
 func (c *MultitenantCompactor) FinishBlockUpload(w http.ResponseWriter, r *http.Request) {
 	blockID, tenantID, err := c.parseBlockUploadParameters(r)
 	if err != nil {
@@ -89,7 +113,7 @@ func (c *MultitenantCompactor) FinishBlockUpload(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if err := c.completeBlockUpload(ctx, r, logger, userBkt, blockID); err != nil {
+	if err := c.completeBlockUpload(ctx, r, logger, userBkt, tenantID, blockID); err != nil {
 		writeBlockUploadError(err, op, "", logger, w)
 		return
 	}
@@ -187,6 +211,10 @@ func (c *MultitenantCompactor) createBlockUpload(ctx context.Context, r *http.Re
 // UploadBlockFile handles requests for uploading block files.
 //
 // It takes the mandatory query parameter "path", specifying the file's destination path.
+// If the optional query parameter "offset" is set, the request body is a part of the file starting
+// at that offset, and the file is stored once all of its parts, as listed in the meta file, have been
+// uploaded. Parts must be uploaded in order, which allows to resume an interrupted upload from the
+// progress reported by this handler or by GetBlockUploadState.
 func (c *MultitenantCompactor) UploadBlockFile(w http.ResponseWriter, r *http.Request) {
 	blockID, tenantID, err := c.parseBlockUploadParameters(r)
 	if err != nil {
@@ -239,6 +267,17 @@ func (c *MultitenantCompactor) UploadBlockFile(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if r.URL.Query().Has("offset") {
+		progress, err := c.uploadBlockFilePart(ctx, r, logger, userBkt, blockID, pth)
+		if err != nil {
+			writeBlockUploadError(err, op, "", logger, w)
+			return
+		}
+
+		util.WriteJSONResponse(w, progress)
+		return
+	}
+
 	// TODO: Verify that upload path and length correspond to file index
 
 	dst := path.Join(blockID.String(), pth)
@@ -258,6 +297,327 @@ func (c *MultitenantCompactor) UploadBlockFile(w http.ResponseWriter, r *http.Re
 	w.WriteHeader(http.StatusOK)
 }
 
+// fileUploadProgress is the progress of the upload of a single block file.
+type fileUploadProgress struct {
+	Path          string `json:"path"`
+	SizeBytes     int64  `json:"size_bytes"`
+	UploadedBytes int64  `json:"uploaded_bytes"`
+}
+
+// blockUploadState is the state of the upload of a block.
+type blockUploadState struct {
+	State         string               `json:"state"`
+	Error         string               `json:"error,omitempty"`
+	SizeBytes     int64                `json:"size_bytes,omitempty"`
+	UploadedBytes int64                `json:"uploaded_bytes,omitempty"`
+	Files         []fileUploadProgress `json:"files,omitempty"`
+}
+
+// GetBlockUploadState handles requests for getting the state of a block upload, including the
+// progress of each file for uploads which are still in progress.
+func (c *MultitenantCompactor) GetBlockUploadState(w http.ResponseWriter, r *http.Request) {
+	blockID, tenantID, err := c.parseBlockUploadParameters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	logger := log.With(util_log.WithContext(ctx, c.logger), "block", blockID)
+
+	const op = "get block upload state"
+
+	userBkt := bucket.NewUserBucketClient(tenantID, c.bucketClient, c.cfgProvider)
+	state, err := c.getBlockUploadState(ctx, userBkt, blockID)
+	if err != nil {
+		writeBlockUploadError(err, op, "", logger, w)
+		return
+	}
+
+	util.WriteJSONResponse(w, state)
+}
+
+func (c *MultitenantCompactor) getBlockUploadState(ctx context.Context, userBkt objstore.Bucket, blockID ulid.ULID) (blockUploadState, error) {
+	exists, err := userBkt.Exists(ctx, path.Join(blockID.String(), block.MetaFilename))
+	if err != nil {
+		return blockUploadState{}, errors.Wrap(err, fmt.Sprintf("failed to check existence of %s in object storage", block.MetaFilename))
+	}
+	if exists {
+		return blockUploadState{State: blockUploadStateComplete}, nil
+	}
+
+	meta, err := readUploadingMeta(ctx, userBkt, blockID)
+	if err != nil {
+		return blockUploadState{}, err
+	}
+
+	validation, err := readValidationFile(ctx, userBkt, blockID)
+	if err != nil {
+		return blockUploadState{}, err
+	}
+	if validation != nil {
+		if validation.Error != "" {
+			return blockUploadState{State: blockUploadStateFailed, Error: validation.Error}, nil
+		}
+		if validation.isInProgress(time.Now()) {
+			return blockUploadState{State: blockUploadStateValidating}, nil
+		}
+		return blockUploadState{State: blockUploadStateFailed, Error: "validation timed out"}, nil
+	}
+
+	state := blockUploadState{State: blockUploadStateUploading}
+	for _, f := range meta.Thanos.Files {
+		if f.RelPath == block.MetaFilename {
+			continue
+		}
+
+		progress := fileUploadProgress{Path: f.RelPath, SizeBytes: f.SizeBytes}
+
+		exists, err := userBkt.Exists(ctx, path.Join(blockID.String(), f.RelPath))
+		if err != nil {
+			return blockUploadState{}, errors.Wrapf(err, "failed to check existence of %s in object storage", f.RelPath)
+		}
+		if exists {
+			progress.UploadedBytes = f.SizeBytes
+		} else {
+			parts, err := listFileParts(ctx, userBkt, blockID, f.RelPath)
+			if err != nil {
+				return blockUploadState{}, err
+			}
+			progress.UploadedBytes = parts.uploadedBytes()
+		}
+
+		state.SizeBytes += progress.SizeBytes
+		state.UploadedBytes += progress.UploadedBytes
+		state.Files = append(state.Files, progress)
+	}
+
+	return state, nil
+}
+
+// uploadBlockFilePart uploads a part of a block file and, once all the parts of the file have been
+// uploaded, stores the file by concatenating the parts.
+func (c *MultitenantCompactor) uploadBlockFilePart(ctx context.Context, r *http.Request, logger log.Logger,
+	userBkt objstore.Bucket, blockID ulid.ULID, pth string) (fileUploadProgress, error) {
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return fileUploadProgress{}, httpError{
+			message:    "invalid offset",
+			statusCode: http.StatusBadRequest,
+		}
+	}
+	if r.ContentLength < 0 {
+		return fileUploadProgress{}, httpError{
+			message:    "missing content length",
+			statusCode: http.StatusBadRequest,
+		}
+	}
+
+	meta, err := readUploadingMeta(ctx, userBkt, blockID)
+	if err != nil {
+		return fileUploadProgress{}, err
+	}
+
+	progress := fileUploadProgress{Path: pth, SizeBytes: -1}
+	for _, f := range meta.Thanos.Files {
+		if f.RelPath == pth {
+			progress.SizeBytes = f.SizeBytes
+			break
+		}
+	}
+	if progress.SizeBytes < 0 {
+		return fileUploadProgress{}, httpError{
+			message:    fmt.Sprintf("file %s is not listed in the block meta file", pth),
+			statusCode: http.StatusBadRequest,
+		}
+	}
+	if offset+r.ContentLength > progress.SizeBytes {
+		return fileUploadProgress{}, httpError{
+			message:    fmt.Sprintf("file part exceeds the file size of %d bytes", progress.SizeBytes),
+			statusCode: http.StatusBadRequest,
+		}
+	}
+
+	parts, err := listFileParts(ctx, userBkt, blockID, pth)
+	if err != nil {
+		return fileUploadProgress{}, err
+	}
+	if uploaded := parts.uploadedBytes(); offset != uploaded {
+		return fileUploadProgress{}, httpError{
+			message:    fmt.Sprintf("unexpected offset %d, the next part of the file must start at offset %d", offset, uploaded),
+			statusCode: http.StatusConflict,
+		}
+	}
+
+	part := filePart{offset: offset, size: r.ContentLength}
+	partPath := part.path(blockID, pth)
+	level.Debug(logger).Log("msg", "uploading block file part to bucket", "destination", partPath, "offset", offset, "size", r.ContentLength)
+	if err := userBkt.Upload(ctx, partPath, bodyReader{r: r}); err != nil {
+		return fileUploadProgress{}, errors.Wrapf(err, "failed uploading %s to bucket", partPath)
+	}
+
+	parts = append(parts, part)
+	progress.UploadedBytes = parts.uploadedBytes()
+	if progress.UploadedBytes < progress.SizeBytes {
+		return progress, nil
+	}
+
+	dst := path.Join(blockID.String(), pth)
+	level.Debug(logger).Log("msg", "all parts of block file uploaded, concatenating them", "destination", dst, "parts", len(parts))
+
+	partsReader := &filePartsReader{ctx: ctx, bkt: userBkt, blockID: blockID, pth: pth, parts: parts, size: progress.SizeBytes}
+	err = userBkt.Upload(ctx, dst, partsReader)
+	partsReader.close()
+	if err != nil {
+		// Delete the parts, so that the upload of the file can be restarted from scratch.
+		deleteFileParts(ctx, logger, userBkt, blockID, pth, parts)
+		return fileUploadProgress{}, errors.Wrapf(err, "failed uploading %s to bucket", dst)
+	}
+
+	deleteFileParts(ctx, logger, userBkt, blockID, pth, parts)
+	return progress, nil
+}
+
+// readUploadingMeta reads the meta file of a block being uploaded.
+func readUploadingMeta(ctx context.Context, userBkt objstore.Bucket, blockID ulid.ULID) (metadata.Meta, error) {
+	rdr, err := userBkt.Get(ctx, path.Join(blockID.String(), uploadingMetaFilename))
+	if err != nil {
+		if userBkt.IsObjNotFoundErr(err) {
+			return metadata.Meta{}, httpError{
+				message:    fmt.Sprintf("upload of block %s not started yet", blockID),
+				statusCode: http.StatusNotFound,
+			}
+		}
+		return metadata.Meta{}, errors.Wrap(err, fmt.Sprintf("failed to download %s from object storage", uploadingMetaFilename))
+	}
+	defer func() {
+		_ = rdr.Close()
+	}()
+
+	return decodeMeta(rdr, uploadingMetaFilename)
+}
+
+// filePart is a part of a block file uploaded on its own.
+type filePart struct {
+	offset int64
+	size   int64
+}
+
+// path returns the path of the part in the tenant's bucket. The part offset and size are encoded in
+// the object name, so that listing the parts is enough to know the progress of the file upload.
+func (p filePart) path(blockID ulid.ULID, pth string) string {
+	return path.Join(blockID.String(), uploadingPartsDirname, pth, fmt.Sprintf("%020d-%020d", p.offset, p.size))
+}
+
+type fileParts []filePart
+
+// uploadedBytes returns the number of contiguous bytes uploaded from the beginning of the file.
+func (ps fileParts) uploadedBytes() int64 {
+	var uploaded int64
+	for _, p := range ps {
+		if p.offset != uploaded {
+			break
+		}
+		uploaded += p.size
+	}
+	return uploaded
+}
+
+// listFileParts returns the uploaded parts of a block file, sorted by offset.
+func listFileParts(ctx context.Context, userBkt objstore.Bucket, blockID ulid.ULID, pth string) (fileParts, error) {
+	var parts fileParts
+	err := userBkt.Iter(ctx, path.Join(blockID.String(), uploadingPartsDirname, pth)+"/", func(name string) error {
+		var p filePart
+		if _, err := fmt.Sscanf(path.Base(name), "%d-%d", &p.offset, &p.size); err != nil {
+			return nil
+		}
+		parts = append(parts, p)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the uploaded parts of %s", pth)
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].offset < parts[j].offset
+	})
+	return parts, nil
+}
+
+func deleteFileParts(ctx context.Context, logger log.Logger, userBkt objstore.Bucket, blockID ulid.ULID, pth string, parts fileParts) {
+	for _, p := range parts {
+		if err := userBkt.Delete(ctx, p.path(blockID, pth)); err != nil && !userBkt.IsObjNotFoundErr(err) {
+			level.Warn(logger).Log("msg", "failed to delete block file part", "path", p.path(blockID, pth), "err", err)
+		}
+	}
+}
+
+// deleteUploadingParts deletes the parts of all the block files which are left in the bucket, for example
+// because a file has been uploaded in parts but then uploaded again at once.
+func deleteUploadingParts(ctx context.Context, logger log.Logger, userBkt objstore.Bucket, blockID ulid.ULID) {
+	err := userBkt.Iter(ctx, path.Join(blockID.String(), uploadingPartsDirname)+"/", func(name string) error {
+		if err := userBkt.Delete(ctx, name); err != nil && !userBkt.IsObjNotFoundErr(err) {
+			level.Warn(logger).Log("msg", "failed to delete block file part", "path", name, "err", err)
+		}
+		return nil
+	}, objstore.WithRecursiveIter)
+	if err != nil {
+		level.Warn(logger).Log("msg", "failed to list the block file parts to delete", "err", err)
+	}
+}
+
+// filePartsReader reads the parts of a block file one after the other.
+type filePartsReader struct {
+	ctx     context.Context
+	bkt     objstore.Bucket
+	blockID ulid.ULID
+	pth     string
+	parts   fileParts
+	size    int64
+
+	curr io.ReadCloser
+}
+
+// ObjectSize implements thanos.ObjectSizer.
+func (r *filePartsReader) ObjectSize() (int64, error) {
+	return r.size, nil
+}
+
+// Read implements io.Reader.
+func (r *filePartsReader) Read(b []byte) (int, error) {
+	for {
+		if r.curr == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+
+			rc, err := r.bkt.Get(r.ctx, r.parts[0].path(r.blockID, r.pth))
+			if err != nil {
+				return 0, err
+			}
+			r.curr = rc
+			r.parts = r.parts[1:]
+		}
+
+		n, err := r.curr.Read(b)
+		if errors.Is(err, io.EOF) {
+			r.close()
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *filePartsReader) close() {
+	if r.curr != nil {
+		_ = r.curr.Close()
+		r.curr = nil
+	}
+}
+
 func decodeMeta(r io.Reader, name string) (metadata.Meta, error) {
 	dec := json.NewDecoder(r)
 	var meta metadata.Meta
@@ -269,29 +629,139 @@ func decodeMeta(r io.Reader, name string) (metadata.Meta, error) {
 }
 
 func (c *MultitenantCompactor) completeBlockUpload(ctx context.Context, r *http.Request,
-	logger log.Logger, userBkt objstore.Bucket, blockID ulid.ULID) error {
+	logger log.Logger, userBkt objstore.Bucket, tenantID string, blockID ulid.ULID) error {
 	level.Debug(logger).Log("msg", "received request to complete block upload", "content_length", r.ContentLength)
 
-	uploadingMetaPath := path.Join(blockID.String(), uploadingMetaFilename)
-	rdr, err := userBkt.Get(ctx, uploadingMetaPath)
+	meta, err := readUploadingMeta(ctx, userBkt, blockID)
 	if err != nil {
-		if userBkt.IsObjNotFoundErr(err) {
-			return httpError{
-				message:    fmt.Sprintf("upload of block %s not started yet", blockID),
-				statusCode: http.StatusNotFound,
-			}
+		return err
+	}
+
+	if !c.cfgProvider.CompactorBlockUploadValidationEnabled(tenantID) {
+		return c.markBlockUploadComplete(ctx, logger, userBkt, blockID, meta)
+	}
+
+	validation, err := readValidationFile(ctx, userBkt, blockID)
+	if err != nil {
+		return err
+	}
+	if validation != nil && validation.Error == "" && validation.isInProgress(time.Now()) {
+		return httpError{
+			message:    "block validation in progress",
+			statusCode: http.StatusConflict,
 		}
-		return errors.Wrap(err, fmt.Sprintf("failed to download %s from object storage", uploadingMetaFilename))
 	}
-	defer func() {
-		_ = rdr.Close()
+
+	if err := checkUploadedBlockFiles(ctx, userBkt, blockID, meta); err != nil {
+		return err
+	}
+
+	if err := uploadValidationFile(ctx, userBkt, blockID, validationFile{LastUpdate: time.Now().UnixMilli()}); err != nil {
+		return err
+	}
+
+	// The block is validated in the background, because downloading and checking a large block may take
+	// longer than clients and proxies are willing to wait for the response. The validation is rejected if
+	// all the validation workers are busy, so that the compactor doesn't run an unbounded number of them.
+	select {
+	case c.blockUploadValidationSlots <- struct{}{}:
+		// The queue has as many slots as workers, so this never blocks.
+		c.blockUploadValidations <- blockUploadValidation{logger: logger, userBkt: userBkt, blockID: blockID, meta: meta}
+	default:
+		if err := userBkt.Delete(ctx, path.Join(blockID.String(), validationFilename)); err != nil && !userBkt.IsObjNotFoundErr(err) {
+			level.Warn(logger).Log("msg", fmt.Sprintf("failed to delete %s from block in object storage", validationFilename), "err", err)
+		}
+		return httpError{
+			message:    "too many block validations in progress, retry later",
+			statusCode: http.StatusTooManyRequests,
+		}
+	}
+
+	level.Debug(logger).Log("msg", "started validation of uploaded block")
+	return nil
+}
+
+// blockUploadValidation is the validation of an uploaded block, run by the block upload validation workers.
+type blockUploadValidation struct {
+	logger  log.Logger
+	userBkt objstore.Bucket
+	blockID ulid.ULID
+	meta    metadata.Meta
+}
+
+// runBlockUploadValidations runs the validations of the uploaded blocks, one at a time, until the context is done.
+func (c *MultitenantCompactor) runBlockUploadValidations(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case v := <-c.blockUploadValidations:
+			c.validateAndCompleteBlockUpload(ctx, v.logger, v.userBkt, v.blockID, v.meta)
+			<-c.blockUploadValidationSlots
+		}
+	}
+}
+
+// validateAndCompleteBlockUpload validates the uploaded block and marks its upload as complete if the block
+// is valid, while reporting the validation progress in the block's validation file. If the context is done
+// before the validation completes, the validation file is left as is, and the validation is considered
+// interrupted once its heartbeat times out.
+func (c *MultitenantCompactor) validateAndCompleteBlockUpload(serviceCtx context.Context, logger log.Logger, userBkt objstore.Bucket, blockID ulid.ULID, meta metadata.Meta) {
+	ctx, cancel := context.WithCancel(serviceCtx)
+	defer cancel()
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+
+		ticker := time.NewTicker(validationHeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := uploadValidationFile(ctx, userBkt, blockID, validationFile{LastUpdate: time.Now().UnixMilli()}); err != nil && ctx.Err() == nil {
+					level.Warn(logger).Log("msg", "failed to update block validation heartbeat", "err", err)
+				}
+			}
+		}
 	}()
 
-	meta, err := decodeMeta(rdr, uploadingMetaFilename)
+	err := c.validateUploadedBlock(ctx, logger, userBkt, blockID, meta)
+
+	// Stop the heartbeat before updating the validation file for the last time.
+	cancel()
+	<-heartbeatDone
+
+	if serviceCtx.Err() != nil {
+		level.Warn(logger).Log("msg", "validation of uploaded block interrupted", "err", serviceCtx.Err())
+		return
+	}
+	ctx = serviceCtx
+
+	if err == nil {
+		err = c.markBlockUploadComplete(ctx, logger, userBkt, blockID, meta)
+	}
 	if err != nil {
-		return err
+		level.Warn(logger).Log("msg", "validation of uploaded block failed", "err", err)
+		deleteUploadingParts(ctx, logger, userBkt, blockID)
+
+		if err := uploadValidationFile(ctx, userBkt, blockID, validationFile{LastUpdate: time.Now().UnixMilli(), Error: err.Error()}); err != nil {
+			level.Error(logger).Log("msg", "failed to store block validation error", "err", err)
+		}
+		return
 	}
 
+	if err := userBkt.Delete(ctx, path.Join(blockID.String(), validationFilename)); err != nil && !userBkt.IsObjNotFoundErr(err) {
+		level.Warn(logger).Log("msg", fmt.Sprintf("failed to delete %s from block in object storage", validationFilename), "err", err)
+	}
+}
+
+// markBlockUploadComplete uploads the block meta file, so the block is considered complete, and deletes
+// the files only needed while the block is being uploaded.
+func (c *MultitenantCompactor) markBlockUploadComplete(ctx context.Context, logger log.Logger, userBkt objstore.Bucket, blockID ulid.ULID, meta metadata.Meta) error {
 	level.Debug(logger).Log("msg", "completing block upload", "files", len(meta.Thanos.Files))
 
 	// Upload meta file so block is considered complete
@@ -299,7 +769,9 @@ func (c *MultitenantCompactor) completeBlockUpload(ctx context.Context, r *http.
 		return err
 	}
 
-	if err := userBkt.Delete(ctx, uploadingMetaPath); err != nil {
+	deleteUploadingParts(ctx, logger, userBkt, blockID)
+
+	if err := userBkt.Delete(ctx, path.Join(blockID.String(), uploadingMetaFilename)); err != nil {
 		level.Warn(logger).Log("msg", fmt.Sprintf(
 			"failed to delete %s from block in object storage", uploadingMetaFilename), "err", err)
 		return nil
@@ -309,6 +781,51 @@ func (c *MultitenantCompactor) completeBlockUpload(ctx context.Context, r *http.
 	return nil
 }
 
+// validationFile is the content of the file storing the state of the validation of a block being uploaded.
+type validationFile struct {
+	// Unix timestamp (milliseconds) of the last update of the file while the validation is in progress.
+	LastUpdate int64 `json:"last_update"`
+	// Error is set if the validation failed.
+	Error string `json:"error,omitempty"`
+}
+
+// isInProgress returns whether the validation is still running, based on its heartbeat.
+func (v validationFile) isInProgress(now time.Time) bool {
+	return now.Sub(time.UnixMilli(v.LastUpdate)) < validationHeartbeatTimeout
+}
+
+// readValidationFile reads the validation file of a block being uploaded. Returns nil if the validation
+// of the block hasn't been started.
+func readValidationFile(ctx context.Context, userBkt objstore.Bucket, blockID ulid.ULID) (*validationFile, error) {
+	rdr, err := userBkt.Get(ctx, path.Join(blockID.String(), validationFilename))
+	if err != nil {
+		if userBkt.IsObjNotFoundErr(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to download %s from object storage", validationFilename))
+	}
+	defer func() {
+		_ = rdr.Close()
+	}()
+
+	v := &validationFile{}
+	if err := json.NewDecoder(rdr).Decode(v); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed decoding %s", validationFilename))
+	}
+	return v, nil
+}
+
+func uploadValidationFile(ctx context.Context, userBkt objstore.Bucket, blockID ulid.ULID, v validationFile) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode block validation state")
+	}
+	if err := userBkt.Upload(ctx, path.Join(blockID.String(), validationFilename), bytes.NewReader(data)); err != nil {
+		return errors.Wrapf(err, "failed uploading %s to bucket", validationFilename)
+	}
+	return nil
+}
+
 // sanitizeMeta sanitizes and validates a metadata.Meta object. If a validation error occurs, an error
 // message gets returned, otherwise an empty string.
 func (c *MultitenantCompactor) sanitizeMeta(logger log.Logger, blockID ulid.ULID, meta *metadata.Meta) string {
@@ -1018,6 +1018,7 @@ func TestMultitenantCompactor_HandleBlockUpload_Complete(t *testing.T) {
 	const blockID = "01G3FZ0JWJYJC0ZM6Y9778P6KD"
 	uploadingMetaPath := path.Join(tenantID, blockID, fmt.Sprintf("uploading-%s", block.MetaFilename))
 	metaPath := path.Join(tenantID, blockID, block.MetaFilename)
+	uploadingPartsPath := path.Join(tenantID, blockID, uploadingPartsDirname) + "/"
 	validMeta := metadata.Meta{
 		BlockMeta: tsdb.BlockMeta{
 			ULID: ulid.MustParse(blockID),
@@ -1045,6 +1046,7 @@ func TestMultitenantCompactor_HandleBlockUpload_Complete(t *testing.T) {
 		bkt.MockExists(metaPath, false, nil)
 		setUpGet(bkt, uploadingMetaPath, metaJSON, nil)
 		bkt.MockUpload(metaPath, nil)
+		bkt.MockIter(uploadingPartsPath, nil, nil)
 		bkt.MockDelete(uploadingMetaPath, nil)
 	}
 	testCases := []struct {
@@ -1154,6 +1156,7 @@ func TestMultitenantCompactor_HandleBlockUpload_Complete(t *testing.T) {
 				require.NoError(t, err)
 				setUpGet(bkt, uploadingMetaPath, metaJSON, nil)
 				bkt.MockUpload(metaPath, nil)
+				bkt.MockIter(uploadingPartsPath, nil, nil)
 				bkt.MockDelete(uploadingMetaPath, fmt.Errorf("test"))
 			},
 			expMeta:      validMeta,
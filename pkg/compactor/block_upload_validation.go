@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/runutil"
+)
+
+// checkUploadedBlockFiles checks that all the files listed in the meta file of a block being uploaded
+// have been completely uploaded.
+func checkUploadedBlockFiles(ctx context.Context, userBkt objstore.Bucket, blockID ulid.ULID, meta metadata.Meta) error {
+	for _, f := range meta.Thanos.Files {
+		if f.RelPath == block.MetaFilename {
+			continue
+		}
+
+		attrs, err := userBkt.Attributes(ctx, path.Join(blockID.String(), f.RelPath))
+		if userBkt.IsObjNotFoundErr(err) {
+			return httpError{
+				message:    fmt.Sprintf("file %s has not been uploaded", f.RelPath),
+				statusCode: http.StatusBadRequest,
+			}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to get attributes of %s", f.RelPath)
+		}
+		if attrs.Size != f.SizeBytes {
+			return httpError{
+				message:    fmt.Sprintf("file %s has size %d, expected %d", f.RelPath, attrs.Size, f.SizeBytes),
+				statusCode: http.StatusBadRequest,
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateUploadedBlock downloads the files of a block being uploaded and checks that the block is valid,
+// so that a broken block never lands in the storage as a complete block.
+func (c *MultitenantCompactor) validateUploadedBlock(ctx context.Context, logger log.Logger, userBkt objstore.Bucket, blockID ulid.ULID, meta metadata.Meta) error {
+	blockDir := filepath.Join(c.compactorCfg.DataDir, "upload", blockID.String())
+	if err := os.RemoveAll(blockDir); err != nil {
+		return errors.Wrap(err, "failed to clean up block validation directory")
+	}
+	defer func() {
+		if err := os.RemoveAll(blockDir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove block validation directory", "path", blockDir, "err", err)
+		}
+	}()
+
+	level.Debug(logger).Log("msg", "downloading block for validation", "dir", blockDir)
+
+	if err := os.MkdirAll(blockDir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "failed to create block validation directory")
+	}
+	if err := objstore.DownloadFile(ctx, logger, userBkt, path.Join(blockID.String(), block.IndexFilename), filepath.Join(blockDir, block.IndexFilename)); err != nil {
+		return errors.Wrap(err, "failed to download block index")
+	}
+	if err := objstore.DownloadDir(ctx, logger, userBkt, blockID.String(), path.Join(blockID.String(), block.ChunksDirname), filepath.Join(blockDir, block.ChunksDirname)); err != nil {
+		return errors.Wrap(err, "failed to download block chunks")
+	}
+	if err := meta.WriteToDir(logger, blockDir); err != nil {
+		return errors.Wrap(err, "failed to write block meta")
+	}
+
+	if err := verifyBlock(logger, blockDir, meta); err != nil {
+		return errors.Wrap(err, "block validation failed")
+	}
+
+	level.Debug(logger).Log("msg", "block successfully validated")
+	return nil
+}
+
+// verifyBlock checks the integrity of the block in the input directory: the index must be readable and
+// consistent with the time range of the meta, every chunk referenced by the index must be readable and
+// hold samples within the chunk time range, and series labels must be valid.
+func verifyBlock(logger log.Logger, blockDir string, meta metadata.Meta) (err error) {
+	if err := block.VerifyIndex(logger, filepath.Join(blockDir, block.IndexFilename), meta.MinTime, meta.MaxTime); err != nil {
+		return errors.Wrap(err, "invalid index")
+	}
+
+	b, err := tsdb.OpenBlock(logger, blockDir, nil)
+	if err != nil {
+		return errors.Wrap(err, "open block")
+	}
+	defer runutil.CloseWithErrCapture(&err, b, "close block")
+
+	indexr, err := b.Index()
+	if err != nil {
+		return errors.Wrap(err, "open index")
+	}
+	defer runutil.CloseWithErrCapture(&err, indexr, "close index reader")
+
+	chunkr, err := b.Chunks()
+	if err != nil {
+		return errors.Wrap(err, "open chunks")
+	}
+	defer runutil.CloseWithErrCapture(&err, chunkr, "close chunks reader")
+
+	postings, err := indexr.Postings(index.AllPostingsKey())
+	if err != nil {
+		return errors.Wrap(err, "get all postings")
+	}
+
+	var (
+		lset, prevLset labels.Labels
+		chks           []chunks.Meta
+	)
+	for postings.Next() {
+		if err := indexr.Series(postings.At(), &lset, &chks); err != nil {
+			return errors.Wrap(err, "read series")
+		}
+
+		if err := verifyLabels(lset); err != nil {
+			return errors.Wrapf(err, "series %s", lset)
+		}
+		if prevLset != nil && labels.Compare(prevLset, lset) >= 0 {
+			return errors.Errorf("series %s is out of order or duplicated", lset)
+		}
+		prevLset = lset.Copy()
+
+		for _, c := range chks {
+			chk, err := chunkr.Chunk(c)
+			if err != nil {
+				return errors.Wrapf(err, "series %s: read chunk %d", lset, c.Ref)
+			}
+
+			it := chk.Iterator(nil)
+			for it.Next() {
+				if t, _ := it.At(); t < c.MinTime || t > c.MaxTime {
+					return errors.Errorf("series %s: chunk %d has a sample at %d outside of its time range [%d, %d]", lset, c.Ref, t, c.MinTime, c.MaxTime)
+				}
+			}
+			if err := it.Err(); err != nil {
+				return errors.Wrapf(err, "series %s: iterate chunk %d", lset, c.Ref)
+			}
+		}
+	}
+
+	return errors.Wrap(postings.Err(), "iterate postings")
+}
+
+// verifyLabels checks that the series labels are valid, sorted and not duplicated.
+func verifyLabels(lset labels.Labels) error {
+	if len(lset) == 0 {
+		return errors.New("empty label set")
+	}
+
+	for i, l := range lset {
+		if !model.LabelName(l.Name).IsValid() {
+			return errors.Errorf("invalid label name %q", l.Name)
+		}
+		if l.Value == "" {
+			return errors.Errorf("empty value for label %q", l.Name)
+		}
+		if !model.LabelValue(l.Value).IsValid() {
+			return errors.Errorf("invalid value for label %q", l.Name)
+		}
+		if i > 0 && l.Name <= lset[i-1].Name {
+			return errors.Errorf("label %q is out of order or duplicated", l.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,392 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
+	"github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+)
+
+func TestVerifyLabels(t *testing.T) {
+	tests := map[string]struct {
+		lset        labels.Labels
+		expectedErr string
+	}{
+		"valid labels": {
+			lset: labels.FromStrings("__name__", "up", "job", "test"),
+		},
+		"empty label set": {
+			lset:        labels.Labels{},
+			expectedErr: "empty label set",
+		},
+		"invalid label name": {
+			lset:        labels.Labels{{Name: "in-valid", Value: "value"}},
+			expectedErr: `invalid label name "in-valid"`,
+		},
+		"empty label value": {
+			lset:        labels.Labels{{Name: "job", Value: ""}},
+			expectedErr: `empty value for label "job"`,
+		},
+		"invalid label value": {
+			lset:        labels.Labels{{Name: "job", Value: "\xff"}},
+			expectedErr: `invalid value for label "job"`,
+		},
+		"unsorted labels": {
+			lset:        labels.Labels{{Name: "job", Value: "test"}, {Name: "__name__", Value: "up"}},
+			expectedErr: `label "__name__" is out of order or duplicated`,
+		},
+		"duplicated labels": {
+			lset:        labels.Labels{{Name: "job", Value: "a"}, {Name: "job", Value: "b"}},
+			expectedErr: `label "job" is out of order or duplicated`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := verifyLabels(testData.lset)
+			if testData.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, testData.expectedErr)
+			}
+		})
+	}
+}
+
+func TestMultitenantCompactor_ResumableBlockUploadWithValidation(t *testing.T) {
+	const tenantID = "test"
+
+	specs := []*testutil.BlockSeriesSpec{
+		{
+			Labels: labels.FromStrings("__name__", "up", "job", "a"),
+			Chunks: []chunks.Meta{
+				tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(10, 1), newSample(20, 2)}),
+			},
+		},
+		{
+			Labels: labels.FromStrings("__name__", "up", "job", "b"),
+			Chunks: []chunks.Meta{
+				tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(10, 1), newSample(30, 3)}),
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		validationEnabled bool
+		corruptMeta       func(meta *metadata.Meta)
+		expectedState     string
+		expectedError     string
+	}{
+		"valid block": {
+			validationEnabled: true,
+			expectedState:     blockUploadStateComplete,
+		},
+		"block with samples outside of the meta time range": {
+			validationEnabled: true,
+			corruptMeta: func(meta *metadata.Meta) {
+				meta.MaxTime = 20
+			},
+			expectedState: blockUploadStateFailed,
+			expectedError: "block validation failed: invalid index",
+		},
+		"block with samples outside of the meta time range and validation disabled": {
+			validationEnabled: false,
+			corruptMeta: func(meta *metadata.Meta) {
+				meta.MaxTime = 20
+			},
+			expectedState: blockUploadStateComplete,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			blocksDir := t.TempDir()
+			meta, err := testutil.GenerateBlockFromSpec(tenantID, blocksDir, specs)
+			require.NoError(t, err)
+			blockID := meta.ULID.String()
+			blockDir := filepath.Join(blocksDir, blockID)
+
+			meta.Thanos.Labels = nil
+			meta.Thanos.Files, err = block.GatherFileStats(blockDir, metadata.NoneFunc, log.NewNopLogger())
+			require.NoError(t, err)
+			if testData.corruptMeta != nil {
+				testData.corruptMeta(meta)
+			}
+
+			// The in-memory bucket can't be used because it doesn't support reading objects while uploading another one.
+			bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: t.TempDir()})
+			require.NoError(t, err)
+			cfgProvider := newMockConfigProvider()
+			cfgProvider.blockUploadEnabled[tenantID] = true
+			cfgProvider.blockUploadValidation[tenantID] = testData.validationEnabled
+			c := &MultitenantCompactor{
+				compactorCfg:               Config{DataDir: t.TempDir()},
+				logger:                     log.NewNopLogger(),
+				bucketClient:               bkt,
+				cfgProvider:                cfgProvider,
+				blockUploadValidations:     make(chan blockUploadValidation, 1),
+				blockUploadValidationSlots: make(chan struct{}, 1),
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			go c.runBlockUploadValidations(ctx)
+
+			metaJSON, err := json.Marshal(meta)
+			require.NoError(t, err)
+			resp := doBlockUploadRequest(t, c.StartBlockUpload, http.MethodPost, fmt.Sprintf("/api/v1/upload/block/%s/start", blockID), tenantID, blockID, metaJSON)
+			require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+			// Upload the files in two parts each.
+			for _, f := range meta.Thanos.Files {
+				if f.RelPath == block.MetaFilename {
+					continue
+				}
+
+				content, err := os.ReadFile(filepath.Join(blockDir, filepath.FromSlash(f.RelPath)))
+				require.NoError(t, err)
+				half := len(content) / 2
+
+				resp := doBlockUploadRequest(t, c.UploadBlockFile, http.MethodPost, fmt.Sprintf("/api/v1/upload/block/%s/files?path=%s&offset=0", blockID, f.RelPath), tenantID, blockID, content[:half])
+				require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+				assert.JSONEq(t, fmt.Sprintf(`{"path": %q, "size_bytes": %d, "uploaded_bytes": %d}`, f.RelPath, len(content), half), resp.Body.String())
+
+				// Parts must be uploaded in order.
+				resp = doBlockUploadRequest(t, c.UploadBlockFile, http.MethodPost, fmt.Sprintf("/api/v1/upload/block/%s/files?path=%s&offset=0", blockID, f.RelPath), tenantID, blockID, content[half:])
+				require.Equal(t, http.StatusConflict, resp.Code)
+				assert.Equal(t, fmt.Sprintf("unexpected offset 0, the next part of the file must start at offset %d\n", half), resp.Body.String())
+
+				resp = doBlockUploadRequest(t, c.UploadBlockFile, http.MethodPost, fmt.Sprintf("/api/v1/upload/block/%s/files?path=%s&offset=%d", blockID, f.RelPath, half), tenantID, blockID, content[half:])
+				require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+				assert.JSONEq(t, fmt.Sprintf(`{"path": %q, "size_bytes": %d, "uploaded_bytes": %d}`, f.RelPath, len(content), len(content)), resp.Body.String())
+
+				uploaded, err := bkt.Get(context.Background(), path.Join(tenantID, blockID, f.RelPath))
+				require.NoError(t, err)
+				uploadedContent, err := io.ReadAll(uploaded)
+				require.NoError(t, err)
+				assert.Equal(t, content, uploadedContent)
+			}
+
+			// The parts are deleted once the files have been assembled.
+			require.NoError(t, bkt.Iter(context.Background(), path.Join(tenantID, blockID, uploadingPartsDirname), func(name string) error {
+				return fmt.Errorf("unexpected object %s", name)
+			}, objstore.WithRecursiveIter))
+
+			// Leave behind the part of a file which has then been uploaded again at once.
+			orphanPart := path.Join(tenantID, blockID, uploadingPartsDirname, "index", "00000000000000000000-00000000000000000001")
+			require.NoError(t, bkt.Upload(context.Background(), orphanPart, bytes.NewReader([]byte{0})))
+
+			resp = doBlockUploadRequest(t, c.FinishBlockUpload, http.MethodPost, fmt.Sprintf("/api/v1/upload/block/%s/finish", blockID), tenantID, blockID, nil)
+			require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+			// The validation runs in the background, and its outcome is reported by the status endpoint.
+			var state blockUploadState
+			test.Poll(t, 10*time.Second, testData.expectedState, func() interface{} {
+				resp := doBlockUploadRequest(t, c.GetBlockUploadState, http.MethodGet, fmt.Sprintf("/api/v1/upload/block/%s/status", blockID), tenantID, blockID, nil)
+				require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+				state = blockUploadState{}
+				require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &state))
+				return state.State
+			})
+			assert.Contains(t, state.Error, testData.expectedError)
+
+			exists, err := bkt.Exists(context.Background(), path.Join(tenantID, blockID, block.MetaFilename))
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedState == blockUploadStateComplete, exists)
+
+			// The parts are deleted whether the validation succeeded or not.
+			exists, err = bkt.Exists(context.Background(), orphanPart)
+			require.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestMultitenantCompactor_GetBlockUploadState(t *testing.T) {
+	const tenantID = "test"
+	const blockID = "01G3FZ0JWJYJC0ZM6Y9778P6KD"
+
+	meta := metadata.Meta{
+		Thanos: metadata.Thanos{
+			Files: []metadata.File{
+				{RelPath: block.MetaFilename},
+				{RelPath: "index", SizeBytes: 10},
+				{RelPath: "chunks/000001", SizeBytes: 20},
+				{RelPath: "chunks/000002", SizeBytes: 30},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		setUpBucket    func(t *testing.T, bkt *objstore.InMemBucket)
+		expectedStatus int
+		expectedBody   string
+	}{
+		"upload not started": {
+			setUpBucket:    func(t *testing.T, bkt *objstore.InMemBucket) {},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   fmt.Sprintf("upload of block %s not started yet\n", blockID),
+		},
+		"upload in progress": {
+			setUpBucket: func(t *testing.T, bkt *objstore.InMemBucket) {
+				uploadMeta(t, bkt, path.Join(tenantID, blockID, uploadingMetaFilename), meta)
+				require.NoError(t, bkt.Upload(context.Background(), path.Join(tenantID, blockID, "index"), bytes.NewReader(make([]byte, 10))))
+				for _, part := range []string{"00000000000000000000-00000000000000000005", "00000000000000000005-00000000000000000005", "00000000000000000015-00000000000000000005"} {
+					require.NoError(t, bkt.Upload(context.Background(), path.Join(tenantID, blockID, uploadingPartsDirname, "chunks/000001", part), bytes.NewReader(make([]byte, 5))))
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{
+				"state": "uploading",
+				"size_bytes": 60,
+				"uploaded_bytes": 20,
+				"files": [
+					{"path": "index", "size_bytes": 10, "uploaded_bytes": 10},
+					{"path": "chunks/000001", "size_bytes": 20, "uploaded_bytes": 10},
+					{"path": "chunks/000002", "size_bytes": 30, "uploaded_bytes": 0}
+				]
+			}`,
+		},
+		"upload complete": {
+			setUpBucket: func(t *testing.T, bkt *objstore.InMemBucket) {
+				uploadMeta(t, bkt, path.Join(tenantID, blockID, block.MetaFilename), meta)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"state": "complete"}`,
+		},
+		"validation in progress": {
+			setUpBucket: func(t *testing.T, bkt *objstore.InMemBucket) {
+				uploadMeta(t, bkt, path.Join(tenantID, blockID, uploadingMetaFilename), meta)
+				uploadValidation(t, bkt, path.Join(tenantID, blockID, validationFilename), validationFile{LastUpdate: time.Now().UnixMilli()})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"state": "validating"}`,
+		},
+		"validation failed": {
+			setUpBucket: func(t *testing.T, bkt *objstore.InMemBucket) {
+				uploadMeta(t, bkt, path.Join(tenantID, blockID, uploadingMetaFilename), meta)
+				uploadValidation(t, bkt, path.Join(tenantID, blockID, validationFilename), validationFile{LastUpdate: time.Now().UnixMilli(), Error: "block validation failed: invalid index"})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"state": "failed", "error": "block validation failed: invalid index"}`,
+		},
+		"validation timed out": {
+			setUpBucket: func(t *testing.T, bkt *objstore.InMemBucket) {
+				uploadMeta(t, bkt, path.Join(tenantID, blockID, uploadingMetaFilename), meta)
+				uploadValidation(t, bkt, path.Join(tenantID, blockID, validationFilename), validationFile{LastUpdate: time.Now().Add(-2 * validationHeartbeatTimeout).UnixMilli()})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"state": "failed", "error": "validation timed out"}`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			bkt := objstore.NewInMemBucket()
+			testData.setUpBucket(t, bkt)
+
+			cfgProvider := newMockConfigProvider()
+			cfgProvider.blockUploadEnabled[tenantID] = true
+			c := &MultitenantCompactor{
+				logger:       log.NewNopLogger(),
+				bucketClient: bkt,
+				cfgProvider:  cfgProvider,
+			}
+
+			resp := doBlockUploadRequest(t, c.GetBlockUploadState, http.MethodGet, fmt.Sprintf("/api/v1/upload/block/%s/status", blockID), tenantID, blockID, nil)
+			require.Equal(t, testData.expectedStatus, resp.Code)
+			if testData.expectedStatus == http.StatusOK {
+				assert.JSONEq(t, testData.expectedBody, resp.Body.String())
+			} else {
+				assert.Equal(t, testData.expectedBody, resp.Body.String())
+			}
+		})
+	}
+}
+
+func uploadValidation(t *testing.T, bkt objstore.Bucket, pth string, v validationFile) {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, bkt.Upload(context.Background(), pth, bytes.NewReader(data)))
+}
+
+func doBlockUploadRequest(t *testing.T, handler http.HandlerFunc, method, url, tenantID, blockID string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, url, bytes.NewReader(body))
+	r = r.WithContext(user.InjectOrgID(r.Context(), tenantID))
+	r = mux.SetURLVars(r, map[string]string{"block": blockID})
+
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestMultitenantCompactor_FinishBlockUpload_ShouldRejectValidationWhenAllWorkersAreBusy(t *testing.T) {
+	const tenantID = "test"
+	const blockID = "01G3FZ0JWJYJC0ZM6Y9778P6KD"
+
+	meta := metadata.Meta{
+		Thanos: metadata.Thanos{
+			Files: []metadata.File{
+				{RelPath: block.MetaFilename},
+				{RelPath: "index", SizeBytes: 10},
+			},
+		},
+	}
+
+	bkt := objstore.NewInMemBucket()
+	uploadMeta(t, bkt, path.Join(tenantID, blockID, uploadingMetaFilename), meta)
+	require.NoError(t, bkt.Upload(context.Background(), path.Join(tenantID, blockID, "index"), bytes.NewReader(make([]byte, 10))))
+
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.blockUploadEnabled[tenantID] = true
+	cfgProvider.blockUploadValidation[tenantID] = true
+
+	// The only validation slot is taken by another block.
+	c := &MultitenantCompactor{
+		logger:                     log.NewNopLogger(),
+		bucketClient:               bkt,
+		cfgProvider:                cfgProvider,
+		blockUploadValidations:     make(chan blockUploadValidation, 1),
+		blockUploadValidationSlots: make(chan struct{}, 1),
+	}
+	c.blockUploadValidationSlots <- struct{}{}
+
+	resp := doBlockUploadRequest(t, c.FinishBlockUpload, http.MethodPost, fmt.Sprintf("/api/v1/upload/block/%s/finish", blockID), tenantID, blockID, nil)
+	require.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "too many block validations in progress, retry later\n", resp.Body.String())
+
+	// The upload can be completed again once a worker is available.
+	exists, err := bkt.Exists(context.Background(), path.Join(tenantID, blockID, validationFilename))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -849,7 +849,10 @@ type mockConfigProvider struct {
 	instancesShardSize    map[string]int
 	splitGroups           map[string]int
 	blockUploadEnabled    map[string]bool
+	blockUploadValidation map[string]bool
 	userPartialBlockDelay map[string]time.Duration
+	seriesDeletionDelay   map[string]time.Duration
+	downsampling          map[string][]time.Duration
 }
 
 func newMockConfigProvider() *mockConfigProvider {
@@ -858,7 +861,10 @@ func newMockConfigProvider() *mockConfigProvider {
 		splitAndMergeShards:   make(map[string]int),
 		splitGroups:           make(map[string]int),
 		blockUploadEnabled:    make(map[string]bool),
+		blockUploadValidation: make(map[string]bool),
 		userPartialBlockDelay: make(map[string]time.Duration),
+		seriesDeletionDelay:   make(map[string]time.Duration),
+		downsampling:          make(map[string][]time.Duration),
 	}
 }
 
@@ -894,10 +900,22 @@ func (m *mockConfigProvider) CompactorBlockUploadEnabled(tenantID string) bool {
 	return m.blockUploadEnabled[tenantID]
 }
 
+func (m *mockConfigProvider) CompactorBlockUploadValidationEnabled(tenantID string) bool {
+	return m.blockUploadValidation[tenantID]
+}
+
 func (m *mockConfigProvider) CompactorPartialBlockDeletionDelay(user string) time.Duration {
 	return m.userPartialBlockDelay[user]
 }
 
+func (m *mockConfigProvider) CompactorSeriesDeletionDelay(user string) time.Duration {
+	return m.seriesDeletionDelay[user]
+}
+
+func (m *mockConfigProvider) CompactorDownsamplingResolutions(user string) []time.Duration {
+	return m.downsampling[user]
+}
+
 func (m *mockConfigProvider) S3SSEType(user string) string {
 	return ""
 }
@@ -24,6 +24,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/compact/downsample"
@@ -311,6 +312,14 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 
 	level.Info(jobLogger).Log("msg", "compaction available and planned; downloading blocks", "blocks", len(toCompact), "plan", fmt.Sprintf("%v", toCompact))
 
+	// Series deletion requests are applied while compacting, so we need to load them before.
+	var deletions mimit_tsdb.Tombstones
+	if c.loadTombstones != nil {
+		if deletions, err = c.loadTombstones(ctx); err != nil {
+			return false, nil, err
+		}
+	}
+
 	// Once we have a plan we need to download the actual data.
 	downloadBegin := time.Now()
 
@@ -327,11 +336,11 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 		// Ensure all input blocks are valid.
 		stats, err := block.GatherIndexHealthStats(jobLogger, filepath.Join(bdir, block.IndexFilename), meta.MinTime, meta.MaxTime)
 		if err != nil {
-			return errors.Wrapf(err, "gather index issues for block %s", bdir)
+			return corruptedBlockError(errors.Wrapf(err, "gather index issues for block %s", bdir), meta.ULID)
 		}
 
 		if err := stats.CriticalErr(); err != nil {
-			return errors.Wrapf(err, "block with not healthy index found %s; Compaction level %v; Labels: %v", bdir, meta.Compaction.Level, meta.Thanos.Labels)
+			return corruptedBlockError(errors.Wrapf(err, "block with not healthy index found %s; Compaction level %v; Labels: %v", bdir, meta.Compaction.Level, meta.Thanos.Labels), meta.ULID)
 		}
 
 		if err := stats.OutOfOrderChunksErr(); err != nil {
@@ -343,7 +352,15 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 		}
 
 		if err := stats.PrometheusIssue5372Err(); err != nil {
-			return errors.Wrapf(err, "block id %s", meta.ULID)
+			return corruptedBlockError(errors.Wrapf(err, "block id %s", meta.ULID), meta.ULID)
+		}
+
+		deletedSeries, err := writeBlockTombstones(jobLogger, bdir, meta, deletions)
+		if err != nil {
+			return errors.Wrapf(err, "apply series deletion requests to block %s", meta.ULID)
+		}
+		if deletedSeries > 0 {
+			level.Info(jobLogger).Log("msg", "series deletion requests will be applied to block", "block", meta.ULID, "series", deletedSeries)
 		}
 		return nil
 	})
@@ -412,6 +429,7 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 			Downsample:   metadata.ThanosDownsample{Resolution: job.Resolution()},
 			Source:       metadata.CompactorSource,
 			SegmentFiles: block.GetSegmentFiles(bdir),
+			Rewrites:     tombstonesRewrites(toCompact, deletions.Overlapping(minTime(toCompact).UnixMilli(), maxTime(toCompact).UnixMilli()-1)),
 		}, nil)
 		if err != nil {
 			return errors.Wrapf(err, "failed to finalize the block %s", bdir)
@@ -530,7 +548,13 @@ func RepairIssue347(ctx context.Context, logger log.Logger, bkt objstore.Bucket,
 
 	level.Info(logger).Log("msg", "Repairing block broken by https://github.com/prometheus/tsdb/issues/347", "id", ie.id, "err", issue347Err)
 
-	tmpdir, err := ioutil.TempDir("", fmt.Sprintf("repair-issue-347-id-%s-", ie.id))
+	return repairBlock(ctx, logger, bkt, blocksMarkedForDeletion, ie.id, block.IgnoreIssue347OutsideChunk)
+}
+
+// repairBlock rewrites the block dropping the chunks for which any of the ignoreChkFns returns true,
+// uploads the rewritten block and marks the source block for deletion.
+func repairBlock(ctx context.Context, logger log.Logger, bkt objstore.Bucket, blocksMarkedForDeletion prometheus.Counter, id ulid.ULID, ignoreChkFns ...func(mint, maxt int64, prev *chunks.Meta, curr *chunks.Meta) (bool, error)) error {
+	tmpdir, err := ioutil.TempDir("", fmt.Sprintf("repair-block-id-%s-", id))
 	if err != nil {
 		return err
 	}
@@ -541,9 +565,9 @@ func RepairIssue347(ctx context.Context, logger log.Logger, bkt objstore.Bucket,
 		}
 	}()
 
-	bdir := filepath.Join(tmpdir, ie.id.String())
-	if err := block.Download(ctx, logger, bkt, ie.id, bdir); err != nil {
-		return errors.Wrapf(err, "download block %s", ie.id)
+	bdir := filepath.Join(tmpdir, id.String())
+	if err := block.Download(ctx, logger, bkt, id, bdir); err != nil {
+		return errors.Wrapf(err, "download block %s", id)
 	}
 
 	meta, err := metadata.ReadFromDir(bdir)
@@ -551,9 +575,19 @@ func RepairIssue347(ctx context.Context, logger log.Logger, bkt objstore.Bucket,
 		return errors.Wrapf(err, "read meta from %s", bdir)
 	}
 
-	resid, err := block.Repair(logger, tmpdir, ie.id, metadata.CompactorRepairSource, block.IgnoreIssue347OutsideChunk)
+	// The chunk is dropped as soon as any of the functions asks to ignore it.
+	ignoreChk := func(mint, maxt int64, prev *chunks.Meta, curr *chunks.Meta) (bool, error) {
+		for _, fn := range ignoreChkFns {
+			if ignore, err := fn(mint, maxt, prev, curr); ignore || err != nil {
+				return ignore, err
+			}
+		}
+		return false, nil
+	}
+
+	resid, err := block.Repair(logger, tmpdir, id, metadata.CompactorRepairSource, ignoreChk)
 	if err != nil {
-		return errors.Wrapf(err, "repair failed for block %s", ie.id)
+		return errors.Wrapf(err, "repair failed for block %s", id)
 	}
 
 	// Verify repaired id before uploading it.
@@ -566,15 +600,15 @@ func RepairIssue347(ctx context.Context, logger log.Logger, bkt objstore.Bucket,
 		return errors.Wrapf(err, "upload of %s failed", resid)
 	}
 
-	level.Info(logger).Log("msg", "deleting broken block", "id", ie.id)
+	level.Info(logger).Log("msg", "deleting broken block", "id", id)
 
 	// Spawn a new context so we always mark a block for deletion in full on shutdown.
 	delCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
 	// TODO(bplotka): Issue with this will introduce overlap that will halt compactor. Automate that (fix duplicate overlaps caused by this).
-	if err := block.MarkForDeletion(delCtx, logger, bkt, ie.id, "source of repaired block", blocksMarkedForDeletion); err != nil {
-		return errors.Wrapf(err, "marking old block %s for deletion has failed", ie.id)
+	if err := block.MarkForDeletion(delCtx, logger, bkt, id, "source of repaired block", blocksMarkedForDeletion); err != nil {
+		return errors.Wrapf(err, "marking old block %s for deletion has failed", id)
 	}
 	return nil
 }
@@ -601,12 +635,14 @@ type BucketCompactorMetrics struct {
 	groupCompactionRunsFailed    prometheus.Counter
 	groupCompactions             prometheus.Counter
 	blocksMarkedForDeletion      prometheus.Counter
-	blocksMarkedForNoCompact     prometheus.Counter
+	blocksMarkedForNoCompact     *prometheus.CounterVec
+	blocksCorrupted              prometheus.Counter
+	blocksRepaired               prometheus.Counter
 }
 
 // NewBucketCompactorMetrics makes a new BucketCompactorMetrics.
 func NewBucketCompactorMetrics(blocksMarkedForDeletion prometheus.Counter, reg prometheus.Registerer) *BucketCompactorMetrics {
-	return &BucketCompactorMetrics{
+	m := &BucketCompactorMetrics{
 		groupCompactionRunsStarted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_compactor_group_compaction_runs_started_total",
 			Help: "Total number of group compaction attempts.",
@@ -624,12 +660,25 @@ func NewBucketCompactorMetrics(blocksMarkedForDeletion prometheus.Counter, reg p
 			Help: "Total number of group compaction attempts that resulted in new block(s).",
 		}),
 		blocksMarkedForDeletion: blocksMarkedForDeletion,
-		blocksMarkedForNoCompact: promauto.With(reg).NewCounter(prometheus.CounterOpts{
-			Name:        "cortex_compactor_blocks_marked_for_no_compaction_total",
-			Help:        "Total number of blocks that were marked for no-compaction.",
-			ConstLabels: prometheus.Labels{"reason": metadata.OutOfOrderChunksNoCompactReason},
+		blocksMarkedForNoCompact: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_marked_for_no_compaction_total",
+			Help: "Total number of blocks that were marked for no-compaction.",
+		}, []string{"reason"}),
+		blocksCorrupted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_corrupted_total",
+			Help: "Total number of source blocks which failed validation while compacting.",
+		}),
+		blocksRepaired: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_repaired_total",
+			Help: "Total number of blocks which failed validation and have been successfully repaired.",
 		}),
 	}
+
+	// Initialize the reason set by the compactor itself, while other reasons
+	// are only exported once a block has been marked for that reason.
+	m.blocksMarkedForNoCompact.WithLabelValues(metadata.OutOfOrderChunksNoCompactReason)
+
+	return m
 }
 
 type ownCompactionJobFunc func(job *Job) (bool, error)
@@ -650,9 +699,12 @@ type BucketCompactor struct {
 	bkt                            objstore.Bucket
 	concurrency                    int
 	skipBlocksWithOutOfOrderChunks bool
+	corruptedBlocksHandling        string
 	ownJob                         ownCompactionJobFunc
 	sortJobs                       JobsOrderFunc
 	blockSyncConcurrency           int
+	loadTombstones                 tombstonesLoaderFunc
+	jobsTracker                    *tenantJobsTracker
 	metrics                        *BucketCompactorMetrics
 }
 
@@ -667,9 +719,12 @@ func NewBucketCompactor(
 	bkt objstore.Bucket,
 	concurrency int,
 	skipBlocksWithOutOfOrderChunks bool,
+	corruptedBlocksHandling string,
 	ownJob ownCompactionJobFunc,
 	sortJobs JobsOrderFunc,
 	blockSyncConcurrency int,
+	loadTombstones tombstonesLoaderFunc,
+	jobsTracker *tenantJobsTracker,
 	metrics *BucketCompactorMetrics,
 ) (*BucketCompactor, error) {
 	if concurrency <= 0 {
@@ -685,9 +740,12 @@ func NewBucketCompactor(
 		bkt:                            bkt,
 		concurrency:                    concurrency,
 		skipBlocksWithOutOfOrderChunks: skipBlocksWithOutOfOrderChunks,
+		corruptedBlocksHandling:        corruptedBlocksHandling,
 		ownJob:                         ownJob,
 		sortJobs:                       sortJobs,
 		blockSyncConcurrency:           blockSyncConcurrency,
+		loadTombstones:                 loadTombstones,
+		jobsTracker:                    jobsTracker,
 		metrics:                        metrics,
 	}, nil
 }
@@ -743,8 +801,10 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 					}
 
 					c.metrics.groupCompactionRunsStarted.Inc()
+					c.jobsTracker.jobStarted(g)
 
 					shouldRerunJob, compactedBlockIDs, err := c.runCompactionJob(workCtx, g)
+					c.jobsTracker.jobFinished(g, err)
 					if err == nil {
 						c.metrics.groupCompactionRunsCompleted.Inc()
 						if hasNonZeroULIDs(compactedBlockIDs) {
@@ -764,23 +824,51 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 
 					if IsIssue347Error(err) {
 						if err := RepairIssue347(workCtx, c.logger, c.bkt, c.sy.metrics.blocksMarkedForDeletion, err); err == nil {
+							c.metrics.blocksRepaired.Inc()
 							mtx.Lock()
 							finishedAllJobs = false
 							mtx.Unlock()
 							continue
 						}
 					}
+
+					corruptedID, corrupted := corruptedBlockID(err)
+					if corrupted || IsOutOfOrderChunkError(err) {
+						c.metrics.blocksCorrupted.Inc()
+					}
+
+					// If configured, try to repair a block which failed validation before excluding it from compaction.
+					if corrupted && c.corruptedBlocksHandling == CorruptedBlocksRepair {
+						repairErr := c.repairCorruptedBlock(workCtx, c.logger, corruptedID)
+						if repairErr == nil {
+							mtx.Lock()
+							finishedAllJobs = false
+							mtx.Unlock()
+							continue
+						}
+						level.Warn(c.logger).Log("msg", "failed to repair corrupted block", "block", corruptedID, "err", repairErr)
+					}
 					// If block has out of order chunk and it has been configured to skip it,
 					// then we can mark the block for no compaction so that the next compaction run
-					// will skip it.
+					// will skip it. Out of order chunks are never repaired, because it would lose samples.
 					if IsOutOfOrderChunkError(err) && c.skipBlocksWithOutOfOrderChunks {
 						if err := block.MarkForNoCompact(
 							ctx,
 							c.logger,
 							c.bkt,
-							err.(OutOfOrderChunksError).id,
+							errors.Cause(err).(OutOfOrderChunksError).id,
 							metadata.OutOfOrderChunksNoCompactReason,
-							"OutofOrderChunk: marking block with out-of-order series/chunks to as no compact to unblock compaction", c.metrics.blocksMarkedForNoCompact); err == nil {
+							"OutofOrderChunk: marking block with out-of-order series/chunks to as no compact to unblock compaction", c.metrics.blocksMarkedForNoCompact.WithLabelValues(metadata.OutOfOrderChunksNoCompactReason)); err == nil {
+							mtx.Lock()
+							finishedAllJobs = false
+							mtx.Unlock()
+							continue
+						}
+					}
+					// If configured, mark the corrupted block for no compaction so that the next compaction
+					// run will skip it, and continue with the other jobs of the tenant.
+					if IsCorruptedBlockError(err) && c.corruptedBlocksHandling != CorruptedBlocksFail {
+						if err := c.markCorruptedBlockForNoCompaction(workCtx, c.logger, corruptedID, err); err == nil {
 							mtx.Lock()
 							finishedAllJobs = false
 							mtx.Unlock()
@@ -809,6 +897,7 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 		if err != nil {
 			return errors.Wrap(err, "build compaction jobs")
 		}
+		c.jobsTracker.jobsPlanned(jobs)
 
 		// There is another check just before we start processing the job, but we can avoid sending it
 		// to the goroutine in the first place.
@@ -16,6 +16,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kit/log"
@@ -35,6 +36,7 @@ import (
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/util"
 	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 const (
@@ -48,12 +50,15 @@ const (
 )
 
 var (
-	errInvalidBlockRanges                 = "compactor block range periods should be divisible by the previous one, but %s is not divisible by %s"
-	errInvalidCompactionOrder             = fmt.Errorf("unsupported compaction order (supported values: %s)", strings.Join(CompactionOrders, ", "))
-	errInvalidMaxOpeningBlocksConcurrency = fmt.Errorf("invalid max-opening-blocks-concurrency value, must be positive")
-	errInvalidMaxClosingBlocksConcurrency = fmt.Errorf("invalid max-closing-blocks-concurrency value, must be positive")
-	errInvalidSymbolFlushersConcurrency   = fmt.Errorf("invalid symbols-flushers-concurrency value, must be positive")
-	RingOp                                = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, nil)
+	errInvalidBlockRanges                      = "compactor block range periods should be divisible by the previous one, but %s is not divisible by %s"
+	errInvalidCompactionOrder                  = fmt.Errorf("unsupported compaction order (supported values: %s)", strings.Join(CompactionOrders, ", "))
+	errInvalidMaxOpeningBlocksConcurrency      = fmt.Errorf("invalid max-opening-blocks-concurrency value, must be positive")
+	errInvalidMaxClosingBlocksConcurrency      = fmt.Errorf("invalid max-closing-blocks-concurrency value, must be positive")
+	errInvalidSymbolFlushersConcurrency        = fmt.Errorf("invalid symbols-flushers-concurrency value, must be positive")
+	errInvalidDownsamplingResolution           = "unsupported downsampling resolution %s (supported values: 5m, 1h)"
+	errInvalidCorruptedBlocksHandling          = fmt.Errorf("unsupported corrupted blocks handling (supported values: %s)", strings.Join(CorruptedBlocksHandlings, ", "))
+	errInvalidBlockUploadValidationConcurrency = fmt.Errorf("invalid block-upload-validation-concurrency value, must be positive")
+	RingOp                                     = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, nil)
 )
 
 // BlocksGrouperFactory builds and returns the grouper to use to compact a tenant's blocks.
@@ -103,6 +108,10 @@ type Config struct {
 
 	CompactionJobsOrder string `yaml:"compaction_jobs_order" category:"advanced"`
 
+	CorruptedBlocksHandling string `yaml:"corrupted_blocks_handling" category:"experimental"`
+
+	BlockUploadValidationConcurrency int `yaml:"block_upload_validation_concurrency" category:"experimental"`
+
 	// No need to add options to customize the retry backoff,
 	// given the defaults should be fine, but allow to override
 	// it in tests.
@@ -134,6 +143,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.DurationVar(&cfg.CleanupInterval, "compactor.cleanup-interval", 15*time.Minute, "How frequently compactor should run blocks cleanup and maintenance, as well as update the bucket index.")
 	f.IntVar(&cfg.CleanupConcurrency, "compactor.cleanup-concurrency", 20, "Max number of tenants for which blocks cleanup and maintenance should run concurrently.")
 	f.StringVar(&cfg.CompactionJobsOrder, "compactor.compaction-jobs-order", CompactionOrderOldestFirst, fmt.Sprintf("The sorting to use when deciding which compaction jobs should run first for a given tenant. Supported values are: %s.", strings.Join(CompactionOrders, ", ")))
+	f.StringVar(&cfg.CorruptedBlocksHandling, "compactor.corrupted-blocks-handling", CorruptedBlocksFail, fmt.Sprintf("How to handle source blocks found corrupted while running a compaction job. %q stops the tenant's compaction, %q marks the block for no-compaction and continues with the other jobs, %q rewrites the block without its chunks outside of the block time range, losing their samples, and marks it for no-compaction if the rewrite fails. Blocks with out-of-order chunks are never repaired, and are always marked for no-compaction. Supported values are: %s.", CorruptedBlocksFail, CorruptedBlocksNoCompact, CorruptedBlocksRepair, strings.Join(CorruptedBlocksHandlings, ", ")))
+	f.IntVar(&cfg.BlockUploadValidationConcurrency, "compactor.block-upload-validation-concurrency", 1, "Max number of blocks uploaded through the block upload API which can be validated concurrently. Requests to complete a block upload are rejected while all validations are in progress.")
 	f.DurationVar(&cfg.DeletionDelay, "compactor.deletion-delay", 12*time.Hour, "Time before a block marked for deletion is deleted from bucket. "+
 		"If not 0, blocks will be marked for deletion and compactor component will permanently delete blocks marked for deletion from the bucket. "+
 		"If 0, blocks will be deleted straight away. Note that deleting blocks immediately can cause query failures.")
@@ -147,7 +158,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.Var(&cfg.DisabledTenants, "compactor.disabled-tenants", "Comma separated list of tenants that cannot be compacted by this compactor. If specified, and compactor would normally pick given tenant for compaction (via -compactor.enabled-tenants or sharding), it will be ignored instead.")
 }
 
-func (cfg *Config) Validate() error {
+func (cfg *Config) Validate(limits validation.Limits) error {
 	// Each block range period should be divisible by the previous one.
 	for i := 1; i < len(cfg.BlockRanges); i++ {
 		if cfg.BlockRanges[i]%cfg.BlockRanges[i-1] != 0 {
@@ -169,7 +180,15 @@ func (cfg *Config) Validate() error {
 		return errInvalidCompactionOrder
 	}
 
-	return nil
+	if !util.StringsContain(CorruptedBlocksHandlings, cfg.CorruptedBlocksHandling) {
+		return errInvalidCorruptedBlocksHandling
+	}
+
+	if cfg.BlockUploadValidationConcurrency < 1 {
+		return errInvalidBlockUploadValidationConcurrency
+	}
+
+	return ValidateDownsamplingResolutions(limits.CompactorDownsamplingResolutions)
 }
 
 // ConfigProvider defines the per-tenant config provider for the MultitenantCompactor.
@@ -194,6 +213,15 @@ type ConfigProvider interface {
 
 	// CompactorBlockUploadEnabled returns whether block upload is enabled for a given tenant.
 	CompactorBlockUploadEnabled(tenantID string) bool
+
+	// CompactorBlockUploadValidationEnabled returns whether blocks uploaded by a given tenant are validated before being marked as complete.
+	CompactorBlockUploadValidationEnabled(tenantID string) bool
+
+	// CompactorSeriesDeletionDelay returns the delay before series deletion requests are applied to blocks for a given user.
+	CompactorSeriesDeletionDelay(userID string) time.Duration
+
+	// CompactorDownsamplingResolutions returns the resolutions blocks are downsampled to for a given user. Empty to disable downsampling.
+	CompactorDownsamplingResolutions(userID string) []time.Duration
 }
 
 // MultitenantCompactor is a multi-tenant TSDB blocks compactor based on Thanos.
@@ -232,6 +260,14 @@ type MultitenantCompactor struct {
 	shardingStrategy shardingStrategy
 	jobsOrder        JobsOrderFunc
 
+	// Status of the compaction jobs run by this instance, exposed through the HTTP API.
+	jobsTracker *jobsTracker
+
+	// Validations of the blocks uploaded through the block upload API, waiting for a worker, and
+	// the slots taken by the validations which are queued or running.
+	blockUploadValidations     chan blockUploadValidation
+	blockUploadValidationSlots chan struct{}
+
 	// Metrics.
 	compactionRunsStarted          prometheus.Counter
 	compactionRunsCompleted        prometheus.Counter
@@ -243,6 +279,10 @@ type MultitenantCompactor struct {
 	compactionRunFailedTenants     prometheus.Gauge
 	compactionRunInterval          prometheus.Gauge
 	blocksMarkedForDeletion        prometheus.Counter
+	blocksDownsampled              *prometheus.CounterVec
+	blocksDownsamplingFailed       prometheus.Counter
+	blocksRewritten                prometheus.Counter
+	blocksRewritesFailed           prometheus.Counter
 
 	// Metrics shared across all BucketCompactor instances.
 	bucketCompactorMetrics *BucketCompactorMetrics
@@ -286,16 +326,19 @@ func newMultitenantCompactor(
 	blocksCompactorFactory BlocksCompactorFactory,
 ) (*MultitenantCompactor, error) {
 	c := &MultitenantCompactor{
-		compactorCfg:           compactorCfg,
-		storageCfg:             storageCfg,
-		cfgProvider:            cfgProvider,
-		parentLogger:           logger,
-		logger:                 log.With(logger, "component", "compactor"),
-		registerer:             registerer,
-		syncerMetrics:          newAggregatedSyncerMetrics(registerer),
-		bucketClientFactory:    bucketClientFactory,
-		blocksGrouperFactory:   blocksGrouperFactory,
-		blocksCompactorFactory: blocksCompactorFactory,
+		compactorCfg:               compactorCfg,
+		storageCfg:                 storageCfg,
+		cfgProvider:                cfgProvider,
+		parentLogger:               logger,
+		logger:                     log.With(logger, "component", "compactor"),
+		registerer:                 registerer,
+		syncerMetrics:              newAggregatedSyncerMetrics(registerer),
+		bucketClientFactory:        bucketClientFactory,
+		blocksGrouperFactory:       blocksGrouperFactory,
+		blocksCompactorFactory:     blocksCompactorFactory,
+		jobsTracker:                newJobsTracker(compactorCfg.CompactionInterval),
+		blockUploadValidations:     make(chan blockUploadValidation, compactorCfg.BlockUploadValidationConcurrency),
+		blockUploadValidationSlots: make(chan struct{}, compactorCfg.BlockUploadValidationConcurrency),
 
 		compactionRunsStarted: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_compactor_runs_started_total",
@@ -338,6 +381,22 @@ func newMultitenantCompactor(
 			Help:        blocksMarkedForDeletionHelp,
 			ConstLabels: prometheus.Labels{"reason": "compaction"},
 		}),
+		blocksDownsampled: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_downsampled_total",
+			Help: "Total number of blocks downsampled by the compactor.",
+		}, []string{"resolution"}),
+		blocksDownsamplingFailed: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_downsampling_failed_total",
+			Help: "Total number of blocks the compactor failed to downsample.",
+		}),
+		blocksRewritten: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_series_deletion_blocks_rewritten_total",
+			Help: "Total number of blocks rewritten by the compactor to apply series deletion requests.",
+		}),
+		blocksRewritesFailed: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_series_deletion_blocks_rewrites_failed_total",
+			Help: "Total number of blocks the compactor failed to rewrite to apply series deletion requests.",
+		}),
 	}
 
 	c.bucketCompactorMetrics = NewBucketCompactorMetrics(c.blocksMarkedForDeletion, registerer)
@@ -468,6 +527,18 @@ func (c *MultitenantCompactor) stopping(_ error) error {
 }
 
 func (c *MultitenantCompactor) running(ctx context.Context) error {
+	// Validate the uploaded blocks in the background, until the compactor is stopped.
+	var validationWorkers sync.WaitGroup
+	defer validationWorkers.Wait()
+
+	for i := 0; i < c.compactorCfg.BlockUploadValidationConcurrency; i++ {
+		validationWorkers.Add(1)
+		go func() {
+			defer validationWorkers.Done()
+			c.runBlockUploadValidations(ctx)
+		}()
+	}
+
 	// Run an initial compaction before starting the interval.
 	c.compactUsers(ctx)
 
@@ -558,7 +629,9 @@ func (c *MultitenantCompactor) compactUsers(ctx context.Context) {
 
 		level.Info(c.logger).Log("msg", "starting compaction of user blocks", "user", userID)
 
-		if err = c.compactUserWithRetries(ctx, userID); err != nil {
+		err = c.compactUserWithRetries(ctx, userID)
+		c.jobsTracker.tenantFinished(userID, err)
+		if err != nil {
 			c.compactionRunFailedTenants.Inc()
 			compactionErrorCount++
 			level.Error(c.logger).Log("msg", "failed to compact user blocks", "user", userID, "err", err)
@@ -569,6 +642,9 @@ func (c *MultitenantCompactor) compactUsers(ctx context.Context) {
 		level.Info(c.logger).Log("msg", "successfully compacted user blocks", "user", userID)
 	}
 
+	// Forget the jobs status of unowned tenants.
+	c.jobsTracker.forgetTenants(ownedUsers)
+
 	// Delete local files for unowned tenants, if there are any. This cleans up
 	// leftover local files for tenants that belong to different compactors now,
 	// or have been deleted completely.
@@ -627,38 +703,7 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 
 	ulogger := util_log.WithUserID(userID, c.logger)
 
-	// While fetching blocks, we filter out blocks that were marked for deletion by using ExcludeMarkedForDeletionFilter.
-	// No delay is used -- all blocks with deletion marker are ignored, and not considered for compaction.
-	excludeMarkedForDeletionFilter := NewExcludeMarkedForDeletionFilter(bucket)
-	// Filters out duplicate blocks that can be formed from two or more overlapping
-	// blocks that fully submatches the source blocks of the older blocks.
-	deduplicateBlocksFilter := NewShardAwareDeduplicateFilter()
-
-	// List of filters to apply (order matters).
-	fetcherFilters := []block.MetadataFilter{
-		// Remove the ingester ID because we don't shard blocks anymore, while still
-		// honoring the shard ID if sharding was done in the past.
-		// Remove TenantID external label to make sure that we compact blocks with and without the label
-		// together.
-		NewLabelRemoverFilter([]string{
-			mimir_tsdb.DeprecatedTenantIDExternalLabel,
-			mimir_tsdb.DeprecatedIngesterIDExternalLabel,
-		}),
-		block.NewConsistencyDelayMetaFilter(ulogger, c.compactorCfg.ConsistencyDelay, reg),
-		excludeMarkedForDeletionFilter,
-		deduplicateBlocksFilter,
-		// removes blocks that should not be compacted due to being marked so.
-		NewNoCompactionMarkFilter(bucket, true),
-	}
-
-	fetcher, err := block.NewMetaFetcher(
-		ulogger,
-		c.compactorCfg.MetaSyncConcurrency,
-		bucket,
-		c.metaSyncDirForUser(userID),
-		reg,
-		fetcherFilters,
-	)
+	fetcher, excludeMarkedForDeletionFilter, deduplicateBlocksFilter, err := c.newMetaFetcher(bucket, c.metaSyncDirForUser(userID), ulogger, reg)
 	if err != nil {
 		return err
 	}
@@ -679,16 +724,21 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 	compactor, err := NewBucketCompactor(
 		ulogger,
 		syncer,
-		c.blocksGrouperFactory(ctx, c.compactorCfg, c.cfgProvider, userID, ulogger, reg),
+		excludeDownsampledBlocksGrouper{c.blocksGrouperFactory(ctx, c.compactorCfg, c.cfgProvider, userID, ulogger, reg)},
 		c.blocksPlanner,
 		c.blocksCompactor,
 		path.Join(c.compactorCfg.DataDir, "compact"),
 		bucket,
 		c.compactorCfg.CompactionConcurrency,
 		true, // Skip blocks with out of order chunks, and mark them for no-compaction.
+		c.compactorCfg.CorruptedBlocksHandling,
 		c.shardingStrategy.ownJob,
 		c.jobsOrder,
 		c.compactorCfg.BlockSyncConcurrency,
+		func(ctx context.Context) (mimir_tsdb.Tombstones, error) {
+			return c.loadApplicableTombstones(ctx, userID)
+		},
+		c.jobsTracker.forTenant(userID),
 		c.bucketCompactorMetrics,
 	)
 	if err != nil {
@@ -699,9 +749,66 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 		return errors.Wrap(err, "compaction")
 	}
 
+	if err := c.applyTombstones(ctx, userID, bucket, syncer, ulogger); err != nil {
+		return errors.Wrap(err, "applying series deletion requests")
+	}
+
+	if len(c.cfgProvider.CompactorDownsamplingResolutions(userID)) > 0 {
+		// Re-sync the metas to also include the blocks produced by the compaction.
+		if err := syncer.SyncMetas(ctx); err != nil {
+			return errors.Wrap(err, "sync before downsampling")
+		}
+
+		if err := c.downsampleUser(ctx, userID, bucket, syncer.Metas(), ulogger); err != nil {
+			return errors.Wrap(err, "downsampling")
+		}
+	}
+
 	return nil
 }
 
+// newMetaFetcher returns the fetcher of the metas of the blocks to compact, along with the filters
+// needed by the syncer. Metas are cached in dir, unless it's empty.
+func (c *MultitenantCompactor) newMetaFetcher(bkt objstore.InstrumentedBucketReader, dir string, logger log.Logger, reg prometheus.Registerer) (*block.MetaFetcher, *ExcludeMarkedForDeletionFilter, *ShardAwareDeduplicateFilter, error) {
+	// While fetching blocks, we filter out blocks that were marked for deletion by using ExcludeMarkedForDeletionFilter.
+	// No delay is used -- all blocks with deletion marker are ignored, and not considered for compaction.
+	excludeMarkedForDeletionFilter := NewExcludeMarkedForDeletionFilter(bkt)
+	// Filters out duplicate blocks that can be formed from two or more overlapping
+	// blocks that fully submatches the source blocks of the older blocks.
+	deduplicateBlocksFilter := NewShardAwareDeduplicateFilter()
+
+	// List of filters to apply (order matters).
+	fetcherFilters := []block.MetadataFilter{
+		// Remove the ingester ID because we don't shard blocks anymore, while still
+		// honoring the shard ID if sharding was done in the past.
+		// Remove TenantID external label to make sure that we compact blocks with and without the label
+		// together.
+		NewLabelRemoverFilter([]string{
+			mimir_tsdb.DeprecatedTenantIDExternalLabel,
+			mimir_tsdb.DeprecatedIngesterIDExternalLabel,
+		}),
+		block.NewConsistencyDelayMetaFilter(logger, c.compactorCfg.ConsistencyDelay, reg),
+		excludeMarkedForDeletionFilter,
+		deduplicateBlocksFilter,
+		// removes blocks that should not be compacted due to being marked so.
+		NewNoCompactionMarkFilter(bkt, true),
+	}
+
+	fetcher, err := block.NewMetaFetcher(
+		logger,
+		c.compactorCfg.MetaSyncConcurrency,
+		bkt,
+		dir,
+		reg,
+		fetcherFilters,
+	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return fetcher, excludeMarkedForDeletionFilter, deduplicateBlocksFilter, nil
+}
+
 func (c *MultitenantCompactor) discoverUsersWithRetries(ctx context.Context) ([]string, error) {
 	var lastErr error
 
@@ -741,6 +848,7 @@ type shardingStrategy interface {
 	compactorOwnUser(userID string) (bool, error)
 	blocksCleanerOwnUser(userID string) (bool, error)
 	ownJob(job *Job) (bool, error)
+	jobOwner(job *Job) (string, error)
 }
 
 // splitAndMergeShardingStrategy is used by split-and-merge compactor when configured with sharding.
@@ -797,23 +905,39 @@ func (s *splitAndMergeShardingStrategy) ownJob(job *Job) (bool, error) {
 	return instanceOwnsTokenInRing(r, s.ringLifecycler.Addr, job.ShardingKey())
 }
 
+// jobOwner returns the address of the compactor which executes the job.
+func (s *splitAndMergeShardingStrategy) jobOwner(job *Job) (string, error) {
+	r := s.ring.ShuffleShard(job.UserID(), s.configProvider.CompactorTenantShardSize(job.UserID()))
+
+	return instanceOwningTokenInRing(r, job.ShardingKey())
+}
+
 func instanceOwnsTokenInRing(r ring.ReadRing, instanceAddr string, key string) (bool, error) {
+	// Check whether this compactor instance owns the token.
+	owner, err := instanceOwningTokenInRing(r, key)
+	if err != nil {
+		return false, err
+	}
+
+	return owner == instanceAddr, nil
+}
+
+func instanceOwningTokenInRing(r ring.ReadRing, key string) (string, error) {
 	// Hash the key.
 	hasher := fnv.New32a()
 	_, _ = hasher.Write([]byte(key))
 	hash := hasher.Sum32()
 
-	// Check whether this compactor instance owns the token.
 	rs, err := r.Get(hash, RingOp, nil, nil, nil)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
 	if len(rs.Instances) != 1 {
-		return false, fmt.Errorf("unexpected number of compactors in the shard (expected 1, got %d)", len(rs.Instances))
+		return "", fmt.Errorf("unexpected number of compactors in the shard (expected 1, got %d)", len(rs.Instances))
 	}
 
-	return rs.Instances[0].Addr == instanceAddr, nil
+	return rs.Instances[0].Addr, nil
 }
 
 const compactorMetaPrefix = "compactor-meta-"
@@ -0,0 +1,409 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	_ "embed" // Used to embed html template
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/listblocks"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// skippedJobDetailsPrefix is the prefix of the details of the no-compact marks
+// created when skipping a compaction job. It's followed by the job key.
+const skippedJobDetailsPrefix = "Skipped compaction job "
+
+var (
+	//go:embed tenants.gohtml
+	tenantsPageHTML     string
+	tenantsPageTemplate = template.Must(template.New("webpage").Parse(tenantsPageHTML))
+
+	//go:embed jobs.gohtml
+	jobsPageHTML     string
+	jobsPageTemplate = template.Must(template.New("webpage").Parse(jobsPageHTML))
+)
+
+type tenantsPageContents struct {
+	Now time.Time `json:"now"`
+	// Instance is the ring address of this compactor. The compaction status is tracked in memory by the
+	// compactor running the compaction, so only the status of the compactions run by this instance is known.
+	Instance string        `json:"instance"`
+	Tenants  []tenantEntry `json:"tenants,omitempty"`
+}
+
+type tenantEntry struct {
+	Tenant string `json:"tenant"`
+	// Owned is true if this compactor belongs to the tenant's shard.
+	Owned  bool          `json:"owned"`
+	Status *tenantStatus `json:"status,omitempty"`
+}
+
+type jobsPageContents struct {
+	Now time.Time `json:"now"`
+	// Instance is the ring address of this compactor. The status of a job is only known by its owner.
+	Instance    string       `json:"instance"`
+	Tenant      string       `json:"tenant"`
+	Backlog     jobsBacklog  `json:"backlog"`
+	Jobs        []jobEntry   `json:"jobs"`
+	SkippedJobs []skippedJob `json:"skipped_jobs,omitempty"`
+}
+
+// jobsBacklog is an estimate of the compaction work left for a tenant, based on the currently planned jobs.
+type jobsBacklog struct {
+	Jobs      int    `json:"jobs"`
+	SplitJobs int    `json:"split_jobs"`
+	MergeJobs int    `json:"merge_jobs"`
+	Blocks    int    `json:"blocks"`
+	SizeBytes uint64 `json:"size_bytes"`
+}
+
+type jobEntry struct {
+	Key       string     `json:"key"`
+	Stage     string     `json:"stage"`
+	MinTime   time.Time  `json:"min_time"`
+	MaxTime   time.Time  `json:"max_time"`
+	Blocks    []string   `json:"blocks"`
+	SizeBytes uint64     `json:"size_bytes"`
+	Owner     string     `json:"owner,omitempty"`
+	Status    *jobStatus `json:"status,omitempty"`
+}
+
+type skippedJob struct {
+	Key       string    `json:"key"`
+	Blocks    []string  `json:"blocks"`
+	SkippedAt time.Time `json:"skipped_at"`
+}
+
+// TenantsHandler lists the tenants with blocks in the bucket, along with the outcome of
+// their last compaction run by this compactor instance. The outcome of the compactions run
+// by other instances is only reported by them.
+func (c *MultitenantCompactor) TenantsHandler(w http.ResponseWriter, req *http.Request) {
+	if c.State() != services.Running {
+		util.WriteTextResponse(w, "Compactor is not running yet.")
+		return
+	}
+
+	userIDs, err := c.discoverUsers(req.Context())
+	if err != nil {
+		util.WriteTextResponse(w, fmt.Sprintf("Can't read tenants: %s", err))
+		return
+	}
+	sort.Strings(userIDs)
+
+	tenants := make([]tenantEntry, 0, len(userIDs))
+	for _, userID := range userIDs {
+		entry := tenantEntry{Tenant: userID}
+		entry.Owned, _ = c.shardingStrategy.compactorOwnUser(userID)
+		if s, ok := c.jobsTracker.tenantStatus(userID); ok {
+			entry.Status = &s
+		}
+		tenants = append(tenants, entry)
+	}
+
+	util.RenderHTTPResponse(w, tenantsPageContents{
+		Now:      time.Now(),
+		Instance: c.ringLifecycler.Addr,
+		Tenants:  tenants,
+	}, tenantsPageTemplate, req)
+}
+
+// JobsHandler lists the compaction jobs currently planned for a tenant, the compactor instance owning each
+// of them and the outcome of their last run, if they have been run by this compactor instance. The outcome
+// of the jobs run by other instances is only reported by their owner.
+func (c *MultitenantCompactor) JobsHandler(w http.ResponseWriter, req *http.Request) {
+	if c.State() != services.Running {
+		util.WriteTextResponse(w, "Compactor is not running yet.")
+		return
+	}
+
+	userID := mux.Vars(req)["tenant"]
+	if userID == "" {
+		util.WriteTextResponse(w, "Tenant ID can't be empty")
+		return
+	}
+
+	userBucket := bucket.NewUserBucketClient(userID, c.bucketClient, c.cfgProvider)
+	logger := util_log.WithUserID(userID, c.logger)
+
+	jobs, err := c.planJobs(req.Context(), userID, userBucket, logger)
+	if err != nil {
+		util.WriteTextResponse(w, fmt.Sprintf("Failed to plan compaction jobs: %s", err))
+		return
+	}
+
+	skipped, err := listSkippedJobs(req.Context(), userBucket, logger)
+	if err != nil {
+		util.WriteTextResponse(w, fmt.Sprintf("Failed to list skipped compaction jobs: %s", err))
+		return
+	}
+
+	statuses := c.jobsTracker.jobsStatus(userID)
+	contents := jobsPageContents{
+		Now:         time.Now(),
+		Instance:    c.ringLifecycler.Addr,
+		Tenant:      userID,
+		Jobs:        make([]jobEntry, 0, len(jobs)),
+		SkippedJobs: skipped,
+	}
+
+	for _, job := range jobs {
+		entry := jobEntry{
+			Key:     job.Key(),
+			Stage:   string(stageMerge),
+			MinTime: util.TimeFromMillis(job.MinTime()).UTC(),
+			MaxTime: util.TimeFromMillis(job.MaxTime()).UTC(),
+		}
+		if job.UseSplitting() {
+			entry.Stage = string(stageSplit)
+			contents.Backlog.SplitJobs++
+		} else {
+			contents.Backlog.MergeJobs++
+		}
+
+		for _, m := range job.metasByMinTime {
+			entry.Blocks = append(entry.Blocks, m.ULID.String())
+			entry.SizeBytes += listblocks.GetBlockSizeBytes(m)
+		}
+
+		if owner, err := c.shardingStrategy.jobOwner(job); err != nil {
+			level.Warn(logger).Log("msg", "unable to find the compactor owning the job", "groupKey", job.Key(), "err", err)
+		} else {
+			entry.Owner = owner
+		}
+
+		if s, ok := statuses[job.Key()]; ok {
+			entry.Status = &s
+		}
+
+		contents.Backlog.Jobs++
+		contents.Backlog.Blocks += len(entry.Blocks)
+		contents.Backlog.SizeBytes += entry.SizeBytes
+		contents.Jobs = append(contents.Jobs, entry)
+	}
+
+	util.RenderHTTPResponse(w, contents, jobsPageTemplate, req)
+}
+
+// SkipJobHandler excludes the blocks of a planned compaction job from compaction, by marking them for no-compaction.
+func (c *MultitenantCompactor) SkipJobHandler(w http.ResponseWriter, req *http.Request) {
+	userID, key, ok := c.parseJobRequest(w, req)
+	if !ok {
+		return
+	}
+
+	ctx := req.Context()
+	userBucket := bucket.NewUserBucketClient(userID, c.bucketClient, c.cfgProvider)
+	logger := log.With(util_log.WithUserID(userID, c.logger), "groupKey", key)
+
+	jobs, err := c.planJobs(ctx, userID, userBucket, logger)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to plan compaction jobs: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	var job *Job
+	for _, j := range jobs {
+		if j.Key() == key {
+			job = j
+			break
+		}
+	}
+	if job == nil {
+		http.Error(w, "compaction job not found", http.StatusNotFound)
+		return
+	}
+
+	for _, id := range job.IDs() {
+		if err := block.MarkForNoCompact(ctx, logger, userBucket, id, metadata.ManualNoCompactReason, skippedJobDetailsPrefix+key, c.bucketCompactorMetrics.blocksMarkedForNoCompact.WithLabelValues(string(metadata.ManualNoCompactReason))); err != nil {
+			level.Error(logger).Log("msg", "failed to skip compaction job", "block", id, "err", err)
+			http.Error(w, fmt.Sprintf("failed to mark block %s for no-compaction: %s", id, err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	level.Info(logger).Log("msg", "skipped compaction job", "blocks", len(job.IDs()))
+	redirectToJobsPage(w, req)
+}
+
+// RetryJobHandler removes the no-compaction marks of the blocks of a previously skipped job,
+// so that the job is planned again by the next compaction run. Only the status of the job
+// tracked by this compactor instance is reset.
+func (c *MultitenantCompactor) RetryJobHandler(w http.ResponseWriter, req *http.Request) {
+	userID, key, ok := c.parseJobRequest(w, req)
+	if !ok {
+		return
+	}
+
+	ctx := req.Context()
+	userBucket := bucket.NewUserBucketClient(userID, c.bucketClient, c.cfgProvider)
+	logger := log.With(util_log.WithUserID(userID, c.logger), "groupKey", key)
+
+	skipped, err := listSkippedJobs(ctx, userBucket, logger)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list skipped compaction jobs: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	var job *skippedJob
+	for i := range skipped {
+		if skipped[i].Key == key {
+			job = &skipped[i]
+			break
+		}
+	}
+	if job == nil {
+		http.Error(w, "skipped compaction job not found", http.StatusNotFound)
+		return
+	}
+
+	for _, id := range job.Blocks {
+		if err := userBucket.Delete(ctx, path.Join(id, metadata.NoCompactMarkFilename)); err != nil && !userBucket.IsObjNotFoundErr(err) {
+			level.Error(logger).Log("msg", "failed to retry compaction job", "block", id, "err", err)
+			http.Error(w, fmt.Sprintf("failed to remove no-compaction mark of block %s: %s", id, err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	c.jobsTracker.resetJob(userID, key)
+	level.Info(logger).Log("msg", "compaction job will be retried by the next compaction run", "blocks", len(job.Blocks))
+	redirectToJobsPage(w, req)
+}
+
+func (c *MultitenantCompactor) parseJobRequest(w http.ResponseWriter, req *http.Request) (userID, key string, ok bool) {
+	if c.State() != services.Running {
+		http.Error(w, "compactor is not running yet", http.StatusServiceUnavailable)
+		return "", "", false
+	}
+
+	userID = mux.Vars(req)["tenant"]
+	if userID == "" {
+		http.Error(w, "tenant ID can't be empty", http.StatusBadRequest)
+		return "", "", false
+	}
+
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, fmt.Sprintf("can't parse form: %s", err), http.StatusBadRequest)
+		return "", "", false
+	}
+
+	key = req.Form.Get("job")
+	if key == "" {
+		http.Error(w, "job can't be empty", http.StatusBadRequest)
+		return "", "", false
+	}
+
+	return userID, key, true
+}
+
+// redirectToJobsPage sends the browser back to the tenant's jobs page after a form submission,
+// while API clients just get a successful response.
+func redirectToJobsPage(w http.ResponseWriter, req *http.Request) {
+	if strings.Contains(req.Header.Get("Accept"), "application/json") {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Redirect(w, req, path.Dir(req.URL.Path), http.StatusSeeOther)
+}
+
+// planJobs returns the compaction jobs currently planned for the tenant, sorted in the order they're executed.
+// The planning is the same done at each compaction run, except that partial blocks and blocks which would be
+// garbage collected by the compaction run are not taken into account.
+func (c *MultitenantCompactor) planJobs(ctx context.Context, userID string, userBucket objstore.InstrumentedBucket, logger log.Logger) ([]*Job, error) {
+	// Metrics tracked while planning are not exported.
+	reg := prometheus.NewRegistry()
+
+	fetcher, _, _, err := c.newMetaFetcher(userBucket, "", logger, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	metas, _, err := fetcher.Fetch(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch metas")
+	}
+
+	grouper := excludeDownsampledBlocksGrouper{c.blocksGrouperFactory(ctx, c.compactorCfg, c.cfgProvider, userID, logger, reg)}
+	jobs, err := grouper.Groups(metas)
+	if err != nil {
+		return nil, errors.Wrap(err, "build compaction jobs")
+	}
+
+	return c.jobsOrder(jobs), nil
+}
+
+// listSkippedJobs returns the compaction jobs which have been skipped through the HTTP API,
+// based on the no-compact marks of the tenant's blocks.
+func listSkippedJobs(ctx context.Context, userBucket objstore.InstrumentedBucket, logger log.Logger) ([]skippedJob, error) {
+	var ids []ulid.ULID
+	err := userBucket.Iter(ctx, bucketindex.MarkersPathname+"/", func(name string) error {
+		if id, ok := bucketindex.IsNoCompactMarkFilename(path.Base(name)); ok {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list no-compact marks")
+	}
+
+	byKey := map[string]*skippedJob{}
+	for _, id := range ids {
+		mark := metadata.NoCompactMark{}
+		if err := metadata.ReadMarker(ctx, logger, userBucket, id.String(), &mark); err != nil {
+			if errors.Is(err, metadata.ErrorMarkerNotFound) {
+				// The mark has been deleted in the meanwhile.
+				continue
+			}
+			return nil, errors.Wrapf(err, "read no-compact mark of block %s", id)
+		}
+
+		if mark.Reason != metadata.ManualNoCompactReason || !strings.HasPrefix(mark.Details, skippedJobDetailsPrefix) {
+			continue
+		}
+
+		key := strings.TrimPrefix(mark.Details, skippedJobDetailsPrefix)
+		job, ok := byKey[key]
+		if !ok {
+			job = &skippedJob{Key: key}
+			byKey[key] = job
+		}
+
+		job.Blocks = append(job.Blocks, id.String())
+		if skippedAt := time.Unix(mark.NoCompactTime, 0).UTC(); skippedAt.After(job.SkippedAt) {
+			job.SkippedAt = skippedAt
+		}
+	}
+
+	out := make([]skippedJob, 0, len(byKey))
+	for _, job := range byKey {
+		sort.Strings(job.Blocks)
+		out = append(out, *job)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key < out[j].Key
+	})
+
+	return out, nil
+}
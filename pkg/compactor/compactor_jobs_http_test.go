@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+func TestMultitenantCompactor_JobsHandlers(t *testing.T) {
+	const userID = "user-1"
+
+	bkt := objstore.NewInMemBucket()
+	block1 := createTSDBBlock(t, bkt, userID, 0, time.Hour.Milliseconds(), 2, nil)
+	block2 := createTSDBBlock(t, bkt, userID, time.Hour.Milliseconds(), 2*time.Hour.Milliseconds(), 2, nil)
+
+	cfg := prepareConfig(t)
+	// Do not compact the tenant in background, to keep the planned jobs unchanged during the test.
+	cfg.DisabledTenants = []string{userID}
+
+	c, _, _, _, _ := prepare(t, cfg, bkt)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), c))
+	})
+
+	getJobs := func(t *testing.T) jobsPageContents {
+		req := httptest.NewRequest(http.MethodGet, "/compactor/tenant/user-1/jobs", nil)
+		req.Header.Set("Accept", "application/json")
+		req = mux.SetURLVars(req, map[string]string{"tenant": userID})
+
+		w := httptest.NewRecorder()
+		c.JobsHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		contents := jobsPageContents{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &contents))
+		return contents
+	}
+
+	postJob := func(t *testing.T, handler http.HandlerFunc, action, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/compactor/tenant/user-1/jobs/"+action, strings.NewReader(url.Values{"job": {key}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = mux.SetURLVars(req, map[string]string{"tenant": userID})
+
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	// The two blocks are planned to be merged together.
+	contents := getJobs(t)
+	require.Len(t, contents.Jobs, 1)
+	job := contents.Jobs[0]
+	assert.Equal(t, "merge", job.Stage)
+	assert.ElementsMatch(t, []string{block1.String(), block2.String()}, job.Blocks)
+	assert.NotEmpty(t, job.Owner)
+	assert.Equal(t, c.ringLifecycler.Addr, contents.Instance)
+	assert.Nil(t, job.Status)
+	assert.Equal(t, 1, contents.Backlog.Jobs)
+	assert.Equal(t, 1, contents.Backlog.MergeJobs)
+	assert.Equal(t, 2, contents.Backlog.Blocks)
+	assert.Empty(t, contents.SkippedJobs)
+
+	// The HTML page lists the same jobs.
+	req := httptest.NewRequest(http.MethodGet, "/compactor/tenant/user-1/jobs", nil)
+	req = mux.SetURLVars(req, map[string]string{"tenant": userID})
+	w := httptest.NewRecorder()
+	c.JobsHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), job.Key)
+
+	// Unknown jobs can't be skipped nor retried.
+	assert.Equal(t, http.StatusNotFound, postJob(t, c.SkipJobHandler, "skip", "unknown").Code)
+	assert.Equal(t, http.StatusNotFound, postJob(t, c.RetryJobHandler, "retry", job.Key).Code)
+
+	// Once skipped, the job is not planned anymore.
+	assert.Equal(t, http.StatusSeeOther, postJob(t, c.SkipJobHandler, "skip", job.Key).Code)
+
+	contents = getJobs(t)
+	assert.Empty(t, contents.Jobs)
+	assert.Equal(t, 0, contents.Backlog.Jobs)
+	require.Len(t, contents.SkippedJobs, 1)
+	assert.Equal(t, job.Key, contents.SkippedJobs[0].Key)
+	assert.ElementsMatch(t, job.Blocks, contents.SkippedJobs[0].Blocks)
+
+	// Once retried, the job is planned again.
+	assert.Equal(t, http.StatusSeeOther, postJob(t, c.RetryJobHandler, "retry", job.Key).Code)
+
+	contents = getJobs(t)
+	require.Len(t, contents.Jobs, 1)
+	assert.Equal(t, job.Key, contents.Jobs[0].Key)
+	assert.Empty(t, contents.SkippedJobs)
+}
+
+func TestMultitenantCompactor_TenantsHandler(t *testing.T) {
+	bkt := objstore.NewInMemBucket()
+	createTSDBBlock(t, bkt, "user-1", 0, time.Hour.Milliseconds(), 2, nil)
+	createTSDBBlock(t, bkt, "user-2", 0, time.Hour.Milliseconds(), 2, nil)
+
+	cfg := prepareConfig(t)
+	cfg.DisabledTenants = []string{"user-1", "user-2"}
+
+	c, _, _, _, _ := prepare(t, cfg, bkt)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), c))
+	})
+
+	c.jobsTracker.tenantFinished("user-2", assert.AnError)
+
+	req := httptest.NewRequest(http.MethodGet, "/compactor/tenants", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	c.TenantsHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	contents := tenantsPageContents{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &contents))
+	require.Len(t, contents.Tenants, 2)
+	assert.Equal(t, "user-1", contents.Tenants[0].Tenant)
+	assert.Nil(t, contents.Tenants[0].Status)
+	assert.Equal(t, "user-2", contents.Tenants[1].Tenant)
+	require.NotNil(t, contents.Tenants[1].Status)
+	assert.Equal(t, assert.AnError.Error(), contents.Tenants[1].Status.LastError)
+}
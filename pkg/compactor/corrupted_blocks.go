@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+)
+
+const (
+	// CorruptedBlocksFail fails the tenant's compaction when a corrupted block is found.
+	CorruptedBlocksFail = "fail"
+	// CorruptedBlocksNoCompact marks corrupted blocks for no-compaction.
+	CorruptedBlocksNoCompact = "no-compact"
+	// CorruptedBlocksRepair rewrites corrupted blocks without their chunks outside of the block time range,
+	// losing their samples, and falls back to marking them for no-compaction if the repair fails.
+	CorruptedBlocksRepair = "repair"
+
+	// CorruptedBlockNoCompactReason is the reason of the no-compact mark of the blocks found corrupted while compacting.
+	CorruptedBlockNoCompactReason metadata.NoCompactReason = "block-index-corrupted"
+)
+
+var CorruptedBlocksHandlings = []string{CorruptedBlocksFail, CorruptedBlocksNoCompact, CorruptedBlocksRepair}
+
+// CorruptedBlockError is a type wrapper for errors returned when a source block fails validation.
+type CorruptedBlockError struct {
+	err error
+	id  ulid.ULID
+}
+
+func corruptedBlockError(err error, brokenBlock ulid.ULID) CorruptedBlockError {
+	return CorruptedBlockError{err: err, id: brokenBlock}
+}
+
+func (e CorruptedBlockError) Error() string {
+	return e.err.Error()
+}
+
+// IsCorruptedBlockError returns true if the base error is a CorruptedBlockError.
+func IsCorruptedBlockError(err error) bool {
+	_, ok := errors.Cause(err).(CorruptedBlockError)
+	return ok
+}
+
+// corruptedBlockID returns the ID of the block which failed validation, if the base error is a CorruptedBlockError.
+func corruptedBlockID(err error) (ulid.ULID, bool) {
+	if e, ok := errors.Cause(err).(CorruptedBlockError); ok {
+		return e.id, true
+	}
+	return ulid.ULID{}, false
+}
+
+// repairCorruptedBlock rewrites a block which failed validation without the chunks outside of the block
+// time range, and marks the corrupted block for deletion. The samples of the dropped chunks are lost.
+func (c *BucketCompactor) repairCorruptedBlock(ctx context.Context, logger log.Logger, id ulid.ULID) error {
+	level.Info(logger).Log("msg", "repairing corrupted block", "id", id)
+
+	err := repairBlock(ctx, logger, c.bkt, c.sy.metrics.blocksMarkedForDeletion, id,
+		block.IgnoreCompleteOutsideChunk, block.IgnoreIssue347OutsideChunk)
+	if err != nil {
+		return err
+	}
+
+	c.metrics.blocksRepaired.Inc()
+	return nil
+}
+
+// markCorruptedBlockForNoCompaction excludes a block which failed validation from compaction, so that
+// it doesn't block the compaction of the tenant's other blocks.
+func (c *BucketCompactor) markCorruptedBlockForNoCompaction(ctx context.Context, logger log.Logger, id ulid.ULID, cause error) error {
+	return block.MarkForNoCompact(
+		ctx,
+		logger,
+		c.bkt,
+		id,
+		CorruptedBlockNoCompactReason,
+		fmt.Sprintf("Corrupted block found while compacting: %s", cause),
+		c.metrics.blocksMarkedForNoCompact.WithLabelValues(string(CorruptedBlockNoCompactReason)))
+}
@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/oklog/ulid"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
+	"github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+)
+
+func TestMultitenantCompactor_CorruptedBlocksHandling(t *testing.T) {
+	const user = "user"
+
+	outOfOrderChunks := []*testutil.BlockSeriesSpec{{
+		Labels: labels.Labels{labels.Label{Name: "case", Value: "out_of_order"}},
+		Chunks: []chunks.Meta{
+			tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(20, 20), newSample(21, 21)}),
+			tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(10, 10), newSample(11, 11)}),
+			// Extend block to cover 2h.
+			tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(0, 0), newSample(2*time.Hour.Milliseconds()-1, 0)}),
+		},
+	}}
+
+	// The block time range is shrunk to 2h after the block has been generated.
+	outsideChunks := []*testutil.BlockSeriesSpec{{
+		Labels: labels.Labels{labels.Label{Name: "case", Value: "outside"}},
+		Chunks: []chunks.Meta{
+			tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(0, 0), newSample(2*time.Hour.Milliseconds()-1, 0)}),
+			tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(3*time.Hour.Milliseconds(), 0), newSample(3*time.Hour.Milliseconds()+1, 0)}),
+		},
+	}}
+
+	tests := map[string]struct {
+		handling                 string
+		specs                    []*testutil.BlockSeriesSpec
+		expectedNoCompactReason  metadata.NoCompactReason
+		expectedRepairedBlocks   int
+		expectedCompactionFailed bool
+	}{
+		"fail on block with chunks outside of its time range": {
+			handling:                 CorruptedBlocksFail,
+			specs:                    outsideChunks,
+			expectedCompactionFailed: true,
+		},
+		"mark block with chunks outside of its time range for no-compaction": {
+			handling:                CorruptedBlocksNoCompact,
+			specs:                   outsideChunks,
+			expectedNoCompactReason: CorruptedBlockNoCompactReason,
+		},
+		"repair block with chunks outside of its time range": {
+			handling:               CorruptedBlocksRepair,
+			specs:                  outsideChunks,
+			expectedRepairedBlocks: 1,
+		},
+		"mark block with out-of-order chunks for no-compaction instead of repairing it": {
+			handling:                CorruptedBlocksRepair,
+			specs:                   outOfOrderChunks,
+			expectedNoCompactReason: metadata.OutOfOrderChunksNoCompactReason,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			storageDir := t.TempDir()
+
+			// We need two blocks to start compaction.
+			var metas []*metadata.Meta
+			for i := 0; i < 2; i++ {
+				meta, err := testutil.GenerateBlockFromSpec(user, filepath.Join(storageDir, user), testData.specs)
+				require.NoError(t, err)
+
+				meta.MaxTime = 2 * time.Hour.Milliseconds()
+				require.NoError(t, meta.WriteToDir(log.NewNopLogger(), filepath.Join(storageDir, user, meta.ULID.String())))
+				metas = append(metas, meta)
+			}
+
+			bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+			require.NoError(t, err)
+
+			cfg := prepareConfig(t)
+			cfg.CorruptedBlocksHandling = testData.handling
+			cfg.CompactionRetries = 1
+			c, _, tsdbPlanner, _, registry := prepare(t, cfg, bkt)
+
+			// Once a corrupted block has been handled, there's nothing left to compact.
+			tsdbPlanner.On("Plan", mock.Anything, mock.Anything).Return(metas, nil).Once()
+			tsdbPlanner.On("Plan", mock.Anything, mock.Anything).Return([]*metadata.Meta{}, nil)
+
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+
+			// Wait until a compaction run has been completed or has failed.
+			test.Poll(t, 10*time.Second, 1.0, func() interface{} {
+				return prom_testutil.ToFloat64(c.compactionRunsCompleted) + prom_testutil.ToFloat64(c.compactionRunsFailed)
+			})
+
+			require.NoError(t, services.StopAndAwaitTerminated(context.Background(), c))
+
+			if testData.expectedCompactionFailed {
+				assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.compactionRunsFailed))
+			} else {
+				assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.compactionRunsCompleted))
+			}
+
+			userBkt := bucket.NewUserBucketClient(user, bkt, nil)
+			var noCompactMarks []*metadata.NoCompactMark
+			var deletionMarks []*metadata.DeletionMark
+			var repaired []metadata.Meta
+
+			require.NoError(t, userBkt.Iter(context.Background(), "", func(name string) error {
+				id, ok := block.IsBlockDir(name)
+				if !ok {
+					return nil
+				}
+
+				if id != metas[0].ULID && id != metas[1].ULID {
+					m, err := block.DownloadMeta(context.Background(), log.NewNopLogger(), userBkt, id)
+					require.NoError(t, err)
+					repaired = append(repaired, m)
+				}
+
+				noCompactMark := &metadata.NoCompactMark{}
+				if err := metadata.ReadMarker(context.Background(), log.NewNopLogger(), objstore.WithNoopInstr(userBkt), id.String(), noCompactMark); err == nil {
+					noCompactMarks = append(noCompactMarks, noCompactMark)
+				}
+
+				deletionMark := &metadata.DeletionMark{}
+				if err := metadata.ReadMarker(context.Background(), log.NewNopLogger(), objstore.WithNoopInstr(userBkt), id.String(), deletionMark); err == nil {
+					deletionMarks = append(deletionMarks, deletionMark)
+				}
+				return nil
+			}))
+
+			if testData.expectedNoCompactReason != "" {
+				require.Len(t, noCompactMarks, 1)
+				assert.Equal(t, testData.expectedNoCompactReason, noCompactMarks[0].Reason)
+				assert.Contains(t, []ulid.ULID{metas[0].ULID, metas[1].ULID}, noCompactMarks[0].ID)
+			} else {
+				assert.Empty(t, noCompactMarks)
+			}
+
+			// Repaired blocks replace the corrupted ones, which are marked for deletion.
+			require.Len(t, repaired, testData.expectedRepairedBlocks)
+			require.Len(t, deletionMarks, testData.expectedRepairedBlocks)
+			for _, m := range repaired {
+				assert.Equal(t, metadata.CompactorRepairSource, m.Thanos.Source)
+				assert.Equal(t, uint64(1), m.Stats.NumChunks)
+			}
+
+			assert.Equal(t, float64(testData.expectedRepairedBlocks), prom_testutil.ToFloat64(c.bucketCompactorMetrics.blocksRepaired))
+			assert.NoError(t, prom_testutil.GatherAndCompare(registry, strings.NewReader(`
+				# HELP cortex_compactor_blocks_corrupted_total Total number of source blocks which failed validation while compacting.
+				# TYPE cortex_compactor_blocks_corrupted_total counter
+				cortex_compactor_blocks_corrupted_total 1
+			`), "cortex_compactor_blocks_corrupted_total"))
+		})
+	}
+}
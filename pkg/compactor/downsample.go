@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// isSupportedDownsamplingResolution returns whether the resolution is one the store-gateway can serve.
+func isSupportedDownsamplingResolution(res time.Duration) bool {
+	return res.Milliseconds() == downsample.ResLevel1 || res.Milliseconds() == downsample.ResLevel2
+}
+
+// ValidateDownsamplingResolutions returns an error if any of the input downsampling resolutions is not supported.
+func ValidateDownsamplingResolutions(resolutions []time.Duration) error {
+	for _, res := range resolutions {
+		if !isSupportedDownsamplingResolution(res) {
+			return errors.Errorf(errInvalidDownsamplingResolution, res.String())
+		}
+	}
+	return nil
+}
+
+// downsamplingResolutions returns the supported resolutions in the input list, in milliseconds,
+// sorted from the highest to the lowest resolution and without duplicates, and the unsupported ones.
+func downsamplingResolutions(resolutions []time.Duration) (supported []int64, unsupported []time.Duration) {
+	seen := map[int64]struct{}{}
+	for _, res := range resolutions {
+		if !isSupportedDownsamplingResolution(res) {
+			unsupported = append(unsupported, res)
+			continue
+		}
+		if _, ok := seen[res.Milliseconds()]; ok {
+			continue
+		}
+		seen[res.Milliseconds()] = struct{}{}
+		supported = append(supported, res.Milliseconds())
+	}
+
+	sort.Slice(supported, func(i, j int) bool {
+		return supported[i] < supported[j]
+	})
+	return supported, unsupported
+}
+
+// excludeDownsampledBlocksGrouper is a Grouper which doesn't plan any compaction of downsampled blocks:
+// they're built from blocks which have already been compacted to the largest block range.
+type excludeDownsampledBlocksGrouper struct {
+	Grouper
+}
+
+func (g excludeDownsampledBlocksGrouper) Groups(blocks map[ulid.ULID]*metadata.Meta) ([]*Job, error) {
+	raw := make(map[ulid.ULID]*metadata.Meta, len(blocks))
+	for id, m := range blocks {
+		if m.Thanos.Downsample.Resolution == downsample.ResLevel0 {
+			raw[id] = m
+		}
+	}
+	return g.Grouper.Groups(raw)
+}
+
+// downsamplingJob is a single block to downsample to a lower resolution.
+type downsamplingJob struct {
+	meta       *metadata.Meta
+	resolution int64
+}
+
+// planDownsampling returns the blocks to downsample for each of the input resolutions. Raw blocks are eligible
+// to be downsampled once they have been compacted to the largest block range and their time range ended since at
+// least another largest block range, so that they're not expected to be compacted anymore. Each resolution is built
+// from the previous one, while the highest resolution is built from raw blocks. A block is not downsampled
+// again if a block with the same sources already exists at the target resolution. Blocks downsampled during
+// this compaction run are downsampled to the next resolution in the next compaction run.
+func planDownsampling(metas map[ulid.ULID]*metadata.Meta, resolutions []int64, largestRange int64, now time.Time) []downsamplingJob {
+	if len(resolutions) == 0 || largestRange <= 0 {
+		return nil
+	}
+
+	// Keep track of the sources which have already been downsampled at each resolution.
+	downsampled := map[int64]map[string]struct{}{}
+	for _, m := range metas {
+		res := m.Thanos.Downsample.Resolution
+		if res == downsample.ResLevel0 {
+			continue
+		}
+		if downsampled[res] == nil {
+			downsampled[res] = map[string]struct{}{}
+		}
+		downsampled[res][blockSourcesKey(m)] = struct{}{}
+	}
+
+	// Sort blocks to get a stable plan.
+	sorted := make([]*metadata.Meta, 0, len(metas))
+	for _, m := range metas {
+		sorted = append(sorted, m)
+	}
+	sorted = sortMetasByMinTime(sorted)
+
+	maxTime := util.TimeToMillis(now) - largestRange
+	var jobs []downsamplingJob
+
+	for i, res := range resolutions {
+		sourceRes := downsample.ResLevel0
+		if i > 0 {
+			sourceRes = resolutions[i-1]
+		}
+
+		for _, m := range sorted {
+			if m.Thanos.Downsample.Resolution != sourceRes {
+				continue
+			}
+			if sourceRes == downsample.ResLevel0 && (m.MaxTime-m.MinTime < largestRange || m.MaxTime > maxTime) {
+				continue
+			}
+			if _, ok := downsampled[res][blockSourcesKey(m)]; ok {
+				continue
+			}
+
+			jobs = append(jobs, downsamplingJob{meta: m, resolution: res})
+		}
+	}
+
+	return jobs
+}
+
+func blockSourcesKey(m *metadata.Meta) string {
+	sources := make([]string, 0, len(m.Compaction.Sources))
+	for _, id := range m.Compaction.Sources {
+		sources = append(sources, id.String())
+	}
+	sort.Strings(sources)
+	return strings.Join(sources, ",")
+}
+
+// downsampleUser downsamples the tenant blocks according to the tenant's configured resolutions.
+// Each block to downsample is owned by a single compactor instance of the tenant's shard.
+func (c *MultitenantCompactor) downsampleUser(ctx context.Context, userID string, bkt objstore.Bucket, metas map[ulid.ULID]*metadata.Meta, logger log.Logger) error {
+	resolutions, unsupported := downsamplingResolutions(c.cfgProvider.CompactorDownsamplingResolutions(userID))
+	if len(unsupported) > 0 {
+		// The limits are validated when loaded, so this is not expected to happen.
+		level.Error(logger).Log("msg", "ignoring unsupported downsampling resolutions configured for tenant", "resolutions", fmt.Sprintf("%v", unsupported))
+	}
+	if len(resolutions) == 0 {
+		return nil
+	}
+
+	largestRange := c.compactorCfg.BlockRanges.ToMilliseconds()
+	if len(largestRange) == 0 {
+		return nil
+	}
+
+	jobs := planDownsampling(metas, resolutions, largestRange[len(largestRange)-1], time.Now())
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		shardingKey := fmt.Sprintf("%s-downsample-%d-%s", userID, job.resolution, job.meta.ULID)
+		if ok, err := c.shardingStrategy.ownJob(NewJob(userID, shardingKey, labels.FromMap(job.meta.Thanos.Labels), job.resolution, metadata.NoneFunc, false, 0, shardingKey)); err != nil {
+			level.Warn(logger).Log("msg", "unable to check if downsampling of block is owned by this compactor", "block", job.meta.ULID, "resolution", job.resolution, "err", err)
+			continue
+		} else if !ok {
+			continue
+		}
+
+		if err := c.downsampleBlock(ctx, bkt, job, logger); err != nil {
+			c.blocksDownsamplingFailed.Inc()
+			return errors.Wrapf(err, "downsample block %s to resolution %d", job.meta.ULID, job.resolution)
+		}
+		c.blocksDownsampled.WithLabelValues(time.Duration(job.resolution * int64(time.Millisecond)).String()).Inc()
+	}
+
+	return nil
+}
+
+// downsampleBlock downloads the input block, downsamples it to the target resolution and uploads the result.
+func (c *MultitenantCompactor) downsampleBlock(ctx context.Context, bkt objstore.Bucket, job downsamplingJob, logger log.Logger) error {
+	begin := time.Now()
+	workDir := filepath.Join(c.compactorCfg.DataDir, "downsample", job.meta.ULID.String())
+
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove downsampling work directory", "path", workDir, "err", err)
+		}
+	}()
+
+	bdir := filepath.Join(workDir, job.meta.ULID.String())
+	if err := block.Download(ctx, logger, bkt, job.meta.ULID, bdir); err != nil {
+		return errors.Wrap(err, "download block")
+	}
+
+	b, err := tsdb.OpenBlock(logger, bdir, downsample.NewPool())
+	if err != nil {
+		return errors.Wrap(err, "open block")
+	}
+
+	id, err := downsample.Downsample(logger, job.meta, b, workDir, job.resolution)
+	if closeErr := b.Close(); closeErr != nil {
+		level.Warn(logger).Log("msg", "failed to close block", "block", job.meta.ULID, "err", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	resdir := filepath.Join(workDir, id.String())
+	if err := block.VerifyIndex(logger, filepath.Join(resdir, block.IndexFilename), job.meta.MinTime, job.meta.MaxTime); err != nil {
+		return errors.Wrapf(err, "invalid result block %s", id)
+	}
+
+	if err := mimir_tsdb.UploadBlock(ctx, logger, bkt, resdir, nil); err != nil {
+		return errors.Wrapf(err, "upload of %s failed", id)
+	}
+
+	elapsed := time.Since(begin)
+	level.Info(logger).Log("msg", "downsampled block", "source_block", job.meta.ULID, "result_block", id, "resolution", job.resolution, "duration", elapsed, "duration_ms", elapsed.Milliseconds())
+	return nil
+}
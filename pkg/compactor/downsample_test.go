@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+func TestDownsamplingResolutions(t *testing.T) {
+	supported, unsupported := downsamplingResolutions(nil)
+	assert.Empty(t, supported)
+	assert.Empty(t, unsupported)
+
+	supported, unsupported = downsamplingResolutions([]time.Duration{time.Hour, 5 * time.Minute, time.Hour})
+	assert.Equal(t, []int64{downsample.ResLevel1, downsample.ResLevel2}, supported)
+	assert.Empty(t, unsupported)
+
+	supported, unsupported = downsamplingResolutions([]time.Duration{10 * time.Minute, time.Hour})
+	assert.Equal(t, []int64{downsample.ResLevel2}, supported)
+	assert.Equal(t, []time.Duration{10 * time.Minute}, unsupported)
+}
+
+func TestPlanDownsampling(t *testing.T) {
+	const largestRange = int64(24 * time.Hour / time.Millisecond)
+
+	now := time.Now()
+	old := util.TimeToMillis(now.Add(-7 * 24 * time.Hour))
+	recent := util.TimeToMillis(now.Add(-12 * time.Hour))
+
+	newMeta := func(id ulid.ULID, minT, maxT, resolution int64, sources ...ulid.ULID) *metadata.Meta {
+		m := &metadata.Meta{
+			BlockMeta: tsdb.BlockMeta{ULID: id, MinTime: minT, MaxTime: maxT},
+			Thanos:    metadata.Thanos{Downsample: metadata.ThanosDownsample{Resolution: resolution}},
+		}
+		if len(sources) == 0 {
+			sources = []ulid.ULID{id}
+		}
+		m.Compaction.Sources = sources
+		return m
+	}
+
+	block1 := ulid.MustNew(1, nil)
+	block2 := ulid.MustNew(2, nil)
+	block3 := ulid.MustNew(3, nil)
+	block4 := ulid.MustNew(4, nil)
+	block5 := ulid.MustNew(5, nil)
+
+	tests := map[string]struct {
+		metas       []*metadata.Meta
+		resolutions []int64
+		expected    []downsamplingJob
+	}{
+		"no resolutions": {
+			metas:       []*metadata.Meta{newMeta(block1, old, old+largestRange, 0)},
+			resolutions: nil,
+			expected:    nil,
+		},
+		"raw block not compacted to the largest range": {
+			metas:       []*metadata.Meta{newMeta(block1, old, old+largestRange/2, 0)},
+			resolutions: []int64{downsample.ResLevel1},
+			expected:    nil,
+		},
+		"raw block too recent": {
+			metas:       []*metadata.Meta{newMeta(block1, recent-largestRange, recent, 0)},
+			resolutions: []int64{downsample.ResLevel1},
+			expected:    nil,
+		},
+		"raw block compacted to the largest range": {
+			metas:       []*metadata.Meta{newMeta(block1, old, old+largestRange, 0)},
+			resolutions: []int64{downsample.ResLevel1, downsample.ResLevel2},
+			expected:    []downsamplingJob{{meta: newMeta(block1, old, old+largestRange, 0), resolution: downsample.ResLevel1}},
+		},
+		"raw block already downsampled at the first resolution": {
+			metas: []*metadata.Meta{
+				newMeta(block1, old, old+largestRange, 0),
+				newMeta(block2, old, old+largestRange, downsample.ResLevel1, block1),
+			},
+			resolutions: []int64{downsample.ResLevel1, downsample.ResLevel2},
+			expected:    []downsamplingJob{{meta: newMeta(block2, old, old+largestRange, downsample.ResLevel1, block1), resolution: downsample.ResLevel2}},
+		},
+		"raw block already downsampled at all resolutions": {
+			metas: []*metadata.Meta{
+				newMeta(block1, old, old+largestRange, 0),
+				newMeta(block2, old, old+largestRange, downsample.ResLevel1, block1),
+				newMeta(block3, old, old+largestRange, downsample.ResLevel2, block1),
+			},
+			resolutions: []int64{downsample.ResLevel1, downsample.ResLevel2},
+			expected:    nil,
+		},
+		"only the lowest resolution configured": {
+			metas: []*metadata.Meta{
+				newMeta(block1, old, old+largestRange, 0),
+				newMeta(block2, old, old+largestRange, downsample.ResLevel1, block1),
+			},
+			resolutions: []int64{downsample.ResLevel2},
+			expected:    []downsamplingJob{{meta: newMeta(block1, old, old+largestRange, 0), resolution: downsample.ResLevel2}},
+		},
+		"raw block compacted again after having been downsampled": {
+			metas: []*metadata.Meta{
+				newMeta(block3, old, old+largestRange, 0, block1, block4),
+				newMeta(block5, old, old+largestRange, downsample.ResLevel1, block1),
+			},
+			resolutions: []int64{downsample.ResLevel1},
+			expected:    []downsamplingJob{{meta: newMeta(block3, old, old+largestRange, 0, block1, block4), resolution: downsample.ResLevel1}},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			metas := map[ulid.ULID]*metadata.Meta{}
+			for _, m := range testData.metas {
+				metas[m.ULID] = m
+			}
+
+			assert.Equal(t, testData.expected, planDownsampling(metas, testData.resolutions, largestRange, now))
+		})
+	}
+}
+
+func TestExcludeDownsampledBlocksGrouper(t *testing.T) {
+	const hour = int64(time.Hour / time.Millisecond)
+
+	block1 := ulid.MustNew(1, nil)
+	block2 := ulid.MustNew(2, nil)
+	block3 := ulid.MustNew(3, nil)
+	block4 := ulid.MustNew(4, nil)
+
+	newMeta := func(id ulid.ULID, resolution int64) *metadata.Meta {
+		return &metadata.Meta{
+			BlockMeta: tsdb.BlockMeta{ULID: id, MinTime: 0, MaxTime: 2 * hour},
+			Thanos:    metadata.Thanos{Downsample: metadata.ThanosDownsample{Resolution: resolution}},
+		}
+	}
+
+	grouper := excludeDownsampledBlocksGrouper{NewSplitAndMergeGrouper("user-1", []int64{2 * hour}, 0, 0, log.NewNopLogger())}
+	jobs, err := grouper.Groups(map[ulid.ULID]*metadata.Meta{
+		block1: newMeta(block1, downsample.ResLevel0),
+		block2: newMeta(block2, downsample.ResLevel0),
+		block3: newMeta(block3, downsample.ResLevel1),
+		block4: newMeta(block4, downsample.ResLevel1),
+	})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, []ulid.ULID{block1, block2}, jobs[0].IDs())
+}
+
+func TestMultitenantCompactor_DownsampleBlock(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	userBkt := bucket.NewUserBucketClient(userID, bkt, nil)
+
+	const hour = int64(time.Hour / time.Millisecond)
+	blockID := createTSDBBlock(t, bkt, userID, 0, 2*hour, 10, map[string]string{"ext": "1"})
+
+	meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), userBkt, blockID)
+	require.NoError(t, err)
+
+	c, _, _, _, _ := prepareWithConfigProvider(t, prepareConfig(t), bkt, newMockConfigProvider())
+	require.NoError(t, c.downsampleBlock(ctx, userBkt, downsamplingJob{meta: &meta, resolution: downsample.ResLevel1}, log.NewNopLogger()))
+
+	var downsampled []metadata.Meta
+	require.NoError(t, userBkt.Iter(ctx, "", func(name string) error {
+		id, ok := block.IsBlockDir(name)
+		if !ok || id == blockID {
+			return nil
+		}
+
+		m, err := block.DownloadMeta(ctx, log.NewNopLogger(), userBkt, id)
+		if err != nil {
+			return err
+		}
+		downsampled = append(downsampled, m)
+		return nil
+	}))
+
+	require.Len(t, downsampled, 1)
+	assert.Equal(t, downsample.ResLevel1, downsampled[0].Thanos.Downsample.Resolution)
+	assert.Equal(t, meta.MinTime, downsampled[0].MinTime)
+	assert.Equal(t, meta.MaxTime, downsampled[0].MaxTime)
+	assert.Equal(t, meta.Compaction.Sources, downsampled[0].Compaction.Sources)
+	assert.Equal(t, map[string]string{"ext": "1"}, downsampled[0].Thanos.Labels)
+	assert.Equal(t, meta.Stats.NumSeries, downsampled[0].Stats.NumSeries)
+}
@@ -0,0 +1,240 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"sync"
+	"time"
+)
+
+// jobStatus is the outcome of the last runs of a compaction job, as seen by this compactor instance.
+type jobStatus struct {
+	Running     bool      `json:"running"`
+	LastStart   time.Time `json:"last_start,omitempty"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// tenantStatus is the outcome of the last compaction of a tenant, as seen by this compactor instance.
+type tenantStatus struct {
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// jobsTracker keeps track of the compaction jobs run by this compactor instance, so that
+// their status can be exposed through the HTTP API. A nil jobsTracker tracks nothing.
+type jobsTracker struct {
+	// How long the status of a job is kept once the job is not planned anymore.
+	retention time.Duration
+
+	mtx     sync.Mutex
+	tenants map[string]*tenantStatus
+	jobs    map[string]map[string]*jobStatus
+}
+
+func newJobsTracker(retention time.Duration) *jobsTracker {
+	return &jobsTracker{
+		retention: retention,
+		tenants:   map[string]*tenantStatus{},
+		jobs:      map[string]map[string]*jobStatus{},
+	}
+}
+
+// forTenant returns a view of the tracker for a single tenant, to be used by the tenant's BucketCompactor.
+func (t *jobsTracker) forTenant(userID string) *tenantJobsTracker {
+	if t == nil {
+		return nil
+	}
+	return &tenantJobsTracker{tracker: t, userID: userID}
+}
+
+func (t *jobsTracker) jobStarted(job *Job) {
+	if t == nil {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	s := t.getOrCreateJob(job.UserID(), job.Key())
+	s.Running = true
+	s.LastStart = time.Now()
+}
+
+func (t *jobsTracker) jobFinished(job *Job, err error) {
+	if t == nil {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	s := t.getOrCreateJob(job.UserID(), job.Key())
+	s.Running = false
+	if err != nil {
+		s.LastFailure = time.Now()
+		s.LastError = err.Error()
+	} else {
+		s.LastSuccess = time.Now()
+		s.LastError = ""
+	}
+}
+
+// jobsPlanned forgets the status of the tenant's jobs which are not planned anymore, typically because
+// they have been successfully completed, once the retention period has elapsed since they last ran.
+func (t *jobsTracker) jobsPlanned(userID string, planned []*Job) {
+	if t == nil {
+		return
+	}
+
+	keys := make(map[string]struct{}, len(planned))
+	for _, job := range planned {
+		keys[job.Key()] = struct{}{}
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	cutoff := time.Now().Add(-t.retention)
+	for key, s := range t.jobs[userID] {
+		if _, ok := keys[key]; ok || s.Running {
+			continue
+		}
+		if s.LastSuccess.Before(cutoff) && s.LastFailure.Before(cutoff) {
+			delete(t.jobs[userID], key)
+		}
+	}
+}
+
+// resetJob forgets the status of a job, e.g. once it has been manually retried.
+func (t *jobsTracker) resetJob(userID, key string) {
+	if t == nil {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	delete(t.jobs[userID], key)
+}
+
+func (t *jobsTracker) tenantFinished(userID string, err error) {
+	if t == nil {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	s, ok := t.tenants[userID]
+	if !ok {
+		s = &tenantStatus{}
+		t.tenants[userID] = s
+	}
+
+	if err != nil {
+		s.LastFailure = time.Now()
+		s.LastError = err.Error()
+	} else {
+		s.LastSuccess = time.Now()
+		s.LastError = ""
+	}
+}
+
+// tenantStatus returns a copy of the status of the input tenant, or false if the tenant has never been compacted by this instance.
+func (t *jobsTracker) tenantStatus(userID string) (tenantStatus, bool) {
+	if t == nil {
+		return tenantStatus{}, false
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	s, ok := t.tenants[userID]
+	if !ok {
+		return tenantStatus{}, false
+	}
+	return *s, true
+}
+
+// jobsStatus returns a copy of the status of all the jobs of the input tenant, by job key.
+func (t *jobsTracker) jobsStatus(userID string) map[string]jobStatus {
+	out := map[string]jobStatus{}
+	if t == nil {
+		return out
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for key, s := range t.jobs[userID] {
+		out[key] = *s
+	}
+	return out
+}
+
+// forgetTenants removes the status of all tenants not in the input set, e.g. because they have
+// been deleted or they're not owned by this compactor instance anymore.
+func (t *jobsTracker) forgetTenants(keep map[string]struct{}) {
+	if t == nil {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for userID := range t.tenants {
+		if _, ok := keep[userID]; !ok {
+			delete(t.tenants, userID)
+		}
+	}
+	for userID := range t.jobs {
+		if _, ok := keep[userID]; !ok {
+			delete(t.jobs, userID)
+		}
+	}
+}
+
+func (t *jobsTracker) getOrCreateJob(userID, key string) *jobStatus {
+	userJobs, ok := t.jobs[userID]
+	if !ok {
+		userJobs = map[string]*jobStatus{}
+		t.jobs[userID] = userJobs
+	}
+
+	s, ok := userJobs[key]
+	if !ok {
+		s = &jobStatus{}
+		userJobs[key] = s
+	}
+	return s
+}
+
+// tenantJobsTracker tracks the compaction jobs of a single tenant. A nil tenantJobsTracker tracks nothing.
+type tenantJobsTracker struct {
+	tracker *jobsTracker
+	userID  string
+}
+
+func (t *tenantJobsTracker) jobsPlanned(planned []*Job) {
+	if t == nil {
+		return
+	}
+	t.tracker.jobsPlanned(t.userID, planned)
+}
+
+func (t *tenantJobsTracker) jobStarted(job *Job) {
+	if t == nil {
+		return
+	}
+	t.tracker.jobStarted(job)
+}
+
+func (t *tenantJobsTracker) jobFinished(job *Job, err error) {
+	if t == nil {
+		return
+	}
+	t.tracker.jobFinished(job, err)
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+)
+
+func TestJobsTracker(t *testing.T) {
+	tracker := newJobsTracker(time.Hour)
+	tenant := tracker.forTenant("user-1")
+
+	job1 := NewJob("user-1", "job-1", nil, 0, metadata.NoneFunc, false, 0, "")
+	job2 := NewJob("user-1", "job-2", nil, 0, metadata.NoneFunc, false, 0, "")
+
+	tenant.jobStarted(job1)
+	assert.True(t, tracker.jobsStatus("user-1")["job-1"].Running)
+
+	tenant.jobFinished(job1, assert.AnError)
+	tenant.jobStarted(job2)
+	tenant.jobFinished(job2, nil)
+
+	statuses := tracker.jobsStatus("user-1")
+	require.Len(t, statuses, 2)
+	assert.False(t, statuses["job-1"].Running)
+	assert.False(t, statuses["job-1"].LastFailure.IsZero())
+	assert.Equal(t, assert.AnError.Error(), statuses["job-1"].LastError)
+	assert.False(t, statuses["job-2"].LastSuccess.IsZero())
+	assert.Empty(t, statuses["job-2"].LastError)
+
+	// Jobs not planned anymore are kept until the retention period has elapsed.
+	tenant.jobsPlanned([]*Job{job1})
+	assert.Len(t, tracker.jobsStatus("user-1"), 2)
+
+	tracker.retention = 0
+	tenant.jobsPlanned([]*Job{job1})
+	assert.Len(t, tracker.jobsStatus("user-1"), 1)
+	assert.Contains(t, tracker.jobsStatus("user-1"), "job-1")
+
+	tracker.resetJob("user-1", "job-1")
+	assert.Empty(t, tracker.jobsStatus("user-1"))
+
+	// Tenants no longer owned are forgotten.
+	tracker.tenantFinished("user-1", nil)
+	tracker.tenantFinished("user-2", nil)
+	tracker.forgetTenants(map[string]struct{}{"user-2": {}})
+
+	_, ok := tracker.tenantStatus("user-1")
+	assert.False(t, ok)
+	_, ok = tracker.tenantStatus("user-2")
+	assert.True(t, ok)
+
+	// A nil tracker tracks nothing.
+	var nilTracker *jobsTracker
+	nilTracker.forTenant("user-1").jobStarted(job1)
+	assert.Empty(t, nilTracker.jobsStatus("user-1"))
+}
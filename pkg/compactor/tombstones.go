@@ -0,0 +1,501 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// tombstonesLoaderFunc returns the series deletion requests to apply while compacting blocks.
+type tombstonesLoaderFunc func(ctx context.Context) (mimir_tsdb.Tombstones, error)
+
+// loadApplicableTombstones returns the pending series deletion requests of the tenant which can
+// no longer be cancelled, and can therefore be physically applied to blocks.
+func (c *MultitenantCompactor) loadApplicableTombstones(ctx context.Context, userID string) (mimir_tsdb.Tombstones, error) {
+	all, err := mimir_tsdb.ReadTombstones(ctx, c.bucketClient, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "read series deletion requests")
+	}
+
+	cutoff := util.TimeToMillis(time.Now().Add(-c.cfgProvider.CompactorSeriesDeletionDelay(userID)))
+
+	var applicable mimir_tsdb.Tombstones
+	for _, t := range all {
+		if t.State == mimir_tsdb.TombstonePending && t.RequestCreatedAt <= cutoff {
+			applicable = append(applicable, t)
+		}
+	}
+	return applicable, nil
+}
+
+// writeBlockTombstones writes the Prometheus tombstones file into the local block directory, for all
+// series of the block matching the series deletion requests. The tombstones are then honored by the TSDB
+// compactor, which doesn't copy the deleted samples to the output blocks. Returns the number of series
+// with deleted samples.
+func writeBlockTombstones(logger log.Logger, bdir string, meta *metadata.Meta, ts mimir_tsdb.Tombstones) (int, error) {
+	// The block max time is exclusive.
+	ts = ts.Overlapping(meta.MinTime, meta.MaxTime-1)
+	if len(ts) == 0 {
+		return 0, nil
+	}
+
+	ir, err := index.NewFileReader(filepath.Join(bdir, block.IndexFilename))
+	if err != nil {
+		return 0, errors.Wrap(err, "open index")
+	}
+	defer func() { _ = ir.Close() }()
+
+	memTombstones := tombstones.NewMemTombstones()
+	deletedSeries := map[storage.SeriesRef]struct{}{}
+
+	for _, t := range ts {
+		interval := tombstones.Interval{Mint: t.StartTime, Maxt: t.EndTime}
+
+		for _, ms := range t.Matchers() {
+			p, err := tsdb.PostingsForMatchers(ir, ms...)
+			if err != nil {
+				return 0, errors.Wrapf(err, "select series for deletion request %s", t.RequestID)
+			}
+
+			for p.Next() {
+				memTombstones.AddInterval(p.At(), interval)
+				deletedSeries[p.At()] = struct{}{}
+			}
+			if err := p.Err(); err != nil {
+				return 0, errors.Wrapf(err, "select series for deletion request %s", t.RequestID)
+			}
+		}
+	}
+
+	if len(deletedSeries) == 0 {
+		return 0, nil
+	}
+
+	if _, err := tombstones.WriteFile(logger, bdir, memTombstones); err != nil {
+		return 0, errors.Wrap(err, "write tombstones")
+	}
+	return len(deletedSeries), nil
+}
+
+// appliedTombstones returns the IDs of the series deletion requests already applied to the block.
+func appliedTombstones(meta *metadata.Meta) map[string]struct{} {
+	applied := map[string]struct{}{}
+	for _, r := range meta.Thanos.Rewrites {
+		for _, d := range r.DeletionsApplied {
+			applied[d.RequestID] = struct{}{}
+		}
+	}
+	return applied
+}
+
+// tombstonesRewrites returns the block rewrites to store in the meta of the block built from the input blocks,
+// recording both the series deletion requests applied to the input blocks and the ones applied while building it.
+func tombstonesRewrites(inputs []*metadata.Meta, ts mimir_tsdb.Tombstones) []metadata.Rewrite {
+	var deletions []metadata.DeletionRequest
+	seen := map[string]struct{}{}
+
+	for _, m := range inputs {
+		for _, r := range m.Thanos.Rewrites {
+			for _, d := range r.DeletionsApplied {
+				if _, ok := seen[d.RequestID]; !ok {
+					seen[d.RequestID] = struct{}{}
+					deletions = append(deletions, d)
+				}
+			}
+		}
+	}
+
+	for _, t := range ts {
+		if _, ok := seen[t.RequestID]; !ok {
+			seen[t.RequestID] = struct{}{}
+			deletions = append(deletions, metadata.DeletionRequest{
+				RequestID: t.RequestID,
+				Intervals: tombstones.Intervals{{Mint: t.StartTime, Maxt: t.EndTime}},
+			})
+		}
+	}
+
+	if len(deletions) == 0 {
+		return nil
+	}
+	return []metadata.Rewrite{{DeletionsApplied: deletions}}
+}
+
+// tombstonesRewriteJob is a block to rewrite to apply the series deletion requests overlapping it.
+type tombstonesRewriteJob struct {
+	meta       *metadata.Meta
+	tombstones mimir_tsdb.Tombstones
+}
+
+// planTombstonesRewrites returns the raw blocks to rewrite to apply the series deletion requests not yet applied
+// to them. The blocks which are still expected to be compacted are skipped, because the requests are applied
+// while compacting them: only blocks which have been compacted to the largest block range, or whose time range
+// ended since at least the largest block range, are rewritten.
+func planTombstonesRewrites(metas map[ulid.ULID]*metadata.Meta, ts mimir_tsdb.Tombstones, largestRange int64, now time.Time) []tombstonesRewriteJob {
+	if len(ts) == 0 {
+		return nil
+	}
+
+	maxTime := util.TimeToMillis(now) - largestRange
+	var jobs []tombstonesRewriteJob
+
+	for _, m := range sortMetasByMinTime(metasToSlice(metas)) {
+		if m.Thanos.Downsample.Resolution != downsample.ResLevel0 {
+			continue
+		}
+		if m.MaxTime-m.MinTime < largestRange && m.MaxTime > maxTime {
+			continue
+		}
+
+		if pending := notAppliedTombstones(m, ts); len(pending) > 0 {
+			jobs = append(jobs, tombstonesRewriteJob{meta: m, tombstones: pending})
+		}
+	}
+
+	return jobs
+}
+
+// planOutdatedDownsampledBlocks returns the downsampled blocks which have been built before some series deletion
+// requests have been applied to their raw source block. Once deleted, they're downsampled again from the
+// rewritten raw block, because series deletion requests can't be applied to downsampled blocks.
+func planOutdatedDownsampledBlocks(metas map[ulid.ULID]*metadata.Meta, ts mimir_tsdb.Tombstones) []*metadata.Meta {
+	if len(ts) == 0 {
+		return nil
+	}
+
+	raw := map[string]*metadata.Meta{}
+	for _, m := range metas {
+		if m.Thanos.Downsample.Resolution == downsample.ResLevel0 {
+			raw[blockSourcesKey(m)] = m
+		}
+	}
+
+	var outdated []*metadata.Meta
+	for _, m := range sortMetasByMinTime(metasToSlice(metas)) {
+		if m.Thanos.Downsample.Resolution == downsample.ResLevel0 {
+			continue
+		}
+
+		source, ok := raw[blockSourcesKey(m)]
+		if !ok || len(notAppliedTombstones(source, ts)) > 0 {
+			// The downsampled block can't be built again yet.
+			continue
+		}
+		if len(notAppliedTombstones(m, ts)) > 0 {
+			outdated = append(outdated, m)
+		}
+	}
+
+	return outdated
+}
+
+// notAppliedTombstones returns the series deletion requests overlapping the block which haven't been applied to it.
+func notAppliedTombstones(meta *metadata.Meta, ts mimir_tsdb.Tombstones) mimir_tsdb.Tombstones {
+	applied := appliedTombstones(meta)
+
+	var out mimir_tsdb.Tombstones
+	// The block max time is exclusive.
+	for _, t := range ts.Overlapping(meta.MinTime, meta.MaxTime-1) {
+		if _, ok := applied[t.RequestID]; !ok {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func metasToSlice(metas map[ulid.ULID]*metadata.Meta) []*metadata.Meta {
+	out := make([]*metadata.Meta, 0, len(metas))
+	for _, m := range metas {
+		out = append(out, m)
+	}
+	return out
+}
+
+// applyTombstones rewrites the tenant blocks which are not compacted anymore to physically remove the samples
+// deleted by the applicable series deletion requests, and marks the requests as processed once they have been
+// applied to all blocks. Each block to rewrite is owned by a single compactor instance of the tenant's shard.
+func (c *MultitenantCompactor) applyTombstones(ctx context.Context, userID string, bkt objstore.Bucket, syncer *Syncer, logger log.Logger) error {
+	ts, err := c.loadApplicableTombstones(ctx, userID)
+	if err != nil || len(ts) == 0 {
+		return err
+	}
+
+	largestRange := c.compactorCfg.BlockRanges.ToMilliseconds()
+	if len(largestRange) == 0 {
+		return nil
+	}
+
+	// Re-sync the metas to also include the blocks produced by the compaction.
+	if err := syncer.SyncMetas(ctx); err != nil {
+		return errors.Wrap(err, "sync before applying series deletion requests")
+	}
+
+	for _, m := range planOutdatedDownsampledBlocks(syncer.Metas(), ts) {
+		if !c.ownTombstonesJob(userID, "delete", m, logger) {
+			continue
+		}
+
+		level.Info(logger).Log("msg", "marking downsampled block for deletion to apply series deletion requests", "block", m.ULID, "resolution", m.Thanos.Downsample.Resolution)
+		if err := block.MarkForDeletion(ctx, logger, bkt, m.ULID, "series deletion requests applied to source block", c.blocksMarkedForDeletion); err != nil {
+			return errors.Wrapf(err, "mark downsampled block %s for deletion", m.ULID)
+		}
+	}
+
+	for _, job := range planTombstonesRewrites(syncer.Metas(), ts, largestRange[len(largestRange)-1], time.Now()) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !c.ownTombstonesJob(userID, "rewrite", job.meta, logger) {
+			continue
+		}
+
+		if err := c.rewriteBlockWithTombstones(ctx, bkt, job, logger); err != nil {
+			c.blocksRewritesFailed.Inc()
+			return errors.Wrapf(err, "rewrite block %s", job.meta.ULID)
+		}
+		c.blocksRewritten.Inc()
+	}
+
+	// Re-sync the metas to include the rewritten blocks, and exclude the ones they replace.
+	if err := syncer.SyncMetas(ctx); err != nil {
+		return errors.Wrap(err, "sync after applying series deletion requests")
+	}
+
+	return c.markProcessedTombstones(ctx, userID, bkt, syncer.Metas(), ts, time.Now(), logger)
+}
+
+func (c *MultitenantCompactor) ownTombstonesJob(userID, action string, meta *metadata.Meta, logger log.Logger) bool {
+	shardingKey := fmt.Sprintf("%s-tombstones-%s-%s", userID, action, meta.ULID)
+	ok, err := c.shardingStrategy.ownJob(NewJob(userID, shardingKey, labels.FromMap(meta.Thanos.Labels), meta.Thanos.Downsample.Resolution, metadata.NoneFunc, false, 0, shardingKey))
+	if err != nil {
+		level.Warn(logger).Log("msg", "unable to check if applying series deletion requests to block is owned by this compactor", "block", meta.ULID, "err", err)
+		return false
+	}
+	return ok
+}
+
+// rewriteBlockWithTombstones downloads the block, rewrites it without the samples deleted by the job's series
+// deletion requests, uploads the result and marks the input block for deletion.
+func (c *MultitenantCompactor) rewriteBlockWithTombstones(ctx context.Context, bkt objstore.Bucket, job tombstonesRewriteJob, logger log.Logger) error {
+	begin := time.Now()
+	workDir := filepath.Join(c.compactorCfg.DataDir, "rewrite", job.meta.ULID.String())
+
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove block rewrite work directory", "path", workDir, "err", err)
+		}
+	}()
+
+	bdir := filepath.Join(workDir, job.meta.ULID.String())
+	if err := block.Download(ctx, logger, bkt, job.meta.ULID, bdir); err != nil {
+		return errors.Wrap(err, "download block")
+	}
+
+	deletedSeries, err := writeBlockTombstones(logger, bdir, job.meta, job.tombstones)
+	if err != nil {
+		return errors.Wrap(err, "apply series deletion requests")
+	}
+
+	id, err := c.blocksCompactor.Compact(workDir, []string{bdir}, nil)
+	if err != nil {
+		return errors.Wrap(err, "rewrite block")
+	}
+
+	if id == (ulid.ULID{}) {
+		// All the samples of the block have been deleted.
+		level.Info(logger).Log("msg", "rewritten block would have no samples, deleting block", "block", job.meta.ULID)
+		return deleteBlock(bkt, job.meta.ULID, bdir, logger, c.blocksMarkedForDeletion)
+	}
+
+	resdir := filepath.Join(workDir, id.String())
+	newMeta, err := metadata.InjectThanos(logger, resdir, metadata.Thanos{
+		Labels:       job.meta.Thanos.Labels,
+		Downsample:   job.meta.Thanos.Downsample,
+		Source:       metadata.CompactorSource,
+		SegmentFiles: block.GetSegmentFiles(resdir),
+		Rewrites:     tombstonesRewrites([]*metadata.Meta{job.meta}, job.tombstones),
+	}, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to finalize the block %s", resdir)
+	}
+
+	if err := os.Remove(filepath.Join(resdir, "tombstones")); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "remove tombstones")
+	}
+
+	if err := block.VerifyIndex(logger, filepath.Join(resdir, block.IndexFilename), newMeta.MinTime, newMeta.MaxTime); err != nil {
+		return errors.Wrapf(err, "invalid result block %s", id)
+	}
+
+	if err := mimir_tsdb.UploadBlock(ctx, logger, bkt, resdir, nil); err != nil {
+		return errors.Wrapf(err, "upload of %s failed", id)
+	}
+
+	if err := deleteBlock(bkt, job.meta.ULID, bdir, logger, c.blocksMarkedForDeletion); err != nil {
+		return err
+	}
+
+	elapsed := time.Since(begin)
+	level.Info(logger).Log("msg", "rewritten block with series deletion requests", "source_block", job.meta.ULID, "result_block", id, "series", deletedSeries, "duration", elapsed, "duration_ms", elapsed.Milliseconds())
+	return nil
+}
+
+// tombstonesProcessingDelay returns how long after the end of its time range a series deletion request can be
+// marked as processed. Ingesters keep the blocks they upload for the TSDB retention period, and blocks are uploaded
+// at most a block range after their samples are written, so an ingester may still return deleted samples until then.
+func (c *MultitenantCompactor) tombstonesProcessingDelay() time.Duration {
+	delay := c.storageCfg.TSDB.Retention
+	if ranges := c.storageCfg.TSDB.BlockRanges; len(ranges) > 0 {
+		delay += ranges[len(ranges)-1]
+	}
+	return delay
+}
+
+// deletedBlocksQueriedDelay returns how long after being marked for deletion a block may still be queried. Queriers
+// and store-gateways keep querying a block until its deletion mark is older than the ignore deletion marks delay, and
+// they've loaded a bucket index listing the deletion mark, which is updated by the compactor on each cleanup.
+func (c *MultitenantCompactor) deletedBlocksQueriedDelay() time.Duration {
+	return c.storageCfg.BucketStore.IgnoreDeletionMarksDelay + c.compactorCfg.CleanupInterval + c.storageCfg.BucketStore.SyncInterval
+}
+
+// markProcessedTombstones marks as processed the series deletion requests which have been applied to all the
+// overlapping blocks, including the blocks marked for no-compaction which are never rewritten, and the blocks
+// marked for deletion which may still be queried.
+func (c *MultitenantCompactor) markProcessedTombstones(ctx context.Context, userID string, bkt objstore.Bucket, metas map[ulid.ULID]*metadata.Meta, ts mimir_tsdb.Tombstones, now time.Time, logger log.Logger) error {
+	noCompactMetas, err := noCompactMarkedBlocksMetas(ctx, bkt, metas, logger)
+	if err != nil {
+		return err
+	}
+
+	// The blocks replaced by the rewritten blocks are still queried for a while after being marked for deletion, and
+	// the samples deleted from the rewritten blocks are only filtered out while the requests are pending.
+	queriedDeletedMetas, err := deletionMarkedBlocksMetas(ctx, bkt, now.Add(-c.deletedBlocksQueriedDelay()), logger)
+	if err != nil {
+		return err
+	}
+
+	cutoff := util.TimeToMillis(now.Add(-c.tombstonesProcessingDelay()))
+
+	for _, t := range ts {
+		if t.EndTime >= cutoff {
+			continue
+		}
+		if !isTombstoneAppliedToAllBlocks(t, metas) || !isTombstoneAppliedToAllBlocks(t, noCompactMetas) || !isTombstoneAppliedToAllBlocks(t, queriedDeletedMetas) {
+			continue
+		}
+
+		t.State = mimir_tsdb.TombstoneProcessed
+		t.StateCreatedAt = util.TimeToMillis(now)
+		if err := mimir_tsdb.WriteTombstone(ctx, c.bucketClient, userID, c.cfgProvider, t); err != nil {
+			return errors.Wrapf(err, "mark series deletion request %s as processed", t.RequestID)
+		}
+		level.Info(logger).Log("msg", "series deletion request has been applied to all blocks and marked as processed", "request_id", t.RequestID)
+	}
+
+	return nil
+}
+
+func isTombstoneAppliedToAllBlocks(t *mimir_tsdb.Tombstone, metas map[ulid.ULID]*metadata.Meta) bool {
+	for _, m := range metas {
+		if len(notAppliedTombstones(m, mimir_tsdb.Tombstones{t})) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// noCompactMarkedBlocksMetas returns the metas of the tenant blocks marked for no-compaction, which are
+// filtered out by the compaction metas fetcher.
+func noCompactMarkedBlocksMetas(ctx context.Context, bkt objstore.Bucket, synced map[ulid.ULID]*metadata.Meta, logger log.Logger) (map[ulid.ULID]*metadata.Meta, error) {
+	metas := map[ulid.ULID]*metadata.Meta{}
+
+	err := bkt.Iter(ctx, bucketindex.MarkersPathname+"/", func(name string) error {
+		id, ok := bucketindex.IsNoCompactMarkFilename(path.Base(name))
+		if !ok {
+			return nil
+		}
+		if _, ok := synced[id]; ok {
+			return nil
+		}
+
+		m, err := block.DownloadMeta(ctx, logger, bkt, id)
+		if bkt.IsObjNotFoundErr(errors.Cause(err)) {
+			// The block has been deleted in the meanwhile.
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "read meta of block %s marked for no-compaction", id)
+		}
+		metas[id] = &m
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list block no-compact marks")
+	}
+
+	return metas, nil
+}
+
+// deletionMarkedBlocksMetas returns the metas of the tenant blocks marked for deletion after the given time, which
+// are filtered out by the compaction metas fetcher.
+func deletionMarkedBlocksMetas(ctx context.Context, bkt objstore.Bucket, markedAfter time.Time, logger log.Logger) (map[ulid.ULID]*metadata.Meta, error) {
+	metas := map[ulid.ULID]*metadata.Meta{}
+
+	err := bkt.Iter(ctx, bucketindex.MarkersPathname+"/", func(name string) error {
+		id, ok := bucketindex.IsBlockDeletionMarkFilename(path.Base(name))
+		if !ok {
+			return nil
+		}
+
+		mark := metadata.DeletionMark{}
+		err := metadata.ReadMarker(ctx, logger, objstore.WithNoopInstr(bkt), id.String(), &mark)
+		if errors.Is(err, metadata.ErrorMarkerNotFound) {
+			// The block has been deleted in the meanwhile.
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "read deletion mark of block %s", id)
+		}
+		if time.Unix(mark.DeletionTime, 0).Before(markedAfter) {
+			return nil
+		}
+
+		m, err := block.DownloadMeta(ctx, logger, bkt, id)
+		if bkt.IsObjNotFoundErr(errors.Cause(err)) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "read meta of block %s marked for deletion", id)
+		}
+		metas[id] = &m
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list block deletion marks")
+	}
+
+	return metas, nil
+}
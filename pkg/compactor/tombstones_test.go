@@ -0,0 +1,344 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+func TestWriteBlockTombstones(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	logger := log.NewNopLogger()
+
+	// Creates series with series_id from 0 to 4, each one with a single sample.
+	blockID := createTSDBBlock(t, bkt, "", 0, 40, 5, nil)
+	bdir := filepath.Join(t.TempDir(), blockID.String())
+	require.NoError(t, block.Download(ctx, logger, bkt, blockID, bdir))
+
+	meta, err := metadata.ReadFromDir(bdir)
+	require.NoError(t, err)
+
+	outside, err := mimir_tsdb.NewTombstone(0, 100, 200, []string{`{series_id="0"}`})
+	require.NoError(t, err)
+
+	deleted, err := writeBlockTombstones(logger, bdir, meta, mimir_tsdb.Tombstones{outside})
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	inside, err := mimir_tsdb.NewTombstone(0, 0, 100, []string{`{series_id="1"}`, `{series_id=~"3|4"}`})
+	require.NoError(t, err)
+
+	deleted, err = writeBlockTombstones(logger, bdir, meta, mimir_tsdb.Tombstones{outside, inside})
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+
+	// The deleted series must not be returned when reading the block.
+	b, err := tsdb.OpenBlock(logger, bdir, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, b.Close()) })
+
+	q, err := tsdb.NewBlockQuerier(b, 0, 40)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, q.Close()) })
+
+	var actual []string
+	set := q.Select(true, nil, labels.MustNewMatcher(labels.MatchRegexp, "series_id", ".+"))
+	for set.Next() {
+		if set.At().Iterator().Next() {
+			actual = append(actual, set.At().Labels().Get("series_id"))
+		}
+	}
+	require.NoError(t, set.Err())
+	assert.Equal(t, []string{"0", "2"}, actual)
+}
+
+func TestMultitenantCompactor_LoadApplicableTombstones(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	now := time.Now()
+
+	old, err := mimir_tsdb.NewTombstone(now.Add(-2*time.Hour).UnixMilli(), 10, 20, []string{`{job="old"}`})
+	require.NoError(t, err)
+	recent, err := mimir_tsdb.NewTombstone(now.Add(-time.Minute).UnixMilli(), 10, 20, []string{`{job="recent"}`})
+	require.NoError(t, err)
+	cancelled, err := mimir_tsdb.NewTombstone(now.Add(-2*time.Hour).UnixMilli(), 10, 20, []string{`{job="cancelled"}`})
+	require.NoError(t, err)
+	cancelled.State = mimir_tsdb.TombstoneCancelled
+
+	for _, ts := range []*mimir_tsdb.Tombstone{old, recent, cancelled} {
+		require.NoError(t, mimir_tsdb.WriteTombstone(ctx, bkt, "user-1", nil, ts))
+	}
+
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.seriesDeletionDelay["user-1"] = time.Hour
+
+	c := &MultitenantCompactor{bucketClient: bkt, cfgProvider: cfgProvider}
+
+	actual, err := c.loadApplicableTombstones(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, mimir_tsdb.Tombstones{old}, actual)
+}
+
+func TestTombstonesRewrites(t *testing.T) {
+	t1, err := mimir_tsdb.NewTombstone(0, 10, 20, []string{`{job="1"}`})
+	require.NoError(t, err)
+	t2, err := mimir_tsdb.NewTombstone(0, 30, 40, []string{`{job="2"}`})
+	require.NoError(t, err)
+
+	assert.Nil(t, tombstonesRewrites([]*metadata.Meta{{}}, nil))
+
+	input := &metadata.Meta{Thanos: metadata.Thanos{Rewrites: tombstonesRewrites(nil, mimir_tsdb.Tombstones{t1})}}
+	assert.Equal(t, map[string]struct{}{t1.RequestID: {}}, appliedTombstones(input))
+
+	assert.Equal(t, []metadata.Rewrite{{DeletionsApplied: []metadata.DeletionRequest{
+		{RequestID: t1.RequestID, Intervals: tombstones.Intervals{{Mint: 10, Maxt: 20}}},
+		{RequestID: t2.RequestID, Intervals: tombstones.Intervals{{Mint: 30, Maxt: 40}}},
+	}}}, tombstonesRewrites([]*metadata.Meta{input, {}}, mimir_tsdb.Tombstones{t1, t2}))
+}
+
+func TestPlanTombstonesRewrites(t *testing.T) {
+	const largestRange = int64(24 * time.Hour / time.Millisecond)
+
+	now := time.Now()
+	old := util.TimeToMillis(now.Add(-7 * 24 * time.Hour))
+	recent := util.TimeToMillis(now.Add(-12 * time.Hour))
+
+	ts, err := mimir_tsdb.NewTombstone(0, old, recent, []string{`{job="test"}`})
+	require.NoError(t, err)
+	outside, err := mimir_tsdb.NewTombstone(0, 0, 10, []string{`{job="test"}`})
+	require.NoError(t, err)
+
+	newMeta := func(id ulid.ULID, minT, maxT, resolution int64, applied ...*mimir_tsdb.Tombstone) *metadata.Meta {
+		return &metadata.Meta{
+			BlockMeta: tsdb.BlockMeta{ULID: id, MinTime: minT, MaxTime: maxT},
+			Thanos: metadata.Thanos{
+				Downsample: metadata.ThanosDownsample{Resolution: resolution},
+				Rewrites:   tombstonesRewrites(nil, applied),
+			},
+		}
+	}
+
+	compacted := newMeta(ulid.MustNew(1, nil), old, old+largestRange, 0)
+	notCompacted := newMeta(ulid.MustNew(2, nil), recent-largestRange/12, recent, 0)
+	smallOld := newMeta(ulid.MustNew(3, nil), old+largestRange, old+largestRange+largestRange/12, 0)
+	applied := newMeta(ulid.MustNew(4, nil), old+2*largestRange, old+3*largestRange, 0, ts)
+	downsampled := newMeta(ulid.MustNew(5, nil), old, old+largestRange, downsample.ResLevel1)
+
+	metas := map[ulid.ULID]*metadata.Meta{}
+	for _, m := range []*metadata.Meta{compacted, notCompacted, smallOld, applied, downsampled} {
+		metas[m.ULID] = m
+	}
+
+	assert.Nil(t, planTombstonesRewrites(metas, nil, largestRange, now))
+	assert.Equal(t, []tombstonesRewriteJob{
+		{meta: compacted, tombstones: mimir_tsdb.Tombstones{ts}},
+		{meta: smallOld, tombstones: mimir_tsdb.Tombstones{ts}},
+	}, planTombstonesRewrites(metas, mimir_tsdb.Tombstones{ts, outside}, largestRange, now))
+}
+
+func TestPlanOutdatedDownsampledBlocks(t *testing.T) {
+	const hour = int64(time.Hour / time.Millisecond)
+
+	ts, err := mimir_tsdb.NewTombstone(0, 0, 100*hour, []string{`{job="test"}`})
+	require.NoError(t, err)
+
+	newMeta := func(id ulid.ULID, minT, resolution int64, source ulid.ULID, applied ...*mimir_tsdb.Tombstone) *metadata.Meta {
+		m := &metadata.Meta{
+			BlockMeta: tsdb.BlockMeta{ULID: id, MinTime: minT, MaxTime: minT + 24*hour},
+			Thanos: metadata.Thanos{
+				Downsample: metadata.ThanosDownsample{Resolution: resolution},
+				Rewrites:   tombstonesRewrites(nil, applied),
+			},
+		}
+		m.Compaction.Sources = []ulid.ULID{source}
+		return m
+	}
+
+	source1, source2, source3 := ulid.MustNew(1, nil), ulid.MustNew(2, nil), ulid.MustNew(3, nil)
+
+	// The raw block has been rewritten, so the downsampled block is outdated.
+	rewritten := newMeta(ulid.MustNew(10, nil), 0, 0, source1, ts)
+	outdated := newMeta(ulid.MustNew(11, nil), 0, downsample.ResLevel1, source1)
+	// The raw block hasn't been rewritten yet.
+	notRewritten := newMeta(ulid.MustNew(12, nil), 24*hour, 0, source2)
+	waiting := newMeta(ulid.MustNew(13, nil), 24*hour, downsample.ResLevel1, source2)
+	// The downsampled block has been built from the rewritten raw block.
+	upToDate := newMeta(ulid.MustNew(14, nil), 48*hour, downsample.ResLevel1, source3, ts)
+	upToDateSource := newMeta(ulid.MustNew(15, nil), 48*hour, 0, source3, ts)
+
+	metas := map[ulid.ULID]*metadata.Meta{}
+	for _, m := range []*metadata.Meta{rewritten, outdated, notRewritten, waiting, upToDate, upToDateSource} {
+		metas[m.ULID] = m
+	}
+
+	assert.Nil(t, planOutdatedDownsampledBlocks(metas, nil))
+	assert.Equal(t, []*metadata.Meta{outdated}, planOutdatedDownsampledBlocks(metas, mimir_tsdb.Tombstones{ts}))
+}
+
+func TestMultitenantCompactor_RewriteBlockWithTombstones(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	bkt := objstore.NewInMemBucket()
+	userBkt := bucket.NewUserBucketClient(userID, bkt, nil)
+
+	// Creates series with series_id from 0 to 4, each one with a single sample.
+	blockID := createTSDBBlock(t, bkt, userID, 0, 40, 5, map[string]string{"ext": "1"})
+	meta, err := block.DownloadMeta(ctx, logger, userBkt, blockID)
+	require.NoError(t, err)
+
+	ts, err := mimir_tsdb.NewTombstone(0, 0, 100, []string{`{series_id=~"1|3"}`})
+	require.NoError(t, err)
+
+	c, _, _, _, _ := prepareWithConfigProvider(t, prepareConfig(t), bkt, newMockConfigProvider())
+	c.blocksCompactor, err = tsdb.NewLeveledCompactor(ctx, nil, logger, []int64{40}, downsample.NewPool(), nil, true)
+	require.NoError(t, err)
+
+	require.NoError(t, c.rewriteBlockWithTombstones(ctx, userBkt, tombstonesRewriteJob{meta: &meta, tombstones: mimir_tsdb.Tombstones{ts}}, logger))
+
+	// The input block must be marked for deletion.
+	exists, err := userBkt.Exists(ctx, path.Join(blockID.String(), metadata.DeletionMarkFilename))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	var rewritten []metadata.Meta
+	require.NoError(t, userBkt.Iter(ctx, "", func(name string) error {
+		id, ok := block.IsBlockDir(name)
+		if !ok || id == blockID {
+			return nil
+		}
+
+		m, err := block.DownloadMeta(ctx, logger, userBkt, id)
+		if err != nil {
+			return err
+		}
+		rewritten = append(rewritten, m)
+		return nil
+	}))
+
+	require.Len(t, rewritten, 1)
+	assert.Equal(t, meta.MinTime, rewritten[0].MinTime)
+	assert.Equal(t, meta.MaxTime, rewritten[0].MaxTime)
+	assert.Equal(t, uint64(3), rewritten[0].Stats.NumSeries)
+	assert.Equal(t, map[string]string{"ext": "1"}, rewritten[0].Thanos.Labels)
+	assert.Equal(t, map[string]struct{}{ts.RequestID: {}}, appliedTombstones(&rewritten[0]))
+}
+
+func TestMultitenantCompactor_MarkProcessedTombstones(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+	bkt := objstore.NewInMemBucket()
+	userBkt := bucket.NewUserBucketClient(userID, bkt, nil)
+	now := time.Now()
+
+	old := util.TimeToMillis(now.Add(-7 * 24 * time.Hour))
+
+	applied, err := mimir_tsdb.NewTombstone(0, old, old+10, []string{`{job="applied"}`})
+	require.NoError(t, err)
+	notApplied, err := mimir_tsdb.NewTombstone(0, old, old+10, []string{`{job="not-applied"}`})
+	require.NoError(t, err)
+	recent, err := mimir_tsdb.NewTombstone(0, util.TimeToMillis(now.Add(-time.Hour)), util.TimeToMillis(now), []string{`{job="recent"}`})
+	require.NoError(t, err)
+	notAppliedToNoCompactBlock, err := mimir_tsdb.NewTombstone(0, old+100, old+110, []string{`{job="no-compact"}`})
+	require.NoError(t, err)
+
+	notAppliedToDeletedBlock, err := mimir_tsdb.NewTombstone(0, old+300, old+310, []string{`{job="deleted"}`})
+	require.NoError(t, err)
+
+	all := mimir_tsdb.Tombstones{applied, notApplied, recent, notAppliedToNoCompactBlock, notAppliedToDeletedBlock}
+	for _, ts := range all {
+		require.NoError(t, mimir_tsdb.WriteTombstone(ctx, bkt, userID, nil, ts))
+	}
+
+	blockID := ulid.MustNew(1, nil)
+	metas := map[ulid.ULID]*metadata.Meta{
+		blockID: {
+			BlockMeta: tsdb.BlockMeta{ULID: blockID, MinTime: old, MaxTime: old + 50},
+			Thanos:    metadata.Thanos{Rewrites: tombstonesRewrites(nil, mimir_tsdb.Tombstones{applied, notAppliedToNoCompactBlock, notAppliedToDeletedBlock})},
+		},
+	}
+
+	// A block marked for no-compaction isn't synced by the compactor, but must be taken into account.
+	noCompactBlockID := ulid.MustNew(2, nil)
+	noCompactMeta := metadata.Meta{BlockMeta: tsdb.BlockMeta{ULID: noCompactBlockID, MinTime: old + 100, MaxTime: old + 200, Version: metadata.TSDBVersion1}}
+	var buf bytes.Buffer
+	require.NoError(t, noCompactMeta.Write(&buf))
+	require.NoError(t, userBkt.Upload(ctx, path.Join(noCompactBlockID.String(), metadata.MetaFilename), &buf))
+	require.NoError(t, userBkt.Upload(ctx, path.Join("markers", noCompactBlockID.String()+"-no-compact-mark.json"), strings.NewReader("{}")))
+
+	// The blocks marked for deletion aren't synced by the compactor either, but are still queried until their deletion
+	// mark is old enough. The block recently marked for deletion is the source of a rewritten block.
+	c, _, _, _, _ := prepareWithConfigProvider(t, prepareConfig(t), bkt, newMockConfigProvider())
+	c.bucketClient = bkt
+
+	for id, markedAt := range map[ulid.ULID]time.Time{
+		ulid.MustNew(3, nil): now.Add(-time.Minute),
+		ulid.MustNew(4, nil): now.Add(-c.deletedBlocksQueriedDelay() - time.Minute),
+	} {
+		deletedMeta := metadata.Meta{BlockMeta: tsdb.BlockMeta{ULID: id, MinTime: old + 300, MaxTime: old + 400, Version: metadata.TSDBVersion1}}
+		buf.Reset()
+		require.NoError(t, deletedMeta.Write(&buf))
+		require.NoError(t, userBkt.Upload(ctx, path.Join(id.String(), metadata.MetaFilename), &buf))
+
+		mark, err := json.Marshal(metadata.DeletionMark{ID: id, Version: metadata.DeletionMarkVersion1, DeletionTime: markedAt.Unix()})
+		require.NoError(t, err)
+		require.NoError(t, userBkt.Upload(ctx, path.Join(id.String(), metadata.DeletionMarkFilename), bytes.NewReader(mark)))
+		require.NoError(t, userBkt.Upload(ctx, bucketindex.BlockDeletionMarkFilepath(id), bytes.NewReader(mark)))
+	}
+
+	require.NoError(t, c.markProcessedTombstones(ctx, userID, userBkt, metas, all, now, logger))
+
+	states := map[string]mimir_tsdb.TombstoneState{}
+	stored, err := mimir_tsdb.ReadTombstones(ctx, bkt, userID)
+	require.NoError(t, err)
+	for _, ts := range stored {
+		states[ts.RequestID] = ts.State
+	}
+
+	assert.Equal(t, map[string]mimir_tsdb.TombstoneState{
+		applied.RequestID:                    mimir_tsdb.TombstoneProcessed,
+		notApplied.RequestID:                 mimir_tsdb.TombstonePending,
+		recent.RequestID:                     mimir_tsdb.TombstonePending,
+		notAppliedToNoCompactBlock.RequestID: mimir_tsdb.TombstonePending,
+		notAppliedToDeletedBlock.RequestID:   mimir_tsdb.TombstonePending,
+	}, states)
+
+	// Once the deletion mark is old enough, the request is marked as processed.
+	require.NoError(t, c.markProcessedTombstones(ctx, userID, userBkt, metas, all, now.Add(2*time.Minute+c.deletedBlocksQueriedDelay()), logger))
+
+	stored, err = mimir_tsdb.ReadTombstones(ctx, bkt, userID)
+	require.NoError(t, err)
+	for _, ts := range stored {
+		if ts.RequestID == notAppliedToDeletedBlock.RequestID {
+			assert.Equal(t, mimir_tsdb.TombstoneProcessed, ts.State)
+		}
+	}
+}
@@ -40,6 +40,22 @@ type MimirClient interface {
 
 	// Query performs an instant query.
 	Query(ctx context.Context, query string, ts time.Time, options ...RequestOption) (model.Vector, error)
+
+	// WriteMetadata writes input metric metadata to Mimir. Returns the response status code and optionally
+	// an error. The error is always returned if request was not successful (eg. received a 4xx or 5xx error).
+	WriteMetadata(ctx context.Context, metadata []prompb.MetricMetadata) (statusCode int, err error)
+
+	// QueryExemplars queries the exemplars of the series matching the query.
+	QueryExemplars(ctx context.Context, query string, start, end time.Time) ([]v1.ExemplarQueryResult, error)
+
+	// LabelNames returns the label names of the series matching the matchers.
+	LabelNames(ctx context.Context, matchers []string, start, end time.Time) ([]string, error)
+
+	// LabelValues returns the values of a label of the series matching the matchers.
+	LabelValues(ctx context.Context, label string, matchers []string, start, end time.Time) (model.LabelValues, error)
+
+	// Metadata returns the metadata of a metric.
+	Metadata(ctx context.Context, metric string) (map[string][]v1.Metadata, error)
 }
 
 type ClientConfig struct {
@@ -153,6 +169,40 @@ func (c *Client) Query(ctx context.Context, query string, ts time.Time, options
 	return vector, nil
 }
 
+// QueryExemplars implements MimirClient.
+func (c *Client) QueryExemplars(ctx context.Context, query string, start, end time.Time) ([]v1.ExemplarQueryResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.ReadTimeout)
+	defer cancel()
+
+	return c.readClient.QueryExemplars(ctx, query, start, end)
+}
+
+// LabelNames implements MimirClient.
+func (c *Client) LabelNames(ctx context.Context, matchers []string, start, end time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.ReadTimeout)
+	defer cancel()
+
+	names, _, err := c.readClient.LabelNames(ctx, matchers, start, end)
+	return names, err
+}
+
+// LabelValues implements MimirClient.
+func (c *Client) LabelValues(ctx context.Context, label string, matchers []string, start, end time.Time) (model.LabelValues, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.ReadTimeout)
+	defer cancel()
+
+	values, _, err := c.readClient.LabelValues(ctx, label, matchers, start, end)
+	return values, err
+}
+
+// Metadata implements MimirClient.
+func (c *Client) Metadata(ctx context.Context, metric string) (map[string][]v1.Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.ReadTimeout)
+	defer cancel()
+
+	return c.readClient.Metadata(ctx, metric, "")
+}
+
 // WriteSeries implements MimirClient.
 func (c *Client) WriteSeries(ctx context.Context, series []prompb.TimeSeries) (int, error) {
 	lastStatusCode := 0
@@ -173,6 +223,11 @@ func (c *Client) WriteSeries(ctx context.Context, series []prompb.TimeSeries) (i
 	return lastStatusCode, nil
 }
 
+// WriteMetadata implements MimirClient.
+func (c *Client) WriteMetadata(ctx context.Context, metadata []prompb.MetricMetadata) (int, error) {
+	return c.sendWriteRequest(ctx, &prompb.WriteRequest{Metadata: metadata})
+}
+
 func (c *Client) sendWriteRequest(ctx context.Context, req *prompb.WriteRequest) (int, error) {
 	data, err := proto.Marshal(req)
 	if err != nil {
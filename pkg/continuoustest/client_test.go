@@ -14,6 +14,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
 	"github.com/grafana/dskit/flagext"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
@@ -106,6 +107,101 @@ func TestClient_WriteSeries(t *testing.T) {
 	})
 }
 
+func TestClient_WriteMetadata(t *testing.T) {
+	var receivedRequests []prompb.WriteRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, err := ioutil.ReadAll(request.Body)
+		require.NoError(t, err)
+		require.NoError(t, request.Body.Close())
+
+		body, err = snappy.Decode(nil, body)
+		require.NoError(t, err)
+
+		var req prompb.WriteRequest
+		require.NoError(t, proto.Unmarshal(body, &req))
+		receivedRequests = append(receivedRequests, req)
+
+		writer.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := ClientConfig{}
+	flagext.DefaultValues(&cfg)
+	require.NoError(t, cfg.WriteBaseEndpoint.Set(server.URL))
+	require.NoError(t, cfg.ReadBaseEndpoint.Set(server.URL))
+
+	c, err := NewClient(cfg, log.NewNopLogger())
+	require.NoError(t, err)
+
+	metadata := generateMetadata()
+	statusCode, err := c.WriteMetadata(context.Background(), metadata)
+	require.NoError(t, err)
+	assert.Equal(t, 200, statusCode)
+
+	require.Len(t, receivedRequests, 1)
+	assert.Equal(t, metadata, receivedRequests[0].Metadata)
+	assert.Empty(t, receivedRequests[0].Timeseries)
+}
+
+func TestClient_LabelsAndMetadata(t *testing.T) {
+	var receivedPaths []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		receivedPaths = append(receivedPaths, request.URL.Path)
+		assert.Equal(t, "anonymous", request.Header.Get("X-Scope-OrgID"))
+
+		var body string
+		switch request.URL.Path {
+		case "/api/v1/labels":
+			body = `{"status":"success","data":["__name__","series_id"]}`
+		case "/api/v1/label/series_id/values":
+			body = `{"status":"success","data":["0","1"]}`
+		case "/api/v1/metadata":
+			body = `{"status":"success","data":{"up":[{"type":"gauge","help":"Up.","unit":""}]}}`
+		case "/api/v1/query_exemplars":
+			body = `{"status":"success","data":[{"seriesLabels":{"__name__":"up"},"exemplars":[{"labels":{"trace_id":"1"},"value":"1","timestamp":1}]}]}`
+		}
+
+		writer.WriteHeader(http.StatusOK)
+		_, err := writer.Write([]byte(body))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := ClientConfig{}
+	flagext.DefaultValues(&cfg)
+	require.NoError(t, cfg.WriteBaseEndpoint.Set(server.URL))
+	require.NoError(t, cfg.ReadBaseEndpoint.Set(server.URL))
+
+	c, err := NewClient(cfg, log.NewNopLogger())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	start, end := time.Unix(0, 0), time.Unix(1000, 0)
+
+	names, err := c.LabelNames(ctx, []string{"up"}, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"__name__", "series_id"}, names)
+
+	values, err := c.LabelValues(ctx, "series_id", []string{"up"}, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, model.LabelValues{"0", "1"}, values)
+
+	metadata, err := c.Metadata(ctx, "up")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]v1.Metadata{"up": {{Type: v1.MetricTypeGauge, Help: "Up."}}}, metadata)
+
+	exemplars, err := c.QueryExemplars(ctx, "up", start, end)
+	require.NoError(t, err)
+	assert.Equal(t, []v1.ExemplarQueryResult{{
+		SeriesLabels: model.LabelSet{"__name__": "up"},
+		Exemplars:    []v1.Exemplar{{Labels: model.LabelSet{"trace_id": "1"}, Value: 1, Timestamp: 1000}},
+	}}, exemplars)
+
+	assert.Equal(t, []string{"/api/v1/labels", "/api/v1/label/series_id/values", "/api/v1/metadata", "/api/v1/query_exemplars"}, receivedPaths)
+}
+
 func TestClient_QueryRange(t *testing.T) {
 	var (
 		receivedRequests []*http.Request
@@ -215,3 +311,28 @@ func (m *ClientMock) Query(ctx context.Context, query string, ts time.Time, opti
 	args := m.Called(ctx, query, ts, options)
 	return args.Get(0).(model.Vector), args.Error(1)
 }
+
+func (m *ClientMock) WriteMetadata(ctx context.Context, metadata []prompb.MetricMetadata) (int, error) {
+	args := m.Called(ctx, metadata)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *ClientMock) QueryExemplars(ctx context.Context, query string, start, end time.Time) ([]v1.ExemplarQueryResult, error) {
+	args := m.Called(ctx, query, start, end)
+	return args.Get(0).([]v1.ExemplarQueryResult), args.Error(1)
+}
+
+func (m *ClientMock) LabelNames(ctx context.Context, matchers []string, start, end time.Time) ([]string, error) {
+	args := m.Called(ctx, matchers, start, end)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *ClientMock) LabelValues(ctx context.Context, label string, matchers []string, start, end time.Time) (model.LabelValues, error) {
+	args := m.Called(ctx, label, matchers, start, end)
+	return args.Get(0).(model.LabelValues), args.Error(1)
+}
+
+func (m *ClientMock) Metadata(ctx context.Context, metric string) (map[string][]v1.Metadata, error) {
+	args := m.Called(ctx, metric)
+	return args.Get(0).(map[string][]v1.Metadata), args.Error(1)
+}
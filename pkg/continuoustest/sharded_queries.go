@@ -0,0 +1,317 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/multierror"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+const (
+	shardedQueriesMetricName = "mimir_continuous_test_sharded_queries"
+	shardedQueriesNumGroups  = 5
+)
+
+// shardedQuery is a query run by the ShardedQueriesTest, with the function computing its expected
+// result from the written series.
+type shardedQuery struct {
+	query string
+
+	// groupBy is the label the query aggregates by, or empty if the query aggregates all series.
+	groupBy string
+
+	// aggregate computes the expected result from the values of the series of a group.
+	aggregate func(values []float64) float64
+}
+
+var shardedQueries = []shardedQuery{
+	{
+		query:     fmt.Sprintf("sum(max_over_time(%s[1s]))", shardedQueriesMetricName),
+		aggregate: sumValues,
+	}, {
+		query:     fmt.Sprintf("count(max_over_time(%s[1s]))", shardedQueriesMetricName),
+		aggregate: func(values []float64) float64 { return float64(len(values)) },
+	}, {
+		query:     fmt.Sprintf("max(max_over_time(%s[1s]))", shardedQueriesMetricName),
+		aggregate: maxValue,
+	}, {
+		query:     fmt.Sprintf("avg by (group) (max_over_time(%s[1s]))", shardedQueriesMetricName),
+		groupBy:   "group",
+		aggregate: func(values []float64) float64 { return sumValues(values) / float64(len(values)) },
+	}, {
+		query:     fmt.Sprintf("min by (group) (max_over_time(%s[1s]))", shardedQueriesMetricName),
+		groupBy:   "group",
+		aggregate: minValue,
+	},
+}
+
+type ShardedQueriesTestConfig struct {
+	Enabled     bool
+	NumSeries   int
+	MaxQueryAge time.Duration
+}
+
+func (cfg *ShardedQueriesTestConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "tests.sharded-queries-test.enabled", false, "Run the test writing series and checking the results of queries which can be sharded and split by the query-frontend against the results computed locally.")
+	f.IntVar(&cfg.NumSeries, "tests.sharded-queries-test.num-series", 1000, "Number of series used for the test.")
+	f.DurationVar(&cfg.MaxQueryAge, "tests.sharded-queries-test.max-query-age", 25*time.Hour, "How back in the past metrics can be queried at most. It should be greater than the query-frontend split interval, so that the range queries are split.")
+}
+
+// ShardedQueriesTest writes series with different values, and checks the results of aggregation queries,
+// which the query-frontend can shard and split by time, match the results computed locally from the written series.
+type ShardedQueriesTest struct {
+	name    string
+	cfg     ShardedQueriesTestConfig
+	client  MimirClient
+	logger  log.Logger
+	metrics *TestMetrics
+
+	tracker writeTracker
+}
+
+func NewShardedQueriesTest(cfg ShardedQueriesTestConfig, client MimirClient, logger log.Logger, reg prometheus.Registerer) *ShardedQueriesTest {
+	const name = "sharded-queries"
+
+	return &ShardedQueriesTest{
+		name:    name,
+		cfg:     cfg,
+		client:  client,
+		logger:  log.With(logger, "test", name),
+		metrics: NewTestMetrics(name, reg),
+	}
+}
+
+// Name implements Test.
+func (t *ShardedQueriesTest) Name() string {
+	return t.name
+}
+
+// Init implements Test.
+func (t *ShardedQueriesTest) Init(ctx context.Context, now time.Time) error {
+	return nil
+}
+
+// Run implements Test.
+func (t *ShardedQueriesTest) Run(ctx context.Context, now time.Time) error {
+	sp, ctx := spanlogger.NewWithLogger(ctx, t.logger, "ShardedQueriesTest.Run")
+	defer sp.Finish()
+
+	errs := new(multierror.MultiError)
+	errs.Add(writeUntilNow(ctx, now, &t.tracker, t.metrics, sp, func(ctx context.Context, timestamp time.Time) (int, error) {
+		return t.client.WriteSeries(ctx, generateShardedQueriesSeries(timestamp, t.cfg.NumSeries))
+	}))
+
+	start, end, ok := t.tracker.queryTimeRange(now, t.cfg.MaxQueryAge)
+	if !ok {
+		level.Info(sp).Log("msg", "Skipped queries because there's no valid time range to query")
+		errs.Add(errors.New("no valid time range to query"))
+		return errs.Err()
+	}
+
+	// Query the whole time range, which is split by the query-frontend if longer than the split interval,
+	// and the last hour. The results cache is disabled to check the results computed by the queriers.
+	ranges := [][2]time.Time{{start, end}}
+	if lastHour := maxTime(start, alignTimestampToInterval(end.Add(-time.Hour), writeInterval)); lastHour.After(start) {
+		ranges = append(ranges, [2]time.Time{lastHour, end})
+	}
+
+	for _, q := range shardedQueries {
+		for _, r := range ranges {
+			errs.Add(t.runRangeQueryAndVerifyResult(ctx, sp, q, r[0], r[1]))
+		}
+		errs.Add(t.runInstantQueryAndVerifyResult(ctx, sp, q, end))
+	}
+	return errs.Err()
+}
+
+func (t *ShardedQueriesTest) runRangeQueryAndVerifyResult(ctx context.Context, logger log.Logger, q shardedQuery, start, end time.Time) error {
+	step := getQueryStep(start, end, writeInterval)
+
+	logger = log.With(logger, "query", q.query, "start", start.UnixMilli(), "end", end.UnixMilli(), "step", step)
+	level.Debug(logger).Log("msg", "Running range query")
+
+	t.metrics.queriesTotal.Inc()
+	matrix, err := t.client.QueryRange(ctx, q.query, start, end, step, WithResultsCacheEnabled(false))
+	if err != nil {
+		t.metrics.queriesFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Failed to execute range query", "err", err)
+		return errors.Wrap(err, "failed to execute range query")
+	}
+
+	t.metrics.queryResultChecksTotal.Inc()
+	if err := verifyShardedQueryResult(matrix, q, t.cfg.NumSeries, start, end, step); err != nil {
+		t.metrics.queryResultChecksFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Range query result check failed", "err", err)
+		return errors.Wrapf(err, "range query %s result check failed", q.query)
+	}
+	return nil
+}
+
+func (t *ShardedQueriesTest) runInstantQueryAndVerifyResult(ctx context.Context, logger log.Logger, q shardedQuery, ts time.Time) error {
+	logger = log.With(logger, "query", q.query, "ts", ts.UnixMilli())
+	level.Debug(logger).Log("msg", "Running instant query")
+
+	t.metrics.queriesTotal.Inc()
+	vector, err := t.client.Query(ctx, q.query, ts, WithResultsCacheEnabled(false))
+	if err != nil {
+		t.metrics.queriesFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Failed to execute instant query", "err", err)
+		return errors.Wrap(err, "failed to execute instant query")
+	}
+
+	// Convert the vector to matrix to reuse the same results comparison utility.
+	matrix := make(model.Matrix, 0, len(vector))
+	for _, entry := range vector {
+		matrix = append(matrix, &model.SampleStream{
+			Metric: entry.Metric,
+			Values: []model.SamplePair{{
+				Timestamp: entry.Timestamp,
+				Value:     entry.Value,
+			}},
+		})
+	}
+
+	t.metrics.queryResultChecksTotal.Inc()
+	if err := verifyShardedQueryResult(matrix, q, t.cfg.NumSeries, ts, ts, writeInterval); err != nil {
+		t.metrics.queryResultChecksFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Instant query result check failed", "err", err)
+		return errors.Wrapf(err, "instant query %s result check failed", q.query)
+	}
+	return nil
+}
+
+// generateShardedQueriesSeries returns numSeries series whose values are the sine wave value at timestamp t plus
+// the series ID, so that each series has a different value. The series are spread across shardedQueriesNumGroups groups.
+func generateShardedQueriesSeries(t time.Time, numSeries int) []prompb.TimeSeries {
+	out := make([]prompb.TimeSeries, 0, numSeries)
+
+	for i := 0; i < numSeries; i++ {
+		out = append(out, prompb.TimeSeries{
+			Labels: []prompb.Label{{
+				Name:  "__name__",
+				Value: shardedQueriesMetricName,
+			}, {
+				Name:  "group",
+				Value: shardedQueriesGroup(i),
+			}, {
+				Name:  "series_id",
+				Value: strconv.Itoa(i),
+			}},
+			Samples: []prompb.Sample{{
+				Value:     shardedQueriesSeriesValue(t, i),
+				Timestamp: t.UnixMilli(),
+			}},
+		})
+	}
+
+	return out
+}
+
+func shardedQueriesSeriesValue(t time.Time, seriesID int) float64 {
+	return generateSineWaveValue(t) + float64(seriesID)
+}
+
+func shardedQueriesGroup(seriesID int) string {
+	return "group-" + strconv.Itoa(seriesID%shardedQueriesNumGroups)
+}
+
+// expectedShardedQueryResult computes the expected result of the query at timestamp t, by group.
+func expectedShardedQueryResult(q shardedQuery, t time.Time, numSeries int) map[string]float64 {
+	values := map[string][]float64{}
+	for i := 0; i < numSeries; i++ {
+		group := ""
+		if q.groupBy != "" {
+			group = shardedQueriesGroup(i)
+		}
+		values[group] = append(values[group], shardedQueriesSeriesValue(t, i))
+	}
+
+	expected := make(map[string]float64, len(values))
+	for group, v := range values {
+		expected[group] = q.aggregate(v)
+	}
+	return expected
+}
+
+// verifyShardedQueryResult checks the query results match the results computed locally from the written series,
+// with a sample for each step from start to end.
+func verifyShardedQueryResult(matrix model.Matrix, q shardedQuery, numSeries int, start, end time.Time, step time.Duration) error {
+	expectedSeries := 1
+	if q.groupBy != "" {
+		expectedSeries = shardedQueriesNumGroups
+		if numSeries < expectedSeries {
+			expectedSeries = numSeries
+		}
+	}
+	if len(matrix) != expectedSeries {
+		return fmt.Errorf("expected %d series in the result but got %d", expectedSeries, len(matrix))
+	}
+
+	expectedSamples := int(end.Sub(start)/step) + 1
+	expected := map[model.Time]map[string]float64{}
+
+	for _, stream := range matrix {
+		group := string(stream.Metric[model.LabelName(q.groupBy)])
+		if len(stream.Values) != expectedSamples {
+			return fmt.Errorf("expected %d samples for series %s but got %d", expectedSamples, stream.Metric, len(stream.Values))
+		}
+
+		for idx, sample := range stream.Values {
+			ts := start.Add(time.Duration(idx) * step)
+			if !sample.Timestamp.Time().Equal(ts) {
+				return fmt.Errorf("sample of series %s at timestamp %d (%s) was expected to have timestamp %d (%s)", stream.Metric, sample.Timestamp, sample.Timestamp.Time().UTC().String(), ts.UnixMilli(), ts.UTC().String())
+			}
+
+			if _, ok := expected[sample.Timestamp]; !ok {
+				expected[sample.Timestamp] = expectedShardedQueryResult(q, ts, numSeries)
+			}
+			expectedValue, ok := expected[sample.Timestamp][group]
+			if !ok {
+				return fmt.Errorf("unexpected series %s in the result", stream.Metric)
+			}
+			if !compareSampleValues(float64(sample.Value), expectedValue) {
+				return fmt.Errorf("sample of series %s at timestamp %d (%s) has value %f while was expecting %f", stream.Metric, sample.Timestamp, ts.UTC().String(), sample.Value, expectedValue)
+			}
+		}
+	}
+
+	return nil
+}
+
+func sumValues(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+func maxValue(values []float64) float64 {
+	max := math.Inf(-1)
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	return max
+}
+
+func minValue(values []float64) float64 {
+	min := math.Inf(1)
+	for _, v := range values {
+		min = math.Min(min, v)
+	}
+	return min
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedQueriesTest_Run(t *testing.T) {
+	logger := log.NewNopLogger()
+	cfg := ShardedQueriesTestConfig{Enabled: true, NumSeries: 7, MaxQueryAge: 25 * time.Hour}
+
+	client := &ClientMock{}
+	client.On("WriteSeries", mock.Anything, mock.Anything).Return(200, nil)
+	client.On("QueryRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(model.Matrix{}, nil)
+	client.On("Query", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(model.Vector{}, nil)
+
+	test := NewShardedQueriesTest(cfg, client, logger, prometheus.NewPedanticRegistry())
+	test.tracker.lastWrittenTimestamp = time.Unix(940, 0)
+
+	// The query results are checked by TestVerifyShardedQueryResult.
+	require.Error(t, test.Run(context.Background(), time.Unix(1000, 0)))
+
+	client.AssertNumberOfCalls(t, "WriteSeries", 3)
+	client.AssertCalled(t, "WriteSeries", mock.Anything, generateShardedQueriesSeries(time.Unix(960, 0), 7))
+
+	// The time range is shorter than 1h, so each query is run once as range query and once as instant query.
+	client.AssertNumberOfCalls(t, "QueryRange", len(shardedQueries))
+	client.AssertNumberOfCalls(t, "Query", len(shardedQueries))
+	for _, q := range shardedQueries {
+		client.AssertCalled(t, "QueryRange", mock.Anything, q.query, time.Unix(960, 0), time.Unix(1000, 0), writeInterval, mock.Anything)
+		client.AssertCalled(t, "Query", mock.Anything, q.query, time.Unix(1000, 0), mock.Anything)
+	}
+}
+
+func TestGenerateShardedQueriesSeries(t *testing.T) {
+	ts := time.Unix(1000, 0)
+	series := generateShardedQueriesSeries(ts, 7)
+	require.Len(t, series, 7)
+
+	assert.Equal(t, "group-1", series[6].Labels[1].Value)
+	assert.Equal(t, "6", series[6].Labels[2].Value)
+	assert.Equal(t, generateSineWaveValue(ts)+6, series[6].Samples[0].Value)
+}
+
+func TestVerifyShardedQueryResult(t *testing.T) {
+	start, end := time.Unix(960, 0), time.Unix(1000, 0)
+	sine := func(ts time.Time) float64 { return generateSineWaveValue(ts) }
+
+	sum := shardedQueries[0]
+	minByGroup := shardedQueries[4]
+	require.Equal(t, "min by (group) (max_over_time(mimir_continuous_test_sharded_queries[1s]))", minByGroup.query)
+
+	// With 7 series, the sum is 7 times the sine wave value plus the sum of the series IDs.
+	sumMatrix := model.Matrix{{Values: []model.SamplePair{
+		newSamplePair(time.Unix(960, 0), 7*sine(time.Unix(960, 0))+21),
+		newSamplePair(time.Unix(980, 0), 7*sine(time.Unix(980, 0))+21),
+		newSamplePair(time.Unix(1000, 0), 7*sine(time.Unix(1000, 0))+21),
+	}}}
+	assert.NoError(t, verifyShardedQueryResult(sumMatrix, sum, 7, start, end, writeInterval))
+
+	// The min of each group is the value of the series with the lowest ID in the group.
+	var minMatrix model.Matrix
+	for i := 0; i < shardedQueriesNumGroups; i++ {
+		minMatrix = append(minMatrix, &model.SampleStream{
+			Metric: model.Metric{"group": model.LabelValue(shardedQueriesGroup(i))},
+			Values: []model.SamplePair{newSamplePair(time.Unix(1000, 0), sine(time.Unix(1000, 0))+float64(i))},
+		})
+	}
+	assert.NoError(t, verifyShardedQueryResult(minMatrix, minByGroup, 7, end, end, writeInterval))
+
+	assert.EqualError(t, verifyShardedQueryResult(minMatrix[1:], minByGroup, 7, end, end, writeInterval), "expected 5 series in the result but got 4")
+
+	sumMatrix[0].Values[1].Value++
+	assert.EqualError(t, verifyShardedQueryResult(sumMatrix, sum, 7, start, end, writeInterval),
+		"sample of series {} at timestamp 980000 ("+time.Unix(980, 0).UTC().String()+") has value 16.797986 while was expecting 15.797986")
+
+	sumMatrix[0].Values = sumMatrix[0].Values[1:]
+	assert.EqualError(t, verifyShardedQueryResult(sumMatrix, sum, 7, start, end, writeInterval), "expected 3 samples for series {} but got 2")
+}
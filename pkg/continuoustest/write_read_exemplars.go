@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/multierror"
+	"github.com/pkg/errors"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+const (
+	exemplarsMetricName = "mimir_continuous_test_exemplars"
+	exemplarsTraceLabel = "trace_id"
+)
+
+type WriteReadExemplarsTestConfig struct {
+	Enabled     bool
+	MaxQueryAge time.Duration
+}
+
+func (cfg *WriteReadExemplarsTestConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "tests.write-read-exemplars-test.enabled", false, "Run the test writing exemplars and checking they're returned by the exemplars query API. Requires the exemplars storage to be enabled for the tenant.")
+	f.DurationVar(&cfg.MaxQueryAge, "tests.write-read-exemplars-test.max-query-age", time.Hour, "How back in the past exemplars can be queried at most. It should be lower than the time range of exemplars the exemplars storage can hold.")
+}
+
+// WriteReadExemplarsTest writes a series with an exemplar for each sample, and checks the exemplars
+// query API returns the written exemplars.
+type WriteReadExemplarsTest struct {
+	name    string
+	cfg     WriteReadExemplarsTestConfig
+	client  MimirClient
+	logger  log.Logger
+	metrics *TestMetrics
+
+	tracker writeTracker
+}
+
+func NewWriteReadExemplarsTest(cfg WriteReadExemplarsTestConfig, client MimirClient, logger log.Logger, reg prometheus.Registerer) *WriteReadExemplarsTest {
+	const name = "write-read-exemplars"
+
+	return &WriteReadExemplarsTest{
+		name:    name,
+		cfg:     cfg,
+		client:  client,
+		logger:  log.With(logger, "test", name),
+		metrics: NewTestMetrics(name, reg),
+	}
+}
+
+// Name implements Test.
+func (t *WriteReadExemplarsTest) Name() string {
+	return t.name
+}
+
+// Init implements Test.
+func (t *WriteReadExemplarsTest) Init(ctx context.Context, now time.Time) error {
+	// The exemplars written by a previous run may have been evicted from the exemplars storage,
+	// so the test only checks the exemplars written since it started.
+	return nil
+}
+
+// Run implements Test.
+func (t *WriteReadExemplarsTest) Run(ctx context.Context, now time.Time) error {
+	sp, ctx := spanlogger.NewWithLogger(ctx, t.logger, "WriteReadExemplarsTest.Run")
+	defer sp.Finish()
+
+	errs := new(multierror.MultiError)
+	errs.Add(writeUntilNow(ctx, now, &t.tracker, t.metrics, sp, func(ctx context.Context, timestamp time.Time) (int, error) {
+		return t.client.WriteSeries(ctx, generateExemplarSeries(timestamp))
+	}))
+
+	start, end, ok := t.tracker.queryTimeRange(now, t.cfg.MaxQueryAge)
+	if !ok {
+		level.Info(sp).Log("msg", "Skipped exemplars query because there's no valid time range to query")
+		errs.Add(errors.New("no valid time range to query"))
+		return errs.Err()
+	}
+
+	errs.Add(t.runExemplarsQueryAndVerifyResult(ctx, sp, start, end))
+	return errs.Err()
+}
+
+func (t *WriteReadExemplarsTest) runExemplarsQueryAndVerifyResult(ctx context.Context, logger log.Logger, start, end time.Time) error {
+	logger = log.With(logger, "query", exemplarsMetricName, "start", start.UnixMilli(), "end", end.UnixMilli())
+	level.Debug(logger).Log("msg", "Running exemplars query")
+
+	t.metrics.queriesTotal.Inc()
+	results, err := t.client.QueryExemplars(ctx, exemplarsMetricName, start, end)
+	if err != nil {
+		t.metrics.queriesFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Failed to execute exemplars query", "err", err)
+		return errors.Wrap(err, "failed to execute exemplars query")
+	}
+
+	t.metrics.queryResultChecksTotal.Inc()
+	if err := verifyExemplars(results, start, end); err != nil {
+		t.metrics.queryResultChecksFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Exemplars query result check failed", "err", err)
+		return errors.Wrap(err, "exemplars query result check failed")
+	}
+	return nil
+}
+
+// generateExemplarSeries returns a series with a sine wave sample and an exemplar at timestamp t. The exemplar
+// has the same value as the sample, and a trace ID derived from its timestamp.
+func generateExemplarSeries(t time.Time) []prompb.TimeSeries {
+	value := generateSineWaveValue(t)
+
+	return []prompb.TimeSeries{{
+		Labels: []prompb.Label{{
+			Name:  "__name__",
+			Value: exemplarsMetricName,
+		}},
+		Samples: []prompb.Sample{{
+			Value:     value,
+			Timestamp: t.UnixMilli(),
+		}},
+		Exemplars: []prompb.Exemplar{{
+			Labels: []prompb.Label{{
+				Name:  exemplarsTraceLabel,
+				Value: exemplarTraceID(t),
+			}},
+			Value:     value,
+			Timestamp: t.UnixMilli(),
+		}},
+	}}
+}
+
+func exemplarTraceID(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 16)
+}
+
+// verifyExemplars checks the exemplars query results contain the exemplars written by generateExemplarSeries()
+// at every write interval between start and end.
+func verifyExemplars(results []v1.ExemplarQueryResult, start, end time.Time) error {
+	if len(results) != 1 {
+		return fmt.Errorf("expected 1 series in the result but got %d", len(results))
+	}
+
+	actual := make(map[model.Time]v1.Exemplar, len(results[0].Exemplars))
+	for _, e := range results[0].Exemplars {
+		actual[e.Timestamp] = e
+	}
+
+	for ts := start; !ts.After(end); ts = ts.Add(writeInterval) {
+		e, ok := actual[model.TimeFromUnixNano(ts.UnixNano())]
+		if !ok {
+			return fmt.Errorf("missing exemplar at timestamp %d (%s)", ts.UnixMilli(), ts.String())
+		}
+
+		expectedTraceID := model.LabelValue(exemplarTraceID(ts))
+		if traceID := e.Labels[exemplarsTraceLabel]; traceID != expectedTraceID {
+			return fmt.Errorf("exemplar at timestamp %d (%s) has trace ID %q while was expecting %q", ts.UnixMilli(), ts.String(), traceID, expectedTraceID)
+		}
+
+		expectedValue := generateSineWaveValue(ts)
+		if !compareSampleValues(float64(e.Value), expectedValue) {
+			return fmt.Errorf("exemplar at timestamp %d (%s) has value %f while was expecting %f", ts.UnixMilli(), ts.String(), e.Value, expectedValue)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadExemplarsTest_Run(t *testing.T) {
+	logger := log.NewNopLogger()
+	cfg := WriteReadExemplarsTestConfig{Enabled: true, MaxQueryAge: time.Hour}
+
+	client := &ClientMock{}
+	client.On("WriteSeries", mock.Anything, mock.Anything).Return(200, nil)
+	client.On("QueryExemplars", mock.Anything, exemplarsMetricName, time.Unix(960, 0), time.Unix(1000, 0)).Return([]v1.ExemplarQueryResult{
+		generateExemplarQueryResult(time.Unix(960, 0), time.Unix(980, 0), time.Unix(1000, 0)),
+	}, nil)
+
+	test := NewWriteReadExemplarsTest(cfg, client, logger, prometheus.NewPedanticRegistry())
+	test.tracker.lastWrittenTimestamp = time.Unix(940, 0)
+
+	require.NoError(t, test.Run(context.Background(), time.Unix(1000, 0)))
+	client.AssertNumberOfCalls(t, "WriteSeries", 3)
+	client.AssertCalled(t, "WriteSeries", mock.Anything, generateExemplarSeries(time.Unix(960, 0)))
+	client.AssertNumberOfCalls(t, "QueryExemplars", 1)
+}
+
+func TestVerifyExemplars(t *testing.T) {
+	start, end := time.Unix(960, 0), time.Unix(1000, 0)
+
+	assert.NoError(t, verifyExemplars([]v1.ExemplarQueryResult{
+		generateExemplarQueryResult(time.Unix(960, 0), time.Unix(980, 0), time.Unix(1000, 0)),
+	}, start, end))
+
+	assert.EqualError(t, verifyExemplars(nil, start, end), "expected 1 series in the result but got 0")
+
+	assert.EqualError(t, verifyExemplars([]v1.ExemplarQueryResult{
+		generateExemplarQueryResult(time.Unix(960, 0), time.Unix(1000, 0)),
+	}, start, end), "missing exemplar at timestamp 980000 ("+time.Unix(980, 0).String()+")")
+
+	wrongTraceID := generateExemplarQueryResult(time.Unix(960, 0), time.Unix(980, 0), time.Unix(1000, 0))
+	wrongTraceID.Exemplars[2].Labels[exemplarsTraceLabel] = "1"
+	assert.EqualError(t, verifyExemplars([]v1.ExemplarQueryResult{wrongTraceID}, start, end),
+		`exemplar at timestamp 1000000 (`+time.Unix(1000, 0).String()+`) has trace ID "1" while was expecting "f4240"`)
+}
+
+func generateExemplarQueryResult(timestamps ...time.Time) v1.ExemplarQueryResult {
+	res := v1.ExemplarQueryResult{SeriesLabels: model.LabelSet{"__name__": exemplarsMetricName}}
+	for _, ts := range timestamps {
+		res.Exemplars = append(res.Exemplars, v1.Exemplar{
+			Labels:    model.LabelSet{exemplarsTraceLabel: model.LabelValue(exemplarTraceID(ts))},
+			Value:     model.SampleValue(generateSineWaveValue(ts)),
+			Timestamp: model.TimeFromUnixNano(ts.UnixNano()),
+		})
+	}
+	return res
+}
@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/multierror"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+const (
+	labelsMetricName = "mimir_continuous_test_labels"
+)
+
+var (
+	labelsMatchers = []string{labelsMetricName}
+)
+
+type WriteReadLabelsTestConfig struct {
+	Enabled   bool
+	NumSeries int
+}
+
+func (cfg *WriteReadLabelsTestConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "tests.write-read-labels-test.enabled", false, "Run the test writing series and checking the label names and values APIs return their labels.")
+	f.IntVar(&cfg.NumSeries, "tests.write-read-labels-test.num-series", 100, "Number of series used for the test.")
+}
+
+// WriteReadLabelsTest writes series at every run, and checks the label names and values APIs return
+// the labels of the written series.
+type WriteReadLabelsTest struct {
+	name    string
+	cfg     WriteReadLabelsTestConfig
+	client  MimirClient
+	logger  log.Logger
+	metrics *TestMetrics
+}
+
+func NewWriteReadLabelsTest(cfg WriteReadLabelsTestConfig, client MimirClient, logger log.Logger, reg prometheus.Registerer) *WriteReadLabelsTest {
+	const name = "write-read-labels"
+
+	return &WriteReadLabelsTest{
+		name:    name,
+		cfg:     cfg,
+		client:  client,
+		logger:  log.With(logger, "test", name),
+		metrics: NewTestMetrics(name, reg),
+	}
+}
+
+// Name implements Test.
+func (t *WriteReadLabelsTest) Name() string {
+	return t.name
+}
+
+// Init implements Test.
+func (t *WriteReadLabelsTest) Init(ctx context.Context, now time.Time) error {
+	return nil
+}
+
+// Run implements Test.
+func (t *WriteReadLabelsTest) Run(ctx context.Context, now time.Time) error {
+	sp, ctx := spanlogger.NewWithLogger(ctx, t.logger, "WriteReadLabelsTest.Run")
+	defer sp.Finish()
+
+	// The series are written only at the current timestamp, and the labels are queried at the same timestamp,
+	// so that the results don't depend on the series written by previous runs.
+	ts := alignTimestampToInterval(now, writeInterval)
+	written, err := writeAndTrack(ctx, ts, nil, t.metrics, sp, func(ctx context.Context, timestamp time.Time) (int, error) {
+		return t.client.WriteSeries(ctx, generateSineWaveSeries(labelsMetricName, timestamp, t.cfg.NumSeries))
+	})
+	if err != nil {
+		return err
+	}
+	if !written {
+		return errors.New("failed to remote write series")
+	}
+
+	errs := new(multierror.MultiError)
+	errs.Add(t.runLabelNamesQueryAndVerifyResult(ctx, sp, ts))
+	errs.Add(t.runLabelValuesQueryAndVerifyResult(ctx, sp, ts))
+	return errs.Err()
+}
+
+func (t *WriteReadLabelsTest) runLabelNamesQueryAndVerifyResult(ctx context.Context, logger log.Logger, ts time.Time) error {
+	logger = log.With(logger, "matchers", labelsMetricName, "ts", ts.UnixMilli())
+	level.Debug(logger).Log("msg", "Running label names query")
+
+	t.metrics.queriesTotal.Inc()
+	names, err := t.client.LabelNames(ctx, labelsMatchers, ts, ts)
+	if err != nil {
+		t.metrics.queriesFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Failed to execute label names query", "err", err)
+		return errors.Wrap(err, "failed to execute label names query")
+	}
+
+	t.metrics.queryResultChecksTotal.Inc()
+	if err := verifyLabelNames(names); err != nil {
+		t.metrics.queryResultChecksFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Label names query result check failed", "err", err)
+		return errors.Wrap(err, "label names query result check failed")
+	}
+	return nil
+}
+
+func (t *WriteReadLabelsTest) runLabelValuesQueryAndVerifyResult(ctx context.Context, logger log.Logger, ts time.Time) error {
+	logger = log.With(logger, "label", "series_id", "matchers", labelsMetricName, "ts", ts.UnixMilli())
+	level.Debug(logger).Log("msg", "Running label values query")
+
+	t.metrics.queriesTotal.Inc()
+	values, err := t.client.LabelValues(ctx, "series_id", labelsMatchers, ts, ts)
+	if err != nil {
+		t.metrics.queriesFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Failed to execute label values query", "err", err)
+		return errors.Wrap(err, "failed to execute label values query")
+	}
+
+	t.metrics.queryResultChecksTotal.Inc()
+	if err := verifySeriesIDLabelValues(values, t.cfg.NumSeries); err != nil {
+		t.metrics.queryResultChecksFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Label values query result check failed", "err", err)
+		return errors.Wrap(err, "label values query result check failed")
+	}
+	return nil
+}
+
+// verifyLabelNames checks the label names are the ones of the series generated by generateSineWaveSeries().
+func verifyLabelNames(names []string) error {
+	expected := []string{"__name__", "series_id"}
+
+	if len(names) != len(expected) {
+		return fmt.Errorf("expected label names %v but got %v", expected, names)
+	}
+	for i := range names {
+		if names[i] != expected[i] {
+			return fmt.Errorf("expected label names %v but got %v", expected, names)
+		}
+	}
+	return nil
+}
+
+// verifySeriesIDLabelValues checks the values of the series_id label are the ones of the expectedSeries series
+// generated by generateSineWaveSeries(), sorted.
+func verifySeriesIDLabelValues(values model.LabelValues, expectedSeries int) error {
+	expected := make([]string, 0, expectedSeries)
+	for i := 0; i < expectedSeries; i++ {
+		expected = append(expected, strconv.Itoa(i))
+	}
+	sort.Strings(expected)
+
+	if len(values) != len(expected) {
+		return fmt.Errorf("expected %d label values but got %d", len(expected), len(values))
+	}
+	for i, v := range values {
+		if string(v) != expected[i] {
+			return fmt.Errorf("expected label value %q at position %d but got %q", expected[i], i, v)
+		}
+	}
+	return nil
+}
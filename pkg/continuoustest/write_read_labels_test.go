@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadLabelsTest_Run(t *testing.T) {
+	logger := log.NewNopLogger()
+	cfg := WriteReadLabelsTestConfig{Enabled: true, NumSeries: 12}
+
+	t.Run("should write series and query their labels at the write timestamp", func(t *testing.T) {
+		ts := time.Unix(980, 0)
+
+		client := &ClientMock{}
+		client.On("WriteSeries", mock.Anything, mock.Anything).Return(200, nil)
+		client.On("LabelNames", mock.Anything, mock.Anything, ts, ts).Return([]string{"__name__", "series_id"}, nil)
+		client.On("LabelValues", mock.Anything, "series_id", mock.Anything, ts, ts).Return(model.LabelValues{"0", "1", "10", "11", "2", "3", "4", "5", "6", "7", "8", "9"}, nil)
+
+		reg := prometheus.NewPedanticRegistry()
+		test := NewWriteReadLabelsTest(cfg, client, logger, reg)
+
+		require.NoError(t, test.Run(context.Background(), time.Unix(999, 0)))
+		client.AssertCalled(t, "WriteSeries", mock.Anything, generateSineWaveSeries(labelsMetricName, ts, 12))
+		client.AssertCalled(t, "LabelNames", mock.Anything, []string{labelsMetricName}, ts, ts)
+
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP mimir_continuous_test_queries_total Total number of attempted query requests.
+			# TYPE mimir_continuous_test_queries_total counter
+			mimir_continuous_test_queries_total{test="write-read-labels"} 2
+
+			# HELP mimir_continuous_test_query_result_checks_failed_total Total number of query results failed when checking for correctness.
+			# TYPE mimir_continuous_test_query_result_checks_failed_total counter
+			mimir_continuous_test_query_result_checks_failed_total{test="write-read-labels"} 0
+		`), "mimir_continuous_test_queries_total", "mimir_continuous_test_query_result_checks_failed_total"))
+	})
+
+	t.Run("should track a failure if label values are missing", func(t *testing.T) {
+		client := &ClientMock{}
+		client.On("WriteSeries", mock.Anything, mock.Anything).Return(200, nil)
+		client.On("LabelNames", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]string{"__name__", "series_id"}, nil)
+		client.On("LabelValues", mock.Anything, "series_id", mock.Anything, mock.Anything, mock.Anything).Return(model.LabelValues{"0", "1"}, nil)
+
+		reg := prometheus.NewPedanticRegistry()
+		test := NewWriteReadLabelsTest(cfg, client, logger, reg)
+
+		require.Error(t, test.Run(context.Background(), time.Unix(1000, 0)))
+
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP mimir_continuous_test_query_result_checks_failed_total Total number of query results failed when checking for correctness.
+			# TYPE mimir_continuous_test_query_result_checks_failed_total counter
+			mimir_continuous_test_query_result_checks_failed_total{test="write-read-labels"} 1
+		`), "mimir_continuous_test_query_result_checks_failed_total"))
+	})
+}
+
+func TestVerifyLabelNames(t *testing.T) {
+	assert.NoError(t, verifyLabelNames([]string{"__name__", "series_id"}))
+	assert.EqualError(t, verifyLabelNames([]string{"__name__"}), "expected label names [__name__ series_id] but got [__name__]")
+	assert.EqualError(t, verifyLabelNames([]string{"__name__", "job"}), "expected label names [__name__ series_id] but got [__name__ job]")
+}
+
+func TestVerifySeriesIDLabelValues(t *testing.T) {
+	assert.NoError(t, verifySeriesIDLabelValues(model.LabelValues{"0", "1", "10", "2", "3", "4", "5", "6", "7", "8", "9"}, 11))
+	assert.EqualError(t, verifySeriesIDLabelValues(model.LabelValues{"0", "1"}, 3), "expected 3 label values but got 2")
+	assert.EqualError(t, verifySeriesIDLabelValues(model.LabelValues{"0", "2"}, 2), `expected label value "1" at position 1 but got "2"`)
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+const (
+	metadataMetricName = "mimir_continuous_test_metadata"
+	metadataHelp       = "Metric written by mimir-continuous-test to check the metric metadata."
+	metadataUnit       = "seconds"
+)
+
+type WriteReadMetadataTestConfig struct {
+	Enabled bool
+}
+
+func (cfg *WriteReadMetadataTestConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "tests.write-read-metadata-test.enabled", false, "Run the test writing metric metadata and checking it's returned by the metadata API.")
+}
+
+// WriteReadMetadataTest writes the metadata of a metric at every run, and checks the metadata API returns it.
+type WriteReadMetadataTest struct {
+	name    string
+	cfg     WriteReadMetadataTestConfig
+	client  MimirClient
+	logger  log.Logger
+	metrics *TestMetrics
+}
+
+func NewWriteReadMetadataTest(cfg WriteReadMetadataTestConfig, client MimirClient, logger log.Logger, reg prometheus.Registerer) *WriteReadMetadataTest {
+	const name = "write-read-metadata"
+
+	return &WriteReadMetadataTest{
+		name:    name,
+		cfg:     cfg,
+		client:  client,
+		logger:  log.With(logger, "test", name),
+		metrics: NewTestMetrics(name, reg),
+	}
+}
+
+// Name implements Test.
+func (t *WriteReadMetadataTest) Name() string {
+	return t.name
+}
+
+// Init implements Test.
+func (t *WriteReadMetadataTest) Init(ctx context.Context, now time.Time) error {
+	return nil
+}
+
+// Run implements Test.
+func (t *WriteReadMetadataTest) Run(ctx context.Context, now time.Time) error {
+	sp, ctx := spanlogger.NewWithLogger(ctx, t.logger, "WriteReadMetadataTest.Run")
+	defer sp.Finish()
+
+	// The metadata is written at every run, because ingesters only keep the recently written metadata.
+	written, err := writeAndTrack(ctx, now, nil, t.metrics, sp, func(ctx context.Context, _ time.Time) (int, error) {
+		return t.client.WriteMetadata(ctx, generateMetadata())
+	})
+	if err != nil {
+		return err
+	}
+	if !written {
+		return errors.New("failed to remote write metadata")
+	}
+
+	return t.runMetadataQueryAndVerifyResult(ctx, sp)
+}
+
+func (t *WriteReadMetadataTest) runMetadataQueryAndVerifyResult(ctx context.Context, logger log.Logger) error {
+	logger = log.With(logger, "metric", metadataMetricName)
+	level.Debug(logger).Log("msg", "Running metadata query")
+
+	t.metrics.queriesTotal.Inc()
+	metadata, err := t.client.Metadata(ctx, metadataMetricName)
+	if err != nil {
+		t.metrics.queriesFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Failed to execute metadata query", "err", err)
+		return errors.Wrap(err, "failed to execute metadata query")
+	}
+
+	t.metrics.queryResultChecksTotal.Inc()
+	if err := verifyMetadata(metadata); err != nil {
+		t.metrics.queryResultChecksFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Metadata query result check failed", "err", err)
+		return errors.Wrap(err, "metadata query result check failed")
+	}
+	return nil
+}
+
+func generateMetadata() []prompb.MetricMetadata {
+	return []prompb.MetricMetadata{{
+		Type:             prompb.MetricMetadata_GAUGE,
+		MetricFamilyName: metadataMetricName,
+		Help:             metadataHelp,
+		Unit:             metadataUnit,
+	}}
+}
+
+// verifyMetadata checks the metadata returned by the metadata API is the one written by generateMetadata().
+func verifyMetadata(metadata map[string][]v1.Metadata) error {
+	expected := v1.Metadata{Type: v1.MetricTypeGauge, Help: metadataHelp, Unit: metadataUnit}
+
+	actual := metadata[metadataMetricName]
+	if len(actual) != 1 {
+		return fmt.Errorf("expected 1 metadata for metric %s but got %d", metadataMetricName, len(actual))
+	}
+	if actual[0] != expected {
+		return fmt.Errorf("expected metadata %+v for metric %s but got %+v", expected, metadataMetricName, actual[0])
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadMetadataTest_Run(t *testing.T) {
+	logger := log.NewNopLogger()
+	cfg := WriteReadMetadataTestConfig{Enabled: true}
+
+	t.Run("should write metadata and track no failure if the metadata matches", func(t *testing.T) {
+		client := &ClientMock{}
+		client.On("WriteMetadata", mock.Anything, mock.Anything).Return(200, nil)
+		client.On("Metadata", mock.Anything, metadataMetricName).Return(map[string][]v1.Metadata{
+			metadataMetricName: {{Type: v1.MetricTypeGauge, Help: metadataHelp, Unit: metadataUnit}},
+		}, nil)
+
+		reg := prometheus.NewPedanticRegistry()
+		test := NewWriteReadMetadataTest(cfg, client, logger, reg)
+
+		require.NoError(t, test.Run(context.Background(), time.Unix(1000, 0)))
+		client.AssertCalled(t, "WriteMetadata", mock.Anything, generateMetadata())
+
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP mimir_continuous_test_writes_total Total number of attempted write requests.
+			# TYPE mimir_continuous_test_writes_total counter
+			mimir_continuous_test_writes_total{test="write-read-metadata"} 1
+
+			# HELP mimir_continuous_test_queries_total Total number of attempted query requests.
+			# TYPE mimir_continuous_test_queries_total counter
+			mimir_continuous_test_queries_total{test="write-read-metadata"} 1
+
+			# HELP mimir_continuous_test_query_result_checks_failed_total Total number of query results failed when checking for correctness.
+			# TYPE mimir_continuous_test_query_result_checks_failed_total counter
+			mimir_continuous_test_query_result_checks_failed_total{test="write-read-metadata"} 0
+		`), "mimir_continuous_test_writes_total", "mimir_continuous_test_queries_total", "mimir_continuous_test_query_result_checks_failed_total"))
+	})
+
+	t.Run("should track a failure if the metadata doesn't match", func(t *testing.T) {
+		client := &ClientMock{}
+		client.On("WriteMetadata", mock.Anything, mock.Anything).Return(200, nil)
+		client.On("Metadata", mock.Anything, metadataMetricName).Return(map[string][]v1.Metadata{
+			metadataMetricName: {{Type: v1.MetricTypeCounter, Help: metadataHelp, Unit: metadataUnit}},
+		}, nil)
+
+		reg := prometheus.NewPedanticRegistry()
+		test := NewWriteReadMetadataTest(cfg, client, logger, reg)
+
+		require.Error(t, test.Run(context.Background(), time.Unix(1000, 0)))
+
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP mimir_continuous_test_query_result_checks_failed_total Total number of query results failed when checking for correctness.
+			# TYPE mimir_continuous_test_query_result_checks_failed_total counter
+			mimir_continuous_test_query_result_checks_failed_total{test="write-read-metadata"} 1
+		`), "mimir_continuous_test_query_result_checks_failed_total"))
+	})
+
+	t.Run("should not query metadata if the write failed", func(t *testing.T) {
+		client := &ClientMock{}
+		client.On("WriteMetadata", mock.Anything, mock.Anything).Return(500, errors.New("500 error"))
+
+		reg := prometheus.NewPedanticRegistry()
+		test := NewWriteReadMetadataTest(cfg, client, logger, reg)
+
+		require.Error(t, test.Run(context.Background(), time.Unix(1000, 0)))
+		client.AssertNotCalled(t, "Metadata", mock.Anything, mock.Anything)
+	})
+}
+
+func TestVerifyMetadata(t *testing.T) {
+	assert.NoError(t, verifyMetadata(map[string][]v1.Metadata{
+		metadataMetricName: {{Type: v1.MetricTypeGauge, Help: metadataHelp, Unit: metadataUnit}},
+	}))
+	assert.EqualError(t, verifyMetadata(map[string][]v1.Metadata{}), "expected 1 metadata for metric mimir_continuous_test_metadata but got 0")
+	assert.EqualError(t, verifyMetadata(map[string][]v1.Metadata{
+		metadataMetricName: {{Type: v1.MetricTypeGauge, Help: "Other.", Unit: metadataUnit}},
+	}), "expected metadata {Type:gauge Help:Metric written by mimir-continuous-test to check the metric metadata. Unit:seconds} for metric mimir_continuous_test_metadata but got {Type:gauge Help:Other. Unit:seconds}")
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/multierror"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+const (
+	outOfOrderMetricName = "mimir_continuous_test_out_of_order"
+)
+
+var (
+	queryOutOfOrderSum = fmt.Sprintf("sum(max_over_time(%s[1s]))", outOfOrderMetricName)
+)
+
+type WriteReadOutOfOrderTestConfig struct {
+	Enabled              bool
+	NumSeries            int
+	OutOfOrderTimeWindow time.Duration
+	MaxQueryAge          time.Duration
+}
+
+func (cfg *WriteReadOutOfOrderTestConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "tests.write-read-out-of-order-test.enabled", false, "Run the test writing out-of-order samples and checking the query results. Requires the out-of-order time window to be configured for the tenant.")
+	f.IntVar(&cfg.NumSeries, "tests.write-read-out-of-order-test.num-series", 100, "Number of series used for the test.")
+	f.DurationVar(&cfg.OutOfOrderTimeWindow, "tests.write-read-out-of-order-test.out-of-order-time-window", 10*time.Minute, "How old out-of-order samples can be written at most. It must be lower or equal to the out_of_order_time_window of the tenant, and greater than -tests.run-interval to write all the samples.")
+	f.DurationVar(&cfg.MaxQueryAge, "tests.write-read-out-of-order-test.max-query-age", time.Hour, "How back in the past metrics can be queried at most.")
+}
+
+// WriteReadOutOfOrderTest writes the samples since the previous run from the newest to the oldest one, so that all
+// the samples but the newest one are out-of-order, and checks the query results.
+type WriteReadOutOfOrderTest struct {
+	name    string
+	cfg     WriteReadOutOfOrderTestConfig
+	client  MimirClient
+	logger  log.Logger
+	metrics *TestMetrics
+
+	tracker writeTracker
+}
+
+func NewWriteReadOutOfOrderTest(cfg WriteReadOutOfOrderTestConfig, client MimirClient, logger log.Logger, reg prometheus.Registerer) *WriteReadOutOfOrderTest {
+	const name = "write-read-out-of-order"
+
+	return &WriteReadOutOfOrderTest{
+		name:    name,
+		cfg:     cfg,
+		client:  client,
+		logger:  log.With(logger, "test", name),
+		metrics: NewTestMetrics(name, reg),
+	}
+}
+
+// Name implements Test.
+func (t *WriteReadOutOfOrderTest) Name() string {
+	return t.name
+}
+
+// Init implements Test.
+func (t *WriteReadOutOfOrderTest) Init(ctx context.Context, now time.Time) error {
+	return nil
+}
+
+// Run implements Test.
+func (t *WriteReadOutOfOrderTest) Run(ctx context.Context, now time.Time) error {
+	sp, ctx := spanlogger.NewWithLogger(ctx, t.logger, "WriteReadOutOfOrderTest.Run")
+	defer sp.Finish()
+
+	errs := new(multierror.MultiError)
+	errs.Add(t.writeSamples(ctx, sp, now))
+
+	start, end, ok := t.tracker.queryTimeRange(now, t.cfg.MaxQueryAge)
+	if !ok {
+		level.Info(sp).Log("msg", "Skipped queries because there's no valid time range to query")
+		errs.Add(errors.New("no valid time range to query"))
+		return errs.Err()
+	}
+
+	errs.Add(t.runRangeQueryAndVerifyResult(ctx, sp, start, end))
+	return errs.Err()
+}
+
+// writeSamples writes the samples of each write timestamp since the previous run, from the newest to the oldest one.
+// The samples older than the out-of-order time window are skipped.
+func (t *WriteReadOutOfOrderTest) writeSamples(ctx context.Context, logger log.Logger, now time.Time) error {
+	from := maxTime(t.tracker.nextWriteTimestamp(now), alignTimestampToInterval(now.Add(-t.cfg.OutOfOrderTimeWindow), writeInterval).Add(writeInterval))
+	to := alignTimestampToInterval(now, writeInterval)
+
+	for ts := to; !ts.Before(from); ts = ts.Add(-writeInterval) {
+		written, err := writeAndTrack(ctx, ts, nil, t.metrics, logger, func(ctx context.Context, timestamp time.Time) (int, error) {
+			return t.client.WriteSeries(ctx, generateSineWaveSeries(outOfOrderMetricName, timestamp, t.cfg.NumSeries))
+		})
+		if err != nil || !written {
+			// The newer samples have been written, so the failed ones can't be retried in order and
+			// there's a gap in the written samples.
+			t.tracker.failed(to)
+			return err
+		}
+	}
+
+	if !to.Before(from) {
+		t.tracker.written(from, to)
+	}
+	return nil
+}
+
+func (t *WriteReadOutOfOrderTest) runRangeQueryAndVerifyResult(ctx context.Context, logger log.Logger, start, end time.Time) error {
+	step := getQueryStep(start, end, writeInterval)
+
+	logger = log.With(logger, "query", queryOutOfOrderSum, "start", start.UnixMilli(), "end", end.UnixMilli(), "step", step)
+	level.Debug(logger).Log("msg", "Running range query")
+
+	// The results cache is disabled, because the out-of-order samples may have been written after
+	// the results have been cached.
+	t.metrics.queriesTotal.Inc()
+	matrix, err := t.client.QueryRange(ctx, queryOutOfOrderSum, start, end, step, WithResultsCacheEnabled(false))
+	if err != nil {
+		t.metrics.queriesFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Failed to execute range query", "err", err)
+		return errors.Wrap(err, "failed to execute range query")
+	}
+
+	t.metrics.queryResultChecksTotal.Inc()
+	if err := verifyOutOfOrderSamplesSum(matrix, t.cfg.NumSeries, start, end, step); err != nil {
+		t.metrics.queryResultChecksFailedTotal.Inc()
+		level.Warn(logger).Log("msg", "Range query result check failed", "err", err)
+		return errors.Wrap(err, "range query result check failed")
+	}
+	return nil
+}
+
+// verifyOutOfOrderSamplesSum checks the range query results have the sum of the sine wave series, with
+// no missing sample from start to end, including the out-of-order ones.
+func verifyOutOfOrderSamplesSum(matrix model.Matrix, expectedSeries int, start, end time.Time, step time.Duration) error {
+	if _, err := verifySineWaveSamplesSum(matrix, expectedSeries, step); err != nil {
+		return err
+	}
+
+	samples := matrix[0].Values
+	if len(samples) == 0 {
+		return errors.New("expected samples in the result but got none")
+	}
+
+	// The samples have no gaps, so it's enough to check the first and last ones.
+	expectedLast := start.Add(end.Sub(start) / step * step)
+	if first := samples[0].Timestamp.Time(); !first.Equal(start) {
+		return fmt.Errorf("first sample has timestamp %d (%s) while was expecting %d (%s)", first.UnixMilli(), first.UTC().String(), start.UnixMilli(), start.UTC().String())
+	}
+	if last := samples[len(samples)-1].Timestamp.Time(); !last.Equal(expectedLast) {
+		return fmt.Errorf("last sample has timestamp %d (%s) while was expecting %d (%s)", last.UnixMilli(), last.UTC().String(), expectedLast.UnixMilli(), expectedLast.UTC().String())
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadOutOfOrderTest_Run(t *testing.T) {
+	logger := log.NewNopLogger()
+	cfg := WriteReadOutOfOrderTestConfig{Enabled: true, NumSeries: 2, OutOfOrderTimeWindow: 10 * time.Minute, MaxQueryAge: time.Hour}
+
+	t.Run("should write samples from the newest to the oldest one and query them", func(t *testing.T) {
+		var written []int64
+
+		client := &ClientMock{}
+		client.On("WriteSeries", mock.Anything, mock.Anything).Return(200, nil).Run(func(args mock.Arguments) {
+			written = append(written, args.Get(1).([]prompb.TimeSeries)[0].Samples[0].Timestamp)
+		})
+		client.On("QueryRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(model.Matrix{
+			{Values: []model.SamplePair{
+				newSamplePair(time.Unix(960, 0), generateSineWaveValue(time.Unix(960, 0))*2),
+				newSamplePair(time.Unix(980, 0), generateSineWaveValue(time.Unix(980, 0))*2),
+				newSamplePair(time.Unix(1000, 0), generateSineWaveValue(time.Unix(1000, 0))*2),
+			}},
+		}, nil)
+
+		test := NewWriteReadOutOfOrderTest(cfg, client, logger, prometheus.NewPedanticRegistry())
+		test.tracker.lastWrittenTimestamp = time.Unix(940, 0)
+
+		require.NoError(t, test.Run(context.Background(), time.Unix(1000, 0)))
+		assert.Equal(t, []int64{1000000, 980000, 960000}, written)
+		client.AssertCalled(t, "QueryRange", mock.Anything, "sum(max_over_time(mimir_continuous_test_out_of_order[1s]))", time.Unix(960, 0), time.Unix(1000, 0), writeInterval, mock.Anything)
+	})
+
+	t.Run("should skip the samples older than the out-of-order time window", func(t *testing.T) {
+		client := &ClientMock{}
+		client.On("WriteSeries", mock.Anything, mock.Anything).Return(200, nil)
+		client.On("QueryRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(model.Matrix{}, nil)
+
+		test := NewWriteReadOutOfOrderTest(cfg, client, logger, prometheus.NewPedanticRegistry())
+		test.tracker.lastWrittenTimestamp = time.Unix(0, 0)
+		test.tracker.queryMinTime = time.Unix(0, 0)
+		test.tracker.queryMaxTime = time.Unix(0, 0)
+
+		_ = test.Run(context.Background(), time.Unix(1000, 0))
+		client.AssertNumberOfCalls(t, "WriteSeries", 30)
+		client.AssertCalled(t, "WriteSeries", mock.Anything, generateSineWaveSeries(outOfOrderMetricName, time.Unix(420, 0), 2))
+		client.AssertNotCalled(t, "WriteSeries", mock.Anything, generateSineWaveSeries(outOfOrderMetricName, time.Unix(400, 0), 2))
+
+		// The time range to query restarts after the gap.
+		assert.Equal(t, time.Unix(420, 0), test.tracker.queryMinTime)
+		assert.Equal(t, time.Unix(1000, 0), test.tracker.queryMaxTime)
+	})
+
+	t.Run("should reset the time range to query on write failure", func(t *testing.T) {
+		client := &ClientMock{}
+		client.On("WriteSeries", mock.Anything, generateSineWaveSeries(outOfOrderMetricName, time.Unix(1000, 0), 2)).Return(200, nil)
+		client.On("WriteSeries", mock.Anything, mock.Anything).Return(500, errors.New("500 error"))
+
+		test := NewWriteReadOutOfOrderTest(cfg, client, logger, prometheus.NewPedanticRegistry())
+		test.tracker.lastWrittenTimestamp = time.Unix(940, 0)
+		test.tracker.queryMinTime = time.Unix(900, 0)
+		test.tracker.queryMaxTime = time.Unix(940, 0)
+
+		require.Error(t, test.Run(context.Background(), time.Unix(1000, 0)))
+		client.AssertNumberOfCalls(t, "WriteSeries", 2)
+		client.AssertNotCalled(t, "QueryRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.Equal(t, writeTracker{lastWrittenTimestamp: time.Unix(1000, 0)}, test.tracker)
+	})
+}
+
+func TestVerifyOutOfOrderSamplesSum(t *testing.T) {
+	start, end := time.Unix(960, 0), time.Unix(1000, 0)
+	samples := []model.SamplePair{
+		newSamplePair(time.Unix(960, 0), generateSineWaveValue(time.Unix(960, 0))*2),
+		newSamplePair(time.Unix(980, 0), generateSineWaveValue(time.Unix(980, 0))*2),
+		newSamplePair(time.Unix(1000, 0), generateSineWaveValue(time.Unix(1000, 0))*2),
+	}
+
+	assert.NoError(t, verifyOutOfOrderSamplesSum(model.Matrix{{Values: samples}}, 2, start, end, writeInterval))
+	assert.EqualError(t, verifyOutOfOrderSamplesSum(model.Matrix{{Values: samples[1:]}}, 2, start, end, writeInterval),
+		"first sample has timestamp 980000 ("+time.Unix(980, 0).UTC().String()+") while was expecting 960000 ("+start.UTC().String()+")")
+	assert.EqualError(t, verifyOutOfOrderSamplesSum(model.Matrix{{Values: samples[:2]}}, 2, start, end, writeInterval),
+		"last sample has timestamp 980000 ("+time.Unix(980, 0).UTC().String()+") while was expecting 1000000 ("+end.UTC().String()+")")
+	assert.EqualError(t, verifyOutOfOrderSamplesSum(model.Matrix{{}}, 2, start, end, writeInterval), "expected samples in the result but got none")
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// writeTracker tracks the samples written by a test at every writeInterval, and the time range
+// which can be queried because it has no gaps.
+type writeTracker struct {
+	lastWrittenTimestamp time.Time
+	queryMinTime         time.Time
+	queryMaxTime         time.Time
+}
+
+func (w *writeTracker) nextWriteTimestamp(now time.Time) time.Time {
+	if w.lastWrittenTimestamp.IsZero() {
+		return alignTimestampToInterval(now, writeInterval)
+	}
+
+	return w.lastWrittenTimestamp.Add(writeInterval)
+}
+
+// written tracks the successful write of the samples from one timestamp to another one, included.
+func (w *writeTracker) written(from, to time.Time) {
+	// If some samples haven't been written since the last written ones, the time range
+	// to query restarts from the new samples.
+	if w.queryMinTime.IsZero() || !from.Equal(w.lastWrittenTimestamp.Add(writeInterval)) {
+		w.queryMinTime = from
+	}
+
+	w.lastWrittenTimestamp = to
+	w.queryMaxTime = to
+}
+
+// failed tracks a write request failed with a 4xx error, which isn't expected to succeed if retried.
+// The samples may have been not written at all or partially written, so we can't reliably assert on
+// query results and the time range to query is reset.
+func (w *writeTracker) failed(timestamp time.Time) {
+	w.lastWrittenTimestamp = timestamp
+	w.queryMinTime = time.Time{}
+	w.queryMaxTime = time.Time{}
+}
+
+// queryTimeRange returns the time range to query, honoring the max query age. Returns false if there's
+// no valid time range to query.
+func (w *writeTracker) queryTimeRange(now time.Time, maxQueryAge time.Duration) (start, end time.Time, ok bool) {
+	// The min and max allowed query timestamps are zero if there's no successfully written data yet.
+	if w.queryMinTime.IsZero() || w.queryMaxTime.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start = maxTime(w.queryMinTime, alignTimestampToInterval(now.Add(-maxQueryAge), writeInterval))
+	if w.queryMaxTime.Before(start) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, w.queryMaxTime, true
+}
+
+// writeUntilNow writes the samples for each write timestamp until now, in order. Returns on the first
+// write request to retry.
+func writeUntilNow(ctx context.Context, now time.Time, tracker *writeTracker, metrics *TestMetrics, logger log.Logger, write writeFunc) error {
+	for timestamp := tracker.nextWriteTimestamp(now); !timestamp.After(now); timestamp = tracker.nextWriteTimestamp(now) {
+		succeeded, err := writeAndTrack(ctx, timestamp, tracker, metrics, logger, write)
+		if err != nil {
+			return err
+		}
+		if succeeded {
+			tracker.written(timestamp, timestamp)
+		}
+	}
+	return nil
+}
+
+// writeFunc writes the samples for a timestamp, returning the response status code and optionally an error.
+type writeFunc func(ctx context.Context, timestamp time.Time) (int, error)
+
+// writeAndTrack runs the write request for the samples at timestamp, tracks it in the test metrics and returns whether
+// it succeeded. A 4xx error is tracked in the writeTracker, if any, and not returned, because retrying the request isn't expected
+// to succeed. Network and 5xx errors are returned, so that the request is retried in the next test run.
+func writeAndTrack(ctx context.Context, timestamp time.Time, tracker *writeTracker, metrics *TestMetrics, logger log.Logger, write writeFunc) (bool, error) {
+	logger = log.With(logger, "timestamp", timestamp.String())
+	statusCode, err := write(ctx, timestamp)
+
+	metrics.writesTotal.Inc()
+	if statusCode/100 != 2 {
+		metrics.writesFailedTotal.WithLabelValues(strconv.Itoa(statusCode)).Inc()
+		level.Warn(logger).Log("msg", "Failed to remote write", "status_code", statusCode, "err", err)
+	} else {
+		level.Debug(logger).Log("msg", "Remote write succeeded")
+	}
+
+	if statusCode/100 == 4 {
+		if tracker != nil {
+			tracker.failed(timestamp)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to remote write")
+	}
+	if statusCode/100 != 2 {
+		return false, errors.Errorf("remote write failed with status code %d", statusCode)
+	}
+	return true, nil
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package continuoustest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTracker(t *testing.T) {
+	w := writeTracker{}
+	assert.Equal(t, time.Unix(980, 0), w.nextWriteTimestamp(time.Unix(999, 0)))
+
+	_, _, ok := w.queryTimeRange(time.Unix(1000, 0), time.Hour)
+	assert.False(t, ok)
+
+	w.written(time.Unix(980, 0), time.Unix(980, 0))
+	w.written(time.Unix(1000, 0), time.Unix(1040, 0))
+	assert.Equal(t, time.Unix(1060, 0), w.nextWriteTimestamp(time.Unix(2000, 0)))
+
+	start, end, ok := w.queryTimeRange(time.Unix(1040, 0), time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, time.Unix(980, 0), start)
+	assert.Equal(t, time.Unix(1040, 0), end)
+
+	// Honor the max query age.
+	start, end, ok = w.queryTimeRange(time.Unix(1050, 0), 30*time.Second)
+	require.True(t, ok)
+	assert.Equal(t, time.Unix(1020, 0), start)
+	assert.Equal(t, time.Unix(1040, 0), end)
+
+	_, _, ok = w.queryTimeRange(time.Unix(1100, 0), 30*time.Second)
+	assert.False(t, ok)
+
+	// The time range to query restarts after a gap.
+	w.written(time.Unix(1100, 0), time.Unix(1100, 0))
+	start, end, ok = w.queryTimeRange(time.Unix(1100, 0), time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, time.Unix(1100, 0), start)
+	assert.Equal(t, time.Unix(1100, 0), end)
+
+	// The time range to query is reset after a failure.
+	w.failed(time.Unix(1120, 0))
+	_, _, ok = w.queryTimeRange(time.Unix(1120, 0), time.Hour)
+	assert.False(t, ok)
+	assert.Equal(t, time.Unix(1140, 0), w.nextWriteTimestamp(time.Unix(1200, 0)))
+}
+
+func TestWriteUntilNow(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	t.Run("should write samples until now", func(t *testing.T) {
+		reg := prometheus.NewPedanticRegistry()
+		metrics := NewTestMetrics("test", reg)
+		w := &writeTracker{lastWrittenTimestamp: time.Unix(940, 0)}
+
+		var written []time.Time
+		err := writeUntilNow(context.Background(), time.Unix(1000, 0), w, metrics, logger, func(_ context.Context, ts time.Time) (int, error) {
+			written = append(written, ts)
+			return 200, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []time.Time{time.Unix(960, 0), time.Unix(980, 0), time.Unix(1000, 0)}, written)
+		assert.Equal(t, time.Unix(960, 0), w.queryMinTime)
+		assert.Equal(t, time.Unix(1000, 0), w.queryMaxTime)
+	})
+
+	t.Run("should stop writing on 5xx error", func(t *testing.T) {
+		reg := prometheus.NewPedanticRegistry()
+		metrics := NewTestMetrics("test", reg)
+		w := &writeTracker{lastWrittenTimestamp: time.Unix(940, 0), queryMinTime: time.Unix(900, 0), queryMaxTime: time.Unix(940, 0)}
+
+		err := writeUntilNow(context.Background(), time.Unix(1000, 0), w, metrics, logger, func(_ context.Context, ts time.Time) (int, error) {
+			return 500, errors.New("500 error")
+		})
+		require.Error(t, err)
+		assert.Equal(t, writeTracker{lastWrittenTimestamp: time.Unix(940, 0), queryMinTime: time.Unix(900, 0), queryMaxTime: time.Unix(940, 0)}, *w)
+
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP mimir_continuous_test_writes_total Total number of attempted write requests.
+			# TYPE mimir_continuous_test_writes_total counter
+			mimir_continuous_test_writes_total{test="test"} 1
+
+			# HELP mimir_continuous_test_writes_failed_total Total number of failed write requests.
+			# TYPE mimir_continuous_test_writes_failed_total counter
+			mimir_continuous_test_writes_failed_total{status_code="500",test="test"} 1
+		`), "mimir_continuous_test_writes_total", "mimir_continuous_test_writes_failed_total"))
+	})
+
+	t.Run("should keep writing next intervals on 4xx error", func(t *testing.T) {
+		reg := prometheus.NewPedanticRegistry()
+		metrics := NewTestMetrics("test", reg)
+		w := &writeTracker{lastWrittenTimestamp: time.Unix(940, 0), queryMinTime: time.Unix(900, 0), queryMaxTime: time.Unix(940, 0)}
+
+		err := writeUntilNow(context.Background(), time.Unix(1000, 0), w, metrics, logger, func(_ context.Context, ts time.Time) (int, error) {
+			if ts.Equal(time.Unix(960, 0)) {
+				return 400, errors.New("400 error")
+			}
+			return 200, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, writeTracker{lastWrittenTimestamp: time.Unix(1000, 0), queryMinTime: time.Unix(980, 0), queryMaxTime: time.Unix(1000, 0)}, *w)
+	})
+}
@@ -104,6 +104,8 @@ type Distributor struct {
 	// Per-user rate limiters.
 	requestRateLimiter   *limiter.RateLimiter
 	ingestionRateLimiter *limiter.RateLimiter
+	// Ingestion rate limiter of the rule results, when the ruler ingestion rate limit is enabled.
+	rulerIngestionRateLimiter *limiter.RateLimiter
 
 	// Manager for subservices (HA Tracker, distributor ring and client pool)
 	subservices        *services.Manager
@@ -348,13 +350,14 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 	// Create the configured ingestion rate limit strategy (local or global). In case
 	// it's an internal dependency and we can't join the distributors ring, we skip rate
 	// limiting.
-	var ingestionRateStrategy, requestRateStrategy limiter.RateLimiterStrategy
+	var ingestionRateStrategy, requestRateStrategy, rulerIngestionRateStrategy limiter.RateLimiterStrategy
 	var distributorsLifecycler *ring.BasicLifecycler
 	var distributorsRing *ring.Ring
 
 	if !canJoinDistributorsRing {
 		requestRateStrategy = newInfiniteRateStrategy()
 		ingestionRateStrategy = newInfiniteRateStrategy()
+		rulerIngestionRateStrategy = newInfiniteRateStrategy()
 	} else {
 		distributorsRing, distributorsLifecycler, err = newRingAndLifecycler(cfg.DistributorRing, d.healthyInstancesCount, log, reg)
 		if err != nil {
@@ -364,10 +367,12 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 		subservices = append(subservices, distributorsLifecycler, distributorsRing)
 		requestRateStrategy = newGlobalRateStrategy(newRequestRateStrategy(limits), d)
 		ingestionRateStrategy = newGlobalRateStrategy(newIngestionRateStrategy(limits), d)
+		rulerIngestionRateStrategy = newGlobalRateStrategy(newRulerIngestionRateStrategy(limits), d)
 	}
 
 	d.requestRateLimiter = limiter.NewRateLimiter(requestRateStrategy, 10*time.Second)
 	d.ingestionRateLimiter = limiter.NewRateLimiter(ingestionRateStrategy, 10*time.Second)
+	d.rulerIngestionRateLimiter = limiter.NewRateLimiter(rulerIngestionRateStrategy, 10*time.Second)
 	d.distributorsLifecycler = distributorsLifecycler
 	d.distributorsRing = distributorsRing
 
@@ -616,6 +621,31 @@ func (d *Distributor) Push(ctx context.Context, req *mimirpb.WriteRequest) (*mim
 	return d.PushWithCleanup(ctx, req, func() { mimirpb.ReuseSlice(req.Timeseries) })
 }
 
+// rulerPushContextKey is the key of the context value marking the requests pushed by the ruler running in
+// the same process. It can't be set by the clients of the push API, unlike the source of the request.
+type rulerPushContextKey struct{}
+
+// RulerPusher pushes the results of the rules evaluated by the ruler running in the same process.
+type RulerPusher struct {
+	distributor *Distributor
+}
+
+// NewRulerPusher makes a new RulerPusher pushing through the distributor.
+func NewRulerPusher(d *Distributor) *RulerPusher {
+	return &RulerPusher{distributor: d}
+}
+
+// Push implements ruler.Pusher.
+func (p *RulerPusher) Push(ctx context.Context, req *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error) {
+	return p.distributor.Push(context.WithValue(ctx, rulerPushContextKey{}, true), req)
+}
+
+// isRulerPush returns whether the request has been pushed by the ruler running in the same process.
+func isRulerPush(ctx context.Context, req *mimirpb.WriteRequest) bool {
+	pushedByRuler, _ := ctx.Value(rulerPushContextKey{}).(bool)
+	return pushedByRuler && req.Source == mimirpb.RULE
+}
+
 // PushWithCleanup takes a WriteRequest and distributes it to ingesters using the ring.
 // Strings in `req` may be pointers into the gRPC buffer which will be reused, so must be copied if retained.
 func (d *Distributor) PushWithCleanup(ctx context.Context, req *mimirpb.WriteRequest, callerCleanup func()) (*mimirpb.WriteResponse, error) {
@@ -660,8 +690,12 @@ func (d *Distributor) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReq
 		return nil, errMaxInflightRequestsBytesReached
 	}
 
+	// The rule results pushed by the ruler have their own rate limit budget, instead of the request
+	// and ingestion rate limits of the tenant, when the ruler ingestion rate limit is enabled.
+	rulerRateLimited := isRulerPush(ctx, req) && d.limits.RulerIngestionRate(userID) > 0
+
 	now := mtime.Now()
-	if !d.requestRateLimiter.AllowN(now, userID, 1) {
+	if !rulerRateLimited && !d.requestRateLimiter.AllowN(now, userID, 1) {
 		validation.DiscardedRequests.WithLabelValues(validation.ReasonRateLimited, userID).Add(1)
 
 		// Return a 429 here to tell the client it is going too fast.
@@ -699,7 +733,9 @@ func (d *Distributor) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReq
 	validatedSamples := 0
 	validatedExemplars := 0
 
-	if d.limits.AcceptHASamples(userID) && len(req.Timeseries) > 0 {
+	// The rule results pushed by the ruler aren't written by HA Prometheus replicas, so they aren't
+	// deduplicated when they're subject to the ruler ingestion rate limit.
+	if !rulerRateLimited && d.limits.AcceptHASamples(userID) && len(req.Timeseries) > 0 {
 		cluster, replica := findHALabels(d.limits.HAReplicaLabel(userID), d.limits.HAClusterLabel(userID), req.Timeseries[0].Labels)
 		// Make a copy of these, since they may be retained as labels on our metrics, e.g. dedupedSamples.
 		cluster, replica = copyString(cluster), copyString(replica)
@@ -857,7 +893,15 @@ func (d *Distributor) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReq
 	}
 
 	totalN := validatedSamples + validatedExemplars + len(validatedMetadata)
-	if !d.ingestionRateLimiter.AllowN(now, userID, totalN) {
+	if rulerRateLimited {
+		if !d.rulerIngestionRateLimiter.AllowN(now, userID, totalN) {
+			validation.DiscardedSamples.WithLabelValues(validation.ReasonRulerRateLimited, userID).Add(float64(validatedSamples))
+			validation.DiscardedExemplars.WithLabelValues(validation.ReasonRulerRateLimited, userID).Add(float64(validatedExemplars))
+			validation.DiscardedMetadata.WithLabelValues(validation.ReasonRulerRateLimited, userID).Add(float64(len(validatedMetadata)))
+			// The ruler fails the evaluation of the rule, so that the rejection shows up in the rule's health.
+			return nil, httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewRulerIngestionRateLimitedError(d.limits.RulerIngestionRate(userID), d.limits.RulerIngestionBurstSize(userID)).Error())
+		}
+	} else if !d.ingestionRateLimiter.AllowN(now, userID, totalN) {
 		validation.DiscardedSamples.WithLabelValues(validation.ReasonRateLimited, userID).Add(float64(validatedSamples))
 		validation.DiscardedExemplars.WithLabelValues(validation.ReasonRateLimited, userID).Add(float64(validatedExemplars))
 		validation.DiscardedMetadata.WithLabelValues(validation.ReasonRateLimited, userID).Add(float64(len(validatedMetadata)))
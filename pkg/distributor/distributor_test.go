@@ -595,6 +595,97 @@ func TestDistributor_PushIngestionRateLimiter(t *testing.T) {
 	}
 }
 
+func TestDistributor_PushRulerIngestionRateLimiter(t *testing.T) {
+	type testPush struct {
+		source        mimirpb.WriteRequest_SourceEnum
+		byRuler       bool
+		samples       int
+		expectedError error
+	}
+
+	ctx := user.InjectOrgID(context.Background(), "user")
+	tests := map[string]struct {
+		rulerIngestionRate      float64
+		rulerIngestionBurstSize int
+		pushes                  []testPush
+		expectedDiscarded       float64
+	}{
+		"rule results are subject to the tenant ingestion rate limit when the ruler ingestion rate limit is disabled": {
+			pushes: []testPush{
+				{source: mimirpb.RULE, samples: 4, expectedError: nil},
+				{source: mimirpb.RULE, samples: 2, expectedError: httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewIngestionRateLimitedError(5, 5).Error())},
+				{source: mimirpb.API, samples: 1, expectedError: nil},
+			},
+		},
+		"rule results are subject to the ruler ingestion rate limit only": {
+			rulerIngestionRate:      10,
+			rulerIngestionBurstSize: 10,
+			pushes: []testPush{
+				{source: mimirpb.API, samples: 5, expectedError: nil},
+				{source: mimirpb.RULE, byRuler: true, samples: 8, expectedError: nil},
+				{source: mimirpb.API, samples: 1, expectedError: httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewIngestionRateLimitedError(5, 5).Error())},
+				{source: mimirpb.RULE, byRuler: true, samples: 3, expectedError: httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewRulerIngestionRateLimitedError(10, 10).Error())},
+				{source: mimirpb.RULE, byRuler: true, samples: 2, expectedError: nil},
+			},
+			expectedDiscarded: 3,
+		},
+		"rule results not pushed by the ruler are subject to the tenant ingestion rate limit": {
+			rulerIngestionRate:      10,
+			rulerIngestionBurstSize: 10,
+			pushes: []testPush{
+				{source: mimirpb.RULE, samples: 4, expectedError: nil},
+				{source: mimirpb.RULE, samples: 2, expectedError: httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewIngestionRateLimitedError(5, 5).Error())},
+				{source: mimirpb.RULE, byRuler: true, samples: 8, expectedError: nil},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			limits := &validation.Limits{}
+			flagext.DefaultValues(limits)
+			limits.IngestionRate = 5
+			limits.IngestionBurstSize = 5
+			limits.RulerIngestionRate = testData.rulerIngestionRate
+			limits.RulerIngestionBurstSize = testData.rulerIngestionBurstSize
+
+			distributors, _, _ := prepare(t, prepConfig{
+				numIngesters:    3,
+				happyIngesters:  3,
+				numDistributors: 1,
+				limits:          limits,
+			})
+
+			validation.DiscardedSamples.DeleteLabelValues(validation.ReasonRulerRateLimited, "user")
+
+			for _, push := range testData.pushes {
+				request := makeWriteRequest(0, push.samples, 0, false)
+				request.Source = push.source
+
+				var pusher interface {
+					Push(context.Context, *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error)
+				} = distributors[0]
+				if push.byRuler {
+					pusher = NewRulerPusher(distributors[0])
+				}
+				response, err := pusher.Push(ctx, request)
+
+				if push.expectedError == nil {
+					assert.Equal(t, emptyResponse, response)
+					assert.Nil(t, err)
+				} else {
+					assert.Nil(t, response)
+					assert.Equal(t, push.expectedError, err)
+				}
+			}
+
+			assert.Equal(t, testData.expectedDiscarded, testutil.ToFloat64(validation.DiscardedSamples.WithLabelValues(validation.ReasonRulerRateLimited, "user")))
+		})
+	}
+}
+
 func TestDistributor_PushInstanceLimits(t *testing.T) {
 	type testPush struct {
 		samples       int
@@ -786,13 +877,16 @@ func TestDistributor_PushHAInstances(t *testing.T) {
 	ctx := user.InjectOrgID(context.Background(), "user")
 
 	for i, tc := range []struct {
-		enableTracker    bool
-		acceptedReplica  string
-		testReplica      string
-		cluster          string
-		samples          int
-		expectedResponse *mimirpb.WriteResponse
-		expectedCode     int32
+		enableTracker      bool
+		acceptedReplica    string
+		testReplica        string
+		cluster            string
+		samples            int
+		source             mimirpb.WriteRequest_SourceEnum
+		byRuler            bool
+		rulerIngestionRate float64
+		expectedResponse   *mimirpb.WriteResponse
+		expectedCode       int32
 	}{
 		{
 			enableTracker:    true,
@@ -830,12 +924,47 @@ func TestDistributor_PushHAInstances(t *testing.T) {
 			expectedResponse: emptyResponse,
 			expectedCode:     400,
 		},
+		// Rule results pushed by the ruler aren't deduplicated when the ruler ingestion rate limit is enabled.
+		{
+			enableTracker:      true,
+			acceptedReplica:    "instance2",
+			testReplica:        "instance0",
+			cluster:            "cluster0",
+			samples:            5,
+			source:             mimirpb.RULE,
+			byRuler:            true,
+			rulerIngestionRate: 10,
+			expectedResponse:   emptyResponse,
+		},
+		// Rule results pushed by the ruler are deduplicated when the ruler ingestion rate limit is disabled.
+		{
+			enableTracker:   true,
+			acceptedReplica: "instance2",
+			testReplica:     "instance0",
+			cluster:         "cluster0",
+			samples:         5,
+			source:          mimirpb.RULE,
+			byRuler:         true,
+			expectedCode:    202,
+		},
+		// Requests claiming to be rule results, but not pushed by the ruler, are deduplicated.
+		{
+			enableTracker:      true,
+			acceptedReplica:    "instance2",
+			testReplica:        "instance0",
+			cluster:            "cluster0",
+			samples:            5,
+			source:             mimirpb.RULE,
+			rulerIngestionRate: 10,
+			expectedCode:       202,
+		},
 	} {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			var limits validation.Limits
 			flagext.DefaultValues(&limits)
 			limits.AcceptHASamples = true
 			limits.MaxLabelValueLength = 15
+			limits.RulerIngestionRate = tc.rulerIngestionRate
 
 			ds, _, _ := prepare(t, prepConfig{
 				numIngesters:    3,
@@ -853,7 +982,15 @@ func TestDistributor_PushHAInstances(t *testing.T) {
 			assert.NoError(t, err)
 
 			request := makeWriteRequestHA(tc.samples, tc.testReplica, tc.cluster)
-			response, err := d.Push(ctx, request)
+			request.Source = tc.source
+
+			var pusher interface {
+				Push(context.Context, *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error)
+			} = d
+			if tc.byRuler {
+				pusher = NewRulerPusher(d)
+			}
+			response, err := pusher.Push(ctx, request)
 			assert.Equal(t, tc.expectedResponse, response)
 
 			httpResp, ok := httpgrpc.HTTPResponseFromError(err)
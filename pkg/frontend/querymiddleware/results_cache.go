@@ -144,6 +144,12 @@ func (PrometheusResponseExtractor) ResponseWithoutHeaders(resp Response) Respons
 	}
 }
 
+// TombstonesGenerationLoader returns the generation of the series deletion requests of a tenant, which changes
+// whenever a series deletion request is created or cancelled.
+type TombstonesGenerationLoader interface {
+	GetTombstonesGeneration(ctx context.Context, userID string) (string, error)
+}
+
 // CacheSplitter generates cache keys. This is a useful interface for downstream
 // consumers who wish to implement their own strategies.
 type CacheSplitter interface {
@@ -134,9 +134,10 @@ func NewTripperware(
 	codec Codec,
 	cacheExtractor Extractor,
 	engineOpts promql.EngineOpts,
+	tombstones TombstonesGenerationLoader,
 	registerer prometheus.Registerer,
 ) (Tripperware, error) {
-	queryRangeTripperware, err := newQueryTripperware(cfg, log, limits, codec, cacheExtractor, engineOpts, registerer)
+	queryRangeTripperware, err := newQueryTripperware(cfg, log, limits, codec, cacheExtractor, engineOpts, tombstones, registerer)
 	if err != nil {
 		return nil, err
 	}
@@ -153,6 +154,7 @@ func newQueryTripperware(
 	codec Codec,
 	cacheExtractor Extractor,
 	engineOpts promql.EngineOpts,
+	tombstones TombstonesGenerationLoader,
 	registerer prometheus.Registerer,
 ) (Tripperware, error) {
 	// Metric used to keep track of each middleware execution duration.
@@ -195,6 +197,7 @@ func newQueryTripperware(
 			codec,
 			c,
 			constSplitter(cfg.SplitQueriesByInterval),
+			tombstones,
 			cacheExtractor,
 			shouldCache,
 			log,
@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/hex"
 	"hash/fnv"
+	"strings"
 	"sync"
 	"time"
 
@@ -74,6 +75,7 @@ type splitAndCacheMiddleware struct {
 	cacheUnalignedRequests bool
 	cache                  cache.Cache
 	splitter               CacheSplitter
+	tombstones             TombstonesGenerationLoader
 	extractor              Extractor
 	shouldCacheReq         shouldCacheFn
 }
@@ -88,6 +90,7 @@ func newSplitAndCacheMiddleware(
 	merger Merger,
 	cache cache.Cache,
 	splitter CacheSplitter,
+	tombstones TombstonesGenerationLoader,
 	extractor Extractor,
 	shouldCacheReq shouldCacheFn,
 	logger log.Logger,
@@ -106,6 +109,7 @@ func newSplitAndCacheMiddleware(
 			metrics:                metrics,
 			cache:                  cache,
 			splitter:               splitter,
+			tombstones:             tombstones,
 			extractor:              extractor,
 			shouldCacheReq:         shouldCacheReq,
 			logger:                 logger,
@@ -130,6 +134,15 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 	maxCacheFreshness := validation.MaxDurationPerTenant(tenantIDs, s.limits.MaxCacheFreshness)
 	maxCacheTime := int64(model.Now().Add(-maxCacheFreshness))
 
+	// The results cached before a series deletion request of the tenants has been created or cancelled aren't used.
+	var tombstonesGeneration string
+	if isCacheEnabled {
+		if tombstonesGeneration, err = s.tombstonesGeneration(ctx, tenantIDs); err != nil {
+			level.Warn(s.logger).Log("msg", "failed to load the series deletion requests, the results cache is not used", "err", err)
+			isCacheEnabled = false
+		}
+	}
+
 	// Lookup the results cache.
 	if isCacheEnabled {
 		// Build the cache keys for all requests to try to fetch from cache.
@@ -144,6 +157,9 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 			}
 
 			splitReq.cacheKey = s.splitter.GenerateCacheKey(tenant.JoinTenantIDs(tenantIDs), splitReq.orig)
+			if tombstonesGeneration != "" {
+				splitReq.cacheKey += ":" + tombstonesGeneration
+			}
 			lookupKeys = append(lookupKeys, splitReq.cacheKey)
 			lookupReqs = append(lookupReqs, splitReq)
 		}
@@ -286,6 +302,30 @@ func (s *splitAndCacheMiddleware) splitRequestByInterval(req Request) (splitRequ
 	return out, nil
 }
 
+// tombstonesGeneration returns the generation of the series deletion requests of the tenants, which is appended to
+// the cache keys. It's empty if none of the tenants has series deletion requests, so that the keys don't change.
+func (s *splitAndCacheMiddleware) tombstonesGeneration(ctx context.Context, tenantIDs []string) (string, error) {
+	if s.tombstones == nil {
+		return "", nil
+	}
+
+	generations := make([]string, 0, len(tenantIDs))
+	empty := true
+	for _, tenantID := range tenantIDs {
+		generation, err := s.tombstones.GetTombstonesGeneration(ctx, tenantID)
+		if err != nil {
+			return "", err
+		}
+		generations = append(generations, generation)
+		empty = empty && generation == ""
+	}
+
+	if empty {
+		return "", nil
+	}
+	return strings.Join(generations, ","), nil
+}
+
 // fetchCacheExtents fetches the extents for the given key from the cache. The returned slice
 // is guaranteed to have the same length of the input keys. For each input key, the fetched
 // extents are stored in the returned slice at the same position. In case of error or cache miss,
@@ -93,6 +93,7 @@ func TestSplitAndCacheMiddleware_SplitByInterval(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
 		log.NewNopLogger(),
 		reg,
 	)
@@ -142,6 +143,7 @@ func TestSplitAndCacheMiddleware_ResultsCache(t *testing.T) {
 		PrometheusCodec,
 		cacheBackend,
 		constSplitter(day),
+		nil,
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
 		log.NewNopLogger(),
@@ -203,6 +205,80 @@ func TestSplitAndCacheMiddleware_ResultsCache(t *testing.T) {
 	assert.Equal(t, 2, cacheBackend.CountStoreCalls())
 }
 
+type staticTombstonesGenerationLoader map[string]string
+
+func (l staticTombstonesGenerationLoader) GetTombstonesGeneration(_ context.Context, userID string) (string, error) {
+	return l[userID], nil
+}
+
+func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotUseResultsCachedBeforeSeriesDeletionRequests(t *testing.T) {
+	cacheBackend := cache.NewInstrumentedMockCache()
+	tombstones := staticTombstonesGenerationLoader{}
+
+	mw := newSplitAndCacheMiddleware(
+		true,
+		true,
+		24*time.Hour,
+		false,
+		mockLimits{maxCacheFreshness: 10 * time.Minute},
+		PrometheusCodec,
+		cacheBackend,
+		constSplitter(day),
+		tombstones,
+		PrometheusResponseExtractor{},
+		resultsCacheAlwaysEnabled,
+		log.NewNopLogger(),
+		prometheus.NewPedanticRegistry(),
+	)
+
+	expectedResponse := &PrometheusResponse{
+		Status: "success",
+		Data: &PrometheusData{
+			ResultType: model.ValMatrix.String(),
+			Result: []SampleStream{
+				{
+					Labels:  []mimirpb.LabelAdapter{{Name: "foo", Value: "bar"}},
+					Samples: []mimirpb.Sample{{Value: 137, TimestampMs: 1634292000000}},
+				},
+			},
+		},
+	}
+
+	downstreamReqs := 0
+	rc := mw.Wrap(HandlerFunc(func(_ context.Context, req Request) (Response, error) {
+		downstreamReqs++
+		return expectedResponse, nil
+	}))
+
+	req := Request(&PrometheusRangeQueryRequest{
+		Path:  "/api/v1/query_range",
+		Start: parseTimeRFC3339(t, "2021-10-15T10:00:00Z").Unix() * 1000,
+		End:   parseTimeRFC3339(t, "2021-10-15T12:00:00Z").Unix() * 1000,
+		Step:  120 * 1000,
+		Query: `{__name__=~".+"}`,
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "1")
+	_, err := rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, 1, downstreamReqs)
+
+	// The cached results are used while there's no new series deletion request.
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, 1, downstreamReqs)
+
+	// The results cached before a series deletion request are not used.
+	tombstones["1"] = "generation-1"
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, 2, downstreamReqs)
+
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, 2, downstreamReqs)
+}
+
 func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotLookupCacheIfStepIsNotAligned(t *testing.T) {
 	cacheBackend := cache.NewInstrumentedMockCache()
 
@@ -215,6 +291,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotLookupCacheIfStepIsNotAli
 		PrometheusCodec,
 		cacheBackend,
 		constSplitter(day),
+		nil,
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
 		log.NewNopLogger(),
@@ -276,6 +353,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_EnabledCachingOfStepUnalignedReque
 		PrometheusCodec,
 		cacheBackend,
 		constSplitter(day),
+		nil,
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
 		log.NewNopLogger(),
@@ -406,6 +484,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotCacheRequestEarlierThanMa
 				PrometheusCodec,
 				cacheBackend,
 				cacheSplitter,
+				nil,
 				PrometheusResponseExtractor{},
 				resultsCacheAlwaysEnabled,
 				log.NewNopLogger(),
@@ -614,6 +693,7 @@ func TestSplitAndCacheMiddleware_ResultsCacheFuzzy(t *testing.T) {
 					PrometheusCodec,
 					cache.NewMockCache(),
 					constSplitter(day),
+					nil,
 					PrometheusResponseExtractor{},
 					resultsCacheAlwaysEnabled,
 					log.NewNopLogger(),
@@ -850,6 +930,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ExtentsEdgeCases(t *testing.T) {
 				PrometheusCodec,
 				cacheBackend,
 				cacheSplitter,
+				nil,
 				PrometheusResponseExtractor{},
 				resultsCacheAlwaysEnabled,
 				log.NewNopLogger(),
@@ -895,6 +976,7 @@ func TestSplitAndCacheMiddleware_StoreAndFetchCacheExtents(t *testing.T) {
 		PrometheusCodec,
 		cacheBackend,
 		constSplitter(day),
+		nil,
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
 		log.NewNopLogger(),
@@ -942,6 +1024,7 @@ func TestSplitAndCacheMiddleware_WrapMultipleTimes(t *testing.T) {
 		PrometheusCodec,
 		cache.NewMockCache(),
 		constSplitter(day),
+		nil,
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
 		log.NewNopLogger(),
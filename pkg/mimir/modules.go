@@ -49,7 +49,10 @@ import (
 	"github.com/grafana/mimir/pkg/querier/tenantfederation"
 	querier_worker "github.com/grafana/mimir/pkg/querier/worker"
 	"github.com/grafana/mimir/pkg/ruler"
+	ruler_tenantfederation "github.com/grafana/mimir/pkg/ruler/tenantfederation"
 	"github.com/grafana/mimir/pkg/scheduler"
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
@@ -313,6 +316,7 @@ func (t *Mimir) initQueryable() (serv services.Service, err error) {
 
 	// Create a querier queryable and PromQL engine
 	t.QuerierQueryable, t.ExemplarQueryable, t.QuerierEngine = querier.New(t.Cfg.Querier, t.Overrides, t.Distributor, t.StoreQueryables, querierRegisterer, util_log.Logger, t.ActivityTracker)
+	t.QuerierQueryable = querier.NewSampleAndChunkQueryable(querier.NewDeletedSeriesQueryable(t.QuerierQueryable, t.TombstonesLoader))
 
 	// Use the distributor to return metric metadata by default
 	t.MetadataSupplier = t.Distributor
@@ -453,6 +457,12 @@ func (t *Mimir) initStoreQueryables() (services.Service, error) {
 		servs = append(servs, q)
 	}
 
+	loader, err := querier.NewBucketTombstonesLoaderFromConfig(t.Cfg.BlocksStorage, util_log.Logger, prometheus.DefaultRegisterer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize series deletion requests loader: %v", err)
+	}
+	t.TombstonesLoader = loader
+
 	// Return service, if any.
 	switch len(servs) {
 	case 0:
@@ -518,6 +528,17 @@ func (t *Mimir) initFlusher() (serv services.Service, err error) {
 func (t *Mimir) initQueryFrontendTripperware() (serv services.Service, err error) {
 	promqlEngineRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "query-frontend"}, prometheus.DefaultRegisterer)
 
+	// The results cached before a series deletion request has been created or cancelled are not used.
+	var tombstones querymiddleware.TombstonesGenerationLoader
+	if t.Cfg.Frontend.QueryMiddleware.CacheResults {
+		reg := prometheus.WrapRegistererWith(prometheus.Labels{"component": "query-frontend"}, prometheus.DefaultRegisterer)
+		bkt, err := bucket.NewClient(context.Background(), t.Cfg.BlocksStorage.Bucket, "query-frontend-tombstones", util_log.Logger, reg)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create the series deletion requests bucket client")
+		}
+		tombstones = tsdb.NewBucketTombstonesLoader(bkt, tsdb.TombstonesCacheTTL, util_log.Logger, reg)
+	}
+
 	tripperware, err := querymiddleware.NewTripperware(
 		t.Cfg.Frontend.QueryMiddleware,
 		util_log.Logger,
@@ -525,6 +546,7 @@ func (t *Mimir) initQueryFrontendTripperware() (serv services.Service, err error
 		querymiddleware.PrometheusCodec,
 		querymiddleware.PrometheusResponseExtractor{},
 		engine.NewPromQLEngineOptions(t.Cfg.Querier.EngineConfig, t.ActivityTracker, util_log.Logger, promqlEngineRegisterer),
+		tombstones,
 		prometheus.DefaultRegisterer,
 	)
 	if err != nil {
@@ -606,6 +628,7 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 		rulerRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "ruler"}, prometheus.DefaultRegisterer)
 
 		queryable, _, eng := querier.New(t.Cfg.Querier, t.Overrides, t.Distributor, t.StoreQueryables, rulerRegisterer, util_log.Logger, t.ActivityTracker)
+		queryable = querier.NewDeletedSeriesQueryable(queryable, t.TombstonesLoader)
 		queryable = querier.NewErrorTranslateQueryableWithFn(queryable, ruler.WrapQueryableErrors)
 
 		if t.Cfg.Ruler.TenantFederation.Enabled {
@@ -623,7 +646,7 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 			federatedQueryFunc := rules.EngineQueryFunc(eng, federatedQueryable)
 
 			embeddedQueryable = federatedQueryable
-			queryFunc = ruler.TenantFederationQueryFunc(regularQueryFunc, federatedQueryFunc)
+			queryFunc = ruler_tenantfederation.QueryFunc(regularQueryFunc, federatedQueryFunc)
 
 		} else {
 			embeddedQueryable = queryable
@@ -632,7 +655,7 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 	}
 	managerFactory := ruler.DefaultTenantManagerFactory(
 		t.Cfg.Ruler,
-		t.Distributor,
+		distributor.NewRulerPusher(t.Distributor),
 		embeddedQueryable,
 		queryFunc,
 		t.Overrides,
@@ -671,7 +694,8 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 	t.API.RegisterRuler(t.Ruler)
 
 	// Expose HTTP configuration and prometheus-compatible Ruler APIs
-	t.API.RegisterRulerAPI(ruler.NewAPI(t.Ruler, t.RulerStorage, util_log.Logger), t.Cfg.Ruler.EnableAPI)
+	tester := ruler.NewRuleGroupTester(t.Cfg.Ruler, queryFunc, t.Overrides, util_log.Logger)
+	t.API.RegisterRulerAPI(ruler.NewAPI(t.Ruler, t.RulerStorage, tester, util_log.Logger), t.Cfg.Ruler.EnableAPI)
 
 	return t.Ruler, nil
 }
@@ -756,7 +780,13 @@ func (t *Mimir) initTenantDeletionAPI() (services.Service, error) {
 		return nil, err
 	}
 
+	seriesDeletionAPI, err := purger.NewSeriesDeletionAPI(t.Cfg.BlocksStorage, t.Overrides, util_log.Logger, prometheus.DefaultRegisterer)
+	if err != nil {
+		return nil, err
+	}
+
 	t.API.RegisterTenantDeletion(tenantDeletionAPI)
+	t.API.RegisterSeriesDeletion(seriesDeletionAPI)
 	return nil, nil
 }
 
@@ -7,6 +7,7 @@ package mimir
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/grafana/dskit/runtimeconfig"
 	"gopkg.in/yaml.v3"
 
+	"github.com/grafana/mimir/pkg/compactor"
 	"github.com/grafana/mimir/pkg/ingester"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -79,9 +81,28 @@ func loadRuntimeConfig(r io.Reader) (interface{}, error) {
 		return nil, errMultipleDocuments
 	}
 
+	// Reject the per-tenant limits the compactor can't apply, so that the previous runtime config is kept.
+	for userID, limits := range overrides.TenantLimits {
+		if limits == nil {
+			continue
+		}
+		if err := compactor.ValidateDownsamplingResolutions(limits.CompactorDownsamplingResolutions); err != nil {
+			return nil, fmt.Errorf("invalid compactor_downsampling_resolutions in overrides of tenant %s: %w", userID, err)
+		}
+	}
+
 	return overrides, nil
 }
 
+// ParseRuntimeConfigOverrides parses the runtime configuration, and returns the limits overridden for each tenant.
+func ParseRuntimeConfigOverrides(r io.Reader) (map[string]*validation.Limits, error) {
+	cfg, err := loadRuntimeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.(*runtimeConfigValues).TenantLimits, nil
+}
+
 func multiClientRuntimeConfigChannel(manager *runtimeconfig.Manager) func() <-chan kv.MultiRuntimeConfig {
 	if manager == nil {
 		return nil
@@ -113,3 +113,23 @@ overrides:
 		assert.Nil(t, actual)
 	}
 }
+
+func TestLoadRuntimeConfig_ShouldReturnErrorOnUnsupportedDownsamplingResolutions(t *testing.T) {
+	validation.SetDefaultLimitsForYAMLUnmarshalling(validation.Limits{})
+
+	actual, err := loadRuntimeConfig(strings.NewReader(`
+overrides:
+  '1234':
+    compactor_downsampling_resolutions: [5m, 1h]
+`))
+	require.NoError(t, err)
+	assert.Len(t, actual.(*runtimeConfigValues).TenantLimits["1234"].CompactorDownsamplingResolutions, 2)
+
+	actual, err = loadRuntimeConfig(strings.NewReader(`
+overrides:
+  '1234':
+    compactor_downsampling_resolutions: [5m, 10m]
+`))
+	require.EqualError(t, err, "invalid compactor_downsampling_resolutions in overrides of tenant 1234: unsupported downsampling resolution 10m0s (supported values: 5m, 1h)")
+	assert.Nil(t, actual)
+}
@@ -8,14 +8,19 @@ package client
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
+	"net/url"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 )
 
-const alertmanagerAPIPath = "/api/v1/alerts"
+const (
+	alertmanagerAPIPath   = "/api/v1/alerts"
+	alertmanagerStatePath = "/multitenant_alertmanager/state"
+)
 
 type configCompat struct {
 	TemplateFiles      map[string]string `yaml:"template_files"`
@@ -80,3 +85,49 @@ func (r *MimirClient) GetAlertmanagerConfig(ctx context.Context) (string, map[st
 
 	return compat.AlertmanagerConfig, compat.TemplateFiles, nil
 }
+
+// AlertmanagerStateImportResult is the number of silences and notification log entries imported and skipped
+// by the import of an Alertmanager state.
+type AlertmanagerStateImportResult struct {
+	Silences        AlertmanagerStateImportCounts `json:"silences"`
+	NotificationLog AlertmanagerStateImportCounts `json:"notification_log"`
+}
+
+// AlertmanagerStateImportCounts is the number of imported and skipped entries of a part of an Alertmanager state.
+type AlertmanagerStateImportCounts struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+// GetAlertmanagerState exports the silences and the notification log of the user's Alertmanager.
+func (r *MimirClient) GetAlertmanagerState(ctx context.Context) ([]byte, error) {
+	res, err := r.doRequest(alertmanagerStatePath, "GET", nil, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+// ImportAlertmanagerState imports the silences and the notification log exported from an Alertmanager
+// into the user's Alertmanager, handling the conflicting silences according to conflict.
+func (r *MimirClient) ImportAlertmanagerState(ctx context.Context, state []byte, conflict string) (AlertmanagerStateImportResult, error) {
+	result := AlertmanagerStateImportResult{}
+
+	res, err := r.doRequest(alertmanagerStatePath+"?conflict="+url.QueryEscape(conflict), "POST", bytes.NewReader(state), int64(len(state)))
+	if err != nil {
+		return result, err
+	}
+
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return result, err
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return result, errors.Wrap(err, "unable to unmarshal response")
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMimirClient_AlertmanagerState(t *testing.T) {
+	var (
+		receivedMethod, receivedURL, receivedTenant string
+		receivedBody                                []byte
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedMethod, receivedURL, receivedTenant = r.Method, r.URL.String(), r.Header.Get("X-Scope-OrgID")
+
+		var err error
+		receivedBody, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte("state"))
+		case http.MethodPost:
+			_, _ = w.Write([]byte(`{"silences":{"imported":2,"skipped":1},"notification_log":{"imported":3,"skipped":0}}`))
+		}
+	}))
+	defer ts.Close()
+
+	client, err := New(Config{
+		Address: ts.URL,
+		ID:      "my-id",
+	})
+	require.NoError(t, err)
+
+	state, err := client.GetAlertmanagerState(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("state"), state)
+	assert.Equal(t, http.MethodGet, receivedMethod)
+	assert.Equal(t, "/multitenant_alertmanager/state", receivedURL)
+	assert.Equal(t, "my-id", receivedTenant)
+
+	result, err := client.ImportAlertmanagerState(context.Background(), state, "overwrite")
+	require.NoError(t, err)
+	assert.Equal(t, AlertmanagerStateImportResult{
+		Silences:        AlertmanagerStateImportCounts{Imported: 2, Skipped: 1},
+		NotificationLog: AlertmanagerStateImportCounts{Imported: 3},
+	}, result)
+	assert.Equal(t, http.MethodPost, receivedMethod)
+	assert.Equal(t, "/multitenant_alertmanager/state?conflict=overwrite", receivedURL)
+	assert.Equal(t, []byte("state"), receivedBody)
+}
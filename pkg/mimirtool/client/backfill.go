@@ -12,6 +12,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -19,13 +20,15 @@ import (
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 )
 
-func (c *MimirClient) Backfill(blocks []string) error {
+// Backfill uploads the blocks in the input directories. If partSize is greater than zero, the block
+// files are uploaded in parts of at most partSize bytes. Uploads interrupted in a previous run are resumed.
+func (c *MimirClient) Backfill(blocks []string, partSize int64) error {
 	// Upload each block
 	var succeeded, failed, alreadyExists int
 
 	for _, b := range blocks {
 		logctx := logrus.WithFields(logrus.Fields{"path": b})
-		if err := c.backfillBlock(b, logctx); err != nil {
+		if err := c.backfillBlock(b, partSize, logctx); err != nil {
 			if errors.Is(err, errConflict) {
 				logctx.Warning("block already exists on the server")
 				alreadyExists++
@@ -55,7 +58,20 @@ func drainAndCloseBody(resp *http.Response) {
 	_ = resp.Body.Close()
 }
 
-func (c *MimirClient) backfillBlock(blockDir string, logctx *logrus.Entry) error {
+// blockUploadStatusPollInterval is how often the status of a block upload is checked while the block is validated.
+var blockUploadStatusPollInterval = 5 * time.Second
+
+// blockUploadState is the state of a block upload returned by the block upload status endpoint.
+type blockUploadState struct {
+	State string `json:"state"`
+	Error string `json:"error"`
+	Files []struct {
+		Path          string `json:"path"`
+		UploadedBytes int64  `json:"uploaded_bytes"`
+	} `json:"files"`
+}
+
+func (c *MimirClient) backfillBlock(blockDir string, partSize int64, logctx *logrus.Entry) error {
 	// blockMeta returned by getBlockMeta will have thanos.files section pre-populated.
 	blockMeta, err := getBlockMeta(blockDir)
 	if err != nil {
@@ -65,24 +81,44 @@ func (c *MimirClient) backfillBlock(blockDir string, logctx *logrus.Entry) error
 	blockID := blockMeta.ULID.String()
 	logctx = logctx.WithFields(logrus.Fields{"block": blockID})
 
-	logctx.WithField("file", "meta.json").Info("making request to start block upload")
-
 	const (
 		endpointPrefix    = "/api/v1/upload/block"
 		startBlockUpload  = "start"
 		uploadFile        = "files"
 		finishBlockUpload = "finish"
+		blockUploadStatus = "status"
 	)
 
-	buf := bytes.NewBuffer(nil)
-	if err := json.NewEncoder(buf).Encode(blockMeta); err != nil {
-		return errors.Wrap(err, "failed to JSON encode payload")
-	}
-	resp, err := c.doRequest(path.Join(endpointPrefix, url.PathEscape(blockID), startBlockUpload), http.MethodPost, buf, int64(buf.Len()))
-	if err != nil {
-		return errors.Wrap(err, "request to start block upload failed")
+	// Resume the upload of the block if it has been started in a previous run.
+	uploadedBytes := map[string]int64{}
+	statusEndpoint := path.Join(endpointPrefix, url.PathEscape(blockID), blockUploadStatus)
+	state, err := c.getBlockUploadState(statusEndpoint)
+	switch {
+	case errors.Is(err, ErrResourceNotFound):
+		logctx.WithField("file", "meta.json").Info("making request to start block upload")
+
+		buf := bytes.NewBuffer(nil)
+		if err := json.NewEncoder(buf).Encode(blockMeta); err != nil {
+			return errors.Wrap(err, "failed to JSON encode payload")
+		}
+		resp, err := c.doRequest(path.Join(endpointPrefix, url.PathEscape(blockID), startBlockUpload), http.MethodPost, buf, int64(buf.Len()))
+		if err != nil {
+			return errors.Wrap(err, "request to start block upload failed")
+		}
+		drainAndCloseBody(resp)
+	case err != nil:
+		return errors.Wrap(err, "request to get block upload status failed")
+	case state.State == "complete":
+		return errConflict
+	case state.State == "validating":
+		logctx.Info("block upload has already been finished, waiting for the block validation")
+		return c.waitBlockUploadCompletion(statusEndpoint, logctx)
+	default:
+		logctx.Info("resuming block upload")
+		for _, f := range state.Files {
+			uploadedBytes[f.Path] = f.UploadedBytes
+		}
 	}
-	drainAndCloseBody(resp)
 
 	// Upload each block file
 	for _, tf := range blockMeta.Thanos.Files {
@@ -91,23 +127,70 @@ func (c *MimirClient) backfillBlock(blockDir string, logctx *logrus.Entry) error
 			continue
 		}
 
-		if err := c.uploadBlockFile(tf, blockDir, path.Join(endpointPrefix, url.PathEscape(blockID), uploadFile), logctx); err != nil {
+		if err := c.uploadBlockFile(tf, blockDir, path.Join(endpointPrefix, url.PathEscape(blockID), uploadFile), uploadedBytes[tf.RelPath], partSize, logctx); err != nil {
 			return err
 		}
 	}
 
-	resp, err = c.doRequest(path.Join(endpointPrefix, url.PathEscape(blockID), finishBlockUpload), http.MethodPost, nil, -1)
+	resp, err := c.doRequest(path.Join(endpointPrefix, url.PathEscape(blockID), finishBlockUpload), http.MethodPost, nil, -1)
 	if err != nil {
 		return errors.Wrap(err, "request to finish block upload failed")
 	}
 	drainAndCloseBody(resp)
 
-	logctx.Info("block uploaded successfully")
+	// The block may be validated by the server before being completed.
+	return c.waitBlockUploadCompletion(statusEndpoint, logctx)
+}
 
-	return nil
+// waitBlockUploadCompletion polls the status of a finished block upload until the server has completed the
+// block, and returns an error if the validation of the block failed.
+func (c *MimirClient) waitBlockUploadCompletion(statusEndpoint string, logctx *logrus.Entry) error {
+	for {
+		state, err := c.getBlockUploadState(statusEndpoint)
+		if err != nil {
+			return errors.Wrap(err, "request to get block upload status failed")
+		}
+
+		switch state.State {
+		case "complete":
+			logctx.Info("block uploaded successfully")
+			return nil
+		case "failed":
+			return errors.Errorf("block upload failed: %s", state.Error)
+		case "validating":
+			logctx.Info("waiting for block validation to complete")
+		default:
+			return errors.Errorf("unexpected block upload state %q after finishing the upload", state.State)
+		}
+
+		time.Sleep(blockUploadStatusPollInterval)
+	}
+}
+
+func (c *MimirClient) getBlockUploadState(statusEndpoint string) (blockUploadState, error) {
+	var state blockUploadState
+
+	resp, err := c.doRequest(statusEndpoint, http.MethodGet, nil, -1)
+	if err != nil {
+		return state, err
+	}
+	defer drainAndCloseBody(resp)
+
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return state, errors.Wrap(err, "failed to decode block upload status")
+	}
+	return state, nil
 }
 
-func (c *MimirClient) uploadBlockFile(tf metadata.File, blockDir, fileUploadEndpoint string, logctx *logrus.Entry) error {
+// uploadBlockFile uploads a block file, starting from the input offset. If partSize is greater than zero
+// or the upload is resumed, the file is uploaded in parts.
+func (c *MimirClient) uploadBlockFile(tf metadata.File, blockDir, fileUploadEndpoint string, offset, partSize int64, logctx *logrus.Entry) error {
+	logctx = logctx.WithFields(logrus.Fields{"file": tf.RelPath, "size": tf.SizeBytes})
+	if offset >= tf.SizeBytes {
+		logctx.Info("block file already uploaded")
+		return nil
+	}
+
 	pth := filepath.Join(blockDir, filepath.FromSlash(tf.RelPath))
 	f, err := os.Open(pth)
 	if err != nil {
@@ -117,13 +200,35 @@ func (c *MimirClient) uploadBlockFile(tf metadata.File, blockDir, fileUploadEndp
 		_ = f.Close()
 	}()
 
-	logctx.WithFields(logrus.Fields{"file": tf.RelPath, "size": tf.SizeBytes}).Info("uploading block file")
+	if offset == 0 && partSize <= 0 {
+		logctx.Info("uploading block file")
 
-	resp, err := c.doRequest(fmt.Sprintf("%s?path=%s", fileUploadEndpoint, url.QueryEscape(tf.RelPath)), http.MethodPost, f, tf.SizeBytes)
-	if err != nil {
-		return errors.Wrapf(err, "request to upload file %q failed", pth)
+		resp, err := c.doRequest(fmt.Sprintf("%s?path=%s", fileUploadEndpoint, url.QueryEscape(tf.RelPath)), http.MethodPost, f, tf.SizeBytes)
+		if err != nil {
+			return errors.Wrapf(err, "request to upload file %q failed", pth)
+		}
+		drainAndCloseBody(resp)
+
+		return nil
+	}
+
+	logctx.WithField("offset", offset).Info("uploading block file in parts")
+
+	for offset < tf.SizeBytes {
+		size := tf.SizeBytes - offset
+		if partSize > 0 && size > partSize {
+			size = partSize
+		}
+
+		resp, err := c.doRequest(fmt.Sprintf("%s?path=%s&offset=%d", fileUploadEndpoint, url.QueryEscape(tf.RelPath), offset), http.MethodPost, io.NewSectionReader(f, offset, size), size)
+		if err != nil {
+			return errors.Wrapf(err, "request to upload part of file %q at offset %d failed", pth, offset)
+		}
+		drainAndCloseBody(resp)
+
+		offset += size
+		logctx.WithField("progress", fmt.Sprintf("%.1f%%", float64(offset)*100/float64(tf.SizeBytes))).Info("uploaded block file part")
 	}
-	drainAndCloseBody(resp)
 
 	return nil
 }
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMimirClient_WaitBlockUploadCompletion(t *testing.T) {
+	defer func(interval time.Duration) {
+		blockUploadStatusPollInterval = interval
+	}(blockUploadStatusPollInterval)
+	blockUploadStatusPollInterval = time.Millisecond
+
+	tests := map[string]struct {
+		states        []string
+		expectedError string
+	}{
+		"validation succeeded": {
+			states: []string{`{"state": "validating"}`, `{"state": "validating"}`, `{"state": "complete"}`},
+		},
+		"validation failed": {
+			states:        []string{`{"state": "validating"}`, `{"state": "failed", "error": "block validation failed: invalid index"}`},
+			expectedError: "block upload failed: block validation failed: invalid index",
+		},
+		"validation disabled": {
+			states: []string{`{"state": "complete"}`},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			requests := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v1/upload/block/block-id/status", r.URL.Path)
+				_, _ = w.Write([]byte(testData.states[requests]))
+				requests++
+			}))
+			defer ts.Close()
+
+			client, err := New(Config{
+				Address: ts.URL,
+				ID:      "my-id",
+			})
+			require.NoError(t, err)
+
+			err = client.waitBlockUploadCompletion("/api/v1/upload/block/block-id/status", logrus.NewEntry(logrus.New()))
+			if testData.expectedError != "" {
+				require.EqualError(t, err, testData.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, len(testData.states), requests)
+		})
+	}
+}
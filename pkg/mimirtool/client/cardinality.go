@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	labelNamesCardinalityPath  = "/prometheus/api/v1/cardinality/label_names"
+	labelValuesCardinalityPath = "/prometheus/api/v1/cardinality/label_values"
+)
+
+// LabelNamesCardinality is the response of the label names cardinality API.
+type LabelNamesCardinality struct {
+	LabelValuesCountTotal int                         `json:"label_values_count_total"`
+	LabelNamesCount       int                         `json:"label_names_count"`
+	Cardinality           []LabelNamesCardinalityItem `json:"cardinality"`
+}
+
+// LabelNamesCardinalityItem is the number of values of a label name.
+type LabelNamesCardinalityItem struct {
+	LabelName        string `json:"label_name"`
+	LabelValuesCount int    `json:"label_values_count"`
+}
+
+// LabelValuesCardinality is the response of the label values cardinality API.
+type LabelValuesCardinality struct {
+	SeriesCountTotal uint64                        `json:"series_count_total"`
+	Labels           []LabelValuesCardinalityLabel `json:"labels"`
+}
+
+// LabelValuesCardinalityLabel is the number of series of a label name, and of its values with the most series.
+type LabelValuesCardinalityLabel struct {
+	LabelName        string                        `json:"label_name"`
+	LabelValuesCount uint64                        `json:"label_values_count"`
+	SeriesCount      uint64                        `json:"series_count"`
+	Cardinality      []LabelValuesCardinalityValue `json:"cardinality"`
+}
+
+// LabelValuesCardinalityValue is the number of series of a label value.
+type LabelValuesCardinalityValue struct {
+	LabelValue  string `json:"label_value"`
+	SeriesCount uint64 `json:"series_count"`
+}
+
+// LabelNamesCardinality returns the label names with the most values, among the series matching the selector,
+// which can be empty.
+func (r *MimirClient) LabelNamesCardinality(ctx context.Context, selector string, limit int) (*LabelNamesCardinality, error) {
+	params := url.Values{}
+	if selector != "" {
+		params.Set("selector", selector)
+	}
+	params.Set("limit", strconv.Itoa(limit))
+
+	result := &LabelNamesCardinality{}
+	if err := r.getCardinality(labelNamesCardinalityPath, params, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LabelValuesCardinality returns the number of series of the label names, and of their values with the most
+// series, among the series matching the selector, which can be empty.
+func (r *MimirClient) LabelValuesCardinality(ctx context.Context, labelNames []string, selector string, limit int) (*LabelValuesCardinality, error) {
+	params := url.Values{}
+	for _, name := range labelNames {
+		params.Add("label_names[]", name)
+	}
+	if selector != "" {
+		params.Set("selector", selector)
+	}
+	params.Set("limit", strconv.Itoa(limit))
+
+	result := &LabelValuesCardinality{}
+	if err := r.getCardinality(labelValuesCardinalityPath, params, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *MimirClient) getCardinality(path string, params url.Values, result interface{}) error {
+	res, err := r.doRequest(path+"?"+params.Encode(), "GET", nil, -1)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return errors.Wrap(err, "unable to decode the cardinality response")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMimirClient_Cardinality(t *testing.T) {
+	var receivedPath, receivedQuery, receivedTenant string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath, receivedQuery, receivedTenant = r.URL.Path, r.URL.RawQuery, r.Header.Get("X-Scope-OrgID")
+
+		switch r.URL.Path {
+		case "/prometheus/api/v1/cardinality/label_names":
+			_, _ = w.Write([]byte(`{"label_values_count_total":3,"label_names_count":2,"cardinality":[{"label_name":"__name__","label_values_count":2},{"label_name":"job","label_values_count":1}]}`))
+		case "/prometheus/api/v1/cardinality/label_values":
+			_, _ = w.Write([]byte(`{"series_count_total":5,"labels":[{"label_name":"job","label_values_count":2,"series_count":4,"cardinality":[{"label_value":"api","series_count":3},{"label_value":"db","series_count":1}]}]}`))
+		}
+	}))
+	defer ts.Close()
+
+	client, err := New(Config{
+		Address: ts.URL,
+		ID:      "my-id",
+	})
+	require.NoError(t, err)
+
+	names, err := client.LabelNamesCardinality(context.Background(), `{job="api"}`, 10)
+	require.NoError(t, err)
+	assert.Equal(t, &LabelNamesCardinality{
+		LabelValuesCountTotal: 3,
+		LabelNamesCount:       2,
+		Cardinality:           []LabelNamesCardinalityItem{{LabelName: "__name__", LabelValuesCount: 2}, {LabelName: "job", LabelValuesCount: 1}},
+	}, names)
+	assert.Equal(t, "/prometheus/api/v1/cardinality/label_names", receivedPath)
+	assert.Equal(t, "limit=10&selector=%7Bjob%3D%22api%22%7D", receivedQuery)
+	assert.Equal(t, "my-id", receivedTenant)
+
+	values, err := client.LabelValuesCardinality(context.Background(), []string{"job", "instance"}, "", 5)
+	require.NoError(t, err)
+	assert.Equal(t, &LabelValuesCardinality{
+		SeriesCountTotal: 5,
+		Labels: []LabelValuesCardinalityLabel{{
+			LabelName:        "job",
+			LabelValuesCount: 2,
+			SeriesCount:      4,
+			Cardinality:      []LabelValuesCardinalityValue{{LabelValue: "api", SeriesCount: 3}, {LabelValue: "db", SeriesCount: 1}},
+		}},
+	}, values)
+	assert.Equal(t, "/prometheus/api/v1/cardinality/label_values", receivedPath)
+	assert.Equal(t, "label_names%5B%5D=job&label_names%5B%5D=instance&limit=5", receivedQuery)
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	configPath        = "/config"
+	runtimeConfigPath = "/runtime_config"
+)
+
+// GetConfig returns the configuration of Grafana Mimir, in YAML.
+func (r *MimirClient) GetConfig(ctx context.Context) ([]byte, error) {
+	return r.getYAML(configPath)
+}
+
+// GetRuntimeConfig returns the runtime configuration of Grafana Mimir, in YAML. With the "diff" mode, only
+// the values which differ from the defaults are returned.
+func (r *MimirClient) GetRuntimeConfig(ctx context.Context, mode string) ([]byte, error) {
+	path := runtimeConfigPath
+	if mode != "" {
+		path += "?" + url.Values{"mode": []string{mode}}.Encode()
+	}
+	return r.getYAML(path)
+}
+
+func (r *MimirClient) getYAML(path string) ([]byte, error) {
+	res, err := r.doRequest(path, "GET", nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read the response of %s", path)
+	}
+	return body, nil
+}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMimirClient_RuntimeConfig(t *testing.T) {
+	var receivedPath, receivedQuery string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath, receivedQuery = r.URL.Path, r.URL.RawQuery
+
+		switch r.URL.Path {
+		case "/config":
+			_, _ = w.Write([]byte("limits:\n  ingestion_rate: 10000\n"))
+		case "/runtime_config":
+			_, _ = w.Write([]byte("overrides:\n  tenant-1:\n    ingestion_rate: 20000\n"))
+		}
+	}))
+	defer ts.Close()
+
+	client, err := New(Config{
+		Address: ts.URL,
+		ID:      "my-id",
+	})
+	require.NoError(t, err)
+
+	config, err := client.GetConfig(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "limits:\n  ingestion_rate: 10000\n", string(config))
+	assert.Equal(t, "/config", receivedPath)
+
+	runtimeConfig, err := client.GetRuntimeConfig(context.Background(), "diff")
+	require.NoError(t, err)
+	assert.Equal(t, "overrides:\n  tenant-1:\n    ingestion_rate: 20000\n", string(runtimeConfig))
+	assert.Equal(t, "/runtime_config", receivedPath)
+	assert.Equal(t, "mode=diff", receivedQuery)
+
+	_, err = client.GetRuntimeConfig(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "", receivedQuery)
+}
@@ -45,6 +45,8 @@ type AlertmanagerCommand struct {
 	AlertmanagerConfigFile string
 	TemplateFiles          []string
 	DisableColor           bool
+	StateFile              string
+	StateImportConflict    string
 
 	cli *client.MimirClient
 }
@@ -82,6 +84,13 @@ func (a *AlertmanagerCommand) Register(app *kingpin.Application, envVars EnvVarN
 	loadalertCmd := alertCmd.Command("load", "Load a set of rules to a designated Grafana Mimir endpoint").Action(a.loadConfig)
 	loadalertCmd.Arg("config", "alertmanager configuration to load").Required().StringVar(&a.AlertmanagerConfigFile)
 	loadalertCmd.Arg("template-files", "The template files to load").ExistingFilesVar(&a.TemplateFiles)
+
+	exportStateCmd := alertCmd.Command("export-state", "Export the silences and the notification log of the Grafana Mimir Alertmanager to a file.").Action(a.exportState)
+	exportStateCmd.Arg("file", "The file to write the Alertmanager state to").Required().StringVar(&a.StateFile)
+
+	importStateCmd := alertCmd.Command("import-state", "Import the silences and the notification log exported from a Grafana Mimir Alertmanager into the Grafana Mimir Alertmanager. The state can be imported into another tenant or another cluster.").Action(a.importState)
+	importStateCmd.Arg("file", "The file to read the Alertmanager state from").Required().ExistingFileVar(&a.StateFile)
+	importStateCmd.Flag("conflict", "How to handle the imported silences having the same ID as an existing silence: merge keeps the most recently updated silence, overwrite keeps the imported silence, and skip keeps the existing silence.").Default("merge").EnumVar(&a.StateImportConflict, "merge", "overwrite", "skip")
 }
 
 func (a *AlertmanagerCommand) setup(k *kingpin.ParseContext) error {
@@ -133,6 +142,40 @@ func (a *AlertmanagerCommand) loadConfig(k *kingpin.ParseContext) error {
 	return a.cli.CreateAlertmanagerConfig(context.Background(), cfg, templates)
 }
 
+func (a *AlertmanagerCommand) exportState(k *kingpin.ParseContext) error {
+	state, err := a.cli.GetAlertmanagerState(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(a.StateFile, state, 0o600); err != nil {
+		return errors.Wrap(err, "unable to write state file: "+a.StateFile)
+	}
+
+	log.Infof("Alertmanager state exported to %s", a.StateFile)
+	return nil
+}
+
+func (a *AlertmanagerCommand) importState(k *kingpin.ParseContext) error {
+	state, err := os.ReadFile(a.StateFile)
+	if err != nil {
+		return errors.Wrap(err, "unable to load state file: "+a.StateFile)
+	}
+
+	result, err := a.cli.ImportAlertmanagerState(context.Background(), state, a.StateImportConflict)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"silences_imported":         result.Silences.Imported,
+		"silences_skipped":          result.Silences.Skipped,
+		"notification_log_imported": result.NotificationLog.Imported,
+		"notification_log_skipped":  result.NotificationLog.Skipped,
+	}).Infof("Alertmanager state imported from %s", a.StateFile)
+	return nil
+}
+
 func (a *AlertmanagerCommand) deleteConfig(k *kingpin.ParseContext) error {
 	err := a.cli.DeleteAlermanagerConfig(context.Background())
 	if err != nil && err != client.ErrResourceNotFound {
@@ -6,6 +6,8 @@
 package commands
 
 import (
+	"fmt"
+
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -96,4 +98,41 @@ func (cmd *AnalyzeCommand) Register(app *kingpin.Application, envVars EnvVarName
 	ruleFileAnalyzeCmd.Flag("output", "The path for the output file").
 		Default("metrics-in-ruler.json").
 		StringVar(&rfCmd.outputFile)
+
+	dcCmd := &DropConfigCommand{}
+	dropConfigCmd := analyzeCmd.Command("drop-config", "Generate the runtime configuration overrides of a tenant dropping the metrics which aren't used, from the output of 'analyze prometheus', with the number of series saved.").Action(dcCmd.run)
+	dropConfigCmd.Flag("address", "Address of the Grafana Mimir cluster; alternatively, set "+envVars.Address+".").
+		Envar(envVars.Address).
+		Required().
+		StringVar(&dcCmd.ClientConfig.Address)
+	dropConfigCmd.Flag("id", "Grafana Mimir tenant ID; alternatively, set "+envVars.TenantID+".").
+		Envar(envVars.TenantID).
+		Required().
+		StringVar(&dcCmd.ClientConfig.ID)
+	dropConfigCmd.Flag("user", fmt.Sprintf("API user to use when contacting Grafana Mimir; alternatively, set %s. If empty, %s is used instead.", envVars.APIUser, envVars.TenantID)).
+		Envar(envVars.APIUser).
+		Default("").
+		StringVar(&dcCmd.ClientConfig.User)
+	dropConfigCmd.Flag("key", "API key to use when contacting Grafana Mimir; alternatively, set "+envVars.APIKey+".").
+		Envar(envVars.APIKey).
+		Default("").
+		StringVar(&dcCmd.ClientConfig.Key)
+	dropConfigCmd.Flag("auth-token", "Authentication token bearer authentication; alternatively, set "+envVars.AuthToken+".").
+		Envar(envVars.AuthToken).
+		Default("").
+		StringVar(&dcCmd.ClientConfig.AuthToken)
+	dropConfigCmd.Flag("prometheus-metrics-file", "The path for the input file containing the metrics from prometheus-analyze command").
+		Default("prometheus-metrics.json").
+		StringVar(&dcCmd.prometheusMetricsFile)
+	dropConfigCmd.Flag("keep-metrics", "Regular expression of the metrics which are never dropped, even if they aren't used. The default keeps the series written by the ruler to restore the state of the alerts.").
+		Default(defaultDropConfigKeptMetrics).
+		StringVar(&dcCmd.keepMetrics)
+	dropConfigCmd.Flag("min-series", "Minimum number of series of the metrics which are dropped.").
+		Default("1").
+		IntVar(&dcCmd.minSeries)
+	dropConfigCmd.Flag("drop-label", "Label name to drop from all the series. Can be specified multiple times.").
+		StringsVar(&dcCmd.dropLabels)
+	dropConfigCmd.Flag("output", "The path for the output file").
+		Default("drop-config.yaml").
+		StringVar(&dcCmd.outputFile)
 }
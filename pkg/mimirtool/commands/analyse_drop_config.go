@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/alecthomas/kingpin.v2"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/mimirtool/analyze"
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const (
+	// The series written by the ruler for the alerts, which are needed to restore the state of the alerts.
+	defaultDropConfigKeptMetrics = "ALERTS|ALERTS_FOR_STATE"
+
+	// Maximum number of metrics whose number of series is requested to the cardinality analysis API at once.
+	dropConfigMetricsBatchSize = 100
+)
+
+// DropConfigCommand generates the overrides of a tenant dropping the metrics which aren't used, from the
+// output of 'analyze prometheus', and estimates the number of series saved with the cardinality analysis API.
+type DropConfigCommand struct {
+	ClientConfig client.Config
+
+	prometheusMetricsFile string
+	keepMetrics           string
+	minSeries             int
+	dropLabels            []string
+	outputFile            string
+}
+
+func (cmd *DropConfigCommand) run(k *kingpin.ParseContext) error {
+	keep, err := regexp.Compile("^(?:" + cmd.keepMetrics + ")$")
+	if err != nil {
+		return errors.Wrap(err, "invalid regular expression of the metrics to keep")
+	}
+
+	var metrics analyze.MetricsInPrometheus
+	if err := readJSONFile(cmd.prometheusMetricsFile, &metrics); err != nil {
+		return errors.Wrap(err, "unable to read the metrics from analyze prometheus")
+	}
+
+	cli, err := client.New(cmd.ClientConfig)
+	if err != nil {
+		return err
+	}
+
+	config, err := buildDropConfig(context.Background(), cli, metrics, keep, uint64(cmd.minSeries), cmd.dropLabels)
+	if err != nil {
+		return err
+	}
+
+	// The overrides of the tenant replace its current overrides, so they're merged into them.
+	var current *yaml.Node
+	if runtimeConfig, err := cli.GetRuntimeConfig(context.Background(), "diff"); err != nil {
+		log.Warnf("Unable to get the runtime configuration of the cluster, the current overrides of tenant %s must be merged manually: %v", cmd.ClientConfig.ID, err)
+	} else if current, err = tenantOverrides(runtimeConfig, cmd.ClientConfig.ID); err != nil {
+		return err
+	}
+
+	f, err := os.Create(cmd.outputFile)
+	if err != nil {
+		return err
+	}
+	if err := config.writeOverrides(f, cmd.ClientConfig.ID, current); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Infof("Overrides of tenant %s written to %s", cmd.ClientConfig.ID, cmd.outputFile)
+
+	return config.writeTable(os.Stdout)
+}
+
+// dropConfig is the series which would be dropped, and the number of series they have.
+type dropConfig struct {
+	SeriesCountTotal uint64
+	Metrics          []droppedSeries
+	Labels           []droppedSeries
+}
+
+type droppedSeries struct {
+	Name        string
+	SeriesCount uint64
+}
+
+// buildDropConfig gets the number of series of the metrics which aren't used, to drop those having at least
+// minSeries series and not matching keep, and estimates the number of series saved by dropping the labels.
+func buildDropConfig(ctx context.Context, api cardinalityAPI, metrics analyze.MetricsInPrometheus, keep *regexp.Regexp, minSeries uint64, dropLabels []string) (*dropConfig, error) {
+	total, err := api.LabelValuesCardinality(ctx, []string{labels.MetricName}, "", 1)
+	if err != nil {
+		return nil, err
+	}
+	config := &dropConfig{SeriesCountTotal: total.SeriesCountTotal}
+
+	var unused []string
+	for _, m := range metrics.AdditionalMetricCounts {
+		if !keep.MatchString(m.Metric) {
+			unused = append(unused, m.Metric)
+		}
+	}
+
+	for start := 0; start < len(unused); start += dropConfigMetricsBatchSize {
+		end := start + dropConfigMetricsBatchSize
+		if end > len(unused) {
+			end = len(unused)
+		}
+
+		selector := fmt.Sprintf("{%s=~%q}", labels.MetricName, metricNamesRegexp(unused[start:end]))
+		result, err := api.LabelValuesCardinality(ctx, []string{labels.MetricName}, selector, end-start)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range result.Labels {
+			for _, v := range l.Cardinality {
+				if v.SeriesCount >= minSeries {
+					config.Metrics = append(config.Metrics, droppedSeries{Name: v.LabelValue, SeriesCount: v.SeriesCount})
+				}
+			}
+		}
+	}
+	sort.Slice(config.Metrics, func(i, j int) bool {
+		if config.Metrics[i].SeriesCount != config.Metrics[j].SeriesCount {
+			return config.Metrics[i].SeriesCount > config.Metrics[j].SeriesCount
+		}
+		return config.Metrics[i].Name < config.Metrics[j].Name
+	})
+
+	for _, name := range dropLabels {
+		result, err := api.LabelValuesCardinality(ctx, []string{name}, "", 1)
+		if err != nil {
+			return nil, err
+		}
+
+		// Once the label is dropped, the series with the value having the most series remain distinct,
+		// so at most the other series having the label are saved.
+		d := droppedSeries{Name: name}
+		for _, l := range result.Labels {
+			if l.LabelName == name && len(l.Cardinality) > 0 {
+				d.SeriesCount = l.SeriesCount - l.Cardinality[0].SeriesCount
+			}
+		}
+		config.Labels = append(config.Labels, d)
+	}
+
+	return config, nil
+}
+
+func metricNamesRegexp(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	sort.Strings(quoted)
+	return strings.Join(quoted, "|")
+}
+
+// dropRelabelConfig is a metric relabel config dropping the series whose source labels match the regex.
+type dropRelabelConfig struct {
+	SourceLabels []string `yaml:"source_labels,flow"`
+	Regex        string   `yaml:"regex"`
+	Action       string   `yaml:"action"`
+}
+
+// tenantOverrides returns the current overrides of the tenant, given the runtime configuration as reported at
+// /runtime_config?mode=diff, or an empty mapping if the tenant has no overrides.
+func tenantOverrides(runtimeConfig []byte, tenantID string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(runtimeConfig, &doc); err != nil {
+		return nil, errors.Wrap(err, "unable to parse the runtime configuration of the cluster")
+	}
+
+	if len(doc.Content) > 0 {
+		if overrides := util.YAMLMappingValue(doc.Content[0], "overrides"); overrides != nil {
+			if current := util.YAMLMappingValue(overrides, tenantID); current != nil && current.Kind == yaml.MappingNode {
+				return current, nil
+			}
+		}
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+}
+
+// writeOverrides writes the runtime configuration overrides of the tenant dropping the metrics and the labels,
+// to review before replacing the overrides of the tenant in the runtime configuration. The drop configuration
+// is merged into the current overrides of the tenant, if they're known, otherwise they must be merged manually.
+func (c *dropConfig) writeOverrides(w io.Writer, tenantID string, current *yaml.Node) error {
+	merged := current != nil
+	if !merged {
+		current = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+
+	if len(c.Metrics) > 0 {
+		names := make([]string, 0, len(c.Metrics))
+		for _, m := range c.Metrics {
+			names = append(names, m.Name)
+		}
+
+		var drop yaml.Node
+		if err := drop.Encode(dropRelabelConfig{
+			SourceLabels: []string{labels.MetricName},
+			Regex:        metricNamesRegexp(names),
+			Action:       "drop",
+		}); err != nil {
+			return err
+		}
+		configs := util.YAMLMappingValue(current, "metric_relabel_configs")
+		if configs == nil || configs.Kind != yaml.SequenceNode {
+			configs = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			util.SetYAMLMappingValue(current, "metric_relabel_configs", configs)
+		}
+		configs.Content = append(configs.Content, &drop)
+	}
+
+	if len(c.Labels) > 0 {
+		dropLabels := util.YAMLMappingValue(current, "drop_labels")
+		if dropLabels == nil || dropLabels.Kind != yaml.SequenceNode {
+			dropLabels = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			util.SetYAMLMappingValue(current, "drop_labels", dropLabels)
+		}
+		existing := map[string]bool{}
+		for _, n := range dropLabels.Content {
+			existing[n.Value] = true
+		}
+		for _, l := range c.Labels {
+			if !existing[l.Name] {
+				dropLabels.Content = append(dropLabels.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: l.Name})
+			}
+		}
+	}
+
+	metricsSeries, labelsSeries := c.savedSeries()
+	fmt.Fprintf(w, "# Series saved by dropping %d metrics: %d of %d.\n", len(c.Metrics), metricsSeries, c.SeriesCountTotal)
+	if len(c.Labels) > 0 {
+		fmt.Fprintf(w, "# Series saved by dropping %d labels: at most %d of %d.\n", len(c.Labels), labelsSeries, c.SeriesCountTotal)
+	}
+	fmt.Fprintf(w, "# The overrides of tenant %s below replace all its current overrides in the runtime configuration.\n", tenantID)
+	if merged {
+		fmt.Fprintln(w, "# They include the current overrides of the tenant, as reported by /runtime_config?mode=diff.")
+	} else {
+		fmt.Fprintln(w, "# The current overrides of the tenant couldn't be fetched: merge them before replacing the overrides.")
+	}
+
+	doc := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "overrides"},
+		{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: tenantID},
+			current,
+		}},
+	}}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func (c *dropConfig) savedSeries() (metricsSeries, labelsSeries uint64) {
+	for _, m := range c.Metrics {
+		metricsSeries += m.SeriesCount
+	}
+	for _, l := range c.Labels {
+		labelsSeries += l.SeriesCount
+	}
+	return metricsSeries, labelsSeries
+}
+
+func (c *dropConfig) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	metricsSeries, labelsSeries := c.savedSeries()
+	fmt.Fprintf(tw, "SERIES: %d, SAVED BY DROPPING METRICS: %d", c.SeriesCountTotal, metricsSeries)
+	if len(c.Labels) > 0 {
+		fmt.Fprintf(tw, ", SAVED BY DROPPING LABELS: at most %d", labelsSeries)
+	}
+	fmt.Fprint(tw, "\n\n")
+
+	fmt.Fprintln(tw, "METRIC\tSERIES")
+	for _, m := range c.Metrics {
+		fmt.Fprintf(tw, "%s\t%d\n", m.Name, m.SeriesCount)
+	}
+	if len(c.Labels) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "LABEL\tSERIES SAVED (AT MOST)")
+		for _, l := range c.Labels {
+			fmt.Fprintf(tw, "%s\t%d\n", l.Name, l.SeriesCount)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/mimirtool/analyze"
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+)
+
+// fakeDropConfigCardinalityAPI answers with the cardinality of the metrics of a tenant having 100 series.
+type fakeDropConfigCardinalityAPI struct {
+	requests []string
+}
+
+func (f *fakeDropConfigCardinalityAPI) LabelNamesCardinality(context.Context, string, int) (*client.LabelNamesCardinality, error) {
+	return nil, fmt.Errorf("unexpected request")
+}
+
+func (f *fakeDropConfigCardinalityAPI) LabelValuesCardinality(_ context.Context, labelNames []string, selector string, limit int) (*client.LabelValuesCardinality, error) {
+	f.requests = append(f.requests, fmt.Sprintf("label_values label_names=%v selector=%s limit=%d", labelNames, selector, limit))
+
+	switch {
+	case labelNames[0] == "missing":
+		return &client.LabelValuesCardinality{SeriesCountTotal: 100}, nil
+	case labelNames[0] == "pod":
+		return &client.LabelValuesCardinality{SeriesCountTotal: 100, Labels: []client.LabelValuesCardinalityLabel{{
+			LabelName:        "pod",
+			LabelValuesCount: 3,
+			SeriesCount:      30,
+			Cardinality:      []client.LabelValuesCardinalityValue{{LabelValue: "pod-1", SeriesCount: 12}},
+		}}}, nil
+	case selector == "":
+		return &client.LabelValuesCardinality{SeriesCountTotal: 100}, nil
+	default:
+		return &client.LabelValuesCardinality{SeriesCountTotal: 21, Labels: []client.LabelValuesCardinalityLabel{{
+			LabelName: "__name__",
+			Cardinality: []client.LabelValuesCardinalityValue{
+				{LabelValue: "go_gc_duration_seconds", SeriesCount: 10},
+				{LabelValue: "process_open_fds", SeriesCount: 10},
+				{LabelValue: "scrape_samples_scraped", SeriesCount: 1},
+			},
+		}}}, nil
+	}
+}
+
+func TestBuildDropConfig(t *testing.T) {
+	metrics := analyze.MetricsInPrometheus{
+		InUseMetricCounts: []analyze.MetricCount{{Metric: "up", Count: 5}},
+		AdditionalMetricCounts: []analyze.MetricCount{
+			{Metric: "process_open_fds", Count: 10},
+			{Metric: "go_gc_duration_seconds", Count: 10},
+			{Metric: "ALERTS_FOR_STATE", Count: 3},
+			{Metric: "scrape_samples_scraped", Count: 1},
+		},
+	}
+
+	api := &fakeDropConfigCardinalityAPI{}
+	config, err := buildDropConfig(context.Background(), api, metrics, regexp.MustCompile("^(?:"+defaultDropConfigKeptMetrics+")$"), 2, []string{"pod", "missing"})
+	require.NoError(t, err)
+
+	// The metrics used, or which are kept, aren't requested.
+	assert.Equal(t, []string{
+		`label_values label_names=[__name__] selector= limit=1`,
+		`label_values label_names=[__name__] selector={__name__=~"go_gc_duration_seconds|process_open_fds|scrape_samples_scraped"} limit=3`,
+		`label_values label_names=[pod] selector= limit=1`,
+		`label_values label_names=[missing] selector= limit=1`,
+	}, api.requests)
+
+	assert.Equal(t, &dropConfig{
+		SeriesCountTotal: 100,
+		Metrics: []droppedSeries{
+			{Name: "go_gc_duration_seconds", SeriesCount: 10},
+			{Name: "process_open_fds", SeriesCount: 10},
+		},
+		Labels: []droppedSeries{
+			{Name: "pod", SeriesCount: 18},
+			{Name: "missing", SeriesCount: 0},
+		},
+	}, config)
+
+	var buf bytes.Buffer
+	require.NoError(t, config.writeOverrides(&buf, "tenant-1", nil))
+	assert.Equal(t, `# Series saved by dropping 2 metrics: 20 of 100.
+# Series saved by dropping 2 labels: at most 18 of 100.
+# The overrides of tenant tenant-1 below replace all its current overrides in the runtime configuration.
+# The current overrides of the tenant couldn't be fetched: merge them before replacing the overrides.
+overrides:
+  tenant-1:
+    metric_relabel_configs:
+      - source_labels: [__name__]
+        regex: go_gc_duration_seconds|process_open_fds
+        action: drop
+    drop_labels:
+      - pod
+      - missing
+`, buf.String())
+
+	// The overrides are valid metric relabel configs.
+	var overrides struct {
+		Overrides map[string]struct {
+			MetricRelabelConfigs []*relabel.Config `yaml:"metric_relabel_configs"`
+			DropLabels           []string          `yaml:"drop_labels"`
+		} `yaml:"overrides"`
+	}
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &overrides))
+	require.Len(t, overrides.Overrides["tenant-1"].MetricRelabelConfigs, 1)
+	assert.Equal(t, relabel.Drop, overrides.Overrides["tenant-1"].MetricRelabelConfigs[0].Action)
+	assert.True(t, overrides.Overrides["tenant-1"].MetricRelabelConfigs[0].Regex.MatchString("process_open_fds"))
+	assert.False(t, overrides.Overrides["tenant-1"].MetricRelabelConfigs[0].Regex.MatchString("up"))
+
+	// The drop configuration is merged into the current overrides of the tenant.
+	current, err := tenantOverrides([]byte(`
+overrides:
+  tenant-1:
+    ingestion_rate: 100
+    metric_relabel_configs:
+      - source_labels: [job]
+        regex: test
+        action: drop
+    drop_labels:
+      - pod
+  tenant-2:
+    ingestion_rate: 200
+`), "tenant-1")
+	require.NoError(t, err)
+
+	buf.Reset()
+	require.NoError(t, config.writeOverrides(&buf, "tenant-1", current))
+	assert.Equal(t, `# Series saved by dropping 2 metrics: 20 of 100.
+# Series saved by dropping 2 labels: at most 18 of 100.
+# The overrides of tenant tenant-1 below replace all its current overrides in the runtime configuration.
+# They include the current overrides of the tenant, as reported by /runtime_config?mode=diff.
+overrides:
+  tenant-1:
+    ingestion_rate: 100
+    metric_relabel_configs:
+      - source_labels: [job]
+        regex: test
+        action: drop
+      - source_labels: [__name__]
+        regex: go_gc_duration_seconds|process_open_fds
+        action: drop
+    drop_labels:
+      - pod
+      - missing
+`, buf.String())
+
+	// A tenant without overrides gets only the drop configuration.
+	current, err = tenantOverrides([]byte("overrides:\n  tenant-2:\n    ingestion_rate: 200\n"), "tenant-1")
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, config.writeOverrides(&buf, "tenant-1", current))
+	assert.Contains(t, buf.String(), `overrides:
+  tenant-1:
+    metric_relabel_configs:
+      - source_labels: [__name__]
+        regex: go_gc_duration_seconds|process_open_fds
+        action: drop
+    drop_labels:
+      - pod
+      - missing
+`)
+
+	buf.Reset()
+	require.NoError(t, config.writeTable(&buf))
+	assert.Equal(t, `SERIES: 100, SAVED BY DROPPING METRICS: 20, SAVED BY DROPPING LABELS: at most 18
+
+METRIC                  SERIES
+go_gc_duration_seconds  10
+process_open_fds        10
+
+LABEL    SERIES SAVED (AT MOST)
+pod      18
+missing  0
+`, buf.String())
+}
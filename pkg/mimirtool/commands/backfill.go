@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/alecthomas/units"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/alecthomas/kingpin.v2"
 
@@ -16,6 +17,7 @@ import (
 type BackfillCommand struct {
 	clientConfig client.Config
 	blocks       blockList
+	partSize     units.Base2Bytes
 }
 
 type blockList []string
@@ -45,6 +47,10 @@ func (c *BackfillCommand) Register(app *kingpin.Application, envVars EnvVarNames
 	cmd.Action(c.backfill)
 	cmd.Arg("block-dir", "block to upload").Required().SetValue(&c.blocks)
 
+	cmd.Flag("part-size", "Maximum size of the parts block files are uploaded in, such as 64MiB. If 0, each block file is uploaded in a single request.").
+		Default("0").
+		BytesVar(&c.partSize)
+
 	cmd.Flag("address", "Address of the Grafana Mimir cluster; alternatively, set "+envVars.Address+".").
 		Envar(envVars.Address).
 		Required().
@@ -93,5 +99,5 @@ func (c *BackfillCommand) backfill(k *kingpin.ParseContext) error {
 		return err
 	}
 
-	return cli.Backfill(c.blocks)
+	return cli.Backfill(c.blocks, int64(c.partSize))
 }
@@ -7,7 +7,6 @@ package commands
 
 import (
 	"context"
-	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -165,30 +164,17 @@ func (b *BucketValidationCommand) validate(k *kingpin.ParseContext) error {
 }
 
 func (b *BucketValidationCommand) printBucketConfigHelp() {
-	fs := flag.NewFlagSet("bucket-config", flag.ContinueOnError)
-	b.cfg.RegisterFlags(fs)
-
-	fmt.Fprintf(fs.Output(), `
-The following help text describes the arguments
-which may be specified in the string that gets
-passed to "-bucket-config".
-
-Example:
-mimirtool bucket-validation --bucket-config='-backend=s3 -s3.endpoint=localhost:9000 -s3.bucket-name=example-bucket'
-
-`)
-	fs.Usage()
+	printBucketConfigHelp("bucket-validation --bucket-config")
 }
 
 func (b *BucketValidationCommand) parseBucketConfig() error {
-	fs := flag.NewFlagSet("bucket-config", flag.ContinueOnError)
-	b.cfg.RegisterFlags(fs)
-	err := fs.Parse(strings.Split(b.bucketConfig, " "))
+	cfg, err := parseBucketConfig(b.bucketConfig)
 	if err != nil {
 		return err
 	}
 
-	return b.cfg.Validate()
+	b.cfg = cfg
+	return nil
 }
 
 func (b *BucketValidationCommand) report(phase string, completed int) {
@@ -0,0 +1,424 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/grafana/mimir/pkg/mimirtool/analyze"
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+)
+
+const (
+	cardinalityFormatTable = "table"
+	cardinalityFormatJSON  = "json"
+
+	// Maximum limit accepted by the cardinality analysis API.
+	maxCardinalityLimit = 500
+)
+
+// cardinalityAPI is the cardinality analysis API of Grafana Mimir.
+type cardinalityAPI interface {
+	LabelNamesCardinality(ctx context.Context, selector string, limit int) (*client.LabelNamesCardinality, error)
+	LabelValuesCardinality(ctx context.Context, labelNames []string, selector string, limit int) (*client.LabelValuesCardinality, error)
+}
+
+// CardinalityCommand analyzes the cardinality of the series of a tenant with the cardinality analysis API.
+type CardinalityCommand struct {
+	ClientConfig client.Config
+	cli          cardinalityAPI
+
+	selector   string
+	limit      int
+	labelNames []string
+	format     string
+	outputFile string
+
+	compareFile        string
+	grafanaMetricsFile string
+	rulerMetricsFile   string
+}
+
+// Register the cardinality commands and flags with the kingpin application.
+func (c *CardinalityCommand) Register(app *kingpin.Application, envVars EnvVarNames) {
+	cardinalityCmd := app.Command("cardinality", "Analyze the cardinality of the series of a tenant, using the cardinality analysis API of Grafana Mimir.").PreAction(c.setup)
+	cardinalityCmd.Flag("address", "Address of the Grafana Mimir cluster; alternatively, set "+envVars.Address+".").Envar(envVars.Address).Required().StringVar(&c.ClientConfig.Address)
+	cardinalityCmd.Flag("id", "Grafana Mimir tenant ID; alternatively, set "+envVars.TenantID+".").Envar(envVars.TenantID).Required().StringVar(&c.ClientConfig.ID)
+	cardinalityCmd.Flag("user", fmt.Sprintf("API user to use when contacting Grafana Mimir; alternatively, set %s. If empty, %s is used instead.", envVars.APIUser, envVars.TenantID)).Default("").Envar(envVars.APIUser).StringVar(&c.ClientConfig.User)
+	cardinalityCmd.Flag("key", "API key to use when contacting Grafana Mimir; alternatively, set "+envVars.APIKey+".").Default("").Envar(envVars.APIKey).StringVar(&c.ClientConfig.Key)
+	cardinalityCmd.Flag("tls-ca-path", "TLS CA certificate to verify Grafana Mimir API as part of mTLS; alternatively, set "+envVars.TLSCAPath+".").Default("").Envar(envVars.TLSCAPath).StringVar(&c.ClientConfig.TLS.CAPath)
+	cardinalityCmd.Flag("tls-cert-path", "TLS client certificate to authenticate with the Grafana Mimir API as part of mTLS; alternatively, set "+envVars.TLSCertPath+".").Default("").Envar(envVars.TLSCertPath).StringVar(&c.ClientConfig.TLS.CertPath)
+	cardinalityCmd.Flag("tls-key-path", "TLS client certificate private key to authenticate with the Grafana Mimir API as part of mTLS; alternatively, set "+envVars.TLSKeyPath+".").Default("").Envar(envVars.TLSKeyPath).StringVar(&c.ClientConfig.TLS.KeyPath)
+	cardinalityCmd.Flag("auth-token", "Authentication token bearer authentication; alternatively, set "+envVars.AuthToken+".").Default("").Envar(envVars.AuthToken).StringVar(&c.ClientConfig.AuthToken)
+
+	labelNamesCmd := cardinalityCmd.Command("label-names", "Show the label names with the most values.").Action(c.labelNamesCardinality)
+	labelValuesCmd := cardinalityCmd.Command("label-values", "Show the number of series of label names, and of their values with the most series.").Action(c.labelValuesCardinality)
+	labelValuesCmd.Flag("label-name", "Label name to show the values of. Can be specified multiple times.").Required().StringsVar(&c.labelNames)
+	reportCmd := cardinalityCmd.Command("report", "Show the metrics with the most series, with their labels with the most values, and the values of these labels with the most series.").Action(c.report)
+	reportCmd.Flag("compare-to", "Path of a report previously written in the JSON format, to show the change of the cardinality since then.").Default("").StringVar(&c.compareFile)
+	reportCmd.Flag("grafana-metrics-file", "Path of the output file of 'analyze grafana' or 'analyze dashboard', to flag the metrics which aren't used in Grafana.").Default("").StringVar(&c.grafanaMetricsFile)
+	reportCmd.Flag("ruler-metrics-file", "Path of the output file of 'analyze ruler' or 'analyze rule-file', to flag the metrics which aren't used in rules.").Default("").StringVar(&c.rulerMetricsFile)
+
+	for _, cmd := range []*kingpin.CmdClause{labelNamesCmd, labelValuesCmd, reportCmd} {
+		cmd.Flag("selector", "PromQL selector of the series to analyze. If empty, all the series are analyzed.").Default("").StringVar(&c.selector)
+		cmd.Flag("limit", fmt.Sprintf("Maximum number of items to show at each level, up to %d.", maxCardinalityLimit)).Default("20").IntVar(&c.limit)
+		cmd.Flag("format", "Output format: table or json.").Default(cardinalityFormatTable).EnumVar(&c.format, cardinalityFormatTable, cardinalityFormatJSON)
+		cmd.Flag("output-file", "Path of the file to write the output to. If empty, the output is written to the standard output.").Default("").StringVar(&c.outputFile)
+	}
+}
+
+func (c *CardinalityCommand) setup(k *kingpin.ParseContext) error {
+	if c.limit <= 0 || c.limit > maxCardinalityLimit {
+		return fmt.Errorf("the limit must be between 1 and %d", maxCardinalityLimit)
+	}
+
+	cli, err := client.New(c.ClientConfig)
+	if err != nil {
+		return err
+	}
+	c.cli = cli
+
+	return nil
+}
+
+func (c *CardinalityCommand) labelNamesCardinality(k *kingpin.ParseContext) error {
+	result, err := c.cli.LabelNamesCardinality(context.Background(), c.selector, c.limit)
+	if err != nil {
+		return err
+	}
+
+	return c.writeOutput(result, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "LABEL NAMES: %d, LABEL VALUES: %d\n\n", result.LabelNamesCount, result.LabelValuesCountTotal)
+		fmt.Fprintln(tw, "LABEL NAME\tVALUES")
+		for _, item := range result.Cardinality {
+			fmt.Fprintf(tw, "%s\t%d\n", item.LabelName, item.LabelValuesCount)
+		}
+		return tw.Flush()
+	})
+}
+
+func (c *CardinalityCommand) labelValuesCardinality(k *kingpin.ParseContext) error {
+	result, err := c.cli.LabelValuesCardinality(context.Background(), c.labelNames, c.selector, c.limit)
+	if err != nil {
+		return err
+	}
+
+	return c.writeOutput(result, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "SERIES: %d\n\n", result.SeriesCountTotal)
+		fmt.Fprintln(tw, "LABEL NAME\tVALUES\tSERIES\tTOP VALUES")
+		for _, l := range result.Labels {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", l.LabelName, l.LabelValuesCount, l.SeriesCount, formatTopLabelValues(l.Cardinality))
+		}
+		return tw.Flush()
+	})
+}
+
+func (c *CardinalityCommand) report(k *kingpin.ParseContext) error {
+	report, err := buildCardinalityReport(context.Background(), c.cli, c.selector, c.limit)
+	if err != nil {
+		return err
+	}
+
+	if c.compareFile != "" {
+		previous := &cardinalityReport{}
+		if err := readJSONFile(c.compareFile, previous); err != nil {
+			return errors.Wrap(err, "unable to read the report to compare to")
+		}
+		report.compareTo(previous)
+	}
+
+	if c.grafanaMetricsFile != "" || c.rulerMetricsFile != "" {
+		used := map[string]struct{}{}
+		if c.grafanaMetricsFile != "" {
+			grafanaMetrics := analyze.MetricsInGrafana{}
+			if err := readJSONFile(c.grafanaMetricsFile, &grafanaMetrics); err != nil {
+				return errors.Wrap(err, "unable to read the metrics used in Grafana")
+			}
+			for _, metric := range grafanaMetrics.MetricsUsed {
+				used[metric] = struct{}{}
+			}
+		}
+		if c.rulerMetricsFile != "" {
+			rulerMetrics := analyze.MetricsInRuler{}
+			if err := readJSONFile(c.rulerMetricsFile, &rulerMetrics); err != nil {
+				return errors.Wrap(err, "unable to read the metrics used in rules")
+			}
+			for _, metric := range rulerMetrics.MetricsUsed {
+				used[metric] = struct{}{}
+			}
+		}
+
+		unused, unusedSeries := report.markUsedMetrics(used)
+		if unused > 0 {
+			log.Warnf("%d of the metrics with the most series, with %d series in total, aren't used", unused, unusedSeries)
+		}
+	}
+
+	return c.writeOutput(report, report.writeTable)
+}
+
+// writeOutput writes the result, either in the JSON format or as tables written by writeTable.
+func (c *CardinalityCommand) writeOutput(result interface{}, writeTable func(io.Writer) error) (returnErr error) {
+	var w io.Writer = os.Stdout
+	if c.outputFile != "" {
+		f, err := os.Create(c.outputFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := f.Close(); err != nil && returnErr == nil {
+				returnErr = err
+			}
+		}()
+		w = f
+	}
+
+	if c.format == cardinalityFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	return writeTable(w)
+}
+
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// cardinalityReport is the cardinality of the metrics with the most series.
+type cardinalityReport struct {
+	SeriesCountTotal         uint64              `json:"series_count_total"`
+	PreviousSeriesCountTotal *uint64             `json:"previous_series_count_total,omitempty"`
+	Metrics                  []metricCardinality `json:"metrics"`
+
+	compared        bool
+	crossReferenced bool
+}
+
+type metricCardinality struct {
+	Name                string             `json:"name"`
+	SeriesCount         uint64             `json:"series_count"`
+	PreviousSeriesCount *uint64            `json:"previous_series_count,omitempty"`
+	Used                *bool              `json:"used,omitempty"`
+	Labels              []labelCardinality `json:"labels"`
+}
+
+type labelCardinality struct {
+	Name                string                               `json:"name"`
+	ValuesCount         uint64                               `json:"values_count"`
+	PreviousValuesCount *uint64                              `json:"previous_values_count,omitempty"`
+	TopValues           []client.LabelValuesCardinalityValue `json:"top_values"`
+}
+
+// buildCardinalityReport walks the metrics with the most series among the series matching the selector, then the
+// labels of each of these metrics with the most values, and the values of these labels with the most series.
+func buildCardinalityReport(ctx context.Context, api cardinalityAPI, selector string, limit int) (*cardinalityReport, error) {
+	var base []*labels.Matcher
+	if selector != "" {
+		var err error
+		base, err = parser.ParseMetricSelector(selector)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid selector")
+		}
+	}
+
+	metrics, err := api.LabelValuesCardinality(ctx, []string{model.MetricNameLabel}, selector, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &cardinalityReport{SeriesCountTotal: metrics.SeriesCountTotal}
+	if len(metrics.Labels) == 0 {
+		return report, nil
+	}
+
+	for _, metric := range metrics.Labels[0].Cardinality {
+		log.Debugf("Analyzing the labels of the metric %s", metric.LabelValue)
+		metricSelector := metricSelector(base, metric.LabelValue)
+
+		// The metric name is always one of the label names, so one more is requested to get up to limit other
+		// labels, within the maximum limit of the API. The metric name is filtered out of the label names.
+		namesLimit := limit + 1
+		if namesLimit > maxCardinalityLimit {
+			namesLimit = maxCardinalityLimit
+		}
+		names, err := api.LabelNamesCardinality(ctx, metricSelector, namesLimit)
+		if err != nil {
+			return nil, err
+		}
+		var labelNames []string
+		for _, item := range names.Cardinality {
+			if item.LabelName != model.MetricNameLabel && len(labelNames) < limit {
+				labelNames = append(labelNames, item.LabelName)
+			}
+		}
+
+		m := metricCardinality{Name: metric.LabelValue, SeriesCount: metric.SeriesCount, Labels: []labelCardinality{}}
+		if len(labelNames) > 0 {
+			values, err := api.LabelValuesCardinality(ctx, labelNames, metricSelector, limit)
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range values.Labels {
+				m.Labels = append(m.Labels, labelCardinality{Name: l.LabelName, ValuesCount: l.LabelValuesCount, TopValues: l.Cardinality})
+			}
+			sort.Slice(m.Labels, func(i, j int) bool {
+				if m.Labels[i].ValuesCount != m.Labels[j].ValuesCount {
+					return m.Labels[i].ValuesCount > m.Labels[j].ValuesCount
+				}
+				return m.Labels[i].Name < m.Labels[j].Name
+			})
+		}
+		report.Metrics = append(report.Metrics, m)
+	}
+
+	return report, nil
+}
+
+// metricSelector returns the selector of the series of the metric matching the base matchers.
+func metricSelector(base []*labels.Matcher, metric string) string {
+	matchers := []string{labels.MustNewMatcher(labels.MatchEqual, model.MetricNameLabel, metric).String()}
+	for _, m := range base {
+		matchers = append(matchers, m.String())
+	}
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+// compareTo sets the cardinality of the previous report, to show the change since then.
+func (r *cardinalityReport) compareTo(previous *cardinalityReport) {
+	r.compared = true
+	r.PreviousSeriesCountTotal = &previous.SeriesCountTotal
+
+	previousMetrics := make(map[string]metricCardinality, len(previous.Metrics))
+	for _, m := range previous.Metrics {
+		previousMetrics[m.Name] = m
+	}
+
+	for i := range r.Metrics {
+		m := &r.Metrics[i]
+		prev, ok := previousMetrics[m.Name]
+		if !ok {
+			continue
+		}
+		seriesCount := prev.SeriesCount
+		m.PreviousSeriesCount = &seriesCount
+
+		previousLabels := make(map[string]uint64, len(prev.Labels))
+		for _, l := range prev.Labels {
+			previousLabels[l.Name] = l.ValuesCount
+		}
+		for j := range m.Labels {
+			if valuesCount, ok := previousLabels[m.Labels[j].Name]; ok {
+				m.Labels[j].PreviousValuesCount = &valuesCount
+			}
+		}
+	}
+}
+
+// markUsedMetrics flags whether the metrics are used, and returns the number of unused metrics and their series.
+func (r *cardinalityReport) markUsedMetrics(used map[string]struct{}) (unusedMetrics int, unusedSeries uint64) {
+	r.crossReferenced = true
+	for i := range r.Metrics {
+		_, ok := used[r.Metrics[i].Name]
+		r.Metrics[i].Used = &ok
+		if !ok {
+			unusedMetrics++
+			unusedSeries += r.Metrics[i].SeriesCount
+		}
+	}
+	return unusedMetrics, unusedSeries
+}
+
+func (r *cardinalityReport) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "SERIES: %d", r.SeriesCountTotal)
+	if r.compared {
+		fmt.Fprintf(tw, " (%s)", formatCardinalityChange(r.SeriesCountTotal, r.PreviousSeriesCountTotal))
+	}
+	fmt.Fprint(tw, "\n\n")
+
+	header := "METRIC\tSERIES"
+	if r.compared {
+		header += "\tCHANGE"
+	}
+	if r.crossReferenced {
+		header += "\tUSED"
+	}
+	fmt.Fprintln(tw, header)
+	for _, m := range r.Metrics {
+		row := m.Name + "\t" + strconv.FormatUint(m.SeriesCount, 10)
+		if r.compared {
+			row += "\t" + formatCardinalityChange(m.SeriesCount, m.PreviousSeriesCount)
+		}
+		if r.crossReferenced {
+			if m.Used != nil && *m.Used {
+				row += "\tyes"
+			} else {
+				row += "\tno"
+			}
+		}
+		fmt.Fprintln(tw, row)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(tw)
+	header = "METRIC\tLABEL\tVALUES"
+	if r.compared {
+		header += "\tCHANGE"
+	}
+	fmt.Fprintln(tw, header+"\tTOP VALUES")
+	for _, m := range r.Metrics {
+		for _, l := range m.Labels {
+			row := m.Name + "\t" + l.Name + "\t" + strconv.FormatUint(l.ValuesCount, 10)
+			if r.compared {
+				row += "\t" + formatCardinalityChange(l.ValuesCount, l.PreviousValuesCount)
+			}
+			fmt.Fprintln(tw, row+"\t"+formatTopLabelValues(l.TopValues))
+		}
+	}
+	return tw.Flush()
+}
+
+// formatCardinalityChange formats the change of a count since the previous report, or "new" if it wasn't in it.
+func formatCardinalityChange(count uint64, previous *uint64) string {
+	if previous == nil {
+		return "new"
+	}
+	change := int64(count) - int64(*previous)
+	if change > 0 {
+		return "+" + strconv.FormatInt(change, 10)
+	}
+	return strconv.FormatInt(change, 10)
+}
+
+func formatTopLabelValues(values []client.LabelValuesCardinalityValue) string {
+	formatted := make([]string, 0, len(values))
+	for _, v := range values {
+		formatted = append(formatted, fmt.Sprintf("%s (%d)", v.LabelValue, v.SeriesCount))
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+)
+
+// fakeCardinalityAPI answers with the cardinality of the metrics up and http_requests_total.
+type fakeCardinalityAPI struct {
+	requests []string
+}
+
+func (f *fakeCardinalityAPI) LabelNamesCardinality(_ context.Context, selector string, limit int) (*client.LabelNamesCardinality, error) {
+	f.requests = append(f.requests, fmt.Sprintf("label_names selector=%s limit=%d", selector, limit))
+
+	switch {
+	case strings.Contains(selector, `__name__="up"`):
+		return &client.LabelNamesCardinality{Cardinality: []client.LabelNamesCardinalityItem{
+			{LabelName: "instance", LabelValuesCount: 3},
+			{LabelName: "__name__", LabelValuesCount: 1},
+			{LabelName: "job", LabelValuesCount: 1},
+		}}, nil
+	default:
+		return &client.LabelNamesCardinality{Cardinality: []client.LabelNamesCardinalityItem{
+			{LabelName: "__name__", LabelValuesCount: 1},
+		}}, nil
+	}
+}
+
+func (f *fakeCardinalityAPI) LabelValuesCardinality(_ context.Context, labelNames []string, selector string, limit int) (*client.LabelValuesCardinality, error) {
+	f.requests = append(f.requests, fmt.Sprintf("label_values label_names=%v selector=%s limit=%d", labelNames, selector, limit))
+
+	if labelNames[0] == "__name__" {
+		return &client.LabelValuesCardinality{SeriesCountTotal: 10, Labels: []client.LabelValuesCardinalityLabel{{
+			LabelName:   "__name__",
+			SeriesCount: 10,
+			Cardinality: []client.LabelValuesCardinalityValue{{LabelValue: "http_requests_total", SeriesCount: 7}, {LabelValue: "up", SeriesCount: 3}},
+		}}}, nil
+	}
+	return &client.LabelValuesCardinality{SeriesCountTotal: 3, Labels: []client.LabelValuesCardinalityLabel{
+		{LabelName: "job", LabelValuesCount: 1, SeriesCount: 3, Cardinality: []client.LabelValuesCardinalityValue{{LabelValue: "node", SeriesCount: 3}}},
+		{LabelName: "instance", LabelValuesCount: 3, SeriesCount: 3, Cardinality: []client.LabelValuesCardinalityValue{{LabelValue: "a", SeriesCount: 1}, {LabelValue: "b", SeriesCount: 1}}},
+	}}, nil
+}
+
+func TestBuildCardinalityReport(t *testing.T) {
+	api := &fakeCardinalityAPI{}
+	report, err := buildCardinalityReport(context.Background(), api, `{job="node"}`, 2)
+	require.NoError(t, err)
+
+	// The labels of each metric are walked, without the metric name.
+	assert.Equal(t, []string{
+		`label_values label_names=[__name__] selector={job="node"} limit=2`,
+		`label_names selector={__name__="http_requests_total",job="node"} limit=3`,
+		`label_names selector={__name__="up",job="node"} limit=3`,
+		`label_values label_names=[instance job] selector={__name__="up",job="node"} limit=2`,
+	}, api.requests)
+
+	assert.Equal(t, &cardinalityReport{
+		SeriesCountTotal: 10,
+		Metrics: []metricCardinality{
+			{Name: "http_requests_total", SeriesCount: 7, Labels: []labelCardinality{}},
+			{Name: "up", SeriesCount: 3, Labels: []labelCardinality{
+				{Name: "instance", ValuesCount: 3, TopValues: []client.LabelValuesCardinalityValue{{LabelValue: "a", SeriesCount: 1}, {LabelValue: "b", SeriesCount: 1}}},
+				{Name: "job", ValuesCount: 1, TopValues: []client.LabelValuesCardinalityValue{{LabelValue: "node", SeriesCount: 3}}},
+			}},
+		},
+	}, report)
+
+	_, err = buildCardinalityReport(context.Background(), api, `{job=~"node"`, 2)
+	require.Error(t, err)
+}
+
+func TestBuildCardinalityReport_MaxLimit(t *testing.T) {
+	api := &fakeCardinalityAPI{}
+	report, err := buildCardinalityReport(context.Background(), api, "", maxCardinalityLimit)
+	require.NoError(t, err)
+
+	// The label names are requested within the maximum limit of the API, and the metric name is filtered out.
+	assert.Equal(t, []string{
+		`label_values label_names=[__name__] selector= limit=500`,
+		`label_names selector={__name__="http_requests_total"} limit=500`,
+		`label_names selector={__name__="up"} limit=500`,
+		`label_values label_names=[instance job] selector={__name__="up"} limit=500`,
+	}, api.requests)
+	require.Len(t, report.Metrics, 2)
+	require.Len(t, report.Metrics[1].Labels, 2)
+}
+
+func TestCardinalityReport_WriteTable(t *testing.T) {
+	report, err := buildCardinalityReport(context.Background(), &fakeCardinalityAPI{}, "", 20)
+	require.NoError(t, err)
+
+	report.compareTo(&cardinalityReport{
+		SeriesCountTotal: 12,
+		Metrics: []metricCardinality{
+			{Name: "up", SeriesCount: 1, Labels: []labelCardinality{{Name: "instance", ValuesCount: 1}}},
+			{Name: "node_cpu_seconds_total", SeriesCount: 5},
+		},
+	})
+	unused, unusedSeries := report.markUsedMetrics(map[string]struct{}{"up": {}})
+	assert.Equal(t, 1, unused)
+	assert.Equal(t, uint64(7), unusedSeries)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.writeTable(&buf))
+	assert.Equal(t, `SERIES: 10 (-2)
+
+METRIC               SERIES  CHANGE  USED
+http_requests_total  7       new     no
+up                   3       +2      yes
+
+METRIC  LABEL     VALUES  CHANGE  TOP VALUES
+up      instance  3       +2      a (1), b (1)
+up      job       1       new     node (3)
+`, buf.String())
+}
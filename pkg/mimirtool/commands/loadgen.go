@@ -12,6 +12,7 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
+	"os"
 	"sync"
 	"time"
 
@@ -49,6 +50,13 @@ var queryRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 	Buckets:   defBuckets,
 }, []string{"success"})
 
+var queryClassRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "loadgen",
+	Name:      "query_class_request_duration_seconds",
+	Help:      "Duration of the queries of each class of the query mix of the workload.",
+	Buckets:   defBuckets,
+}, []string{"class", "success"})
+
 type LoadgenCommand struct {
 	writeURL       string
 	activeSeries   int
@@ -66,10 +74,20 @@ type LoadgenCommand struct {
 
 	metricsListenAddress string
 
+	workloadFile   string
+	seed           int64
+	duration       time.Duration
+	reportInterval time.Duration
+
 	// Runtime stuff.
 	wg          sync.WaitGroup
 	writeClient remote.WriteClient
 	queryClient v1.API
+
+	// Runtime stuff of the workload.
+	generators []*metricFamilyGenerator
+	queryMix   *queryMix
+	stats      *workloadStats
 }
 
 func (c *LoadgenCommand) Register(app *kingpin.Application, _ EnvVarNames) {
@@ -103,6 +121,15 @@ func (c *LoadgenCommand) Register(app *kingpin.Application, _ EnvVarNames) {
 
 	cmd.Flag("metrics-listen-address", "address to serve metrics on").
 		Default(":8080").StringVar(&loadgenCommand.metricsListenAddress)
+
+	cmd.Flag("workload-file", "workload spec file defining the metric families and the query mix to generate, instead of the series and query flags").
+		Default("").StringVar(&loadgenCommand.workloadFile)
+	cmd.Flag("seed", "seed of the generated workload, overriding the seed of the workload spec file").
+		Default("0").Int64Var(&loadgenCommand.seed)
+	cmd.Flag("duration", "duration of the load generation, 0 to run until interrupted").
+		Default("0s").DurationVar(&loadgenCommand.duration)
+	cmd.Flag("report-interval", "period to print the latency and error stats of the workload").
+		Default("1m").DurationVar(&loadgenCommand.reportInterval)
 }
 
 func (c *LoadgenCommand) run(k *kingpin.ParseContext) error {
@@ -110,6 +137,14 @@ func (c *LoadgenCommand) run(k *kingpin.ParseContext) error {
 		return errors.New("either a -write-url or -query-url flag must be provided to run the loadgen command")
 	}
 
+	var spec *workloadSpec
+	if c.workloadFile != "" {
+		var err error
+		if spec, err = loadWorkloadSpec(c.workloadFile); err != nil {
+			return err
+		}
+	}
+
 	http.Handle("/metrics", promhttp.Handler())
 	go func() {
 		err := http.ListenAndServe(c.metricsListenAddress, nil)
@@ -118,27 +153,28 @@ func (c *LoadgenCommand) run(k *kingpin.ParseContext) error {
 		}
 	}()
 
+	ctx := context.Background()
+	if c.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.duration)
+		defer cancel()
+	}
+
+	if spec != nil {
+		return c.runWorkload(ctx, spec)
+	}
+
 	if c.writeURL != "" {
 		log.Printf("setting up write load gen:\n  url=%s\n  parallelism: %v\n  active_series: %d\n interval: %v\n", c.writeURL, c.parallelism, c.activeSeries, c.scrapeInterval)
-		writeURL, err := url.Parse(c.writeURL)
-		if err != nil {
+		if err := c.setupWriteClient(); err != nil {
 			return err
 		}
 
-		writeClient, err := remote.NewWriteClient("loadgen", &remote.ClientConfig{
-			URL:     &config.URL{URL: writeURL},
-			Timeout: model.Duration(c.writeTimeout),
-		})
-		if err != nil {
-			return err
-		}
-		c.writeClient = writeClient
-
 		c.wg.Add(c.parallelism)
 
 		metricsPerShard := c.activeSeries / c.parallelism
 		for i := 0; i < c.activeSeries; i += metricsPerShard {
-			go c.runWriteShard(i, i+metricsPerShard)
+			go c.runWriteShard(ctx, i, i+metricsPerShard)
 		}
 	} else {
 		log.Println("write load generation is disabled, -write-url flag has not been set")
@@ -146,18 +182,14 @@ func (c *LoadgenCommand) run(k *kingpin.ParseContext) error {
 
 	if c.queryURL != "" {
 		log.Printf("setting up query load gen:\n  url=%s\n  parallelism: %v\n  query: %s", c.queryURL, c.queryParallelism, c.query)
-		queryClient, err := api.NewClient(api.Config{
-			Address: c.queryURL,
-		})
-		if err != nil {
+		if err := c.setupQueryClient(); err != nil {
 			return err
 		}
-		c.queryClient = v1.NewAPI(queryClient)
 
 		c.wg.Add(c.queryParallelism)
 
 		for i := 0; i < c.queryParallelism; i++ {
-			go c.runQueryShard()
+			go c.runQueryShard(ctx)
 		}
 	} else {
 		log.Println("query load generation is disabled, -query-url flag has not been set")
@@ -167,12 +199,47 @@ func (c *LoadgenCommand) run(k *kingpin.ParseContext) error {
 	return nil
 }
 
-func (c *LoadgenCommand) runWriteShard(from, to int) {
+func (c *LoadgenCommand) setupWriteClient() error {
+	writeURL, err := url.Parse(c.writeURL)
+	if err != nil {
+		return err
+	}
+
+	writeClient, err := remote.NewWriteClient("loadgen", &remote.ClientConfig{
+		URL:     &config.URL{URL: writeURL},
+		Timeout: model.Duration(c.writeTimeout),
+	})
+	if err != nil {
+		return err
+	}
+	c.writeClient = writeClient
+	return nil
+}
+
+func (c *LoadgenCommand) setupQueryClient() error {
+	queryClient, err := api.NewClient(api.Config{
+		Address: c.queryURL,
+	})
+	if err != nil {
+		return err
+	}
+	c.queryClient = v1.NewAPI(queryClient)
+	return nil
+}
+
+func (c *LoadgenCommand) runWriteShard(ctx context.Context, from, to int) {
 	defer c.wg.Done()
 	ticker := time.NewTicker(c.scrapeInterval)
+	defer ticker.Stop()
+
 	c.runScrape(from, to)
-	for range ticker.C {
-		c.runScrape(from, to)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.runScrape(from, to)
+		}
 	}
 }
 
@@ -210,9 +277,15 @@ func (c *LoadgenCommand) runBatch(from, to int) error {
 		req.Timeseries = append(req.Timeseries, timeseries)
 	}
 
-	data, err := proto.Marshal(&req)
+	_, err := c.store(&req)
+	return err
+}
+
+// store sends the write request, and returns its duration.
+func (c *LoadgenCommand) store(req *prompb.WriteRequest) (time.Duration, error) {
+	data, err := proto.Marshal(req)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	compressed := snappy.Encode(nil, data)
@@ -220,16 +293,16 @@ func (c *LoadgenCommand) runBatch(from, to int) error {
 	start := time.Now()
 	if err := c.writeClient.Store(context.Background(), compressed); err != nil {
 		writeRequestDuration.WithLabelValues("error").Observe(time.Since(start).Seconds())
-		return err
+		return time.Since(start), err
 	}
 	writeRequestDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
 
-	return nil
+	return time.Since(start), nil
 }
 
-func (c *LoadgenCommand) runQueryShard() {
+func (c *LoadgenCommand) runQueryShard(ctx context.Context) {
 	defer c.wg.Done()
-	for {
+	for ctx.Err() == nil {
 		c.runQuery()
 	}
 }
@@ -251,3 +324,148 @@ func (c *LoadgenCommand) runQuery() {
 	}
 	queryRequestDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
 }
+
+// runWorkload generates the load of the workload spec, until the context is done.
+func (c *LoadgenCommand) runWorkload(ctx context.Context, spec *workloadSpec) error {
+	seed := spec.Seed
+	if c.seed != 0 {
+		seed = c.seed
+	}
+	c.stats = newWorkloadStats()
+
+	if c.writeURL != "" && len(spec.MetricFamilies) > 0 {
+		log.Printf("setting up workload write load gen:\n  url=%s\n  parallelism: %v\n  metric_families: %d\n  interval: %v\n  seed: %d\n", c.writeURL, c.parallelism, len(spec.MetricFamilies), c.scrapeInterval, seed)
+		if err := c.setupWriteClient(); err != nil {
+			return err
+		}
+
+		for _, f := range spec.MetricFamilies {
+			c.generators = append(c.generators, newMetricFamilyGenerator(f, seed))
+		}
+
+		c.wg.Add(c.parallelism)
+		for i := 0; i < c.parallelism; i++ {
+			go c.runWorkloadWriteShard(ctx, rand.New(rand.NewSource(seed+int64(i))), i)
+		}
+
+		for _, g := range c.generators {
+			if g.spec.ChurnRate > 0 {
+				c.wg.Add(1)
+				go c.runChurn(ctx, g)
+			}
+		}
+	} else {
+		log.Println("write load generation is disabled, -write-url flag has not been set or the workload has no metric families")
+	}
+
+	if c.queryURL != "" && len(spec.Queries) > 0 {
+		log.Printf("setting up workload query load gen:\n  url=%s\n  parallelism: %v\n  queries: %d\n  seed: %d\n", c.queryURL, c.queryParallelism, len(spec.Queries), seed)
+		if err := c.setupQueryClient(); err != nil {
+			return err
+		}
+		c.queryMix = newQueryMix(spec.Queries)
+
+		// The query workers have their own random generators, following the ones of the write shards.
+		c.wg.Add(c.queryParallelism)
+		for i := 0; i < c.queryParallelism; i++ {
+			go c.runWorkloadQueryShard(ctx, rand.New(rand.NewSource(seed+int64(c.parallelism+i))))
+		}
+	} else {
+		log.Println("query load generation is disabled, -query-url flag has not been set or the workload has no queries")
+	}
+
+	go func() {
+		ticker := time.NewTicker(c.reportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.stats.report(os.Stdout); err != nil {
+					log.Printf("error reporting workload stats: %v", err)
+				}
+			}
+		}
+	}()
+
+	c.wg.Wait()
+	return c.stats.report(os.Stdout)
+}
+
+func (c *LoadgenCommand) runWorkloadWriteShard(ctx context.Context, rnd *rand.Rand, shard int) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.scrapeInterval)
+	defer ticker.Stop()
+
+	for scrape := 0; ; scrape++ {
+		for _, req := range workloadWriteRequests(c.generators, rnd, shard, c.parallelism, scrape, c.batchSize, time.Now()) {
+			req := req
+			duration, err := c.store(&req)
+			if err != nil {
+				log.Printf("error sending batch: %v", err)
+			}
+			c.stats.observe(writeClassName, duration, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *LoadgenCommand) runChurn(ctx context.Context, g *metricFamilyGenerator) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(time.Duration(g.spec.ChurnInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.churn()
+		}
+	}
+}
+
+func (c *LoadgenCommand) runWorkloadQueryShard(ctx context.Context, rnd *rand.Rand) {
+	defer c.wg.Done()
+	for ctx.Err() == nil {
+		c.runWorkloadQuery(ctx, c.queryMix.pick(rnd))
+	}
+}
+
+func (c *LoadgenCommand) runWorkloadQuery(ctx context.Context, class queryClassSpec) {
+	queryCtx, cancel := context.WithTimeout(ctx, c.queryTimeout)
+	defer cancel()
+
+	end := time.Now().Add(-time.Duration(class.Offset))
+	start := time.Now()
+	var err error
+	if class.Type == queryTypeInstant {
+		_, _, err = c.queryClient.Query(queryCtx, class.Query, end)
+	} else {
+		_, _, err = c.queryClient.QueryRange(queryCtx, class.Query, v1.Range{
+			Start: end.Add(-time.Duration(class.Range)),
+			End:   end,
+			Step:  time.Duration(class.Step),
+		})
+	}
+	duration := time.Since(start)
+
+	// The queries interrupted by the end of the load generation aren't accounted.
+	if ctx.Err() != nil {
+		return
+	}
+
+	success := "success"
+	if err != nil {
+		success = "error"
+		log.Printf("error doing query of class %s: %v", class.Name, err)
+	}
+	queryClassRequestDuration.WithLabelValues(class.Name, success).Observe(duration.Seconds())
+	c.stats.observe(class.Name, duration, err)
+}
@@ -0,0 +1,574 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	metricTypeCounter = "counter"
+	metricTypeGauge   = "gauge"
+
+	labelDistributionUniform = "uniform"
+	labelDistributionZipf    = "zipf"
+
+	queryTypeInstant = "instant"
+	queryTypeRange   = "range"
+
+	defaultZipfSkew = 1.1
+
+	// Maximum number of latencies kept per class to compute the latency percentiles.
+	maxLatencySamples = 10000
+
+	// Name of the class of the write requests in the report.
+	writeClassName = "write"
+)
+
+// workloadSpec is the spec of the workload generated by the load generator, read from the workload file.
+type workloadSpec struct {
+	// Seed of the random generators, so that the generated load can be replayed.
+	Seed           int64              `yaml:"seed"`
+	MetricFamilies []metricFamilySpec `yaml:"metric_families"`
+	Queries        []queryClassSpec   `yaml:"queries"`
+}
+
+// metricFamilySpec is the spec of the series of a metric family.
+type metricFamilySpec struct {
+	Name         string      `yaml:"name"`
+	Type         string      `yaml:"type"`
+	Help         string      `yaml:"help"`
+	Unit         string      `yaml:"unit"`
+	ActiveSeries int         `yaml:"active_series"`
+	Labels       []labelSpec `yaml:"labels"`
+
+	// Ratio of the active series replaced by new series every churn interval.
+	ChurnRate     float64        `yaml:"churn_rate"`
+	ChurnInterval model.Duration `yaml:"churn_interval"`
+
+	// Ratio of the samples sent with a timestamp up to the max delay in the past.
+	OutOfOrderRatio    float64        `yaml:"out_of_order_ratio"`
+	OutOfOrderMaxDelay model.Duration `yaml:"out_of_order_max_delay"`
+
+	// Ratio of the samples sent with an exemplar.
+	ExemplarRatio float64 `yaml:"exemplar_ratio"`
+}
+
+// labelSpec is the spec of the values of a label of the series of a metric family.
+type labelSpec struct {
+	Name string `yaml:"name"`
+	// Values of the label. When not set, cardinality values named "<name>-<index>" are generated.
+	Values      []string `yaml:"values"`
+	Cardinality int      `yaml:"cardinality"`
+	// Distribution of the values across the series, either uniform or zipf.
+	Distribution string  `yaml:"distribution"`
+	ZipfSkew     float64 `yaml:"zipf_skew"`
+}
+
+// queryClassSpec is the spec of a class of queries of the query mix.
+type queryClassSpec struct {
+	Name  string `yaml:"name"`
+	Query string `yaml:"query"`
+	Type  string `yaml:"type"`
+	// Weight of the class in the query mix.
+	Weight int `yaml:"weight"`
+	// Time range and step of range queries.
+	Range model.Duration `yaml:"range"`
+	Step  model.Duration `yaml:"step"`
+	// Offset from now of the time of instant queries, and of the end of range queries.
+	Offset model.Duration `yaml:"offset"`
+}
+
+func loadWorkloadSpec(filename string) (*workloadSpec, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseWorkloadSpec(f)
+}
+
+func parseWorkloadSpec(r io.Reader) (*workloadSpec, error) {
+	spec := &workloadSpec{}
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(spec); err != nil {
+		return nil, errors.Wrap(err, "unable to decode the workload spec")
+	}
+	if err := spec.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid workload spec")
+	}
+	return spec, nil
+}
+
+// validate validates the spec and sets the defaults of the unset options.
+func (s *workloadSpec) validate() error {
+	if len(s.MetricFamilies) == 0 && len(s.Queries) == 0 {
+		return errors.New("at least one metric family or query is required")
+	}
+
+	families := map[string]struct{}{}
+	for i := range s.MetricFamilies {
+		f := &s.MetricFamilies[i]
+		if err := f.validate(); err != nil {
+			return errors.Wrapf(err, "metric family %q", f.Name)
+		}
+		if _, ok := families[f.Name]; ok {
+			return fmt.Errorf("duplicate metric family %q", f.Name)
+		}
+		families[f.Name] = struct{}{}
+	}
+
+	classes := map[string]struct{}{}
+	for i := range s.Queries {
+		q := &s.Queries[i]
+		if err := q.validate(); err != nil {
+			return errors.Wrapf(err, "query %q", q.Name)
+		}
+		if _, ok := classes[q.Name]; ok {
+			return fmt.Errorf("duplicate query %q", q.Name)
+		}
+		classes[q.Name] = struct{}{}
+	}
+	return nil
+}
+
+func (f *metricFamilySpec) validate() error {
+	if !model.IsValidMetricName(model.LabelValue(f.Name)) {
+		return errors.New("invalid metric name")
+	}
+	if f.Type == "" {
+		f.Type = metricTypeGauge
+	}
+	if f.Type != metricTypeCounter && f.Type != metricTypeGauge {
+		return fmt.Errorf("invalid type %q, supported values: %s, %s", f.Type, metricTypeCounter, metricTypeGauge)
+	}
+	if f.ActiveSeries <= 0 {
+		return errors.New("the number of active series must be greater than 0")
+	}
+
+	// The number of label values combinations must allow as many unique series as the active series.
+	combinations := 1
+	labelNames := map[string]struct{}{}
+	for i := range f.Labels {
+		l := &f.Labels[i]
+		if err := l.validate(); err != nil {
+			return errors.Wrapf(err, "label %q", l.Name)
+		}
+		if _, ok := labelNames[l.Name]; ok {
+			return fmt.Errorf("duplicate label %q", l.Name)
+		}
+		labelNames[l.Name] = struct{}{}
+
+		if combinations < f.ActiveSeries {
+			combinations *= l.Cardinality
+		}
+	}
+	if combinations < f.ActiveSeries {
+		return fmt.Errorf("the labels allow %d unique series, which is less than the %d active series", combinations, f.ActiveSeries)
+	}
+
+	if f.ChurnRate < 0 || f.ChurnRate > 1 {
+		return errors.New("the churn rate must be between 0 and 1")
+	}
+	if f.ChurnRate > 0 && f.ChurnInterval <= 0 {
+		return errors.New("the churn interval must be greater than 0 when the churn rate is set")
+	}
+	if f.OutOfOrderRatio < 0 || f.OutOfOrderRatio > 1 {
+		return errors.New("the out-of-order ratio must be between 0 and 1")
+	}
+	if f.OutOfOrderRatio > 0 && f.OutOfOrderMaxDelay <= 0 {
+		return errors.New("the out-of-order max delay must be greater than 0 when the out-of-order ratio is set")
+	}
+	if f.ExemplarRatio < 0 || f.ExemplarRatio > 1 {
+		return errors.New("the exemplar ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+func (l *labelSpec) validate() error {
+	if !model.LabelName(l.Name).IsValid() || l.Name == model.MetricNameLabel {
+		return errors.New("invalid label name")
+	}
+	if len(l.Values) > 0 {
+		if l.Cardinality != 0 && l.Cardinality != len(l.Values) {
+			return errors.New("the cardinality must match the number of values")
+		}
+		l.Cardinality = len(l.Values)
+	}
+	if l.Cardinality <= 0 {
+		return errors.New("either the values or the cardinality are required")
+	}
+
+	if l.Distribution == "" {
+		l.Distribution = labelDistributionUniform
+	}
+	switch l.Distribution {
+	case labelDistributionUniform:
+	case labelDistributionZipf:
+		if l.ZipfSkew == 0 {
+			l.ZipfSkew = defaultZipfSkew
+		}
+		if l.ZipfSkew <= 1 {
+			return errors.New("the zipf skew must be greater than 1")
+		}
+	default:
+		return fmt.Errorf("invalid distribution %q, supported values: %s, %s", l.Distribution, labelDistributionUniform, labelDistributionZipf)
+	}
+	return nil
+}
+
+func (q *queryClassSpec) validate() error {
+	if q.Name == "" {
+		return errors.New("the name is required")
+	}
+	if q.Name == writeClassName {
+		return fmt.Errorf("the name %q is reserved", writeClassName)
+	}
+	if q.Query == "" {
+		return errors.New("the query is required")
+	}
+	if q.Type == "" {
+		q.Type = queryTypeRange
+	}
+	if q.Type != queryTypeInstant && q.Type != queryTypeRange {
+		return fmt.Errorf("invalid type %q, supported values: %s, %s", q.Type, queryTypeInstant, queryTypeRange)
+	}
+	if q.Weight < 0 {
+		return errors.New("the weight can't be negative")
+	}
+	if q.Weight == 0 {
+		q.Weight = 1
+	}
+	if q.Range == 0 {
+		q.Range = model.Duration(time.Hour)
+	}
+	if q.Step == 0 {
+		q.Step = model.Duration(time.Minute)
+	}
+	if q.Range < 0 || q.Step < 0 || q.Offset < 0 {
+		return errors.New("the range, step and offset can't be negative")
+	}
+	return nil
+}
+
+// workloadSeries is a series of a metric family. The series are identified by a sequential ID,
+// from which their labels are generated.
+type workloadSeries struct {
+	id     int
+	labels []prompb.Label
+	// Key of the combination of label values of the series.
+	key string
+}
+
+// metricFamilyGenerator generates the active series of a metric family, and replaces them as they churn.
+// The series are deterministically generated from the seed.
+type metricFamilyGenerator struct {
+	spec metricFamilySpec
+	seed int64
+
+	mtx    sync.Mutex
+	series []workloadSeries
+	// Keys of the combinations of label values of the active series.
+	active map[string]struct{}
+	nextID int
+}
+
+func newMetricFamilyGenerator(spec metricFamilySpec, seed int64) *metricFamilyGenerator {
+	g := &metricFamilyGenerator{
+		spec:   spec,
+		seed:   seed,
+		series: make([]workloadSeries, 0, spec.ActiveSeries),
+		active: make(map[string]struct{}, spec.ActiveSeries),
+	}
+	for i := 0; i < spec.ActiveSeries; i++ {
+		g.series = append(g.series, g.newSeries())
+	}
+	return g
+}
+
+// newSeries generates the next series, with a combination of label values different from the ones of
+// the active series. Must be called with the mutex held, or before the generator is shared.
+func (g *metricFamilyGenerator) newSeries() workloadSeries {
+	id := g.nextID
+	g.nextID++
+
+	rnd := rand.New(rand.NewSource(g.seriesSeed(id)))
+	indexes := make([]int, len(g.spec.Labels))
+	for i, l := range g.spec.Labels {
+		if l.Distribution == labelDistributionZipf && l.Cardinality > 1 {
+			indexes[i] = int(rand.NewZipf(rnd, l.ZipfSkew, 1, uint64(l.Cardinality-1)).Uint64())
+		} else {
+			indexes[i] = rnd.Intn(l.Cardinality)
+		}
+	}
+
+	// When the combination is already used, the next unused combination is picked. There's always one,
+	// since the validation guarantees there are at least as many combinations as active series.
+	key := labelIndexesKey(indexes)
+	for _, ok := g.active[key]; ok; _, ok = g.active[key] {
+		for i := len(indexes) - 1; i >= 0; i-- {
+			indexes[i] = (indexes[i] + 1) % g.spec.Labels[i].Cardinality
+			if indexes[i] != 0 {
+				break
+			}
+		}
+		key = labelIndexesKey(indexes)
+	}
+	g.active[key] = struct{}{}
+
+	labels := make([]prompb.Label, 0, len(indexes)+1)
+	labels = append(labels, prompb.Label{Name: model.MetricNameLabel, Value: g.spec.Name})
+	for i, l := range g.spec.Labels {
+		labels = append(labels, prompb.Label{Name: l.Name, Value: labelValue(l, indexes[i])})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+	return workloadSeries{id: id, labels: labels, key: key}
+}
+
+func (g *metricFamilyGenerator) seriesSeed(id int) int64 {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%d/%s/%d", g.seed, g.spec.Name, id)
+	return int64(h.Sum64())
+}
+
+// churn replaces the oldest active series with new series, according to the churn rate.
+func (g *metricFamilyGenerator) churn() {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	n := int(math.Ceil(g.spec.ChurnRate * float64(len(g.series))))
+	for _, s := range g.series[:n] {
+		delete(g.active, s.key)
+	}
+
+	series := make([]workloadSeries, 0, len(g.series))
+	series = append(series, g.series[n:]...)
+	for i := 0; i < n; i++ {
+		series = append(series, g.newSeries())
+	}
+	g.series = series
+}
+
+// activeSeries returns the active series. The returned slice must not be modified.
+func (g *metricFamilyGenerator) activeSeries() []workloadSeries {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.series
+}
+
+func (g *metricFamilyGenerator) metadata() prompb.MetricMetadata {
+	typ := prompb.MetricMetadata_GAUGE
+	if g.spec.Type == metricTypeCounter {
+		typ = prompb.MetricMetadata_COUNTER
+	}
+	return prompb.MetricMetadata{
+		Type:             typ,
+		MetricFamilyName: g.spec.Name,
+		Help:             g.spec.Help,
+		Unit:             g.spec.Unit,
+	}
+}
+
+// sample returns the sample of the series of the given scrape, and its exemplar if any.
+func (g *metricFamilyGenerator) sample(rnd *rand.Rand, s workloadSeries, scrape int, now time.Time) (prompb.Sample, []prompb.Exemplar) {
+	ts := now
+	if g.spec.OutOfOrderRatio > 0 && rnd.Float64() < g.spec.OutOfOrderRatio {
+		ts = now.Add(-time.Duration(1 + rnd.Int63n(int64(g.spec.OutOfOrderMaxDelay))))
+	}
+
+	var value float64
+	if g.spec.Type == metricTypeCounter {
+		// The counters of the series increase at different rates.
+		value = float64(scrape) * float64(1+s.id%10)
+	} else {
+		value = rnd.Float64() * 100
+	}
+
+	sample := prompb.Sample{Timestamp: ts.UnixNano() / int64(time.Millisecond), Value: value}
+	if g.spec.ExemplarRatio == 0 || rnd.Float64() >= g.spec.ExemplarRatio {
+		return sample, nil
+	}
+	return sample, []prompb.Exemplar{{
+		Labels:    []prompb.Label{{Name: "trace_id", Value: fmt.Sprintf("%016x", rnd.Uint64())}},
+		Value:     value,
+		Timestamp: sample.Timestamp,
+	}}
+}
+
+func labelValue(l labelSpec, index int) string {
+	if len(l.Values) > 0 {
+		return l.Values[index]
+	}
+	return fmt.Sprintf("%s-%d", l.Name, index)
+}
+
+func labelIndexesKey(indexes []int) string {
+	b := strings.Builder{}
+	for _, i := range indexes {
+		fmt.Fprintf(&b, "%d,", i)
+	}
+	return b.String()
+}
+
+// workloadWriteRequests returns the write requests of the given scrape of a write shard. Each shard sends
+// the series whose ID modulo the number of shards is the shard, and the first shard sends the metadata.
+func workloadWriteRequests(generators []*metricFamilyGenerator, rnd *rand.Rand, shard, shards, scrape, batchSize int, now time.Time) []prompb.WriteRequest {
+	var (
+		reqs []prompb.WriteRequest
+		req  prompb.WriteRequest
+	)
+
+	if shard == 0 {
+		for _, g := range generators {
+			req.Metadata = append(req.Metadata, g.metadata())
+		}
+	}
+
+	for _, g := range generators {
+		for _, s := range g.activeSeries() {
+			if s.id%shards != shard {
+				continue
+			}
+
+			sample, exemplars := g.sample(rnd, s, scrape, now)
+			req.Timeseries = append(req.Timeseries, prompb.TimeSeries{
+				Labels:    s.labels,
+				Samples:   []prompb.Sample{sample},
+				Exemplars: exemplars,
+			})
+
+			if len(req.Timeseries) >= batchSize {
+				reqs = append(reqs, req)
+				req = prompb.WriteRequest{}
+			}
+		}
+	}
+
+	if len(req.Timeseries) > 0 || len(req.Metadata) > 0 {
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// queryMix picks the classes of the queries according to their weight.
+type queryMix struct {
+	classes     []queryClassSpec
+	totalWeight int
+}
+
+func newQueryMix(classes []queryClassSpec) *queryMix {
+	m := &queryMix{classes: classes}
+	for _, c := range classes {
+		m.totalWeight += c.Weight
+	}
+	return m
+}
+
+func (m *queryMix) pick(rnd *rand.Rand) queryClassSpec {
+	n := rnd.Intn(m.totalWeight)
+	for _, c := range m.classes {
+		if n < c.Weight {
+			return c
+		}
+		n -= c.Weight
+	}
+	return m.classes[len(m.classes)-1]
+}
+
+// classStats tracks the number of requests, the errors and the latencies of a class of requests.
+type classStats struct {
+	requests  int
+	errors    int
+	latencies []time.Duration
+}
+
+// workloadStats tracks the stats of the write requests and of each class of queries.
+type workloadStats struct {
+	mtx     sync.Mutex
+	classes map[string]*classStats
+	// Used to sample the latencies once the max number of latencies kept per class is reached.
+	rnd *rand.Rand
+}
+
+func newWorkloadStats() *workloadStats {
+	return &workloadStats{
+		classes: map[string]*classStats{},
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (s *workloadStats) observe(class string, latency time.Duration, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	cs, ok := s.classes[class]
+	if !ok {
+		cs = &classStats{}
+		s.classes[class] = cs
+	}
+
+	cs.requests++
+	if err != nil {
+		cs.errors++
+	}
+	if len(cs.latencies) < maxLatencySamples {
+		cs.latencies = append(cs.latencies, latency)
+	} else if i := s.rnd.Intn(cs.requests); i < maxLatencySamples {
+		cs.latencies[i] = latency
+	}
+}
+
+// report writes the stats of each class.
+func (s *workloadStats) report(w io.Writer) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	names := make([]string, 0, len(s.classes))
+	for name := range s.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLASS\tREQUESTS\tERRORS\tP50\tP90\tP99\tMAX")
+	for _, name := range names {
+		cs := s.classes[name]
+		latencies := make([]time.Duration, len(cs.latencies))
+		copy(latencies, cs.latencies)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\n", name, cs.requests, cs.errors,
+			latencyPercentile(latencies, 0.5), latencyPercentile(latencies, 0.9), latencyPercentile(latencies, 0.99), latencyPercentile(latencies, 1))
+	}
+	return tw.Flush()
+}
+
+// latencyPercentile returns the percentile of the sorted latencies.
+func latencyPercentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
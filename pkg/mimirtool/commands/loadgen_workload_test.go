@@ -0,0 +1,306 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkloadSpec(t *testing.T) {
+	spec, err := parseWorkloadSpec(strings.NewReader(`
+seed: 42
+metric_families:
+  - name: http_requests_total
+    type: counter
+    help: Total number of HTTP requests.
+    active_series: 100
+    labels:
+      - name: instance
+        cardinality: 50
+        distribution: zipf
+      - name: status
+        values: ["200", "500"]
+    churn_rate: 0.1
+    churn_interval: 10m
+queries:
+  - name: selective
+    type: instant
+    query: http_requests_total{instance="instance-0"}
+  - name: aggregation
+    query: sum(rate(http_requests_total[5m]))
+    weight: 3
+    range: 6h
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(42), spec.Seed)
+	require.Len(t, spec.MetricFamilies, 1)
+	assert.Equal(t, labelSpec{Name: "instance", Cardinality: 50, Distribution: labelDistributionZipf, ZipfSkew: defaultZipfSkew}, spec.MetricFamilies[0].Labels[0])
+	assert.Equal(t, labelSpec{Name: "status", Values: []string{"200", "500"}, Cardinality: 2, Distribution: labelDistributionUniform}, spec.MetricFamilies[0].Labels[1])
+	assert.Equal(t, model.Duration(10*time.Minute), spec.MetricFamilies[0].ChurnInterval)
+
+	assert.Equal(t, []queryClassSpec{
+		{
+			Name:   "selective",
+			Query:  `http_requests_total{instance="instance-0"}`,
+			Type:   queryTypeInstant,
+			Weight: 1,
+			Range:  model.Duration(time.Hour),
+			Step:   model.Duration(time.Minute),
+		},
+		{
+			Name:   "aggregation",
+			Query:  "sum(rate(http_requests_total[5m]))",
+			Type:   queryTypeRange,
+			Weight: 3,
+			Range:  model.Duration(6 * time.Hour),
+			Step:   model.Duration(time.Minute),
+		},
+	}, spec.Queries)
+}
+
+func TestParseWorkloadSpec_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		spec          string
+		expectedError string
+	}{
+		"empty workload": {
+			spec:          "seed: 1",
+			expectedError: "at least one metric family or query is required",
+		},
+		"unknown field": {
+			spec:          "metric_familes: []",
+			expectedError: "field metric_familes not found",
+		},
+		"not enough label values combinations": {
+			spec: `
+metric_families:
+  - name: up
+    active_series: 10
+    labels:
+      - name: instance
+        cardinality: 3
+      - name: job
+        values: [a, b, c]
+`,
+			expectedError: "the labels allow 9 unique series, which is less than the 10 active series",
+		},
+		"invalid label distribution": {
+			spec: `
+metric_families:
+  - name: up
+    active_series: 1
+    labels:
+      - name: instance
+        cardinality: 3
+        distribution: normal
+`,
+			expectedError: `invalid distribution "normal"`,
+		},
+		"churn rate without churn interval": {
+			spec: `
+metric_families:
+  - name: up
+    active_series: 1
+    churn_rate: 0.5
+`,
+			expectedError: "the churn interval must be greater than 0",
+		},
+		"duplicate query": {
+			spec: `
+queries:
+  - name: up
+    query: up
+  - name: up
+    query: up
+`,
+			expectedError: `duplicate query "up"`,
+		},
+		"invalid query type": {
+			spec: `
+queries:
+  - name: up
+    query: up
+    type: series
+`,
+			expectedError: `invalid type "series"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseWorkloadSpec(strings.NewReader(tc.spec))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectedError)
+		})
+	}
+}
+
+func newTestMetricFamilySpec(activeSeries int) metricFamilySpec {
+	spec := metricFamilySpec{
+		Name:         "http_requests_total",
+		Type:         metricTypeCounter,
+		ActiveSeries: activeSeries,
+		Labels: []labelSpec{
+			{Name: "instance", Cardinality: 10, Distribution: labelDistributionZipf, ZipfSkew: defaultZipfSkew},
+			{Name: "status", Values: []string{"200", "404", "500"}, Cardinality: 3, Distribution: labelDistributionUniform},
+		},
+		ChurnRate:     0.25,
+		ChurnInterval: model.Duration(time.Minute),
+	}
+	return spec
+}
+
+func seriesLabels(series []workloadSeries) []string {
+	labels := make([]string, 0, len(series))
+	for _, s := range series {
+		b := strings.Builder{}
+		for _, l := range s.labels {
+			b.WriteString(l.Name + "=" + l.Value + ",")
+		}
+		labels = append(labels, b.String())
+	}
+	return labels
+}
+
+func TestMetricFamilyGenerator(t *testing.T) {
+	// All the combinations of label values are used.
+	g := newMetricFamilyGenerator(newTestMetricFamilySpec(30), 1)
+	series := seriesLabels(g.activeSeries())
+	require.Len(t, series, 30)
+	assert.ElementsMatch(t, uniqueStrings(series), series)
+	for _, s := range g.activeSeries() {
+		require.Len(t, s.labels, 3)
+		assert.Equal(t, prompb.Label{Name: model.MetricNameLabel, Value: "http_requests_total"}, s.labels[0])
+		assert.Equal(t, "instance", s.labels[1].Name)
+		assert.Regexp(t, "^instance-[0-9]$", s.labels[1].Value)
+		assert.Equal(t, "status", s.labels[2].Name)
+		assert.Contains(t, []string{"200", "404", "500"}, s.labels[2].Value)
+	}
+
+	// The series are generated deterministically from the seed.
+	assert.Equal(t, series, seriesLabels(newMetricFamilyGenerator(newTestMetricFamilySpec(30), 1).activeSeries()))
+
+	g = newMetricFamilyGenerator(newTestMetricFamilySpec(20), 1)
+	other := newMetricFamilyGenerator(newTestMetricFamilySpec(20), 2)
+	assert.NotEqual(t, seriesLabels(g.activeSeries()), seriesLabels(other.activeSeries()))
+
+	// The churn replaces the oldest series.
+	before := g.activeSeries()
+	g.churn()
+	after := g.activeSeries()
+	require.Len(t, after, 20)
+	assert.Equal(t, before[5:], after[:15])
+	for i, s := range after[15:] {
+		assert.Equal(t, 20+i, s.id)
+	}
+	assert.ElementsMatch(t, uniqueStrings(seriesLabels(after)), seriesLabels(after))
+
+	// The churn is deterministic too.
+	same := newMetricFamilyGenerator(newTestMetricFamilySpec(20), 1)
+	same.churn()
+	assert.Equal(t, seriesLabels(after), seriesLabels(same.activeSeries()))
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]struct{}{}
+	unique := []string{}
+	for _, v := range values {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+func TestWorkloadWriteRequests(t *testing.T) {
+	counterSpec := newTestMetricFamilySpec(30)
+	gaugeSpec := metricFamilySpec{
+		Name:               "temperature_celsius",
+		Type:               metricTypeGauge,
+		ActiveSeries:       4,
+		Labels:             []labelSpec{{Name: "room", Cardinality: 4, Distribution: labelDistributionUniform}},
+		OutOfOrderRatio:    1,
+		OutOfOrderMaxDelay: model.Duration(time.Minute),
+		ExemplarRatio:      1,
+	}
+	generators := []*metricFamilyGenerator{newMetricFamilyGenerator(counterSpec, 1), newMetricFamilyGenerator(gaugeSpec, 1)}
+	now := time.Now()
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+
+	series := map[string]struct{}{}
+	for shard := 0; shard < 3; shard++ {
+		reqs := workloadWriteRequests(generators, rand.New(rand.NewSource(int64(shard))), shard, 3, 2, 5, now)
+
+		// The requests are deterministic.
+		assert.Equal(t, reqs, workloadWriteRequests(generators, rand.New(rand.NewSource(int64(shard))), shard, 3, 2, 5, now))
+
+		for i, req := range reqs {
+			if shard == 0 && i == 0 {
+				assert.Equal(t, []prompb.MetricMetadata{
+					{Type: prompb.MetricMetadata_COUNTER, MetricFamilyName: "http_requests_total"},
+					{Type: prompb.MetricMetadata_GAUGE, MetricFamilyName: "temperature_celsius"},
+				}, req.Metadata)
+			} else {
+				assert.Empty(t, req.Metadata)
+			}
+			assert.LessOrEqual(t, len(req.Timeseries), 5)
+
+			for _, ts := range req.Timeseries {
+				series[seriesLabels([]workloadSeries{{labels: ts.Labels}})[0]] = struct{}{}
+				require.Len(t, ts.Samples, 1)
+
+				if ts.Labels[0].Value == "temperature_celsius" {
+					assert.Less(t, ts.Samples[0].Timestamp, nowMs)
+					assert.GreaterOrEqual(t, ts.Samples[0].Timestamp, nowMs-time.Minute.Milliseconds())
+					require.Len(t, ts.Exemplars, 1)
+					assert.Equal(t, "trace_id", ts.Exemplars[0].Labels[0].Name)
+				} else {
+					assert.Equal(t, nowMs, ts.Samples[0].Timestamp)
+					assert.Empty(t, ts.Exemplars)
+				}
+			}
+		}
+	}
+
+	// The shards send all the series.
+	assert.Len(t, series, 34)
+}
+
+func TestQueryMix(t *testing.T) {
+	mix := newQueryMix([]queryClassSpec{{Name: "a", Weight: 1}, {Name: "b", Weight: 3}})
+	rnd := rand.New(rand.NewSource(1))
+
+	picked := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		picked[mix.pick(rnd).Name]++
+	}
+	assert.InDelta(t, 2500, picked["a"], 200)
+	assert.InDelta(t, 7500, picked["b"], 200)
+}
+
+func TestWorkloadStats(t *testing.T) {
+	stats := newWorkloadStats()
+	for i := 1; i <= 100; i++ {
+		stats.observe("query", time.Duration(i)*time.Millisecond, nil)
+	}
+	stats.observe("write", time.Second, errors.New("failed"))
+
+	var buf bytes.Buffer
+	require.NoError(t, stats.report(&buf))
+	assert.Equal(t, `CLASS  REQUESTS  ERRORS  P50   P90   P99   MAX
+query  100       0       50ms  90ms  99ms  100ms
+write  1         1       1s    1s    1s    1s
+`, buf.String())
+}
@@ -0,0 +1,627 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/alecthomas/kingpin.v2"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/mimir"
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	limitSourceOverride = "override"
+	limitSourceDefault  = "default"
+)
+
+// OverridesCommand manages the per-tenant limits overrides of a runtime configuration file.
+type OverridesCommand struct {
+	ClientConfig client.Config
+
+	overridesFile string
+	configFile    string
+	tenantID      string
+	limits        []string
+	removeLimits  []string
+}
+
+// Register the overrides commands and flags with the kingpin application.
+func (c *OverridesCommand) Register(app *kingpin.Application, envVars EnvVarNames) {
+	overridesCmd := app.Command("overrides", "Lint, diff and edit the per-tenant limits overrides of a runtime configuration file.")
+
+	lintCmd := overridesCmd.Command("lint", "Validate the overrides of a runtime configuration file, and show the limits overridden for each tenant.").Action(c.lint)
+	lintCmd.Arg("runtime-config-file", "The runtime configuration file to validate.").Required().ExistingFileVar(&c.overridesFile)
+
+	getCmd := overridesCmd.Command("get", "Show the effective limits of a tenant, and whether they come from the defaults or from an override.").Action(c.get)
+	getCmd.Arg("runtime-config-file", "The runtime configuration file to read the overrides from.").Required().ExistingFileVar(&c.overridesFile)
+	getCmd.Arg("tenant", "The tenant to show the limits of.").Required().StringVar(&c.tenantID)
+	getCmd.Arg("limits", "The limits to show. If empty, all the limits are shown.").StringsVar(&c.limits)
+
+	setCmd := overridesCmd.Command("set", "Set or remove limits overrides of a tenant, validating the runtime configuration file before writing it.").Action(c.set)
+	setCmd.Arg("runtime-config-file", "The runtime configuration file to edit.").Required().ExistingFileVar(&c.overridesFile)
+	setCmd.Arg("tenant", "The tenant to set the limits of.").Required().StringVar(&c.tenantID)
+	setCmd.Arg("limits", "The limits to set, as limit=value, with the value in YAML.").StringsVar(&c.limits)
+	setCmd.Flag("remove", "Limit whose override is removed, so that the default applies. Can be specified multiple times.").StringsVar(&c.removeLimits)
+
+	for _, cmd := range []*kingpin.CmdClause{lintCmd, getCmd, setCmd} {
+		cmd.Flag("config-file", "Grafana Mimir configuration file, whose limits are the defaults of the overrides. If empty, the default values of the limits are used.").Default("").StringVar(&c.configFile)
+	}
+
+	diffCmd := overridesCmd.Command("diff", "Compare the overrides of a runtime configuration file with the ones a Grafana Mimir cluster reports at /runtime_config?mode=diff, using the default limits of the cluster.").Action(c.diff)
+	diffCmd.Arg("runtime-config-file", "The runtime configuration file to compare.").Required().ExistingFileVar(&c.overridesFile)
+	diffCmd.Flag("address", "Address of the Grafana Mimir cluster; alternatively, set "+envVars.Address+".").Envar(envVars.Address).Required().StringVar(&c.ClientConfig.Address)
+	diffCmd.Flag("id", "Grafana Mimir tenant ID; alternatively, set "+envVars.TenantID+".").Envar(envVars.TenantID).Default("").StringVar(&c.ClientConfig.ID)
+	diffCmd.Flag("user", fmt.Sprintf("API user to use when contacting Grafana Mimir; alternatively, set %s. If empty, %s is used instead.", envVars.APIUser, envVars.TenantID)).Default("").Envar(envVars.APIUser).StringVar(&c.ClientConfig.User)
+	diffCmd.Flag("key", "API key to use when contacting Grafana Mimir; alternatively, set "+envVars.APIKey+".").Default("").Envar(envVars.APIKey).StringVar(&c.ClientConfig.Key)
+	diffCmd.Flag("tls-ca-path", "TLS CA certificate to verify Grafana Mimir API as part of mTLS; alternatively, set "+envVars.TLSCAPath+".").Default("").Envar(envVars.TLSCAPath).StringVar(&c.ClientConfig.TLS.CAPath)
+	diffCmd.Flag("tls-cert-path", "TLS client certificate to authenticate with the Grafana Mimir API as part of mTLS; alternatively, set "+envVars.TLSCertPath+".").Default("").Envar(envVars.TLSCertPath).StringVar(&c.ClientConfig.TLS.CertPath)
+	diffCmd.Flag("tls-key-path", "TLS client certificate private key to authenticate with the Grafana Mimir API as part of mTLS; alternatively, set "+envVars.TLSKeyPath+".").Default("").Envar(envVars.TLSKeyPath).StringVar(&c.ClientConfig.TLS.KeyPath)
+	diffCmd.Flag("auth-token", "Authentication token bearer authentication; alternatively, set "+envVars.AuthToken+".").Default("").Envar(envVars.AuthToken).StringVar(&c.ClientConfig.AuthToken)
+}
+
+// loadDefaultLimits loads the default limits from the Grafana Mimir configuration file, if any.
+func (c *OverridesCommand) loadDefaultLimits() (validation.Limits, error) {
+	var config []byte
+	if c.configFile != "" {
+		var err error
+		if config, err = os.ReadFile(c.configFile); err != nil {
+			return validation.Limits{}, err
+		}
+	}
+	defaults, err := parseDefaultLimits(config)
+	if err != nil {
+		return validation.Limits{}, errors.Wrapf(err, "invalid limits in %s", c.configFile)
+	}
+	return defaults, nil
+}
+
+func (c *OverridesCommand) loadOverrides() (*overrides, error) {
+	defaults, err := c.loadDefaultLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(c.overridesFile)
+	if err != nil {
+		return nil, err
+	}
+	o, err := parseOverrides(data, defaults)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid overrides in %s", c.overridesFile)
+	}
+	o.logWarnings()
+	return o, nil
+}
+
+func (c *OverridesCommand) lint(k *kingpin.ParseContext) error {
+	o, err := c.loadOverrides()
+	if err != nil {
+		return err
+	}
+
+	if err := o.writeOverridden(os.Stdout); err != nil {
+		return err
+	}
+	if len(o.warnings) > 0 {
+		log.Infof("%s can be loaded, with the overrides of %d tenants and %d warnings", c.overridesFile, len(o.limits), len(o.warnings))
+		return nil
+	}
+	log.Infof("%s is valid, with the overrides of %d tenants", c.overridesFile, len(o.limits))
+	return nil
+}
+
+func (c *OverridesCommand) get(k *kingpin.ParseContext) error {
+	o, err := c.loadOverrides()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := o.limits[c.tenantID]; !ok {
+		log.Infof("%s has no overrides for tenant %s, the defaults apply", c.overridesFile, c.tenantID)
+	}
+	return o.writeTenant(os.Stdout, c.tenantID, c.limits)
+}
+
+func (c *OverridesCommand) set(k *kingpin.ParseContext) error {
+	if len(c.limits) == 0 && len(c.removeLimits) == 0 {
+		return errors.New("no limit to set or remove")
+	}
+
+	data, err := os.ReadFile(c.overridesFile)
+	if err != nil {
+		return err
+	}
+	data, err = setOverrides(data, c.tenantID, c.limits, c.removeLimits)
+	if err != nil {
+		return err
+	}
+
+	// Write the file only once the edited overrides can be loaded.
+	defaults, err := c.loadDefaultLimits()
+	if err != nil {
+		return err
+	}
+	o, err := parseOverrides(data, defaults)
+	if err != nil {
+		return errors.Wrap(err, "the edited overrides are invalid, the file is left unchanged")
+	}
+	o.logWarnings()
+	if err := os.WriteFile(c.overridesFile, data, 0o644); err != nil {
+		return err
+	}
+	log.Infof("Overrides of tenant %s written to %s", c.tenantID, c.overridesFile)
+
+	names := append([]string{}, c.removeLimits...)
+	for _, limit := range c.limits {
+		names = append(names, strings.SplitN(limit, "=", 2)[0])
+	}
+	return o.writeTenant(os.Stdout, c.tenantID, names)
+}
+
+func (c *OverridesCommand) diff(k *kingpin.ParseContext) error {
+	cli, err := client.New(c.ClientConfig)
+	if err != nil {
+		return err
+	}
+
+	config, err := cli.GetConfig(context.Background())
+	if err != nil {
+		return errors.Wrap(err, "unable to get the configuration of the cluster")
+	}
+	defaults, err := parseDefaultLimits(config)
+	if err != nil {
+		return errors.Wrap(err, "unable to parse the limits of the cluster")
+	}
+
+	data, err := os.ReadFile(c.overridesFile)
+	if err != nil {
+		return err
+	}
+	o, err := parseOverrides(data, defaults)
+	if err != nil {
+		return errors.Wrapf(err, "invalid overrides in %s", c.overridesFile)
+	}
+	o.logWarnings()
+
+	clusterDiff, err := cli.GetRuntimeConfig(context.Background(), "diff")
+	if err != nil {
+		return errors.Wrap(err, "unable to get the runtime configuration of the cluster")
+	}
+	diffs, err := diffOverrides(o, clusterDiff)
+	if err != nil {
+		return err
+	}
+
+	if len(diffs) == 0 {
+		log.Infof("The overrides of %s match the ones of the cluster", c.overridesFile)
+		return nil
+	}
+	return writeOverridesDiffs(os.Stdout, diffs)
+}
+
+// parseDefaultLimits parses the limits of a Grafana Mimir configuration, over the default values of the flags.
+func parseDefaultLimits(config []byte) (validation.Limits, error) {
+	var defaults validation.Limits
+	flagext.DefaultValues(&defaults)
+	validation.SetDefaultLimitsForYAMLUnmarshalling(defaults)
+
+	cfg := struct {
+		Limits validation.Limits `yaml:"limits"`
+	}{Limits: defaults}
+	if err := yaml.Unmarshal(config, &cfg); err != nil {
+		return validation.Limits{}, err
+	}
+	return cfg.Limits, nil
+}
+
+// overrides are the limits of the tenants of a runtime configuration, with the limits they override.
+type overrides struct {
+	defaults   validation.Limits
+	limits     map[string]*validation.Limits
+	overridden map[string]map[string]bool
+
+	// warnings are the limits which Grafana Mimir loads, but which fail the checks of validation.Limits.Validate.
+	warnings []string
+}
+
+// parseOverrides parses the overrides of a runtime configuration over the default limits, failing exactly
+// when Grafana Mimir would fail to load them. The limits failing validation.Limits.Validate are only reported
+// as warnings, because Grafana Mimir doesn't check them.
+func parseOverrides(data []byte, defaults validation.Limits) (*overrides, error) {
+	validation.SetDefaultLimitsForYAMLUnmarshalling(defaults)
+	limits, err := mimir.ParseRuntimeConfigOverrides(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	o := &overrides{defaults: defaults, limits: map[string]*validation.Limits{}, overridden: map[string]map[string]bool{}}
+	tenants := make([]string, 0, len(limits))
+	for tenant, l := range limits {
+		if l != nil {
+			o.limits[tenant] = l
+			tenants = append(tenants, tenant)
+		}
+	}
+	sort.Strings(tenants)
+	if err := defaults.Validate(); err != nil {
+		o.warnings = append(o.warnings, fmt.Sprintf("invalid default limits: %v", err))
+	}
+	for _, tenant := range tenants {
+		if err := o.limits[tenant].Validate(); err != nil {
+			o.warnings = append(o.warnings, fmt.Sprintf("invalid overrides of tenant %s: %v", tenant, err))
+		}
+	}
+	if len(doc.Content) > 0 {
+		if tenants := util.YAMLMappingValue(doc.Content[0], "overrides"); tenants != nil && tenants.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(tenants.Content); i += 2 {
+				keys := map[string]bool{}
+				collectMappingKeys(tenants.Content[i+1], keys)
+				o.overridden[tenants.Content[i].Value] = keys
+			}
+		}
+	}
+	return o, nil
+}
+
+func (o *overrides) logWarnings() {
+	for _, w := range o.warnings {
+		log.Warn(w)
+	}
+}
+
+// collectMappingKeys collects the keys of a mapping, following the aliases and the merge keys.
+func collectMappingKeys(n *yaml.Node, keys map[string]bool) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		collectMappingKeys(n.Alias, keys)
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			collectMappingKeys(c, keys)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == "<<" {
+				collectMappingKeys(n.Content[i+1], keys)
+			} else {
+				keys[n.Content[i].Value] = true
+			}
+		}
+	}
+}
+
+// limitNames returns the names of the limits, in the YAML configuration.
+func limitNames() map[string]bool {
+	names := map[string]bool{}
+	t := reflect.TypeOf(validation.Limits{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// limitValues returns the values of the limits by name, as they are formatted in YAML.
+func limitValues(l *validation.Limits) (map[string]string, error) {
+	values, err := util.YAMLMarshalUnmarshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted := make(map[string]string, len(values))
+	for name, v := range values {
+		if formatted[name], err = formatLimitValue(v); err != nil {
+			return nil, err
+		}
+	}
+	return formatted, nil
+}
+
+// formatLimitValue formats a limit value on a single line, with lists and maps in JSON.
+func formatLimitValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	default:
+		out, err := json.Marshal(v)
+		return string(out), err
+	}
+}
+
+type limitRow struct {
+	Name    string
+	Value   string
+	Default string
+	Source  string
+}
+
+// tenantLimits returns the effective limits of a tenant, or all its limits if names is empty.
+func (o *overrides) tenantLimits(tenant string, names []string) ([]limitRow, error) {
+	defaults, err := limitValues(&o.defaults)
+	if err != nil {
+		return nil, err
+	}
+	values := defaults
+	if l, ok := o.limits[tenant]; ok {
+		if values, err = limitValues(l); err != nil {
+			return nil, err
+		}
+	}
+
+	known := limitNames()
+	if len(names) == 0 {
+		for name := range known {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	rows := make([]limitRow, 0, len(names))
+	for _, name := range names {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown limit %s", name)
+		}
+		row := limitRow{Name: name, Value: values[name], Default: defaults[name], Source: limitSourceDefault}
+		if o.overridden[tenant][name] {
+			row.Source = limitSourceOverride
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// writeTenant writes the effective limits of a tenant, and whether they come from the defaults or an override.
+func (o *overrides) writeTenant(w io.Writer, tenant string, names []string) error {
+	rows, err := o.tenantLimits(tenant, names)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LIMIT\tVALUE\tSOURCE\tDEFAULT")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Name, r.Value, r.Source, r.Default)
+	}
+	return tw.Flush()
+}
+
+// writeOverridden writes the limits overridden by each tenant, warning about the overrides which set
+// a limit to its default value.
+func (o *overrides) writeOverridden(w io.Writer) error {
+	tenants := make([]string, 0, len(o.limits))
+	for tenant := range o.limits {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TENANT\tLIMIT\tVALUE\tDEFAULT")
+	for _, tenant := range tenants {
+		names := make([]string, 0, len(o.overridden[tenant]))
+		for name := range o.overridden[tenant] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		rows, err := o.tenantLimits(tenant, names)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if r.Value == r.Default {
+				log.Warnf("The overrides of tenant %s set %s to its default value %s", tenant, r.Name, r.Default)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", tenant, r.Name, r.Value, r.Default)
+		}
+	}
+	return tw.Flush()
+}
+
+// setOverrides sets and removes limits overrides of a tenant in a runtime configuration, given as limit=value,
+// keeping the rest of the configuration as is.
+func setOverrides(data []byte, tenant string, limits []string, remove []string) ([]byte, error) {
+	known := limitNames()
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("the runtime configuration isn't a YAML mapping")
+	}
+
+	tenants := mappingOrEmpty(root, "overrides")
+	limitsNode := mappingOrEmpty(tenants, tenant)
+	if limitsNode.Kind == yaml.AliasNode {
+		return nil, fmt.Errorf("the overrides of tenant %s are an alias of the anchor %s, edit the anchor instead", tenant, limitsNode.Value)
+	}
+
+	for _, limit := range limits {
+		parts := strings.SplitN(limit, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid limit %q, expected limit=value", limit)
+		}
+		if !known[parts[0]] {
+			return nil, fmt.Errorf("unknown limit %s", parts[0])
+		}
+
+		var value yaml.Node
+		if err := yaml.Unmarshal([]byte(parts[1]), &value); err != nil {
+			return nil, errors.Wrapf(err, "invalid value of limit %s", parts[0])
+		}
+		if len(value.Content) == 0 {
+			return nil, fmt.Errorf("missing value of limit %s", parts[0])
+		}
+		util.SetYAMLMappingValue(limitsNode, parts[0], value.Content[0])
+	}
+
+	for _, name := range remove {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown limit %s", name)
+		}
+		util.RemoveYAMLMappingKey(limitsNode, name)
+	}
+	if len(limitsNode.Content) == 0 {
+		util.RemoveYAMLMappingKey(tenants, tenant)
+	}
+
+	clearMergeTags(&doc)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mappingOrEmpty returns the value of a key of a mapping, setting it to an empty mapping if it's missing or null.
+func mappingOrEmpty(m *yaml.Node, key string) *yaml.Node {
+	if v := util.YAMLMappingValue(m, key); v != nil && v.Tag != "!!null" {
+		return v
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode}
+	util.SetYAMLMappingValue(m, key, v)
+	return v
+}
+
+// clearMergeTags clears the tags of the merge keys, which would otherwise be written as "!!merge <<".
+func clearMergeTags(n *yaml.Node) {
+	if n.Tag == "!!merge" {
+		n.Tag = ""
+	}
+	for _, c := range n.Content {
+		clearMergeTags(c)
+	}
+}
+
+// overridesDiff is a limit whose effective value differs between the runtime configuration and the cluster.
+type overridesDiff struct {
+	Tenant        string
+	Limit         string
+	Local         string
+	LocalSource   string
+	Cluster       string
+	ClusterSource string
+}
+
+// diffOverrides compares the overrides with the ones of the cluster, given as reported at
+// /runtime_config?mode=diff, where only the limits differing from the defaults of the cluster are kept.
+func diffOverrides(o *overrides, clusterDiff []byte) ([]overridesDiff, error) {
+	var cluster struct {
+		Overrides map[string]map[string]interface{} `yaml:"overrides"`
+	}
+	if err := yaml.Unmarshal(clusterDiff, &cluster); err != nil {
+		return nil, errors.Wrap(err, "unable to parse the runtime configuration of the cluster")
+	}
+
+	defaults, err := util.YAMLMarshalUnmarshal(&o.defaults)
+	if err != nil {
+		return nil, err
+	}
+	local := map[string]map[string]interface{}{}
+	for tenant, l := range o.limits {
+		values, err := util.YAMLMarshalUnmarshal(l)
+		if err != nil {
+			return nil, err
+		}
+		if local[tenant], err = util.DiffConfig(defaults, values); err != nil {
+			return nil, err
+		}
+	}
+
+	tenants := map[string]bool{}
+	for tenant := range local {
+		tenants[tenant] = true
+	}
+	for tenant := range cluster.Overrides {
+		tenants[tenant] = true
+	}
+	sortedTenants := make([]string, 0, len(tenants))
+	for tenant := range tenants {
+		sortedTenants = append(sortedTenants, tenant)
+	}
+	sort.Strings(sortedTenants)
+
+	var diffs []overridesDiff
+	for _, tenant := range sortedTenants {
+		limits := map[string]bool{}
+		for limit := range local[tenant] {
+			limits[limit] = true
+		}
+		for limit := range cluster.Overrides[tenant] {
+			limits[limit] = true
+		}
+		sortedLimits := make([]string, 0, len(limits))
+		for limit := range limits {
+			sortedLimits = append(sortedLimits, limit)
+		}
+		sort.Strings(sortedLimits)
+
+		for _, limit := range sortedLimits {
+			d := overridesDiff{Tenant: tenant, Limit: limit}
+			if d.Local, d.LocalSource, err = diffValue(local[tenant], defaults, limit); err != nil {
+				return nil, err
+			}
+			if d.Cluster, d.ClusterSource, err = diffValue(cluster.Overrides[tenant], defaults, limit); err != nil {
+				return nil, err
+			}
+			if d.Local != d.Cluster {
+				diffs = append(diffs, d)
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// diffValue returns the value of a limit in the diff of the overrides of a tenant, or its default value.
+func diffValue(diff, defaults map[string]interface{}, limit string) (string, string, error) {
+	if v, ok := diff[limit]; ok {
+		value, err := formatLimitValue(v)
+		return value, limitSourceOverride, err
+	}
+	value, err := formatLimitValue(defaults[limit])
+	return value, limitSourceDefault, err
+}
+
+func writeOverridesDiffs(w io.Writer, diffs []overridesDiff) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TENANT\tLIMIT\tLOCAL\tCLUSTER")
+	for _, d := range diffs {
+		fmt.Fprintf(tw, "%s\t%s\t%s (%s)\t%s (%s)\n", d.Tenant, d.Limit, d.Local, d.LocalSource, d.Cluster, d.ClusterSource)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRuntimeConfig = `# Runtime configuration.
+overrides:
+  base: &base
+    ingestion_rate: 20000
+    max_global_series_per_user: 150000
+  tenant-1: *base
+  tenant-2:
+    <<: *base
+    # The shard size of the tenant.
+    ingestion_tenant_shard_size: 3
+multi_kv_config:
+  primary: consul
+`
+
+func TestParseOverrides(t *testing.T) {
+	defaults, err := parseDefaultLimits([]byte("limits:\n  max_global_series_per_user: 100000\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 100000, defaults.MaxGlobalSeriesPerUser)
+	assert.Equal(t, 30, defaults.MaxLabelNamesPerSeries)
+
+	o, err := parseOverrides([]byte(testRuntimeConfig), defaults)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, o.writeOverridden(&buf))
+	assert.Equal(t, `TENANT    LIMIT                        VALUE   DEFAULT
+base      ingestion_rate               20000   10000
+base      max_global_series_per_user   150000  100000
+tenant-1  ingestion_rate               20000   10000
+tenant-1  max_global_series_per_user   150000  100000
+tenant-2  ingestion_rate               20000   10000
+tenant-2  ingestion_tenant_shard_size  3       0
+tenant-2  max_global_series_per_user   150000  100000
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, o.writeTenant(&buf, "tenant-2", []string{"ingestion_tenant_shard_size", "max_label_names_per_series", "drop_labels"}))
+	assert.Equal(t, `LIMIT                        VALUE  SOURCE    DEFAULT
+ingestion_tenant_shard_size  3      override  0
+max_label_names_per_series   30     default   30
+drop_labels                  []     default   []
+`, buf.String())
+
+	// The tenants without overrides have the default limits.
+	buf.Reset()
+	require.NoError(t, o.writeTenant(&buf, "tenant-3", []string{"max_global_series_per_user"}))
+	assert.Equal(t, `LIMIT                       VALUE   SOURCE   DEFAULT
+max_global_series_per_user  100000  default  100000
+`, buf.String())
+
+	assert.EqualError(t, o.writeTenant(&buf, "tenant-2", []string{"unknown"}), "unknown limit unknown")
+}
+
+func TestParseOverrides_Invalid(t *testing.T) {
+	defaults, err := parseDefaultLimits(nil)
+	require.NoError(t, err)
+
+	_, err = parseOverrides([]byte("overrides:\n  tenant-1:\n    ingestion_rat: 10\n"), defaults)
+	assert.ErrorContains(t, err, "field ingestion_rat not found")
+
+	_, err = parseOverrides([]byte("overrides:\n  tenant-1:\n    ingestion_rate: 10\n---\noverrides: {}\n"), defaults)
+	assert.Error(t, err)
+
+	// Grafana Mimir loads the limits failing validation, so they're only warnings.
+	o, err := parseOverrides([]byte("overrides:\n  tenant-1:\n    store_gateway_tenant_shard_size: -1\n"), defaults)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invalid overrides of tenant tenant-1: invalid store_gateway_tenant_shard_size -1, the value must be greater or equal to 0"}, o.warnings)
+}
+
+func TestSetOverrides(t *testing.T) {
+	out, err := setOverrides([]byte(testRuntimeConfig), "tenant-2", []string{"ingestion_burst_size=300000", "drop_labels=[pod]"}, []string{"ingestion_tenant_shard_size"})
+	require.NoError(t, err)
+	assert.Equal(t, `# Runtime configuration.
+overrides:
+  base: &base
+    ingestion_rate: 20000
+    max_global_series_per_user: 150000
+  tenant-1: *base
+  tenant-2:
+    <<: *base
+    ingestion_burst_size: 300000
+    drop_labels: [pod]
+multi_kv_config:
+  primary: consul
+`, string(out))
+
+	out, err = setOverrides(nil, "tenant-1", []string{"ingestion_rate=5"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "overrides:\n  tenant-1:\n    ingestion_rate: 5\n", string(out))
+
+	// The tenants are removed once they have no overrides.
+	out, err = setOverrides(out, "tenant-1", nil, []string{"ingestion_rate"})
+	require.NoError(t, err)
+	assert.Equal(t, "overrides: {}\n", string(out))
+
+	_, err = setOverrides([]byte(testRuntimeConfig), "tenant-1", []string{"ingestion_rate=5"}, nil)
+	assert.EqualError(t, err, "the overrides of tenant tenant-1 are an alias of the anchor base, edit the anchor instead")
+
+	_, err = setOverrides([]byte(testRuntimeConfig), "tenant-2", []string{"ingestion_rat=5"}, nil)
+	assert.EqualError(t, err, "unknown limit ingestion_rat")
+
+	_, err = setOverrides([]byte(testRuntimeConfig), "tenant-2", []string{"ingestion_rate"}, nil)
+	assert.EqualError(t, err, `invalid limit "ingestion_rate", expected limit=value`)
+}
+
+func TestDiffOverrides(t *testing.T) {
+	defaults, err := parseDefaultLimits([]byte("limits:\n  max_global_series_per_user: 150000\n"))
+	require.NoError(t, err)
+	o, err := parseOverrides([]byte(testRuntimeConfig), defaults)
+	require.NoError(t, err)
+
+	// The cluster has the previous version of the runtime configuration, where tenant-1 had a lower
+	// ingestion rate and tenant-3 had overrides.
+	diffs, err := diffOverrides(o, []byte(`overrides:
+  base:
+    ingestion_rate: 20000
+  tenant-1:
+    ingestion_rate: 15000
+  tenant-2:
+    ingestion_rate: 20000
+    ingestion_tenant_shard_size: 3
+  tenant-3:
+    max_global_series_per_user: 300000
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []overridesDiff{
+		{Tenant: "tenant-1", Limit: "ingestion_rate", Local: "20000", LocalSource: "override", Cluster: "15000", ClusterSource: "override"},
+		{Tenant: "tenant-3", Limit: "max_global_series_per_user", Local: "150000", LocalSource: "default", Cluster: "300000", ClusterSource: "override"},
+	}, diffs)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeOverridesDiffs(&buf, diffs))
+	assert.Equal(t, `TENANT    LIMIT                       LOCAL             CLUSTER
+tenant-1  ingestion_rate              20000 (override)  15000 (override)
+tenant-3  max_global_series_per_user  150000 (default)  300000 (override)
+`, buf.String())
+}
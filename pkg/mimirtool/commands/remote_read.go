@@ -9,6 +9,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -20,6 +21,7 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/grafana/dskit/concurrency"
 	config_util "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
@@ -43,14 +45,20 @@ type RemoteReadCommand struct {
 	readTimeout time.Duration
 	tsdbPath    string
 
-	selector string
-	from     string
-	to       string
+	selectors   []string
+	from        string
+	to          string
+	parallelism int
+
+	format        string
+	outputFile    string
+	blockDuration time.Duration
+	shardDuration time.Duration
 }
 
 func (c *RemoteReadCommand) Register(app *kingpin.Application, envVars EnvVarNames) {
 	remoteReadCmd := app.Command("remote-read", "Inspect stored series in Grafana Mimir using the remote read API.")
-	exportCmd := remoteReadCmd.Command("export", "Export metrics remote read series into a local TSDB.").Action(c.export)
+	exportCmd := remoteReadCmd.Command("export", "Export metrics remote read series into a local TSDB, or a file in the OpenMetrics or CSV format.").Action(c.export)
 	dumpCmd := remoteReadCmd.Command("dump", "Dump remote read series.").Action(c.dump)
 	statsCmd := remoteReadCmd.Command("stats", "Show statistic of remote read series.").Action(c.stats)
 
@@ -75,9 +83,9 @@ func (c *RemoteReadCommand) Register(app *kingpin.Application, envVars EnvVarNam
 			Default("30s").
 			DurationVar(&c.readTimeout)
 
-		cmd.Flag("selector", `PromQL selector to filter metrics on. To return all metrics '{__name__!=""}' can be used.`).
+		cmd.Flag("selector", `PromQL selector to filter metrics on. To return all metrics '{__name__!=""}' can be used. Can be specified multiple times.`).
 			Default("up").
-			StringVar(&c.selector)
+			StringsVar(&c.selectors)
 
 		cmd.Flag("from", "Start of the time window to select metrics.").
 			Default(now.Add(-time.Hour).Format(time.RFC3339)).
@@ -90,6 +98,21 @@ func (c *RemoteReadCommand) Register(app *kingpin.Application, envVars EnvVarNam
 	exportCmd.Flag("tsdb-path", "Path to the folder where to store the TSDB blocks, if not set a new directory in $TEMP is created.").
 		Default("").
 		StringVar(&c.tsdbPath)
+	exportCmd.Flag("format", "Format of the export: "+strings.Join(exportFormats, ", ")+".").
+		Default(exportFormatTSDB).
+		EnumVar(&c.format, exportFormats...)
+	exportCmd.Flag("output-file", "Path of the file to write the OpenMetrics or CSV export to. If not set, the export is written to the standard output. When the export is split into time shards, each shard is written to its own file, whose name is suffixed with the time range of the shard.").
+		Default("").
+		StringVar(&c.outputFile)
+	exportCmd.Flag("block-duration", "Duration of the TSDB blocks.").
+		Default("2h").
+		DurationVar(&c.blockDuration)
+	exportCmd.Flag("shard-duration", "Split the export into time shards of this duration, queried and written one after the other, to limit the memory used by large exports. It must be a multiple of the block duration. 0 to query the whole time window at once.").
+		Default("0s").
+		DurationVar(&c.shardDuration)
+	exportCmd.Flag("parallelism", "Number of selectors to query concurrently.").
+		Default("4").
+		IntVar(&c.parallelism)
 }
 
 type setTenantIDTransport struct {
@@ -223,8 +246,11 @@ func (c *RemoteReadCommand) readClient() (remote.ReadClient, error) {
 	return readClient, nil
 }
 
+// remoteReadQuery returns the series matching the selector between mint and maxt, in milliseconds.
+type remoteReadQuery func(ctx context.Context, selector string, mint, maxt int64) ([]*prompb.TimeSeries, error)
+
 // prepare() validates the input and prepares the client to query remote read endpoints
-func (c *RemoteReadCommand) prepare() (query func(context.Context) ([]*prompb.TimeSeries, error), from, to time.Time, err error) {
+func (c *RemoteReadCommand) prepare() (query remoteReadQuery, from, to time.Time, err error) {
 	from, err = time.Parse(time.RFC3339, c.from)
 	if err != nil {
 		return nil, time.Time{}, time.Time{}, fmt.Errorf("error parsing from: '%s' value: %w", c.from, err)
@@ -235,9 +261,12 @@ func (c *RemoteReadCommand) prepare() (query func(context.Context) ([]*prompb.Ti
 		return nil, time.Time{}, time.Time{}, fmt.Errorf("error parsing to: '%s' value: %w", c.to, err)
 	}
 
-	matchers, err := parser.ParseMetricSelector(c.selector)
-	if err != nil {
-		return nil, time.Time{}, time.Time{}, err
+	matchers := make(map[string][]*labels.Matcher, len(c.selectors))
+	for _, selector := range c.selectors {
+		matchers[selector], err = parser.ParseMetricSelector(selector)
+		if err != nil {
+			return nil, time.Time{}, time.Time{}, err
+		}
 	}
 
 	readClient, err := c.readClient()
@@ -245,37 +274,52 @@ func (c *RemoteReadCommand) prepare() (query func(context.Context) ([]*prompb.Ti
 		return nil, time.Time{}, time.Time{}, err
 	}
 
-	pbQuery, err := remote.ToQuery(
-		int64(model.TimeFromUnixNano(from.UnixNano())),
-		int64(model.TimeFromUnixNano(to.UnixNano())),
-		matchers,
-		nil,
-	)
-	if err != nil {
-		return nil, time.Time{}, time.Time{}, err
-	}
+	return func(ctx context.Context, selector string, mint, maxt int64) ([]*prompb.TimeSeries, error) {
+		pbQuery, err := remote.ToQuery(mint, maxt, matchers[selector], nil)
+		if err != nil {
+			return nil, err
+		}
 
-	return func(ctx context.Context) ([]*prompb.TimeSeries, error) {
-		log.Infof("Querying time from=%s to=%s with selector=%s", from.Format(time.RFC3339), to.Format(time.RFC3339), c.selector)
+		log.Infof("Querying time from=%s to=%s with selector=%s", model.Time(mint).Time().UTC().Format(time.RFC3339), model.Time(maxt).Time().UTC().Format(time.RFC3339), selector)
 		resp, err := readClient.Read(ctx, pbQuery)
 		if err != nil {
 			return nil, err
 		}
 
+		log.Infof("Queried %d series with selector=%s", len(resp.Timeseries), selector)
 		return resp.Timeseries, nil
 
 	}, from, to, nil
 }
 
+// querySelectors queries the selectors between mint and maxt, with up to parallelism concurrent queries,
+// and merges the series they match.
+func (c *RemoteReadCommand) querySelectors(ctx context.Context, query remoteReadQuery, mint, maxt int64, parallelism int) ([]*prompb.TimeSeries, error) {
+	results := make([][]*prompb.TimeSeries, len(c.selectors))
+	err := concurrency.ForEachJob(ctx, len(c.selectors), parallelism, func(ctx context.Context, idx int) error {
+		var err error
+		results[idx], err = query(ctx, c.selectors[idx], mint, maxt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 1 {
+		return results[0], nil
+	}
+	return mergeSeries(results), nil
+}
+
 func (c *RemoteReadCommand) dump(k *kingpin.ParseContext) error {
-	query, _, _, err := c.prepare()
+	query, from, to, err := c.prepare()
 	if err != nil {
 		return err
 	}
 
-	timeseries, err := query(context.Background())
+	timeseries, err := c.querySelectors(context.Background(), query, timeToMillis(from), timeToMillis(to), 1)
 	if err != nil {
-		return nil
+		return err
 	}
 
 	iterator := newTimeSeriesIterator(timeseries)
@@ -301,14 +345,14 @@ func (c *RemoteReadCommand) dump(k *kingpin.ParseContext) error {
 }
 
 func (c *RemoteReadCommand) stats(k *kingpin.ParseContext) error {
-	query, _, _, err := c.prepare()
+	query, from, to, err := c.prepare()
 	if err != nil {
 		return err
 	}
 
-	timeseries, err := query(context.Background())
+	timeseries, err := c.querySelectors(context.Background(), query, timeToMillis(from), timeToMillis(to), 1)
 	if err != nil {
-		return nil
+		return err
 	}
 
 	num := struct {
@@ -383,11 +427,32 @@ func (c *RemoteReadCommand) stats(k *kingpin.ParseContext) error {
 }
 
 func (c *RemoteReadCommand) export(k *kingpin.ParseContext) error {
+	if c.blockDuration <= 0 {
+		return errors.New("the block duration must be greater than 0")
+	}
+	if c.shardDuration < 0 || c.shardDuration%c.blockDuration != 0 {
+		return errors.New("the shard duration must be a multiple of the block duration")
+	}
+	if c.shardDuration > 0 && c.format != exportFormatTSDB && c.outputFile == "" {
+		return errors.New("the output file is required to split the export into time shards")
+	}
+	if c.parallelism <= 0 {
+		return errors.New("the parallelism must be greater than 0")
+	}
+
 	query, from, to, err := c.prepare()
 	if err != nil {
 		return err
 	}
 
+	if c.format == exportFormatTSDB {
+		return c.exportTSDB(query, from, to)
+	}
+	return c.exportFile(query, from, to)
+}
+
+func (c *RemoteReadCommand) exportTSDB(query remoteReadQuery, from, to time.Time) error {
+	var err error
 	if c.tsdbPath == "" {
 		c.tsdbPath, err = os.MkdirTemp("", "mimirtool-tsdb")
 		if err != nil {
@@ -404,18 +469,6 @@ func (c *RemoteReadCommand) export(k *kingpin.ParseContext) error {
 		log.Infof("Using existing TSDB in path '%s'", c.tsdbPath)
 	}
 
-	mint := model.TimeFromUnixNano(from.UnixNano())
-	maxt := model.TimeFromUnixNano(to.UnixNano())
-
-	timeseries, err := query(context.Background())
-	if err != nil {
-		return err
-	}
-
-	iterator := func() backfill.Iterator {
-		return newTimeSeriesIterator(timeseries)
-	}
-
 	pipeR, pipeW := io.Pipe()
 	go func() {
 		scanner := bufio.NewScanner(pipeR)
@@ -429,8 +482,25 @@ func (c *RemoteReadCommand) export(k *kingpin.ParseContext) error {
 	defer pipeR.Close()
 
 	log.Infof("Store TSDB blocks in '%s'", c.tsdbPath)
-	if err := backfill.CreateBlocks(iterator, int64(mint), int64(maxt), 1000, c.tsdbPath, true, pipeW); err != nil {
-		return err
+	for _, shard := range timeShards(timeToMillis(from), timeToMillis(to), c.shardDuration.Milliseconds()) {
+		timeseries, err := c.querySelectors(context.Background(), query, shard.mint, shard.maxt, c.parallelism)
+		if err != nil {
+			return err
+		}
+
+		iterator := func() backfill.Iterator {
+			return newTimeSeriesIterator(timeseries)
+		}
+
+		if err := backfill.CreateBlocks(iterator, shard.mint, shard.maxt, c.blockDuration.Milliseconds(), 1000, c.tsdbPath, true, pipeW); err != nil {
+			return err
+		}
+
+		samples := 0
+		for _, s := range timeseries {
+			samples += len(s.Samples)
+		}
+		log.Infof("Exported %d series, %d samples from=%s to=%s", len(timeseries), samples, model.Time(shard.mint).Time().UTC().Format(time.RFC3339), model.Time(shard.maxt).Time().UTC().Format(time.RFC3339))
 	}
 
 	// ensure that tsdb directory has WAL, otherwise 'promtool tsdb dump' fails
@@ -443,3 +513,84 @@ func (c *RemoteReadCommand) export(k *kingpin.ParseContext) error {
 
 	return nil
 }
+
+// exportFile writes the export to the output file, or to the standard output. Each time shard is queried and
+// written to its own file, since the columns of the CSV format depend on the exported series, and
+// the OpenMetrics format doesn't allow the samples of a metric to be split across the file.
+func (c *RemoteReadCommand) exportFile(query remoteReadQuery, from, to time.Time) error {
+	for _, shard := range timeShards(timeToMillis(from), timeToMillis(to), c.shardDuration.Milliseconds()) {
+		timeseries, err := c.querySelectors(context.Background(), query, shard.mint, shard.maxt, c.parallelism)
+		if err != nil {
+			return err
+		}
+
+		outputFile := c.outputFile
+		if c.shardDuration > 0 {
+			outputFile = shardOutputFile(c.outputFile, shard)
+		}
+		if err := c.writeExportFile(outputFile, timeseries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RemoteReadCommand) writeExportFile(outputFile string, timeseries []*prompb.TimeSeries) (returnErr error) {
+	var output io.Writer = os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := f.Close(); err != nil && returnErr == nil {
+				returnErr = err
+			}
+		}()
+		output = f
+		log.Infof("Writing the %s export to '%s'", c.format, outputFile)
+	}
+
+	progress := newExportProgress(timeseries)
+	switch c.format {
+	case exportFormatOpenMetrics:
+		return writeOpenMetrics(output, timeseries, progress)
+	default:
+		return writeCSV(output, timeseries, progress)
+	}
+}
+
+// shardOutputFile returns the path of the file of a time shard of the export, suffixing the name of the output
+// file with the time range of the shard, in milliseconds.
+func shardOutputFile(outputFile string, shard timeShard) string {
+	ext := filepath.Ext(outputFile)
+	return fmt.Sprintf("%s-%d-%d%s", strings.TrimSuffix(outputFile, ext), shard.mint, shard.maxt, ext)
+}
+
+func timeToMillis(t time.Time) int64 {
+	return int64(model.TimeFromUnixNano(t.UnixNano()))
+}
+
+// timeShard is a time range, with inclusive bounds in milliseconds.
+type timeShard struct {
+	mint, maxt int64
+}
+
+// timeShards splits the time range between mint and maxt into shards aligned on the duration. It returns a
+// single shard if the duration is 0.
+func timeShards(mint, maxt, duration int64) []timeShard {
+	if duration <= 0 {
+		return []timeShard{{mint: mint, maxt: maxt}}
+	}
+
+	var shards []timeShard
+	for start := mint; start <= maxt; {
+		end := (start/duration+1)*duration - 1
+		if end > maxt {
+			end = maxt
+		}
+		shards = append(shards, timeShard{mint: start, maxt: end})
+		start = end + 1
+	}
+	return shards
+}
@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	exportFormatTSDB        = "tsdb"
+	exportFormatOpenMetrics = "openmetrics"
+	exportFormatCSV         = "csv"
+
+	// The columns of the timestamps and values of the samples, in the CSV exports. Label names
+	// starting with __ are reserved, so they don't collide with the columns of the labels.
+	timestampColumn = "__timestamp__"
+	valueColumn     = "__value__"
+
+	// Number of series between two logs of the progress of an export.
+	exportProgressSeries = 1000
+)
+
+var exportFormats = []string{exportFormatTSDB, exportFormatOpenMetrics, exportFormatCSV}
+
+// exportProgress logs the progress of an export, in number of series.
+type exportProgress struct {
+	totalSeries int
+	series      int
+	samples     int
+}
+
+func newExportProgress(series []*prompb.TimeSeries) *exportProgress {
+	return &exportProgress{totalSeries: len(series)}
+}
+
+func (p *exportProgress) exported(series *prompb.TimeSeries) {
+	p.series++
+	p.samples += len(series.Samples)
+	if p.series%exportProgressSeries == 0 || p.series == p.totalSeries {
+		log.Infof("Exported %d/%d series, %d samples", p.series, p.totalSeries, p.samples)
+	}
+}
+
+// mergeSeries merges the series returned for several selectors, removing the series matched by more than
+// one selector, and sorts them by metric name and labels.
+func mergeSeries(results [][]*prompb.TimeSeries) []*prompb.TimeSeries {
+	type seriesWithLabels struct {
+		series *prompb.TimeSeries
+		labels labels.Labels
+	}
+
+	seen := map[string]struct{}{}
+	var merged []seriesWithLabels
+	for _, result := range results {
+		for _, s := range result {
+			lbls := labelsFromProto(s.Labels)
+			key := lbls.String()
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			merged = append(merged, seriesWithLabels{series: s, labels: lbls})
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		if a, b := merged[i].labels.Get(model.MetricNameLabel), merged[j].labels.Get(model.MetricNameLabel); a != b {
+			return a < b
+		}
+		return labels.Compare(merged[i].labels, merged[j].labels) < 0
+	})
+
+	series := make([]*prompb.TimeSeries, 0, len(merged))
+	for _, s := range merged {
+		series = append(series, s.series)
+	}
+	return series
+}
+
+func labelsFromProto(lbls []prompb.Label) labels.Labels {
+	b := labels.NewBuilder(nil)
+	for _, l := range lbls {
+		b.Set(l.Name, l.Value)
+	}
+	return b.Labels()
+}
+
+// labelNames returns the label names of the series, starting with the metric name.
+func labelNames(series []*prompb.TimeSeries) []string {
+	seen := map[string]struct{}{}
+	for _, s := range series {
+		for _, l := range s.Labels {
+			seen[l.Name] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		if name != model.MetricNameLabel {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := seen[model.MetricNameLabel]; ok {
+		names = append([]string{model.MetricNameLabel}, names...)
+	}
+	return names
+}
+
+func formatSampleValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var openMetricsLabelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeOpenMetrics writes the series, sorted by metric name, in the OpenMetrics text format. The metrics
+// have the unknown type, since the remote read API doesn't return the metadata.
+func writeOpenMetrics(w io.Writer, series []*prompb.TimeSeries, progress *exportProgress) error {
+	bw := bufio.NewWriter(w)
+	lastName := ""
+	for _, s := range series {
+		name := ""
+		var lbls []string
+		for _, l := range s.Labels {
+			if l.Name == model.MetricNameLabel {
+				name = l.Value
+				continue
+			}
+			lbls = append(lbls, fmt.Sprintf(`%s="%s"`, l.Name, openMetricsLabelValueReplacer.Replace(l.Value)))
+		}
+		if name != lastName {
+			fmt.Fprintf(bw, "# TYPE %s unknown\n", name)
+			lastName = name
+		}
+
+		metric := name
+		if len(lbls) > 0 {
+			metric += "{" + strings.Join(lbls, ",") + "}"
+		}
+		for _, sample := range s.Samples {
+			fmt.Fprintf(bw, "%s %s %s\n", metric, formatSampleValue(sample.Value), strconv.FormatFloat(float64(sample.Timestamp)/1000, 'f', -1, 64))
+		}
+		progress.exported(s)
+	}
+	fmt.Fprintln(bw, "# EOF")
+	return bw.Flush()
+}
+
+// writeCSV writes a row for each sample of the series, with a column for each label name, followed by the
+// timestamp in milliseconds and the value of the sample.
+func writeCSV(w io.Writer, series []*prompb.TimeSeries, progress *exportProgress) error {
+	names := labelNames(series)
+	columns := make(map[string]int, len(names))
+	for i, name := range names {
+		columns[name] = i
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(append([]string{}, names...), timestampColumn, valueColumn)); err != nil {
+		return err
+	}
+
+	row := make([]string, len(names)+2)
+	for _, s := range series {
+		for i := range row {
+			row[i] = ""
+		}
+		for _, l := range s.Labels {
+			row[columns[l.Name]] = l.Value
+		}
+		for _, sample := range s.Samples {
+			row[len(names)] = strconv.FormatInt(sample.Timestamp, 10)
+			row[len(names)+1] = formatSampleValue(sample.Value)
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		progress.exported(s)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestExportSeries() []*prompb.TimeSeries {
+	return []*prompb.TimeSeries{
+		{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: `say "hi"`}},
+			Samples: []prompb.Sample{{Timestamp: 1000, Value: 1}, {Timestamp: 2500, Value: 0}},
+		},
+		{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "temperature"}, {Name: "room", Value: "kitchen"}},
+			Samples: []prompb.Sample{{Timestamp: 1000, Value: math.Inf(1)}},
+		},
+	}
+}
+
+func TestMergeSeries(t *testing.T) {
+	series := newTestExportSeries()
+	other := &prompb.TimeSeries{Labels: []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "api"}}}
+
+	merged := mergeSeries([][]*prompb.TimeSeries{series, {other, series[0]}})
+	assert.Equal(t, []*prompb.TimeSeries{series[1], other, series[0]}, merged)
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	series := mergeSeries([][]*prompb.TimeSeries{newTestExportSeries()})
+	require.NoError(t, writeOpenMetrics(&buf, series, newExportProgress(series)))
+	assert.Equal(t, `# TYPE temperature unknown
+temperature{room="kitchen"} +Inf 1
+# TYPE up unknown
+up{job="say \"hi\""} 1 1
+up{job="say \"hi\""} 0 2.5
+# EOF
+`, buf.String())
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	series := newTestExportSeries()
+	require.NoError(t, writeCSV(&buf, series, newExportProgress(series)))
+	assert.Equal(t, `__name__,job,room,__timestamp__,__value__
+up,"say ""hi""",,1000,1
+up,"say ""hi""",,2500,0
+temperature,,kitchen,1000,+Inf
+`, buf.String())
+}
+
+func TestTimeShards(t *testing.T) {
+	assert.Equal(t, []timeShard{{mint: 500, maxt: 4500}}, timeShards(500, 4500, 0))
+	assert.Equal(t, []timeShard{
+		{mint: 500, maxt: 1999},
+		{mint: 2000, maxt: 3999},
+		{mint: 4000, maxt: 4500},
+	}, timeShards(500, 4500, 2000))
+	assert.Equal(t, []timeShard{{mint: 2000, maxt: 3999}}, timeShards(2000, 3999, 2000))
+}
+
+func TestRemoteReadCommand_ExportTSDB(t *testing.T) {
+	blockDuration := 2 * time.Hour
+	from := time.Unix(0, 0).Add(time.Hour)
+	to := from.Add(5 * time.Hour)
+
+	c := &RemoteReadCommand{
+		selectors:     []string{"up", "temperature"},
+		parallelism:   2,
+		tsdbPath:      t.TempDir(),
+		blockDuration: blockDuration,
+		shardDuration: 2 * blockDuration,
+	}
+
+	// The query returns a sample every minute, for the selector's series.
+	var (
+		queriesMtx sync.Mutex
+		queries    []timeShard
+	)
+	query := func(_ context.Context, selector string, mint, maxt int64) ([]*prompb.TimeSeries, error) {
+		queriesMtx.Lock()
+		queries = append(queries, timeShard{mint: mint, maxt: maxt})
+		queriesMtx.Unlock()
+
+		s := &prompb.TimeSeries{Labels: []prompb.Label{{Name: "__name__", Value: selector}}}
+		for ts := mint; ts <= maxt; ts += time.Minute.Milliseconds() {
+			s.Samples = append(s.Samples, prompb.Sample{Timestamp: ts, Value: 1})
+		}
+		return []*prompb.TimeSeries{s}, nil
+	}
+	require.NoError(t, c.exportTSDB(query, from, to))
+
+	// Each selector has been queried for each shard.
+	assert.ElementsMatch(t, []timeShard{
+		{mint: from.UnixMilli(), maxt: (4 * time.Hour).Milliseconds() - 1},
+		{mint: from.UnixMilli(), maxt: (4 * time.Hour).Milliseconds() - 1},
+		{mint: (4 * time.Hour).Milliseconds(), maxt: to.UnixMilli()},
+		{mint: (4 * time.Hour).Milliseconds(), maxt: to.UnixMilli()},
+	}, queries)
+
+	db, err := tsdb.OpenDBReadOnly(c.tsdbPath, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+	blocks, err := db.Blocks()
+	require.NoError(t, err)
+
+	// The blocks are aligned on the block duration, and the max time of a block is its last sample + 1ms.
+	var ranges [][2]int64
+	var samples uint64
+	for _, b := range blocks {
+		ranges = append(ranges, [2]int64{b.Meta().MinTime, b.Meta().MaxTime})
+		samples += b.Meta().Stats.NumSamples
+	}
+	lastSample := func(blockEnd time.Duration) int64 { return (blockEnd - time.Minute).Milliseconds() + 1 }
+	assert.ElementsMatch(t, [][2]int64{
+		{from.UnixMilli(), lastSample(2 * time.Hour)},
+		{(2 * time.Hour).Milliseconds(), lastSample(4 * time.Hour)},
+		{(4 * time.Hour).Milliseconds(), lastSample(6 * time.Hour)},
+		{to.UnixMilli(), to.UnixMilli() + 1},
+	}, ranges)
+	assert.Equal(t, uint64(2*(5*60+1)), samples)
+}
+
+func TestRemoteReadCommand_ExportFileShards(t *testing.T) {
+	blockDuration := 2 * time.Hour
+	from := time.Unix(0, 0).Add(time.Hour)
+	to := from.Add(2 * time.Hour)
+	dir := t.TempDir()
+
+	c := &RemoteReadCommand{
+		selectors:     []string{"up"},
+		parallelism:   1,
+		format:        exportFormatCSV,
+		outputFile:    filepath.Join(dir, "export.csv"),
+		blockDuration: blockDuration,
+		shardDuration: blockDuration,
+	}
+
+	// The query returns a sample at the start and at the end of the queried time range.
+	query := func(_ context.Context, selector string, mint, maxt int64) ([]*prompb.TimeSeries, error) {
+		return []*prompb.TimeSeries{{
+			Labels:  []prompb.Label{{Name: "__name__", Value: selector}},
+			Samples: []prompb.Sample{{Timestamp: mint, Value: 1}, {Timestamp: maxt, Value: 2}},
+		}}, nil
+	}
+	require.NoError(t, c.exportFile(query, from, to))
+
+	// Each shard is written to its own file.
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var files []string
+	for _, e := range entries {
+		files = append(files, e.Name())
+	}
+	assert.Equal(t, []string{"export-3600000-7199999.csv", "export-7200000-10800000.csv"}, files)
+
+	content, err := os.ReadFile(filepath.Join(dir, "export-7200000-10800000.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, `__name__,__timestamp__,__value__
+up,7200000,1
+up,10800000,2
+`, string(content))
+}
@@ -8,10 +8,12 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
@@ -24,6 +26,7 @@ import (
 	"github.com/grafana/mimir/pkg/mimirtool/printer"
 	"github.com/grafana/mimir/pkg/mimirtool/rules"
 	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/grafana/mimir/pkg/mimirtool/rules/unittest"
 )
 
 const (
@@ -82,6 +85,11 @@ type RuleCommand struct {
 	// Rules check flags
 	Strict bool
 
+	// Test Rules Config
+	TestFiles       []string
+	JUnitOutput     string
+	EvaluationDelay time.Duration
+
 	// List Rules Config
 	Format string
 
@@ -130,6 +138,9 @@ func (r *RuleCommand) Register(app *kingpin.Application, envVars EnvVarNames) {
 	checkCmd := rulesCmd.
 		Command("check", "Run various best practice checks against rules.").
 		Action(r.checkRecordingRuleNames)
+	testCmd := rulesCmd.
+		Command("test", "Run the unit tests of rule files, evaluating the rules like the Grafana Mimir ruler does.").
+		Action(r.testRules)
 
 	// Require Mimir cluster address and tentant ID on all these commands
 	for _, c := range []*kingpin.CmdClause{listCmd, printRulesCmd, getRuleGroupCmd, deleteRuleGroupCmd, loadRulesCmd, diffRulesCmd, syncRulesCmd} {
@@ -234,6 +245,11 @@ func (r *RuleCommand) Register(app *kingpin.Application, envVars EnvVarNames) {
 	).StringVar(&r.RuleFilesPath)
 	checkCmd.Flag("strict", "fails rules checks that do not match best practices exactly").BoolVar(&r.Strict)
 
+	// Test Command
+	testCmd.Arg("test-files", "The unit test files, in the promtool test file format.").Required().ExistingFilesVar(&r.TestFiles)
+	testCmd.Flag("junit-output", "File to write the results to, in the JUnit XML format.").StringVar(&r.JUnitOutput)
+	testCmd.Flag("evaluation-delay", "Evaluation delay of the rule groups which don't set one, like the ruler's -ruler.evaluation-delay-duration.").Default("0s").DurationVar(&r.EvaluationDelay)
+
 	// List Command
 	listCmd.Flag("format", "Backend type to interact with: <json|yaml|table>").Default("table").EnumVar(&r.Format, formats...)
 	listCmd.Flag("disable-color", "disable colored output").BoolVar(&r.DisableColor)
@@ -722,6 +738,57 @@ func (r *RuleCommand) checkRecordingRuleNames(k *kingpin.ParseContext) error {
 	return nil
 }
 
+func (r *RuleCommand) testRules(k *kingpin.ParseContext) error {
+	results := unittest.Run(unittest.Config{EvaluationDelay: r.EvaluationDelay}, r.TestFiles...)
+
+	failed := printTestResults(os.Stdout, results)
+
+	if r.JUnitOutput != "" {
+		f, err := os.Create(r.JUnitOutput)
+		if err != nil {
+			return errors.Wrap(err, "unable to create the JUnit output file")
+		}
+		if err := unittest.WriteJUnit(f, results); err != nil {
+			_ = f.Close()
+			return errors.Wrap(err, "unable to write the JUnit output file")
+		}
+		if err := f.Close(); err != nil {
+			return errors.Wrap(err, "unable to write the JUnit output file")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test files failed", failed, len(results))
+	}
+	return nil
+}
+
+// printTestResults prints the results like promtool does, and returns the number of failed test files.
+func printTestResults(w io.Writer, results []unittest.FileResult) int {
+	failed := 0
+	for _, r := range results {
+		fmt.Fprintln(w, "Unit Testing: ", r.Filename)
+		if !r.Failed() {
+			fmt.Fprintln(w, "  SUCCESS")
+			fmt.Fprintln(w)
+			continue
+		}
+
+		failed++
+		fmt.Fprintln(w, "  FAILED:")
+		if r.Err != nil {
+			fmt.Fprintln(w, r.Err.Error())
+			fmt.Fprintln(w)
+		}
+		for _, t := range r.Tests {
+			for _, err := range t.Errors {
+				fmt.Fprintf(w, "    name: %s,\n%s\n\n", t.Name, err.Error())
+			}
+		}
+	}
+	return failed
+}
+
 // Taken from https://github.com/prometheus/prometheus/blob/8c8de46003d1800c9d40121b4a5e5de8582ef6e1/cmd/promtool/main.go#L403
 type compareRuleType struct {
 	metric string
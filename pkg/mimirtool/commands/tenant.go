@@ -0,0 +1,427 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	"github.com/grafana/mimir/pkg/alertmanager/alertstore"
+	alertstore_bucketclient "github.com/grafana/mimir/pkg/alertmanager/alertstore/bucketclient"
+	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/ruler/rulestore"
+	rulestore_bucketclient "github.com/grafana/mimir/pkg/ruler/rulestore/bucketclient"
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+// TenantCommand is the kingpin command to manage tenants.
+type TenantCommand struct {
+	sourceTenant      string
+	destinationTenant string
+
+	sourceBlocksBucketConfig            string
+	destinationBlocksBucketConfig       string
+	sourceRulerBucketConfig             string
+	destinationRulerBucketConfig        string
+	sourceAlertmanagerBucketConfig      string
+	destinationAlertmanagerBucketConfig string
+	bucketConfigHelp                    bool
+
+	minTime string
+	maxTime string
+}
+
+// Register is used to register the command to a parent command.
+func (c *TenantCommand) Register(app *kingpin.Application, _ EnvVarNames) {
+	tenantCmd := app.Command("tenant", "Manage the data and the configuration of tenants.")
+
+	migrateCmd := tenantCmd.Command("migrate", "Copy the blocks, the rule groups and the Alertmanager configuration of a tenant to another cluster or another tenant. The migration can be resumed: the blocks already copied are skipped.").Action(c.migrate)
+	migrateCmd.Flag("source-tenant", "The tenant to migrate.").Required().StringVar(&c.sourceTenant)
+	migrateCmd.Flag("destination-tenant", "The tenant to migrate to. Defaults to the source tenant.").StringVar(&c.destinationTenant)
+	migrateCmd.Flag("source-blocks-bucket-config", "The CLI args to configure the source blocks storage bucket. The blocks aren't migrated if not set.").StringVar(&c.sourceBlocksBucketConfig)
+	migrateCmd.Flag("destination-blocks-bucket-config", "The CLI args to configure the destination blocks storage bucket.").StringVar(&c.destinationBlocksBucketConfig)
+	migrateCmd.Flag("source-ruler-bucket-config", "The CLI args to configure the source ruler storage bucket. The rule groups aren't migrated if not set.").StringVar(&c.sourceRulerBucketConfig)
+	migrateCmd.Flag("destination-ruler-bucket-config", "The CLI args to configure the destination ruler storage bucket.").StringVar(&c.destinationRulerBucketConfig)
+	migrateCmd.Flag("source-alertmanager-bucket-config", "The CLI args to configure the source Alertmanager storage bucket. The Alertmanager configuration isn't migrated if not set.").StringVar(&c.sourceAlertmanagerBucketConfig)
+	migrateCmd.Flag("destination-alertmanager-bucket-config", "The CLI args to configure the destination Alertmanager storage bucket.").StringVar(&c.destinationAlertmanagerBucketConfig)
+	migrateCmd.Flag("bucket-config-help", "Help text explaining how to use the bucket config parameters.").BoolVar(&c.bucketConfigHelp)
+	migrateCmd.Flag("min-time", "Only migrate the blocks with samples after this time, in RFC3339 format.").StringVar(&c.minTime)
+	migrateCmd.Flag("max-time", "Only migrate the blocks with samples before this time, in RFC3339 format.").StringVar(&c.maxTime)
+}
+
+func (c *TenantCommand) migrate(k *kingpin.ParseContext) error {
+	if c.bucketConfigHelp {
+		printBucketConfigHelp("tenant migrate --source-blocks-bucket-config")
+		return nil
+	}
+
+	m := &tenantMigration{
+		logger:            log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr)),
+		sourceTenant:      c.sourceTenant,
+		destinationTenant: c.destinationTenant,
+		minTime:           math.MinInt64,
+		maxTime:           math.MaxInt64,
+	}
+	if m.destinationTenant == "" {
+		m.destinationTenant = m.sourceTenant
+	}
+
+	if c.minTime != "" {
+		t, err := time.Parse(time.RFC3339, c.minTime)
+		if err != nil {
+			return errors.Wrap(err, "error parsing min time")
+		}
+		m.minTime = t.UnixNano() / int64(time.Millisecond)
+	}
+	if c.maxTime != "" {
+		t, err := time.Parse(time.RFC3339, c.maxTime)
+		if err != nil {
+			return errors.Wrap(err, "error parsing max time")
+		}
+		m.maxTime = t.UnixNano() / int64(time.Millisecond)
+	}
+
+	if c.sourceBlocksBucketConfig == "" && c.sourceRulerBucketConfig == "" && c.sourceAlertmanagerBucketConfig == "" {
+		return errors.New("at least one of the source blocks, ruler or Alertmanager bucket configs must be set")
+	}
+
+	ctx := context.Background()
+
+	if c.sourceBlocksBucketConfig != "" {
+		src, dst, err := m.newBuckets(ctx, "blocks", c.sourceBlocksBucketConfig, c.destinationBlocksBucketConfig)
+		if err != nil {
+			return err
+		}
+		if err := m.migrateBlocks(ctx, src, dst); err != nil {
+			return errors.Wrap(err, "failed to migrate the blocks")
+		}
+	}
+
+	if c.sourceRulerBucketConfig != "" {
+		src, dst, err := m.newBuckets(ctx, "ruler", c.sourceRulerBucketConfig, c.destinationRulerBucketConfig)
+		if err != nil {
+			return err
+		}
+		err = m.migrateRuleGroups(ctx, rulestore_bucketclient.NewBucketRuleStore(src, nil, m.logger), rulestore_bucketclient.NewBucketRuleStore(dst, nil, m.logger))
+		if err != nil {
+			return errors.Wrap(err, "failed to migrate the rule groups")
+		}
+	}
+
+	if c.sourceAlertmanagerBucketConfig != "" {
+		src, dst, err := m.newBuckets(ctx, "alertmanager", c.sourceAlertmanagerBucketConfig, c.destinationAlertmanagerBucketConfig)
+		if err != nil {
+			return err
+		}
+		err = m.migrateAlertmanagerConfig(ctx, alertstore_bucketclient.NewBucketAlertStore(src, nil, m.logger), alertstore_bucketclient.NewBucketAlertStore(dst, nil, m.logger))
+		if err != nil {
+			return errors.Wrap(err, "failed to migrate the Alertmanager configuration")
+		}
+	}
+
+	return nil
+}
+
+// tenantMigration copies the blocks, the rule groups and the Alertmanager configuration of a tenant.
+type tenantMigration struct {
+	logger            log.Logger
+	sourceTenant      string
+	destinationTenant string
+
+	// Time range of the blocks to migrate, in milliseconds.
+	minTime int64
+	maxTime int64
+}
+
+func (m *tenantMigration) newBuckets(ctx context.Context, name, sourceConfig, destinationConfig string) (src, dst objstore.Bucket, err error) {
+	if destinationConfig == "" {
+		return nil, nil, fmt.Errorf("the destination %s bucket config must be set", name)
+	}
+
+	srcCfg, err := parseBucketConfig(sourceConfig)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error when parsing the source %s bucket config", name)
+	}
+	dstCfg, err := parseBucketConfig(destinationConfig)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error when parsing the destination %s bucket config", name)
+	}
+
+	if src, err = bucket.NewClient(ctx, srcCfg, "source-"+name, m.logger, nil); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create the source %s bucket client", name)
+	}
+	if dst, err = bucket.NewClient(ctx, dstCfg, "destination-"+name, m.logger, nil); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create the destination %s bucket client", name)
+	}
+	return src, dst, nil
+}
+
+// migrateBlocks copies the blocks of the source tenant within the time range, and updates the bucket
+// index of the destination tenant. The blocks already copied are skipped, so that an interrupted
+// migration can be resumed.
+func (m *tenantMigration) migrateBlocks(ctx context.Context, src, dst objstore.Bucket) error {
+	srcBkt := bucket.NewUserBucketClient(m.sourceTenant, src, nil)
+	dstBkt := bucket.NewUserBucketClient(m.destinationTenant, dst, nil)
+
+	var ids []ulid.ULID
+	err := srcBkt.Iter(ctx, "", func(name string) error {
+		if id, ok := block.IsBlockDir(name); ok {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "list blocks")
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Compare(ids[j]) < 0 })
+
+	copied := 0
+	for _, id := range ids {
+		ok, err := m.migrateBlock(ctx, srcBkt, dstBkt, id)
+		if err != nil {
+			return errors.Wrapf(err, "block %s", id)
+		}
+		if ok {
+			copied++
+		}
+	}
+	level.Info(m.logger).Log("msg", "blocks migrated", "copied", copied, "total", len(ids))
+
+	// The bucket index is updated from the existing one, if any.
+	old, err := bucketindex.ReadIndex(ctx, dst, m.destinationTenant, nil, m.logger)
+	if err != nil && !errors.Is(err, bucketindex.ErrIndexNotFound) {
+		return errors.Wrap(err, "read bucket index")
+	}
+	idx, _, err := bucketindex.NewUpdater(dst, m.destinationTenant, nil, m.logger).UpdateIndex(ctx, old)
+	if err != nil {
+		return errors.Wrap(err, "update bucket index")
+	}
+	if err := bucketindex.WriteIndex(ctx, dst, m.destinationTenant, nil, idx); err != nil {
+		return errors.Wrap(err, "write bucket index")
+	}
+	level.Info(m.logger).Log("msg", "bucket index updated", "blocks", len(idx.Blocks))
+
+	return nil
+}
+
+// migrateBlock copies the files of the block, verifying their checksum, and returns whether it has been
+// copied. The meta.json is copied last, so that the block is only complete in the destination once all
+// its files have been copied.
+func (m *tenantMigration) migrateBlock(ctx context.Context, src, dst objstore.Bucket, id ulid.ULID) (bool, error) {
+	logger := log.With(m.logger, "block", id.String())
+
+	meta, err := readBlockMeta(ctx, src, id)
+	if src.IsObjNotFoundErr(errors.Cause(err)) {
+		level.Warn(logger).Log("msg", "skipped partial block")
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if ok, err := src.Exists(ctx, path.Join(id.String(), metadata.DeletionMarkFilename)); err != nil {
+		return false, err
+	} else if ok {
+		level.Info(logger).Log("msg", "skipped block marked for deletion")
+		return false, nil
+	}
+
+	if meta.MinTime > m.maxTime || meta.MaxTime <= m.minTime {
+		level.Debug(logger).Log("msg", "skipped block out of the time range")
+		return false, nil
+	}
+
+	if ok, err := dst.Exists(ctx, path.Join(id.String(), block.MetaFilename)); err != nil {
+		return false, err
+	} else if ok {
+		level.Info(logger).Log("msg", "skipped block already migrated")
+		return false, nil
+	}
+
+	hashes := map[string]*metadata.ObjectHash{}
+	for _, f := range meta.Thanos.Files {
+		hashes[f.RelPath] = f.Hash
+	}
+
+	var files []string
+	err = src.Iter(ctx, id.String(), func(name string) error {
+		if !strings.HasSuffix(name, "/") {
+			files = append(files, name)
+		}
+		return nil
+	}, objstore.WithRecursiveIter)
+	if err != nil {
+		return false, errors.Wrap(err, "list block files")
+	}
+
+	for _, name := range files {
+		relPath := strings.TrimPrefix(name, id.String()+"/")
+		if relPath == block.MetaFilename || relPath == metadata.DeletionMarkFilename {
+			continue
+		}
+		if err := copyObjectWithChecksum(ctx, src, dst, name, hashes[relPath]); err != nil {
+			return false, errors.Wrapf(err, "copy %s", relPath)
+		}
+	}
+
+	// The blocks of the tenant may have the tenant ID in their external labels.
+	if _, ok := meta.Thanos.Labels[mimir_tsdb.DeprecatedTenantIDExternalLabel]; ok {
+		meta.Thanos.Labels[mimir_tsdb.DeprecatedTenantIDExternalLabel] = m.destinationTenant
+	}
+
+	var buf bytes.Buffer
+	if err := meta.Write(&buf); err != nil {
+		return false, errors.Wrap(err, "encode meta.json")
+	}
+	if err := dst.Upload(ctx, path.Join(id.String(), block.MetaFilename), &buf); err != nil {
+		return false, errors.Wrap(err, "upload meta.json")
+	}
+
+	level.Info(logger).Log("msg", "block migrated", "files", len(files))
+	return true, nil
+}
+
+// migrateRuleGroups copies the rule groups of the source tenant, replacing the existing ones with the same name.
+func (m *tenantMigration) migrateRuleGroups(ctx context.Context, src, dst rulestore.RuleStore) error {
+	groups, err := src.ListRuleGroupsForUserAndNamespace(ctx, m.sourceTenant, "")
+	if err != nil {
+		return errors.Wrap(err, "list rule groups")
+	}
+	if err := src.LoadRuleGroups(ctx, map[string]rulespb.RuleGroupList{m.sourceTenant: groups}); err != nil {
+		return errors.Wrap(err, "load rule groups")
+	}
+
+	for _, g := range groups {
+		g.User = m.destinationTenant
+		if err := dst.SetRuleGroup(ctx, m.destinationTenant, g.Namespace, g); err != nil {
+			return errors.Wrapf(err, "set rule group %s/%s", g.Namespace, g.Name)
+		}
+	}
+	level.Info(m.logger).Log("msg", "rule groups migrated", "groups", len(groups))
+
+	return nil
+}
+
+// migrateAlertmanagerConfig copies the Alertmanager configuration of the source tenant, if any.
+func (m *tenantMigration) migrateAlertmanagerConfig(ctx context.Context, src, dst alertstore.AlertStore) error {
+	cfg, err := src.GetAlertConfig(ctx, m.sourceTenant)
+	if errors.Is(err, alertspb.ErrNotFound) {
+		level.Info(m.logger).Log("msg", "no Alertmanager configuration to migrate")
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "get Alertmanager configuration")
+	}
+
+	cfg.User = m.destinationTenant
+	if err := dst.SetAlertConfig(ctx, cfg); err != nil {
+		return errors.Wrap(err, "set Alertmanager configuration")
+	}
+	level.Info(m.logger).Log("msg", "Alertmanager configuration migrated")
+
+	return nil
+}
+
+func readBlockMeta(ctx context.Context, bkt objstore.Bucket, id ulid.ULID) (*metadata.Meta, error) {
+	rc, err := bkt.Get(ctx, path.Join(id.String(), block.MetaFilename))
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Read(rc)
+}
+
+// copyObjectWithChecksum copies the object, and verifies that the SHA256 checksum of the copy matches the
+// one of the source object, and the expected hash if any.
+func copyObjectWithChecksum(ctx context.Context, src, dst objstore.Bucket, name string, expected *metadata.ObjectHash) error {
+	rc, err := src.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if err := dst.Upload(ctx, name, io.TeeReader(rc, h)); err != nil {
+		return err
+	}
+	checksum := hex.EncodeToString(h.Sum(nil))
+
+	if expected != nil && expected.Func == metadata.SHA256Func && expected.Value != checksum {
+		return fmt.Errorf("checksum mismatch of the source object: expected %s, got %s", expected.Value, checksum)
+	}
+
+	copied, err := objectChecksum(ctx, dst, name)
+	if err != nil {
+		return errors.Wrap(err, "read the copied object")
+	}
+	if copied != checksum {
+		return fmt.Errorf("checksum mismatch of the copied object: expected %s, got %s", checksum, copied)
+	}
+	return nil
+}
+
+func objectChecksum(ctx context.Context, bkt objstore.Bucket, name string) (string, error) {
+	rc, err := bkt.Get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// parseBucketConfig parses the CLI args configuring a storage bucket.
+func parseBucketConfig(args string) (bucket.Config, error) {
+	cfg := bucket.Config{}
+	fs := flag.NewFlagSet("bucket-config", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse(strings.Split(args, " ")); err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// printBucketConfigHelp prints the help of the CLI args configuring a storage bucket.
+func printBucketConfigHelp(example string) {
+	cfg := bucket.Config{}
+	fs := flag.NewFlagSet("bucket-config", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+
+	fmt.Fprintf(fs.Output(), `
+The following help text describes the arguments
+which may be specified in the string that gets
+passed to the bucket config parameters.
+
+Example:
+mimirtool %s='-backend=s3 -s3.endpoint=localhost:9000 -s3.bucket-name=example-bucket'
+
+`, example)
+	fs.Usage()
+}
@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	alertstore_bucketclient "github.com/grafana/mimir/pkg/alertmanager/alertstore/bucketclient"
+	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	rulestore_bucketclient "github.com/grafana/mimir/pkg/ruler/rulestore/bucketclient"
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+func newTestTenantMigration(minTime, maxTime int64) *tenantMigration {
+	return &tenantMigration{
+		logger:            log.NewNopLogger(),
+		sourceTenant:      "user-1",
+		destinationTenant: "user-2",
+		minTime:           minTime,
+		maxTime:           maxTime,
+	}
+}
+
+// uploadTestBlock uploads a block made of a chunks file and a meta.json, with the hash of the chunks file.
+func uploadTestBlock(t *testing.T, bkt objstore.Bucket, userID string, minTime, maxTime int64, chunksHash string) ulid.ULID {
+	id := ulid.MustNew(uint64(minTime), nil)
+	chunks := []byte("chunks of " + id.String())
+	if chunksHash == "" {
+		sum := sha256.Sum256(chunks)
+		chunksHash = hex.EncodeToString(sum[:])
+	}
+
+	meta := metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{ULID: id, MinTime: minTime, MaxTime: maxTime, Version: metadata.TSDBVersion1},
+		Thanos: metadata.Thanos{
+			Version: metadata.ThanosVersion1,
+			Labels:  map[string]string{mimir_tsdb.DeprecatedTenantIDExternalLabel: userID},
+			Files: []metadata.File{
+				{RelPath: "chunks/000001", SizeBytes: int64(len(chunks)), Hash: &metadata.ObjectHash{Func: metadata.SHA256Func, Value: chunksHash}},
+				{RelPath: block.MetaFilename},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, meta.Write(&buf))
+	require.NoError(t, bkt.Upload(context.Background(), path.Join(userID, id.String(), "chunks/000001"), bytes.NewReader(chunks)))
+	require.NoError(t, bkt.Upload(context.Background(), path.Join(userID, id.String(), block.MetaFilename), &buf))
+	return id
+}
+
+func TestTenantMigration_MigrateBlocks(t *testing.T) {
+	ctx := context.Background()
+	src := objstore.NewInMemBucket()
+	dst := objstore.NewInMemBucket()
+
+	inRange := uploadTestBlock(t, src, "user-1", 2000, 3000, "")
+	outOfRange := uploadTestBlock(t, src, "user-1", 0, 1000, "")
+	markedForDeletion := uploadTestBlock(t, src, "user-1", 4000, 5000, "")
+	require.NoError(t, src.Upload(ctx, path.Join("user-1", markedForDeletion.String(), metadata.DeletionMarkFilename), bytes.NewReader([]byte("{}"))))
+	partial := ulid.MustNew(6000, nil)
+	require.NoError(t, src.Upload(ctx, path.Join("user-1", partial.String(), "index"), bytes.NewReader([]byte("index"))))
+	otherTenant := uploadTestBlock(t, src, "user-3", 2500, 3000, "")
+
+	m := newTestTenantMigration(1500, math.MaxInt64)
+	require.NoError(t, m.migrateBlocks(ctx, src, dst))
+
+	// Only the block in the time range has been copied, with the tenant external label of the destination tenant.
+	for _, id := range []ulid.ULID{outOfRange, markedForDeletion, partial, otherTenant} {
+		ok, err := dst.Exists(ctx, path.Join("user-2", id.String(), block.MetaFilename))
+		require.NoError(t, err)
+		assert.False(t, ok, id.String())
+	}
+
+	copiedChunks, err := objectChecksum(ctx, dst, path.Join("user-2", inRange.String(), "chunks/000001"))
+	require.NoError(t, err)
+	srcChunks, err := objectChecksum(ctx, src, path.Join("user-1", inRange.String(), "chunks/000001"))
+	require.NoError(t, err)
+	assert.Equal(t, srcChunks, copiedChunks)
+
+	meta, err := readBlockMeta(ctx, bucket.NewUserBucketClient("user-2", dst, nil), inRange)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{mimir_tsdb.DeprecatedTenantIDExternalLabel: "user-2"}, meta.Thanos.Labels)
+	assert.Equal(t, int64(2000), meta.MinTime)
+
+	// The bucket index of the destination tenant has been generated.
+	idx, err := bucketindex.ReadIndex(ctx, dst, "user-2", nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, idx.Blocks, 1)
+	assert.Equal(t, inRange, idx.Blocks[0].ID)
+
+	// The migration is resumed: the blocks already copied are skipped.
+	require.NoError(t, dst.Delete(ctx, path.Join("user-2", inRange.String(), "chunks/000001")))
+	require.NoError(t, m.migrateBlocks(ctx, src, dst))
+	ok, err := dst.Exists(ctx, path.Join("user-2", inRange.String(), "chunks/000001"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestTenantMigration_MigrateBlocks_ChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	src := objstore.NewInMemBucket()
+	dst := objstore.NewInMemBucket()
+
+	id := uploadTestBlock(t, src, "user-1", 2000, 3000, "0123456789abcdef")
+
+	m := newTestTenantMigration(math.MinInt64, math.MaxInt64)
+	err := m.migrateBlocks(ctx, src, dst)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch of the source object")
+
+	// The block isn't complete in the destination.
+	ok, err := dst.Exists(ctx, path.Join("user-2", id.String(), block.MetaFilename))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestTenantMigration_MigrateRuleGroups(t *testing.T) {
+	ctx := context.Background()
+	src := rulestore_bucketclient.NewBucketRuleStore(objstore.NewInMemBucket(), nil, log.NewNopLogger())
+	dst := rulestore_bucketclient.NewBucketRuleStore(objstore.NewInMemBucket(), nil, log.NewNopLogger())
+
+	for _, g := range []*rulespb.RuleGroupDesc{
+		{Name: "group-1", Namespace: "namespace-1", User: "user-1", Interval: time.Minute, Rules: []*rulespb.RuleDesc{{Record: "rule_1", Expr: "up"}}},
+		{Name: "group-2", Namespace: "namespace-2", User: "user-1", Interval: time.Minute, Rules: []*rulespb.RuleDesc{{Alert: "Alert", Expr: "up == 0"}}},
+	} {
+		require.NoError(t, src.SetRuleGroup(ctx, g.User, g.Namespace, g))
+	}
+
+	m := newTestTenantMigration(math.MinInt64, math.MaxInt64)
+	require.NoError(t, m.migrateRuleGroups(ctx, src, dst))
+
+	g, err := dst.GetRuleGroup(ctx, "user-2", "namespace-1", "group-1")
+	require.NoError(t, err)
+	assert.Equal(t, "user-2", g.User)
+	assert.Equal(t, []*rulespb.RuleDesc{{Record: "rule_1", Expr: "up"}}, g.Rules)
+
+	g, err = dst.GetRuleGroup(ctx, "user-2", "namespace-2", "group-2")
+	require.NoError(t, err)
+	assert.Equal(t, []*rulespb.RuleDesc{{Alert: "Alert", Expr: "up == 0"}}, g.Rules)
+
+	// Migrating the rule groups again replaces them.
+	require.NoError(t, m.migrateRuleGroups(ctx, src, dst))
+	groups, err := dst.ListRuleGroupsForUserAndNamespace(ctx, "user-2", "")
+	require.NoError(t, err)
+	assert.Len(t, groups, 2)
+}
+
+func TestTenantMigration_MigrateAlertmanagerConfig(t *testing.T) {
+	ctx := context.Background()
+	src := alertstore_bucketclient.NewBucketAlertStore(objstore.NewInMemBucket(), nil, log.NewNopLogger())
+	dst := alertstore_bucketclient.NewBucketAlertStore(objstore.NewInMemBucket(), nil, log.NewNopLogger())
+
+	// There's nothing to migrate when the source tenant has no configuration.
+	m := newTestTenantMigration(math.MinInt64, math.MaxInt64)
+	require.NoError(t, m.migrateAlertmanagerConfig(ctx, src, dst))
+	_, err := dst.GetAlertConfig(ctx, "user-2")
+	assert.ErrorIs(t, err, alertspb.ErrNotFound)
+
+	require.NoError(t, src.SetAlertConfig(ctx, alertspb.AlertConfigDesc{
+		User:      "user-1",
+		RawConfig: "route:\n  receiver: default",
+		Templates: []*alertspb.TemplateDesc{{Filename: "default.tmpl", Body: "{{ define \"default\" }}{{ end }}"}},
+	}))
+	require.NoError(t, m.migrateAlertmanagerConfig(ctx, src, dst))
+
+	cfg, err := dst.GetAlertConfig(ctx, "user-2")
+	require.NoError(t, err)
+	assert.Equal(t, alertspb.AlertConfigDesc{
+		User:      "user-2",
+		RawConfig: "route:\n  receiver: default",
+		Templates: []*alertspb.TemplateDesc{{Filename: "default.tmpl", Body: "{{ define \"default\" }}{{ end }}"}},
+	}, cfg)
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/mitchellh/colorstring"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	yaml "gopkg.in/yaml.v3"
 
@@ -24,6 +25,9 @@ var (
 	errDiffRuleLen       = errors.New("rule groups have a different number of rules")
 	errDiffRWConfigs     = errors.New("rule groups have different remote write configs")
 	errDiffSourceTenants = errors.New("rule groups have different source tenants")
+	errDiffBackfill      = errors.New("rule groups have different backfilling of missed iterations")
+	errDiffEvalDelay     = errors.New("rule groups have different evaluation delays")
+	errDiffQueryTimeout  = errors.New("rule groups have different query timeouts")
 )
 
 // NamespaceState is used to denote the difference between the staged namespace
@@ -102,6 +106,18 @@ func CompareGroups(groupOne, groupTwo rwrulefmt.RuleGroup) error {
 		return errDiffSourceTenants
 	}
 
+	if groupOne.BackfillMissedIterations != groupTwo.BackfillMissedIterations {
+		return errDiffBackfill
+	}
+
+	if !durationPointersEqual(groupOne.EvaluationDelay, groupTwo.EvaluationDelay) {
+		return errDiffEvalDelay
+	}
+
+	if groupOne.QueryTimeout != groupTwo.QueryTimeout {
+		return errDiffQueryTimeout
+	}
+
 	for i := range groupOne.Rules {
 		eq := rulesEqual(&groupOne.Rules[i], &groupTwo.Rules[i])
 		if !eq {
@@ -140,6 +156,14 @@ func stringSlicesElementsMatch(s1, s2 []string) bool {
 	return true
 }
 
+// durationPointersEqual returns true if both durations are unset, or set to the same value.
+func durationPointersEqual(d1, d2 *model.Duration) bool {
+	if d1 == nil || d2 == nil {
+		return d1 == d2
+	}
+	return *d1 == *d2
+}
+
 func rulesEqual(a, b *rulefmt.RuleNode) bool {
 	if a.Alert.Value != b.Alert.Value ||
 		a.Record.Value != b.Record.Value ||
@@ -7,7 +7,9 @@ package rules
 
 import (
 	"testing"
+	"time"
 
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
@@ -410,6 +412,88 @@ func TestCompareGroups(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "different backfilling of missed iterations",
+			groupOne: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name: "example_group",
+					Rules: []rulefmt.RuleNode{
+						{
+							Record: yaml.Node{Value: "one"},
+							Expr:   yaml.Node{Value: "up"},
+						},
+					},
+				},
+				BackfillMissedIterations: true,
+			},
+			groupTwo: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name: "example_group",
+					Rules: []rulefmt.RuleNode{
+						{
+							Record: yaml.Node{Value: "one"},
+							Expr:   yaml.Node{Value: "up"},
+						},
+					},
+				},
+			},
+			expectedErr: errDiffBackfill,
+		},
+		{
+			name: "different evaluation delays",
+			groupOne: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name:            "example_group",
+					EvaluationDelay: durationPtr(model.Duration(time.Minute)),
+					Rules: []rulefmt.RuleNode{
+						{
+							Record: yaml.Node{Value: "one"},
+							Expr:   yaml.Node{Value: "up"},
+						},
+					},
+				},
+			},
+			groupTwo: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name: "example_group",
+					Rules: []rulefmt.RuleNode{
+						{
+							Record: yaml.Node{Value: "one"},
+							Expr:   yaml.Node{Value: "up"},
+						},
+					},
+				},
+			},
+			expectedErr: errDiffEvalDelay,
+		},
+		{
+			name: "different query timeouts",
+			groupOne: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name: "example_group",
+					Rules: []rulefmt.RuleNode{
+						{
+							Record: yaml.Node{Value: "one"},
+							Expr:   yaml.Node{Value: "up"},
+						},
+					},
+				},
+				QueryTimeout: model.Duration(30 * time.Second),
+			},
+			groupTwo: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name: "example_group",
+					Rules: []rulefmt.RuleNode{
+						{
+							Record: yaml.Node{Value: "one"},
+							Expr:   yaml.Node{Value: "up"},
+						},
+					},
+				},
+				QueryTimeout: model.Duration(time.Minute),
+			},
+			expectedErr: errDiffQueryTimeout,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -418,3 +502,7 @@ func TestCompareGroups(t *testing.T) {
 		})
 	}
 }
+
+func durationPtr(d model.Duration) *model.Duration {
+	return &d
+}
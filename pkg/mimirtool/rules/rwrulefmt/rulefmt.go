@@ -5,7 +5,10 @@
 
 package rwrulefmt
 
-import "github.com/prometheus/prometheus/model/rulefmt"
+import (
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
 
 // Wrapper around Prometheus rulefmt.
 
@@ -14,6 +17,10 @@ type RuleGroup struct {
 	rulefmt.RuleGroup `yaml:",inline"`
 	// RWConfigs is used by the remote write forwarding ruler
 	RWConfigs []RemoteWriteConfig `yaml:"remote_write,omitempty"`
+	// BackfillMissedIterations enables the backfilling of the missed iterations of the recording rules by the Mimir ruler
+	BackfillMissedIterations bool `yaml:"backfill_missed_iterations,omitempty"`
+	// QueryTimeout is the maximum duration of the evaluation of the rules by the Mimir ruler
+	QueryTimeout model.Duration `yaml:"query_timeout,omitempty"`
 }
 
 // RemoteWriteConfig is used to specify a remote write endpoint
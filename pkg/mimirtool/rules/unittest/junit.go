@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package unittest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results in the JUnit XML format, with a test suite per test file and a test case
+// per test group. A test file which can't be loaded is reported as a test case in error.
+func WriteJUnit(w io.Writer, results []FileResult) error {
+	report := junitTestSuites{}
+
+	for _, r := range results {
+		suite := junitTestSuite{Name: r.Filename}
+
+		if r.Err != nil {
+			suite.Errors++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      r.Filename,
+				ClassName: r.Filename,
+				Time:      formatSeconds(0),
+				Error:     &junitMessage{Message: "unable to load the test file", Contents: r.Err.Error()},
+			})
+		}
+
+		var seconds float64
+		for _, t := range r.Tests {
+			tc := junitTestCase{
+				Name:      t.Name,
+				ClassName: r.Filename,
+				Time:      formatSeconds(t.Duration.Seconds()),
+			}
+			if len(t.Errors) > 0 {
+				suite.Failures++
+				msgs := make([]string, 0, len(t.Errors))
+				for _, err := range t.Errors {
+					msgs = append(msgs, err.Error())
+				}
+				tc.Failure = &junitMessage{
+					Message:  fmt.Sprintf("%d failed assertions", len(t.Errors)),
+					Contents: strings.Join(msgs, "\n"),
+				}
+			}
+			seconds += t.Duration.Seconds()
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Tests = len(suite.Cases)
+		suite.Time = formatSeconds(seconds)
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func formatSeconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
@@ -0,0 +1,711 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+// Provenance-includes-location: https://github.com/prometheus/prometheus/blob/main/cmd/promtool/unittest.go
+// Provenance-includes-license: Apache-2.0
+// Provenance-includes-copyright: The Prometheus Authors.
+
+package unittest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/weaveworks/common/user"
+	yaml "gopkg.in/yaml.v3"
+
+	mimirrules "github.com/grafana/mimir/pkg/mimirtool/rules"
+	"github.com/grafana/mimir/pkg/querier/engine"
+	"github.com/grafana/mimir/pkg/querier/tenantfederation"
+	ruler_tenantfederation "github.com/grafana/mimir/pkg/ruler/tenantfederation"
+)
+
+// DefaultTenantID is the tenant owning the rules and the input series of a test group which doesn't set one.
+const DefaultTenantID = "anonymous"
+
+// Config configures how the rules are evaluated.
+type Config struct {
+	// EvaluationDelay is the evaluation delay of the rule groups which don't set one,
+	// like the ruler's -ruler.evaluation-delay-duration.
+	EvaluationDelay time.Duration
+}
+
+// FileResult is the result of the unit tests of a test file.
+type FileResult struct {
+	Filename string
+	// Err is set when the test file or its rule files can't be loaded, in which case no test is run.
+	Err   error
+	Tests []TestResult
+}
+
+// Failed returns whether the test file couldn't be loaded or any of its tests failed.
+func (r FileResult) Failed() bool {
+	if r.Err != nil {
+		return true
+	}
+	for _, t := range r.Tests {
+		if len(t.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// TestResult is the result of a test group of a test file.
+type TestResult struct {
+	Name     string
+	Duration time.Duration
+	Errors   []error
+}
+
+// Run runs the unit tests of the test files. The test files use the format of promtool test files,
+// with the additional tenant fields of the test groups, input series and PromQL expression tests.
+func Run(cfg Config, files ...string) []FileResult {
+	// Like Mimir does when tenant federation is enabled, so that the federated rule groups query the
+	// series of each of their source tenants.
+	tenant.WithDefaultResolver(tenant.NewMultiResolver())
+
+	results := make([]FileResult, 0, len(files))
+	for _, f := range files {
+		results = append(results, runFile(cfg, f))
+	}
+	return results
+}
+
+func runFile(cfg Config, filename string) FileResult {
+	result := FileResult{Filename: filename}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	var unitTestInp unitTestFile
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&unitTestInp); err != nil {
+		result.Err = errors.Wrap(err, "unable to parse the test file")
+		return result
+	}
+	if err := resolveAndGlobFilepaths(filepath.Dir(filename), &unitTestInp); err != nil {
+		result.Err = err
+		return result
+	}
+
+	if unitTestInp.EvaluationInterval == 0 {
+		unitTestInp.EvaluationInterval = model.Duration(1 * time.Minute)
+	}
+	evalInterval := time.Duration(unitTestInp.EvaluationInterval)
+
+	// Giving number for groups mentioned in the file for ordering.
+	// Lower number group should be evaluated before higher number group.
+	groupOrderMap := make(map[string]int)
+	for i, gn := range unitTestInp.GroupEvalOrder {
+		if _, ok := groupOrderMap[gn]; ok {
+			result.Err = fmt.Errorf("group name repeated in evaluation order: %s", gn)
+			return result
+		}
+		groupOrderMap[gn] = i
+	}
+
+	var groups []ruleGroup
+	for _, rf := range unitTestInp.RuleFiles {
+		nss, errs := mimirrules.Parse(rf)
+		if len(errs) > 0 {
+			result.Err = errors.Wrapf(errs[0], "unable to parse the rule file %s", rf)
+			return result
+		}
+		for _, ns := range nss {
+			for _, g := range ns.Groups {
+				groups = append(groups, ruleGroup{file: rf, group: g.RuleGroup})
+			}
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groupOrderMap[groups[i].group.Name] < groupOrderMap[groups[j].group.Name]
+	})
+
+	for i, t := range unitTestInp.Tests {
+		name := t.TestGroupName
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+
+		start := time.Now()
+		errs := t.test(cfg, evalInterval, groups)
+		result.Tests = append(result.Tests, TestResult{Name: name, Duration: time.Since(start), Errors: errs})
+	}
+	return result
+}
+
+// unitTestFile holds the contents of a single unit test file.
+type unitTestFile struct {
+	RuleFiles          []string       `yaml:"rule_files"`
+	EvaluationInterval model.Duration `yaml:"evaluation_interval,omitempty"`
+	GroupEvalOrder     []string       `yaml:"group_eval_order"`
+	Tests              []testGroup    `yaml:"tests"`
+}
+
+// resolveAndGlobFilepaths joins all relative paths in a configuration
+// with a given base directory and replaces all globs with matching files.
+func resolveAndGlobFilepaths(baseDir string, utf *unitTestFile) error {
+	var globbedFiles []string
+	for _, rf := range utf.RuleFiles {
+		if rf != "" && !filepath.IsAbs(rf) {
+			rf = filepath.Join(baseDir, rf)
+		}
+
+		m, err := filepath.Glob(rf)
+		if err != nil {
+			return err
+		}
+		if len(m) == 0 {
+			return fmt.Errorf("no rule file matches the pattern %s", rf)
+		}
+		globbedFiles = append(globbedFiles, m...)
+	}
+	utf.RuleFiles = globbedFiles
+	return nil
+}
+
+// ruleGroup is a rule group read from a rule file.
+type ruleGroup struct {
+	file  string
+	group rulefmt.RuleGroup
+}
+
+// testGroup is a group of input series and tests associated with it.
+type testGroup struct {
+	Interval        model.Duration   `yaml:"interval"`
+	InputSeries     []series         `yaml:"input_series"`
+	AlertRuleTests  []alertTestCase  `yaml:"alert_rule_test,omitempty"`
+	PromqlExprTests []promqlTestCase `yaml:"promql_expr_test,omitempty"`
+	ExternalLabels  labels.Labels    `yaml:"external_labels,omitempty"`
+	ExternalURL     string           `yaml:"external_url,omitempty"`
+	TestGroupName   string           `yaml:"name,omitempty"`
+	// Tenant owning the rules, and the input series which don't set one.
+	Tenant string `yaml:"tenant,omitempty"`
+}
+
+// test performs the unit tests.
+func (tg *testGroup) test(cfg Config, evalInterval time.Duration, ruleGroups []ruleGroup) []error {
+	if tg.Tenant == "" {
+		tg.Tenant = DefaultTenantID
+	}
+	if tg.Interval == 0 {
+		tg.Interval = model.Duration(evalInterval)
+	}
+
+	// Setup a testing suite per tenant, the tenant owning the rules always having one to write their results.
+	suites := map[string]*promql.LazyLoader{}
+	for _, tenantID := range tg.tenants() {
+		suite, err := promql.NewLazyLoader(nil, tg.seriesLoadingString(tenantID), promql.LazyLoaderOpts{EnableAtModifier: true, EnableNegativeOffset: true})
+		if err != nil {
+			return []error{errors.Wrapf(err, "tenant %s", tenantID)}
+		}
+		defer suite.Close()
+		suites[tenantID] = suite
+	}
+
+	// The rules are evaluated with the engine options of the ruler, which queries the series of the tenant
+	// owning them or, for the federated rule groups, the series of their source tenants.
+	var engineCfg engine.Config
+	flagext.DefaultValues(&engineCfg)
+	eng := promql.NewEngine(engine.NewPromQLEngineOptions(engineCfg, nil, log.NewNopLogger(), nil))
+
+	queryable := tenantsQueryable(suites)
+	federatedQueryable := tenantfederation.NewQueryable(queryable, false, log.NewNopLogger())
+
+	externalURL, err := url.Parse(tg.ExternalURL)
+	if err != nil {
+		return []error{errors.Wrap(err, "invalid external_url")}
+	}
+
+	ctx := user.InjectOrgID(context.Background(), tg.Tenant)
+	opts := &rules.ManagerOptions{
+		ExternalURL: externalURL,
+		QueryFunc:   ruler_tenantfederation.QueryFunc(rules.EngineQueryFunc(eng, queryable), rules.EngineQueryFunc(eng, federatedQueryable)),
+		NotifyFunc:  func(ctx context.Context, expr string, alerts ...*rules.Alert) {},
+		Context:     ctx,
+		Appendable:  suites[tg.Tenant].Storage(),
+		Queryable:   queryable,
+		Logger:      log.NewNopLogger(),
+		Metrics:     rules.NewGroupMetrics(nil),
+		DefaultEvaluationDelay: func() time.Duration {
+			return cfg.EvaluationDelay
+		},
+	}
+	groups, err := tg.newGroups(ruleGroups, opts)
+	if err != nil {
+		return []error{err}
+	}
+
+	// Bounds for evaluating the rules.
+	mint := time.Unix(0, 0).UTC()
+	maxt := mint.Add(tg.maxEvalTime())
+
+	// Pre-processing some data for testing alerts.
+	// All this preparation is so that we can test alerts as we evaluate the rules.
+	// This avoids storing them in memory, as the number of evals might be high.
+
+	// All the `eval_time` for which we have unit tests for alerts.
+	alertEvalTimesMap := map[model.Duration]struct{}{}
+	// Map of all the eval_time+alertname combination present in the unit tests.
+	alertsInTest := make(map[model.Duration]map[string]struct{})
+	// Map of all the unit tests for given eval_time.
+	alertTests := make(map[model.Duration][]alertTestCase)
+	for _, alert := range tg.AlertRuleTests {
+		if alert.Alertname == "" {
+			return []error{fmt.Errorf("an item under alert_rule_test misses required attribute alertname at eval_time %v", alert.EvalTime)}
+		}
+		alertEvalTimesMap[alert.EvalTime] = struct{}{}
+
+		if _, ok := alertsInTest[alert.EvalTime]; !ok {
+			alertsInTest[alert.EvalTime] = make(map[string]struct{})
+		}
+		alertsInTest[alert.EvalTime][alert.Alertname] = struct{}{}
+
+		alertTests[alert.EvalTime] = append(alertTests[alert.EvalTime], alert)
+	}
+	alertEvalTimes := make([]model.Duration, 0, len(alertEvalTimesMap))
+	for k := range alertEvalTimesMap {
+		alertEvalTimes = append(alertEvalTimes, k)
+	}
+	sort.Slice(alertEvalTimes, func(i, j int) bool {
+		return alertEvalTimes[i] < alertEvalTimes[j]
+	})
+
+	// Current index in alertEvalTimes what we are looking at.
+	curr := 0
+
+	var errs []error
+	for ts := mint; ts.Before(maxt) || ts.Equal(maxt); ts = ts.Add(evalInterval) {
+		for _, suite := range suites {
+			suite.WithSamplesTill(ts, func(err error) {
+				if err != nil {
+					errs = append(errs, err)
+				}
+			})
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+
+		// Collects the alerts asked for unit testing.
+		var evalErrs []error
+		for _, g := range groups {
+			g.Eval(ruler_tenantfederation.GroupContextFunc(ctx, g), ts)
+			for _, r := range g.Rules() {
+				if r.LastError() != nil {
+					evalErrs = append(evalErrs, fmt.Errorf("    rule: %s, time: %s, err: %v",
+						r.Name(), ts.Sub(time.Unix(0, 0).UTC()), r.LastError()))
+				}
+			}
+		}
+		errs = append(errs, evalErrs...)
+		// Only end testing at this point if errors occurred evaluating above,
+		// rather than any test failures already collected in errs.
+		if len(evalErrs) > 0 {
+			return errs
+		}
+
+		for {
+			if !(curr < len(alertEvalTimes) && ts.Sub(mint) <= time.Duration(alertEvalTimes[curr]) &&
+				time.Duration(alertEvalTimes[curr]) < ts.Add(evalInterval).Sub(mint)) {
+				break
+			}
+
+			// We need to check alerts for this time.
+			// If 'ts <= `eval_time=alertEvalTimes[curr]` < ts+evalInterval'
+			// then we compare alerts with the Eval at `ts`.
+			t := alertEvalTimes[curr]
+
+			presentAlerts := alertsInTest[t]
+			got := make(map[string]labelsAndAnnotations)
+
+			// Same Alert name can be present in multiple groups.
+			// Hence we collect them all to check against expected alerts.
+			for _, g := range groups {
+				for _, r := range g.Rules() {
+					ar, ok := r.(*rules.AlertingRule)
+					if !ok {
+						continue
+					}
+					if _, ok := presentAlerts[ar.Name()]; !ok {
+						continue
+					}
+
+					var alerts labelsAndAnnotations
+					for _, a := range ar.ActiveAlerts() {
+						if a.State == rules.StateFiring {
+							alerts = append(alerts, labelAndAnnotation{
+								Labels:      append(labels.Labels{}, a.Labels...),
+								Annotations: append(labels.Labels{}, a.Annotations...),
+							})
+						}
+					}
+
+					got[ar.Name()] = append(got[ar.Name()], alerts...)
+				}
+			}
+
+			for _, testcase := range alertTests[t] {
+				// Checking alerts.
+				gotAlerts := got[testcase.Alertname]
+
+				var expAlerts labelsAndAnnotations
+				for _, a := range testcase.ExpAlerts {
+					// User gives only the labels from alerting rule, which doesn't
+					// include this label (added by the ruler during Eval).
+					expLabels := labels.NewBuilder(labels.FromMap(a.ExpLabels)).Set(labels.AlertName, testcase.Alertname).Labels()
+
+					expAlerts = append(expAlerts, labelAndAnnotation{
+						Labels:      expLabels,
+						Annotations: labels.FromMap(a.ExpAnnotations),
+					})
+				}
+
+				sort.Sort(gotAlerts)
+				sort.Sort(expAlerts)
+
+				if !reflect.DeepEqual(expAlerts, gotAlerts) {
+					expString := indentLines(expAlerts.String(), "            ")
+					gotString := indentLines(gotAlerts.String(), "            ")
+					errs = append(errs, fmt.Errorf("    alertname: %s, time: %s, \n        exp:%v, \n        got:%v",
+						testcase.Alertname, testcase.EvalTime.String(), expString, gotString))
+				}
+			}
+
+			curr++
+		}
+	}
+
+	// Checking promql expressions.
+Outer:
+	for _, testCase := range tg.PromqlExprTests {
+		tenantID := testCase.Tenant
+		if tenantID == "" {
+			tenantID = tg.Tenant
+		}
+
+		got, err := query(user.InjectOrgID(context.Background(), tenantID), testCase.Expr, mint.Add(time.Duration(testCase.EvalTime)),
+			eng, queryable, federatedQueryable)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("    expr: %q, time: %s, err: %s", testCase.Expr,
+				testCase.EvalTime.String(), err.Error()))
+			continue
+		}
+
+		var gotSamples []parsedSample
+		for _, s := range got {
+			gotSamples = append(gotSamples, parsedSample{
+				Labels: s.Metric.Copy(),
+				Value:  s.V,
+			})
+		}
+
+		var expSamples []parsedSample
+		for _, s := range testCase.ExpSamples {
+			lb, err := parser.ParseMetric(s.Labels)
+			if err != nil {
+				err = fmt.Errorf("labels %q: %w", s.Labels, err)
+				errs = append(errs, fmt.Errorf("    expr: %q, time: %s, err: %w", testCase.Expr,
+					testCase.EvalTime.String(), err))
+				continue Outer
+			}
+			expSamples = append(expSamples, parsedSample{
+				Labels: lb,
+				Value:  s.Value,
+			})
+		}
+
+		sort.Slice(expSamples, func(i, j int) bool {
+			return labels.Compare(expSamples[i].Labels, expSamples[j].Labels) <= 0
+		})
+		sort.Slice(gotSamples, func(i, j int) bool {
+			return labels.Compare(gotSamples[i].Labels, gotSamples[j].Labels) <= 0
+		})
+		if !reflect.DeepEqual(expSamples, gotSamples) {
+			errs = append(errs, fmt.Errorf("    expr: %q, time: %s,\n        exp: %v\n        got: %v", testCase.Expr,
+				testCase.EvalTime.String(), parsedSamplesString(expSamples), parsedSamplesString(gotSamples)))
+		}
+	}
+
+	return errs
+}
+
+// newGroups makes the groups of rules of the rule files, like the ruler does. The alerting rules are
+// considered restored, to ensure the ALERTS series are written when they run.
+func (tg *testGroup) newGroups(ruleGroups []ruleGroup, opts *rules.ManagerOptions) ([]*rules.Group, error) {
+	groups := make([]*rules.Group, 0, len(ruleGroups))
+	for _, rg := range ruleGroups {
+		interval := time.Duration(tg.Interval)
+		if rg.group.Interval != 0 {
+			interval = time.Duration(rg.group.Interval)
+		}
+
+		var evaluationDelay *time.Duration
+		if rg.group.EvaluationDelay != nil {
+			d := time.Duration(*rg.group.EvaluationDelay)
+			evaluationDelay = &d
+		}
+
+		rs := make([]rules.Rule, 0, len(rg.group.Rules))
+		for _, r := range rg.group.Rules {
+			expr, err := parser.ParseExpr(r.Expr.Value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s: group %s", rg.file, rg.group.Name)
+			}
+
+			if r.Alert.Value != "" {
+				rs = append(rs, rules.NewAlertingRule(r.Alert.Value, expr, time.Duration(r.For), labels.FromMap(r.Labels),
+					labels.FromMap(r.Annotations), tg.ExternalLabels, tg.ExternalURL, true, opts.Logger))
+				continue
+			}
+			rs = append(rs, rules.NewRecordingRule(r.Record.Value, expr, labels.FromMap(r.Labels)))
+		}
+
+		groups = append(groups, rules.NewGroup(rules.GroupOptions{
+			Name:            rg.group.Name,
+			File:            rg.file,
+			Interval:        interval,
+			Limit:           rg.group.Limit,
+			Rules:           rs,
+			SourceTenants:   rg.group.SourceTenants,
+			Opts:            opts,
+			EvaluationDelay: evaluationDelay,
+		}))
+	}
+	return groups, nil
+}
+
+// tenants returns the tenants owning the rules and the input series.
+func (tg *testGroup) tenants() []string {
+	tenants := []string{tg.Tenant}
+	for _, is := range tg.InputSeries {
+		tenantID := is.tenant(tg.Tenant)
+		found := false
+		for _, t := range tenants {
+			found = found || t == tenantID
+		}
+		if !found {
+			tenants = append(tenants, tenantID)
+		}
+	}
+	return tenants
+}
+
+// seriesLoadingString returns the input series of the tenant in PromQL notation.
+func (tg *testGroup) seriesLoadingString(tenantID string) string {
+	result := fmt.Sprintf("load %v\n", shortDuration(tg.Interval))
+	for _, is := range tg.InputSeries {
+		if is.tenant(tg.Tenant) == tenantID {
+			result += fmt.Sprintf("  %v %v\n", is.Series, is.Values)
+		}
+	}
+	return result
+}
+
+func shortDuration(d model.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// maxEvalTime returns the max eval time among all alert and promql unit tests.
+func (tg *testGroup) maxEvalTime() time.Duration {
+	var maxd model.Duration
+	for _, alert := range tg.AlertRuleTests {
+		if alert.EvalTime > maxd {
+			maxd = alert.EvalTime
+		}
+	}
+	for _, pet := range tg.PromqlExprTests {
+		if pet.EvalTime > maxd {
+			maxd = pet.EvalTime
+		}
+	}
+	return time.Duration(maxd)
+}
+
+// tenantsQueryable queries the series of the tenant of the context. The tenants without input series have no series.
+type tenantsQueryable map[string]*promql.LazyLoader
+
+func (q tenantsQueryable) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	tenantID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	suite, ok := q[tenantID]
+	if !ok {
+		return storage.NoopQuerier(), nil
+	}
+	return suite.Queryable().Querier(ctx, mint, maxt)
+}
+
+// query runs the query against the series of the tenant of the context, or of the tenants of the context
+// if there are several of them, like a query to the federated tenants does.
+func query(ctx context.Context, qs string, t time.Time, engine *promql.Engine, queryable, federatedQueryable storage.Queryable) (promql.Vector, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(tenantIDs) > 1 {
+		queryable = federatedQueryable
+	}
+
+	q, err := engine.NewInstantQuery(queryable, nil, qs, t)
+	if err != nil {
+		return nil, err
+	}
+	res := q.Exec(ctx)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	switch v := res.Value.(type) {
+	case promql.Vector:
+		return v, nil
+	case promql.Scalar:
+		return promql.Vector{promql.Sample{
+			Point:  promql.Point(v),
+			Metric: labels.Labels{},
+		}}, nil
+	default:
+		return nil, errors.New("rule result is not a vector or scalar")
+	}
+}
+
+// indentLines prefixes each line in the supplied string with the given "indent"
+// string.
+func indentLines(lines, indent string) string {
+	sb := strings.Builder{}
+	n := strings.Split(lines, "\n")
+	for i, l := range n {
+		if i > 0 {
+			sb.WriteString(indent)
+		}
+		sb.WriteString(l)
+		if i != len(n)-1 {
+			sb.WriteRune('\n')
+		}
+	}
+	return sb.String()
+}
+
+type labelsAndAnnotations []labelAndAnnotation
+
+func (la labelsAndAnnotations) Len() int      { return len(la) }
+func (la labelsAndAnnotations) Swap(i, j int) { la[i], la[j] = la[j], la[i] }
+func (la labelsAndAnnotations) Less(i, j int) bool {
+	diff := labels.Compare(la[i].Labels, la[j].Labels)
+	if diff != 0 {
+		return diff < 0
+	}
+	return labels.Compare(la[i].Annotations, la[j].Annotations) < 0
+}
+
+func (la labelsAndAnnotations) String() string {
+	if len(la) == 0 {
+		return "[]"
+	}
+	s := "[\n0:" + indentLines("\n"+la[0].String(), "  ")
+	for i, l := range la[1:] {
+		s += ",\n" + fmt.Sprintf("%d", i+1) + ":" + indentLines("\n"+l.String(), "  ")
+	}
+	s += "\n]"
+
+	return s
+}
+
+type labelAndAnnotation struct {
+	Labels      labels.Labels
+	Annotations labels.Labels
+}
+
+func (la *labelAndAnnotation) String() string {
+	return "Labels:" + la.Labels.String() + "\nAnnotations:" + la.Annotations.String()
+}
+
+type series struct {
+	Series string `yaml:"series"`
+	Values string `yaml:"values"`
+	// Tenant of the series, defaulting to the tenant of the test group.
+	Tenant string `yaml:"tenant,omitempty"`
+}
+
+func (s series) tenant(defaultTenantID string) string {
+	if s.Tenant == "" {
+		return defaultTenantID
+	}
+	return s.Tenant
+}
+
+type alertTestCase struct {
+	EvalTime  model.Duration `yaml:"eval_time"`
+	Alertname string         `yaml:"alertname"`
+	ExpAlerts []alert        `yaml:"exp_alerts"`
+}
+
+type alert struct {
+	ExpLabels      map[string]string `yaml:"exp_labels"`
+	ExpAnnotations map[string]string `yaml:"exp_annotations"`
+}
+
+type promqlTestCase struct {
+	Expr       string         `yaml:"expr"`
+	EvalTime   model.Duration `yaml:"eval_time"`
+	ExpSamples []sample       `yaml:"exp_samples"`
+	// Tenant to run the expression for, defaulting to the tenant of the test group. Several tenants
+	// separated by "|" run the expression against the series of all of them, like tenant federation does.
+	Tenant string `yaml:"tenant,omitempty"`
+}
+
+type sample struct {
+	Labels string  `yaml:"labels"`
+	Value  float64 `yaml:"value"`
+}
+
+// parsedSample is a sample with parsed Labels.
+type parsedSample struct {
+	Labels labels.Labels
+	Value  float64
+}
+
+func parsedSamplesString(pss []parsedSample) string {
+	if len(pss) == 0 {
+		return "nil"
+	}
+	s := pss[0].String()
+	for _, ps := range pss[1:] {
+		s += ", " + ps.String()
+	}
+	return s
+}
+
+func (ps *parsedSample) String() string {
+	return ps.Labels.String() + " " + strconv.FormatFloat(ps.Value, 'E', -1, 64)
+}
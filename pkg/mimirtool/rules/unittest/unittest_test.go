@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package unittest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	results := Run(Config{}, "testdata/tests.yaml", "testdata/failing.yaml", "testdata/missing.yaml")
+	require.Len(t, results, 3)
+
+	// Recording, alerting and federated rule groups, with and without evaluation delay.
+	assert.False(t, results[0].Failed())
+	require.Len(t, results[0].Tests, 1)
+	assert.Equal(t, "rules", results[0].Tests[0].Name)
+
+	assert.True(t, results[1].Failed())
+	require.Len(t, results[1].Tests, 2)
+	assert.Equal(t, "wrong value", results[1].Tests[0].Name)
+	require.Len(t, results[1].Tests[0].Errors, 1)
+	assert.Contains(t, results[1].Tests[0].Errors[0].Error(), `exp: {__name__="job:up:sum", job="api"} 2E+00`)
+	assert.Empty(t, results[1].Tests[1].Errors)
+
+	assert.True(t, results[2].Failed())
+	assert.Error(t, results[2].Err)
+	assert.Empty(t, results[2].Tests)
+}
+
+func TestRun_DefaultEvaluationDelay(t *testing.T) {
+	// The rule groups which don't set an evaluation delay use the default one.
+	results := Run(Config{EvaluationDelay: time.Minute}, "testdata/tests.yaml")
+	require.Len(t, results, 1)
+	require.Len(t, results[0].Tests, 1)
+	require.NotEmpty(t, results[0].Tests[0].Errors)
+	assert.Contains(t, results[0].Tests[0].Errors[0].Error(), `expr: "timestamp(job:up:sum)", time: 6m`)
+}
+
+func TestWriteJUnit(t *testing.T) {
+	results := []FileResult{
+		{Filename: "ok.yaml", Tests: []TestResult{
+			{Name: "first", Duration: 1500 * time.Millisecond},
+			{Name: "second", Duration: 500 * time.Millisecond, Errors: []error{errors.New("exp: 1"), errors.New("exp: 2")}},
+		}},
+		{Filename: "broken.yaml", Err: errors.New("no rule file matches the pattern rules.yaml")},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJUnit(&buf, results))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="3" failures="1" errors="1">
+  <testsuite name="ok.yaml" tests="2" failures="1" errors="0" time="2.000">
+    <testcase name="first" classname="ok.yaml" time="1.500"></testcase>
+    <testcase name="second" classname="ok.yaml" time="0.500">
+      <failure message="2 failed assertions">exp: 1&#xA;exp: 2</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="broken.yaml" tests="1" failures="0" errors="1" time="0.000">
+    <testcase name="broken.yaml" classname="broken.yaml" time="0.000">
+      <error message="unable to load the test file">no rule file matches the pattern rules.yaml</error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+}
@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package purger
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// SeriesDeletionConfigProvider provides per-tenant configuration of the series deletion API.
+type SeriesDeletionConfigProvider interface {
+	bucket.TenantConfigProvider
+
+	// CompactorSeriesDeletionDelay returns the time after which a series deletion request
+	// can no longer be cancelled, because the compactor may have started to physically remove the data.
+	CompactorSeriesDeletionDelay(userID string) time.Duration
+}
+
+// SeriesDeletionAPI implements the Prometheus compatible series deletion API for the blocks storage.
+// Deletion requests are stored as tombstones in the bucket.
+type SeriesDeletionAPI struct {
+	bucketClient objstore.Bucket
+	logger       log.Logger
+	cfgProvider  SeriesDeletionConfigProvider
+}
+
+func NewSeriesDeletionAPI(storageCfg mimir_tsdb.BlocksStorageConfig, cfgProvider SeriesDeletionConfigProvider, logger log.Logger, reg prometheus.Registerer) (*SeriesDeletionAPI, error) {
+	bucketClient, err := createBucketClient(storageCfg, "series-deletion", logger, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSeriesDeletionAPI(bucketClient, cfgProvider, logger), nil
+}
+
+func newSeriesDeletionAPI(bkt objstore.Bucket, cfgProvider SeriesDeletionConfigProvider, logger log.Logger) *SeriesDeletionAPI {
+	return &SeriesDeletionAPI{
+		bucketClient: bkt,
+		cfgProvider:  cfgProvider,
+		logger:       logger,
+	}
+}
+
+// AddDeleteRequest creates a new series deletion request. Supports the same parameters
+// as the Prometheus /api/v1/admin/tsdb/delete_series endpoint.
+func (api *SeriesDeletionAPI) AddDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	selectors := r.Form["match[]"]
+	if len(selectors) == 0 {
+		http.Error(w, "selectors not set", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+
+	startTime := int64(0)
+	if start := r.Form.Get("start"); start != "" {
+		if startTime, err = util.ParseTime(start); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	endTime := util.TimeToMillis(now)
+	if end := r.Form.Get("end"); end != "" {
+		if endTime, err = util.ParseTime(end); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tombstone, err := mimir_tsdb.NewTombstone(util.TimeToMillis(now), startTime, endTime, selectors)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existing, err := mimir_tsdb.ReadTombstone(ctx, api.bucketClient, userID, tombstone.RequestID)
+	if err != nil {
+		level.Error(api.logger).Log("msg", "failed to read tombstone", "user", userID, "request_id", tombstone.RequestID, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing != nil && existing.State == mimir_tsdb.TombstonePending {
+		// The same request has already been submitted.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := mimir_tsdb.WriteTombstone(ctx, api.bucketClient, userID, api.cfgProvider, tombstone); err != nil {
+		level.Error(api.logger).Log("msg", "failed to write tombstone", "user", userID, "request_id", tombstone.RequestID, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(api.logger).Log("msg", "series deletion request created", "user", userID, "request_id", tombstone.RequestID, "selectors", len(selectors), "start", startTime, "end", endTime)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteRequestStatus is a series deletion request, as returned by the GetAllDeleteRequests endpoint.
+type DeleteRequestStatus struct {
+	*mimir_tsdb.Tombstone
+
+	// Whether the request can still be cancelled.
+	Cancellable bool `json:"cancellable"`
+}
+
+// GetAllDeleteRequests returns all series deletion requests of the tenant.
+func (api *SeriesDeletionAPI) GetAllDeleteRequests(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	tombstones, err := mimir_tsdb.ReadTombstones(ctx, api.bucketClient, userID)
+	if err != nil {
+		level.Error(api.logger).Log("msg", "failed to read tombstones", "user", userID, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	result := make([]DeleteRequestStatus, 0, len(tombstones))
+	for _, t := range tombstones {
+		result = append(result, DeleteRequestStatus{
+			Tombstone:   t,
+			Cancellable: api.isCancellable(userID, t, now),
+		})
+	}
+
+	util.WriteJSONResponse(w, result)
+}
+
+// CancelDeleteRequest cancels a pending series deletion request, as long as the compactor may not
+// have started to physically remove the data yet.
+func (api *SeriesDeletionAPI) CancelDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requestID := r.Form.Get("request_id")
+	if requestID == "" {
+		http.Error(w, "request_id not set", http.StatusBadRequest)
+		return
+	}
+
+	tombstone, err := mimir_tsdb.ReadTombstone(ctx, api.bucketClient, userID, requestID)
+	if err != nil {
+		level.Error(api.logger).Log("msg", "failed to read tombstone", "user", userID, "request_id", requestID, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tombstone == nil {
+		http.Error(w, "deletion request not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	if !api.isCancellable(userID, tombstone, now) {
+		http.Error(w, "deletion request can no longer be cancelled", http.StatusBadRequest)
+		return
+	}
+
+	tombstone.State = mimir_tsdb.TombstoneCancelled
+	tombstone.StateCreatedAt = util.TimeToMillis(now)
+
+	if err := mimir_tsdb.WriteTombstone(ctx, api.bucketClient, userID, api.cfgProvider, tombstone); err != nil {
+		level.Error(api.logger).Log("msg", "failed to write tombstone", "user", userID, "request_id", requestID, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(api.logger).Log("msg", "series deletion request cancelled", "user", userID, "request_id", requestID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *SeriesDeletionAPI) isCancellable(userID string, t *mimir_tsdb.Tombstone, now time.Time) bool {
+	if t.State != mimir_tsdb.TombstonePending {
+		return false
+	}
+
+	return now.Before(util.TimeFromMillis(t.RequestCreatedAt).Add(api.cfgProvider.CompactorSeriesDeletionDelay(userID)))
+}
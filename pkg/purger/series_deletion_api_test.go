@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package purger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+type mockSeriesDeletionConfigProvider struct {
+	delay time.Duration
+}
+
+func (m mockSeriesDeletionConfigProvider) CompactorSeriesDeletionDelay(string) time.Duration {
+	return m.delay
+}
+
+func (m mockSeriesDeletionConfigProvider) S3SSEType(string) string { return "" }
+
+func (m mockSeriesDeletionConfigProvider) S3SSEKMSKeyID(string) string { return "" }
+
+func (m mockSeriesDeletionConfigProvider) S3SSEKMSEncryptionContext(string) string { return "" }
+
+func newSeriesDeletionRequest(t *testing.T, method, target string, form url.Values) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req.WithContext(user.InjectOrgID(context.Background(), "user"))
+}
+
+func TestSeriesDeletionAPI_AddDeleteRequest(t *testing.T) {
+	for name, tc := range map[string]struct {
+		form         url.Values
+		expectedCode int
+	}{
+		"no selectors": {
+			form:         url.Values{"start": {"10"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid selector": {
+			form:         url.Values{"match[]": {`{job=`}},
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid time range": {
+			form:         url.Values{"match[]": {`{job="test"}`}, "start": {"20"}, "end": {"10"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid start": {
+			form:         url.Values{"match[]": {`{job="test"}`}, "start": {"foo"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		"valid request": {
+			form:         url.Values{"match[]": {`{job="test"}`, `up`}, "start": {"10"}, "end": {"20"}},
+			expectedCode: http.StatusNoContent,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			bkt := objstore.NewInMemBucket()
+			api := newSeriesDeletionAPI(bkt, mockSeriesDeletionConfigProvider{delay: time.Hour}, log.NewNopLogger())
+
+			resp := httptest.NewRecorder()
+			api.AddDeleteRequest(resp, newSeriesDeletionRequest(t, http.MethodPost, "/api/v1/admin/tsdb/delete_series", tc.form))
+			require.Equal(t, tc.expectedCode, resp.Code, resp.Body.String())
+
+			all, err := tsdb.ReadTombstones(context.Background(), bkt, "user")
+			require.NoError(t, err)
+
+			if tc.expectedCode != http.StatusNoContent {
+				require.Empty(t, all)
+				return
+			}
+
+			require.Len(t, all, 1)
+			assert.Equal(t, tsdb.TombstonePending, all[0].State)
+			assert.Equal(t, int64(10000), all[0].StartTime)
+			assert.Equal(t, int64(20000), all[0].EndTime)
+			assert.Equal(t, tc.form["match[]"], all[0].Selectors)
+		})
+	}
+}
+
+func TestSeriesDeletionAPI_AddDeleteRequest_Unauthorized(t *testing.T) {
+	api := newSeriesDeletionAPI(objstore.NewInMemBucket(), mockSeriesDeletionConfigProvider{}, log.NewNopLogger())
+
+	resp := httptest.NewRecorder()
+	api.AddDeleteRequest(resp, &http.Request{})
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestSeriesDeletionAPI_GetAndCancelDeleteRequests(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	cfg := mockSeriesDeletionConfigProvider{delay: time.Hour}
+	api := newSeriesDeletionAPI(bkt, cfg, log.NewNopLogger())
+
+	now := time.Now()
+	recent, err := tsdb.NewTombstone(now.Add(-time.Minute).UnixMilli(), 10, 20, []string{`{job="recent"}`})
+	require.NoError(t, err)
+	old, err := tsdb.NewTombstone(now.Add(-2*time.Hour).UnixMilli(), 10, 20, []string{`{job="old"}`})
+	require.NoError(t, err)
+	require.NoError(t, tsdb.WriteTombstone(ctx, bkt, "user", cfg, recent))
+	require.NoError(t, tsdb.WriteTombstone(ctx, bkt, "user", cfg, old))
+
+	getCancellable := func() map[string]bool {
+		resp := httptest.NewRecorder()
+		api.GetAllDeleteRequests(resp, newSeriesDeletionRequest(t, http.MethodGet, "/api/v1/admin/tsdb/delete_series", nil))
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var statuses []DeleteRequestStatus
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &statuses))
+
+		out := map[string]bool{}
+		for _, s := range statuses {
+			out[s.RequestID] = s.Cancellable
+		}
+		return out
+	}
+
+	cancel := func(requestID string) int {
+		resp := httptest.NewRecorder()
+		api.CancelDeleteRequest(resp, newSeriesDeletionRequest(t, http.MethodPost, "/api/v1/admin/tsdb/cancel_delete_request", url.Values{"request_id": {requestID}}))
+		return resp.Code
+	}
+
+	assert.Equal(t, map[string]bool{recent.RequestID: true, old.RequestID: false}, getCancellable())
+
+	assert.Equal(t, http.StatusBadRequest, cancel(""))
+	assert.Equal(t, http.StatusNotFound, cancel("unknown"))
+	assert.Equal(t, http.StatusBadRequest, cancel(old.RequestID))
+	assert.Equal(t, http.StatusNoContent, cancel(recent.RequestID))
+
+	// A cancelled request can't be cancelled again.
+	assert.Equal(t, http.StatusBadRequest, cancel(recent.RequestID))
+	assert.Equal(t, map[string]bool{recent.RequestID: false, old.RequestID: false}, getCancellable())
+
+	actual, err := tsdb.ReadTombstone(ctx, bkt, "user", recent.RequestID)
+	require.NoError(t, err)
+	assert.Equal(t, tsdb.TombstoneCancelled, actual.State)
+}
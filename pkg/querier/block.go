@@ -8,11 +8,13 @@ package querier
 import (
 	"math"
 	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 
@@ -43,11 +45,37 @@ func convertMatchersToLabelMatcher(matchers []*labels.Matcher) []storepb.LabelMa
 	return converted
 }
 
+// aggrsFromFunc returns the aggregates to fetch from downsampled chunks in order to
+// best approximate the raw samples, given the PromQL function the series are selected for.
+func aggrsFromFunc(f string) []storepb.Aggr {
+	if f == "min" || strings.HasPrefix(f, "min_") {
+		return []storepb.Aggr{storepb.Aggr_MIN}
+	}
+	if f == "max" || strings.HasPrefix(f, "max_") {
+		return []storepb.Aggr{storepb.Aggr_MAX}
+	}
+	if f == "count" || strings.HasPrefix(f, "count_") {
+		return []storepb.Aggr{storepb.Aggr_COUNT}
+	}
+	// f == "sum" falls through here since we want the actual samples.
+	if strings.HasPrefix(f, "sum_") {
+		return []storepb.Aggr{storepb.Aggr_SUM}
+	}
+	if f == "increase" || f == "rate" || f == "irate" || f == "resets" {
+		return []storepb.Aggr{storepb.Aggr_COUNTER}
+	}
+	// In the default case, we fetch count and sum to compute an average.
+	return []storepb.Aggr{storepb.Aggr_COUNT, storepb.Aggr_SUM}
+}
+
 // Implementation of storage.SeriesSet, based on individual responses from store client.
 type blockQuerierSeriesSet struct {
 	series   []*storepb.Series
 	warnings storage.Warnings
 
+	// Aggregates requested for downsampled chunks, if any.
+	aggrs []storepb.Aggr
+
 	// next response to process
 	next int
 
@@ -74,7 +102,9 @@ func (bqss *blockQuerierSeriesSet) Next() bool {
 		bqss.next++
 	}
 
-	bqss.currSeries = newBlockQuerierSeries(currLabels, currChunks)
+	series := newBlockQuerierSeries(currLabels, currChunks)
+	series.aggrs = bqss.aggrs
+	bqss.currSeries = series
 	return true
 }
 
@@ -102,6 +132,9 @@ func newBlockQuerierSeries(lbls []labels.Label, chunks []storepb.AggrChunk) *blo
 type blockQuerierSeries struct {
 	labels labels.Labels
 	chunks []storepb.AggrChunk
+
+	// Aggregates requested for downsampled chunks, if any.
+	aggrs []storepb.Aggr
 }
 
 func (bqs *blockQuerierSeries) Labels() labels.Labels {
@@ -117,18 +150,80 @@ func (bqs *blockQuerierSeries) Iterator() chunkenc.Iterator {
 	its := make([]iteratorWithMaxTime, 0, len(bqs.chunks))
 
 	for _, c := range bqs.chunks {
-		ch, err := chunkenc.FromData(chunkenc.EncXOR, c.Raw.Data)
+		it, err := bqs.chunkIterator(c)
 		if err != nil {
-			return series.NewErrIterator(errors.Wrapf(err, "failed to initialize chunk from XOR encoded raw data (series: %v min time: %d max time: %d)", bqs.Labels(), c.MinTime, c.MaxTime))
+			return series.NewErrIterator(err)
 		}
 
-		it := ch.Iterator(nil)
 		its = append(its, iteratorWithMaxTime{it, c.MaxTime})
 	}
 
+	if len(bqs.aggrs) == 1 && bqs.aggrs[0] == storepb.Aggr_COUNTER {
+		// Counter resets must be applied across all chunks of the series.
+		counterIts := make([]chunkenc.Iterator, 0, len(its))
+		for _, it := range its {
+			counterIts = append(counterIts, it.Iterator)
+		}
+		return downsample.NewApplyCounterResetsIterator(counterIts...)
+	}
+
 	return newBlockQuerierSeriesIterator(bqs.Labels(), its)
 }
 
+// chunkIterator returns an iterator over the samples of the input chunk. Downsampled chunks are
+// iterated using the requested aggregates.
+func (bqs *blockQuerierSeries) chunkIterator(c storepb.AggrChunk) (chunkenc.Iterator, error) {
+	if c.Raw != nil {
+		ch, err := chunkenc.FromData(chunkenc.EncXOR, c.Raw.Data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to initialize chunk from XOR encoded raw data (series: %v min time: %d max time: %d)", bqs.Labels(), c.MinTime, c.MaxTime)
+		}
+		return ch.Iterator(nil), nil
+	}
+
+	aggrIterator := func(aggr storepb.Aggr) (chunkenc.Iterator, error) {
+		var data *storepb.Chunk
+		switch aggr {
+		case storepb.Aggr_COUNT:
+			data = c.Count
+		case storepb.Aggr_SUM:
+			data = c.Sum
+		case storepb.Aggr_MIN:
+			data = c.Min
+		case storepb.Aggr_MAX:
+			data = c.Max
+		case storepb.Aggr_COUNTER:
+			data = c.Counter
+		}
+		if data == nil {
+			return nil, errors.Errorf("no %s aggregate in downsampled chunk (series: %v min time: %d max time: %d)", aggr.String(), bqs.Labels(), c.MinTime, c.MaxTime)
+		}
+
+		ch, err := chunkenc.FromData(chunkenc.EncXOR, data.Data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to initialize chunk from XOR encoded %s aggregate (series: %v min time: %d max time: %d)", aggr.String(), bqs.Labels(), c.MinTime, c.MaxTime)
+		}
+		return ch.Iterator(nil), nil
+	}
+
+	if len(bqs.aggrs) == 2 && bqs.aggrs[0] == storepb.Aggr_COUNT && bqs.aggrs[1] == storepb.Aggr_SUM {
+		cnt, err := aggrIterator(storepb.Aggr_COUNT)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := aggrIterator(storepb.Aggr_SUM)
+		if err != nil {
+			return nil, err
+		}
+		return downsample.NewAverageChunkIterator(cnt, sum), nil
+	}
+
+	if len(bqs.aggrs) != 1 {
+		return nil, errors.Errorf("unexpected downsampled chunk (series: %v min time: %d max time: %d)", bqs.Labels(), c.MinTime, c.MaxTime)
+	}
+	return aggrIterator(bqs.aggrs[0])
+}
+
 func newBlockQuerierSeriesIterator(labels labels.Labels, its []iteratorWithMaxTime) *blockQuerierSeriesIterator {
 	return &blockQuerierSeriesIterator{labels: labels, iterators: its, lastT: math.MinInt64}
 }
@@ -370,6 +370,72 @@ func verifyNextSeries(t *testing.T, ss storage.SeriesSet, labels labels.Labels,
 
 // createAggrChunkWithSineSamples takes a min/maxTime and a step duration, it generates a chunk given these specs.
 // The minTime and maxTime are both inclusive.
+func TestBlockQuerierSeries_DownsampledChunks(t *testing.T) {
+	lbls := labels.FromStrings("__name__", "test")
+
+	xorChunk := func(samples ...promql.Point) *storepb.Chunk {
+		return createAggrChunk(0, 0, samples...).Raw
+	}
+
+	// A downsampled chunk with 2 aggregated samples, followed by a raw chunk.
+	downsampled := storepb.AggrChunk{
+		MinTime: 0,
+		MaxTime: 600,
+		Count:   xorChunk(promql.Point{T: 300, V: 2}, promql.Point{T: 600, V: 4}),
+		Sum:     xorChunk(promql.Point{T: 300, V: 10}, promql.Point{T: 600, V: 8}),
+		Min:     xorChunk(promql.Point{T: 300, V: 4}, promql.Point{T: 600, V: 1}),
+		Max:     xorChunk(promql.Point{T: 300, V: 6}, promql.Point{T: 600, V: 3}),
+	}
+	raw := createAggrChunkWithSamples(promql.Point{T: 700, V: 5})
+
+	tests := map[string]struct {
+		aggrs    []storepb.Aggr
+		expected []promql.Point
+	}{
+		"average": {
+			aggrs:    aggrsFromFunc(""),
+			expected: []promql.Point{{T: 300, V: 5}, {T: 600, V: 2}, {T: 700, V: 5}},
+		},
+		"min": {
+			aggrs:    aggrsFromFunc("min_over_time"),
+			expected: []promql.Point{{T: 300, V: 4}, {T: 600, V: 1}, {T: 700, V: 5}},
+		},
+		"max": {
+			aggrs:    aggrsFromFunc("max"),
+			expected: []promql.Point{{T: 300, V: 6}, {T: 600, V: 3}, {T: 700, V: 5}},
+		},
+		"count": {
+			aggrs:    aggrsFromFunc("count_over_time"),
+			expected: []promql.Point{{T: 300, V: 2}, {T: 600, V: 4}, {T: 700, V: 5}},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			series := newBlockQuerierSeries(lbls, []storepb.AggrChunk{downsampled, raw})
+			series.aggrs = testData.aggrs
+
+			var actual []promql.Point
+			it := series.Iterator()
+			for it.Next() {
+				ts, v := it.At()
+				actual = append(actual, promql.Point{T: ts, V: v})
+			}
+			require.NoError(t, it.Err())
+			assert.Equal(t, testData.expected, actual)
+		})
+	}
+
+	t.Run("missing aggregate", func(t *testing.T) {
+		series := newBlockQuerierSeries(lbls, []storepb.AggrChunk{downsampled})
+		series.aggrs = aggrsFromFunc("rate")
+
+		it := series.Iterator()
+		require.False(t, it.Next())
+		require.Error(t, it.Err())
+	})
+}
+
 func createAggrChunkWithSineSamples(minTime, maxTime time.Time, step time.Duration) storepb.AggrChunk {
 	var samples []promql.Point
 
@@ -60,7 +60,7 @@ func (c *BlocksConsistencyChecker) Check(knownBlocks bucketindex.Blocks, knownDe
 		//   on the configured retention period).
 		// - Blocks uploaded by compactor: the source blocks are marked for deletion but will continue to be
 		//   queried by queriers for a while (depends on the configured deletion marks delay).
-		if c.uploadGracePeriod > 0 && time.Since(block.GetUploadedAt()) < c.uploadGracePeriod {
+		if c.isRecentlyUploaded(block) {
 			level.Debug(c.logger).Log("msg", "block skipped from consistency check because it was uploaded recently", "block", block.ID.String(), "uploadedAt", block.GetUploadedAt().String())
 			continue
 		}
@@ -89,3 +89,9 @@ func (c *BlocksConsistencyChecker) Check(knownBlocks bucketindex.Blocks, knownDe
 
 	return missingBlocks
 }
+
+// isRecentlyUploaded returns whether the block has been uploaded too recently to expect
+// store-gateways to have discovered and loaded it.
+func (c *BlocksConsistencyChecker) isRecentlyUploaded(block *bucketindex.Block) bool {
+	return c.uploadGracePeriod > 0 && time.Since(block.GetUploadedAt()) < c.uploadGracePeriod
+}
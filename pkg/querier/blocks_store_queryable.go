@@ -28,6 +28,7 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/extprom"
 	"github.com/thanos-io/thanos/pkg/store/hintspb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
@@ -160,6 +161,9 @@ type BlocksStoreQueryable struct {
 	metrics         *blocksStoreQueryableMetrics
 	limits          BlocksStoreLimits
 
+	// Whether downsampled blocks are queried, based on the query step.
+	autoDownsampling bool
+
 	// Subservices manager.
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -171,6 +175,7 @@ func NewBlocksStoreQueryable(
 	consistency *BlocksConsistencyChecker,
 	limits BlocksStoreLimits,
 	queryStoreAfter time.Duration,
+	autoDownsampling bool,
 	logger log.Logger,
 	reg prometheus.Registerer,
 ) (*BlocksStoreQueryable, error) {
@@ -189,6 +194,7 @@ func NewBlocksStoreQueryable(
 		subservicesWatcher: services.NewFailureWatcher(),
 		metrics:            newBlocksStoreQueryableMetrics(reg),
 		limits:             limits,
+		autoDownsampling:   autoDownsampling,
 	}
 
 	q.Service = services.NewBasicService(q.starting, q.running, q.stopping)
@@ -267,7 +273,7 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 		reg,
 	)
 
-	return NewBlocksStoreQueryable(stores, finder, consistency, limits, querierCfg.QueryStoreAfter, logger, reg)
+	return NewBlocksStoreQueryable(stores, finder, consistency, limits, querierCfg.QueryStoreAfter, querierCfg.AutoDownsamplingEnabled, logger, reg)
 }
 
 func (q *BlocksStoreQueryable) starting(ctx context.Context) error {
@@ -318,6 +324,8 @@ func (q *BlocksStoreQueryable) Querier(ctx context.Context, mint, maxt int64) (s
 		consistency:     q.consistency,
 		logger:          q.logger,
 		queryStoreAfter: q.queryStoreAfter,
+
+		autoDownsampling: q.autoDownsampling,
 	}, nil
 }
 
@@ -335,6 +343,9 @@ type blocksStoreQuerier struct {
 	// If set, the querier manipulates the max time to not be greater than
 	// "now - queryStoreAfter" so that most recent blocks are not queried.
 	queryStoreAfter time.Duration
+
+	// If set, downsampled blocks are queried when the resolution is fine enough for the query step.
+	autoDownsampling bool
 }
 
 // Select implements storage.Querier interface.
@@ -377,7 +388,7 @@ func (q *blocksStoreQuerier) LabelNames(matchers ...*labels.Matcher) ([]string,
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, queryFunc)
+	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, downsample.ResLevel0, queryFunc)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -418,7 +429,7 @@ func (q *blocksStoreQuerier) LabelValues(name string, matchers ...*labels.Matche
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, queryFunc)
+	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, downsample.ResLevel0, queryFunc)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -450,8 +461,10 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		return storage.ErrSeriesSet(err)
 	}
 
+	maxResolution := q.maxResolutionWindow(sp)
+
 	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
-		seriesSets, queriedBlocks, warnings, numChunks, err := q.fetchSeriesFromStores(spanCtx, sp, clients, minT, maxT, matchers, convertedMatchers, maxChunksLimit, leftChunksLimit)
+		seriesSets, queriedBlocks, warnings, numChunks, err := q.fetchSeriesFromStores(spanCtx, sp, clients, minT, maxT, maxResolution, matchers, convertedMatchers, maxChunksLimit, leftChunksLimit)
 		if err != nil {
 			return nil, err
 		}
@@ -468,7 +481,7 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		return queriedBlocks, nil
 	}
 
-	err = q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, shard, queryFunc)
+	err = q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, shard, maxResolution, queryFunc)
 	if err != nil {
 		return storage.ErrSeriesSet(err)
 	}
@@ -482,7 +495,16 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		resWarnings)
 }
 
-func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logger log.Logger, minT, maxT int64, shard *sharding.ShardSelector,
+// maxResolutionWindow returns the max resolution of the blocks to query for the input hints. Downsampled
+// blocks are queried only if their resolution is at least 5 times finer than the query step.
+func (q *blocksStoreQuerier) maxResolutionWindow(sp *storage.SelectHints) int64 {
+	if !q.autoDownsampling || sp == nil || sp.Func == "series" {
+		return downsample.ResLevel0
+	}
+	return sp.Step / 5
+}
+
+func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logger log.Logger, minT, maxT int64, shard *sharding.ShardSelector, maxResolution int64,
 	queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error)) error {
 	// If queryStoreAfter is enabled, we do manipulate the query maxt to query samples up until
 	// now - queryStoreAfter, because the most recent time range is covered by ingesters. This
@@ -518,6 +540,10 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 
 	q.metrics.blocksFound.Add(float64(len(knownBlocks)))
 
+	// Only query the blocks with the lowest resolution allowed for each time range, like the
+	// store-gateway does, so that the consistency check only expects the blocks actually queried.
+	knownBlocks = filterBlocksByResolution(knownBlocks, minT, maxT, maxResolution, q.consistency.isRecentlyUploaded)
+
 	if shard != nil && shard.ShardCount > 0 {
 		level.Debug(logger).Log("msg", "filtering blocks due to sharding", "blocksBeforeFiltering", knownBlocks.String(), "shardID", shard.LabelValue())
 
@@ -680,6 +706,7 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 	clients map[BlocksStoreClient][]ulid.ULID,
 	minT int64,
 	maxT int64,
+	maxResolution int64,
 	matchers []*labels.Matcher,
 	convertedMatchers []storepb.LabelMatcher,
 	maxChunksLimit int,
@@ -696,8 +723,13 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 		spanLog       = spanlogger.FromContext(ctx, q.logger)
 		queryLimiter  = limiter.QueryLimiterFromContextWithFallback(ctx)
 		reqStats      = stats.FromContext(ctx)
+		aggrs         = []storepb.Aggr(nil)
 	)
 
+	if maxResolution > downsample.ResLevel0 {
+		aggrs = aggrsFromFunc(sp.Func)
+	}
+
 	// Concurrently fetch series from all clients.
 	for c, blockIDs := range clients {
 		// Change variables scope since it will be used in a goroutine.
@@ -711,7 +743,7 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 			// But this is an acceptable workaround for now.
 			skipChunks := sp != nil && sp.Func == "series"
 
-			req, err := createSeriesRequest(minT, maxT, convertedMatchers, skipChunks, blockIDs)
+			req, err := createSeriesRequest(minT, maxT, maxResolution, aggrs, convertedMatchers, skipChunks, blockIDs)
 			if err != nil {
 				return errors.Wrapf(err, "failed to create series request")
 			}
@@ -805,7 +837,7 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 
 			// Store the result.
 			mtx.Lock()
-			seriesSets = append(seriesSets, &blockQuerierSeriesSet{series: mySeries})
+			seriesSets = append(seriesSets, &blockQuerierSeriesSet{series: mySeries, aggrs: aggrs})
 			warnings = append(warnings, myWarnings...)
 			queriedBlocks = append(queriedBlocks, myQueriedBlocks...)
 			mtx.Unlock()
@@ -980,7 +1012,7 @@ func (q *blocksStoreQuerier) fetchLabelValuesFromStore(
 	return valueSets, warnings, queriedBlocks, nil
 }
 
-func createSeriesRequest(minT, maxT int64, matchers []storepb.LabelMatcher, skipChunks bool, blockIDs []ulid.ULID) (*storepb.SeriesRequest, error) {
+func createSeriesRequest(minT, maxT, maxResolution int64, aggrs []storepb.Aggr, matchers []storepb.LabelMatcher, skipChunks bool, blockIDs []ulid.ULID) (*storepb.SeriesRequest, error) {
 	// Selectively query only specific blocks.
 	hints := &hintspb.SeriesRequestHints{
 		BlockMatchers: []storepb.LabelMatcher{
@@ -1004,6 +1036,8 @@ func createSeriesRequest(minT, maxT int64, matchers []storepb.LabelMatcher, skip
 		PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT,
 		Hints:                   anyHints,
 		SkipChunks:              skipChunks,
+		MaxResolutionWindow:     maxResolution,
+		Aggregates:              aggrs,
 	}, nil
 }
 
@@ -1087,6 +1121,88 @@ func convertBlockHintsToULIDs(hints []hintspb.Block) ([]ulid.ULID, error) {
 	return res, nil
 }
 
+func hasDownsampledBlocks(blocks bucketindex.Blocks) bool {
+	for _, b := range blocks {
+		if b.Resolution != downsample.ResLevel0 {
+			return true
+		}
+	}
+	return false
+}
+
+// filterBlocksByResolution returns the blocks covering the time range between minT and maxT (both included)
+// with the lowest resolution not greater than maxResolution. Time ranges not covered by such blocks are filled
+// with higher resolution blocks, down to raw blocks. Downsampled blocks for which skip returns true are ignored.
+// The selection matches the one done by the store-gateway.
+func filterBlocksByResolution(blocks bucketindex.Blocks, minT, maxT, maxResolution int64, skip func(*bucketindex.Block) bool) bucketindex.Blocks {
+	// Raw blocks are always queried when there's no downsampled block to choose from.
+	if !hasDownsampledBlocks(blocks) {
+		return blocks
+	}
+
+	resolutions := []int64{downsample.ResLevel2, downsample.ResLevel1, downsample.ResLevel0}
+
+	byResolution := map[int64][]*bucketindex.Block{}
+	for _, b := range blocks {
+		if b.Resolution != downsample.ResLevel0 && skip(b) {
+			continue
+		}
+		byResolution[b.Resolution] = append(byResolution[b.Resolution], b)
+	}
+
+	for _, bs := range byResolution {
+		sort.Slice(bs, func(i, j int) bool {
+			if bs[i].MinTime == bs[j].MinTime {
+				return bs[i].MaxTime < bs[j].MaxTime
+			}
+			return bs[i].MinTime < bs[j].MinTime
+		})
+	}
+
+	selected := map[ulid.ULID]struct{}{}
+
+	var selectFor func(minT, maxT int64, resIdx int)
+	selectFor = func(minT, maxT int64, resIdx int) {
+		if minT > maxT {
+			return
+		}
+
+		start := minT
+		for _, b := range byResolution[resolutions[resIdx]] {
+			if b.MaxTime <= minT {
+				continue
+			}
+			// NOTE: Block intervals are half-open: [MinTime, MaxTime).
+			if b.MinTime > maxT {
+				break
+			}
+
+			if resIdx+1 < len(resolutions) {
+				selectFor(start, b.MinTime-1, resIdx+1)
+			}
+			selected[b.ID] = struct{}{}
+			start = b.MaxTime
+		}
+
+		if resIdx+1 < len(resolutions) {
+			selectFor(start, maxT, resIdx+1)
+		}
+	}
+
+	resIdx := 0
+	for ; resIdx < len(resolutions)-1 && resolutions[resIdx] > maxResolution; resIdx++ {
+	}
+	selectFor(minT, maxT, resIdx)
+
+	result := make(bucketindex.Blocks, 0, len(selected))
+	for _, b := range blocks {
+		if _, ok := selected[b.ID]; ok {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
 // countChunksAndBytes returns the number of chunks and size of the chunks making up the provided series in bytes
 func countChunksAndBytes(series ...*storepb.Series) (chunks, bytes int) {
 	for _, s := range series {
@@ -29,6 +29,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/compact/downsample"
 	"github.com/thanos-io/thanos/pkg/store/hintspb"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
@@ -1668,7 +1669,7 @@ func TestBlocksStoreQuerier_PromQLExecution(t *testing.T) {
 
 			// Instantiate the querier that will be executed to run the query.
 			logger := log.NewNopLogger()
-			queryable, err := NewBlocksStoreQueryable(stores, finder, NewBlocksConsistencyChecker(0, 0, logger, nil), &blocksStoreLimitsMock{}, 0, logger, nil)
+			queryable, err := NewBlocksStoreQueryable(stores, finder, NewBlocksConsistencyChecker(0, 0, logger, nil), &blocksStoreLimitsMock{}, 0, false, logger, nil)
 			require.NoError(t, err)
 			require.NoError(t, services.StartAndAwaitRunning(context.Background(), queryable))
 			defer services.StopAndAwaitTerminated(context.Background(), queryable) // nolint:errcheck
@@ -1724,6 +1725,54 @@ func TestCanBlockWithCompactorShardIdContainQueryShard(t *testing.T) {
 	}
 }
 
+func TestFilterBlocksByResolution(t *testing.T) {
+	raw1 := &bucketindex.Block{ID: ulid.MustNew(1, nil), MinTime: 0, MaxTime: 100}
+	raw2 := &bucketindex.Block{ID: ulid.MustNew(2, nil), MinTime: 100, MaxTime: 200}
+	raw3 := &bucketindex.Block{ID: ulid.MustNew(3, nil), MinTime: 200, MaxTime: 300}
+	res1Block1 := &bucketindex.Block{ID: ulid.MustNew(4, nil), MinTime: 0, MaxTime: 100, Resolution: downsample.ResLevel1}
+	res1Block2 := &bucketindex.Block{ID: ulid.MustNew(5, nil), MinTime: 100, MaxTime: 200, Resolution: downsample.ResLevel1}
+	res2Block1 := &bucketindex.Block{ID: ulid.MustNew(6, nil), MinTime: 0, MaxTime: 100, Resolution: downsample.ResLevel2}
+
+	allBlocks := bucketindex.Blocks{raw3, raw2, raw1, res1Block2, res1Block1, res2Block1}
+	noSkip := func(*bucketindex.Block) bool { return false }
+
+	for name, testcase := range map[string]struct {
+		maxResolution  int64
+		skip           func(*bucketindex.Block) bool
+		expectedBlocks bucketindex.Blocks
+	}{
+		"raw resolution": {
+			maxResolution:  downsample.ResLevel0,
+			skip:           noSkip,
+			expectedBlocks: bucketindex.Blocks{raw3, raw2, raw1},
+		},
+		"max resolution lower than the first downsampling level": {
+			maxResolution:  downsample.ResLevel1 - 1,
+			skip:           noSkip,
+			expectedBlocks: bucketindex.Blocks{raw3, raw2, raw1},
+		},
+		"first downsampling level, with gaps filled with raw blocks": {
+			maxResolution:  downsample.ResLevel1,
+			skip:           noSkip,
+			expectedBlocks: bucketindex.Blocks{raw3, res1Block2, res1Block1},
+		},
+		"second downsampling level, with gaps filled with higher resolution blocks": {
+			maxResolution:  downsample.ResLevel2,
+			skip:           noSkip,
+			expectedBlocks: bucketindex.Blocks{raw3, res1Block2, res2Block1},
+		},
+		"skipped downsampled blocks are replaced by higher resolution blocks": {
+			maxResolution:  downsample.ResLevel2,
+			skip:           func(b *bucketindex.Block) bool { return b.ID == res2Block1.ID || b.ID == res1Block2.ID },
+			expectedBlocks: bucketindex.Blocks{raw3, raw2, res1Block1},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testcase.expectedBlocks, filterBlocksByResolution(allBlocks, 0, 299, testcase.maxResolution, testcase.skip))
+		})
+	}
+}
+
 func TestFilterBlocksByShard(t *testing.T) {
 	block1 := &bucketindex.Block{ID: ulid.MustNew(ulid.Now(), crand.Reader), MinTime: 0, MaxTime: 100, CompactorShardID: "1_of_4"}
 	block2 := &bucketindex.Block{ID: ulid.MustNew(ulid.Now(), crand.Reader), MinTime: 0, MaxTime: 100, CompactorShardID: "2_of_4"}
@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+// NewBucketTombstonesLoaderFromConfig creates a new tombstones loader reading from the blocks storage bucket.
+func NewBucketTombstonesLoaderFromConfig(storageCfg mimir_tsdb.BlocksStorageConfig, logger log.Logger, reg prometheus.Registerer) (*mimir_tsdb.BucketTombstonesLoader, error) {
+	bkt, err := bucket.NewClient(context.Background(), storageCfg.Bucket, "querier-tombstones", logger, reg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create bucket client")
+	}
+
+	reg = prometheus.WrapRegistererWith(prometheus.Labels{"component": "querier"}, reg)
+	return mimir_tsdb.NewBucketTombstonesLoader(bkt, mimir_tsdb.TombstonesCacheTTL, logger, reg), nil
+}
+
+// NewDeletedSeriesQueryable returns a queryable which filters out samples deleted by pending
+// series deletion requests from the results of the wrapped queryable.
+func NewDeletedSeriesQueryable(q storage.Queryable, loader mimir_tsdb.TombstonesLoader) storage.Queryable {
+	return storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		all, err := loader.GetTombstones(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		querier, err := q.Querier(ctx, mint, maxt)
+		if err != nil {
+			return nil, err
+		}
+
+		ts := all.Overlapping(mint, maxt)
+		if len(ts) == 0 {
+			return querier, nil
+		}
+
+		return &deletedSeriesQuerier{Querier: querier, tombstones: ts, mint: mint, maxt: maxt}, nil
+	})
+}
+
+type deletedSeriesQuerier struct {
+	storage.Querier
+
+	tombstones mimir_tsdb.Tombstones
+	mint, maxt int64
+}
+
+func (q *deletedSeriesQuerier) Select(sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	mint, maxt := q.mint, q.maxt
+	if hints != nil {
+		mint, maxt = hints.Start, hints.End
+	}
+
+	ts := q.tombstones.Overlapping(mint, maxt)
+	if len(ts) == 0 {
+		return q.Querier.Select(sortSeries, hints, matchers...)
+	}
+
+	return &deletedSeriesSet{
+		SeriesSet:  q.Querier.Select(sortSeries, hints, matchers...),
+		tombstones: ts,
+		mint:       mint,
+		maxt:       maxt,
+	}
+}
+
+// deletedSeriesSet removes deleted samples from each series, and skips series
+// whose samples have all been deleted in the queried time range.
+type deletedSeriesSet struct {
+	storage.SeriesSet
+
+	tombstones mimir_tsdb.Tombstones
+	mint, maxt int64
+	curr       storage.Series
+}
+
+func (s *deletedSeriesSet) Next() bool {
+	for s.SeriesSet.Next() {
+		series := s.SeriesSet.At()
+
+		intervals := s.tombstones.DeletedIntervals(series.Labels())
+		if len(intervals) == 0 {
+			s.curr = series
+			return true
+		}
+
+		if (tombstones.Interval{Mint: s.mint, Maxt: s.maxt}).IsSubrange(intervals) {
+			continue
+		}
+
+		s.curr = &deletedSeries{Series: series, intervals: intervals}
+		return true
+	}
+	return false
+}
+
+func (s *deletedSeriesSet) At() storage.Series {
+	return s.curr
+}
+
+type deletedSeries struct {
+	storage.Series
+
+	intervals tombstones.Intervals
+}
+
+func (s *deletedSeries) Iterator() chunkenc.Iterator {
+	return &tsdb.DeletedIterator{Iter: s.Series.Iterator(), Intervals: s.intervals}
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/storage/series"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+type staticTombstonesLoader struct {
+	tombstones mimir_tsdb.Tombstones
+	err        error
+}
+
+func (l staticTombstonesLoader) GetTombstones(context.Context, string) (mimir_tsdb.Tombstones, error) {
+	return l.tombstones, l.err
+}
+
+type seriesSetQuerier struct {
+	storage.Querier
+	series []storage.Series
+}
+
+func (q seriesSetQuerier) Select(bool, *storage.SelectHints, ...*labels.Matcher) storage.SeriesSet {
+	return series.NewConcreteSeriesSet(q.series)
+}
+
+func TestDeletedSeriesQueryable(t *testing.T) {
+	samples := func(ts ...int64) []model.SamplePair {
+		out := make([]model.SamplePair, 0, len(ts))
+		for _, t := range ts {
+			out = append(out, model.SamplePair{Timestamp: model.Time(t), Value: model.SampleValue(t)})
+		}
+		return out
+	}
+
+	input := []storage.Series{
+		series.NewConcreteSeries(labels.FromStrings("job", "a"), samples(10, 20, 30, 40)),
+		series.NewConcreteSeries(labels.FromStrings("job", "b"), samples(10, 20, 30, 40)),
+		series.NewConcreteSeries(labels.FromStrings("job", "c"), samples(10, 20, 30, 40)),
+	}
+
+	upstream := storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		return seriesSetQuerier{series: input}, nil
+	})
+
+	partial, err := mimir_tsdb.NewTombstone(0, 15, 30, []string{`{job="a"}`})
+	require.NoError(t, err)
+	full, err := mimir_tsdb.NewTombstone(0, 0, 100, []string{`{job="b"}`})
+	require.NoError(t, err)
+	other, err := mimir_tsdb.NewTombstone(0, 200, 300, []string{`{job="c"}`})
+	require.NoError(t, err)
+
+	ctx := user.InjectOrgID(context.Background(), "user")
+
+	t.Run("deleted samples are filtered out", func(t *testing.T) {
+		q, err := NewDeletedSeriesQueryable(upstream, staticTombstonesLoader{tombstones: mimir_tsdb.Tombstones{partial, full, other}}).Querier(ctx, 0, 50)
+		require.NoError(t, err)
+
+		actual := map[string][]int64{}
+		set := q.Select(true, &storage.SelectHints{Start: 0, End: 50})
+		for set.Next() {
+			var ts []int64
+			it := set.At().Iterator()
+			for it.Next() {
+				t, _ := it.At()
+				ts = append(ts, t)
+			}
+			require.NoError(t, it.Err())
+			actual[set.At().Labels().Get("job")] = ts
+		}
+		require.NoError(t, set.Err())
+
+		assert.Equal(t, map[string][]int64{
+			"a": {10, 40},
+			"c": {10, 20, 30, 40},
+		}, actual)
+	})
+
+	t.Run("querier is not wrapped if no tombstones overlap", func(t *testing.T) {
+		q, err := NewDeletedSeriesQueryable(upstream, staticTombstonesLoader{tombstones: mimir_tsdb.Tombstones{other}}).Querier(ctx, 0, 50)
+		require.NoError(t, err)
+		assert.IsType(t, seriesSetQuerier{}, q)
+	})
+
+	t.Run("tombstones loading failure", func(t *testing.T) {
+		_, err := NewDeletedSeriesQueryable(upstream, staticTombstonesLoader{err: errors.New("failure")}).Querier(ctx, 0, 50)
+		require.EqualError(t, err, "failure")
+	})
+}
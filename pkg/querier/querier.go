@@ -52,6 +52,8 @@ type Config struct {
 
 	ShuffleShardingIngestersEnabled bool `yaml:"shuffle_sharding_ingesters_enabled" category:"advanced"`
 
+	AutoDownsamplingEnabled bool `yaml:"auto_downsampling_enabled" category:"experimental"`
+
 	// PromQL engine config.
 	EngineConfig engine.Config `yaml:",inline"`
 }
@@ -79,6 +81,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	flagext.DeprecatedFlag(f, shuffleShardingIngestersLookbackPeriodFlag, fmt.Sprintf("Deprecated: this setting should always be the same as -%s and will now behave as if it is", queryIngestersWithinFlag), logger)
 	f.BoolVar(&cfg.ShuffleShardingIngestersEnabled, "querier.shuffle-sharding-ingesters-enabled", true, fmt.Sprintf("Fetch in-memory series from the minimum set of required ingesters, selecting only ingesters which may have received series since -%s. If this setting is false or -%s is '0', queriers always query all ingesters (ingesters shuffle sharding on read path is disabled).", queryIngestersWithinFlag, queryIngestersWithinFlag))
 
+	f.BoolVar(&cfg.AutoDownsamplingEnabled, "querier.auto-downsampling-enabled", false, "Query blocks downsampled by the compactor when their resolution is at least 5 times finer than the query step. Downsampled chunks are aggregated according to the PromQL function the series are selected for.")
+
 	cfg.EngineConfig.RegisterFlags(f)
 }
 
@@ -30,6 +30,7 @@ import (
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
 	"github.com/grafana/mimir/pkg/ruler/rulestore"
+	"github.com/grafana/mimir/pkg/util"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 )
 
@@ -106,6 +107,37 @@ type recordingRule struct {
 	EvaluationTime float64       `json:"evaluationTime"`
 }
 
+// RuleHistoryDiscovery has the evaluation history of all rules
+type RuleHistoryDiscovery struct {
+	RuleGroups []*RuleGroupHistory `json:"groups"`
+}
+
+// RuleGroupHistory has the evaluation history of the rules which are part of a group
+type RuleGroupHistory struct {
+	Name     string         `json:"name"`
+	File     string         `json:"file"`
+	Interval float64        `json:"interval"`
+	Rules    []*RuleHistory `json:"rules"`
+}
+
+// RuleHistory has the last evaluations of a rule, the most recent first
+type RuleHistory struct {
+	Name        string            `json:"name"`
+	Type        v1.RuleType       `json:"type"`
+	Health      string            `json:"health"`
+	Evaluations []*RuleEvaluation `json:"evaluations"`
+}
+
+// RuleEvaluation has info for a past evaluation of a rule
+type RuleEvaluation struct {
+	Timestamp      time.Time `json:"timestamp"`
+	EvaluationTime float64   `json:"evaluationTime"`
+	Samples        int64     `json:"samples"`
+	LastError      string    `json:"lastError"`
+	// FiringAlerts is only set for alerting rules.
+	FiringAlerts *int64 `json:"firingAlerts,omitempty"`
+}
+
 func respondError(logger log.Logger, w http.ResponseWriter, msg string) {
 	b, err := json.Marshal(&response{
 		Status:    "error",
@@ -128,17 +160,19 @@ func respondError(logger log.Logger, w http.ResponseWriter, msg string) {
 
 // API is used to handle HTTP requests for the ruler service
 type API struct {
-	ruler *Ruler
-	store rulestore.RuleStore
+	ruler  *Ruler
+	store  rulestore.RuleStore
+	tester *RuleGroupTester
 
 	logger log.Logger
 }
 
-// NewAPI returns a new API struct with the provided ruler and rule store
-func NewAPI(r *Ruler, s rulestore.RuleStore, logger log.Logger) *API {
+// NewAPI returns a new API struct with the provided ruler, rule store and rule group tester
+func NewAPI(r *Ruler, s rulestore.RuleStore, tester *RuleGroupTester, logger log.Logger) *API {
 	return &API{
 		ruler:  r,
 		store:  s,
+		tester: tester,
 		logger: logger,
 	}
 }
@@ -153,7 +187,7 @@ func (a *API) PrometheusRules(w http.ResponseWriter, req *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	rgs, err := a.ruler.GetRules(req.Context())
+	rgs, err := a.ruler.GetRules(req.Context(), &RulesRequest{})
 
 	if err != nil {
 		respondError(logger, w, err.Error())
@@ -236,6 +270,86 @@ func (a *API) PrometheusRules(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// PrometheusRulesHistory returns the last evaluations of each rule of the tenant, across all rulers.
+func (a *API) PrometheusRulesHistory(w http.ResponseWriter, req *http.Request) {
+	logger := util_log.WithContext(req.Context(), a.logger)
+	userID, err := tenant.TenantID(req.Context())
+	if err != nil || userID == "" {
+		level.Error(logger).Log("msg", "error extracting org id from context", "err", err)
+		respondError(logger, w, "no valid org id found")
+		return
+	}
+
+	rgs, err := a.ruler.GetRules(req.Context(), &RulesRequest{IncludeEvaluationHistory: true})
+	if err != nil {
+		respondError(logger, w, err.Error())
+		return
+	}
+
+	groups := make([]*RuleGroupHistory, 0, len(rgs))
+	for _, g := range rgs {
+		grp := &RuleGroupHistory{
+			Name:     g.Group.Name,
+			File:     g.Group.Namespace,
+			Interval: g.Group.Interval.Seconds(),
+			Rules:    make([]*RuleHistory, 0, len(g.ActiveRules)),
+		}
+
+		for _, rl := range g.ActiveRules {
+			r := &RuleHistory{
+				Name:        rl.Rule.GetRecord(),
+				Type:        v1.RuleTypeRecording,
+				Health:      rl.GetHealth(),
+				Evaluations: make([]*RuleEvaluation, 0, len(rl.EvaluationHistory)),
+			}
+			alerting := rl.Rule.GetAlert() != ""
+			if alerting {
+				r.Name = rl.Rule.GetAlert()
+				r.Type = v1.RuleTypeAlerting
+			}
+
+			for _, e := range rl.EvaluationHistory {
+				evaluation := &RuleEvaluation{
+					Timestamp:      e.GetEvaluationTimestamp(),
+					EvaluationTime: e.GetEvaluationDuration().Seconds(),
+					Samples:        e.GetSamples(),
+					LastError:      e.GetLastError(),
+				}
+				if alerting {
+					firingAlerts := e.GetFiringAlerts()
+					evaluation.FiringAlerts = &firingAlerts
+				}
+				r.Evaluations = append(r.Evaluations, evaluation)
+			}
+			grp.Rules = append(grp.Rules, r)
+		}
+		groups = append(groups, grp)
+	}
+
+	// keep data.groups are in order
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].File != groups[j].File {
+			return groups[i].File < groups[j].File
+		}
+		return groups[i].Name < groups[j].Name
+	})
+
+	b, err := json.Marshal(&response{
+		Status: "success",
+		Data:   &RuleHistoryDiscovery{RuleGroups: groups},
+	})
+	if err != nil {
+		level.Error(logger).Log("msg", "error marshaling json response", "err", err)
+		respondError(logger, w, "unable to marshal the requested data")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if n, err := w.Write(b); err != nil {
+		level.Error(logger).Log("msg", "error writing response", "bytesWritten", n, "err", err)
+	}
+}
+
 func (a *API) PrometheusAlerts(w http.ResponseWriter, req *http.Request) {
 	logger := util_log.WithContext(req.Context(), a.logger)
 	userID, err := tenant.TenantID(req.Context())
@@ -246,7 +360,7 @@ func (a *API) PrometheusAlerts(w http.ResponseWriter, req *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	rgs, err := a.ruler.GetRules(req.Context())
+	rgs, err := a.ruler.GetRules(req.Context(), &RulesRequest{})
 
 	if err != nil {
 		respondError(logger, w, err.Error())
@@ -422,7 +536,11 @@ func (a *API) ListRules(w http.ResponseWriter, req *http.Request) {
 
 	level.Debug(logger).Log("msg", "retrieved rule groups from rule store", "userID", userID, "num_namespaces", len(rgs))
 
-	formatted := rgs.Formatted()
+	formatted, err := rgs.FormattedWithOptions()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	marshalAndSend(formatted, w, logger)
 }
 
@@ -444,7 +562,11 @@ func (a *API) GetRuleGroup(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	formatted := rulespb.FromProto(rg)
+	formatted, err := rulespb.FromProtoWithOptions(rg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	marshalAndSend(formatted, w, logger)
 }
 
@@ -473,6 +595,15 @@ func (a *API) CreateRuleGroup(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// The Mimir specific options of the rule group are set alongside the Prometheus rule group fields.
+	opts := rulespb.RuleGroupOptions{}
+	err = yaml.Unmarshal(payload, &opts)
+	if err != nil {
+		level.Error(logger).Log("msg", "unable to unmarshal rule group options", "err", err.Error())
+		http.Error(w, ErrBadRuleGroup.Error(), http.StatusBadRequest)
+		return
+	}
+
 	errs := a.ruler.manager.ValidateRuleGroup(rg)
 	if len(errs) > 0 {
 		e := []string{}
@@ -491,6 +622,12 @@ func (a *API) CreateRuleGroup(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err := a.ruler.AssertRuleGroupEvaluationLimits(userID, time.Duration(rg.Interval), time.Duration(opts.QueryTimeout)); err != nil {
+		level.Error(logger).Log("msg", "limit validation failure", "err", err.Error(), "user", userID)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	rgs, err := a.store.ListRuleGroupsForUserAndNamespace(req.Context(), userID, "")
 	if err != nil {
 		level.Error(logger).Log("msg", "unable to fetch current rule groups for validation", "err", err.Error(), "user", userID)
@@ -505,6 +642,11 @@ func (a *API) CreateRuleGroup(w http.ResponseWriter, req *http.Request) {
 	}
 
 	rgProto := rulespb.ToProto(userID, namespace, rg)
+	if err := rgProto.SetRuleGroupOptions(opts); err != nil {
+		level.Error(logger).Log("msg", "unable to set rule group options", "err", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	level.Debug(logger).Log("msg", "attempting to store rulegroup", "userID", userID, "group", rgProto.String())
 	err = a.store.SetRuleGroup(req.Context(), userID, namespace, rgProto)
@@ -517,6 +659,84 @@ func (a *API) CreateRuleGroup(w http.ResponseWriter, req *http.Request) {
 	respondAccepted(w, logger)
 }
 
+// TestRuleGroup evaluates the rule group in the request body at the time given by the optional `time`
+// parameter, and returns the series its recording rules would write and the pending and firing alerts of its
+// alerting rules, without writing nor sending anything.
+func (a *API) TestRuleGroup(w http.ResponseWriter, req *http.Request) {
+	logger := util_log.WithContext(req.Context(), a.logger)
+	userID, _, _, err := parseRequest(req, true, false)
+	if err != nil {
+		respondError(logger, w, err.Error())
+		return
+	}
+
+	ts := time.Now()
+	if t := req.FormValue("time"); t != "" {
+		ms, err := util.ParseTime(t)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ts = util.TimeFromMillis(ms)
+	}
+
+	payload, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		level.Error(logger).Log("msg", "unable to read rule group payload", "err", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rg := rulefmt.RuleGroup{}
+	err = yaml.Unmarshal(payload, &rg)
+	if err != nil {
+		level.Error(logger).Log("msg", "unable to unmarshal rule group payload", "err", err.Error())
+		http.Error(w, ErrBadRuleGroup.Error(), http.StatusBadRequest)
+		return
+	}
+
+	errs := a.ruler.manager.ValidateRuleGroup(rg)
+	if len(errs) > 0 {
+		e := []string{}
+		for _, err := range errs {
+			e = append(e, err.Error())
+		}
+
+		http.Error(w, strings.Join(e, ", "), http.StatusBadRequest)
+		return
+	}
+
+	opts := rulespb.RuleGroupOptions{}
+	err = yaml.Unmarshal(payload, &opts)
+	if err != nil {
+		http.Error(w, ErrBadRuleGroup.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.ruler.AssertMaxRulesPerRuleGroup(userID, len(rg.Rules)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.ruler.AssertRuleGroupEvaluationLimits(userID, time.Duration(rg.Interval), time.Duration(opts.QueryTimeout)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := a.tester.Test(req.Context(), userID, rg, opts, ts)
+	if err != nil {
+		if errors.Is(err, errFederatedRuleGroupsDisabled) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		level.Error(logger).Log("msg", "unable to test rule group", "err", err.Error(), "user", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	marshalAndSend(result, w, logger)
+}
+
 func (a *API) DeleteNamespace(w http.ResponseWriter, req *http.Request) {
 	logger := util_log.WithContext(req.Context(), a.logger)
 
@@ -9,20 +9,28 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/gorilla/mux"
 	"github.com/grafana/dskit/services"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/common/user"
+	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/ruler/tenantfederation"
 )
 
 func TestRuler(t *testing.T) {
@@ -168,7 +176,7 @@ func TestRuler(t *testing.T) {
 			// Ensure all rules are loaded before usage
 			r.syncRules(context.Background(), rulerSyncReasonInitial)
 
-			a := NewAPI(r, r.store, log.NewNopLogger())
+			a := NewAPI(r, r.store, nil, log.NewNopLogger())
 
 			req := requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/api/v1/rules", nil, tc.userID)
 			w := httptest.NewRecorder()
@@ -208,7 +216,7 @@ func TestRuler_alerts(t *testing.T) {
 	// Ensure all rules are loaded before usage
 	r.syncRules(context.Background(), rulerSyncReasonInitial)
 
-	a := NewAPI(r, r.store, log.NewNopLogger())
+	a := NewAPI(r, r.store, nil, log.NewNopLogger())
 
 	req := requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/api/v1/alerts", nil, "user1")
 	w := httptest.NewRecorder()
@@ -236,13 +244,94 @@ func TestRuler_alerts(t *testing.T) {
 	require.Equal(t, string(expectedResponse), string(body))
 }
 
+func TestRuler_PrometheusRulesHistory(t *testing.T) {
+	cfg := defaultRulerConfig(t)
+	cfg.EvaluationHistorySize = 2
+
+	rulerAddrMap := map[string]*Ruler{}
+
+	r := buildRuler(t, cfg, newMockRuleStore(mockRules), rulerAddrMap)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), r))
+	defer services.StopAndAwaitTerminated(context.Background(), r) //nolint:errcheck
+
+	// Make sure mock grpc client can find this instance, based on instance address registered in the ring.
+	rulerAddrMap[r.lifecycler.GetInstanceAddr()] = r
+
+	// Ensure all rules are loaded before usage
+	r.syncRules(context.Background(), rulerSyncReasonInitial)
+
+	// Evaluate the rule groups more times than the size of the history, with the context the rules manager
+	// evaluates them with.
+	groups := r.manager.GetRules("user1")
+	contextFunc := trackRuleEvaluationsContextFunc(newRuleEvaluationHistory(cfg.EvaluationHistorySize).GroupEvaluationContextFunc(nil))
+	contexts := make([]context.Context, len(groups))
+	for i, g := range groups {
+		contexts[i] = contextFunc(user.InjectOrgID(context.Background(), "user1"), g)
+	}
+	for i := 0; i < 3; i++ {
+		for j, g := range groups {
+			g.Eval(contexts[j], time.Now())
+		}
+	}
+
+	a := NewAPI(r, r.store, nil, log.NewNopLogger())
+
+	req := requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/api/v1/rules/history", nil, "user1")
+	w := httptest.NewRecorder()
+	a.PrometheusRulesHistory(w, req)
+
+	resp := w.Result()
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	discovery := &RuleHistoryDiscovery{}
+	require.NoError(t, json.Unmarshal(body, &response{Data: discovery}))
+
+	require.Len(t, discovery.RuleGroups, 1)
+	group := discovery.RuleGroups[0]
+	assert.Equal(t, "group1", group.Name)
+	assert.Equal(t, "namespace1", group.File)
+	assert.Equal(t, 60.0, group.Interval)
+
+	require.Len(t, group.Rules, 2)
+	assert.Equal(t, "UP_RULE", group.Rules[0].Name)
+	assert.Equal(t, v1.RuleTypeRecording, group.Rules[0].Type)
+	assert.Equal(t, "UP_ALERT", group.Rules[1].Name)
+	assert.Equal(t, v1.RuleTypeAlerting, group.Rules[1].Type)
+
+	for _, rl := range group.Rules {
+		assert.Equal(t, "ok", rl.Health)
+		require.Len(t, rl.Evaluations, cfg.EvaluationHistorySize)
+		assert.True(t, rl.Evaluations[0].Timestamp.After(rl.Evaluations[1].Timestamp))
+
+		for _, e := range rl.Evaluations {
+			assert.Equal(t, int64(0), e.Samples)
+			assert.Empty(t, e.LastError)
+			if rl.Type == v1.RuleTypeAlerting {
+				require.NotNil(t, e.FiringAlerts)
+				assert.Equal(t, int64(0), *e.FiringAlerts)
+			} else {
+				assert.Nil(t, e.FiringAlerts)
+			}
+		}
+	}
+
+	// The evaluation history is only retrieved when it's asked for.
+	rgs, err := r.GetRules(user.InjectOrgID(context.Background(), "user1"), &RulesRequest{})
+	require.NoError(t, err)
+	require.Len(t, rgs, 1)
+	for _, rl := range rgs[0].ActiveRules {
+		assert.Empty(t, rl.EvaluationHistory)
+	}
+}
+
 func TestRuler_Create(t *testing.T) {
 	cfg := defaultRulerConfig(t)
 
 	r := newTestRuler(t, cfg, newMockRuleStore(make(map[string]rulespb.RuleGroupList)))
 	defer services.StopAndAwaitTerminated(context.Background(), r) //nolint:errcheck
 
-	a := NewAPI(r, r.store, log.NewNopLogger())
+	a := NewAPI(r, r.store, nil, log.NewNopLogger())
 
 	tc := []struct {
 		name   string
@@ -296,6 +385,33 @@ rules:
 `,
 			output: "name: test\ninterval: 15s\nrules:\n    - record: up_rule\n      expr: up{}\n    - alert: up_alert\n      expr: sum(up{}) > 1\n      for: 30s\n      labels:\n        test: test\n      annotations:\n        test: test\n",
 		},
+		{
+			name:   "with backfilling of missed iterations enabled",
+			status: 202,
+			input: `
+name: test
+interval: 15s
+backfill_missed_iterations: true
+rules:
+- record: up_rule
+  expr: up{}
+`,
+			output: "name: test\ninterval: 15s\nrules:\n    - record: up_rule\n      expr: up{}\nbackfill_missed_iterations: true\n",
+		},
+		{
+			name:   "with evaluation delay and query timeout",
+			status: 202,
+			input: `
+name: test
+interval: 15s
+evaluation_delay: 1m
+query_timeout: 10s
+rules:
+- record: up_rule
+  expr: up{}
+`,
+			output: "name: test\ninterval: 15s\nevaluation_delay: 1m\nrules:\n    - record: up_rule\n      expr: up{}\nquery_timeout: 10s\n",
+		},
 	}
 
 	for _, tt := range tc {
@@ -370,7 +486,7 @@ func TestRuler_DeleteNamespace(t *testing.T) {
 	r := newTestRuler(t, cfg, newMockRuleStore(mockRulesNamespaces))
 	defer services.StopAndAwaitTerminated(context.Background(), r) //nolint:errcheck
 
-	a := NewAPI(r, r.store, log.NewNopLogger())
+	a := NewAPI(r, r.store, nil, log.NewNopLogger())
 
 	router := mux.NewRouter()
 	router.Path("/prometheus/config/v1/rules/{namespace}").Methods(http.MethodDelete).HandlerFunc(a.DeleteNamespace)
@@ -409,7 +525,7 @@ func TestRuler_LimitsPerGroup(t *testing.T) {
 
 	r.limits = &ruleLimits{maxRuleGroups: 1, maxRulesPerRuleGroup: 1}
 
-	a := NewAPI(r, r.store, log.NewNopLogger())
+	a := NewAPI(r, r.store, nil, log.NewNopLogger())
 
 	tc := []struct {
 		name   string
@@ -462,7 +578,7 @@ func TestRuler_RulerGroupLimits(t *testing.T) {
 
 	r.limits = &ruleLimits{maxRuleGroups: 1, maxRulesPerRuleGroup: 1}
 
-	a := NewAPI(r, r.store, log.NewNopLogger())
+	a := NewAPI(r, r.store, nil, log.NewNopLogger())
 
 	tc := []struct {
 		name   string
@@ -514,6 +630,255 @@ rules:
 	}
 }
 
+func TestRuler_RuleGroupEvaluationLimits(t *testing.T) {
+	cfg := defaultRulerConfig(t)
+	cfg.EvaluationInterval = time.Minute
+
+	r := newTestRuler(t, cfg, newMockRuleStore(make(map[string]rulespb.RuleGroupList)))
+	defer services.StopAndAwaitTerminated(context.Background(), r) //nolint:errcheck
+
+	r.limits = &ruleLimits{
+		maxRuleGroups:        10,
+		maxRulesPerRuleGroup: 10,
+		minInterval:          30 * time.Second,
+		maxInterval:          5 * time.Minute,
+		maxQueryTimeout:      time.Minute,
+	}
+
+	a := NewAPI(r, r.store, nil, log.NewNopLogger())
+
+	router := mux.NewRouter()
+	router.Path("/prometheus/config/v1/rules/{namespace}").Methods("POST").HandlerFunc(a.CreateRuleGroup)
+
+	tc := []struct {
+		name   string
+		input  string
+		output string
+		status int
+	}{
+		{
+			name:   "with the default interval and no query timeout",
+			status: 202,
+			input: `
+name: test
+rules:
+- record: up_rule
+  expr: up{}
+`,
+			output: "{\"status\":\"success\",\"data\":null,\"errorType\":\"\",\"error\":\"\"}",
+		},
+		{
+			name:   "with an interval and a query timeout within the limits",
+			status: 202,
+			input: `
+name: test
+interval: 30s
+query_timeout: 1m
+rules:
+- record: up_rule
+  expr: up{}
+`,
+			output: "{\"status\":\"success\",\"data\":null,\"errorType\":\"\",\"error\":\"\"}",
+		},
+		{
+			name:   "with an interval shorter than the minimum interval",
+			status: 400,
+			input: `
+name: test
+interval: 15s
+rules:
+- record: up_rule
+  expr: up{}
+`,
+			output: fmt.Sprintf(errMinRuleGroupIntervalLimitExceeded+"\n", 30*time.Second, 15*time.Second),
+		},
+		{
+			name:   "with an interval longer than the maximum interval",
+			status: 400,
+			input: `
+name: test
+interval: 10m
+rules:
+- record: up_rule
+  expr: up{}
+`,
+			output: fmt.Sprintf(errMaxRuleGroupIntervalLimitExceeded+"\n", 5*time.Minute, 10*time.Minute),
+		},
+		{
+			name:   "with a query timeout longer than the maximum query timeout",
+			status: 400,
+			input: `
+name: test
+query_timeout: 2m
+rules:
+- record: up_rule
+  expr: up{}
+`,
+			output: fmt.Sprintf(errMaxRuleGroupQueryTimeoutLimitExceeded+"\n", time.Minute, 2*time.Minute),
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			req := requestFor(t, http.MethodPost, "https://localhost:8080/prometheus/config/v1/rules/namespace", strings.NewReader(tt.input), "user1")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+			require.Equal(t, tt.status, w.Code)
+			require.Equal(t, tt.output, w.Body.String())
+		})
+	}
+}
+
+func TestRuler_TestRuleGroup(t *testing.T) {
+	cfg := defaultRulerConfig(t)
+
+	r := newTestRuler(t, cfg, newMockRuleStore(make(map[string]rulespb.RuleGroupList)))
+	defer services.StopAndAwaitTerminated(context.Background(), r) //nolint:errcheck
+
+	evalTime := time.Unix(1600000000, 0).UTC()
+
+	queryFunc := func(ctx context.Context, qs string, ts time.Time) (promql.Vector, error) {
+		userID, err := tenantfederation.ExtractTenantIDs(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "user1", userID)
+		require.False(t, ts.After(evalTime))
+
+		switch qs {
+		case "up":
+			return promql.Vector{
+				{Point: promql.Point{T: ts.UnixMilli(), V: 1}, Metric: labels.FromStrings(labels.MetricName, "up", "job", "a")},
+				{Point: promql.Point{T: ts.UnixMilli(), V: 0}, Metric: labels.FromStrings(labels.MetricName, "up", "job", "b")},
+			}, nil
+		case "up == 0":
+			return promql.Vector{
+				{Point: promql.Point{T: ts.UnixMilli(), V: 0}, Metric: labels.FromStrings(labels.MetricName, "up", "job", "b")},
+			}, nil
+		case "up{job=\"c\"} == 0":
+			// The series only matches at the last evaluation.
+			if ts.Before(evalTime) {
+				return nil, nil
+			}
+			return promql.Vector{
+				{Point: promql.Point{T: ts.UnixMilli(), V: 0}, Metric: labels.FromStrings(labels.MetricName, "up", "job", "c")},
+			}, nil
+		default:
+			return nil, errors.New("query failed")
+		}
+	}
+
+	a := NewAPI(r, r.store, NewRuleGroupTester(cfg, queryFunc, ruleLimits{}, log.NewNopLogger()), log.NewNopLogger())
+
+	router := mux.NewRouter()
+	router.Path("/prometheus/config/v1/rules/{namespace}/test").Methods("POST").HandlerFunc(a.TestRuleGroup)
+
+	tc := []struct {
+		name     string
+		input    string
+		status   int
+		expected *RuleGroupTestResult
+	}{
+		{
+			name:   "with an invalid rule group",
+			status: 400,
+			input: `
+name: rg_name
+interval: 15s
+`,
+		},
+		{
+			name:   "with a valid rule group",
+			status: 200,
+			input: `
+name: test
+rules:
+- record: up:copy
+  expr: up
+  labels:
+    source: test
+- alert: UpIsZero
+  expr: up == 0
+  annotations:
+    summary: '{{ $labels.job }} is down'
+- alert: UpIsZeroForLong
+  expr: up == 0
+  for: 5m
+- alert: UpIsZeroSinceLastEvaluation
+  expr: up{job="c"} == 0
+  for: 5m
+- record: failing
+  expr: sum(failing)
+`,
+			expected: &RuleGroupTestResult{
+				Name:           "test",
+				EvaluationTime: evalTime,
+				Rules: []RuleTestResult{
+					{
+						Record: "up:copy",
+						Health: "ok",
+						Series: []TestSample{
+							{Labels: labels.FromStrings(labels.MetricName, "up:copy", "job", "a", "source", "test"), Value: 1, Timestamp: evalTime},
+							{Labels: labels.FromStrings(labels.MetricName, "up:copy", "job", "b", "source", "test"), Value: 0, Timestamp: evalTime},
+						},
+					},
+					{
+						Alert:  "UpIsZero",
+						Health: "ok",
+						Alerts: []TestAlert{
+							{Labels: labels.FromStrings(labels.AlertName, "UpIsZero", "job", "b"), Annotations: labels.FromStrings("summary", "b is down"), State: "firing", ActiveAt: evalTime},
+						},
+					},
+					{
+						Alert:  "UpIsZeroForLong",
+						Health: "ok",
+						Alerts: []TestAlert{
+							{Labels: labels.FromStrings(labels.AlertName, "UpIsZeroForLong", "job", "b"), State: "firing", ActiveAt: evalTime.Add(-5 * time.Minute)},
+						},
+					},
+					{
+						Alert:  "UpIsZeroSinceLastEvaluation",
+						Health: "ok",
+						Alerts: []TestAlert{
+							{Labels: labels.FromStrings(labels.AlertName, "UpIsZeroSinceLastEvaluation", "job", "c"), State: "pending", ActiveAt: evalTime},
+						},
+					},
+					{
+						Record:    "failing",
+						Health:    "err",
+						LastError: "query failed",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			req := requestFor(t, http.MethodPost, fmt.Sprintf("https://localhost:8080/prometheus/config/v1/rules/namespace/test?time=%d", evalTime.Unix()), strings.NewReader(tt.input), "user1")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+			require.Equal(t, tt.status, w.Code)
+			if tt.expected == nil {
+				return
+			}
+
+			actual := &RuleGroupTestResult{}
+			require.NoError(t, yaml.Unmarshal(w.Body.Bytes(), actual))
+			actual.EvaluationTime = actual.EvaluationTime.UTC()
+			for i := range actual.Rules {
+				for j := range actual.Rules[i].Series {
+					actual.Rules[i].Series[j].Timestamp = actual.Rules[i].Series[j].Timestamp.UTC()
+				}
+				for j := range actual.Rules[i].Alerts {
+					actual.Rules[i].Alerts[j].ActiveAt = actual.Rules[i].Alerts[j].ActiveAt.UTC()
+				}
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
 func requestFor(t *testing.T, method string, url string, body io.Reader, userID string) *http.Request {
 	t.Helper()
 
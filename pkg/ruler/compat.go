@@ -8,6 +8,7 @@ package ruler
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/go-kit/log"
@@ -15,6 +16,7 @@ import (
 	"github.com/gogo/status"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/notifier"
@@ -27,6 +29,8 @@ import (
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/querier"
 	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/ruler/tenantfederation"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 )
 
@@ -120,6 +124,13 @@ type RulesLimits interface {
 	RulerTenantShardSize(userID string) int
 	RulerMaxRuleGroupsPerTenant(userID string) int
 	RulerMaxRulesPerRuleGroup(userID string) int
+	RulerMaxIndependentRuleEvaluationConcurrencyPerTenant(userID string) int64
+	RulerMissedIterationsBackfillWindow(userID string) time.Duration
+	RulerMissedIterationsBackfillRate(userID string) float64
+	RulerMinRuleGroupInterval(userID string) time.Duration
+	RulerMaxRuleGroupInterval(userID string) time.Duration
+	RulerMaxRuleGroupQueryTimeout(userID string) time.Duration
+	OutOfOrderTimeWindow(userID string) model.Duration
 }
 
 func MetricsQueryFunc(qf rules.QueryFunc, queries, failedQueries prometheus.Counter) rules.QueryFunc {
@@ -253,6 +264,10 @@ func DefaultTenantManagerFactory(
 			Help: "Total amount of wall clock time spent processing queries by the ruler.",
 		}, []string{"user"})
 	}
+	concurrencyController := newRuleConcurrencyController(cfg.MaxGlobalRuleEvaluationConcurrency, overrides, reg)
+	queryTimeouts := newRuleGroupQueryTimeouts(overrides, reg)
+	backfillMetrics := newMissedIterationsBackfillMetrics(reg)
+
 	return func(ctx context.Context, userID string, notifier *notifier.Manager, logger log.Logger, reg prometheus.Registerer) RulesManager {
 		var queryTime prometheus.Counter = nil
 		if rulerQuerySeconds != nil {
@@ -262,13 +277,19 @@ func DefaultTenantManagerFactory(
 
 		wrappedQueryFunc = MetricsQueryFunc(queryFunc, totalQueries, failedQueries)
 		wrappedQueryFunc = RecordAndReportRuleQueryMetrics(wrappedQueryFunc, queryTime, logger)
+		wrappedQueryFunc = concurrencyController.WrapQueryFunc(wrappedQueryFunc)
+
+		ctx = user.InjectOrgID(ctx, userID)
+		appendable := NewPusherAppendable(p, userID, overrides, totalWrites, failedWrites)
+		options := &ruleGroupOptionsStore{}
+		history := newRuleEvaluationHistory(cfg.EvaluationHistorySize)
 
-		return rules.NewManager(&rules.ManagerOptions{
-			Appendable:                 NewPusherAppendable(p, userID, overrides, totalWrites, failedWrites),
+		manager := rules.NewManager(&rules.ManagerOptions{
+			Appendable:                 history.WrapAppendable(appendable),
 			Queryable:                  embeddedQueryable,
-			QueryFunc:                  wrappedQueryFunc,
-			Context:                    user.InjectOrgID(ctx, userID),
-			GroupEvaluationContextFunc: FederatedGroupContextFunc,
+			QueryFunc:                  trackRuleQueries(history.WrapQueryFunc(queryTimeouts.WrapQueryFunc(wrappedQueryFunc))),
+			Context:                    ctx,
+			GroupEvaluationContextFunc: trackRuleEvaluationsContextFunc(queryTimeouts.GroupEvaluationContextFunc(userID, options, concurrencyController.GroupEvaluationContextFunc(userID, history.GroupEvaluationContextFunc(tenantfederation.GroupContextFunc)))),
 			ExternalURL:                cfg.ExternalURL.URL,
 			NotifyFunc:                 SendAlerts(notifier, cfg.ExternalURL.URL.String()),
 			Logger:                     log.With(logger, "user", userID),
@@ -282,7 +303,103 @@ func DefaultTenantManagerFactory(
 				return overrides.EvaluationDelay(userID)
 			},
 		})
+
+		return &tenantRulesManager{
+			Manager:     manager,
+			userID:      userID,
+			concurrency: concurrencyController,
+			options:     options,
+			history:     history,
+			backfiller:  newMissedIterationsBackfiller(ctx, userID, options, embeddedQueryable, wrappedQueryFunc, appendable, overrides, backfillMetrics),
+		}
+	}
+}
+
+// tenantRulesManager is the rules manager of a tenant, which keeps track of the options of the rule groups
+// and of the evaluation history of their rules, and backfills the missed iterations of the rule groups having
+// backfilling enabled.
+type tenantRulesManager struct {
+	*rules.Manager
+
+	userID      string
+	concurrency *ruleConcurrencyController
+	options     *ruleGroupOptionsStore
+	history     *ruleEvaluationHistory
+	backfiller  *missedIterationsBackfiller
+}
+
+func (m *tenantRulesManager) Update(interval time.Duration, files []string, externalLabels labels.Labels, externalURL string, ruleGroupPostProcessFunc rules.RuleGroupPostProcessFunc) error {
+	postProcessFunc := func(g *rules.Group, lastEvalTimestamp time.Time, logger log.Logger) error {
+		// The missed iterations are backfilled in the background, off the evaluation path of the group.
+		m.backfiller.ScheduleMissedIterations(g, lastEvalTimestamp, logger)
+		if ruleGroupPostProcessFunc != nil {
+			return ruleGroupPostProcessFunc(g, lastEvalTimestamp, logger)
+		}
+		return nil
+	}
+
+	err := m.Manager.Update(interval, files, externalLabels, externalURL, postProcessFunc)
+	m.backfiller.RetainGroups(m.RuleGroups())
+	m.history.RetainGroups(m.RuleGroups())
+	return err
+}
+
+// Run starts the rules manager and the backfilling of the missed iterations. Blocks until Stop is called.
+func (m *tenantRulesManager) Run() {
+	go m.backfiller.Run()
+	m.Manager.Run()
+}
+
+// Stop stops the rules manager and the backfilling of the missed iterations, and forgets the concurrency
+// slots of the tenant.
+func (m *tenantRulesManager) Stop() {
+	m.backfiller.Stop()
+	m.Manager.Stop()
+	m.concurrency.RemoveTenant(m.userID)
+}
+
+// RuleEvaluationHistory implements ruleEvaluationHistoryGetter.
+func (m *tenantRulesManager) RuleEvaluationHistory(g *rules.Group) [][]*RuleEvaluationDesc {
+	return m.history.Evaluations(g)
+}
+
+// SetRuleGroupOptions implements ruleGroupOptionsSetter.
+func (m *tenantRulesManager) SetRuleGroupOptions(opts map[string]rulespb.RuleGroupOptions) {
+	m.options.set(opts)
+}
+
+// ruleGroupOptionsStore holds the options of the rule groups of a tenant, by the key of the group in the rules manager.
+type ruleGroupOptionsStore struct {
+	mtx     sync.RWMutex
+	options map[string]rulespb.RuleGroupOptions
+}
+
+func (s *ruleGroupOptionsStore) set(opts map[string]rulespb.RuleGroupOptions) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.options = opts
+}
+
+// get returns the options of the rule group. It's safe to call on a nil store.
+func (s *ruleGroupOptionsStore) get(g *rules.Group) rulespb.RuleGroupOptions {
+	if s == nil {
+		return rulespb.RuleGroupOptions{}
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.options[rules.GroupKey(g.File(), g.Name())]
+}
+
+// ruleGroupQueryTimeout returns the query timeout of the rule group, limited by the maximum query timeout of the tenant.
+func ruleGroupQueryTimeout(limits RulesLimits, userID string, opts rulespb.RuleGroupOptions) time.Duration {
+	timeout := time.Duration(opts.QueryTimeout)
+	if limit := limits.RulerMaxRuleGroupQueryTimeout(userID); limit > 0 && (timeout <= 0 || timeout > limit) {
+		timeout = limit
 	}
+	return timeout
 }
 
 type QueryableError struct {
@@ -29,6 +29,8 @@ import (
 
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/ruler/tenantfederation"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 type fakePusher struct {
@@ -113,6 +115,12 @@ func TestPusherErrors(t *testing.T) {
 			expectedFailures: 0, // 400 errors not reported as failures.
 		},
 
+		"429 error": {
+			returnedError:    httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewRulerIngestionRateLimitedError(10, 100).Error()),
+			expectedWrites:   1,
+			expectedFailures: 0, // 429 errors not reported as failures.
+		},
+
 		"500 error": {
 			returnedError:    httpgrpc.Errorf(http.StatusInternalServerError, "test error"),
 			expectedWrites:   1,
@@ -318,7 +326,7 @@ func TestManagerFactory_CorrectQueryableUsed(t *testing.T) {
 			regularQueryFunc := rules.EngineQueryFunc(eng, regularQueryable)
 			federatedQueryFunc := rules.EngineQueryFunc(eng, federatedQueryable)
 
-			queryFunc := TenantFederationQueryFunc(regularQueryFunc, federatedQueryFunc)
+			queryFunc := tenantfederation.QueryFunc(regularQueryFunc, federatedQueryFunc)
 
 			// create and use manager factory
 			managerFactory := DefaultTenantManagerFactory(cfg, pusher, federatedQueryable, queryFunc, overrides, nil)
@@ -28,6 +28,19 @@ import (
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
 )
 
+// ruleGroupOptionsSetter is implemented by the RulesManager supporting the options of the rule groups
+// which aren't part of the Prometheus rule group format.
+type ruleGroupOptionsSetter interface {
+	SetRuleGroupOptions(opts map[string]rulespb.RuleGroupOptions)
+}
+
+// ruleEvaluationHistoryGetter is implemented by the RulesManager keeping the evaluation history of the rules.
+type ruleEvaluationHistoryGetter interface {
+	// RuleEvaluationHistory returns the last evaluations of each rule of the group, the most recent first,
+	// by index of the rule in the group.
+	RuleEvaluationHistory(g *promRules.Group) [][]*RuleEvaluationDesc
+}
+
 type DefaultMultiTenantManager struct {
 	cfg            Config
 	notifierCfg    *config.Config
@@ -148,6 +161,11 @@ func (r *DefaultMultiTenantManager) syncRulesToManager(ctx context.Context, user
 		return
 	}
 
+	// The options of the rule groups aren't part of the rule files, so they're updated on every sync.
+	if setter, ok := manager.(ruleGroupOptionsSetter); ok {
+		setter.SetRuleGroupOptions(r.ruleGroupOptions(user, groups))
+	}
+
 	// We need to update the manager only if it was just created or rules on disk have changed.
 	if !(created || update) {
 		level.Debug(r.logger).Log("msg", "rules have not changed, skipping rule manager update", "user", user)
@@ -168,6 +186,20 @@ func (r *DefaultMultiTenantManager) syncRulesToManager(ctx context.Context, user
 	r.lastReloadSuccessfulTimestamp.WithLabelValues(user).SetToCurrentTime()
 }
 
+// ruleGroupOptions returns the options of the rule groups, by the key of the group in the rules manager.
+func (r *DefaultMultiTenantManager) ruleGroupOptions(user string, groups rulespb.RuleGroupList) map[string]rulespb.RuleGroupOptions {
+	opts := make(map[string]rulespb.RuleGroupOptions, len(groups))
+	for _, g := range groups {
+		o, err := g.GetRuleGroupOptions()
+		if err != nil {
+			level.Warn(r.logger).Log("msg", "unable to read rule group options", "user", user, "namespace", g.Namespace, "group", g.Name, "err", err)
+			continue
+		}
+		opts[promRules.GroupKey(r.mapper.ruleFilename(user, g.Namespace), g.Name)] = o
+	}
+	return opts
+}
+
 // getOrCreateManager retrieves the user manager. If it doesn't exist, it will create and start it first.
 func (r *DefaultMultiTenantManager) getOrCreateManager(ctx context.Context, user string) (RulesManager, bool, error) {
 	// Check if it already exists. Since rules are synched frequently, we expect to already exist
@@ -272,6 +304,17 @@ func (r *DefaultMultiTenantManager) GetRules(userID string) []*promRules.Group {
 	return nil
 }
 
+func (r *DefaultMultiTenantManager) GetRuleEvaluationHistory(userID string, g *promRules.Group) [][]*RuleEvaluationDesc {
+	r.userManagerMtx.RLock()
+	mngr := r.userManagers[userID]
+	r.userManagerMtx.RUnlock()
+
+	if getter, ok := mngr.(ruleEvaluationHistoryGetter); ok {
+		return getter.RuleEvaluationHistory(g)
+	}
+	return nil
+}
+
 func (r *DefaultMultiTenantManager) Stop() {
 	r.notifiersMtx.Lock()
 	for _, n := range r.notifiers {
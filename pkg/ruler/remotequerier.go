@@ -32,6 +32,7 @@ import (
 	"github.com/weaveworks/common/user"
 	"google.golang.org/grpc"
 
+	"github.com/grafana/mimir/pkg/ruler/tenantfederation"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 	"github.com/grafana/mimir/pkg/util/version"
 )
@@ -311,7 +312,7 @@ func scalarToPromQLVector(sc *prommodel.Scalar) promql.Vector {
 // In case the expression to evaluate corresponds to a federated rule, the ExtractTenantIDs function will take care
 // of normalizing and concatenating source tenants by separating them with a '|' character.
 func WithOrgIDMiddleware(ctx context.Context, req *httpgrpc.HTTPRequest) error {
-	orgID, err := ExtractTenantIDs(ctx)
+	orgID, err := tenantfederation.ExtractTenantIDs(ctx)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,375 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/mimir/pkg/ruler/tenantfederation"
+)
+
+type missedIterationsBackfillMetrics struct {
+	backfilledIterations *prometheus.CounterVec
+	failedBackfills      *prometheus.CounterVec
+}
+
+func newMissedIterationsBackfillMetrics(reg prometheus.Registerer) *missedIterationsBackfillMetrics {
+	return &missedIterationsBackfillMetrics{
+		backfilledIterations: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ruler_backfilled_rule_group_iterations_total",
+			Help: "Total number of missed rule group iterations whose recording rules have been re-evaluated.",
+		}, []string{"user"}),
+		failedBackfills: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ruler_backfilled_rule_group_iterations_failed_total",
+			Help: "Total number of missed rule group iterations whose recording rules have failed to be re-evaluated.",
+		}, []string{"user"}),
+	}
+}
+
+// missedIterationsBackfiller re-evaluates the recording rules of the rule groups of a tenant at the timestamps
+// of the iterations the rules manager missed, for the rule groups which have backfilling enabled.
+//
+// Iterations are missed when an evaluation of the group takes longer than its interval, and when the group isn't
+// evaluated for a while, for example because the ruler restarted or the group moved to another ruler. The former
+// are detected from the timestamp of the previous evaluation of the group, while the latter are detected from the
+// samples written by the recording rules of the group before its first evaluation by this ruler.
+//
+// The missed iterations are re-evaluated in the background, one rule group at a time and at the tenant's backfill
+// rate, so that backfilling doesn't delay the evaluation of the rule groups.
+//
+// The backfilled samples are older than the samples already written by the group, so they can only be ingested
+// within the out-of-order time window of the tenant.
+type missedIterationsBackfiller struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	userID     string
+	queryable  storage.Queryable
+	queryFunc  rules.QueryFunc
+	appendable storage.Appendable
+	limits     RulesLimits
+	metrics    *missedIterationsBackfillMetrics
+	limiter    *rate.Limiter
+
+	options *ruleGroupOptionsStore
+
+	// Notified when a backfill is scheduled.
+	scheduled chan struct{}
+
+	mtx sync.Mutex
+	// Rule groups whose samples written before their first evaluation have been checked, by group key.
+	checked map[string]struct{}
+	// Backfills waiting to be run, by group key.
+	pending map[string]*pendingBackfill
+}
+
+// pendingBackfill is the backfill of the missed iterations of a rule group, waiting to be run.
+type pendingBackfill struct {
+	group          *rules.Group
+	recordingRules []*rules.RecordingRule
+	logger         log.Logger
+
+	// If not zero, the samples written by the recording rules of the group between minTimestamp and the
+	// first evaluation of the group by this ruler are checked for missed iterations.
+	firstEvalTimestamp time.Time
+	minTimestamp       time.Time
+
+	// Iterations missed since the first evaluation of the group by this ruler.
+	missed []time.Time
+}
+
+func newMissedIterationsBackfiller(ctx context.Context, userID string, options *ruleGroupOptionsStore, queryable storage.Queryable, queryFunc rules.QueryFunc, appendable storage.Appendable, limits RulesLimits, metrics *missedIterationsBackfillMetrics) *missedIterationsBackfiller {
+	ctx, cancel := context.WithCancel(ctx)
+	return &missedIterationsBackfiller{
+		ctx:        ctx,
+		cancel:     cancel,
+		userID:     userID,
+		queryable:  queryable,
+		queryFunc:  queryFunc,
+		appendable: appendable,
+		limits:     limits,
+		metrics:    metrics,
+		limiter:    rate.NewLimiter(rate.Inf, 1),
+		options:    options,
+		scheduled:  make(chan struct{}, 1),
+		checked:    map[string]struct{}{},
+		pending:    map[string]*pendingBackfill{},
+	}
+}
+
+// Run runs the scheduled backfills until Stop is called.
+func (b *missedIterationsBackfiller) Run() {
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-b.scheduled:
+			b.runPending()
+		}
+	}
+}
+
+// Stop stops running the backfills. The pending backfills are dropped.
+func (b *missedIterationsBackfiller) Stop() {
+	b.cancel()
+}
+
+// RetainGroups forgets the state and the pending backfills of the rule groups which aren't in groups anymore.
+func (b *missedIterationsBackfiller) RetainGroups(groups []*rules.Group) {
+	retained := make(map[string]struct{}, len(groups))
+	for _, g := range groups {
+		retained[rules.GroupKey(g.File(), g.Name())] = struct{}{}
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for key := range b.checked {
+		if _, ok := retained[key]; !ok {
+			delete(b.checked, key)
+		}
+	}
+	for key := range b.pending {
+		if _, ok := retained[key]; !ok {
+			delete(b.pending, key)
+		}
+	}
+}
+
+// ScheduleMissedIterations is called by the rules manager before each evaluation of the group, but the first one,
+// with the timestamp of the previous evaluation of the group. It schedules the backfill of the iterations of the
+// group missed since then, which is run in the background.
+func (b *missedIterationsBackfiller) ScheduleMissedIterations(g *rules.Group, lastEvalTimestamp time.Time, logger log.Logger) {
+	interval := g.Interval()
+	recordingRules := groupRecordingRules(g)
+	if interval <= 0 || len(recordingRules) == 0 || !b.options.get(g).BackfillMissedIterations {
+		return
+	}
+
+	window := b.limits.RulerMissedIterationsBackfillWindow(b.userID)
+	if oooWindow := time.Duration(b.limits.OutOfOrderTimeWindow(b.userID)); oooWindow < window {
+		window = oooWindow
+	}
+	if window <= 0 {
+		return
+	}
+
+	// The rules manager evaluates the group at the timestamps aligned with the previous evaluation.
+	evalTimestamp := lastEvalTimestamp.Add(time.Since(lastEvalTimestamp) / interval * interval)
+	minTimestamp := evalTimestamp.Add(-window)
+
+	var missed []time.Time
+	for ts := lastEvalTimestamp.Add(interval); ts.Before(evalTimestamp); ts = ts.Add(interval) {
+		if !ts.Before(minTimestamp) {
+			missed = append(missed, ts)
+		}
+	}
+
+	key := rules.GroupKey(g.File(), g.Name())
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	_, checked := b.checked[key]
+	if checked && len(missed) == 0 {
+		return
+	}
+
+	p, ok := b.pending[key]
+	if !ok {
+		p = &pendingBackfill{}
+		b.pending[key] = p
+	}
+	// The group may have been replaced by an update of the rules manager.
+	p.group, p.recordingRules, p.logger = g, recordingRules, logger
+	p.missed = append(p.missed, missed...)
+	if !checked {
+		b.checked[key] = struct{}{}
+		p.firstEvalTimestamp, p.minTimestamp = lastEvalTimestamp, minTimestamp
+	}
+
+	select {
+	case b.scheduled <- struct{}{}:
+	default:
+	}
+}
+
+// runPending runs the pending backfills, one rule group at a time.
+func (b *missedIterationsBackfiller) runPending() {
+	for b.ctx.Err() == nil {
+		p := b.nextPending()
+		if p == nil {
+			return
+		}
+		if err := b.backfill(p); err != nil {
+			level.Warn(p.logger).Log("msg", "failed to backfill missed iterations of the rule group", "err", err)
+		}
+	}
+}
+
+// nextPending removes a pending backfill and returns it, or returns nil if there's none.
+func (b *missedIterationsBackfiller) nextPending() *pendingBackfill {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for key, p := range b.pending {
+		delete(b.pending, key)
+		return p
+	}
+	return nil
+}
+
+// backfill re-evaluates the missed iterations of a rule group at the tenant's backfill rate.
+func (b *missedIterationsBackfiller) backfill(p *pendingBackfill) error {
+	g := p.group
+	missed := p.missed
+
+	if !p.firstEvalTimestamp.IsZero() {
+		before, err := b.findMissedIterations(g, p.recordingRules, p.firstEvalTimestamp, p.minTimestamp)
+		if err != nil {
+			return errors.Wrap(err, "unable to find the missed iterations of the rule group")
+		}
+		missed = append(before, missed...)
+	}
+
+	if len(missed) == 0 {
+		return nil
+	}
+
+	level.Info(p.logger).Log("msg", "backfilling missed iterations of the recording rules of the rule group", "iterations", len(missed), "first", missed[0], "last", missed[len(missed)-1])
+
+	ctx := tenantfederation.GroupContextFunc(b.ctx, g)
+	for _, ts := range missed {
+		limit := rate.Inf
+		if r := b.limits.RulerMissedIterationsBackfillRate(b.userID); r > 0 {
+			limit = rate.Limit(r)
+		}
+		b.limiter.SetLimit(limit)
+		if err := b.limiter.Wait(b.ctx); err != nil {
+			return err
+		}
+
+		if err := b.evalRecordingRules(ctx, g, p.recordingRules, ts); err != nil {
+			b.metrics.failedBackfills.WithLabelValues(b.userID).Inc()
+			return errors.Wrapf(err, "unable to backfill the iteration of the rule group at %s", ts)
+		}
+		b.metrics.backfilledIterations.WithLabelValues(b.userID).Inc()
+	}
+
+	return nil
+}
+
+// findMissedIterations returns the timestamps of the iterations of the group before lastEvalTimestamp for which
+// none of its recording rules wrote a sample, after the oldest sample written by its recording rules since minTimestamp.
+func (b *missedIterationsBackfiller) findMissedIterations(g *rules.Group, recordingRules []*rules.RecordingRule, lastEvalTimestamp, minTimestamp time.Time) ([]time.Time, error) {
+	var (
+		interval = g.Interval()
+		delay    = g.EvaluationDelay()
+		// Samples are written at the timestamp of the evaluation minus the evaluation delay.
+		lastSampleTimestamp = lastEvalTimestamp.Add(-delay)
+	)
+
+	mint, maxt := minTimestamp.Add(-delay).UnixMilli(), lastSampleTimestamp.UnixMilli()
+	q, err := b.queryable.Querier(b.ctx, mint, maxt)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	// Iterations are numbered backwards from the last evaluation of the group.
+	evaluated := map[int64]struct{}{}
+	oldest := int64(-1)
+
+	for _, r := range recordingRules {
+		matchers := []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, r.Name())}
+		for _, l := range r.Labels() {
+			matchers = append(matchers, labels.MustNewMatcher(labels.MatchEqual, l.Name, l.Value))
+		}
+
+		set := q.Select(false, nil, matchers...)
+		for set.Next() {
+			it := set.At().Iterator()
+			for it.Next() {
+				t, _ := it.At()
+				if t < mint || t > maxt {
+					continue
+				}
+				n := int64((lastSampleTimestamp.Sub(time.UnixMilli(t)) + interval/2) / interval)
+				evaluated[n] = struct{}{}
+				if n > oldest {
+					oldest = n
+				}
+			}
+			if err := it.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if err := set.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var missed []time.Time
+	for n := oldest - 1; n > 0; n-- {
+		if _, ok := evaluated[n]; !ok {
+			missed = append(missed, lastEvalTimestamp.Add(-time.Duration(n)*interval))
+		}
+	}
+	return missed, nil
+}
+
+// evalRecordingRules evaluates the recording rules at the timestamp ts, and writes their results.
+func (b *missedIterationsBackfiller) evalRecordingRules(ctx context.Context, g *rules.Group, recordingRules []*rules.RecordingRule, ts time.Time) error {
+	delay := g.EvaluationDelay()
+
+	for _, r := range recordingRules {
+		vector, err := b.queryFunc(ctx, r.Query().String(), ts.Add(-delay))
+		if err != nil {
+			return errors.Wrapf(err, "rule %s", r.Name())
+		}
+		if limit := g.Limit(); limit > 0 && len(vector) > limit {
+			return fmt.Errorf("rule %s: exceeded limit of %d with %d series", r.Name(), limit, len(vector))
+		}
+
+		app := b.appendable.Appender(ctx)
+		for _, s := range vector {
+			lb := labels.NewBuilder(s.Metric)
+			lb.Set(labels.MetricName, r.Name())
+			for _, l := range r.Labels() {
+				lb.Set(l.Name, l.Value)
+			}
+
+			if _, err := app.Append(0, lb.Labels(), s.T, s.V); err != nil {
+				_ = app.Rollback()
+				return errors.Wrapf(err, "rule %s", r.Name())
+			}
+		}
+		if err := app.Commit(); err != nil {
+			return errors.Wrapf(err, "rule %s", r.Name())
+		}
+	}
+
+	return nil
+}
+
+func groupRecordingRules(g *rules.Group) []*rules.RecordingRule {
+	var recordingRules []*rules.RecordingRule
+	for _, r := range g.Rules() {
+		if rr, ok := r.(*rules.RecordingRule); ok {
+			recordingRules = append(recordingRules, rr)
+		}
+	}
+	return recordingRules
+}
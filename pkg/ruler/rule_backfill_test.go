@@ -0,0 +1,281 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/storage/series"
+)
+
+func TestMissedIterationsBackfiller_RuleGroupPostProcessFunc(t *testing.T) {
+	const (
+		userID   = "user"
+		interval = time.Minute
+	)
+
+	// The previous evaluation of the group happened in the middle of the current interval.
+	lastEval := time.Now().Add(-interval / 2).Truncate(time.Millisecond)
+
+	tests := map[string]struct {
+		backfillEnabled      bool
+		backfillWindow       time.Duration
+		outOfOrderTimeWindow time.Duration
+		lastEval             time.Time
+		writtenSamples       []time.Time
+		expectedBackfilled   []time.Time
+	}{
+		"backfilling disabled for the rule group": {
+			backfillEnabled:      false,
+			backfillWindow:       time.Hour,
+			outOfOrderTimeWindow: time.Hour,
+			lastEval:             lastEval.Add(-3 * interval),
+		},
+		"out-of-order ingestion disabled for the tenant": {
+			backfillEnabled:      true,
+			backfillWindow:       time.Hour,
+			outOfOrderTimeWindow: 0,
+			lastEval:             lastEval.Add(-3 * interval),
+		},
+		"no missed iterations": {
+			backfillEnabled:      true,
+			backfillWindow:       time.Hour,
+			outOfOrderTimeWindow: time.Hour,
+			lastEval:             lastEval,
+			writtenSamples:       []time.Time{lastEval.Add(-interval), lastEval},
+		},
+		"iterations missed since the previous evaluation": {
+			backfillEnabled:      true,
+			backfillWindow:       time.Hour,
+			outOfOrderTimeWindow: time.Hour,
+			lastEval:             lastEval.Add(-3 * interval),
+			expectedBackfilled:   []time.Time{lastEval.Add(-2 * interval), lastEval.Add(-interval)},
+		},
+		"iterations missed since the previous evaluation, outside of the backfill window": {
+			backfillEnabled:      true,
+			backfillWindow:       2 * interval,
+			outOfOrderTimeWindow: time.Hour,
+			lastEval:             lastEval.Add(-5 * interval),
+			expectedBackfilled:   []time.Time{lastEval.Add(-2 * interval), lastEval.Add(-interval)},
+		},
+		"iterations missed since the previous evaluation, outside of the out-of-order time window": {
+			backfillEnabled:      true,
+			backfillWindow:       time.Hour,
+			outOfOrderTimeWindow: interval,
+			lastEval:             lastEval.Add(-5 * interval),
+			expectedBackfilled:   []time.Time{lastEval.Add(-interval)},
+		},
+		"iterations missed before the first evaluation of the rule group": {
+			backfillEnabled:      true,
+			backfillWindow:       time.Hour,
+			outOfOrderTimeWindow: time.Hour,
+			lastEval:             lastEval,
+			writtenSamples:       []time.Time{lastEval.Add(-6 * interval), lastEval.Add(-5 * interval), lastEval.Add(-2 * interval), lastEval},
+			expectedBackfilled:   []time.Time{lastEval.Add(-4 * interval), lastEval.Add(-3 * interval), lastEval.Add(-interval)},
+		},
+		"iterations missed before the first evaluation of the rule group, outside of the backfill window": {
+			backfillEnabled:      true,
+			backfillWindow:       3*interval + interval/2,
+			outOfOrderTimeWindow: time.Hour,
+			lastEval:             lastEval,
+			writtenSamples:       []time.Time{lastEval.Add(-6 * interval), lastEval.Add(-3 * interval), lastEval},
+			expectedBackfilled:   []time.Time{lastEval.Add(-2 * interval), lastEval.Add(-interval)},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				mtx     sync.Mutex
+				queried []time.Time
+			)
+			queryFunc := func(_ context.Context, qs string, ts time.Time) (promql.Vector, error) {
+				// Alerting rules must not be backfilled.
+				assert.Equal(t, `sum by(job) (up)`, qs)
+
+				mtx.Lock()
+				queried = append(queried, ts)
+				mtx.Unlock()
+				return promql.Vector{{Point: promql.Point{T: ts.UnixMilli(), V: 1}, Metric: labels.FromStrings("job", "test")}}, nil
+			}
+
+			written := make([]model.SamplePair, 0, len(testData.writtenSamples))
+			for _, ts := range testData.writtenSamples {
+				written = append(written, model.SamplePair{Timestamp: model.Time(ts.UnixMilli()), Value: 1})
+			}
+			queryable := seriesQueryable{
+				series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "job:up:sum", "job", "test", "source", "recording"), written),
+				series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "job:up:sum", "job", "test", "source", "other"), []model.SamplePair{
+					{Timestamp: model.Time(testData.lastEval.Add(-3 * interval).UnixMilli()), Value: 1},
+				}),
+			}
+
+			appendable := &recordingAppendable{}
+			limits := ruleLimits{backfillWindow: testData.backfillWindow, outOfOrderTimeWindow: testData.outOfOrderTimeWindow}
+			options := &ruleGroupOptionsStore{}
+			options.set(map[string]rulespb.RuleGroupOptions{
+				rules.GroupKey("file", "group"): {BackfillMissedIterations: testData.backfillEnabled},
+			})
+			b := newMissedIterationsBackfiller(context.Background(), userID, options, queryable, queryFunc, appendable, limits, newMissedIterationsBackfillMetrics(prometheus.NewPedanticRegistry()))
+
+			g := rules.NewGroup(rules.GroupOptions{
+				Name:     "group",
+				File:     "file",
+				Interval: interval,
+				Rules: []rules.Rule{
+					rules.NewRecordingRule("job:up:sum", mustParseExpr(t, `sum by(job) (up)`), labels.FromStrings("source", "recording")),
+					rules.NewAlertingRule("HighErrorRate", mustParseExpr(t, `rate(errors_total[5m]) > 1`), 0, nil, nil, nil, "", false, log.NewNopLogger()),
+				},
+				Opts: &rules.ManagerOptions{Logger: log.NewNopLogger()},
+			})
+
+			// The missed iterations are backfilled in the background.
+			b.ScheduleMissedIterations(g, testData.lastEval, log.NewNopLogger())
+			b.runPending()
+
+			sort.Slice(queried, func(i, j int) bool { return queried[i].Before(queried[j]) })
+			assert.Equal(t, testData.expectedBackfilled, queried)
+			assert.Equal(t, float64(len(testData.expectedBackfilled)), prom_testutil.ToFloat64(b.metrics.backfilledIterations.WithLabelValues(userID)))
+			if len(testData.expectedBackfilled) > 0 {
+				assert.Equal(t, []string{"job:up:sum"}, appendable.metricNames())
+			}
+
+			// The samples written before the first evaluation of the group are only checked once.
+			queried = nil
+			b.ScheduleMissedIterations(g, lastEval, log.NewNopLogger())
+			b.runPending()
+			assert.Empty(t, queried)
+		})
+	}
+}
+
+func TestMissedIterationsBackfiller_RetainGroups(t *testing.T) {
+	options := &ruleGroupOptionsStore{}
+	options.set(map[string]rulespb.RuleGroupOptions{
+		rules.GroupKey("file", "group-1"): {BackfillMissedIterations: true},
+		rules.GroupKey("file", "group-2"): {BackfillMissedIterations: true},
+	})
+	limits := ruleLimits{backfillWindow: time.Hour, outOfOrderTimeWindow: time.Hour}
+	b := newMissedIterationsBackfiller(context.Background(), "user", options, seriesQueryable{}, nil, &recordingAppendable{}, limits, newMissedIterationsBackfillMetrics(nil))
+
+	newGroup := func(name string) *rules.Group {
+		return rules.NewGroup(rules.GroupOptions{
+			Name:     name,
+			File:     "file",
+			Interval: time.Minute,
+			Rules:    []rules.Rule{rules.NewRecordingRule("job:up:sum", mustParseExpr(t, `sum by(job) (up)`), nil)},
+			Opts:     &rules.ManagerOptions{Logger: log.NewNopLogger()},
+		})
+	}
+	g1, g2 := newGroup("group-1"), newGroup("group-2")
+
+	b.ScheduleMissedIterations(g1, time.Now(), log.NewNopLogger())
+	b.ScheduleMissedIterations(g2, time.Now(), log.NewNopLogger())
+	assert.Len(t, b.checked, 2)
+	assert.Len(t, b.pending, 2)
+
+	// The state of the groups is kept by group key, so it's retained when the groups are replaced.
+	b.RetainGroups([]*rules.Group{newGroup("group-2")})
+	assert.Equal(t, map[string]struct{}{rules.GroupKey("file", "group-2"): {}}, b.checked)
+	assert.Len(t, b.pending, 1)
+	assert.Contains(t, b.pending, rules.GroupKey("file", "group-2"))
+}
+
+func TestMissedIterationsBackfiller_ShouldBackfillAtTheTenantRate(t *testing.T) {
+	const interval = time.Minute
+
+	var (
+		mtx     sync.Mutex
+		queried []time.Time
+	)
+	queryFunc := func(_ context.Context, _ string, ts time.Time) (promql.Vector, error) {
+		mtx.Lock()
+		queried = append(queried, ts)
+		mtx.Unlock()
+		return nil, nil
+	}
+
+	limits := ruleLimits{backfillWindow: time.Hour, backfillRate: 20, outOfOrderTimeWindow: time.Hour}
+	options := &ruleGroupOptionsStore{}
+	options.set(map[string]rulespb.RuleGroupOptions{
+		rules.GroupKey("file", "group"): {BackfillMissedIterations: true},
+	})
+	b := newMissedIterationsBackfiller(context.Background(), "user", options, seriesQueryable{}, queryFunc, &recordingAppendable{}, limits, newMissedIterationsBackfillMetrics(prometheus.NewPedanticRegistry()))
+	go b.Run()
+	defer b.Stop()
+
+	g := rules.NewGroup(rules.GroupOptions{
+		Name:     "group",
+		File:     "file",
+		Interval: interval,
+		Rules: []rules.Rule{
+			rules.NewRecordingRule("job:up:sum", mustParseExpr(t, `sum by(job) (up)`), nil),
+		},
+		Opts: &rules.ManagerOptions{Logger: log.NewNopLogger()},
+	})
+
+	// Scheduling the backfill doesn't wait for the missed iterations to be re-evaluated.
+	start := time.Now()
+	b.ScheduleMissedIterations(g, time.Now().Add(-11*interval), log.NewNopLogger())
+
+	test.Poll(t, 5*time.Second, 10, func() interface{} {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(queried)
+	})
+
+	// The first iteration is backfilled immediately, while the others are limited to 20 per second.
+	assert.GreaterOrEqual(t, time.Since(start), 9*time.Second/20)
+}
+
+// seriesQueryable is a storage.Queryable returning the series matching the selectors.
+type seriesQueryable []storage.Series
+
+func (q seriesQueryable) Querier(_ context.Context, _, _ int64) (storage.Querier, error) {
+	return q, nil
+}
+
+func (q seriesQueryable) Select(_ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	var selected []storage.Series
+	for _, s := range q {
+		matches := true
+		for _, m := range matchers {
+			if !m.Matches(s.Labels().Get(m.Name)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			selected = append(selected, s)
+		}
+	}
+	return series.NewConcreteSeriesSet(selected)
+}
+
+func (q seriesQueryable) LabelValues(_ string, _ ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
+
+func (q seriesQueryable) LabelNames(_ ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return nil, nil, nil
+}
+
+func (q seriesQueryable) Close() error {
+	return nil
+}
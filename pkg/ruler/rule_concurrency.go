@@ -0,0 +1,361 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"go.uber.org/atomic"
+	"golang.org/x/sync/semaphore"
+)
+
+const groupEvaluationContextKey contextKey = 2
+
+var errConcurrencyDisabled = errors.New("concurrent evaluation of independent rules disabled for the tenant")
+
+// Names of the series written by alerting rules.
+var alertingRuleOutputs = []string{"ALERTS", "ALERTS_FOR_STATE"}
+
+// ruleConcurrencyController evaluates the queries of the independent rules of a rule group concurrently,
+// up to a per-tenant and a global concurrency limit.
+//
+// The Prometheus rules manager evaluates the rules of a group one after the other. At the beginning of
+// each group evaluation, the controller starts running the queries of the rules which don't depend on the
+// output of the rules before them in the group, so that their results are ready by the time the rules
+// manager gets to them. The queries of the other rules are run when the rules manager evaluates them,
+// after the rules they depend on have been evaluated. The queries which aren't the query of a rule, like
+// the queries run by the templates of alerting rules, are always run by the rules manager. The queries run
+// concurrently are cancelled at the deadline of the query of the first rule of the group, if any.
+//
+// The rule of each query is read from the context, so the query function must be wrapped by trackRuleQueries.
+type ruleConcurrencyController struct {
+	limits RulesLimits
+
+	globalSlots *semaphore.Weighted
+
+	tenantSlotsMtx sync.Mutex
+	tenantSlots    map[string]*tenantConcurrencySlots
+
+	slotsInUse           prometheus.Gauge
+	concurrentQueries    *prometheus.CounterVec
+	slowGroupEvaluations *prometheus.CounterVec
+}
+
+type tenantConcurrencySlots struct {
+	slots *semaphore.Weighted
+	limit int64
+}
+
+func newRuleConcurrencyController(maxGlobalConcurrency int64, limits RulesLimits, reg prometheus.Registerer) *ruleConcurrencyController {
+	c := &ruleConcurrencyController{
+		limits:      limits,
+		tenantSlots: map[string]*tenantConcurrencySlots{},
+		slotsInUse: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_ruler_independent_rule_evaluation_concurrency_slots_in_use",
+			Help: "Current number of concurrency slots in use by the evaluation of independent rules.",
+		}),
+		concurrentQueries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ruler_independent_rule_evaluations_total",
+			Help: "Total number of rule queries run concurrently with the other rules of their group.",
+		}, []string{"user"}),
+		slowGroupEvaluations: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ruler_rule_group_slow_evaluations_total",
+			Help: "Total number of rule group evaluations which took longer than the group interval.",
+		}, []string{"user"}),
+	}
+	if maxGlobalConcurrency > 0 {
+		c.globalSlots = semaphore.NewWeighted(maxGlobalConcurrency)
+	}
+	return c
+}
+
+// groupEvaluation holds the state of the evaluation of a rule group. It's only accessed by the goroutine
+// evaluating the rule group.
+type groupEvaluation struct {
+	// The context of the evaluation of the group, which the queries run concurrently are derived from.
+	ctx    context.Context
+	userID string
+	group  *rules.Group
+
+	// The query expression of each rule of the group, and whether it can be run concurrently.
+	exprs       []string
+	independent []bool
+
+	iteration      *groupIteration
+	lastEvaluation time.Time
+}
+
+// groupIteration is a single evaluation of a rule group.
+type groupIteration struct {
+	number int64
+	rules  []*ruleQuery
+	cancel context.CancelFunc
+}
+
+// ruleQuery is the query of an independent rule, which is run either concurrently by the controller or
+// by the rules manager, whichever claims it first.
+type ruleQuery struct {
+	claimed atomic.Bool
+	done    chan struct{}
+	result  promql.Vector
+	err     error
+}
+
+// GroupEvaluationContextFunc returns a rules.ContextWrapFunc injecting the state used to evaluate the
+// independent rules of the group in the context, on top of the context prepared by next.
+func (c *ruleConcurrencyController) GroupEvaluationContextFunc(userID string, next rules.ContextWrapFunc) rules.ContextWrapFunc {
+	return func(ctx context.Context, g *rules.Group) context.Context {
+		if next != nil {
+			ctx = next(ctx, g)
+		}
+
+		exprs, independent := independentRules(g.Rules())
+		e := &groupEvaluation{
+			ctx:         ctx,
+			userID:      userID,
+			group:       g,
+			exprs:       exprs,
+			independent: independent,
+		}
+		return context.WithValue(ctx, groupEvaluationContextKey, e)
+	}
+}
+
+// WrapQueryFunc returns a rules.QueryFunc returning the results of the queries run concurrently, and running
+// the other queries through qf.
+func (c *ruleConcurrencyController) WrapQueryFunc(qf rules.QueryFunc) rules.QueryFunc {
+	return func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		e, ok := ctx.Value(groupEvaluationContextKey).(*groupEvaluation)
+		if !ok {
+			return qf(ctx, qs, t)
+		}
+
+		// The queries run by the templates of alerting rules are never run concurrently.
+		r, ok := evaluatedRuleFromContext(ctx)
+		if !ok || !r.ruleQuery || r.index >= len(e.exprs) {
+			return qf(ctx, qs, t)
+		}
+
+		it := e.iteration
+		if it == nil || it.number != r.iteration {
+			// This is the first query of a new evaluation of the group.
+			c.observeGroupEvaluation(e)
+			if it != nil {
+				it.cancel()
+			}
+			it = c.startIteration(ctx, e, qf, t, r.iteration)
+			e.iteration = it
+		}
+
+		if r.index == len(e.exprs)-1 {
+			// Stop running queries concurrently once the last rule of the group has been evaluated.
+			defer it.cancel()
+		}
+
+		rq := it.rules[r.index]
+		if rq == nil || rq.claimed.CAS(false, true) {
+			return qf(ctx, qs, t)
+		}
+
+		<-rq.done
+		return rq.result, rq.err
+	}
+}
+
+// observeGroupEvaluation tracks whether the previous evaluation of the group was slow.
+func (c *ruleConcurrencyController) observeGroupEvaluation(e *groupEvaluation) {
+	interval := e.group.Interval()
+	if interval <= 0 {
+		return
+	}
+
+	if last := e.group.GetLastEvaluation(); !last.IsZero() && !last.Equal(e.lastEvaluation) {
+		e.lastEvaluation = last
+		if e.group.GetEvaluationTime() > interval {
+			c.slowGroupEvaluations.WithLabelValues(e.userID).Inc()
+		}
+	}
+}
+
+// startIteration starts running the queries of the independent rules of a new evaluation of the group,
+// given the context of the query of the first rule.
+func (c *ruleConcurrencyController) startIteration(queryCtx context.Context, e *groupEvaluation, qf rules.QueryFunc, t time.Time, number int64) *groupIteration {
+	it := &groupIteration{
+		number: number,
+		rules:  make([]*ruleQuery, len(e.exprs)),
+	}
+
+	// The query of the first rule is done before the queries run concurrently, so they're only derived
+	// from its deadline.
+	var ctx context.Context
+	if deadline, ok := queryCtx.Deadline(); ok {
+		ctx, it.cancel = context.WithDeadline(e.ctx, deadline)
+	} else {
+		ctx, it.cancel = context.WithCancel(e.ctx)
+	}
+
+	if c.globalSlots == nil || c.limits.RulerMaxIndependentRuleEvaluationConcurrencyPerTenant(e.userID) <= 0 {
+		return it
+	}
+
+	count := 0
+	for i, independent := range e.independent {
+		if independent {
+			it.rules[i] = &ruleQuery{done: make(chan struct{})}
+			count++
+		}
+	}
+
+	// Running a single query concurrently is pointless.
+	if count < 2 {
+		it.rules = make([]*ruleQuery, len(e.exprs))
+		return it
+	}
+
+	go c.runQueries(ctx, e.userID, e.exprs, it.rules, qf, t)
+	return it
+}
+
+// runQueries runs the queries of the independent rules not claimed by the rules manager yet, as soon
+// as concurrency slots are available.
+func (c *ruleConcurrencyController) runQueries(ctx context.Context, userID string, exprs []string, queries []*ruleQuery, qf rules.QueryFunc, t time.Time) {
+	for i, rq := range queries {
+		if rq == nil || rq.claimed.Load() {
+			continue
+		}
+
+		tenantSlots, err := c.acquireSlot(ctx, userID)
+		if err != nil {
+			return
+		}
+		if !rq.claimed.CAS(false, true) {
+			c.releaseSlot(tenantSlots)
+			continue
+		}
+
+		c.concurrentQueries.WithLabelValues(userID).Inc()
+		go func(rq *ruleQuery, qs string) {
+			defer close(rq.done)
+			defer c.releaseSlot(tenantSlots)
+
+			rq.result, rq.err = qf(ctx, qs, t)
+		}(rq, exprs[i])
+	}
+}
+
+func (c *ruleConcurrencyController) acquireSlot(ctx context.Context, userID string) (*semaphore.Weighted, error) {
+	tenantSlots := c.getTenantSlots(userID)
+	if tenantSlots == nil {
+		return nil, errConcurrencyDisabled
+	}
+	if err := tenantSlots.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	if err := c.globalSlots.Acquire(ctx, 1); err != nil {
+		tenantSlots.Release(1)
+		return nil, err
+	}
+
+	c.slotsInUse.Inc()
+	return tenantSlots, nil
+}
+
+func (c *ruleConcurrencyController) releaseSlot(tenantSlots *semaphore.Weighted) {
+	c.slotsInUse.Dec()
+	c.globalSlots.Release(1)
+	tenantSlots.Release(1)
+}
+
+// getTenantSlots returns the semaphore limiting the concurrency of the tenant, replacing it if the
+// tenant's limit has changed, or nil if the concurrent evaluation is disabled for the tenant.
+func (c *ruleConcurrencyController) getTenantSlots(userID string) *semaphore.Weighted {
+	limit := c.limits.RulerMaxIndependentRuleEvaluationConcurrencyPerTenant(userID)
+
+	c.tenantSlotsMtx.Lock()
+	defer c.tenantSlotsMtx.Unlock()
+
+	if limit <= 0 {
+		delete(c.tenantSlots, userID)
+		return nil
+	}
+
+	s, ok := c.tenantSlots[userID]
+	if !ok || s.limit != limit {
+		s = &tenantConcurrencySlots{slots: semaphore.NewWeighted(limit), limit: limit}
+		c.tenantSlots[userID] = s
+	}
+	return s.slots
+}
+
+// RemoveTenant forgets the concurrency slots of the tenant, once its rules manager is stopped. The slots in use
+// are released to the semaphore they've been acquired from.
+func (c *ruleConcurrencyController) RemoveTenant(userID string) {
+	c.tenantSlotsMtx.Lock()
+	defer c.tenantSlotsMtx.Unlock()
+
+	delete(c.tenantSlots, userID)
+}
+
+// independentRules returns the query expression of each rule, and whether each rule is independent, that is
+// whether its query doesn't select any series written by the rules before it in the group.
+func independentRules(rs []rules.Rule) ([]string, []bool) {
+	var (
+		exprs       = make([]string, len(rs))
+		independent = make([]bool, len(rs))
+		outputs     []string
+	)
+
+	for i, r := range rs {
+		exprs[i] = r.Query().String()
+		independent[i] = !selectsAny(r.Query(), outputs)
+
+		switch r.(type) {
+		case *rules.RecordingRule:
+			outputs = append(outputs, r.Name())
+		case *rules.AlertingRule:
+			outputs = append(outputs, alertingRuleOutputs...)
+		}
+	}
+
+	return exprs, independent
+}
+
+// selectsAny returns whether any selector of the expression may select series with one of the metric names.
+func selectsAny(expr parser.Expr, names []string) bool {
+	if len(names) == 0 {
+		return false
+	}
+
+	found := false
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if vs, ok := node.(*parser.VectorSelector); ok && !found {
+			for _, name := range names {
+				if selectsMetricName(vs.LabelMatchers, name) {
+					found = true
+					break
+				}
+			}
+		}
+		return nil
+	})
+	return found
+}
+
+// selectsMetricName returns whether the matchers may select series with the metric name.
+func selectsMetricName(matchers []*labels.Matcher, name string) bool {
+	for _, m := range matchers {
+		if m.Name == labels.MetricName && !m.Matches(name) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,342 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/ruler/tenantfederation"
+)
+
+func TestIndependentRules(t *testing.T) {
+	recording := func(name, expr string) rules.Rule {
+		return rules.NewRecordingRule(name, mustParseExpr(t, expr), nil)
+	}
+	alerting := func(name, expr string) rules.Rule {
+		return rules.NewAlertingRule(name, mustParseExpr(t, expr), 0, nil, nil, nil, "", false, log.NewNopLogger())
+	}
+
+	tests := map[string]struct {
+		rules               []rules.Rule
+		expectedIndependent []bool
+	}{
+		"no rules": {
+			rules:               nil,
+			expectedIndependent: []bool{},
+		},
+		"independent rules": {
+			rules: []rules.Rule{
+				recording("job:up:sum", `sum by(job) (up)`),
+				recording("job:requests:rate5m", `sum by(job) (rate(requests_total[5m]))`),
+				alerting("HighErrorRate", `rate(errors_total[5m]) > 1`),
+			},
+			expectedIndependent: []bool{true, true, true},
+		},
+		"rule using the output of a previous recording rule": {
+			rules: []rules.Rule{
+				recording("job:requests:rate5m", `sum by(job) (rate(requests_total[5m]))`),
+				recording("job:requests:rate5m:max", `max(job:requests:rate5m)`),
+				recording("job:up:sum", `sum by(job) (up)`),
+			},
+			expectedIndependent: []bool{true, false, true},
+		},
+		"rule using the output of a following recording rule": {
+			rules: []rules.Rule{
+				recording("job:requests:rate5m:max", `max(job:requests:rate5m)`),
+				recording("job:requests:rate5m", `sum by(job) (rate(requests_total[5m]))`),
+			},
+			expectedIndependent: []bool{true, true},
+		},
+		"rule using the output of a previous alerting rule": {
+			rules: []rules.Rule{
+				alerting("HighErrorRate", `rate(errors_total[5m]) > 1`),
+				recording("alerts:count", `count(ALERTS{alertstate="firing"})`),
+			},
+			expectedIndependent: []bool{true, false},
+		},
+		"rule with a regular expression matching the output of a previous rule": {
+			rules: []rules.Rule{
+				recording("job:requests:rate5m", `sum by(job) (rate(requests_total[5m]))`),
+				recording("job:all:count", `count({__name__=~"job:.+"})`),
+				recording("other:all:count", `count({__name__=~"other_.+"})`),
+			},
+			expectedIndependent: []bool{true, false, true},
+		},
+		"rule without metric name matcher": {
+			rules: []rules.Rule{
+				recording("job:requests:rate5m", `sum by(job) (rate(requests_total[5m]))`),
+				recording("job:count", `count({job="test"})`),
+			},
+			expectedIndependent: []bool{true, false},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			exprs, independent := independentRules(testData.rules)
+			assert.Equal(t, testData.expectedIndependent, independent)
+			require.Len(t, exprs, len(testData.rules))
+			for i, r := range testData.rules {
+				assert.Equal(t, r.Query().String(), exprs[i])
+			}
+		})
+	}
+}
+
+func TestRuleConcurrencyController(t *testing.T) {
+	const userID = "user"
+
+	groupRules := []rules.Rule{
+		rules.NewRecordingRule("job:a:sum", mustParseExpr(t, `sum by(job) (a)`), nil),
+		rules.NewRecordingRule("job:b:sum", mustParseExpr(t, `sum by(job) (b)`), nil),
+		rules.NewRecordingRule("job:c:sum", mustParseExpr(t, `sum by(job) (c)`), nil),
+		rules.NewRecordingRule("job:d:sum", mustParseExpr(t, `sum by(job) (d)`), nil),
+		rules.NewRecordingRule("job:a:max", mustParseExpr(t, `max(job:a:sum)`), nil),
+	}
+
+	tests := map[string]struct {
+		maxGlobalConcurrency int64
+		maxTenantConcurrency int64
+		expectedMaxInflight  int
+	}{
+		"concurrent evaluation disabled": {
+			maxGlobalConcurrency: 0,
+			maxTenantConcurrency: 4,
+			expectedMaxInflight:  1,
+		},
+		"concurrent evaluation disabled for the tenant": {
+			maxGlobalConcurrency: 4,
+			maxTenantConcurrency: 0,
+			expectedMaxInflight:  1,
+		},
+		"concurrent evaluation limited by the tenant limit": {
+			maxGlobalConcurrency: 10,
+			maxTenantConcurrency: 2,
+			// The rules manager evaluates the rules not claimed by the controller yet on its own.
+			expectedMaxInflight: 3,
+		},
+		"concurrent evaluation limited by the global limit": {
+			maxGlobalConcurrency: 1,
+			maxTenantConcurrency: 10,
+			expectedMaxInflight:  2,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			c := newRuleConcurrencyController(testData.maxGlobalConcurrency, ruleLimits{maxConcurrency: testData.maxTenantConcurrency}, prometheus.NewPedanticRegistry())
+
+			var (
+				mtx         sync.Mutex
+				inflight    int
+				maxInflight int
+				appendable  = &recordingAppendable{}
+				queried     []string
+			)
+			queryFunc := func(ctx context.Context, qs string, ts time.Time) (promql.Vector, error) {
+				mtx.Lock()
+				inflight++
+				if inflight > maxInflight {
+					maxInflight = inflight
+				}
+				queried = append(queried, qs)
+				if qs == `max(job:a:sum)` {
+					// Dependent rules must be evaluated once the rules they depend on have been evaluated.
+					assert.Contains(t, appendable.metricNames(), "job:a:sum")
+				}
+				mtx.Unlock()
+
+				time.Sleep(50 * time.Millisecond)
+
+				mtx.Lock()
+				inflight--
+				mtx.Unlock()
+				return promql.Vector{{Point: promql.Point{T: ts.UnixMilli(), V: 1}, Metric: labels.FromStrings("job", "test")}}, nil
+			}
+
+			opts := &rules.ManagerOptions{
+				QueryFunc:  trackRuleQueries(c.WrapQueryFunc(queryFunc)),
+				Appendable: appendable,
+				Context:    context.Background(),
+				Logger:     log.NewNopLogger(),
+			}
+			g := rules.NewGroup(rules.GroupOptions{
+				Name:     "group",
+				File:     "file",
+				Interval: time.Minute,
+				Rules:    groupRules,
+				Opts:     opts,
+			})
+
+			ctx := trackRuleEvaluationsContextFunc(c.GroupEvaluationContextFunc(userID, tenantfederation.GroupContextFunc))(context.Background(), g)
+			ts := time.Now()
+			g.Eval(ctx, ts)
+
+			assert.Equal(t, testData.expectedMaxInflight, maxInflight)
+			assert.Len(t, queried, len(groupRules))
+			for _, r := range groupRules {
+				assert.Equal(t, rules.HealthGood, r.Health(), r.Name())
+				assert.Contains(t, appendable.metricNames(), r.Name())
+			}
+
+			// The rules are evaluated again at the next evaluation of the group.
+			queried = nil
+			g.Eval(ctx, ts.Add(time.Minute))
+			assert.Len(t, queried, len(groupRules))
+			assert.Equal(t, 0.0, prom_testutil.ToFloat64(c.slotsInUse))
+		})
+	}
+}
+
+func TestRuleConcurrencyController_TemplateQueries(t *testing.T) {
+	const userID = "user"
+
+	// The query run by the template of the alerting rule is the same as the query of the following rule.
+	groupRules := []rules.Rule{
+		rules.NewAlertingRule("HighErrorRate", mustParseExpr(t, `sum by(job) (errors)`), 0, nil,
+			labels.FromStrings("summary", `{{ with query "sum by(job) (requests)" }}{{ . | first | value }}{{ end }}`), nil, "", false, log.NewNopLogger()),
+		rules.NewRecordingRule("job:requests:sum", mustParseExpr(t, `sum by(job) (requests)`), nil),
+		rules.NewRecordingRule("job:up:sum", mustParseExpr(t, `sum by(job) (up)`), nil),
+	}
+
+	c := newRuleConcurrencyController(4, ruleLimits{maxConcurrency: 4}, prometheus.NewPedanticRegistry())
+
+	var (
+		mtx     sync.Mutex
+		queried = map[string]int{}
+	)
+	queryFunc := func(ctx context.Context, qs string, ts time.Time) (promql.Vector, error) {
+		mtx.Lock()
+		queried[qs]++
+		mtx.Unlock()
+		return promql.Vector{{Point: promql.Point{T: ts.UnixMilli(), V: 1}, Metric: labels.FromStrings("job", "test")}}, nil
+	}
+
+	g := rules.NewGroup(rules.GroupOptions{
+		Name:     "group",
+		File:     "file",
+		Interval: time.Minute,
+		Rules:    groupRules,
+		Opts: &rules.ManagerOptions{
+			QueryFunc:  trackRuleQueries(c.WrapQueryFunc(queryFunc)),
+			NotifyFunc: func(context.Context, string, ...*rules.Alert) {},
+			Appendable: &recordingAppendable{},
+			Context:    context.Background(),
+			Logger:     log.NewNopLogger(),
+		},
+	})
+
+	ctx := trackRuleEvaluationsContextFunc(c.GroupEvaluationContextFunc(userID, nil))(context.Background(), g)
+	g.Eval(ctx, time.Now())
+
+	for _, r := range groupRules {
+		assert.Equal(t, rules.HealthGood, r.Health(), r.Name())
+	}
+	// The query of each rule is run once, and the template query is run on its own.
+	assert.Equal(t, map[string]int{
+		`sum by(job) (errors)`:   1,
+		`sum by(job) (requests)`: 2,
+		`sum by(job) (up)`:       1,
+	}, queried)
+}
+
+func TestRuleConcurrencyController_TenantSlots(t *testing.T) {
+	limits := &ruleLimits{maxConcurrency: 2}
+	c := newRuleConcurrencyController(4, limits, prometheus.NewPedanticRegistry())
+
+	slots, err := c.acquireSlot(context.Background(), "user-1")
+	require.NoError(t, err)
+	c.releaseSlot(slots)
+	assert.Len(t, c.tenantSlots, 1)
+
+	// The slots of a tenant are forgotten once the concurrent evaluation is disabled for the tenant,
+	// rather than blocking the queries.
+	limits.maxConcurrency = 0
+	_, err = c.acquireSlot(context.Background(), "user-1")
+	assert.ErrorIs(t, err, errConcurrencyDisabled)
+	assert.Empty(t, c.tenantSlots)
+
+	limits.maxConcurrency = 2
+	slots, err = c.acquireSlot(context.Background(), "user-1")
+	require.NoError(t, err)
+	c.releaseSlot(slots)
+
+	// The slots of a tenant are forgotten once its rules manager is stopped.
+	c.RemoveTenant("user-1")
+	assert.Empty(t, c.tenantSlots)
+}
+
+func mustParseExpr(t *testing.T, expr string) parser.Expr {
+	t.Helper()
+
+	parsed, err := parser.ParseExpr(expr)
+	require.NoError(t, err)
+	return parsed
+}
+
+// recordingAppendable records the metric names of the series appended to it.
+type recordingAppendable struct {
+	mtx   sync.Mutex
+	names map[string]struct{}
+}
+
+func (a *recordingAppendable) Appender(_ context.Context) storage.Appender {
+	return &recordingAppender{parent: a}
+}
+
+func (a *recordingAppendable) metricNames() []string {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	names := make([]string, 0, len(a.names))
+	for name := range a.names {
+		names = append(names, name)
+	}
+	return names
+}
+
+type recordingAppender struct {
+	parent *recordingAppendable
+	names  []string
+}
+
+func (a *recordingAppender) Append(_ storage.SeriesRef, l labels.Labels, _ int64, _ float64) (storage.SeriesRef, error) {
+	a.names = append(a.names, l.Get(labels.MetricName))
+	return 0, nil
+}
+
+func (a *recordingAppender) AppendExemplar(_ storage.SeriesRef, _ labels.Labels, _ exemplar.Exemplar) (storage.SeriesRef, error) {
+	return 0, nil
+}
+
+func (a *recordingAppender) Commit() error {
+	a.parent.mtx.Lock()
+	defer a.parent.mtx.Unlock()
+
+	if a.parent.names == nil {
+		a.parent.names = map[string]struct{}{}
+	}
+	for _, name := range a.names {
+		a.parent.names[name] = struct{}{}
+	}
+	return nil
+}
+
+func (a *recordingAppender) Rollback() error {
+	return nil
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+)
+
+const (
+	ruleEvaluationTrackerContextKey contextKey = 4
+	evaluatedRuleContextKey         contextKey = 5
+)
+
+// evaluatedRule identifies the rule of a group whose evaluation runs a query.
+type evaluatedRule struct {
+	// The index of the rule in the group.
+	index int
+	// The number of the evaluation of the group, starting from 1.
+	iteration int64
+	// Whether the query is the query of the rule, rather than a query run by the templates of an alerting rule.
+	ruleQuery bool
+}
+
+// ruleEvaluationTracker identifies the rule whose evaluation runs each query of a rule group.
+//
+// The Prometheus rules manager passes the same query function to all the rules of a group, so the rule is
+// identified by the query it runs: the rules manager evaluates the rules of a group one after the other, and
+// the evaluation of each rule starts by running the query of the rule, at the evaluation timestamp of the
+// group. The evaluation timestamp tells the evaluations of the group apart, and the query expression tells
+// which rule is evaluated. The rules manager starts a tracing span for the evaluation of each rule, so the
+// following queries run in the same context are the queries run by the templates of an alerting rule, even
+// when they're the same as the query of another rule of the group.
+//
+// It's only accessed by the goroutine evaluating the rule group.
+type ruleEvaluationTracker struct {
+	// The query expression of each rule of the group.
+	exprs []string
+
+	// The context and the timestamp of the last query of a rule.
+	lastCtx context.Context
+	lastTs  time.Time
+
+	last evaluatedRule
+	// The index of the next rule of the group to be evaluated.
+	next int
+}
+
+func newRuleEvaluationTracker(g *rules.Group) *ruleEvaluationTracker {
+	exprs := make([]string, 0, len(g.Rules()))
+	for _, r := range g.Rules() {
+		exprs = append(exprs, r.Query().String())
+	}
+	return &ruleEvaluationTracker{exprs: exprs}
+}
+
+// observe returns the rule whose evaluation runs the query. The index of the rule is the number of
+// rules of the group if it's unknown.
+func (t *ruleEvaluationTracker) observe(ctx context.Context, qs string, ts time.Time) evaluatedRule {
+	if ctx == t.lastCtx {
+		r := t.last
+		r.ruleQuery = false
+		return r
+	}
+	t.lastCtx = ctx
+
+	if t.last.iteration == 0 || !ts.Equal(t.lastTs) {
+		// This is the first rule of a new evaluation of the group.
+		t.lastTs = ts
+		t.last.iteration++
+		t.next = 0
+	}
+
+	i := t.next
+	for i < len(t.exprs) && t.exprs[i] != qs {
+		i++
+	}
+	if i < len(t.exprs) {
+		t.next = i + 1
+	}
+	t.last = evaluatedRule{index: i, iteration: t.last.iteration, ruleQuery: i < len(t.exprs)}
+	return t.last
+}
+
+// trackRuleEvaluationsContextFunc returns a rules.ContextWrapFunc injecting a ruleEvaluationTracker in the
+// context, on top of the context prepared by next.
+func trackRuleEvaluationsContextFunc(next rules.ContextWrapFunc) rules.ContextWrapFunc {
+	return func(ctx context.Context, g *rules.Group) context.Context {
+		if next != nil {
+			ctx = next(ctx, g)
+		}
+		return context.WithValue(ctx, ruleEvaluationTrackerContextKey, newRuleEvaluationTracker(g))
+	}
+}
+
+// trackRuleQueries returns a rules.QueryFunc setting the rule whose evaluation runs the query in the context
+// passed to qf, which can be read with evaluatedRuleFromContext. It must wrap the query functions reading it.
+func trackRuleQueries(qf rules.QueryFunc) rules.QueryFunc {
+	return func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		if tracker, ok := ctx.Value(ruleEvaluationTrackerContextKey).(*ruleEvaluationTracker); ok {
+			ctx = context.WithValue(ctx, evaluatedRuleContextKey, tracker.observe(ctx, qs, t))
+		}
+		return qf(ctx, qs, t)
+	}
+}
+
+// evaluatedRuleFromContext returns the rule whose evaluation runs the query, if the query is run by the
+// evaluation of a rule group. The index of the rule is the number of rules of the group if it's unknown.
+func evaluatedRuleFromContext(ctx context.Context) (evaluatedRule, bool) {
+	r, ok := ctx.Value(evaluatedRuleContextKey).(evaluatedRule)
+	return r, ok
+}
+
+// lastEvaluatedRule returns the rule whose evaluation ran the last query of the rule group, given the
+// context of the rule group evaluation. The rules manager writes the output of a rule right after
+// running its query, so this is the rule whose output is written.
+func lastEvaluatedRule(ctx context.Context) (evaluatedRule, bool) {
+	tracker, ok := ctx.Value(ruleEvaluationTrackerContextKey).(*ruleEvaluationTracker)
+	if !ok || tracker.last.iteration == 0 {
+		return evaluatedRule{}, false
+	}
+	return tracker.last, true
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleEvaluationTracker(t *testing.T) {
+	// The query run by the template of the alerting rule is the same as the query of the following rule,
+	// and two rules have the same query.
+	groupRules := []rules.Rule{
+		rules.NewAlertingRule("HighErrorRate", mustParseExpr(t, `sum by(job) (errors)`), 0, nil,
+			labels.FromStrings("summary", `{{ with query "sum by(job) (requests)" }}{{ . | first | value }}{{ end }}`), nil, "", false, log.NewNopLogger()),
+		rules.NewRecordingRule("job:requests:sum", mustParseExpr(t, `sum by(job) (requests)`), nil),
+		rules.NewRecordingRule("job:requests:sum2", mustParseExpr(t, `sum by(job) (requests)`), nil),
+	}
+
+	type query struct {
+		expr string
+		rule evaluatedRule
+	}
+	var (
+		mtx     sync.Mutex
+		queries []query
+	)
+	queryFunc := func(ctx context.Context, qs string, ts time.Time) (promql.Vector, error) {
+		r, ok := evaluatedRuleFromContext(ctx)
+		require.True(t, ok)
+
+		mtx.Lock()
+		queries = append(queries, query{expr: qs, rule: r})
+		mtx.Unlock()
+		return promql.Vector{{Point: promql.Point{T: ts.UnixMilli(), V: 1}, Metric: labels.FromStrings("job", "test")}}, nil
+	}
+
+	g := rules.NewGroup(rules.GroupOptions{
+		Name:     "group",
+		File:     "file",
+		Interval: time.Minute,
+		Rules:    groupRules,
+		Opts: &rules.ManagerOptions{
+			QueryFunc:  trackRuleQueries(queryFunc),
+			NotifyFunc: func(context.Context, string, ...*rules.Alert) {},
+			Appendable: &recordingAppendable{},
+			Context:    context.Background(),
+			Logger:     log.NewNopLogger(),
+		},
+	})
+
+	ctx := trackRuleEvaluationsContextFunc(nil)(context.Background(), g)
+	ts := time.Now()
+	g.Eval(ctx, ts)
+	g.Eval(ctx, ts.Add(time.Minute))
+
+	expected := func(iteration int64) []query {
+		return []query{
+			{expr: `sum by(job) (errors)`, rule: evaluatedRule{index: 0, iteration: iteration, ruleQuery: true}},
+			{expr: `sum by(job) (requests)`, rule: evaluatedRule{index: 0, iteration: iteration, ruleQuery: false}},
+			{expr: `sum by(job) (requests)`, rule: evaluatedRule{index: 1, iteration: iteration, ruleQuery: true}},
+			{expr: `sum by(job) (requests)`, rule: evaluatedRule{index: 2, iteration: iteration, ruleQuery: true}},
+		}
+	}
+	assert.Equal(t, append(expected(1), expected(2)...), queries)
+
+	last, ok := lastEvaluatedRule(ctx)
+	require.True(t, ok)
+	assert.Equal(t, evaluatedRule{index: 2, iteration: 2, ruleQuery: true}, last)
+}
@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/ruler/tenantfederation"
+)
+
+var errFederatedRuleGroupsDisabled = errors.New("federated rule groups are not enabled")
+
+// RuleGroupTester evaluates a rule group against the data of a tenant, without writing the series
+// recorded by its rules nor sending the alerts of its alerting rules.
+type RuleGroupTester struct {
+	cfg       Config
+	queryFunc rules.QueryFunc
+	limits    RulesLimits
+	logger    log.Logger
+}
+
+// NewRuleGroupTester makes a new RuleGroupTester running the queries of the rules through queryFunc.
+func NewRuleGroupTester(cfg Config, queryFunc rules.QueryFunc, limits RulesLimits, logger log.Logger) *RuleGroupTester {
+	return &RuleGroupTester{
+		cfg:       cfg,
+		queryFunc: queryFunc,
+		limits:    limits,
+		logger:    logger,
+	}
+}
+
+// RuleGroupTestResult is the result of the evaluation of a rule group by the RuleGroupTester.
+type RuleGroupTestResult struct {
+	Name           string           `yaml:"name"`
+	EvaluationTime time.Time        `yaml:"evaluation_time"`
+	Rules          []RuleTestResult `yaml:"rules"`
+}
+
+// RuleTestResult is the result of the evaluation of a single rule by the RuleGroupTester.
+type RuleTestResult struct {
+	Record    string `yaml:"record,omitempty"`
+	Alert     string `yaml:"alert,omitempty"`
+	Health    string `yaml:"health"`
+	LastError string `yaml:"last_error,omitempty"`
+
+	// Series which would be written by the rule.
+	Series []TestSample `yaml:"series,omitempty"`
+	// Alerts which would be pending or firing after the evaluation of the alerting rule.
+	Alerts []TestAlert `yaml:"alerts,omitempty"`
+}
+
+// TestSample is a sample which would be written by a rule.
+type TestSample struct {
+	Labels    labels.Labels `yaml:"labels"`
+	Value     float64       `yaml:"value"`
+	Timestamp time.Time     `yaml:"timestamp"`
+}
+
+// TestAlert is an alert which would be pending or firing after the evaluation of an alerting rule.
+type TestAlert struct {
+	Labels      labels.Labels `yaml:"labels"`
+	Annotations labels.Labels `yaml:"annotations,omitempty"`
+	State       string        `yaml:"state"`
+	ActiveAt    time.Time     `yaml:"active_at"`
+	Value       float64       `yaml:"value"`
+}
+
+// Test evaluates the rules of the rule group at the timestamp ts, within the query timeout of the rule group.
+// Rules are evaluated one after the other, but as nothing is written, rules using the output of the other rules
+// of the group are evaluated against the series already written by them.
+//
+// Alerting rules with a `for` duration are also evaluated at each evaluation of the rule group during the `for`
+// duration before ts, so that the alerts which would have been active during the whole duration are firing.
+func (t *RuleGroupTester) Test(ctx context.Context, userID string, rg rulefmt.RuleGroup, opts rulespb.RuleGroupOptions, ts time.Time) (*RuleGroupTestResult, error) {
+	ctx = user.InjectOrgID(ctx, userID)
+	if timeout := ruleGroupQueryTimeout(t.limits, userID, opts); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if len(rg.SourceTenants) > 0 {
+		if !t.cfg.TenantFederation.Enabled {
+			return nil, errFederatedRuleGroupsDisabled
+		}
+		ctx = tenantfederation.ContextWithSourceTenants(ctx, rg.SourceTenants)
+	}
+
+	evaluationDelay := t.limits.EvaluationDelay(userID)
+	if rg.EvaluationDelay != nil {
+		evaluationDelay = time.Duration(*rg.EvaluationDelay)
+	}
+
+	interval := time.Duration(rg.Interval)
+	if interval == 0 {
+		interval = t.cfg.EvaluationInterval
+	}
+
+	result := &RuleGroupTestResult{
+		Name:           rg.Name,
+		EvaluationTime: ts,
+		Rules:          make([]RuleTestResult, 0, len(rg.Rules)),
+	}
+
+	for _, node := range rg.Rules {
+		rule, err := t.newRule(node)
+		if err != nil {
+			return nil, err
+		}
+
+		r := RuleTestResult{Record: node.Record.Value, Alert: node.Alert.Value}
+
+		var vector promql.Vector
+		for _, evalTs := range ruleEvaluationTimes(rule, ts, interval) {
+			if vector, err = rule.Eval(ctx, evaluationDelay, evalTs, t.queryFunc, t.cfg.ExternalURL.URL, rg.Limit); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			r.Health = string(rules.HealthBad)
+			r.LastError = err.Error()
+			result.Rules = append(result.Rules, r)
+			continue
+		}
+		r.Health = string(rules.HealthGood)
+
+		switch rule := rule.(type) {
+		case *rules.RecordingRule:
+			r.Series = testSamples(vector)
+		case *rules.AlertingRule:
+			for _, a := range rule.ActiveAlerts() {
+				r.Alerts = append(r.Alerts, TestAlert{
+					Labels:      a.Labels,
+					Annotations: a.Annotations,
+					State:       a.State.String(),
+					ActiveAt:    a.ActiveAt,
+					Value:       a.Value,
+				})
+			}
+		}
+
+		result.Rules = append(result.Rules, r)
+	}
+
+	return result, nil
+}
+
+func (t *RuleGroupTester) newRule(node rulefmt.RuleNode) (rules.Rule, error) {
+	expr, err := parser.ParseExpr(node.Expr.Value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse the expression of the rule %q", node.Expr.Value)
+	}
+
+	if node.Alert.Value != "" {
+		// The alert is considered restored, so that the evaluation doesn't depend on the state of the alert in the ruler.
+		return rules.NewAlertingRule(node.Alert.Value, expr, time.Duration(node.For), labels.FromMap(node.Labels), labels.FromMap(node.Annotations), nil, t.cfg.ExternalURL.String(), true, t.logger), nil
+	}
+	return rules.NewRecordingRule(node.Record.Value, expr, labels.FromMap(node.Labels)), nil
+}
+
+// ruleEvaluationTimes returns the timestamps the rule is evaluated at, ending with ts. Alerting rules with a `for`
+// duration are evaluated at each interval since the first evaluation of the rule group at least `for` before ts.
+func ruleEvaluationTimes(rule rules.Rule, ts time.Time, interval time.Duration) []time.Time {
+	alertingRule, ok := rule.(*rules.AlertingRule)
+	if !ok || alertingRule.HoldDuration() <= 0 || interval <= 0 {
+		return []time.Time{ts}
+	}
+
+	n := int((alertingRule.HoldDuration() + interval - 1) / interval)
+	out := make([]time.Time, 0, n+1)
+	for i := n; i >= 0; i-- {
+		out = append(out, ts.Add(-time.Duration(i)*interval))
+	}
+	return out
+}
+
+func testSamples(vector promql.Vector) []TestSample {
+	samples := make([]TestSample, 0, len(vector))
+	for _, s := range vector {
+		samples = append(samples, TestSample{
+			Labels:    s.Metric,
+			Value:     s.V,
+			Timestamp: time.UnixMilli(s.T).UTC(),
+		})
+	}
+	return samples
+}
@@ -0,0 +1,262 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+)
+
+const ruleHistoryContextKey contextKey = 3
+
+// ruleEvaluationHistory keeps the last evaluations of each rule of the rule groups of a tenant.
+//
+// The Prometheus rules manager only keeps the state of the last evaluation of each rule. Before running
+// the query of a rule, the rules manager has completed the previous evaluation of the rules which come
+// after it in the group, and the current evaluation of the rules which come before it, so the history
+// records the state of the rules evaluated since the previous query of the group at each query. The
+// number of samples written by each rule is counted by the appendable used by the rules manager, which
+// looks up the rule whose output is written with lastEvaluatedRule.
+//
+// The rule of each query is read from the context, so the query function must be wrapped by trackRuleQueries.
+//
+// A nil history keeps nothing.
+type ruleEvaluationHistory struct {
+	size int
+
+	mtx    sync.Mutex
+	groups map[*rules.Group]*groupEvaluationHistory
+}
+
+type groupEvaluationHistory struct {
+	// The evaluations of the rules, by index of the rule in the group, the most recent last.
+	evaluations [][]*RuleEvaluationDesc
+	// The timestamp of the last recorded evaluation of the rules, by index of the rule in the group.
+	lastRecorded []time.Time
+	// The number of samples written by the rules since their last recorded evaluation, by index of the rule in the group.
+	pendingSamples []int64
+}
+
+// groupHistoryContext is the state injected in the context of the evaluation of a rule group.
+type groupHistoryContext struct {
+	group *rules.Group
+}
+
+// newRuleEvaluationHistory returns a history keeping the last size evaluations of each rule,
+// or nil if size isn't positive.
+func newRuleEvaluationHistory(size int) *ruleEvaluationHistory {
+	if size <= 0 {
+		return nil
+	}
+	return &ruleEvaluationHistory{
+		size:   size,
+		groups: map[*rules.Group]*groupEvaluationHistory{},
+	}
+}
+
+// GroupEvaluationContextFunc returns a rules.ContextWrapFunc injecting the group in the context, on top of
+// the context prepared by next.
+func (h *ruleEvaluationHistory) GroupEvaluationContextFunc(next rules.ContextWrapFunc) rules.ContextWrapFunc {
+	if h == nil {
+		return next
+	}
+
+	return func(ctx context.Context, g *rules.Group) context.Context {
+		if next != nil {
+			ctx = next(ctx, g)
+		}
+		return context.WithValue(ctx, ruleHistoryContextKey, &groupHistoryContext{group: g})
+	}
+}
+
+// WrapQueryFunc returns a rules.QueryFunc recording the evaluations of the rules of the group before
+// running the query through qf.
+func (h *ruleEvaluationHistory) WrapQueryFunc(qf rules.QueryFunc) rules.QueryFunc {
+	if h == nil {
+		return qf
+	}
+
+	return func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		// The queries run by the templates of an alerting rule are run while the rule is locked, so
+		// the evaluations are only recorded at the query of each rule.
+		if hc, ok := ctx.Value(ruleHistoryContextKey).(*groupHistoryContext); ok {
+			if r, ok := evaluatedRuleFromContext(ctx); ok && r.ruleQuery {
+				h.record(hc.group)
+			}
+		}
+		return qf(ctx, qs, t)
+	}
+}
+
+// WrapAppendable returns a storage.Appendable counting the samples written by the rules of the group.
+func (h *ruleEvaluationHistory) WrapAppendable(a storage.Appendable) storage.Appendable {
+	if h == nil {
+		return a
+	}
+	return &historyAppendable{Appendable: a, history: h}
+}
+
+// RetainGroups forgets the history of the rule groups which aren't in groups anymore.
+func (h *ruleEvaluationHistory) RetainGroups(groups []*rules.Group) {
+	if h == nil {
+		return
+	}
+
+	retained := make(map[*rules.Group]struct{}, len(groups))
+	for _, g := range groups {
+		retained[g] = struct{}{}
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for g := range h.groups {
+		if _, ok := retained[g]; !ok {
+			delete(h.groups, g)
+		}
+	}
+}
+
+// Evaluations returns the last evaluations of each rule of the group, the most recent first,
+// by index of the rule in the group.
+func (h *ruleEvaluationHistory) Evaluations(g *rules.Group) [][]*RuleEvaluationDesc {
+	if h == nil {
+		return nil
+	}
+
+	h.record(g)
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	gh := h.groups[g]
+	result := make([][]*RuleEvaluationDesc, len(gh.evaluations))
+	for i, evaluations := range gh.evaluations {
+		result[i] = make([]*RuleEvaluationDesc, 0, len(evaluations))
+		for j := len(evaluations) - 1; j >= 0; j-- {
+			result[i] = append(result[i], evaluations[j])
+		}
+	}
+	return result
+}
+
+// record records the evaluations of the rules of the group completed since their last recorded evaluation.
+func (h *ruleEvaluationHistory) record(g *rules.Group) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	gh := h.group(g)
+	for i, r := range g.Rules() {
+		ts := r.GetEvaluationTimestamp()
+		if ts.IsZero() || !ts.After(gh.lastRecorded[i]) {
+			continue
+		}
+		gh.lastRecorded[i] = ts
+
+		evaluation := &RuleEvaluationDesc{
+			EvaluationTimestamp: ts,
+			EvaluationDuration:  r.GetEvaluationDuration(),
+			Samples:             gh.pendingSamples[i],
+		}
+		gh.pendingSamples[i] = 0
+
+		if err := r.LastError(); err != nil {
+			evaluation.LastError = err.Error()
+		}
+		if ar, ok := r.(*rules.AlertingRule); ok {
+			for _, a := range ar.ActiveAlerts() {
+				if a.State == rules.StateFiring {
+					evaluation.FiringAlerts++
+				}
+			}
+		}
+
+		gh.evaluations[i] = append(gh.evaluations[i], evaluation)
+		if len(gh.evaluations[i]) > h.size {
+			gh.evaluations[i] = gh.evaluations[i][len(gh.evaluations[i])-h.size:]
+		}
+	}
+}
+
+// addSamples adds the number of samples written by the rule of the group with the given index.
+func (h *ruleEvaluationHistory) addSamples(g *rules.Group, rule int, samples int64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	gh := h.group(g)
+	if rule < len(gh.pendingSamples) {
+		gh.pendingSamples[rule] += samples
+	}
+}
+
+// group returns the history of the group. It must be called with the lock held.
+func (h *ruleEvaluationHistory) group(g *rules.Group) *groupEvaluationHistory {
+	gh, ok := h.groups[g]
+	if !ok {
+		gh = &groupEvaluationHistory{
+			evaluations:    make([][]*RuleEvaluationDesc, len(g.Rules())),
+			lastRecorded:   make([]time.Time, len(g.Rules())),
+			pendingSamples: make([]int64, len(g.Rules())),
+		}
+		h.groups[g] = gh
+	}
+	return gh
+}
+
+type historyAppendable struct {
+	storage.Appendable
+	history *ruleEvaluationHistory
+}
+
+// Appender returns a storage.Appender counting the samples written by the rule being evaluated, which
+// is the rule whose query has been run last by the evaluation of the group.
+func (a *historyAppendable) Appender(ctx context.Context) storage.Appender {
+	app := a.Appendable.Appender(ctx)
+
+	hc, ok := ctx.Value(ruleHistoryContextKey).(*groupHistoryContext)
+	if !ok {
+		return app
+	}
+	r, ok := lastEvaluatedRule(ctx)
+	if !ok {
+		return app
+	}
+	return &historyAppender{Appender: app, history: a.history, group: hc.group, rule: r.index}
+}
+
+type historyAppender struct {
+	storage.Appender
+	history *ruleEvaluationHistory
+	group   *rules.Group
+	rule    int
+	samples int64
+}
+
+func (a *historyAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	ref, err := a.Appender.Append(ref, l, t, v)
+	// Staleness markers aren't written by the evaluation of the rule.
+	if err == nil && !value.IsStaleNaN(v) {
+		a.samples++
+	}
+	return ref, err
+}
+
+func (a *historyAppender) Commit() error {
+	if err := a.Appender.Commit(); err != nil {
+		return err
+	}
+	a.history.addSamples(a.group, a.rule, a.samples)
+	return nil
+}
+
+func (a *historyAppender) Rollback() error {
+	a.samples = 0
+	return a.Appender.Rollback()
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/ruler/tenantfederation"
+)
+
+func TestRuleEvaluationHistory(t *testing.T) {
+	const historySize = 2
+
+	h := newRuleEvaluationHistory(historySize)
+
+	var failQueries bool
+	queryFunc := func(_ context.Context, qs string, ts time.Time) (promql.Vector, error) {
+		if failQueries {
+			return nil, errors.New("query failed")
+		}
+		return promql.Vector{
+			{Point: promql.Point{T: ts.UnixMilli(), V: 1}, Metric: labels.FromStrings("job", "a")},
+			{Point: promql.Point{T: ts.UnixMilli(), V: 1}, Metric: labels.FromStrings("job", "b")},
+		}, nil
+	}
+
+	recording := rules.NewRecordingRule("job:up:sum", mustParseExpr(t, `sum by(job) (up)`), nil)
+	// The alerting rule is restored, so that it writes the series of its alerts.
+	alerting := rules.NewAlertingRule("JobDown", mustParseExpr(t, `sum by(job) (up) < 1`), 0, nil, nil, nil, "", true, log.NewNopLogger())
+
+	g := rules.NewGroup(rules.GroupOptions{
+		Name:     "group",
+		File:     "file",
+		Interval: time.Minute,
+		Rules:    []rules.Rule{recording, alerting},
+		Opts: &rules.ManagerOptions{
+			QueryFunc:  trackRuleQueries(h.WrapQueryFunc(queryFunc)),
+			Appendable: h.WrapAppendable(&recordingAppendable{}),
+			NotifyFunc: func(context.Context, string, ...*rules.Alert) {},
+			Context:    context.Background(),
+			Logger:     log.NewNopLogger(),
+		},
+	})
+	ctx := trackRuleEvaluationsContextFunc(h.GroupEvaluationContextFunc(tenantfederation.GroupContextFunc))(context.Background(), g)
+
+	// No evaluation of the rules yet.
+	assert.Equal(t, [][]*RuleEvaluationDesc{{}, {}}, h.Evaluations(g))
+
+	// Each active alert writes an ALERTS and an ALERTS_FOR_STATE sample.
+	g.Eval(ctx, time.Now())
+	evaluations := h.Evaluations(g)
+	require.Len(t, evaluations, 2)
+	require.Len(t, evaluations[0], 1)
+	assert.Equal(t, int64(2), evaluations[0][0].Samples)
+	assert.Equal(t, "", evaluations[0][0].LastError)
+	assert.Equal(t, int64(0), evaluations[0][0].FiringAlerts)
+	assert.Equal(t, recording.GetEvaluationTimestamp(), evaluations[0][0].EvaluationTimestamp)
+	assert.Equal(t, recording.GetEvaluationDuration(), evaluations[0][0].EvaluationDuration)
+	require.Len(t, evaluations[1], 1)
+	assert.Equal(t, int64(4), evaluations[1][0].Samples)
+	assert.Equal(t, int64(2), evaluations[1][0].FiringAlerts)
+
+	// The evaluations of the group record the previous evaluation of the rules as well.
+	failQueries = true
+	g.Eval(ctx, time.Now())
+	failQueries = false
+	g.Eval(ctx, time.Now())
+
+	evaluations = h.Evaluations(g)
+	require.Len(t, evaluations[0], historySize)
+	assert.Equal(t, int64(2), evaluations[0][0].Samples)
+	assert.Equal(t, "", evaluations[0][0].LastError)
+	assert.Equal(t, int64(0), evaluations[0][1].Samples)
+	assert.Contains(t, evaluations[0][1].LastError, "query failed")
+	assert.True(t, evaluations[0][0].EvaluationTimestamp.After(evaluations[0][1].EvaluationTimestamp))
+
+	require.Len(t, evaluations[1], historySize)
+	assert.Equal(t, int64(4), evaluations[1][0].Samples)
+	assert.Equal(t, int64(2), evaluations[1][0].FiringAlerts)
+	assert.Contains(t, evaluations[1][1].LastError, "query failed")
+
+	// The history of the groups which aren't retained is forgotten.
+	h.RetainGroups(nil)
+	assert.Empty(t, h.groups)
+}
+
+func TestRuleEvaluationHistory_SamplesByRule(t *testing.T) {
+	h := newRuleEvaluationHistory(1)
+
+	queryFunc := func(_ context.Context, qs string, ts time.Time) (promql.Vector, error) {
+		vector := promql.Vector{{Point: promql.Point{T: ts.UnixMilli(), V: 1}, Metric: labels.FromStrings("job", "a")}}
+		if qs == `sum by(job) (up)` {
+			vector = append(vector, promql.Sample{Point: promql.Point{T: ts.UnixMilli(), V: 1}, Metric: labels.FromStrings("job", "b")})
+		}
+		return vector, nil
+	}
+
+	// The recording rules write series with the same metric name, and the alerting rule runs a query
+	// from its annotations. The alerting rule is restored, so that it writes the series of its alerts.
+	groupRules := []rules.Rule{
+		rules.NewRecordingRule("job:up:sum", mustParseExpr(t, `sum by(job) (up)`), labels.FromStrings("source", "a")),
+		rules.NewAlertingRule("JobDown", mustParseExpr(t, `sum by(job) (up) < 1`), time.Hour, nil,
+			labels.FromStrings("summary", `{{ with query "sum(up)" }}{{ . | first | value }}{{ end }}`), nil, "", true, log.NewNopLogger()),
+		rules.NewRecordingRule("job:up:sum", mustParseExpr(t, `sum by(job) (up{job="a"})`), labels.FromStrings("source", "b")),
+	}
+
+	g := rules.NewGroup(rules.GroupOptions{
+		Name:     "group",
+		File:     "file",
+		Interval: time.Minute,
+		Rules:    groupRules,
+		Opts: &rules.ManagerOptions{
+			QueryFunc:  trackRuleQueries(h.WrapQueryFunc(queryFunc)),
+			Appendable: h.WrapAppendable(&recordingAppendable{}),
+			NotifyFunc: func(context.Context, string, ...*rules.Alert) {},
+			Context:    context.Background(),
+			Logger:     log.NewNopLogger(),
+		},
+	})
+	ctx := trackRuleEvaluationsContextFunc(h.GroupEvaluationContextFunc(nil))(context.Background(), g)
+
+	for i := 0; i < 2; i++ {
+		g.Eval(ctx, time.Now())
+
+		evaluations := h.Evaluations(g)
+		require.Len(t, evaluations, 3)
+		for _, e := range evaluations {
+			require.Len(t, e, 1)
+		}
+		assert.Equal(t, int64(2), evaluations[0][0].Samples)
+		assert.Equal(t, int64(2), evaluations[1][0].Samples)
+		assert.Equal(t, int64(1), evaluations[2][0].Samples)
+	}
+}
+
+func TestRuleEvaluationHistory_Disabled(t *testing.T) {
+	h := newRuleEvaluationHistory(0)
+	require.Nil(t, h)
+
+	appendable := &recordingAppendable{}
+	assert.Same(t, appendable, h.WrapAppendable(appendable))
+	assert.Nil(t, h.GroupEvaluationContextFunc(nil))
+	assert.Nil(t, h.Evaluations(nil))
+	h.RetainGroups(nil)
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+)
+
+const groupQueryTimeoutContextKey contextKey = 6
+
+// ruleGroupQueryTimeouts cancels the queries of a rule group evaluation running for longer than the
+// query timeout of the group, counted from the query of the first rule of the evaluation.
+//
+// The rule of each query is read from the context, so the query function must be wrapped by trackRuleQueries.
+type ruleGroupQueryTimeouts struct {
+	limits RulesLimits
+
+	timedOutGroupEvaluations *prometheus.CounterVec
+}
+
+func newRuleGroupQueryTimeouts(limits RulesLimits, reg prometheus.Registerer) *ruleGroupQueryTimeouts {
+	return &ruleGroupQueryTimeouts{
+		limits: limits,
+		timedOutGroupEvaluations: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ruler_rule_group_evaluations_timed_out_total",
+			Help: "Total number of rule group evaluations whose queries have been cancelled because they exceeded the query timeout of the group.",
+		}, []string{"user"}),
+	}
+}
+
+// groupQueryTimeout holds the deadline of the current evaluation of a rule group. It's only accessed by
+// the goroutine evaluating the rule group.
+type groupQueryTimeout struct {
+	userID  string
+	group   *rules.Group
+	options *ruleGroupOptionsStore
+
+	iteration int64
+	// The queries of the current evaluation are cancelled at the deadline, if any.
+	deadline time.Time
+}
+
+// GroupEvaluationContextFunc returns a rules.ContextWrapFunc injecting the deadline of the evaluations of the
+// group in the context, on top of the context prepared by next. The query timeout of the group is looked up
+// in options at each evaluation of the group.
+func (q *ruleGroupQueryTimeouts) GroupEvaluationContextFunc(userID string, options *ruleGroupOptionsStore, next rules.ContextWrapFunc) rules.ContextWrapFunc {
+	return func(ctx context.Context, g *rules.Group) context.Context {
+		if next != nil {
+			ctx = next(ctx, g)
+		}
+		return context.WithValue(ctx, groupQueryTimeoutContextKey, &groupQueryTimeout{userID: userID, group: g, options: options})
+	}
+}
+
+// WrapQueryFunc returns a rules.QueryFunc running the queries through qf, cancelled at the deadline of the
+// evaluation of the group.
+func (q *ruleGroupQueryTimeouts) WrapQueryFunc(qf rules.QueryFunc) rules.QueryFunc {
+	return func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		g, ok := ctx.Value(groupQueryTimeoutContextKey).(*groupQueryTimeout)
+		if !ok {
+			return qf(ctx, qs, t)
+		}
+		r, ok := evaluatedRuleFromContext(ctx)
+		if !ok {
+			return qf(ctx, qs, t)
+		}
+
+		if r.iteration != g.iteration {
+			// This is the first query of a new evaluation of the group.
+			g.iteration = r.iteration
+			g.deadline = time.Time{}
+			if timeout := ruleGroupQueryTimeout(q.limits, g.userID, g.options.get(g.group)); timeout > 0 {
+				g.deadline = time.Now().Add(timeout)
+			}
+		}
+		if g.deadline.IsZero() {
+			return qf(ctx, qs, t)
+		}
+
+		if r.ruleQuery && r.index == len(g.group.Rules())-1 {
+			// The evaluation of the group has timed out if the query of its last rule ends past the deadline.
+			defer func() {
+				if time.Now().After(g.deadline) {
+					q.timedOutGroupEvaluations.WithLabelValues(g.userID).Inc()
+				}
+			}()
+		}
+
+		ctx, cancel := context.WithDeadline(ctx, g.deadline)
+		defer cancel()
+		return qf(ctx, qs, t)
+	}
+}
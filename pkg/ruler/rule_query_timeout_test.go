@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/mimir/pkg/ruler/rulespb"
+)
+
+func TestRuleGroupQueryTimeouts(t *testing.T) {
+	const userID = "user"
+
+	groupRules := []rules.Rule{
+		rules.NewRecordingRule("job:fast:sum", mustParseExpr(t, `sum by(job) (fast)`), nil),
+		rules.NewRecordingRule("job:slow:sum", mustParseExpr(t, `sum by(job) (slow)`), nil),
+	}
+
+	tests := map[string]struct {
+		groupQueryTimeout model.Duration
+		maxQueryTimeout   time.Duration
+		expectedTimedOut  bool
+	}{
+		"no query timeout": {
+			expectedTimedOut: false,
+		},
+		"query timeout of the rule group": {
+			groupQueryTimeout: model.Duration(100 * time.Millisecond),
+			expectedTimedOut:  true,
+		},
+		"query timeout of the rule group higher than the tenant limit": {
+			groupQueryTimeout: model.Duration(time.Hour),
+			maxQueryTimeout:   100 * time.Millisecond,
+			expectedTimedOut:  true,
+		},
+		"query timeout of the tenant": {
+			maxQueryTimeout:  100 * time.Millisecond,
+			expectedTimedOut: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			q := newRuleGroupQueryTimeouts(ruleLimits{maxQueryTimeout: testData.maxQueryTimeout}, prometheus.NewPedanticRegistry())
+
+			queryFunc := func(ctx context.Context, qs string, ts time.Time) (promql.Vector, error) {
+				if qs == `sum by(job) (slow)` {
+					select {
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					case <-time.After(time.Second):
+					}
+				}
+				return promql.Vector{{Point: promql.Point{T: ts.UnixMilli(), V: 1}, Metric: labels.FromStrings("job", "test")}}, nil
+			}
+
+			g := rules.NewGroup(rules.GroupOptions{
+				Name:     "group",
+				File:     "file",
+				Interval: time.Minute,
+				Rules:    groupRules,
+				Opts: &rules.ManagerOptions{
+					QueryFunc:  trackRuleQueries(q.WrapQueryFunc(queryFunc)),
+					Appendable: &recordingAppendable{},
+					Context:    context.Background(),
+					Logger:     log.NewNopLogger(),
+				},
+			})
+
+			options := &ruleGroupOptionsStore{}
+			options.set(map[string]rulespb.RuleGroupOptions{
+				rules.GroupKey("file", "group"): {QueryTimeout: testData.groupQueryTimeout},
+			})
+
+			ctx := trackRuleEvaluationsContextFunc(q.GroupEvaluationContextFunc(userID, options, nil))(context.Background(), g)
+			g.Eval(ctx, time.Now())
+
+			assert.Equal(t, rules.HealthGood, groupRules[0].Health())
+			if testData.expectedTimedOut {
+				assert.Equal(t, rules.HealthBad, groupRules[1].Health())
+				assert.ErrorIs(t, groupRules[1].LastError(), context.DeadlineExceeded)
+				assert.Equal(t, 1.0, prom_testutil.ToFloat64(q.timedOutGroupEvaluations.WithLabelValues(userID)))
+			} else {
+				assert.Equal(t, rules.HealthGood, groupRules[1].Health())
+				assert.Equal(t, 0.0, prom_testutil.ToFloat64(q.timedOutGroupEvaluations.WithLabelValues(userID)))
+			}
+		})
+	}
+}
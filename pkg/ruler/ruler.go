@@ -65,6 +65,9 @@ const (
 	// Limit errors
 	errMaxRuleGroupsPerUserLimitExceeded        = "per-user rule groups limit (limit: %d actual: %d) exceeded"
 	errMaxRulesPerRuleGroupPerUserLimitExceeded = "per-user rules per rule group limit (limit: %d actual: %d) exceeded"
+	errMinRuleGroupIntervalLimitExceeded        = "per-user minimum rule group interval limit (limit: %s actual: %s) exceeded"
+	errMaxRuleGroupIntervalLimitExceeded        = "per-user maximum rule group interval limit (limit: %s actual: %s) exceeded"
+	errMaxRuleGroupQueryTimeoutLimitExceeded    = "per-user maximum rule group query timeout limit (limit: %s actual: %s) exceeded"
 
 	// errors
 	errListAllUser = "unable to list the ruler users"
@@ -116,6 +119,10 @@ type Config struct {
 	QueryFrontend QueryFrontendConfig `yaml:"query_frontend" category:"experimental"`
 
 	TenantFederation TenantFederationConfig `yaml:"tenant_federation"`
+
+	MaxGlobalRuleEvaluationConcurrency int64 `yaml:"max_global_rule_evaluation_concurrency" category:"experimental"`
+
+	EvaluationHistorySize int `yaml:"evaluation_history_size" category:"experimental"`
 }
 
 // Validate config and returns error on failure
@@ -150,6 +157,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 
 	f.StringVar(&cfg.RulePath, "ruler.rule-path", "./data-ruler/", "Directory to store temporary rule files loaded by the Prometheus rule managers. This directory is not required to be persisted between restarts.")
 	f.BoolVar(&cfg.EnableAPI, "ruler.enable-api", true, "Enable the ruler config API.")
+	f.Int64Var(&cfg.MaxGlobalRuleEvaluationConcurrency, "ruler.max-global-rule-evaluation-concurrency", 0, "Global concurrency limit for the evaluation of the rules which don't depend on the output of other rules of their group. Rules are evaluated concurrently within a group up to this limit across all the tenants, and up to the tenant's limit. 0 to disable concurrent rule evaluation.")
+	f.IntVar(&cfg.EvaluationHistorySize, "ruler.evaluation-history-size", 0, "Number of the last evaluations of each rule kept in memory by the ruler, and exposed by the rules evaluation history API. 0 to disable the evaluation history.")
 	f.DurationVar(&cfg.OutageTolerance, "ruler.for-outage-tolerance", time.Hour, `Max time to tolerate outage for restoring "for" state of alert.`)
 	f.DurationVar(&cfg.ForGracePeriod, "ruler.for-grace-period", 10*time.Minute, `Minimum duration between alert and restored "for" state. This is maintained only for alerts with configured "for" time greater than grace period.`)
 	f.DurationVar(&cfg.ResendDelay, "ruler.resend-delay", time.Minute, `Minimum amount of time to wait before resending an alert to Alertmanager.`)
@@ -199,6 +208,8 @@ type MultiTenantManager interface {
 	SyncRuleGroups(ctx context.Context, ruleGroups map[string]rulespb.RuleGroupList)
 	// GetRules fetches rules for a particular tenant (userID).
 	GetRules(userID string) []*promRules.Group
+	// GetRuleEvaluationHistory fetches the last evaluations of each rule of a rule group of a particular tenant (userID).
+	GetRuleEvaluationHistory(userID string, g *promRules.Group) [][]*RuleEvaluationDesc
 	// Stop stops all Manager components.
 	Stop()
 	// ValidateRuleGroup validates a rulegroup
@@ -206,6 +217,7 @@ type MultiTenantManager interface {
 }
 
 // Ruler evaluates rules.
+//
 //	+---------------------------------------------------------------+
 //	|                                                               |
 //	|                   Query       +-------------+                 |
@@ -599,8 +611,9 @@ func filterRuleGroups(userID string, ruleGroups []*rulespb.RuleGroupDesc, ring r
 	return result
 }
 
-// GetRules retrieves the running rules from this ruler and all running rulers in the ring.
-func (r *Ruler) GetRules(ctx context.Context) ([]*GroupStateDesc, error) {
+// GetRules retrieves the running rules from this ruler and all running rulers in the ring. The evaluation
+// history of the rules is only retrieved if req asks for it.
+func (r *Ruler) GetRules(ctx context.Context, req *RulesRequest) ([]*GroupStateDesc, error) {
 	userID, err := tenant.TenantID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("no user id found in context")
@@ -638,7 +651,7 @@ func (r *Ruler) GetRules(ctx context.Context) ([]*GroupStateDesc, error) {
 			return errors.Wrapf(err, "unable to get client for ruler %s", addr)
 		}
 
-		newGrps, err := rulerClient.Rules(ctx, &RulesRequest{})
+		newGrps, err := rulerClient.Rules(ctx, req)
 		if err != nil {
 			return errors.Wrapf(err, "unable to retrieve rules from ruler %s", addr)
 		}
@@ -660,7 +673,7 @@ func (r *Ruler) Rules(ctx context.Context, in *RulesRequest) (*RulesResponse, er
 		return nil, fmt.Errorf("no user id found in context")
 	}
 
-	groupDescs, err := r.getLocalRules(userID)
+	groupDescs, err := r.getLocalRules(userID, in.IncludeEvaluationHistory)
 	if err != nil {
 		return nil, err
 	}
@@ -668,7 +681,7 @@ func (r *Ruler) Rules(ctx context.Context, in *RulesRequest) (*RulesResponse, er
 	return &RulesResponse{Groups: groupDescs}, nil
 }
 
-func (r *Ruler) getLocalRules(userID string) ([]*GroupStateDesc, error) {
+func (r *Ruler) getLocalRules(userID string, includeEvaluationHistory bool) ([]*GroupStateDesc, error) {
 	groups := r.manager.GetRules(userID)
 
 	groupDescs := make([]*GroupStateDesc, 0, len(groups))
@@ -683,6 +696,11 @@ func (r *Ruler) getLocalRules(userID string) ([]*GroupStateDesc, error) {
 			return nil, errors.Wrap(err, "unable to decode rule filename")
 		}
 
+		var history [][]*RuleEvaluationDesc
+		if includeEvaluationHistory {
+			history = r.manager.GetRuleEvaluationHistory(userID, group)
+		}
+
 		groupDesc := &GroupStateDesc{
 			Group: &rulespb.RuleGroupDesc{
 				Name:          group.Name(),
@@ -695,7 +713,7 @@ func (r *Ruler) getLocalRules(userID string) ([]*GroupStateDesc, error) {
 			EvaluationTimestamp: group.GetLastEvaluation(),
 			EvaluationDuration:  group.GetEvaluationTime(),
 		}
-		for _, r := range group.Rules() {
+		for i, r := range group.Rules() {
 			lastError := ""
 			if r.LastError() != nil {
 				lastError = r.LastError().Error()
@@ -749,6 +767,9 @@ func (r *Ruler) getLocalRules(userID string) ([]*GroupStateDesc, error) {
 			default:
 				return nil, errors.Errorf("failed to assert type of rule '%v'", rule.Name())
 			}
+			if i < len(history) {
+				ruleDesc.EvaluationHistory = history[i]
+			}
 			groupDesc.ActiveRules = append(groupDesc.ActiveRules, ruleDesc)
 		}
 		groupDescs = append(groupDescs, groupDesc)
@@ -787,6 +808,26 @@ func (r *Ruler) AssertMaxRulesPerRuleGroup(userID string, rules int) error {
 	return fmt.Errorf(errMaxRulesPerRuleGroupPerUserLimitExceeded, limit, rules)
 }
 
+// AssertRuleGroupEvaluationLimits checks the evaluation interval and the query timeout of a rule group
+// against the limits of the user, and returns an error if they're not within the limits.
+// A zero interval is the default evaluation interval, and a zero query timeout is no timeout.
+func (r *Ruler) AssertRuleGroupEvaluationLimits(userID string, interval, queryTimeout time.Duration) error {
+	if interval == 0 {
+		interval = r.cfg.EvaluationInterval
+	}
+
+	if limit := r.limits.RulerMinRuleGroupInterval(userID); limit > 0 && interval < limit {
+		return fmt.Errorf(errMinRuleGroupIntervalLimitExceeded, limit, interval)
+	}
+	if limit := r.limits.RulerMaxRuleGroupInterval(userID); limit > 0 && interval > limit {
+		return fmt.Errorf(errMaxRuleGroupIntervalLimitExceeded, limit, interval)
+	}
+	if limit := r.limits.RulerMaxRuleGroupQueryTimeout(userID); limit > 0 && queryTimeout > limit {
+		return fmt.Errorf(errMaxRuleGroupQueryTimeoutLimitExceeded, limit, queryTimeout)
+	}
+	return nil
+}
+
 func (r *Ruler) DeleteTenantConfiguration(w http.ResponseWriter, req *http.Request) {
 	logger := util_log.WithContext(req.Context(), r.logger)
 
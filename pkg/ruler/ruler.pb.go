@@ -39,6 +39,8 @@ var _ = time.Kitchen
 const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
 type RulesRequest struct {
+	// Whether the evaluation history of the rules is included in the response.
+	IncludeEvaluationHistory bool `protobuf:"varint,1,opt,name=includeEvaluationHistory,proto3" json:"includeEvaluationHistory,omitempty"`
 }
 
 func (m *RulesRequest) Reset()      { *m = RulesRequest{} }
@@ -73,6 +75,13 @@ func (m *RulesRequest) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_RulesRequest proto.InternalMessageInfo
 
+func (m *RulesRequest) GetIncludeEvaluationHistory() bool {
+	if m != nil {
+		return m.IncludeEvaluationHistory
+	}
+	return false
+}
+
 type RulesResponse struct {
 	Groups []*GroupStateDesc `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
 }
@@ -186,13 +195,14 @@ func (m *GroupStateDesc) GetEvaluationDuration() time.Duration {
 
 // RuleStateDesc is a proto representation of a Prometheus Rule
 type RuleStateDesc struct {
-	Rule                *rulespb.RuleDesc `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
-	State               string            `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
-	Health              string            `protobuf:"bytes,3,opt,name=health,proto3" json:"health,omitempty"`
-	LastError           string            `protobuf:"bytes,4,opt,name=lastError,proto3" json:"lastError,omitempty"`
-	Alerts              []*AlertStateDesc `protobuf:"bytes,5,rep,name=alerts,proto3" json:"alerts,omitempty"`
-	EvaluationTimestamp time.Time         `protobuf:"bytes,6,opt,name=evaluationTimestamp,proto3,stdtime" json:"evaluationTimestamp"`
-	EvaluationDuration  time.Duration     `protobuf:"bytes,7,opt,name=evaluationDuration,proto3,stdduration" json:"evaluationDuration"`
+	Rule                *rulespb.RuleDesc     `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	State               string                `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Health              string                `protobuf:"bytes,3,opt,name=health,proto3" json:"health,omitempty"`
+	LastError           string                `protobuf:"bytes,4,opt,name=lastError,proto3" json:"lastError,omitempty"`
+	Alerts              []*AlertStateDesc     `protobuf:"bytes,5,rep,name=alerts,proto3" json:"alerts,omitempty"`
+	EvaluationTimestamp time.Time             `protobuf:"bytes,6,opt,name=evaluationTimestamp,proto3,stdtime" json:"evaluationTimestamp"`
+	EvaluationDuration  time.Duration         `protobuf:"bytes,7,opt,name=evaluationDuration,proto3,stdduration" json:"evaluationDuration"`
+	EvaluationHistory   []*RuleEvaluationDesc `protobuf:"bytes,8,rep,name=evaluationHistory,proto3" json:"evaluationHistory,omitempty"`
 }
 
 func (m *RuleStateDesc) Reset()      { *m = RuleStateDesc{} }
@@ -276,6 +286,89 @@ func (m *RuleStateDesc) GetEvaluationDuration() time.Duration {
 	return 0
 }
 
+func (m *RuleStateDesc) GetEvaluationHistory() []*RuleEvaluationDesc {
+	if m != nil {
+		return m.EvaluationHistory
+	}
+	return nil
+}
+
+// RuleEvaluationDesc is a proto representation of a past evaluation of a Prometheus Rule
+type RuleEvaluationDesc struct {
+	EvaluationTimestamp time.Time     `protobuf:"bytes,1,opt,name=evaluationTimestamp,proto3,stdtime" json:"evaluationTimestamp"`
+	EvaluationDuration  time.Duration `protobuf:"bytes,2,opt,name=evaluationDuration,proto3,stdduration" json:"evaluationDuration"`
+	Samples             int64         `protobuf:"varint,3,opt,name=samples,proto3" json:"samples,omitempty"`
+	LastError           string        `protobuf:"bytes,4,opt,name=lastError,proto3" json:"lastError,omitempty"`
+	FiringAlerts        int64         `protobuf:"varint,5,opt,name=firingAlerts,proto3" json:"firingAlerts,omitempty"`
+}
+
+func (m *RuleEvaluationDesc) Reset()      { *m = RuleEvaluationDesc{} }
+func (*RuleEvaluationDesc) ProtoMessage() {}
+func (*RuleEvaluationDesc) Descriptor() ([]byte, []int) {
+	return fileDescriptor_9ecbec0a4cfddea6, []int{4}
+}
+func (m *RuleEvaluationDesc) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RuleEvaluationDesc) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RuleEvaluationDesc.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *RuleEvaluationDesc) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RuleEvaluationDesc.Merge(m, src)
+}
+func (m *RuleEvaluationDesc) XXX_Size() int {
+	return m.Size()
+}
+func (m *RuleEvaluationDesc) XXX_DiscardUnknown() {
+	xxx_messageInfo_RuleEvaluationDesc.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RuleEvaluationDesc proto.InternalMessageInfo
+
+func (m *RuleEvaluationDesc) GetEvaluationTimestamp() time.Time {
+	if m != nil {
+		return m.EvaluationTimestamp
+	}
+	return time.Time{}
+}
+
+func (m *RuleEvaluationDesc) GetEvaluationDuration() time.Duration {
+	if m != nil {
+		return m.EvaluationDuration
+	}
+	return 0
+}
+
+func (m *RuleEvaluationDesc) GetSamples() int64 {
+	if m != nil {
+		return m.Samples
+	}
+	return 0
+}
+
+func (m *RuleEvaluationDesc) GetLastError() string {
+	if m != nil {
+		return m.LastError
+	}
+	return ""
+}
+
+func (m *RuleEvaluationDesc) GetFiringAlerts() int64 {
+	if m != nil {
+		return m.FiringAlerts
+	}
+	return 0
+}
+
 type AlertStateDesc struct {
 	State       string                                              `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
 	Labels      []github_com_grafana_mimir_pkg_mimirpb.LabelAdapter `protobuf:"bytes,2,rep,name=labels,proto3,customtype=github.com/grafana/mimir/pkg/mimirpb.LabelAdapter" json:"labels"`
@@ -291,7 +384,7 @@ type AlertStateDesc struct {
 func (m *AlertStateDesc) Reset()      { *m = AlertStateDesc{} }
 func (*AlertStateDesc) ProtoMessage() {}
 func (*AlertStateDesc) Descriptor() ([]byte, []int) {
-	return fileDescriptor_9ecbec0a4cfddea6, []int{4}
+	return fileDescriptor_9ecbec0a4cfddea6, []int{5}
 }
 func (m *AlertStateDesc) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -374,56 +467,63 @@ func init() {
 	proto.RegisterType((*RulesResponse)(nil), "ruler.RulesResponse")
 	proto.RegisterType((*GroupStateDesc)(nil), "ruler.GroupStateDesc")
 	proto.RegisterType((*RuleStateDesc)(nil), "ruler.RuleStateDesc")
+	proto.RegisterType((*RuleEvaluationDesc)(nil), "ruler.RuleEvaluationDesc")
 	proto.RegisterType((*AlertStateDesc)(nil), "ruler.AlertStateDesc")
 }
 
 func init() { proto.RegisterFile("ruler.proto", fileDescriptor_9ecbec0a4cfddea6) }
 
 var fileDescriptor_9ecbec0a4cfddea6 = []byte{
-	// 682 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x54, 0xcd, 0x6e, 0x13, 0x3b,
-	0x14, 0x1e, 0xa7, 0x4d, 0x9a, 0x38, 0xbd, 0xbd, 0x92, 0x9b, 0x7b, 0x35, 0x44, 0xc8, 0xa9, 0xc2,
-	0xa6, 0x42, 0xea, 0x04, 0x4a, 0x05, 0x62, 0x01, 0x28, 0x55, 0x0b, 0x1b, 0x16, 0x68, 0x0a, 0x6c,
-	0x2b, 0x4f, 0xe2, 0x4c, 0x47, 0x4c, 0xc6, 0x83, 0xed, 0x89, 0x58, 0xf2, 0x08, 0x5d, 0xb2, 0x66,
-	0xc5, 0xa3, 0x74, 0xd9, 0x65, 0x85, 0x50, 0xa1, 0xd3, 0x0d, 0xcb, 0x3e, 0x02, 0xf2, 0xf1, 0x84,
-	0x24, 0x50, 0x10, 0x11, 0xea, 0x26, 0xf1, 0xf9, 0xf9, 0xbe, 0x73, 0xce, 0x77, 0xc6, 0xc6, 0x75,
-	0x99, 0xc5, 0x5c, 0x7a, 0xa9, 0x14, 0x5a, 0x90, 0x32, 0x18, 0xcd, 0x8d, 0x30, 0xd2, 0x07, 0x59,
-	0xe0, 0xf5, 0xc4, 0xb0, 0x13, 0x8a, 0x50, 0x74, 0x20, 0x1a, 0x64, 0x03, 0xb0, 0xc0, 0x80, 0x93,
-	0x45, 0x35, 0x69, 0x28, 0x44, 0x18, 0xf3, 0x49, 0x56, 0x3f, 0x93, 0x4c, 0x47, 0x22, 0x29, 0xe2,
-	0xad, 0x1f, 0xe3, 0x3a, 0x1a, 0x72, 0xa5, 0xd9, 0x30, 0x2d, 0x12, 0x6e, 0x4d, 0xd7, 0x93, 0x6c,
-	0xc0, 0x12, 0xd6, 0x19, 0x46, 0xc3, 0x48, 0x76, 0xd2, 0x57, 0xa1, 0x3d, 0xa5, 0x81, 0xfd, 0x2f,
-	0x10, 0x77, 0x7f, 0x8b, 0x80, 0x29, 0xe0, 0x57, 0xa5, 0x81, 0xfd, 0xb7, 0xb8, 0xf6, 0x0a, 0x5e,
-	0xf6, 0x8d, 0xe9, 0xf3, 0xd7, 0x19, 0x57, 0xba, 0xfd, 0x10, 0xff, 0x53, 0xd8, 0x2a, 0x15, 0x89,
-	0xe2, 0x64, 0x03, 0x57, 0x42, 0x29, 0xb2, 0x54, 0xb9, 0x68, 0x6d, 0x61, 0xbd, 0xbe, 0xf9, 0x9f,
-	0x67, 0xf5, 0x79, 0x62, 0x9c, 0x7b, 0x9a, 0x69, 0xbe, 0xc3, 0x55, 0xcf, 0x2f, 0x92, 0xda, 0xef,
-	0x4b, 0x78, 0x65, 0x36, 0x44, 0x6e, 0xe2, 0x32, 0x04, 0x5d, 0xb4, 0x86, 0xd6, 0xeb, 0x9b, 0x0d,
-	0xcf, 0xd6, 0x37, 0x65, 0x20, 0x13, 0xf0, 0x36, 0x85, 0xdc, 0xc3, 0xcb, 0xac, 0xa7, 0xa3, 0x11,
-	0xdf, 0x87, 0x24, 0xb7, 0x04, 0x35, 0x1b, 0x45, 0x4d, 0x03, 0x99, 0x94, 0xac, 0xdb, 0x4c, 0x68,
-	0x97, 0xbc, 0xc4, 0xab, 0x7c, 0xc4, 0xe2, 0x0c, 0x64, 0x7e, 0x3e, 0x96, 0xd3, 0x5d, 0x80, 0x92,
-	0x4d, 0xcf, 0x0a, 0xee, 0x8d, 0x05, 0xf7, 0xbe, 0x67, 0x6c, 0x57, 0x8f, 0x4e, 0x5b, 0xce, 0xe1,
-	0xe7, 0x16, 0xf2, 0x2f, 0x23, 0x20, 0x7b, 0x98, 0x4c, 0xdc, 0x3b, 0xc5, 0x1a, 0xdd, 0x45, 0xa0,
-	0xbd, 0xf6, 0x13, 0xed, 0x38, 0xc1, 0xb2, 0xbe, 0x33, 0xac, 0x97, 0xc0, 0xdb, 0x9f, 0x4a, 0x56,
-	0xe5, 0x89, 0x46, 0x37, 0xf0, 0xa2, 0x19, 0xb1, 0x90, 0xe8, 0xdf, 0x29, 0x89, 0x60, 0x54, 0x08,
-	0x92, 0x06, 0x2e, 0x2b, 0x83, 0x70, 0x4b, 0x6b, 0x68, 0xbd, 0xe6, 0x5b, 0x83, 0xfc, 0x8f, 0x2b,
-	0x07, 0x9c, 0xc5, 0xfa, 0x00, 0x86, 0xad, 0xf9, 0x85, 0x45, 0xae, 0xe3, 0x5a, 0xcc, 0x94, 0xde,
-	0x95, 0x52, 0x48, 0x68, 0xb8, 0xe6, 0x4f, 0x1c, 0x66, 0xad, 0x2c, 0xe6, 0x52, 0x2b, 0xb7, 0x3c,
-	0xb3, 0xd6, 0xae, 0x71, 0x4e, 0xad, 0xd5, 0x26, 0xfd, 0x4a, 0xde, 0xca, 0xd5, 0xc8, 0xbb, 0xf4,
-	0x77, 0xf2, 0x5e, 0x2c, 0xe2, 0x95, 0xd9, 0x39, 0x26, 0xd2, 0xa1, 0x69, 0xe9, 0x06, 0xb8, 0x12,
-	0xb3, 0x80, 0xc7, 0xe3, 0xef, 0x6c, 0xd5, 0xeb, 0x09, 0xa9, 0xf9, 0x9b, 0x34, 0xf0, 0x9e, 0x1a,
-	0xff, 0x33, 0x16, 0xc9, 0xed, 0xfb, 0xa6, 0xd6, 0xc7, 0xd3, 0xd6, 0xed, 0x3f, 0xb9, 0x93, 0x16,
-	0xd7, 0xed, 0xb3, 0x54, 0x73, 0xe9, 0x17, 0xec, 0x24, 0xc5, 0x75, 0x96, 0x24, 0x42, 0x43, 0x7b,
-	0xca, 0x5d, 0xb8, 0x92, 0x62, 0xd3, 0x25, 0xcc, 0xbc, 0x46, 0x17, 0x0e, 0x8b, 0x47, 0xbe, 0x35,
-	0x48, 0x17, 0xd7, 0x8a, 0xdb, 0xc5, 0xb4, 0x5b, 0x9e, 0x63, 0x77, 0x55, 0x0b, 0xeb, 0x6a, 0xf2,
-	0x08, 0x57, 0x07, 0x91, 0xe4, 0x7d, 0xc3, 0x30, 0xcf, 0xf6, 0x97, 0x00, 0xd5, 0xd5, 0x64, 0x17,
-	0xd7, 0x25, 0x57, 0x22, 0x1e, 0x59, 0x8e, 0xa5, 0x39, 0x38, 0xf0, 0x18, 0xd8, 0xd5, 0xe4, 0x31,
-	0x5e, 0x36, 0x1f, 0xf3, 0xbe, 0xe2, 0x89, 0x36, 0x3c, 0xd5, 0x79, 0x78, 0x0c, 0x72, 0x8f, 0x27,
-	0xda, 0xb6, 0x33, 0x62, 0x71, 0xd4, 0xdf, 0xcf, 0x12, 0x1d, 0xc5, 0x6e, 0x6d, 0x1e, 0x1a, 0x00,
-	0xbe, 0x30, 0xb8, 0xcd, 0x07, 0xb8, 0x6c, 0x2e, 0xab, 0x24, 0x5b, 0xf6, 0xa0, 0xc8, 0xea, 0xd4,
-	0x9b, 0x35, 0x7e, 0x5d, 0x9b, 0x8d, 0x59, 0xa7, 0x7d, 0x62, 0xdb, 0xce, 0xf6, 0xd6, 0xf1, 0x19,
-	0x75, 0x4e, 0xce, 0xa8, 0x73, 0x71, 0x46, 0xd1, 0xdb, 0x9c, 0xa2, 0x0f, 0x39, 0x45, 0x47, 0x39,
-	0x45, 0xc7, 0x39, 0x45, 0x5f, 0x72, 0x8a, 0xbe, 0xe6, 0xd4, 0xb9, 0xc8, 0x29, 0x3a, 0x3c, 0xa7,
-	0xce, 0xf1, 0x39, 0x75, 0x4e, 0xce, 0xa9, 0x13, 0x54, 0xa0, 0xbd, 0x3b, 0xdf, 0x02, 0x00, 0x00,
-	0xff, 0xff, 0x0c, 0x0b, 0x21, 0x72, 0xb2, 0x06, 0x00, 0x00,
+	// 772 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x55, 0x4f, 0x6f, 0xd3, 0x30,
+	0x14, 0x8f, 0xdb, 0xf5, 0x9f, 0xdb, 0x0d, 0xe1, 0x0d, 0x94, 0x55, 0x28, 0xad, 0xc2, 0x65, 0x42,
+	0x5a, 0x0a, 0x63, 0x02, 0x81, 0x04, 0xa8, 0xd3, 0xc6, 0x10, 0xe2, 0x80, 0x32, 0xe0, 0x3a, 0xb9,
+	0xad, 0x9b, 0x45, 0xa4, 0x71, 0xb0, 0x9d, 0x0a, 0x6e, 0x7c, 0x84, 0x1d, 0xb9, 0x70, 0x41, 0x42,
+	0xe2, 0xa3, 0xec, 0xb8, 0xe3, 0xc4, 0x61, 0xb0, 0xee, 0xc2, 0x71, 0x1f, 0x01, 0xd9, 0x4e, 0xd6,
+	0x96, 0x6e, 0x40, 0x35, 0xed, 0xd2, 0xe6, 0xfd, 0xf9, 0xfd, 0x9e, 0xdf, 0x7b, 0x3f, 0x27, 0xb0,
+	0xcc, 0xe2, 0x80, 0x30, 0x27, 0x62, 0x54, 0x50, 0x94, 0x53, 0x46, 0x75, 0xd9, 0xf3, 0xc5, 0x4e,
+	0xdc, 0x72, 0xda, 0xb4, 0xd7, 0xf0, 0xa8, 0x47, 0x1b, 0x2a, 0xda, 0x8a, 0xbb, 0xca, 0x52, 0x86,
+	0x7a, 0xd2, 0xa8, 0xaa, 0xe5, 0x51, 0xea, 0x05, 0x64, 0x98, 0xd5, 0x89, 0x19, 0x16, 0x3e, 0x0d,
+	0x93, 0x78, 0xed, 0xcf, 0xb8, 0xf0, 0x7b, 0x84, 0x0b, 0xdc, 0x8b, 0x92, 0x84, 0xdb, 0xa3, 0xf5,
+	0x18, 0xee, 0xe2, 0x10, 0x37, 0x7a, 0x7e, 0xcf, 0x67, 0x8d, 0xe8, 0xad, 0xa7, 0x9f, 0xa2, 0x96,
+	0xfe, 0x4f, 0x10, 0xf7, 0xfe, 0x8a, 0x50, 0x5d, 0xa8, 0x5f, 0x1e, 0xb5, 0xf4, 0xbf, 0xc6, 0xd9,
+	0xcf, 0x61, 0xc5, 0x95, 0xa6, 0x4b, 0xde, 0xc5, 0x84, 0x0b, 0xf4, 0x10, 0x9a, 0x7e, 0xd8, 0x0e,
+	0xe2, 0x0e, 0xd9, 0xe8, 0xe3, 0x20, 0x56, 0xa7, 0x7e, 0xe6, 0x73, 0x41, 0xd9, 0x07, 0x13, 0xd4,
+	0xc1, 0x52, 0xd1, 0x3d, 0x37, 0x6e, 0x3f, 0x86, 0xb3, 0x09, 0x17, 0x8f, 0x68, 0xc8, 0x09, 0x5a,
+	0x86, 0x79, 0x8f, 0xd1, 0x38, 0xe2, 0x26, 0xa8, 0x67, 0x97, 0xca, 0x2b, 0xd7, 0x1c, 0x3d, 0xdb,
+	0x4d, 0xe9, 0xdc, 0x12, 0x58, 0x90, 0x75, 0xc2, 0xdb, 0x6e, 0x92, 0x64, 0x7f, 0xc9, 0xc0, 0xb9,
+	0xf1, 0x10, 0xba, 0x05, 0x73, 0x2a, 0xa8, 0x6a, 0x97, 0x57, 0x16, 0x1c, 0x7d, 0x76, 0x59, 0x46,
+	0x65, 0x2a, 0xbc, 0x4e, 0x41, 0xf7, 0x61, 0x05, 0xb7, 0x85, 0xdf, 0x27, 0xdb, 0x2a, 0xc9, 0xcc,
+	0xd4, 0xb3, 0xa7, 0x10, 0xa6, 0x20, 0xc3, 0x92, 0x65, 0x9d, 0xa9, 0x8e, 0x8b, 0xde, 0xc0, 0x79,
+	0x72, 0xda, 0xcc, 0xab, 0x74, 0x15, 0x66, 0x56, 0x95, 0xac, 0x3a, 0x7a, 0x59, 0x4e, 0xba, 0x2c,
+	0xe7, 0x34, 0x63, 0xad, 0xb8, 0x77, 0x58, 0x33, 0x76, 0x7f, 0xd4, 0x80, 0x7b, 0x16, 0x01, 0xda,
+	0x82, 0x68, 0xe8, 0x5e, 0x4f, 0x24, 0x60, 0xce, 0x28, 0xda, 0xc5, 0x09, 0xda, 0x34, 0x41, 0xb3,
+	0x7e, 0x92, 0xac, 0x67, 0xc0, 0xed, 0xaf, 0x59, 0x38, 0x3b, 0xd6, 0x0b, 0xba, 0x09, 0x67, 0x64,
+	0x8b, 0xc9, 0x88, 0xae, 0x8c, 0x8c, 0x48, 0xb5, 0xaa, 0x82, 0x68, 0x01, 0xe6, 0xb8, 0x44, 0x98,
+	0x99, 0x3a, 0x58, 0x2a, 0xb9, 0xda, 0x40, 0xd7, 0x61, 0x7e, 0x87, 0xe0, 0x40, 0xec, 0xa8, 0x66,
+	0x4b, 0x6e, 0x62, 0xa1, 0x1b, 0xb0, 0x14, 0x60, 0x2e, 0x36, 0x18, 0xa3, 0x4c, 0x1d, 0xb8, 0xe4,
+	0x0e, 0x1d, 0x72, 0xad, 0x38, 0x20, 0x4c, 0x70, 0x33, 0x37, 0xb6, 0xd6, 0xa6, 0x74, 0x8e, 0xac,
+	0x55, 0x27, 0x9d, 0x37, 0xde, 0xfc, 0xe5, 0x8c, 0xb7, 0x70, 0xa1, 0xf1, 0xa2, 0x4d, 0x78, 0x95,
+	0x4c, 0x08, 0xbf, 0xa8, 0xda, 0x5c, 0x1c, 0x51, 0xd2, 0x50, 0xfc, 0xaa, 0xd5, 0x49, 0x8c, 0xfd,
+	0x39, 0x03, 0xd1, 0x64, 0xe6, 0x79, 0xc3, 0x00, 0x97, 0x33, 0x8c, 0xcc, 0xc5, 0x86, 0x61, 0xc2,
+	0x02, 0xc7, 0xbd, 0x48, 0x5e, 0x26, 0xa9, 0x8f, 0xac, 0x9b, 0x9a, 0xff, 0x10, 0x88, 0x0d, 0x2b,
+	0x5d, 0x9f, 0xf9, 0xa1, 0xd7, 0x4c, 0x65, 0x22, 0xc1, 0x63, 0x3e, 0xfb, 0x64, 0x06, 0xce, 0x8d,
+	0x0b, 0x66, 0xa8, 0x51, 0x30, 0xaa, 0xd1, 0x2e, 0xcc, 0x07, 0xb8, 0x45, 0x82, 0xf4, 0x42, 0xcf,
+	0x3b, 0x6d, 0xca, 0x04, 0x79, 0x1f, 0xb5, 0x9c, 0x17, 0xd2, 0xff, 0x12, 0xfb, 0x6c, 0xed, 0x81,
+	0xec, 0xe3, 0xfb, 0x61, 0xed, 0xce, 0xff, 0xbc, 0x38, 0x35, 0xae, 0xd9, 0xc1, 0x91, 0x20, 0xcc,
+	0x4d, 0xd8, 0x51, 0x04, 0xcb, 0x38, 0x0c, 0xa9, 0x50, 0xad, 0xcb, 0x86, 0x2f, 0xa3, 0xd8, 0x68,
+	0x09, 0xd9, 0xaf, 0x9c, 0x39, 0x51, 0x03, 0x04, 0xae, 0x36, 0x50, 0x13, 0x96, 0x92, 0xd7, 0x18,
+	0x16, 0x66, 0x6e, 0x0a, 0x5d, 0x14, 0x35, 0xac, 0x29, 0xd0, 0x13, 0x58, 0xec, 0xfa, 0x8c, 0x74,
+	0x24, 0xc3, 0x34, 0xd7, 0xac, 0xa0, 0x50, 0x4d, 0x81, 0x36, 0x60, 0x99, 0x11, 0x4e, 0x83, 0xbe,
+	0xe6, 0x28, 0x4c, 0xc1, 0x01, 0x53, 0x60, 0x53, 0xa0, 0xa7, 0xb0, 0x22, 0x45, 0xb1, 0xcd, 0x49,
+	0x28, 0x24, 0x4f, 0x71, 0x1a, 0x1e, 0x89, 0xdc, 0x22, 0xa1, 0xd0, 0xc7, 0xe9, 0xe3, 0xc0, 0xef,
+	0x6c, 0xc7, 0xa1, 0xf0, 0x03, 0xb3, 0x34, 0x0d, 0x8d, 0x02, 0xbe, 0x96, 0xb8, 0x95, 0x47, 0x30,
+	0x27, 0x6f, 0x24, 0x43, 0xab, 0xfa, 0x81, 0xa3, 0xf9, 0x91, 0x2b, 0x9d, 0x7e, 0x02, 0xab, 0x0b,
+	0xe3, 0x4e, 0xfd, 0x2d, 0xb3, 0x8d, 0xb5, 0xd5, 0xfd, 0x23, 0xcb, 0x38, 0x38, 0xb2, 0x8c, 0x93,
+	0x23, 0x0b, 0x7c, 0x1c, 0x58, 0xe0, 0xdb, 0xc0, 0x02, 0x7b, 0x03, 0x0b, 0xec, 0x0f, 0x2c, 0xf0,
+	0x73, 0x60, 0x81, 0x5f, 0x03, 0xcb, 0x38, 0x19, 0x58, 0x60, 0xf7, 0xd8, 0x32, 0xf6, 0x8f, 0x2d,
+	0xe3, 0xe0, 0xd8, 0x32, 0x5a, 0x79, 0x75, 0xbc, 0xbb, 0xbf, 0x07, 0x00, 0x61, 0xcb, 0x05, 0x83,
+	0x57, 0x08, 0x00, 0x00,
 }
 
 func (this *RulesRequest) Equal(that interface{}) bool {
@@ -445,6 +545,9 @@ func (this *RulesRequest) Equal(that interface{}) bool {
 	} else if this == nil {
 		return false
 	}
+	if this.IncludeEvaluationHistory != that1.IncludeEvaluationHistory {
+		return false
+	}
 	return true
 }
 func (this *RulesResponse) Equal(that interface{}) bool {
@@ -559,6 +662,50 @@ func (this *RuleStateDesc) Equal(that interface{}) bool {
 	if this.EvaluationDuration != that1.EvaluationDuration {
 		return false
 	}
+	if len(this.EvaluationHistory) != len(that1.EvaluationHistory) {
+		return false
+	}
+	for i := range this.EvaluationHistory {
+		if !this.EvaluationHistory[i].Equal(that1.EvaluationHistory[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (this *RuleEvaluationDesc) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*RuleEvaluationDesc)
+	if !ok {
+		that2, ok := that.(RuleEvaluationDesc)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !this.EvaluationTimestamp.Equal(that1.EvaluationTimestamp) {
+		return false
+	}
+	if this.EvaluationDuration != that1.EvaluationDuration {
+		return false
+	}
+	if this.Samples != that1.Samples {
+		return false
+	}
+	if this.LastError != that1.LastError {
+		return false
+	}
+	if this.FiringAlerts != that1.FiringAlerts {
+		return false
+	}
 	return true
 }
 func (this *AlertStateDesc) Equal(that interface{}) bool {
@@ -623,8 +770,9 @@ func (this *RulesRequest) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 4)
+	s := make([]string, 0, 5)
 	s = append(s, "&ruler.RulesRequest{")
+	s = append(s, "IncludeEvaluationHistory: "+fmt.Sprintf("%#v", this.IncludeEvaluationHistory)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -661,7 +809,7 @@ func (this *RuleStateDesc) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 11)
+	s := make([]string, 0, 12)
 	s = append(s, "&ruler.RuleStateDesc{")
 	if this.Rule != nil {
 		s = append(s, "Rule: "+fmt.Sprintf("%#v", this.Rule)+",\n")
@@ -674,6 +822,23 @@ func (this *RuleStateDesc) GoString() string {
 	}
 	s = append(s, "EvaluationTimestamp: "+fmt.Sprintf("%#v", this.EvaluationTimestamp)+",\n")
 	s = append(s, "EvaluationDuration: "+fmt.Sprintf("%#v", this.EvaluationDuration)+",\n")
+	if this.EvaluationHistory != nil {
+		s = append(s, "EvaluationHistory: "+fmt.Sprintf("%#v", this.EvaluationHistory)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *RuleEvaluationDesc) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 9)
+	s = append(s, "&ruler.RuleEvaluationDesc{")
+	s = append(s, "EvaluationTimestamp: "+fmt.Sprintf("%#v", this.EvaluationTimestamp)+",\n")
+	s = append(s, "EvaluationDuration: "+fmt.Sprintf("%#v", this.EvaluationDuration)+",\n")
+	s = append(s, "Samples: "+fmt.Sprintf("%#v", this.Samples)+",\n")
+	s = append(s, "LastError: "+fmt.Sprintf("%#v", this.LastError)+",\n")
+	s = append(s, "FiringAlerts: "+fmt.Sprintf("%#v", this.FiringAlerts)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -804,6 +969,16 @@ func (m *RulesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.IncludeEvaluationHistory {
+		i--
+		if m.IncludeEvaluationHistory {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x8
+	}
 	return len(dAtA) - i, nil
 }
 
@@ -929,6 +1104,20 @@ func (m *RuleStateDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.EvaluationHistory) > 0 {
+		for iNdEx := len(m.EvaluationHistory) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.EvaluationHistory[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintRuler(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x42
+		}
+	}
 	n4, err4 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.EvaluationDuration, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.EvaluationDuration):])
 	if err4 != nil {
 		return 0, err4
@@ -995,7 +1184,7 @@ func (m *RuleStateDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *AlertStateDesc) Marshal() (dAtA []byte, err error) {
+func (m *RuleEvaluationDesc) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -1005,55 +1194,111 @@ func (m *AlertStateDesc) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *AlertStateDesc) MarshalTo(dAtA []byte) (int, error) {
+func (m *RuleEvaluationDesc) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *AlertStateDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *RuleEvaluationDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	n7, err7 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ValidUntil, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.ValidUntil):])
+	if m.FiringAlerts != 0 {
+		i = encodeVarintRuler(dAtA, i, uint64(m.FiringAlerts))
+		i--
+		dAtA[i] = 0x28
+	}
+	if len(m.LastError) > 0 {
+		i -= len(m.LastError)
+		copy(dAtA[i:], m.LastError)
+		i = encodeVarintRuler(dAtA, i, uint64(len(m.LastError)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Samples != 0 {
+		i = encodeVarintRuler(dAtA, i, uint64(m.Samples))
+		i--
+		dAtA[i] = 0x18
+	}
+	n7, err7 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.EvaluationDuration, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.EvaluationDuration):])
 	if err7 != nil {
 		return 0, err7
 	}
 	i -= n7
 	i = encodeVarintRuler(dAtA, i, uint64(n7))
 	i--
-	dAtA[i] = 0x4a
-	n8, err8 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.LastSentAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.LastSentAt):])
+	dAtA[i] = 0x12
+	n8, err8 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.EvaluationTimestamp, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.EvaluationTimestamp):])
 	if err8 != nil {
 		return 0, err8
 	}
 	i -= n8
 	i = encodeVarintRuler(dAtA, i, uint64(n8))
 	i--
-	dAtA[i] = 0x42
-	n9, err9 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ResolvedAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.ResolvedAt):])
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
+func (m *AlertStateDesc) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *AlertStateDesc) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *AlertStateDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	n9, err9 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ValidUntil, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.ValidUntil):])
 	if err9 != nil {
 		return 0, err9
 	}
 	i -= n9
 	i = encodeVarintRuler(dAtA, i, uint64(n9))
 	i--
-	dAtA[i] = 0x3a
-	n10, err10 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.FiredAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.FiredAt):])
+	dAtA[i] = 0x4a
+	n10, err10 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.LastSentAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.LastSentAt):])
 	if err10 != nil {
 		return 0, err10
 	}
 	i -= n10
 	i = encodeVarintRuler(dAtA, i, uint64(n10))
 	i--
-	dAtA[i] = 0x32
-	n11, err11 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ActiveAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.ActiveAt):])
+	dAtA[i] = 0x42
+	n11, err11 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ResolvedAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.ResolvedAt):])
 	if err11 != nil {
 		return 0, err11
 	}
 	i -= n11
 	i = encodeVarintRuler(dAtA, i, uint64(n11))
 	i--
+	dAtA[i] = 0x3a
+	n12, err12 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.FiredAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.FiredAt):])
+	if err12 != nil {
+		return 0, err12
+	}
+	i -= n12
+	i = encodeVarintRuler(dAtA, i, uint64(n12))
+	i--
+	dAtA[i] = 0x32
+	n13, err13 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ActiveAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.ActiveAt):])
+	if err13 != nil {
+		return 0, err13
+	}
+	i -= n13
+	i = encodeVarintRuler(dAtA, i, uint64(n13))
+	i--
 	dAtA[i] = 0x2a
 	if m.Value != 0 {
 		i -= 8
@@ -1116,6 +1361,9 @@ func (m *RulesRequest) Size() (n int) {
 	}
 	var l int
 	_ = l
+	if m.IncludeEvaluationHistory {
+		n += 2
+	}
 	return n
 }
 
@@ -1189,6 +1437,35 @@ func (m *RuleStateDesc) Size() (n int) {
 	n += 1 + l + sovRuler(uint64(l))
 	l = github_com_gogo_protobuf_types.SizeOfStdDuration(m.EvaluationDuration)
 	n += 1 + l + sovRuler(uint64(l))
+	if len(m.EvaluationHistory) > 0 {
+		for _, e := range m.EvaluationHistory {
+			l = e.Size()
+			n += 1 + l + sovRuler(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *RuleEvaluationDesc) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = github_com_gogo_protobuf_types.SizeOfStdTime(m.EvaluationTimestamp)
+	n += 1 + l + sovRuler(uint64(l))
+	l = github_com_gogo_protobuf_types.SizeOfStdDuration(m.EvaluationDuration)
+	n += 1 + l + sovRuler(uint64(l))
+	if m.Samples != 0 {
+		n += 1 + sovRuler(uint64(m.Samples))
+	}
+	l = len(m.LastError)
+	if l > 0 {
+		n += 1 + l + sovRuler(uint64(l))
+	}
+	if m.FiringAlerts != 0 {
+		n += 1 + sovRuler(uint64(m.FiringAlerts))
+	}
 	return n
 }
 
@@ -1241,6 +1518,7 @@ func (this *RulesRequest) String() string {
 		return "nil"
 	}
 	s := strings.Join([]string{`&RulesRequest{`,
+		`IncludeEvaluationHistory:` + fmt.Sprintf("%v", this.IncludeEvaluationHistory) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1287,6 +1565,11 @@ func (this *RuleStateDesc) String() string {
 		repeatedStringForAlerts += strings.Replace(f.String(), "AlertStateDesc", "AlertStateDesc", 1) + ","
 	}
 	repeatedStringForAlerts += "}"
+	repeatedStringForEvaluationHistory := "[]*RuleEvaluationDesc{"
+	for _, f := range this.EvaluationHistory {
+		repeatedStringForEvaluationHistory += strings.Replace(f.String(), "RuleEvaluationDesc", "RuleEvaluationDesc", 1) + ","
+	}
+	repeatedStringForEvaluationHistory += "}"
 	s := strings.Join([]string{`&RuleStateDesc{`,
 		`Rule:` + strings.Replace(fmt.Sprintf("%v", this.Rule), "RuleDesc", "rulespb.RuleDesc", 1) + `,`,
 		`State:` + fmt.Sprintf("%v", this.State) + `,`,
@@ -1295,6 +1578,21 @@ func (this *RuleStateDesc) String() string {
 		`Alerts:` + repeatedStringForAlerts + `,`,
 		`EvaluationTimestamp:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.EvaluationTimestamp), "Timestamp", "timestamp.Timestamp", 1), `&`, ``, 1) + `,`,
 		`EvaluationDuration:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.EvaluationDuration), "Duration", "duration.Duration", 1), `&`, ``, 1) + `,`,
+		`EvaluationHistory:` + repeatedStringForEvaluationHistory + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *RuleEvaluationDesc) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&RuleEvaluationDesc{`,
+		`EvaluationTimestamp:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.EvaluationTimestamp), "Timestamp", "timestamp.Timestamp", 1), `&`, ``, 1) + `,`,
+		`EvaluationDuration:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.EvaluationDuration), "Duration", "duration.Duration", 1), `&`, ``, 1) + `,`,
+		`Samples:` + fmt.Sprintf("%v", this.Samples) + `,`,
+		`LastError:` + fmt.Sprintf("%v", this.LastError) + `,`,
+		`FiringAlerts:` + fmt.Sprintf("%v", this.FiringAlerts) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1354,16 +1652,33 @@ func (m *RulesRequest) Unmarshal(dAtA []byte) error {
 			return fmt.Errorf("proto: RulesRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IncludeEvaluationHistory", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IncludeEvaluationHistory = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRuler(dAtA[iNdEx:])
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthRuler
-			}
-			if (iNdEx + skippy) < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthRuler
 			}
 			if (iNdEx + skippy) > l {
@@ -1447,10 +1762,7 @@ func (m *RulesResponse) Unmarshal(dAtA []byte) error {
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthRuler
-			}
-			if (iNdEx + skippy) < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthRuler
 			}
 			if (iNdEx + skippy) > l {
@@ -1636,10 +1948,7 @@ func (m *GroupStateDesc) Unmarshal(dAtA []byte) error {
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthRuler
-			}
-			if (iNdEx + skippy) < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthRuler
 			}
 			if (iNdEx + skippy) > l {
@@ -1915,16 +2224,233 @@ func (m *RuleStateDesc) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EvaluationHistory", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRuler
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRuler
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.EvaluationHistory = append(m.EvaluationHistory, &RuleEvaluationDesc{})
+			if err := m.EvaluationHistory[len(m.EvaluationHistory)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRuler(dAtA[iNdEx:])
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthRuler
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *RuleEvaluationDesc) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowRuler
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: RuleEvaluationDesc: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: RuleEvaluationDesc: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EvaluationTimestamp", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRuler
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRuler
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdTimeUnmarshal(&m.EvaluationTimestamp, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EvaluationDuration", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRuler
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRuler
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdDurationUnmarshal(&m.EvaluationDuration, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Samples", wireType)
+			}
+			m.Samples = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Samples |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastError", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRuler
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthRuler
 			}
-			if (iNdEx + skippy) < 0 {
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LastError = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FiringAlerts", wireType)
+			}
+			m.FiringAlerts = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FiringAlerts |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipRuler(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthRuler
 			}
 			if (iNdEx + skippy) > l {
@@ -2250,10 +2776,7 @@ func (m *AlertStateDesc) Unmarshal(dAtA []byte) error {
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthRuler
-			}
-			if (iNdEx + skippy) < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthRuler
 			}
 			if (iNdEx + skippy) > l {
@@ -2271,6 +2794,7 @@ func (m *AlertStateDesc) Unmarshal(dAtA []byte) error {
 func skipRuler(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
+	depth := 0
 	for iNdEx < l {
 		var wire uint64
 		for shift := uint(0); ; shift += 7 {
@@ -2302,10 +2826,8 @@ func skipRuler(dAtA []byte) (n int, err error) {
 					break
 				}
 			}
-			return iNdEx, nil
 		case 1:
 			iNdEx += 8
-			return iNdEx, nil
 		case 2:
 			var length int
 			for shift := uint(0); ; shift += 7 {
@@ -2326,55 +2848,30 @@ func skipRuler(dAtA []byte) (n int, err error) {
 				return 0, ErrInvalidLengthRuler
 			}
 			iNdEx += length
-			if iNdEx < 0 {
-				return 0, ErrInvalidLengthRuler
-			}
-			return iNdEx, nil
 		case 3:
-			for {
-				var innerWire uint64
-				var start int = iNdEx
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return 0, ErrIntOverflowRuler
-					}
-					if iNdEx >= l {
-						return 0, io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					innerWire |= (uint64(b) & 0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				innerWireType := int(innerWire & 0x7)
-				if innerWireType == 4 {
-					break
-				}
-				next, err := skipRuler(dAtA[start:])
-				if err != nil {
-					return 0, err
-				}
-				iNdEx = start + next
-				if iNdEx < 0 {
-					return 0, ErrInvalidLengthRuler
-				}
-			}
-			return iNdEx, nil
+			depth++
 		case 4:
-			return iNdEx, nil
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupRuler
+			}
+			depth--
 		case 5:
 			iNdEx += 4
-			return iNdEx, nil
 		default:
 			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
 		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthRuler
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
 	}
-	panic("unreachable")
+	return 0, io.ErrUnexpectedEOF
 }
 
 var (
-	ErrInvalidLengthRuler = fmt.Errorf("proto: negative length found during unmarshaling")
-	ErrIntOverflowRuler   = fmt.Errorf("proto: integer overflow")
+	ErrInvalidLengthRuler        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowRuler          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupRuler = fmt.Errorf("proto: unexpected end of group")
 )
@@ -29,6 +29,7 @@ import (
 	"github.com/grafana/dskit/services"
 	"github.com/prometheus/client_golang/prometheus"
 	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/prometheus/prometheus/notifier"
@@ -80,6 +81,13 @@ type ruleLimits struct {
 	tenantShard          int
 	maxRulesPerRuleGroup int
 	maxRuleGroups        int
+	maxConcurrency       int64
+	backfillWindow       time.Duration
+	backfillRate         float64
+	outOfOrderTimeWindow time.Duration
+	minInterval          time.Duration
+	maxInterval          time.Duration
+	maxQueryTimeout      time.Duration
 }
 
 func (r ruleLimits) EvaluationDelay(_ string) time.Duration {
@@ -98,6 +106,34 @@ func (r ruleLimits) RulerMaxRulesPerRuleGroup(_ string) int {
 	return r.maxRulesPerRuleGroup
 }
 
+func (r ruleLimits) RulerMaxIndependentRuleEvaluationConcurrencyPerTenant(_ string) int64 {
+	return r.maxConcurrency
+}
+
+func (r ruleLimits) RulerMissedIterationsBackfillWindow(_ string) time.Duration {
+	return r.backfillWindow
+}
+
+func (r ruleLimits) RulerMissedIterationsBackfillRate(_ string) float64 {
+	return r.backfillRate
+}
+
+func (r ruleLimits) OutOfOrderTimeWindow(_ string) model.Duration {
+	return model.Duration(r.outOfOrderTimeWindow)
+}
+
+func (r ruleLimits) RulerMinRuleGroupInterval(_ string) time.Duration {
+	return r.minInterval
+}
+
+func (r ruleLimits) RulerMaxRuleGroupInterval(_ string) time.Duration {
+	return r.maxInterval
+}
+
+func (r ruleLimits) RulerMaxRuleGroupQueryTimeout(_ string) time.Duration {
+	return r.maxQueryTimeout
+}
+
 func testSetup() (storage.QueryableFunc, promRules.QueryFunc, Pusher, log.Logger, RulesLimits) {
 	noopQueryable := storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
 		return storage.NoopQuerier(), nil
@@ -416,7 +452,7 @@ func TestGetRules(t *testing.T) {
 			for u := range allRulesByUser {
 				ctx := user.InjectOrgID(context.Background(), u)
 				forEachRuler(func(_ string, r *Ruler) {
-					rules, err := r.GetRules(ctx)
+					rules, err := r.GetRules(ctx, &RulesRequest{})
 					require.NoError(t, err)
 					require.Equal(t, len(allRulesByUser[u]), len(rules))
 
@@ -26,6 +26,8 @@ func ToProto(user string, namespace string, rl rulefmt.RuleGroup) *RuleGroupDesc
 		User:          user,
 		SourceTenants: rl.SourceTenants,
 	}
+	// Encoding the evaluation delay in a protobuf Struct can't fail.
+	_ = rg.setEvaluationDelay(rl.EvaluationDelay)
 	return &rg
 }
 
@@ -54,6 +56,11 @@ func FromProto(rg *RuleGroupDesc) rulefmt.RuleGroup {
 		SourceTenants: rg.GetSourceTenants(),
 	}
 
+	// Rule groups with invalid options are still evaluated, without the options.
+	if delay, err := rg.getEvaluationDelay(); err == nil {
+		formattedRuleGroup.EvaluationDelay = delay
+	}
+
 	for i, rl := range rg.GetRules() {
 		exprNode := yaml.Node{}
 		exprNode.SetString(rl.GetExpr())
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package rulespb
+
+import (
+	"github.com/gogo/protobuf/types"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+const (
+	backfillMissedIterationsOption = "backfill_missed_iterations"
+	evaluationDelayOption          = "evaluation_delay"
+	queryTimeoutOption             = "query_timeout"
+)
+
+// RuleGroupOptions holds the options of a rule group which are specific to Mimir,
+// and not part of the Prometheus rule group format.
+type RuleGroupOptions struct {
+	// BackfillMissedIterations enables the re-evaluation of the missed iterations of the
+	// recording rules of the group.
+	BackfillMissedIterations bool `yaml:"backfill_missed_iterations,omitempty"`
+
+	// QueryTimeout is the maximum duration of the evaluation of the rules of the group.
+	QueryTimeout model.Duration `yaml:"query_timeout,omitempty"`
+}
+
+// RuleGroup is a Prometheus rule group along with its Mimir specific options.
+type RuleGroup struct {
+	rulefmt.RuleGroup `yaml:",inline"`
+	RuleGroupOptions  `yaml:",inline"`
+}
+
+// GetRuleGroupOptions returns the Mimir specific options of the rule group.
+func (m *RuleGroupDesc) GetRuleGroupOptions() (RuleGroupOptions, error) {
+	opts := RuleGroupOptions{}
+
+	s, err := m.optionsStruct()
+	if err != nil {
+		return opts, err
+	}
+	if s != nil {
+		if v, ok := s.Fields[backfillMissedIterationsOption]; ok {
+			opts.BackfillMissedIterations = v.GetBoolValue()
+		}
+		if v, ok := s.Fields[queryTimeoutOption]; ok {
+			d, err := model.ParseDuration(v.GetStringValue())
+			if err != nil {
+				return opts, errors.Wrap(err, "unable to decode the query timeout of the rule group")
+			}
+			opts.QueryTimeout = d
+		}
+	}
+
+	return opts, nil
+}
+
+// SetRuleGroupOptions replaces the Mimir specific options of the rule group.
+func (m *RuleGroupDesc) SetRuleGroupOptions(opts RuleGroupOptions) error {
+	fields := map[string]*types.Value{}
+	if opts.BackfillMissedIterations {
+		fields[backfillMissedIterationsOption] = &types.Value{Kind: &types.Value_BoolValue{BoolValue: true}}
+	}
+	if opts.QueryTimeout > 0 {
+		fields[queryTimeoutOption] = &types.Value{Kind: &types.Value_StringValue{StringValue: opts.QueryTimeout.String()}}
+	}
+
+	// The evaluation delay is part of the Prometheus rule group format, and is kept as is.
+	s, err := m.optionsStruct()
+	if err != nil {
+		return err
+	}
+	if v, ok := s.GetFields()[evaluationDelayOption]; ok {
+		fields[evaluationDelayOption] = v
+	}
+
+	return m.setOptionsStruct(fields)
+}
+
+// getEvaluationDelay returns the evaluation delay of the rule group, which isn't part of the rule group
+// protobuf, so it's stored along with the Mimir specific options.
+func (m *RuleGroupDesc) getEvaluationDelay() (*model.Duration, error) {
+	s, err := m.optionsStruct()
+	if err != nil {
+		return nil, err
+	}
+	v, ok := s.GetFields()[evaluationDelayOption]
+	if !ok {
+		return nil, nil
+	}
+	d, err := model.ParseDuration(v.GetStringValue())
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode the evaluation delay of the rule group")
+	}
+	return &d, nil
+}
+
+// setEvaluationDelay replaces the evaluation delay of the rule group, keeping the Mimir specific options.
+func (m *RuleGroupDesc) setEvaluationDelay(delay *model.Duration) error {
+	s, err := m.optionsStruct()
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]*types.Value{}
+	for name, v := range s.GetFields() {
+		if name != evaluationDelayOption {
+			fields[name] = v
+		}
+	}
+	if delay != nil {
+		fields[evaluationDelayOption] = &types.Value{Kind: &types.Value_StringValue{StringValue: delay.String()}}
+	}
+
+	return m.setOptionsStruct(fields)
+}
+
+// optionsStruct returns the protobuf Struct holding the options of the rule group, or nil if there's none.
+func (m *RuleGroupDesc) optionsStruct() (*types.Struct, error) {
+	for _, opt := range m.GetOptions() {
+		if !types.Is(opt, &types.Struct{}) {
+			continue
+		}
+
+		s := &types.Struct{}
+		if err := types.UnmarshalAny(opt, s); err != nil {
+			return nil, errors.Wrap(err, "unable to decode rule group options")
+		}
+		return s, nil
+	}
+	return nil, nil
+}
+
+func (m *RuleGroupDesc) setOptionsStruct(fields map[string]*types.Value) error {
+	m.Options = nil
+	if len(fields) == 0 {
+		return nil
+	}
+
+	opt, err := types.MarshalAny(&types.Struct{Fields: fields})
+	if err != nil {
+		return errors.Wrap(err, "unable to encode rule group options")
+	}
+	m.Options = []*types.Any{opt}
+	return nil
+}
+
+// FromProtoWithOptions generates a RuleGroup, including the Mimir specific options of the rule group.
+func FromProtoWithOptions(rg *RuleGroupDesc) (RuleGroup, error) {
+	opts, err := rg.GetRuleGroupOptions()
+	if err != nil {
+		return RuleGroup{}, err
+	}
+	return RuleGroup{RuleGroup: FromProto(rg), RuleGroupOptions: opts}, nil
+}
+
+// FormattedWithOptions returns the rule group list as a set of formatted rule groups, including their
+// Mimir specific options, mapped by namespace.
+func (l RuleGroupList) FormattedWithOptions() (map[string][]RuleGroup, error) {
+	ruleMap := map[string][]RuleGroup{}
+	for _, g := range l {
+		rg, err := FromProtoWithOptions(g)
+		if err != nil {
+			return nil, err
+		}
+		ruleMap[g.Namespace] = append(ruleMap[g.Namespace], rg)
+	}
+	return ruleMap, nil
+}
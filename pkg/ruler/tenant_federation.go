@@ -3,15 +3,7 @@
 package ruler
 
 import (
-	"context"
 	"flag"
-	"time"
-
-	"github.com/prometheus/prometheus/promql"
-	"github.com/prometheus/prometheus/rules"
-	"github.com/weaveworks/common/user"
-
-	"github.com/grafana/dskit/tenant"
 
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
 )
@@ -26,35 +18,6 @@ func (cfg *TenantFederationConfig) RegisterFlags(f *flag.FlagSet) {
 
 type contextKey int
 
-const federatedGroupSourceTenants contextKey = 1
-
-// FederatedGroupContextFunc prepares the context for federated rules.
-// It injects g.SourceTenants() in to the context to be used by mergeQuerier.
-func FederatedGroupContextFunc(ctx context.Context, g *rules.Group) context.Context {
-	if len(g.SourceTenants()) == 0 {
-		return ctx
-	}
-	return context.WithValue(ctx, federatedGroupSourceTenants, g.SourceTenants())
-}
-
-// ExtractTenantIDs gets the rule group org ID from the context.
-func ExtractTenantIDs(ctx context.Context) (string, error) {
-	if sourceTenants, _ := ctx.Value(federatedGroupSourceTenants).([]string); len(sourceTenants) > 0 {
-		return tenant.JoinTenantIDs(tenant.NormalizeTenantIDs(sourceTenants)), nil
-	}
-	return tenant.TenantID(ctx)
-}
-
-func TenantFederationQueryFunc(regularQueryable, federatedQueryable rules.QueryFunc) rules.QueryFunc {
-	return func(ctx context.Context, q string, t time.Time) (promql.Vector, error) {
-		if sourceTenants, _ := ctx.Value(federatedGroupSourceTenants).([]string); len(sourceTenants) > 0 {
-			ctx = user.InjectOrgID(ctx, tenant.JoinTenantIDs(tenant.NormalizeTenantIDs(sourceTenants)))
-			return federatedQueryable(ctx, q, t)
-		}
-		return regularQueryable(ctx, q, t)
-	}
-}
-
 func RemoveFederatedRuleGroups(groups map[string]rulespb.RuleGroupList) {
 	for userID, groupList := range groups {
 		amended := make(rulespb.RuleGroupList, 0, len(groupList))
@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tenantfederation
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/weaveworks/common/user"
+)
+
+type contextKey int
+
+const sourceTenantsContextKey contextKey = 0
+
+// GroupContextFunc prepares the context for federated rules.
+// It injects g.SourceTenants() in to the context to be used by mergeQuerier.
+func GroupContextFunc(ctx context.Context, g *rules.Group) context.Context {
+	return ContextWithSourceTenants(ctx, g.SourceTenants())
+}
+
+// ContextWithSourceTenants returns a context to run the queries of a rule group whose source tenants are
+// sourceTenants. The context is returned unchanged if the rule group is not federated.
+func ContextWithSourceTenants(ctx context.Context, sourceTenants []string) context.Context {
+	if len(sourceTenants) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, sourceTenantsContextKey, sourceTenants)
+}
+
+// ExtractTenantIDs gets the rule group org ID from the context.
+func ExtractTenantIDs(ctx context.Context) (string, error) {
+	if sourceTenants, _ := ctx.Value(sourceTenantsContextKey).([]string); len(sourceTenants) > 0 {
+		return tenant.JoinTenantIDs(tenant.NormalizeTenantIDs(sourceTenants)), nil
+	}
+	return tenant.TenantID(ctx)
+}
+
+// QueryFunc returns a rules.QueryFunc running the queries of federated rule groups through federatedQueryable,
+// with the source tenants of the rule group as org ID, and the queries of the other rule groups through
+// regularQueryable.
+func QueryFunc(regularQueryable, federatedQueryable rules.QueryFunc) rules.QueryFunc {
+	return func(ctx context.Context, q string, t time.Time) (promql.Vector, error) {
+		if sourceTenants, _ := ctx.Value(sourceTenantsContextKey).([]string); len(sourceTenants) > 0 {
+			ctx = user.InjectOrgID(ctx, tenant.JoinTenantIDs(tenant.NormalizeTenantIDs(sourceTenants)))
+			return federatedQueryable(ctx, q, t)
+		}
+		return regularQueryable(ctx, q, t)
+	}
+}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tenantfederation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+)
+
+func TestQueryFunc(t *testing.T) {
+	var queryable, orgID string
+	newQueryFunc := func(name string) func(ctx context.Context, q string, ts time.Time) (promql.Vector, error) {
+		return func(ctx context.Context, q string, ts time.Time) (promql.Vector, error) {
+			var err error
+			queryable = name
+			orgID, err = tenant.TenantID(ctx)
+			return nil, err
+		}
+	}
+	queryFunc := QueryFunc(newQueryFunc("regular"), newQueryFunc("federated"))
+
+	ctx := user.InjectOrgID(context.Background(), "tenant-1")
+
+	_, err := queryFunc(ctx, "up", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "regular", queryable)
+	assert.Equal(t, "tenant-1", orgID)
+
+	orgID, err = ExtractTenantIDs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-1", orgID)
+
+	ctx = ContextWithSourceTenants(ctx, []string{"tenant-3", "tenant-2"})
+
+	_, err = queryFunc(ctx, "up", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "federated", queryable)
+	assert.Equal(t, "tenant-2|tenant-3", orgID)
+
+	orgID, err = ExtractTenantIDs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-2|tenant-3", orgID)
+}
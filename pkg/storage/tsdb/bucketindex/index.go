@@ -25,6 +25,7 @@ const (
 	IndexCompressedFilename = IndexFilename + ".gz"
 	IndexVersion1           = 1
 	IndexVersion2           = 2 // Added CompactorShardID field.
+	IndexVersion3           = 3 // Added Resolution and BlockNoCompactMarks fields.
 	SegmentsFormatUnknown   = ""
 
 	// SegmentsFormat1Based6Digits defined segments numbered with 6 digits numbers in a sequence starting from number 1
@@ -43,6 +44,9 @@ type Index struct {
 	// List of block deletion marks.
 	BlockDeletionMarks BlockDeletionMarks `json:"block_deletion_marks"`
 
+	// List of block no-compact marks.
+	BlockNoCompactMarks BlockNoCompactMarks `json:"block_no_compact_marks"`
+
 	// UpdatedAt is a unix timestamp (seconds precision) of when the index has been updated
 	// (written in the storage) the last time.
 	UpdatedAt int64 `json:"updated_at"`
@@ -52,7 +56,7 @@ func (idx *Index) GetUpdatedAt() time.Time {
 	return time.Unix(idx.UpdatedAt, 0)
 }
 
-// RemoveBlock removes block and its deletion mark (if any) from index.
+// RemoveBlock removes block and its deletion and no-compact marks (if any) from index.
 func (idx *Index) RemoveBlock(id ulid.ULID) {
 	for i := 0; i < len(idx.Blocks); i++ {
 		if idx.Blocks[i].ID == id {
@@ -67,6 +71,13 @@ func (idx *Index) RemoveBlock(id ulid.ULID) {
 			break
 		}
 	}
+
+	for i := 0; i < len(idx.BlockNoCompactMarks); i++ {
+		if idx.BlockNoCompactMarks[i].ID == id {
+			idx.BlockNoCompactMarks = append(idx.BlockNoCompactMarks[:i], idx.BlockNoCompactMarks[i+1:]...)
+			break
+		}
+	}
 }
 
 // Block holds the information about a block in the index.
@@ -91,6 +102,9 @@ type Block struct {
 
 	// Block's compactor shard ID, copied from tsdb.CompactorShardIDExternalLabel label.
 	CompactorShardID string `json:"compactor_shard_id,omitempty"`
+
+	// Block's downsampling resolution (millis precision). Zero for raw blocks.
+	Resolution int64 `json:"resolution,omitempty"`
 }
 
 // Within returns whether the block contains samples within the provided range.
@@ -118,6 +132,7 @@ func (m *Block) ThanosMeta() *metadata.Meta {
 		Thanos: metadata.Thanos{
 			Version:      metadata.ThanosVersion1,
 			SegmentFiles: m.thanosMetaSegmentFiles(),
+			Downsample:   metadata.ThanosDownsample{Resolution: m.Resolution},
 		},
 	}
 }
@@ -154,6 +169,7 @@ func BlockFromThanosMeta(meta metadata.Meta) *Block {
 		SegmentsFormat:   segmentsFormat,
 		SegmentsNum:      segmentsNum,
 		CompactorShardID: meta.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel],
+		Resolution:       meta.Thanos.Downsample.Resolution,
 	}
 }
 
@@ -246,6 +262,43 @@ func (s BlockDeletionMarks) Clone() BlockDeletionMarks {
 	return clone
 }
 
+// BlockNoCompactMark holds the information about a block's no-compact mark in the index.
+type BlockNoCompactMark struct {
+	// Block ID.
+	ID ulid.ULID `json:"block_id"`
+
+	// NoCompactTime is a unix timestamp (seconds precision) of when the block was marked for no-compaction.
+	NoCompactTime int64 `json:"no_compact_time"`
+
+	// Reason and Details explain why the block has been excluded from compaction.
+	Reason  metadata.NoCompactReason `json:"reason"`
+	Details string                   `json:"details,omitempty"`
+}
+
+func (m *BlockNoCompactMark) GetNoCompactTime() time.Time {
+	return time.Unix(m.NoCompactTime, 0)
+}
+
+func BlockNoCompactMarkFromThanosMarker(mark *metadata.NoCompactMark) *BlockNoCompactMark {
+	return &BlockNoCompactMark{
+		ID:            mark.ID,
+		NoCompactTime: mark.NoCompactTime,
+		Reason:        mark.Reason,
+		Details:       mark.Details,
+	}
+}
+
+// BlockNoCompactMarks holds a set of block no-compact marks in the index. No ordering guaranteed.
+type BlockNoCompactMarks []*BlockNoCompactMark
+
+func (s BlockNoCompactMarks) GetULIDs() []ulid.ULID {
+	ids := make([]ulid.ULID, len(s))
+	for i, m := range s {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
 // Blocks holds a set of blocks in the index. No ordering guaranteed.
 type Blocks []*Block
 
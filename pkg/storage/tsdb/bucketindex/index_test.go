@@ -21,13 +21,15 @@ func TestIndex_RemoveBlock(t *testing.T) {
 	block2 := ulid.MustNew(2, nil)
 	block3 := ulid.MustNew(3, nil)
 	idx := &Index{
-		Blocks:             Blocks{{ID: block1}, {ID: block2}, {ID: block3}},
-		BlockDeletionMarks: BlockDeletionMarks{{ID: block2}, {ID: block3}},
+		Blocks:              Blocks{{ID: block1}, {ID: block2}, {ID: block3}},
+		BlockDeletionMarks:  BlockDeletionMarks{{ID: block2}, {ID: block3}},
+		BlockNoCompactMarks: BlockNoCompactMarks{{ID: block1}, {ID: block2}},
 	}
 
 	idx.RemoveBlock(block2)
 	assert.ElementsMatch(t, []ulid.ULID{block1, block3}, idx.Blocks.GetULIDs())
 	assert.ElementsMatch(t, []ulid.ULID{block3}, idx.BlockDeletionMarks.GetULIDs())
+	assert.ElementsMatch(t, []ulid.ULID{block1}, idx.BlockNoCompactMarks.GetULIDs())
 }
 
 func TestDetectBlockSegmentsFormat(t *testing.T) {
@@ -143,6 +145,24 @@ func TestBlockFromThanosMeta(t *testing.T) {
 		meta     metadata.Meta
 		expected Block
 	}{
+		"meta.json of a downsampled block": {
+			meta: metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:    blockID,
+					MinTime: 10,
+					MaxTime: 20,
+				},
+				Thanos: metadata.Thanos{
+					Downsample: metadata.ThanosDownsample{Resolution: 3600000},
+				},
+			},
+			expected: Block{
+				ID:         blockID,
+				MinTime:    10,
+				MaxTime:    20,
+				Resolution: 3600000,
+			},
+		},
 		"meta.json without SegmentFiles and Files": {
 			meta: metadata.Meta{
 				BlockMeta: tsdb.BlockMeta{
@@ -378,6 +398,26 @@ func TestBlock_ThanosMeta(t *testing.T) {
 				},
 			},
 		},
+		"downsampled block": {
+			block: Block{
+				ID:         blockID,
+				MinTime:    10,
+				MaxTime:    20,
+				Resolution: 300000,
+			},
+			expected: &metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:    blockID,
+					MinTime: 10,
+					MaxTime: 20,
+					Version: metadata.TSDBVersion1,
+				},
+				Thanos: metadata.Thanos{
+					Version:    metadata.ThanosVersion1,
+					Downsample: metadata.ThanosDownsample{Resolution: 300000},
+				},
+			},
+		},
 	}
 
 	for testName, testData := range tests {
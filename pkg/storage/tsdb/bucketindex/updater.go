@@ -30,6 +30,9 @@ var (
 	ErrBlockMetaCorrupted         = block.ErrorSyncMetaCorrupted
 	ErrBlockDeletionMarkNotFound  = errors.New("block deletion mark not found")
 	ErrBlockDeletionMarkCorrupted = errors.New("block deletion mark corrupted")
+
+	ErrBlockNoCompactMarkNotFound  = errors.New("block no-compact mark not found")
+	ErrBlockNoCompactMarkCorrupted = errors.New("block no-compact mark corrupted")
 )
 
 // Updater is responsible to generate an update in-memory bucket index.
@@ -50,11 +53,13 @@ func NewUpdater(bkt objstore.Bucket, userID string, cfgProvider bucket.TenantCon
 func (w *Updater) UpdateIndex(ctx context.Context, old *Index) (*Index, map[ulid.ULID]error, error) {
 	var oldBlocks []*Block
 	var oldBlockDeletionMarks []*BlockDeletionMark
+	var oldBlockNoCompactMarks []*BlockNoCompactMark
 
 	// Use the old index if provided, and it is using the latest version format.
-	if old != nil && old.Version == IndexVersion2 {
+	if old != nil && old.Version == IndexVersion3 {
 		oldBlocks = old.Blocks
 		oldBlockDeletionMarks = old.BlockDeletionMarks
+		oldBlockNoCompactMarks = old.BlockNoCompactMarks
 	}
 
 	blocks, partials, err := w.updateBlocks(ctx, oldBlocks)
@@ -67,11 +72,17 @@ func (w *Updater) UpdateIndex(ctx context.Context, old *Index) (*Index, map[ulid
 		return nil, nil, err
 	}
 
+	blockNoCompactMarks, err := w.updateBlockNoCompactMarks(ctx, oldBlockNoCompactMarks)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	return &Index{
-		Version:            IndexVersion2,
-		Blocks:             blocks,
-		BlockDeletionMarks: blockDeletionMarks,
-		UpdatedAt:          time.Now().Unix(),
+		Version:             IndexVersion3,
+		Blocks:              blocks,
+		BlockDeletionMarks:  blockDeletionMarks,
+		BlockNoCompactMarks: blockNoCompactMarks,
+		UpdatedAt:           time.Now().Unix(),
 	}, partials, nil
 }
 
@@ -228,3 +239,65 @@ func (w *Updater) updateBlockDeletionMarkIndexEntry(ctx context.Context, id ulid
 
 	return BlockDeletionMarkFromThanosMarker(&m), nil
 }
+
+func (w *Updater) updateBlockNoCompactMarks(ctx context.Context, old []*BlockNoCompactMark) ([]*BlockNoCompactMark, error) {
+	out := make([]*BlockNoCompactMark, 0, len(old))
+	discovered := map[ulid.ULID]struct{}{}
+
+	// Find all markers in the storage.
+	err := w.bkt.Iter(ctx, MarkersPathname+"/", func(name string) error {
+		if blockID, ok := IsNoCompactMarkFilename(path.Base(name)); ok {
+			discovered[blockID] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list block no-compact marks")
+	}
+
+	// No-compact marks are never updated in place (they're deleted and re-created instead),
+	// so all markers already existing in the index can just be copied.
+	for _, m := range old {
+		if _, ok := discovered[m.ID]; ok {
+			out = append(out, m)
+			delete(discovered, m.ID)
+		}
+	}
+
+	// Remaining markers are new ones and we have to fetch them.
+	for id := range discovered {
+		m, err := w.updateBlockNoCompactMarkIndexEntry(ctx, id)
+		if errors.Is(err, ErrBlockNoCompactMarkNotFound) {
+			// This could happen if the mark is deleted between the "list objects" and now.
+			level.Warn(w.logger).Log("msg", "skipped missing block no-compact mark when updating bucket index", "block", id.String())
+			continue
+		}
+		if errors.Is(err, ErrBlockNoCompactMarkCorrupted) {
+			level.Error(w.logger).Log("msg", "skipped corrupted block no-compact mark when updating bucket index", "block", id.String(), "err", err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, m)
+	}
+
+	return out, nil
+}
+
+func (w *Updater) updateBlockNoCompactMarkIndexEntry(ctx context.Context, id ulid.ULID) (*BlockNoCompactMark, error) {
+	m := metadata.NoCompactMark{}
+
+	if err := metadata.ReadMarker(ctx, w.logger, w.bkt, id.String(), &m); err != nil {
+		if errors.Is(err, metadata.ErrorMarkerNotFound) {
+			return nil, errors.Wrap(ErrBlockNoCompactMarkNotFound, err.Error())
+		}
+		if errors.Is(err, metadata.ErrorUnmarshalMarker) {
+			return nil, errors.Wrap(ErrBlockNoCompactMarkCorrupted, err.Error())
+		}
+		return nil, err
+	}
+
+	return BlockNoCompactMarkFromThanosMarker(&m), nil
+}
@@ -37,7 +37,7 @@ func TestUpdater_UpdateIndex(t *testing.T) {
 	// Generate the initial index.
 	bkt = BucketWithGlobalMarkers(bkt)
 	block1 := testutil.MockStorageBlockWithExtLabels(t, bkt, userID, 10, 20, nil)
-	testutil.MockNoCompactMark(t, bkt, userID, block1.BlockMeta) // no-compact mark is ignored by bucket index updater.
+	testutil.MockNoCompactMark(t, bkt, userID, block1.BlockMeta) // no-compact marks are tracked separately, see TestUpdater_UpdateIndex_ShouldIncludeNoCompactMarks.
 	block2 := testutil.MockStorageBlockWithExtLabels(t, bkt, userID, 20, 30, map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "1_of_5"})
 	block2Mark := testutil.MockStorageDeletionMark(t, bkt, userID, block2.BlockMeta)
 
@@ -157,6 +157,40 @@ func TestUpdater_UpdateIndex_ShouldSkipCorruptedDeletionMarks(t *testing.T) {
 	assert.Empty(t, partials)
 }
 
+func TestUpdater_UpdateIndex_ShouldIncludeNoCompactMarks(t *testing.T) {
+	const userID = "user-1"
+
+	bkt, _ := testutil.PrepareFilesystemBucket(t)
+
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	// Mock some blocks in the storage.
+	bkt = BucketWithGlobalMarkers(bkt)
+	block1 := testutil.MockStorageBlockWithExtLabels(t, bkt, userID, 10, 20, nil)
+	block2 := testutil.MockStorageBlockWithExtLabels(t, bkt, userID, 20, 30, nil)
+	block3 := testutil.MockStorageBlockWithExtLabels(t, bkt, userID, 30, 40, nil)
+	block1Mark := testutil.MockNoCompactMark(t, bkt, userID, block1.BlockMeta)
+	block2Mark := testutil.MockNoCompactMark(t, bkt, userID, block2.BlockMeta)
+
+	// Overwrite a block's no-compact-mark.json with invalid data.
+	require.NoError(t, bkt.Upload(ctx, path.Join(userID, block2Mark.ID.String(), metadata.NoCompactMarkFilename), bytes.NewReader([]byte("invalid!}"))))
+
+	w := NewUpdater(bkt, userID, nil, logger)
+	idx, _, err := w.UpdateIndex(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, BlockNoCompactMarks{BlockNoCompactMarkFromThanosMarker(block1Mark)}, idx.BlockNoCompactMarks)
+
+	// Mark another block, remove the no-compact mark of the first one and update the index.
+	block3Mark := testutil.MockNoCompactMark(t, bkt, userID, block3.BlockMeta)
+	require.NoError(t, bkt.Delete(ctx, path.Join(userID, block1.ULID.String(), metadata.NoCompactMarkFilename)))
+
+	idx, _, err = w.UpdateIndex(ctx, idx)
+	require.NoError(t, err)
+	assert.Equal(t, BlockNoCompactMarks{BlockNoCompactMarkFromThanosMarker(block3Mark)}, idx.BlockNoCompactMarks)
+	assert.Equal(t, metadata.ManualNoCompactReason, idx.BlockNoCompactMarks[0].Reason)
+}
+
 func TestUpdater_UpdateIndex_NoTenantInTheBucket(t *testing.T) {
 	const userID = "user-1"
 
@@ -168,7 +202,7 @@ func TestUpdater_UpdateIndex_NoTenantInTheBucket(t *testing.T) {
 		idx, partials, err := w.UpdateIndex(ctx, oldIdx)
 
 		require.NoError(t, err)
-		assert.Equal(t, IndexVersion2, idx.Version)
+		assert.Equal(t, IndexVersion3, idx.Version)
 		assert.InDelta(t, time.Now().Unix(), idx.UpdatedAt, 2)
 		assert.Len(t, idx.Blocks, 0)
 		assert.Len(t, idx.BlockDeletionMarks, 0)
@@ -239,7 +273,7 @@ func getBlockUploadedAt(t testing.TB, bkt objstore.Bucket, userID string, blockI
 }
 
 func assertBucketIndexEqual(t testing.TB, idx *Index, bkt objstore.Bucket, userID string, expectedBlocks []metadata.Meta, expectedDeletionMarks []*metadata.DeletionMark) {
-	assert.Equal(t, IndexVersion2, idx.Version)
+	assert.Equal(t, IndexVersion3, idx.Version)
 	assert.InDelta(t, time.Now().Unix(), idx.UpdatedAt, 2)
 
 	// Build the list of expected block index entries.
@@ -251,6 +285,7 @@ func assertBucketIndexEqual(t testing.TB, idx *Index, bkt objstore.Bucket, userI
 			MaxTime:          b.MaxTime,
 			UploadedAt:       getBlockUploadedAt(t, bkt, userID, b.ULID),
 			CompactorShardID: b.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel],
+			Resolution:       b.Thanos.Downsample.Resolution,
 		})
 	}
 
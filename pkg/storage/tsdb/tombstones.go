@@ -0,0 +1,250 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// TombstonesPath is the location of series deletion requests, relative to user-specific prefix.
+const TombstonesPath = "tombstones"
+
+// TombstoneState is the state of a series deletion request.
+type TombstoneState string
+
+const (
+	// TombstonePending is the state of a deletion request which is applied at query time,
+	// and physically removed by the compactor once the cancel period has elapsed.
+	TombstonePending TombstoneState = "pending"
+
+	// TombstoneCancelled is the state of a deletion request which has been cancelled by the tenant.
+	TombstoneCancelled TombstoneState = "cancelled"
+
+	// TombstoneProcessed is the state of a deletion request whose samples have been physically removed
+	// from all blocks by the compactor, and is therefore no longer applied at query time.
+	TombstoneProcessed TombstoneState = "processed"
+)
+
+var errInvalidTombstoneTimeRange = errors.New("tombstone start time must be before or equal to end time")
+
+// Tombstone is a series deletion request, stored in the bucket.
+type Tombstone struct {
+	RequestID string         `json:"request_id"`
+	State     TombstoneState `json:"state"`
+
+	// Unix timestamp (milliseconds) when the deletion request was created.
+	RequestCreatedAt int64 `json:"request_created_at"`
+	// Unix timestamp (milliseconds) when the deletion request transitioned to the current state.
+	StateCreatedAt int64 `json:"state_created_at"`
+
+	// Time range of the samples to delete, in milliseconds. Both ends are inclusive.
+	StartTime int64    `json:"start_time"`
+	EndTime   int64    `json:"end_time"`
+	Selectors []string `json:"selectors"`
+
+	// Parsed selectors. Populated by ParseMatchers.
+	matchers [][]*labels.Matcher
+}
+
+// NewTombstone creates a new pending tombstone, and parses its selectors.
+func NewTombstone(createdAt, startTime, endTime int64, selectors []string) (*Tombstone, error) {
+	if startTime > endTime {
+		return nil, errInvalidTombstoneTimeRange
+	}
+
+	t := &Tombstone{
+		RequestID:        TombstoneRequestID(startTime, endTime, selectors),
+		State:            TombstonePending,
+		RequestCreatedAt: createdAt,
+		StateCreatedAt:   createdAt,
+		StartTime:        startTime,
+		EndTime:          endTime,
+		Selectors:        selectors,
+	}
+
+	if err := t.ParseMatchers(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// TombstoneRequestID returns a deterministic ID for a deletion request, so that
+// submitting the same request twice doesn't create two tombstones.
+func TombstoneRequestID(startTime, endTime int64, selectors []string) string {
+	sorted := append([]string(nil), selectors...)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	_, _ = h.Write([]byte(strconv.FormatInt(startTime, 10)))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(strconv.FormatInt(endTime, 10)))
+	for _, s := range sorted {
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(s))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ParseMatchers parses the tombstone selectors. It must be called before using a tombstone
+// read from the bucket.
+func (t *Tombstone) ParseMatchers() error {
+	t.matchers = make([][]*labels.Matcher, 0, len(t.Selectors))
+	for _, s := range t.Selectors {
+		ms, err := parser.ParseMetricSelector(s)
+		if err != nil {
+			return errors.Wrapf(err, "parse selector %q", s)
+		}
+		t.matchers = append(t.matchers, ms)
+	}
+	return nil
+}
+
+// Matchers returns the parsed selectors.
+func (t *Tombstone) Matchers() [][]*labels.Matcher {
+	return t.matchers
+}
+
+// Overlaps returns whether the tombstone deletes any sample in the [mint, maxt] time range.
+func (t *Tombstone) Overlaps(mint, maxt int64) bool {
+	return t.StartTime <= maxt && mint <= t.EndTime
+}
+
+// Matches returns whether the series with the given labels is selected by the tombstone.
+func (t *Tombstone) Matches(lbls labels.Labels) bool {
+	for _, ms := range t.matchers {
+		if matchesAll(ms, lbls) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAll(ms []*labels.Matcher, lbls labels.Labels) bool {
+	for _, m := range ms {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Tombstones is a list of series deletion requests.
+type Tombstones []*Tombstone
+
+// Overlapping returns the tombstones deleting samples in the [mint, maxt] time range.
+func (ts Tombstones) Overlapping(mint, maxt int64) Tombstones {
+	var out Tombstones
+	for _, t := range ts {
+		if t.Overlaps(mint, maxt) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// DeletedIntervals returns the time intervals deleted for the series with the given labels.
+func (ts Tombstones) DeletedIntervals(lbls labels.Labels) tombstones.Intervals {
+	var intervals tombstones.Intervals
+	for _, t := range ts {
+		if t.Matches(lbls) {
+			intervals = intervals.Add(tombstones.Interval{Mint: t.StartTime, Maxt: t.EndTime})
+		}
+	}
+	return intervals
+}
+
+func tombstoneFilePath(requestID string) string {
+	return path.Join(TombstonesPath, requestID+".json")
+}
+
+// WriteTombstone uploads the tombstone to the tenant location in the bucket, overwriting any previous version.
+func WriteTombstone(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, t *Tombstone) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return errors.Wrap(err, "serialize tombstone")
+	}
+
+	return errors.Wrap(bkt.Upload(ctx, tombstoneFilePath(t.RequestID), bytes.NewReader(data)), "upload tombstone")
+}
+
+// ReadTombstone returns the tombstone with the given request ID. If it doesn't exist, returns nil tombstone, and no error.
+func ReadTombstone(ctx context.Context, bkt objstore.BucketReader, userID, requestID string) (*Tombstone, error) {
+	t, err := readTombstone(ctx, bkt, path.Join(userID, tombstoneFilePath(requestID)))
+	if bkt.IsObjNotFoundErr(errors.Cause(err)) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// ReadTombstones returns all tombstones of the given tenant, in any state.
+func ReadTombstones(ctx context.Context, bkt objstore.BucketReader, userID string) (Tombstones, error) {
+	var out Tombstones
+
+	err := bkt.Iter(ctx, path.Join(userID, TombstonesPath)+objstore.DirDelim, func(name string) error {
+		if !strings.HasSuffix(name, ".json") {
+			return nil
+		}
+
+		t, err := readTombstone(ctx, bkt, name)
+		if bkt.IsObjNotFoundErr(errors.Cause(err)) {
+			// Deleted in the meanwhile.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		out = append(out, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func readTombstone(ctx context.Context, bkt objstore.BucketReader, name string) (*Tombstone, error) {
+	r, err := bkt.Get(ctx, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read tombstone object: %s", name)
+	}
+
+	t := &Tombstone{}
+	err = json.NewDecoder(r).Decode(t)
+
+	// Close reader before dealing with decode error.
+	if closeErr := r.Close(); closeErr != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to close bucket reader", "err", closeErr)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode tombstone object: %s", name)
+	}
+
+	if err := t.ParseMatchers(); err != nil {
+		return nil, errors.Wrapf(err, "invalid tombstone object: %s", name)
+	}
+
+	return t, nil
+}
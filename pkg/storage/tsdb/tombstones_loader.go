@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+// TombstonesCacheTTL is how long the tombstones of a tenant are cached before being reloaded from the bucket.
+const TombstonesCacheTTL = time.Minute
+
+// TombstonesLoader returns the pending series deletion requests of a tenant.
+type TombstonesLoader interface {
+	GetTombstones(ctx context.Context, userID string) (Tombstones, error)
+}
+
+type cachedTombstones struct {
+	tombstones Tombstones
+	loadedAt   time.Time
+}
+
+// BucketTombstonesLoader loads tombstones from the bucket, and caches them in memory.
+type BucketTombstonesLoader struct {
+	bkt    objstore.BucketReader
+	ttl    time.Duration
+	logger log.Logger
+
+	mtx   sync.Mutex
+	cache map[string]cachedTombstones
+
+	loadFailures prometheus.Counter
+}
+
+// NewBucketTombstonesLoader creates a new BucketTombstonesLoader caching the tombstones of each tenant for the ttl.
+func NewBucketTombstonesLoader(bkt objstore.BucketReader, ttl time.Duration, logger log.Logger, reg prometheus.Registerer) *BucketTombstonesLoader {
+	return &BucketTombstonesLoader{
+		bkt:    bkt,
+		ttl:    ttl,
+		logger: logger,
+		cache:  map[string]cachedTombstones{},
+		loadFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_tombstones_load_failures_total",
+			Help: "Total number of failures while loading series deletion requests from the storage.",
+		}),
+	}
+}
+
+// GetTombstones implements TombstonesLoader. If tombstones can't be reloaded, the previously
+// loaded ones are returned, so that deleted series are not returned by queries.
+func (l *BucketTombstonesLoader) GetTombstones(ctx context.Context, userID string) (Tombstones, error) {
+	all, err := l.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(Tombstones, 0, len(all))
+	for _, t := range all {
+		if t.State == TombstonePending {
+			pending = append(pending, t)
+		}
+	}
+	return pending, nil
+}
+
+// GetTombstonesGeneration returns an identifier of the series deletion requests of the tenant which are not
+// cancelled. It changes whenever a request is created or cancelled, but not when a request is processed, and
+// is empty if the tenant has no such request.
+func (l *BucketTombstonesLoader) GetTombstonesGeneration(ctx context.Context, userID string) (string, error) {
+	all, err := l.load(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	ids := make([]string, 0, len(all))
+	for _, t := range all {
+		if t.State != TombstoneCancelled {
+			ids = append(ids, t.RequestID)
+		}
+	}
+	if len(ids) == 0 {
+		return "", nil
+	}
+	sort.Strings(ids)
+
+	h := sha256.New()
+	for _, id := range ids {
+		_, _ = h.Write([]byte(id))
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// load returns the tombstones of the tenant in any state, from the cache if they've been loaded since less than the TTL.
+func (l *BucketTombstonesLoader) load(ctx context.Context, userID string) (Tombstones, error) {
+	l.mtx.Lock()
+	cached, ok := l.cache[userID]
+	l.mtx.Unlock()
+
+	if ok && time.Since(cached.loadedAt) < l.ttl {
+		return cached.tombstones, nil
+	}
+
+	all, err := ReadTombstones(ctx, l.bkt, userID)
+	if err != nil {
+		l.loadFailures.Inc()
+		if ok {
+			level.Warn(l.logger).Log("msg", "failed to reload series deletion requests, using previously loaded ones", "user", userID, "err", err)
+			return cached.tombstones, nil
+		}
+		return nil, errors.Wrap(err, "failed to load series deletion requests")
+	}
+
+	l.mtx.Lock()
+	l.cache[userID] = cachedTombstones{tombstones: all, loadedAt: time.Now()}
+	l.mtx.Unlock()
+
+	return all, nil
+}
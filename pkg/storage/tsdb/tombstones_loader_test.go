@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+func TestBucketTombstonesLoader(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	pending, err := NewTombstone(0, 10, 20, []string{`{job="a"}`})
+	require.NoError(t, err)
+	cancelled, err := NewTombstone(0, 10, 20, []string{`{job="b"}`})
+	require.NoError(t, err)
+	cancelled.State = TombstoneCancelled
+
+	require.NoError(t, WriteTombstone(ctx, bkt, "user", nil, pending))
+	require.NoError(t, WriteTombstone(ctx, bkt, "user", nil, cancelled))
+
+	loader := NewBucketTombstonesLoader(bkt, time.Hour, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	actual, err := loader.GetTombstones(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, Tombstones{pending}, actual)
+
+	// Cached tombstones are returned until the TTL expires.
+	other, err := NewTombstone(0, 10, 20, []string{`{job="c"}`})
+	require.NoError(t, err)
+	require.NoError(t, WriteTombstone(ctx, bkt, "user", nil, other))
+
+	actual, err = loader.GetTombstones(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, Tombstones{pending}, actual)
+
+	loader.ttl = 0
+	actual, err = loader.GetTombstones(ctx, "user")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, Tombstones{pending, other}, actual)
+}
+
+func TestBucketTombstonesLoader_GetTombstonesGeneration(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	loader := NewBucketTombstonesLoader(bkt, 0, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	// No series deletion request.
+	gen, err := loader.GetTombstonesGeneration(ctx, "user")
+	require.NoError(t, err)
+	assert.Empty(t, gen)
+
+	first, err := NewTombstone(0, 10, 20, []string{`{job="a"}`})
+	require.NoError(t, err)
+	require.NoError(t, WriteTombstone(ctx, bkt, "user", nil, first))
+
+	firstGen, err := loader.GetTombstonesGeneration(ctx, "user")
+	require.NoError(t, err)
+	assert.NotEmpty(t, firstGen)
+
+	// Processing a request doesn't change the generation.
+	first.State = TombstoneProcessed
+	require.NoError(t, WriteTombstone(ctx, bkt, "user", nil, first))
+
+	gen, err = loader.GetTombstonesGeneration(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, firstGen, gen)
+
+	// Creating a request changes the generation.
+	second, err := NewTombstone(0, 10, 20, []string{`{job="b"}`})
+	require.NoError(t, err)
+	require.NoError(t, WriteTombstone(ctx, bkt, "user", nil, second))
+
+	gen, err = loader.GetTombstonesGeneration(ctx, "user")
+	require.NoError(t, err)
+	assert.NotEqual(t, firstGen, gen)
+
+	// Cancelling a request changes the generation back.
+	second.State = TombstoneCancelled
+	require.NoError(t, WriteTombstone(ctx, bkt, "user", nil, second))
+
+	gen, err = loader.GetTombstonesGeneration(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, firstGen, gen)
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/objstore"
+)
+
+func TestNewTombstone(t *testing.T) {
+	_, err := NewTombstone(0, 20, 10, []string{`{job="test"}`})
+	require.Equal(t, errInvalidTombstoneTimeRange, err)
+
+	_, err = NewTombstone(0, 10, 20, []string{`{job=`})
+	require.Error(t, err)
+
+	ts, err := NewTombstone(5, 10, 20, []string{`{job="test"}`, `up`})
+	require.NoError(t, err)
+	assert.Equal(t, TombstonePending, ts.State)
+	assert.Len(t, ts.Matchers(), 2)
+
+	// The request ID doesn't depend on the order of selectors.
+	assert.Equal(t, ts.RequestID, TombstoneRequestID(10, 20, []string{`up`, `{job="test"}`}))
+	assert.NotEqual(t, ts.RequestID, TombstoneRequestID(10, 21, []string{`up`, `{job="test"}`}))
+}
+
+func TestTombstones_DeletedIntervals(t *testing.T) {
+	first, err := NewTombstone(0, 10, 20, []string{`{job="a"}`})
+	require.NoError(t, err)
+	second, err := NewTombstone(0, 15, 30, []string{`{job="a", instance="1"}`, `{job="b"}`})
+	require.NoError(t, err)
+
+	ts := Tombstones{first, second}
+
+	assert.Equal(t, Tombstones{second}, ts.Overlapping(25, 40))
+	assert.Empty(t, ts.Overlapping(31, 40))
+
+	assert.Equal(t, tombstones.Intervals{{Mint: 10, Maxt: 20}}, ts.DeletedIntervals(labels.FromStrings("job", "a", "instance", "2")))
+	assert.Equal(t, tombstones.Intervals{{Mint: 10, Maxt: 30}}, ts.DeletedIntervals(labels.FromStrings("job", "a", "instance", "1")))
+	assert.Equal(t, tombstones.Intervals{{Mint: 15, Maxt: 30}}, ts.DeletedIntervals(labels.FromStrings("job", "b")))
+	assert.Empty(t, ts.DeletedIntervals(labels.FromStrings("job", "c")))
+}
+
+func TestWriteAndReadTombstones(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	first, err := NewTombstone(1, 10, 20, []string{`{job="a"}`})
+	require.NoError(t, err)
+	second, err := NewTombstone(2, 10, 30, []string{`{job="b"}`})
+	require.NoError(t, err)
+
+	require.NoError(t, WriteTombstone(ctx, bkt, "user-1", nil, first))
+	require.NoError(t, WriteTombstone(ctx, bkt, "user-1", nil, second))
+	require.NoError(t, WriteTombstone(ctx, bkt, "user-2", nil, second))
+
+	actual, err := ReadTombstone(ctx, bkt, "user-1", first.RequestID)
+	require.NoError(t, err)
+	assert.Equal(t, first, actual)
+
+	actual, err = ReadTombstone(ctx, bkt, "user-1", "unknown")
+	require.NoError(t, err)
+	assert.Nil(t, actual)
+
+	all, err := ReadTombstones(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, Tombstones{first, second}, all)
+
+	all, err = ReadTombstones(ctx, bkt, "user-3")
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
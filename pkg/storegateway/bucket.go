@@ -35,11 +35,13 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/prometheus/prometheus/tsdb/encoding"
 	"github.com/prometheus/prometheus/tsdb/hashcache"
 	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/compact/downsample"
@@ -132,6 +134,9 @@ type BucketStore struct {
 
 	// Enables hints in the Series() response.
 	enableSeriesResponseHints bool
+
+	// Loads the series deletion requests whose samples are removed from the Series() response.
+	tombstonesLoader mimir_tsdb.TombstonesLoader
 }
 
 type noopCache struct{}
@@ -202,6 +207,13 @@ func WithChunkPool(chunkPool pool.Bytes) BucketStoreOption {
 	}
 }
 
+// WithTombstonesLoader sets the loader of the series deletion requests applied to the Series() response.
+func WithTombstonesLoader(loader mimir_tsdb.TombstonesLoader) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.tombstonesLoader = loader
+	}
+}
+
 // WithDebugLogging enables debug logging.
 func WithDebugLogging() BucketStoreOption {
 	return func(s *BucketStore) {
@@ -949,6 +961,17 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 		}
 	}
 
+	// The samples deleted by the pending series deletion requests are removed from the response, so that they're
+	// not returned until the compactor has rewritten the blocks and the store-gateway doesn't load the old ones anymore.
+	var deletions mimir_tsdb.Tombstones
+	if s.tombstonesLoader != nil {
+		all, err := s.tombstonesLoader.GetTombstones(ctx, s.userID)
+		if err != nil {
+			return status.Error(codes.Internal, errors.Wrap(err, "load series deletion requests").Error())
+		}
+		deletions = all.Overlapping(req.MinTime, req.MaxTime)
+	}
+
 	gspan, gctx := tracing.StartSpan(gctx, "bucket_store_preload_all")
 
 	s.mtx.RLock()
@@ -1078,7 +1101,24 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 				lset, _ = set.At()
 			} else {
 				lset, series.Chunks = set.At()
+			}
 
+			if intervals := deletions.DeletedIntervals(lset); len(intervals) > 0 {
+				if (tombstones.Interval{Mint: req.MinTime, Maxt: req.MaxTime}).IsSubrange(intervals) {
+					continue
+				}
+				if !req.SkipChunks {
+					if series.Chunks, err = removeDeletedSamples(series.Chunks, intervals); err != nil {
+						err = status.Error(codes.Internal, errors.Wrap(err, "remove deleted samples").Error())
+						return
+					}
+					if len(series.Chunks) == 0 {
+						continue
+					}
+				}
+			}
+
+			if !req.SkipChunks {
 				stats.mergedChunksCount += len(series.Chunks)
 				s.metrics.chunkSizeBytes.Observe(float64(chunksSize(series.Chunks)))
 			}
@@ -1115,6 +1155,92 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 	return err
 }
 
+// removeDeletedSamples removes the samples in the deleted intervals from the chunks. The chunks partially deleted
+// are re-encoded, and the ones fully deleted are dropped.
+func removeDeletedSamples(chks []storepb.AggrChunk, intervals tombstones.Intervals) ([]storepb.AggrChunk, error) {
+	out := chks[:0]
+
+	for _, chk := range chks {
+		if (tombstones.Interval{Mint: chk.MinTime, Maxt: chk.MaxTime}).IsSubrange(intervals) {
+			continue
+		}
+		if !overlapsIntervals(chk.MinTime, chk.MaxTime, intervals) {
+			out = append(out, chk)
+			continue
+		}
+
+		// The raw chunk, or each aggregate of the downsampled chunk, is re-encoded.
+		mint, maxt := int64(math.MaxInt64), int64(math.MinInt64)
+		for _, c := range []**storepb.Chunk{&chk.Raw, &chk.Count, &chk.Sum, &chk.Min, &chk.Max, &chk.Counter} {
+			if *c == nil {
+				continue
+			}
+
+			rewritten, cmint, cmaxt, err := removeDeletedChunkSamples(*c, intervals)
+			if err != nil {
+				return nil, err
+			}
+			*c = rewritten
+			if rewritten != nil {
+				mint, maxt = util_math.Min64(mint, cmint), util_math.Max64(maxt, cmaxt)
+			}
+		}
+
+		if mint > maxt {
+			// All the samples of the chunk have been deleted.
+			continue
+		}
+		chk.MinTime, chk.MaxTime = mint, maxt
+		out = append(out, chk)
+	}
+
+	return out, nil
+}
+
+// removeDeletedChunkSamples returns the XOR chunk without the samples in the deleted intervals, and the time range
+// of the remaining samples. Returns a nil chunk if all the samples have been deleted.
+func removeDeletedChunkSamples(in *storepb.Chunk, intervals tombstones.Intervals) (*storepb.Chunk, int64, int64, error) {
+	if in.Type != storepb.Chunk_XOR {
+		return nil, 0, 0, errors.Errorf("unsupported chunk encoding %d", in.Type)
+	}
+
+	c, err := chunkenc.FromData(chunkenc.EncXOR, in.Data)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	out := chunkenc.NewXORChunk()
+	app, err := out.Appender()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	it := &tsdb.DeletedIterator{Iter: c.Iterator(nil), Intervals: intervals}
+	mint, maxt := int64(math.MaxInt64), int64(math.MinInt64)
+	for it.Next() {
+		t, v := it.At()
+		app.Append(t, v)
+		mint, maxt = util_math.Min64(mint, t), util_math.Max64(maxt, t)
+	}
+	if err := it.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+
+	if out.NumSamples() == 0 {
+		return nil, 0, 0, nil
+	}
+	return &storepb.Chunk{Type: storepb.Chunk_XOR, Data: out.Bytes()}, mint, maxt, nil
+}
+
+func overlapsIntervals(mint, maxt int64, intervals tombstones.Intervals) bool {
+	for _, i := range intervals {
+		if i.Mint <= maxt && mint <= i.Maxt {
+			return true
+		}
+	}
+	return false
+}
+
 func chunksSize(chks []storepb.AggrChunk) (size int) {
 	for _, chk := range chks {
 		size += chk.Size() // This gets the encoded proto size.
@@ -1555,16 +1681,19 @@ func (s *bucketBlockSet) getFor(mint, maxt, maxResolutionMillis int64, blockMatc
 			break
 		}
 
-		if i+1 < len(s.resolutions) {
-			bs = append(bs, s.getFor(start, b.meta.MinTime-1, s.resolutions[i+1], blockMatchers)...)
+		// Include the block in the list of matching ones only if there are no block-level matchers
+		// or they actually match. A block not matching doesn't cover its time range, which is then
+		// filled with higher resolution blocks, so that the querier can choose which resolution to
+		// query for each time range.
+		if len(blockMatchers) > 0 && !b.matchLabels(blockMatchers) {
+			continue
 		}
 
-		// Include the block in the list of matching ones only if there are no block-level matchers
-		// or they actually match.
-		if len(blockMatchers) == 0 || b.matchLabels(blockMatchers) {
-			bs = append(bs, b)
+		if i+1 < len(s.resolutions) {
+			bs = append(bs, s.getFor(start, b.meta.MinTime-1, s.resolutions[i+1], blockMatchers)...)
 		}
 
+		bs = append(bs, b)
 		start = b.meta.MaxTime
 	}
 
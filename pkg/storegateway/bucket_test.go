@@ -425,6 +425,41 @@ func TestBucketBlockSet_remove(t *testing.T) {
 	assert.Equal(t, input[2].id, res[1].meta.ULID)
 }
 
+func TestBucketBlockSet_getFor_WithBlockMatchers(t *testing.T) {
+	set := newBucketBlockSet()
+
+	type resBlock struct {
+		id         ulid.ULID
+		window     int64
+		mint, maxt int64
+	}
+	input := []resBlock{
+		{id: ulid.MustNew(1, nil), window: downsample.ResLevel0, mint: 0, maxt: 100},
+		{id: ulid.MustNew(2, nil), window: downsample.ResLevel0, mint: 100, maxt: 200},
+		{id: ulid.MustNew(3, nil), window: downsample.ResLevel1, mint: 0, maxt: 100},
+		{id: ulid.MustNew(4, nil), window: downsample.ResLevel1, mint: 100, maxt: 200},
+	}
+
+	for _, in := range input {
+		var m metadata.Meta
+		m.ULID = in.id
+		m.Thanos.Downsample.Resolution = in.window
+		m.MinTime = in.mint
+		m.MaxTime = in.maxt
+		assert.NoError(t, set.add(&bucketBlock{meta: &m, blockLabels: labels.FromStrings(block.BlockIDLabel, in.id.String())}))
+	}
+
+	// The not matching downsampled block doesn't cover its time range, which is filled with the raw block.
+	matchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchRegexp, block.BlockIDLabel, input[1].id.String()+"|"+input[2].id.String()),
+	}
+
+	res := set.getFor(0, 200, downsample.ResLevel1, matchers)
+	require.Len(t, res, 2)
+	assert.Equal(t, input[2].id, res[0].meta.ULID)
+	assert.Equal(t, input[1].id, res[1].meta.ULID)
+}
+
 // Regression tests against: https://github.com/thanos-io/thanos/issues/1983.
 func TestReadIndexCache_LoadSeries(t *testing.T) {
 	bkt := objstore.NewInMemBucket()
@@ -1808,6 +1843,147 @@ func TestSeries_BlockWithMultipleChunks(t *testing.T) {
 	}
 }
 
+type staticTombstonesLoader mimir_tsdb.Tombstones
+
+func (l staticTombstonesLoader) GetTombstones(context.Context, string) (mimir_tsdb.Tombstones, error) {
+	return mimir_tsdb.Tombstones(l), nil
+}
+
+func TestSeries_DeletedSamples(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	headOpts := tsdb.DefaultHeadOptions()
+	headOpts.ChunkDirRoot = filepath.Join(tmpDir, "block")
+	headOpts.ChunkRange = math.MaxInt64
+
+	h, err := tsdb.NewHead(nil, nil, nil, nil, headOpts, nil)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, h.Close()) }()
+
+	for _, series := range []labels.Labels{labels.FromStrings("__name__", "test", "job", "a"), labels.FromStrings("__name__", "test", "job", "b")} {
+		for ts := int64(0); ts < 1000; ts++ {
+			app := h.Appender(context.Background())
+			_, err := app.Append(0, series, ts, float64(ts))
+			require.NoError(t, err)
+			require.NoError(t, app.Commit())
+		}
+	}
+
+	blk := createBlockFromHead(t, headOpts.ChunkDirRoot, h)
+	_, err = metadata.InjectThanos(log.NewNopLogger(), filepath.Join(headOpts.ChunkDirRoot, blk.String()), metadata.Thanos{
+		Labels:     labels.Labels{{Name: "ext1", Value: "1"}}.Map(),
+		Downsample: metadata.ThanosDownsample{Resolution: 0},
+		Source:     metadata.TestSource,
+	}, nil)
+	require.NoError(t, err)
+
+	bkt, err := filesystem.NewBucket(filepath.Join(tmpDir, "bucket"))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, bkt.Close()) }()
+
+	instrBkt := objstore.WithNoopInstr(bkt)
+	logger := log.NewNopLogger()
+	require.NoError(t, block.Upload(context.Background(), logger, bkt, filepath.Join(headOpts.ChunkDirRoot, blk.String()), metadata.NoneFunc))
+
+	fetcher, err := block.NewMetaFetcher(logger, 10, instrBkt, tmpDir, nil, nil)
+	require.NoError(t, err)
+
+	// The samples of the series "a" are deleted in the [100, 499] and [900, 2000] time ranges.
+	partial, err := mimir_tsdb.NewTombstone(0, 100, 499, []string{`{job="a"}`})
+	require.NoError(t, err)
+	end, err := mimir_tsdb.NewTombstone(0, 900, 2000, []string{`{job="a"}`})
+	require.NoError(t, err)
+
+	store, err := NewBucketStore(
+		"tenant",
+		instrBkt,
+		fetcher,
+		tmpDir,
+		NewChunksLimiterFactory(0),
+		NewSeriesLimiterFactory(0),
+		newGapBasedPartitioner(mimir_tsdb.DefaultPartitionerMaxGapSize, nil),
+		10,
+		mimir_tsdb.DefaultPostingOffsetInMemorySampling,
+		indexheader.BinaryReaderConfig{},
+		true,
+		false,
+		0,
+		hashcache.NewSeriesHashCache(1024*1024),
+		NewBucketStoreMetrics(nil),
+		WithLogger(logger),
+		WithTombstonesLoader(staticTombstonesLoader{partial, end}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, store.SyncBlocks(context.Background()))
+
+	tests := map[string]struct {
+		reqMinTime      int64
+		reqMaxTime      int64
+		skipChunks      bool
+		expectedSamples map[string]int
+	}{
+		"query the entire block": {
+			reqMinTime:      0,
+			reqMaxTime:      999,
+			expectedSamples: map[string]int{"a": 500, "b": 1000},
+		},
+		"query a partially deleted time range": {
+			reqMinTime:      50,
+			reqMaxTime:      150,
+			expectedSamples: map[string]int{"a": 50, "b": 101},
+		},
+		"query a fully deleted time range": {
+			reqMinTime:      900,
+			reqMaxTime:      999,
+			expectedSamples: map[string]int{"b": 100},
+		},
+		"query the series of a fully deleted time range": {
+			reqMinTime:      900,
+			reqMaxTime:      999,
+			skipChunks:      true,
+			expectedSamples: map[string]int{"b": 0},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			req := &storepb.SeriesRequest{
+				MinTime:    testData.reqMinTime,
+				MaxTime:    testData.reqMaxTime,
+				SkipChunks: testData.skipChunks,
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "test"},
+				},
+			}
+
+			srv := newBucketStoreSeriesServer(context.Background())
+			require.NoError(t, store.Series(req, srv))
+
+			actual := map[string]int{}
+			for _, series := range srv.SeriesSet {
+				job := labelpb.ZLabelsToPromLabels(series.Labels).Get("job")
+				actual[job] = 0
+
+				for _, chk := range series.Chunks {
+					decoded, err := chunkenc.FromData(chunkenc.EncXOR, chk.Raw.Data)
+					require.NoError(t, err)
+
+					it := decoded.Iterator(nil)
+					for it.Next() {
+						ts, _ := it.At()
+						if ts >= testData.reqMinTime && ts <= testData.reqMaxTime {
+							actual[job]++
+						}
+						assert.False(t, job == "a" && ((ts >= 100 && ts <= 499) || ts >= 900), "deleted sample %d returned", ts)
+					}
+					require.NoError(t, it.Err())
+				}
+			}
+			assert.Equal(t, testData.expectedSamples, actual)
+		})
+	}
+}
+
 func mustMarshalAny(pb proto.Message) *types.Any {
 	out, err := types.MarshalAny(pb)
 	if err != nil {
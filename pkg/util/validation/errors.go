@@ -287,6 +287,12 @@ func NewIngestionRateLimitedError(limit float64, burst int) LimitError {
 		ingestionRateFlag, ingestionBurstSizeFlag))
 }
 
+func NewRulerIngestionRateLimitedError(limit float64, burst int) LimitError {
+	return LimitError(globalerror.RulerIngestionRateLimited.MessageWithLimitConfig(
+		fmt.Sprintf("the rule results have been rejected because the tenant exceeded the ruler ingestion rate limit, set to %v samples/s with a maximum allowed burst of %d. This limit is applied on the total number of samples of the rule results received across all distributors", limit, burst),
+		rulerIngestionRateFlag, rulerIngestionBurstSizeFlag))
+}
+
 // formatLabelSet formats label adapters as a metric name with labels, while preserving
 // label order, and keeping duplicates. If there are multiple "__name__" labels, only
 // first one is used as metric name, other ones will be included as regular labels.
@@ -22,6 +22,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/ingester/activeseries"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 )
 
 const (
@@ -43,6 +44,9 @@ const (
 	ingestionRateFlag          = "distributor.ingestion-rate-limit"
 	ingestionBurstSizeFlag     = "distributor.ingestion-burst-size"
 	HATrackerMaxClustersFlag   = "distributor.ha-tracker.max-clusters"
+
+	rulerIngestionRateFlag      = "ruler.ingestion-rate-limit"
+	rulerIngestionBurstSizeFlag = "ruler.ingestion-burst-size"
 )
 
 // LimitError are errors that do not comply with the limits specified.
@@ -119,21 +123,32 @@ type Limits struct {
 	LabelValuesMaxCardinalityLabelNamesPerRequest int  `yaml:"label_values_max_cardinality_label_names_per_request" json:"label_values_max_cardinality_label_names_per_request"`
 
 	// Ruler defaults and limits.
-	RulerEvaluationDelay        model.Duration `yaml:"ruler_evaluation_delay_duration" json:"ruler_evaluation_delay_duration"`
-	RulerTenantShardSize        int            `yaml:"ruler_tenant_shard_size" json:"ruler_tenant_shard_size"`
-	RulerMaxRulesPerRuleGroup   int            `yaml:"ruler_max_rules_per_rule_group" json:"ruler_max_rules_per_rule_group"`
-	RulerMaxRuleGroupsPerTenant int            `yaml:"ruler_max_rule_groups_per_tenant" json:"ruler_max_rule_groups_per_tenant"`
+	RulerEvaluationDelay                                  model.Duration `yaml:"ruler_evaluation_delay_duration" json:"ruler_evaluation_delay_duration"`
+	RulerTenantShardSize                                  int            `yaml:"ruler_tenant_shard_size" json:"ruler_tenant_shard_size"`
+	RulerMaxRulesPerRuleGroup                             int            `yaml:"ruler_max_rules_per_rule_group" json:"ruler_max_rules_per_rule_group"`
+	RulerMaxRuleGroupsPerTenant                           int            `yaml:"ruler_max_rule_groups_per_tenant" json:"ruler_max_rule_groups_per_tenant"`
+	RulerMaxIndependentRuleEvaluationConcurrencyPerTenant int64          `yaml:"ruler_max_independent_rule_evaluation_concurrency_per_tenant" json:"ruler_max_independent_rule_evaluation_concurrency_per_tenant" category:"experimental"`
+	RulerMissedIterationsBackfillWindow                   model.Duration `yaml:"ruler_missed_iterations_backfill_window" json:"ruler_missed_iterations_backfill_window" category:"experimental"`
+	RulerMissedIterationsBackfillRate                     float64        `yaml:"ruler_missed_iterations_backfill_rate" json:"ruler_missed_iterations_backfill_rate" category:"experimental"`
+	RulerMinRuleGroupInterval                             model.Duration `yaml:"ruler_min_rule_group_interval" json:"ruler_min_rule_group_interval" category:"experimental"`
+	RulerMaxRuleGroupInterval                             model.Duration `yaml:"ruler_max_rule_group_interval" json:"ruler_max_rule_group_interval" category:"experimental"`
+	RulerMaxRuleGroupQueryTimeout                         model.Duration `yaml:"ruler_max_rule_group_query_timeout" json:"ruler_max_rule_group_query_timeout" category:"experimental"`
+	RulerIngestionRate                                    float64        `yaml:"ruler_ingestion_rate" json:"ruler_ingestion_rate" category:"experimental"`
+	RulerIngestionBurstSize                               int            `yaml:"ruler_ingestion_burst_size" json:"ruler_ingestion_burst_size" category:"experimental"`
 
 	// Store-gateway.
 	StoreGatewayTenantShardSize int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
 
 	// Compactor.
-	CompactorBlocksRetentionPeriod     model.Duration `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
-	CompactorSplitAndMergeShards       int            `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
-	CompactorSplitGroups               int            `yaml:"compactor_split_groups" json:"compactor_split_groups"`
-	CompactorTenantShardSize           int            `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
-	CompactorPartialBlockDeletionDelay model.Duration `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
-	CompactorBlockUploadEnabled        bool           `yaml:"compactor_block_upload_enabled" json:"compactor_block_upload_enabled"`
+	CompactorBlocksRetentionPeriod        model.Duration          `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
+	CompactorSplitAndMergeShards          int                     `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
+	CompactorSplitGroups                  int                     `yaml:"compactor_split_groups" json:"compactor_split_groups"`
+	CompactorTenantShardSize              int                     `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
+	CompactorPartialBlockDeletionDelay    model.Duration          `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
+	CompactorBlockUploadEnabled           bool                    `yaml:"compactor_block_upload_enabled" json:"compactor_block_upload_enabled"`
+	CompactorBlockUploadValidationEnabled bool                    `yaml:"compactor_block_upload_validation_enabled" json:"compactor_block_upload_validation_enabled" category:"experimental"`
+	CompactorSeriesDeletionDelay          model.Duration          `yaml:"compactor_series_deletion_delay" json:"compactor_series_deletion_delay" category:"experimental"`
+	CompactorDownsamplingResolutions      mimir_tsdb.DurationList `yaml:"compactor_downsampling_resolutions" json:"compactor_downsampling_resolutions" category:"experimental"`
 
 	// This config doesn't have a CLI flag registered here because they're registered in
 	// their own original config struct.
@@ -207,6 +222,15 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.RulerTenantShardSize, "ruler.tenant-shard-size", 0, "The tenant's shard size when sharding is used by ruler. Value of 0 disables shuffle sharding for the tenant, and tenant rules will be sharded across all ruler replicas.")
 	f.IntVar(&l.RulerMaxRulesPerRuleGroup, "ruler.max-rules-per-rule-group", 20, "Maximum number of rules per rule group per-tenant. 0 to disable.")
 	f.IntVar(&l.RulerMaxRuleGroupsPerTenant, "ruler.max-rule-groups-per-tenant", 70, "Maximum number of rule groups per-tenant. 0 to disable.")
+	f.Int64Var(&l.RulerMaxIndependentRuleEvaluationConcurrencyPerTenant, "ruler.max-independent-rule-evaluation-concurrency-per-tenant", 4, "Maximum number of rules per tenant which don't depend on the output of other rules of their group and can be evaluated concurrently. Concurrent rule evaluation must be enabled with -ruler.max-global-rule-evaluation-concurrency. 0 to disable.")
+	_ = l.RulerMissedIterationsBackfillWindow.Set("1h")
+	f.Var(&l.RulerMissedIterationsBackfillWindow, "ruler.missed-iterations-backfill-window", "How far back the missed iterations of the recording rules of the rule groups with backfilling enabled are re-evaluated. The window is also limited by the tenant's out-of-order time window, which must be enabled for backfilling to happen. 0 to disable.")
+	f.Float64Var(&l.RulerMissedIterationsBackfillRate, "ruler.missed-iterations-backfill-rate", 1, "Maximum number of missed rule group iterations re-evaluated per second per-tenant. The missed iterations are re-evaluated in the background, separately from the evaluation of the rule groups. 0 for no limit.")
+	f.Var(&l.RulerMinRuleGroupInterval, "ruler.min-rule-group-interval", "Minimum evaluation interval of the rule groups per-tenant. Rule groups with a lower interval are rejected. 0 to disable.")
+	f.Var(&l.RulerMaxRuleGroupInterval, "ruler.max-rule-group-interval", "Maximum evaluation interval of the rule groups per-tenant. Rule groups with a higher interval are rejected. 0 to disable.")
+	f.Var(&l.RulerMaxRuleGroupQueryTimeout, "ruler.max-rule-group-query-timeout", "Maximum duration of the evaluation of the rules of a rule group per-tenant. Rule groups with a higher query timeout are rejected, and the evaluation of the rule groups without a query timeout is cancelled after this duration. 0 to disable.")
+	f.Float64Var(&l.RulerIngestionRate, rulerIngestionRateFlag, 0, "Per-tenant ingestion rate limit of the rule results in samples per second. When enabled, the rule results are limited by this rate limit instead of the request and ingestion rate limits of the tenant. 0 to limit the rule results with the request and ingestion rate limits of the tenant.")
+	f.IntVar(&l.RulerIngestionBurstSize, rulerIngestionBurstSizeFlag, 200000, "Per-tenant allowed ingestion burst size of the rule results (in number of samples).")
 
 	f.Var(&l.CompactorBlocksRetentionPeriod, "compactor.blocks-retention-period", "Delete blocks containing samples older than the specified retention period. 0 to disable.")
 	f.IntVar(&l.CompactorSplitAndMergeShards, "compactor.split-and-merge-shards", 0, "The number of shards to use when splitting blocks. 0 to disable splitting.")
@@ -214,6 +238,10 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.CompactorTenantShardSize, "compactor.compactor-tenant-shard-size", 0, "Max number of compactors that can compact blocks for single tenant. 0 to disable the limit and use all compactors.")
 	f.Var(&l.CompactorPartialBlockDeletionDelay, "compactor.partial-block-deletion-delay", fmt.Sprintf("If a partial block (unfinished block without %s file) hasn't been modified for this time, it will be marked for deletion. 0 to disable.", block.MetaFilename))
 	f.BoolVar(&l.CompactorBlockUploadEnabled, "compactor.block-upload-enabled", false, "Enable block upload API for the tenant.")
+	f.BoolVar(&l.CompactorBlockUploadValidationEnabled, "compactor.block-upload-validation-enabled", false, "Validate the index, chunks and labels of blocks uploaded through the block upload API before marking the upload as complete.")
+	_ = l.CompactorSeriesDeletionDelay.Set("24h")
+	f.Var(&l.CompactorSeriesDeletionDelay, "compactor.series-deletion-delay", "Time after a series deletion request has been created before the compactor starts physically removing the deleted series from blocks. Deletion requests can be cancelled until this delay has elapsed. Deleted series are filtered out at query time immediately.")
+	f.Var(&l.CompactorDownsamplingResolutions, "compactor.downsampling-resolutions", "Comma-separated list of resolutions the compactor downsamples the tenant's blocks to, once they have been compacted to the largest block range. Supported resolutions are 5m and 1h. Empty to disable downsampling.")
 
 	// Store-gateway.
 	f.IntVar(&l.StoreGatewayTenantShardSize, "store-gateway.tenant-shard-size", 0, "The tenant's shard size, used when store-gateway sharding is enabled. Value of 0 disables shuffle sharding for the tenant, that is all tenant blocks are sharded across all store-gateway replicas.")
@@ -236,6 +264,29 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.AlertmanagerMaxAlertsSizeBytes, "alertmanager.max-alerts-size-bytes", 0, "Maximum total size of alerts that a single tenant can have, alert size is the sum of the bytes of its labels, annotations and generatorURL. Inserting more alerts will fail with a log message and metric increment. 0 = no limit.")
 }
 
+// Validate validates the limits, which are either the default limits or the limits of a tenant.
+func (l *Limits) Validate() error {
+	for _, shardSize := range []struct {
+		name  string
+		value int
+	}{
+		{"ingestion_tenant_shard_size", l.IngestionTenantShardSize},
+		{"ruler_tenant_shard_size", l.RulerTenantShardSize},
+		{"store_gateway_tenant_shard_size", l.StoreGatewayTenantShardSize},
+		{"compactor_tenant_shard_size", l.CompactorTenantShardSize},
+	} {
+		if shardSize.value < 0 {
+			return fmt.Errorf("invalid %s %d, the value must be greater or equal to 0", shardSize.name, shardSize.value)
+		}
+	}
+
+	if l.RulerMinRuleGroupInterval > 0 && l.RulerMaxRuleGroupInterval > 0 && l.RulerMinRuleGroupInterval > l.RulerMaxRuleGroupInterval {
+		return fmt.Errorf("invalid ruler_min_rule_group_interval %s, the value must be lower or equal to ruler_max_rule_group_interval %s", l.RulerMinRuleGroupInterval, l.RulerMaxRuleGroupInterval)
+	}
+
+	return nil
+}
+
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
 func (l *Limits) UnmarshalYAML(value *yaml.Node) error {
 	// We want to set l to the defaults and then overwrite it with the input.
@@ -549,11 +600,26 @@ func (o *Overrides) CompactorPartialBlockDeletionDelay(userID string) time.Durat
 	return time.Duration(o.getOverridesForUser(userID).CompactorPartialBlockDeletionDelay)
 }
 
+// CompactorSeriesDeletionDelay returns the delay before the compactor applies series deletion requests for a given user.
+func (o *Overrides) CompactorSeriesDeletionDelay(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).CompactorSeriesDeletionDelay)
+}
+
+// CompactorDownsamplingResolutions returns the resolutions the compactor downsamples blocks to for a given user.
+func (o *Overrides) CompactorDownsamplingResolutions(userID string) []time.Duration {
+	return o.getOverridesForUser(userID).CompactorDownsamplingResolutions
+}
+
 // CompactorBlockUploadEnabled returns whether block upload is enabled for a certain tenant.
 func (o *Overrides) CompactorBlockUploadEnabled(tenantID string) bool {
 	return o.getOverridesForUser(tenantID).CompactorBlockUploadEnabled
 }
 
+// CompactorBlockUploadValidationEnabled returns whether blocks uploaded by a certain tenant are validated.
+func (o *Overrides) CompactorBlockUploadValidationEnabled(tenantID string) bool {
+	return o.getOverridesForUser(tenantID).CompactorBlockUploadValidationEnabled
+}
+
 // MetricRelabelConfigs returns the metric relabel configs for a given user.
 func (o *Overrides) MetricRelabelConfigs(userID string) []*relabel.Config {
 	return o.getOverridesForUser(userID).MetricRelabelConfigs
@@ -574,6 +640,46 @@ func (o *Overrides) RulerMaxRuleGroupsPerTenant(userID string) int {
 	return o.getOverridesForUser(userID).RulerMaxRuleGroupsPerTenant
 }
 
+// RulerMaxIndependentRuleEvaluationConcurrencyPerTenant returns the maximum number of independent rules of a given user which can be evaluated concurrently.
+func (o *Overrides) RulerMaxIndependentRuleEvaluationConcurrencyPerTenant(userID string) int64 {
+	return o.getOverridesForUser(userID).RulerMaxIndependentRuleEvaluationConcurrencyPerTenant
+}
+
+// RulerMissedIterationsBackfillWindow returns how far back the missed iterations of the recording rules of a given user are re-evaluated.
+func (o *Overrides) RulerMissedIterationsBackfillWindow(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).RulerMissedIterationsBackfillWindow)
+}
+
+// RulerMissedIterationsBackfillRate returns the maximum number of missed rule group iterations of a given user re-evaluated per second.
+func (o *Overrides) RulerMissedIterationsBackfillRate(userID string) float64 {
+	return o.getOverridesForUser(userID).RulerMissedIterationsBackfillRate
+}
+
+// RulerMinRuleGroupInterval returns the minimum evaluation interval of the rule groups for a given user.
+func (o *Overrides) RulerMinRuleGroupInterval(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).RulerMinRuleGroupInterval)
+}
+
+// RulerMaxRuleGroupInterval returns the maximum evaluation interval of the rule groups for a given user.
+func (o *Overrides) RulerMaxRuleGroupInterval(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).RulerMaxRuleGroupInterval)
+}
+
+// RulerMaxRuleGroupQueryTimeout returns the maximum duration of the evaluation of the rules of a rule group for a given user.
+func (o *Overrides) RulerMaxRuleGroupQueryTimeout(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).RulerMaxRuleGroupQueryTimeout)
+}
+
+// RulerIngestionRate returns the ingestion rate limit of the rule results of the tenant, in samples per second.
+func (o *Overrides) RulerIngestionRate(userID string) float64 {
+	return o.getOverridesForUser(userID).RulerIngestionRate
+}
+
+// RulerIngestionBurstSize returns the ingestion burst size of the rule results of the tenant, in number of samples.
+func (o *Overrides) RulerIngestionBurstSize(userID string) int {
+	return o.getOverridesForUser(userID).RulerIngestionBurstSize
+}
+
 // StoreGatewayTenantShardSize returns the store-gateway shard size for a given user.
 func (o *Overrides) StoreGatewayTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).StoreGatewayTenantShardSize
@@ -87,6 +87,39 @@ func TestLimitsLoadingFromYaml(t *testing.T) {
 	assert.Equal(t, 100, l.MaxLabelNameLength, "from defaults")
 }
 
+func TestLimitsValidate(t *testing.T) {
+	tests := map[string]struct {
+		limits   Limits
+		expected string
+	}{
+		"defaults": {
+			limits: Limits{},
+		},
+		"negative store-gateway shard size": {
+			limits:   Limits{StoreGatewayTenantShardSize: -1},
+			expected: "invalid store_gateway_tenant_shard_size -1, the value must be greater or equal to 0",
+		},
+		"ruler min rule group interval higher than the max": {
+			limits:   Limits{RulerMinRuleGroupInterval: model.Duration(time.Minute), RulerMaxRuleGroupInterval: model.Duration(30 * time.Second)},
+			expected: "invalid ruler_min_rule_group_interval 1m, the value must be lower or equal to ruler_max_rule_group_interval 30s",
+		},
+		"ruler min rule group interval without max": {
+			limits: Limits{RulerMinRuleGroupInterval: model.Duration(time.Minute)},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.limits.Validate()
+			if tc.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expected)
+			}
+		})
+	}
+}
+
 func TestLimitsLoadingFromJson(t *testing.T) {
 	SetDefaultLimitsForYAMLUnmarshalling(Limits{
 		MaxLabelNameLength: 100,
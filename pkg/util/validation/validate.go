@@ -59,6 +59,9 @@ var (
 
 	// ReasonTooManyHAClusters is one of the reasons for discarding samples.
 	ReasonTooManyHAClusters = "too_many_ha_clusters"
+
+	// ReasonRulerRateLimited is the reason for discarding the samples of the rule results exceeding the ruler ingestion rate limit.
+	ReasonRulerRateLimited = "ruler_rate_limited"
 )
 
 func metricReasonFromErrorID(id globalerror.ID) string {
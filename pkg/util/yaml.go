@@ -22,3 +22,38 @@ func YAMLMarshalUnmarshal(in interface{}) (map[string]interface{}, error) {
 
 	return object, nil
 }
+
+// YAMLMappingValue returns the value of a key of a YAML mapping node, or nil if the node isn't a mapping
+// or the key is missing.
+func YAMLMappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// SetYAMLMappingValue sets the value of a key of a YAML mapping node, adding the key if it's missing.
+func SetYAMLMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// RemoveYAMLMappingKey removes a key, along with its value, from a YAML mapping node.
+func RemoveYAMLMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAMLMappingNodes(t *testing.T) {
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte("a: 1\nb: [2]\n"), &doc))
+	m := doc.Content[0]
+
+	assert.Equal(t, "1", YAMLMappingValue(m, "a").Value)
+	assert.Equal(t, yaml.SequenceNode, YAMLMappingValue(m, "b").Kind)
+	assert.Nil(t, YAMLMappingValue(m, "c"))
+	assert.Nil(t, YAMLMappingValue(YAMLMappingValue(m, "b"), "a"))
+
+	SetYAMLMappingValue(m, "a", &yaml.Node{Kind: yaml.ScalarNode, Value: "3"})
+	SetYAMLMappingValue(m, "c", &yaml.Node{Kind: yaml.ScalarNode, Value: "4"})
+	RemoveYAMLMappingKey(m, "b")
+	RemoveYAMLMappingKey(m, "d")
+
+	out, err := yaml.Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, "a: 3\nc: 4\n", string(out))
+}
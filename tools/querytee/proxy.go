@@ -34,9 +34,13 @@ type ProxyConfig struct {
 	BackendReadTimeout             time.Duration
 	CompareResponses               bool
 	ValueComparisonTolerance       float64
+	MetricComparisonTolerances     MetricTolerances
 	UseRelativeError               bool
+	CompareIgnoreOrder             bool
 	PassThroughNonRegisteredRoutes bool
 	SkipRecentSamples              time.Duration
+	RecordFile                     string
+	RecordSampleRate               float64
 }
 
 func (cfg *ProxyConfig) RegisterFlags(f *flag.FlagSet) {
@@ -46,9 +50,13 @@ func (cfg *ProxyConfig) RegisterFlags(f *flag.FlagSet) {
 	f.DurationVar(&cfg.BackendReadTimeout, "backend.read-timeout", 90*time.Second, "The timeout when reading the response from a backend.")
 	f.BoolVar(&cfg.CompareResponses, "proxy.compare-responses", false, "Compare responses between preferred and secondary endpoints for supported routes.")
 	f.Float64Var(&cfg.ValueComparisonTolerance, "proxy.value-comparison-tolerance", 0.000001, "The tolerance to apply when comparing floating point values in the responses. 0 to disable tolerance and require exact match (not recommended).")
+	f.Var(&cfg.MetricComparisonTolerances, "proxy.value-comparison-tolerance-per-metric", "The tolerance to apply when comparing floating point values of the metrics whose name matches a regular expression, overriding -proxy.value-comparison-tolerance, in the form <regexp>=<tolerance>. Can be set multiple times, and the first regular expression matching the metric name applies.")
 	f.BoolVar(&cfg.UseRelativeError, "proxy.compare-use-relative-error", false, "Use relative error tolerance when comparing floating point values.")
 	f.DurationVar(&cfg.SkipRecentSamples, "proxy.compare-skip-recent-samples", 60*time.Second, "The window from now to skip comparing samples. 0 to disable.")
+	f.BoolVar(&cfg.CompareIgnoreOrder, "proxy.compare-ignore-order", true, "Ignore the order of the items when comparing the responses of the APIs not guaranteeing it, like the series, metadata, exemplars, rules and alerts APIs.")
 	f.BoolVar(&cfg.PassThroughNonRegisteredRoutes, "proxy.passthrough-non-registered-routes", false, "Passthrough requests for non-registered routes to preferred backend.")
+	f.StringVar(&cfg.RecordFile, "proxy.record-file", "", "Path of the file to record the requests received for supported routes to, in order to replay them later with -replay.file. Empty to disable recording.")
+	f.Float64Var(&cfg.RecordSampleRate, "proxy.record-sample-rate", 1, "The fraction of the requests to record, between 0 and 1.")
 }
 
 type Route struct {
@@ -64,6 +72,7 @@ type Proxy struct {
 	logger   log.Logger
 	metrics  *ProxyMetrics
 	routes   []Route
+	recorder *Recorder
 
 	// The HTTP server used to run the proxy service.
 	srv         *http.Server
@@ -89,6 +98,36 @@ func NewProxy(cfg ProxyConfig, logger log.Logger, routes []Route, registerer pro
 		routes:  routes,
 	}
 
+	var err error
+	if p.backends, err = newProxyBackends(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.CompareResponses && len(p.backends) != 2 {
+		return nil, fmt.Errorf("when enabling comparison of results number of backends should be 2 exactly")
+	}
+
+	// At least 2 backends are suggested
+	if len(p.backends) < 2 {
+		level.Warn(p.logger).Log("msg", "The proxy is running with only 1 backend. At least 2 backends are required to fulfil the purpose of the proxy and compare results.")
+	}
+
+	if cfg.RecordFile != "" {
+		if cfg.RecordSampleRate <= 0 || cfg.RecordSampleRate > 1 {
+			return nil, fmt.Errorf("the -proxy.record-sample-rate flag must be greater than 0 and lower or equal to 1")
+		}
+		if p.recorder, err = NewRecorder(cfg.RecordFile, cfg.RecordSampleRate); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// newProxyBackends parses the backend endpoints, and checks the preferred backend exists among them.
+func newProxyBackends(cfg ProxyConfig) ([]*ProxyBackend, error) {
+	var backends []*ProxyBackend
+
 	// Parse the backend endpoints (comma separated).
 	parts := strings.Split(cfg.BackendEndpoints, ",")
 
@@ -115,18 +154,18 @@ func NewProxy(cfg ProxyConfig, logger log.Logger, routes []Route, registerer pro
 			preferred = preferredIdx == idx
 		}
 
-		p.backends = append(p.backends, NewProxyBackend(name, u, cfg.BackendReadTimeout, preferred))
+		backends = append(backends, NewProxyBackend(name, u, cfg.BackendReadTimeout, preferred))
 	}
 
 	// At least 1 backend is required
-	if len(p.backends) < 1 {
+	if len(backends) < 1 {
 		return nil, errMinBackends
 	}
 
 	// If the preferred backend is configured, then it must exists among the actual backends.
 	if cfg.PreferredBackend != "" {
 		exists := false
-		for _, b := range p.backends {
+		for _, b := range backends {
 			if b.preferred {
 				exists = true
 				break
@@ -138,16 +177,7 @@ func NewProxy(cfg ProxyConfig, logger log.Logger, routes []Route, registerer pro
 		}
 	}
 
-	if cfg.CompareResponses && len(p.backends) != 2 {
-		return nil, fmt.Errorf("when enabling comparison of results number of backends should be 2 exactly")
-	}
-
-	// At least 2 backends are suggested
-	if len(p.backends) < 2 {
-		level.Warn(p.logger).Log("msg", "The proxy is running with only 1 backend. At least 2 backends are required to fulfil the purpose of the proxy and compare results.")
-	}
-
-	return p, nil
+	return backends, nil
 }
 
 func (p *Proxy) Start() error {
@@ -170,7 +200,7 @@ func (p *Proxy) Start() error {
 		if p.cfg.CompareResponses {
 			comparator = route.ResponseComparator
 		}
-		router.Path(route.Path).Methods(route.Methods...).Handler(NewProxyEndpoint(p.backends, route.RouteName, p.metrics, p.logger, comparator))
+		router.Path(route.Path).Methods(route.Methods...).Handler(NewProxyEndpoint(p.backends, route.RouteName, p.metrics, p.logger, comparator, p.recorder))
 	}
 
 	if p.cfg.PassThroughNonRegisteredRoutes {
@@ -208,7 +238,13 @@ func (p *Proxy) Stop() error {
 		return nil
 	}
 
-	return p.srv.Shutdown(context.Background())
+	err := p.srv.Shutdown(context.Background())
+	if p.recorder != nil {
+		if closeErr := p.recorder.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
 func (p *Proxy) Await() {
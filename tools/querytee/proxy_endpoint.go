@@ -7,11 +7,14 @@ package querytee
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,6 +33,7 @@ type ProxyEndpoint struct {
 	metrics    *ProxyMetrics
 	logger     log.Logger
 	comparator ResponsesComparator
+	recorder   *Recorder
 
 	// Whether for this endpoint there's a preferred backend configured.
 	hasPreferredBackend bool
@@ -38,7 +42,7 @@ type ProxyEndpoint struct {
 	routeName string
 }
 
-func NewProxyEndpoint(backends []*ProxyBackend, routeName string, metrics *ProxyMetrics, logger log.Logger, comparator ResponsesComparator) *ProxyEndpoint {
+func NewProxyEndpoint(backends []*ProxyBackend, routeName string, metrics *ProxyMetrics, logger log.Logger, comparator ResponsesComparator, recorder *Recorder) *ProxyEndpoint {
 	hasPreferredBackend := false
 	for _, backend := range backends {
 		if backend.preferred {
@@ -53,6 +57,7 @@ func NewProxyEndpoint(backends []*ProxyBackend, routeName string, metrics *Proxy
 		metrics:             metrics,
 		logger:              logger,
 		comparator:          comparator,
+		recorder:            recorder,
 		hasPreferredBackend: hasPreferredBackend,
 	}
 }
@@ -106,6 +111,16 @@ func (p *ProxyEndpoint) executeBackendRequests(r *http.Request, resCh chan *back
 
 	level.Debug(p.logger).Log("msg", "Received request", "path", r.URL.Path, "query", query)
 
+	if p.recorder != nil {
+		params, err := url.ParseQuery(query)
+		if err == nil {
+			err = p.recorder.Record(r, p.routeName, params, time.Now())
+		}
+		if err != nil {
+			level.Warn(p.logger).Log("msg", "Unable to record request", "err", err)
+		}
+	}
+
 	wg.Add(len(p.backends))
 	for _, b := range p.backends {
 		b := b
@@ -163,10 +178,10 @@ func (p *ProxyEndpoint) executeBackendRequests(r *http.Request, resCh chan *back
 		}
 
 		result := comparisonSuccess
-		err := p.compareResponses(expectedResponse, actualResponse)
+		err := compareResponses(p.comparator, expectedResponse, actualResponse)
 		if err != nil {
-			level.Error(util_log.Logger).Log("msg", "response comparison failed", "route-name", p.routeName,
-				"query", r.URL.RawQuery, "err", err)
+			level.Error(util_log.Logger).Log(append([]interface{}{"msg", "response comparison failed", "route-name", p.routeName,
+				"path", r.URL.Path, "query", r.URL.RawQuery}, comparisonFailureLogFields(err)...)...)
 			result = comparisonFailed
 		}
 
@@ -209,7 +224,7 @@ func (p *ProxyEndpoint) waitBackendResponseForDownstream(resCh chan *backendResp
 	return responses[0]
 }
 
-func (p *ProxyEndpoint) compareResponses(expectedResponse, actualResponse *backendResponse) error {
+func compareResponses(comparator ResponsesComparator, expectedResponse, actualResponse *backendResponse) error {
 	// compare response body only if we get a 200
 	if expectedResponse.status != 200 {
 		return fmt.Errorf("skipped comparison of response because we got status code %d from preferred backend's response", expectedResponse.status)
@@ -223,7 +238,7 @@ func (p *ProxyEndpoint) compareResponses(expectedResponse, actualResponse *backe
 		return fmt.Errorf("expected status code %d but got %d", expectedResponse.status, actualResponse.status)
 	}
 
-	return p.comparator.Compare(expectedResponse.body, actualResponse.body)
+	return comparator.Compare(expectedResponse.body, actualResponse.body)
 }
 
 type backendResponse struct {
@@ -249,3 +264,13 @@ func (r *backendResponse) statusCode() int {
 
 	return r.status
 }
+
+// comparisonFailureLogFields returns the fields to log for a failed comparison. The differences found
+// are logged in their own fields, so that they can be filtered.
+func comparisonFailureLogFields(err error) []interface{} {
+	var diffErr *ComparisonDiffError
+	if errors.As(err, &diffErr) {
+		return []interface{}{"differences", diffErr.Total, "diffs", strings.Join(diffErr.Diffs, "; ")}
+	}
+	return []interface{}{"err", err}
+}
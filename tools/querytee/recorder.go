@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querytee
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/weaveworks/common/user"
+)
+
+// RecordedRequest is a request received by the proxy, recorded to be replayed later.
+type RecordedRequest struct {
+	Timestamp time.Time  `json:"timestamp"`
+	Tenant    string     `json:"tenant,omitempty"`
+	Method    string     `json:"method"`
+	Path      string     `json:"path"`
+	RouteName string     `json:"route"`
+	Params    url.Values `json:"params"`
+}
+
+// Recorder records a sample of the requests received by the proxy to a file, one JSON object per line.
+type Recorder struct {
+	sampleRate float64
+
+	mtx  sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+	rand *rand.Rand
+}
+
+// NewRecorder makes a new Recorder appending the requests to the file, which is created if it doesn't exist.
+// Each request is recorded with a probability of sampleRate.
+func NewRecorder(path string, sampleRate float64) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening the record file")
+	}
+
+	return &Recorder{
+		sampleRate: sampleRate,
+		file:       f,
+		enc:        json.NewEncoder(f),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Record records the request, if sampled. The params are the parsed query string and form of the request.
+func (r *Recorder) Record(req *http.Request, routeName string, params url.Values, timestamp time.Time) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.sampleRate < 1 && r.rand.Float64() >= r.sampleRate {
+		return nil
+	}
+
+	return r.enc.Encode(RecordedRequest{
+		Timestamp: timestamp,
+		Tenant:    requestTenant(req),
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		RouteName: routeName,
+		Params:    params,
+	})
+}
+
+func (r *Recorder) Close() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.file.Close()
+}
+
+// requestTenant returns the tenant of the request, from the tenant header or else from the HTTP basic
+// authentication username. The password is never recorded.
+func requestTenant(req *http.Request) string {
+	if tenant := req.Header.Get(user.OrgIDHeaderName); tenant != "" {
+		return tenant
+	}
+	if username, _, ok := req.BasicAuth(); ok {
+		return username
+	}
+	return ""
+}
+
+// ReadRecordedRequests reads the requests recorded by a Recorder.
+func ReadRecordedRequests(r io.Reader) ([]RecordedRequest, error) {
+	var requests []RecordedRequest
+
+	dec := json.NewDecoder(r)
+	for {
+		var req RecordedRequest
+		if err := dec.Decode(&req); err == io.EOF {
+			return requests, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "decoding the recorded request %d", len(requests)+1)
+		}
+		requests = append(requests, req)
+	}
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querytee
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxy_RecordRequests(t *testing.T) {
+	backend := httptest.NewServer(mockQueryResponse("/api/v1/query", 200, `{"status":"success"}`))
+	defer backend.Close()
+
+	recordFile := filepath.Join(t.TempDir(), "requests.jsonl")
+	cfg := ProxyConfig{
+		BackendEndpoints:   backend.URL,
+		BackendReadTimeout: time.Second,
+		RecordFile:         recordFile,
+		RecordSampleRate:   1,
+	}
+	routes := []Route{{Path: "/api/v1/query", RouteName: "api_v1_query", Methods: []string{"GET", "POST"}}}
+
+	p, err := NewProxy(cfg, log.NewNopLogger(), routes, nil)
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+
+	// A GET request with the tenant header.
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s/api/v1/query?query=up&time=1660000000", p.Endpoint()), nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Scope-OrgID", "tenant-1")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	// A POST request with HTTP basic authentication, whose password isn't recorded.
+	req, err = http.NewRequest("POST", fmt.Sprintf("http://%s/api/v1/query", p.Endpoint()), strings.NewReader("query=sum(up)"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("tenant-2", "secret")
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	require.NoError(t, p.Stop())
+
+	data, err := os.ReadFile(recordFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	requests, err := ReadRecordedRequests(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+
+	for i := range requests {
+		assert.WithinDuration(t, time.Now(), requests[i].Timestamp, time.Minute)
+		requests[i].Timestamp = time.Time{}
+	}
+	assert.Equal(t, []RecordedRequest{
+		{Tenant: "tenant-1", Method: "GET", Path: "/api/v1/query", RouteName: "api_v1_query", Params: url.Values{"query": {"up"}, "time": {"1660000000"}}},
+		{Tenant: "tenant-2", Method: "POST", Path: "/api/v1/query", RouteName: "api_v1_query", Params: url.Values{"query": {"sum(up)"}}},
+	}, requests)
+}
+
+func TestNewProxy_InvalidRecordSampleRate(t *testing.T) {
+	cfg := ProxyConfig{
+		BackendEndpoints: "http://backend-1",
+		RecordFile:       filepath.Join(t.TempDir(), "requests.jsonl"),
+		RecordSampleRate: 0,
+	}
+
+	_, err := NewProxy(cfg, log.NewNopLogger(), testRoutes, nil)
+	assert.EqualError(t, err, "the -proxy.record-sample-rate flag must be greater than 0 and lower or equal to 1")
+}
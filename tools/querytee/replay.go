@@ -0,0 +1,407 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querytee
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/weaveworks/common/user"
+	"golang.org/x/time/rate"
+)
+
+// The request params holding the time range of the query, which are shifted when replaying.
+var timeParams = []string{"time", "start", "end"}
+
+type ReplayConfig struct {
+	File                 string
+	ShiftTimes           bool
+	MaxRequestsPerSecond float64
+	Concurrency          int
+}
+
+func (cfg *ReplayConfig) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&cfg.File, "replay.file", "", "Path of a file of requests recorded with -proxy.record-file to replay against the backends. When set, the query-tee replays the requests, prints a report and exits instead of running the proxy.")
+	f.BoolVar(&cfg.ShiftTimes, "replay.shift-times", false, "Shift the time parameters of the replayed requests by the time elapsed since they were recorded, so that the queries cover the same time range relative to now.")
+	f.Float64Var(&cfg.MaxRequestsPerSecond, "replay.max-requests-per-second", 0, "The maximum number of requests replayed per second. 0 to disable throttling.")
+	f.IntVar(&cfg.Concurrency, "replay.concurrency", 4, "The maximum number of requests replayed concurrently.")
+}
+
+// Replayer sends recorded requests to the backends, and reports the latency of each backend and the
+// comparison of their responses.
+type Replayer struct {
+	cfg         ReplayConfig
+	backends    []*ProxyBackend
+	reference   *ProxyBackend
+	comparators map[string]ResponsesComparator
+	logger      log.Logger
+
+	// Mocked in tests.
+	now func() time.Time
+}
+
+// NewReplayer makes a new Replayer. When the comparison of responses is enabled, the responses of each backend
+// are compared with the ones of the preferred backend, or of the first backend if there's no preferred backend.
+func NewReplayer(cfg ReplayConfig, proxyCfg ProxyConfig, routes []Route, logger log.Logger) (*Replayer, error) {
+	if cfg.Concurrency < 1 {
+		return nil, fmt.Errorf("the -replay.concurrency flag must be greater than 0")
+	}
+
+	backends, err := newProxyBackends(proxyCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Replayer{
+		cfg:         cfg,
+		backends:    backends,
+		reference:   backends[0],
+		comparators: map[string]ResponsesComparator{},
+		logger:      logger,
+		now:         time.Now,
+	}
+	for _, b := range backends {
+		if b.preferred {
+			r.reference = b
+		}
+	}
+
+	if proxyCfg.CompareResponses {
+		if len(backends) < 2 {
+			return nil, fmt.Errorf("when enabling comparison of results at least 2 backends are required")
+		}
+		for _, route := range routes {
+			if route.ResponseComparator != nil {
+				r.comparators[route.RouteName] = route.ResponseComparator
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// Replay sends the requests to all the backends, up to the configured concurrency and rate.
+func (r *Replayer) Replay(ctx context.Context, requests []RecordedRequest) (*ReplayReport, error) {
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if r.cfg.MaxRequestsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(r.cfg.MaxRequestsPerSecond), 1)
+	}
+
+	report := newReplayReport(r.reference.name)
+	reqCh := make(chan RecordedRequest)
+
+	wg := sync.WaitGroup{}
+	wg.Add(r.cfg.Concurrency)
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for req := range reqCh {
+				r.replayRequest(req, report)
+			}
+		}()
+	}
+
+	var err error
+	for i, req := range requests {
+		if err = limiter.Wait(ctx); err != nil {
+			break
+		}
+		reqCh <- req
+
+		if (i+1)%1000 == 0 {
+			level.Info(r.logger).Log("msg", "Replaying requests", "replayed", i+1, "total", len(requests))
+		}
+	}
+	close(reqCh)
+	wg.Wait()
+
+	return report, err
+}
+
+func (r *Replayer) replayRequest(rec RecordedRequest, report *ReplayReport) {
+	params := rec.Params
+	if r.cfg.ShiftTimes {
+		shifted, err := shiftTimeParams(params, r.now().Sub(rec.Timestamp))
+		if err != nil {
+			level.Warn(r.logger).Log("msg", "Unable to shift the time of the request, replaying it unchanged", "path", rec.Path, "err", err)
+		} else {
+			params = shifted
+		}
+	}
+
+	req, body, err := newReplayRequest(rec, params)
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "Unable to create the request to replay", "path", rec.Path, "err", err)
+		return
+	}
+
+	// Send the same request to all backends.
+	var (
+		wg        = sync.WaitGroup{}
+		responses = make([]*backendResponse, len(r.backends))
+	)
+	wg.Add(len(r.backends))
+	for i, b := range r.backends {
+		i, b := i, b
+
+		go func() {
+			defer wg.Done()
+
+			var bodyReader io.ReadCloser
+			if len(body) > 0 {
+				bodyReader = ioutil.NopCloser(bytes.NewReader(body))
+			}
+
+			start := time.Now()
+			status, resBody, err := b.ForwardRequest(req, bodyReader)
+			elapsed := time.Since(start)
+
+			responses[i] = &backendResponse{backend: b, status: status, body: resBody, err: err}
+			report.addLatency(b.name, rec.RouteName, elapsed, responses[i].succeeded())
+		}()
+	}
+	wg.Wait()
+
+	comparator, ok := r.comparators[rec.RouteName]
+	if !ok {
+		return
+	}
+
+	var expected *backendResponse
+	for _, res := range responses {
+		if res.backend == r.reference {
+			expected = res
+		}
+	}
+	for _, res := range responses {
+		if res == expected {
+			continue
+		}
+
+		err := compareResponses(comparator, expected, res)
+		if err != nil {
+			level.Warn(r.logger).Log(append([]interface{}{"msg", "response comparison failed", "route-name", rec.RouteName, "backend", res.backend.name,
+				"tenant", rec.Tenant, "path", rec.Path, "query", params.Encode()}, comparisonFailureLogFields(err)...)...)
+		}
+		report.addComparison(res.backend.name, rec.RouteName, err == nil)
+	}
+}
+
+// newReplayRequest makes the request to send to the backends, with the params in the query string or in
+// the body of POST requests.
+func newReplayRequest(rec RecordedRequest, params url.Values) (*http.Request, []byte, error) {
+	u := &url.URL{Path: rec.Path}
+
+	var body []byte
+	if rec.Method == http.MethodPost {
+		body = []byte(params.Encode())
+	} else {
+		u.RawQuery = params.Encode()
+	}
+
+	req, err := http.NewRequest(rec.Method, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rec.Method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if rec.Tenant != "" {
+		req.Header.Set(user.OrgIDHeaderName, rec.Tenant)
+	}
+
+	return req, body, nil
+}
+
+// shiftTimeParams returns a copy of the params with the time params shifted. The shift is rounded down to a
+// multiple of the step of range queries, so that aligned queries remain aligned.
+func shiftTimeParams(params url.Values, shift time.Duration) (url.Values, error) {
+	if stepParam := params.Get("step"); stepParam != "" {
+		step, err := parseDurationParam(stepParam)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid step")
+		}
+		if step > 0 {
+			shift = shift.Truncate(step)
+		}
+	}
+
+	shifted := make(url.Values, len(params))
+	for name, values := range params {
+		shifted[name] = append([]string{}, values...)
+	}
+
+	for _, name := range timeParams {
+		value := shifted.Get(name)
+		if value == "" {
+			continue
+		}
+
+		// The time is either a Unix timestamp in seconds or a RFC 3339 timestamp, kept in the same format.
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			ms := int64(math.Round(seconds*1000)) + shift.Milliseconds()
+			shifted.Set(name, strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64))
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q", name, value)
+		}
+		shifted.Set(name, t.Add(shift).Format(time.RFC3339Nano))
+	}
+
+	return shifted, nil
+}
+
+// parseDurationParam parses a duration either in seconds or in the Prometheus duration format.
+func parseDurationParam(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	d, err := model.ParseDuration(value)
+	return time.Duration(d), err
+}
+
+type replayKey struct {
+	backend string
+	route   string
+}
+
+type replayLatencies struct {
+	durations []time.Duration
+	errors    int
+}
+
+type replayComparisons struct {
+	compared int
+	failed   int
+}
+
+// ReplayReport is the latency of the requests replayed to each backend, and the result of the comparison of their
+// responses with the reference backend, by route.
+type ReplayReport struct {
+	referenceBackend string
+
+	mtx         sync.Mutex
+	latencies   map[replayKey]*replayLatencies
+	comparisons map[replayKey]*replayComparisons
+}
+
+func newReplayReport(referenceBackend string) *ReplayReport {
+	return &ReplayReport{
+		referenceBackend: referenceBackend,
+		latencies:        map[replayKey]*replayLatencies{},
+		comparisons:      map[replayKey]*replayComparisons{},
+	}
+}
+
+func (r *ReplayReport) addLatency(backend, route string, elapsed time.Duration, succeeded bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	key := replayKey{backend: backend, route: route}
+	l, ok := r.latencies[key]
+	if !ok {
+		l = &replayLatencies{}
+		r.latencies[key] = l
+	}
+	l.durations = append(l.durations, elapsed)
+	if !succeeded {
+		l.errors++
+	}
+}
+
+func (r *ReplayReport) addComparison(backend, route string, succeeded bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	key := replayKey{backend: backend, route: route}
+	c, ok := r.comparisons[key]
+	if !ok {
+		c = &replayComparisons{}
+		r.comparisons[key] = c
+	}
+	c.compared++
+	if !succeeded {
+		c.failed++
+	}
+}
+
+// Write writes the report as tables.
+func (r *ReplayReport) Write(w io.Writer) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tROUTE\tREQUESTS\tERRORS\tP50\tP90\tP99\tMAX")
+	latencyKeys := make([]replayKey, 0, len(r.latencies))
+	for key := range r.latencies {
+		latencyKeys = append(latencyKeys, key)
+	}
+	for _, key := range sortReplayKeys(latencyKeys) {
+		l := r.latencies[key]
+		sort.Slice(l.durations, func(i, j int) bool { return l.durations[i] < l.durations[j] })
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\n", key.backend, key.route, len(l.durations), l.errors,
+			formatLatency(durationQuantile(l.durations, 0.5)), formatLatency(durationQuantile(l.durations, 0.9)),
+			formatLatency(durationQuantile(l.durations, 0.99)), formatLatency(durationQuantile(l.durations, 1)))
+	}
+
+	if len(r.comparisons) > 0 {
+		comparisonKeys := make([]replayKey, 0, len(r.comparisons))
+		for key := range r.comparisons {
+			comparisonKeys = append(comparisonKeys, key)
+		}
+
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "BACKEND\tROUTE\tCOMPARED WITH %s\tFAILED\n", strings.ToUpper(r.referenceBackend))
+		for _, key := range sortReplayKeys(comparisonKeys) {
+			c := r.comparisons[key]
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", key.backend, key.route, c.compared, c.failed)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func sortReplayKeys(keys []replayKey) []replayKey {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].backend != keys[j].backend {
+			return keys[i].backend < keys[j].backend
+		}
+		return keys[i].route < keys[j].route
+	})
+	return keys
+}
+
+// durationQuantile returns the quantile of the sorted durations, using the nearest-rank method.
+func durationQuantile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+func formatLatency(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querytee
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayer_Replay(t *testing.T) {
+	const (
+		queryResponse1 = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up"},"value":[1660000000,"1"]}]}}`
+		queryResponse2 = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up"},"value":[1660000000,"2"]}]}}`
+	)
+
+	var (
+		receivedMtx sync.Mutex
+		received    []string
+	)
+	mockBackend := func(name, response string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, r.ParseForm())
+
+			receivedMtx.Lock()
+			received = append(received, name+" "+r.Method+" "+r.URL.Path+" "+r.Header.Get("X-Scope-OrgID")+" "+r.Form.Encode())
+			receivedMtx.Unlock()
+
+			if r.Form.Get("query") == "sum(up)" {
+				_, _ = w.Write([]byte(response))
+			} else {
+				_, _ = w.Write([]byte(queryResponse1))
+			}
+		}))
+	}
+	backend1 := mockBackend("backend-1", queryResponse1)
+	defer backend1.Close()
+	backend2 := mockBackend("backend-2", queryResponse2)
+	defer backend2.Close()
+
+	// Use a different hostname for each backend, since the backends are named after their hostname.
+	backend2URL := strings.Replace(backend2.URL, "127.0.0.1", "localhost", 1)
+
+	proxyCfg := ProxyConfig{
+		BackendEndpoints:   backend1.URL + "," + backend2URL,
+		BackendReadTimeout: time.Second,
+		CompareResponses:   true,
+	}
+	routes := []Route{
+		{Path: "/api/v1/query", RouteName: "api_v1_query", ResponseComparator: NewSamplesComparator(SampleComparisonOptions{})},
+		{Path: "/api/v1/labels", RouteName: "api_v1_labels"},
+	}
+
+	recordedAt := time.Unix(1660000000, 0)
+	replayer, err := NewReplayer(ReplayConfig{ShiftTimes: true, Concurrency: 1}, proxyCfg, routes, log.NewNopLogger())
+	require.NoError(t, err)
+	replayer.now = func() time.Time { return recordedAt.Add(time.Hour) }
+
+	report, err := replayer.Replay(context.Background(), []RecordedRequest{
+		{Timestamp: recordedAt, Tenant: "tenant-1", Method: "GET", Path: "/api/v1/query", RouteName: "api_v1_query", Params: url.Values{"query": {"up"}, "time": {"1660000000"}}},
+		{Timestamp: recordedAt, Tenant: "tenant-1", Method: "POST", Path: "/api/v1/query", RouteName: "api_v1_query", Params: url.Values{"query": {"sum(up)"}}},
+		{Timestamp: recordedAt, Method: "GET", Path: "/api/v1/labels", RouteName: "api_v1_labels", Params: url.Values{"start": {"2022-08-08T22:06:40Z"}}},
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		"backend-1 GET /api/v1/query tenant-1 query=up&time=1660003600",
+		"backend-2 GET /api/v1/query tenant-1 query=up&time=1660003600",
+		"backend-1 POST /api/v1/query tenant-1 query=sum%28up%29",
+		"backend-2 POST /api/v1/query tenant-1 query=sum%28up%29",
+		"backend-1 GET /api/v1/labels  start=2022-08-08T23%3A06%3A40Z",
+		"backend-2 GET /api/v1/labels  start=2022-08-08T23%3A06%3A40Z",
+	}, received)
+
+	// The latencies vary, so only the comparison results are checked exactly.
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 8)
+	assert.Regexp(t, `^BACKEND\s+ROUTE\s+REQUESTS\s+ERRORS\s+P50\s+P90\s+P99\s+MAX$`, lines[0])
+	assert.Regexp(t, `^127\.0\.0\.1\s+api_v1_labels\s+1\s+0\s`, lines[1])
+	assert.Regexp(t, `^127\.0\.0\.1\s+api_v1_query\s+2\s+0\s`, lines[2])
+	assert.Regexp(t, `^localhost\s+api_v1_labels\s+1\s+0\s`, lines[3])
+	assert.Regexp(t, `^localhost\s+api_v1_query\s+2\s+0\s`, lines[4])
+	assert.Equal(t, "", lines[5])
+	assert.Regexp(t, `^BACKEND\s+ROUTE\s+COMPARED WITH 127\.0\.0\.1\s+FAILED$`, lines[6])
+	assert.Regexp(t, `^localhost\s+api_v1_query\s+2\s+1$`, lines[7])
+}
+
+func TestShiftTimeParams(t *testing.T) {
+	tests := map[string]struct {
+		params   url.Values
+		shift    time.Duration
+		expected url.Values
+	}{
+		"unix timestamps": {
+			params:   url.Values{"query": {"up"}, "time": {"1660000000.5"}},
+			shift:    90 * time.Second,
+			expected: url.Values{"query": {"up"}, "time": {"1660000090.5"}},
+		},
+		"RFC 3339 timestamps": {
+			params:   url.Values{"start": {"2022-08-08T22:06:40Z"}, "end": {"2022-08-08T23:06:40.123Z"}},
+			shift:    time.Minute,
+			expected: url.Values{"start": {"2022-08-08T22:07:40Z"}, "end": {"2022-08-08T23:07:40.123Z"}},
+		},
+		"range query with the shift rounded down to a multiple of the step": {
+			params:   url.Values{"start": {"1660000000"}, "end": {"1660003600"}, "step": {"1m"}},
+			shift:    150 * time.Second,
+			expected: url.Values{"start": {"1660000120"}, "end": {"1660003720"}, "step": {"1m"}},
+		},
+		"range query with the step in seconds": {
+			params:   url.Values{"start": {"1660000000"}, "end": {"1660003600"}, "step": {"30"}},
+			shift:    100 * time.Second,
+			expected: url.Values{"start": {"1660000090"}, "end": {"1660003690"}, "step": {"30"}},
+		},
+		"no time params": {
+			params:   url.Values{"match[]": {"up"}},
+			shift:    time.Hour,
+			expected: url.Values{"match[]": {"up"}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			original := url.Values{}
+			for k, v := range tc.params {
+				original[k] = append([]string{}, v...)
+			}
+
+			actual, err := shiftTimeParams(tc.params, tc.shift)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+
+			// The recorded params aren't modified.
+			assert.Equal(t, original, tc.params)
+		})
+	}
+
+	_, err := shiftTimeParams(url.Values{"time": {"yesterday"}}, time.Hour)
+	assert.EqualError(t, err, `invalid time "yesterday"`)
+}
+
+func TestDurationQuantile(t *testing.T) {
+	durations := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	assert.Equal(t, time.Duration(5), durationQuantile(durations, 0.5))
+	assert.Equal(t, time.Duration(9), durationQuantile(durations, 0.9))
+	assert.Equal(t, time.Duration(10), durationQuantile(durations, 0.99))
+	assert.Equal(t, time.Duration(10), durationQuantile(durations, 1))
+	assert.Equal(t, time.Duration(0), durationQuantile(nil, 0.5))
+}
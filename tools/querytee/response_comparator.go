@@ -9,6 +9,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-kit/log/level"
@@ -33,6 +36,60 @@ type SampleComparisonOptions struct {
 	Tolerance         float64
 	UseRelativeError  bool
 	SkipRecentSamples time.Duration
+
+	// The tolerance of the metrics whose name matches a regular expression, overriding Tolerance.
+	MetricTolerances MetricTolerances
+}
+
+// forMetric returns the options to compare the samples of a metric.
+func (opts SampleComparisonOptions) forMetric(metric model.Metric) SampleComparisonOptions {
+	name := string(metric[model.MetricNameLabel])
+	for _, t := range opts.MetricTolerances {
+		if t.Regexp.MatchString(name) {
+			opts.Tolerance = t.Tolerance
+			break
+		}
+	}
+	return opts
+}
+
+// MetricTolerance is the tolerance to apply when comparing the values of the metrics whose name matches a regular expression.
+type MetricTolerance struct {
+	Regexp    *regexp.Regexp
+	Tolerance float64
+}
+
+// MetricTolerances is a list of metric tolerances, which can be set multiple times from the command line as <regexp>=<tolerance>.
+// The first regular expression matching the name of a metric applies.
+type MetricTolerances []MetricTolerance
+
+// String implements flag.Value.
+func (m *MetricTolerances) String() string {
+	parts := make([]string, 0, len(*m))
+	for _, t := range *m {
+		parts = append(parts, fmt.Sprintf("%s=%g", strings.TrimSuffix(strings.TrimPrefix(t.Regexp.String(), "^(?:"), ")$"), t.Tolerance))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Set implements flag.Value.
+func (m *MetricTolerances) Set(value string) error {
+	idx := strings.LastIndex(value, "=")
+	if idx < 0 {
+		return fmt.Errorf("invalid metric tolerance %q, expected <regexp>=<tolerance>", value)
+	}
+
+	re, err := regexp.Compile("^(?:" + value[:idx] + ")$")
+	if err != nil {
+		return errors.Wrapf(err, "invalid metric tolerance regexp %q", value[:idx])
+	}
+	tolerance, err := strconv.ParseFloat(value[idx+1:], 64)
+	if err != nil {
+		return errors.Wrapf(err, "invalid metric tolerance %q", value[idx+1:])
+	}
+
+	*m = append(*m, MetricTolerance{Regexp: re, Tolerance: tolerance})
+	return nil
 }
 
 func NewSamplesComparator(opts SampleComparisonOptions) *SamplesComparator {
@@ -114,23 +171,28 @@ func compareMatrix(expectedRaw, actualRaw json.RawMessage, opts SampleComparison
 		}
 
 		actualMetric := actual[actualMetricIndex]
-		expectedMetricLen := len(expectedMetric.Values)
-		actualMetricLen := len(actualMetric.Values)
+
+		// The most recent samples may not have been ingested by both backends yet.
+		expectedValues := withoutRecentSamples(expectedMetric.Values, opts)
+		actualValues := withoutRecentSamples(actualMetric.Values, opts)
+		expectedMetricLen := len(expectedValues)
+		actualMetricLen := len(actualValues)
 
 		if expectedMetricLen != actualMetricLen {
 			err := fmt.Errorf("expected %d samples for metric %s but got %d", expectedMetricLen,
 				expectedMetric.Metric, actualMetricLen)
 			if expectedMetricLen > 0 && actualMetricLen > 0 {
-				level.Error(util_log.Logger).Log("msg", err.Error(), "oldest-expected-ts", expectedMetric.Values[0].Timestamp,
-					"newest-expected-ts", expectedMetric.Values[expectedMetricLen-1].Timestamp,
-					"oldest-actual-ts", actualMetric.Values[0].Timestamp, "newest-actual-ts", actualMetric.Values[actualMetricLen-1].Timestamp)
+				level.Error(util_log.Logger).Log("msg", err.Error(), "oldest-expected-ts", expectedValues[0].Timestamp,
+					"newest-expected-ts", expectedValues[expectedMetricLen-1].Timestamp,
+					"oldest-actual-ts", actualValues[0].Timestamp, "newest-actual-ts", actualValues[actualMetricLen-1].Timestamp)
 			}
 			return err
 		}
 
-		for i, expectedSamplePair := range expectedMetric.Values {
-			actualSamplePair := actualMetric.Values[i]
-			err := compareSamplePair(expectedSamplePair, actualSamplePair, opts)
+		metricOpts := opts.forMetric(expectedMetric.Metric)
+		for i, expectedSamplePair := range expectedValues {
+			actualSamplePair := actualValues[i]
+			err := compareSamplePair(expectedSamplePair, actualSamplePair, metricOpts)
 			if err != nil {
 				return errors.Wrapf(err, "sample pair not matching for metric %s", expectedMetric.Metric)
 			}
@@ -176,7 +238,7 @@ func compareVector(expectedRaw, actualRaw json.RawMessage, opts SampleComparison
 		}, model.SamplePair{
 			Timestamp: actualMetric.Timestamp,
 			Value:     actualMetric.Value,
-		}, opts)
+		}, opts.forMetric(expectedMetric.Metric))
 		if err != nil {
 			return errors.Wrapf(err, "sample pair not matching for metric %s", expectedMetric.Metric)
 		}
@@ -206,6 +268,20 @@ func compareScalar(expectedRaw, actualRaw json.RawMessage, opts SampleComparison
 	}, opts)
 }
 
+// withoutRecentSamples returns the samples older than the window of recent samples to skip.
+func withoutRecentSamples(samples []model.SamplePair, opts SampleComparisonOptions) []model.SamplePair {
+	if opts.SkipRecentSamples <= 0 {
+		return samples
+	}
+
+	for i, s := range samples {
+		if time.Since(s.Timestamp.Time()) < opts.SkipRecentSamples {
+			return samples[:i]
+		}
+	}
+	return samples
+}
+
 func compareSamplePair(expected, actual model.SamplePair, opts SampleComparisonOptions) error {
 	if expected.Timestamp != actual.Timestamp {
 		return fmt.Errorf("expected timestamp %v but got %v", expected.Timestamp, actual.Timestamp)
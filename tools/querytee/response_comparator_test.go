@@ -239,6 +239,7 @@ func TestCompareSamplesResponse(t *testing.T) {
 		err               error
 		useRelativeError  bool
 		skipRecentSamples time.Duration
+		metricTolerances  []string
 	}{
 		{
 			name: "difference in response status",
@@ -406,12 +407,57 @@ func TestCompareSamplesResponse(t *testing.T) {
 						}`),
 			skipRecentSamples: time.Hour,
 		},
+		{
+			name: "should not fail when the matrix has recent samples missing and configured to skip",
+			expected: json.RawMessage(`{
+							"status": "success",
+							"data": {"resultType":"matrix","result":[{"metric":{"foo":"bar"},"values":[[1,"1"],[` + now + `,"10"]]}]}
+						}`),
+			actual: json.RawMessage(`{
+							"status": "success",
+							"data": {"resultType":"matrix","result":[{"metric":{"foo":"bar"},"values":[[1,"1"]]}]}
+						}`),
+			skipRecentSamples: time.Hour,
+		},
+		{
+			name:             "should pass if values are different but within the tolerance of the metric",
+			tolerance:        0.000001,
+			metricTolerances: []string{"node_.*=0.01", "cpu_usage=0.1"},
+			expected: json.RawMessage(`{
+							"status": "success",
+							"data": {"resultType":"vector","result":[{"metric":{"__name__":"cpu_usage"},"value":[1,"1.5"]}]}
+						}`),
+			actual: json.RawMessage(`{
+							"status": "success",
+							"data": {"resultType":"vector","result":[{"metric":{"__name__":"cpu_usage"},"value":[1,"1.55"]}]}
+						}`),
+		},
+		{
+			name:             "should fail if values are different over the tolerance of the metric",
+			tolerance:        0.000001,
+			metricTolerances: []string{"cpu=0.1"},
+			expected: json.RawMessage(`{
+							"status": "success",
+							"data": {"resultType":"vector","result":[{"metric":{"__name__":"cpu_usage"},"value":[1,"1.5"]}]}
+						}`),
+			actual: json.RawMessage(`{
+							"status": "success",
+							"data": {"resultType":"vector","result":[{"metric":{"__name__":"cpu_usage"},"value":[1,"1.55"]}]}
+						}`),
+			err: errors.New(`sample pair not matching for metric cpu_usage: expected value 1.5 for timestamp 1 but got 1.55`),
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
+			var metricTolerances MetricTolerances
+			for _, v := range tc.metricTolerances {
+				require.NoError(t, metricTolerances.Set(v))
+			}
+
 			samplesComparator := NewSamplesComparator(SampleComparisonOptions{
 				Tolerance:         float64(tc.tolerance),
 				UseRelativeError:  bool(tc.useRelativeError),
 				SkipRecentSamples: tc.skipRecentSamples,
+				MetricTolerances:  metricTolerances,
 			})
 			err := samplesComparator.Compare(tc.expected, tc.actual)
 			if tc.err == nil {
@@ -423,3 +469,14 @@ func TestCompareSamplesResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricTolerances(t *testing.T) {
+	var m MetricTolerances
+	require.NoError(t, m.Set("node_.*=0.01"))
+	require.NoError(t, m.Set(`a="b"=1e-3`))
+	require.Equal(t, `node_.*=0.01,a="b"=0.001`, m.String())
+
+	require.EqualError(t, m.Set("node_.*"), `invalid metric tolerance "node_.*", expected <regexp>=<tolerance>`)
+	require.EqualError(t, m.Set("node_.*=x"), `invalid metric tolerance "x": strconv.ParseFloat: parsing "x": invalid syntax`)
+	require.EqualError(t, m.Set("node_(=1"), "invalid metric tolerance regexp \"node_(\": error parsing regexp: missing closing ): `^(?:node_()$`")
+}
@@ -0,0 +1,267 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querytee
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// maxComparisonDiffs is the maximum number of differences reported by a comparison.
+const maxComparisonDiffs = 10
+
+type StructuredComparisonOptions struct {
+	// YAML is true if the responses are YAML documents rather than JSON.
+	YAML bool
+
+	// UnorderedArrays ignores the order of the items of the arrays, for the APIs not guaranteeing it.
+	UnorderedArrays bool
+
+	// IgnoredFields are the names of the object fields not compared at any depth, typically
+	// the timestamps and values changing between evaluations.
+	IgnoredFields []string
+}
+
+// StructuredComparator compares responses made of arbitrary JSON or YAML documents, like the responses of
+// the labels, series, metadata, rules and alerts APIs.
+type StructuredComparator struct {
+	opts    StructuredComparisonOptions
+	ignored map[string]struct{}
+}
+
+func NewStructuredComparator(opts StructuredComparisonOptions) *StructuredComparator {
+	ignored := make(map[string]struct{}, len(opts.IgnoredFields))
+	for _, f := range opts.IgnoredFields {
+		ignored[f] = struct{}{}
+	}
+
+	return &StructuredComparator{
+		opts:    opts,
+		ignored: ignored,
+	}
+}
+
+// ComparisonDiffError is returned when the compared responses are different, with the first differences found.
+type ComparisonDiffError struct {
+	Diffs []string
+	Total int
+}
+
+func (e *ComparisonDiffError) Error() string {
+	if e.Total > len(e.Diffs) {
+		return fmt.Sprintf("found %d differences, first ones: %s", e.Total, strings.Join(e.Diffs, "; "))
+	}
+	return fmt.Sprintf("found %d differences: %s", e.Total, strings.Join(e.Diffs, "; "))
+}
+
+func (s *StructuredComparator) Compare(expectedResponse, actualResponse []byte) error {
+	expected, err := s.unmarshal(expectedResponse)
+	if err != nil {
+		return errors.Wrap(err, "unable to unmarshal expected response")
+	}
+
+	actual, err := s.unmarshal(actualResponse)
+	if err != nil {
+		return errors.Wrap(err, "unable to unmarshal actual response")
+	}
+
+	d := &differ{unordered: s.opts.UnorderedArrays}
+	d.compare("$", s.normalize(expected), s.normalize(actual))
+	if d.total == 0 {
+		return nil
+	}
+	return &ComparisonDiffError{Diffs: d.diffs, Total: d.total}
+}
+
+func (s *StructuredComparator) unmarshal(data []byte) (interface{}, error) {
+	var v interface{}
+	if s.opts.YAML {
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		// Convert to the same types as JSON, so that values are compared the same way.
+		return jsonRoundTrip(v)
+	}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func jsonRoundTrip(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+// normalize removes the ignored fields and, if the order of arrays is ignored, sorts the arrays.
+func (s *StructuredComparator) normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if _, ok := s.ignored[k]; ok {
+				delete(v, k)
+				continue
+			}
+			v[k] = s.normalize(item)
+		}
+		return v
+
+	case []interface{}:
+		for i, item := range v {
+			v[i] = s.normalize(item)
+		}
+		if s.opts.UnorderedArrays {
+			sort.SliceStable(v, func(i, j int) bool {
+				return canonical(v[i]) < canonical(v[j])
+			})
+		}
+		return v
+
+	default:
+		return v
+	}
+}
+
+// canonical returns the JSON encoding of a value, with the keys of the objects sorted.
+func canonical(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// differ collects the differences between two documents.
+type differ struct {
+	unordered bool
+	diffs     []string
+	total     int
+}
+
+func (d *differ) add(format string, args ...interface{}) {
+	d.total++
+	if len(d.diffs) < maxComparisonDiffs {
+		d.diffs = append(d.diffs, fmt.Sprintf(format, args...))
+	}
+}
+
+func (d *differ) compare(path string, expected, actual interface{}) {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			d.add("%s: expected %s but got %s", path, canonical(expected), canonical(actual))
+			return
+		}
+		d.compareObjects(path, e, a)
+
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			d.add("%s: expected %s but got %s", path, canonical(expected), canonical(actual))
+			return
+		}
+		d.compareArrays(path, e, a)
+
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			d.add("%s: expected %s but got %s", path, canonical(expected), canonical(actual))
+		}
+	}
+}
+
+func (d *differ) compareObjects(path string, expected, actual map[string]interface{}) {
+	keys := make([]string, 0, len(expected)+len(actual))
+	for k := range expected {
+		keys = append(keys, k)
+	}
+	for k := range actual {
+		if _, ok := expected[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		e, inExpected := expected[k]
+		a, inActual := actual[k]
+		keyPath := path + "." + k
+
+		switch {
+		case !inActual:
+			d.add("%s: missing from actual response", keyPath)
+		case !inExpected:
+			d.add("%s: unexpected in actual response", keyPath)
+		default:
+			d.compare(keyPath, e, a)
+		}
+	}
+}
+
+func (d *differ) compareArrays(path string, expected, actual []interface{}) {
+	itemPath := func(i int) string { return fmt.Sprintf("%s[%d]", path, i) }
+	if d.unordered {
+		// The indexes of the remaining items are meaningless.
+		expected, actual = withoutCommonItems(expected, actual)
+		itemPath = func(int) string { return path + "[]" }
+	}
+
+	// The remaining items are compared in order, which for sorted arrays pairs the items
+	// that are the most likely to differ only slightly.
+	n := len(expected)
+	if len(actual) < n {
+		n = len(actual)
+	}
+
+	for i := 0; i < n; i++ {
+		d.compare(itemPath(i), expected[i], actual[i])
+	}
+	for i := n; i < len(expected); i++ {
+		d.add("%s: %s missing from actual response", itemPath(i), canonical(expected[i]))
+	}
+	for i := n; i < len(actual); i++ {
+		d.add("%s: %s unexpected in actual response", itemPath(i), canonical(actual[i]))
+	}
+}
+
+// withoutCommonItems returns the items of the sorted arrays that are not in both of them.
+func withoutCommonItems(expected, actual []interface{}) ([]interface{}, []interface{}) {
+	counts := make(map[string]int, len(actual))
+	for _, item := range actual {
+		counts[canonical(item)]++
+	}
+
+	var onlyExpected []interface{}
+	for _, item := range expected {
+		key := canonical(item)
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+		onlyExpected = append(onlyExpected, item)
+	}
+
+	var onlyActual []interface{}
+	for _, item := range actual {
+		key := canonical(item)
+		if counts[key] > 0 {
+			counts[key]--
+			onlyActual = append(onlyActual, item)
+		}
+	}
+
+	return onlyExpected, onlyActual
+}
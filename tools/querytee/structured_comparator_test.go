@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querytee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuredComparator_Compare(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		opts     StructuredComparisonOptions
+		expected string
+		actual   string
+		err      string
+	}{
+		{
+			name:     "same label names",
+			expected: `{"status":"success","data":["__name__","job"]}`,
+			actual:   `{"status":"success","data":["__name__","job"]}`,
+		},
+		{
+			name:     "different order of label names",
+			expected: `{"status":"success","data":["__name__","job"]}`,
+			actual:   `{"status":"success","data":["job","__name__"]}`,
+			err:      `found 2 differences: $.data[0]: expected "__name__" but got "job"; $.data[1]: expected "job" but got "__name__"`,
+		},
+		{
+			name:     "missing and unexpected label names",
+			expected: `{"status":"success","data":["__name__","instance","job"]}`,
+			actual:   `{"status":"success","data":["__name__","job"]}`,
+			err:      `found 2 differences: $.data[1]: expected "instance" but got "job"; $.data[2]: "job" missing from actual response`,
+		},
+		{
+			name:     "different order of series ignored",
+			opts:     StructuredComparisonOptions{UnorderedArrays: true},
+			expected: `{"status":"success","data":[{"__name__":"up","job":"a"},{"__name__":"up","job":"b"}]}`,
+			actual:   `{"status":"success","data":[{"job":"b","__name__":"up"},{"__name__":"up","job":"a"}]}`,
+		},
+		{
+			name:     "missing series with unordered arrays",
+			opts:     StructuredComparisonOptions{UnorderedArrays: true},
+			expected: `{"status":"success","data":[{"__name__":"up","job":"a"},{"__name__":"up","job":"b"},{"__name__":"up","job":"c"}]}`,
+			actual:   `{"status":"success","data":[{"__name__":"up","job":"c"},{"__name__":"up","job":"a"}]}`,
+			err:      `found 1 differences: $.data[]: {"__name__":"up","job":"b"} missing from actual response`,
+		},
+		{
+			name:     "different series with unordered arrays",
+			opts:     StructuredComparisonOptions{UnorderedArrays: true},
+			expected: `{"status":"success","data":[{"__name__":"up","job":"a"},{"__name__":"up","job":"b"}]}`,
+			actual:   `{"status":"success","data":[{"__name__":"up","job":"a"},{"__name__":"up","job":"c","pod":"p"}]}`,
+			err:      `found 2 differences: $.data[].job: expected "b" but got "c"; $.data[].pod: unexpected in actual response`,
+		},
+		{
+			name: "ignored fields",
+			opts: StructuredComparisonOptions{UnorderedArrays: true, IgnoredFields: []string{"activeAt", "value"}},
+			expected: `{"status":"success","data":{"alerts":[
+				{"labels":{"alertname":"A"},"state":"firing","activeAt":"2022-07-01T10:00:00Z","value":"1e+00"},
+				{"labels":{"alertname":"B"},"state":"pending","activeAt":"2022-07-01T10:00:00Z","value":"2e+00"}
+			]}}`,
+			actual: `{"status":"success","data":{"alerts":[
+				{"labels":{"alertname":"B"},"state":"pending","activeAt":"2022-07-01T10:00:15Z","value":"3e+00"},
+				{"labels":{"alertname":"A"},"state":"firing","activeAt":"2022-07-01T10:00:15Z","value":"1e+00"}
+			]}}`,
+		},
+		{
+			name: "different state of alert",
+			opts: StructuredComparisonOptions{UnorderedArrays: true, IgnoredFields: []string{"activeAt", "value"}},
+			expected: `{"status":"success","data":{"alerts":[
+				{"labels":{"alertname":"A"},"state":"firing","activeAt":"2022-07-01T10:00:00Z","value":"1e+00"}
+			]}}`,
+			actual: `{"status":"success","data":{"alerts":[
+				{"labels":{"alertname":"A"},"state":"pending","activeAt":"2022-07-01T10:00:15Z","value":"1e+00"}
+			]}}`,
+			err: `found 1 differences: $.data.alerts[].state: expected "firing" but got "pending"`,
+		},
+		{
+			name:     "missing field",
+			expected: `{"status":"success","data":{"up":[{"type":"gauge","help":"Up.","unit":""}]}}`,
+			actual:   `{"status":"success","data":{"up":[{"type":"gauge","help":"Up."}]}}`,
+			err:      `found 1 differences: $.data.up[0].unit: missing from actual response`,
+		},
+		{
+			name:     "different types",
+			expected: `{"status":"success","data":["job"]}`,
+			actual:   `{"status":"success","data":null}`,
+			err:      `found 1 differences: $.data: expected ["job"] but got null`,
+		},
+		{
+			name:     "too many differences",
+			expected: `[1,2,3,4,5,6,7,8,9,10,11,12]`,
+			actual:   `[12,11,10,9,8,7,6,5,4,3,2,1]`,
+			err:      `found 12 differences, first ones: $[0]: expected 1 but got 12; $[1]: expected 2 but got 11; $[2]: expected 3 but got 10; $[3]: expected 4 but got 9; $[4]: expected 5 but got 8; $[5]: expected 6 but got 7; $[6]: expected 7 but got 6; $[7]: expected 8 but got 5; $[8]: expected 9 but got 4; $[9]: expected 10 but got 3`,
+		},
+		{
+			name: "YAML rules",
+			opts: StructuredComparisonOptions{YAML: true, UnorderedArrays: true},
+			expected: `team-a:
+  - name: group
+    rules:
+      - record: job:up:sum
+        expr: sum by (job) (up)
+`,
+			actual: `team-a:
+- name: group
+  rules:
+  - expr: sum by (job) (up)
+    record: job:up:sum
+`,
+		},
+		{
+			name: "different YAML rules",
+			opts: StructuredComparisonOptions{YAML: true, UnorderedArrays: true},
+			expected: `team-a:
+  - name: group
+    interval: 1m
+`,
+			actual: `team-a:
+  - name: group
+    interval: 2m
+`,
+			err: `found 1 differences: $.team-a[].interval: expected "1m" but got "2m"`,
+		},
+		{
+			name:     "invalid actual response",
+			expected: `{}`,
+			actual:   `{`,
+			err:      `unable to unmarshal actual response: unexpected end of JSON input`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewStructuredComparator(tc.opts).Compare([]byte(tc.expected), []byte(tc.actual))
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}